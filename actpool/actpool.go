@@ -8,6 +8,7 @@ package actpool
 
 import (
 	"context"
+	"io"
 	"sync"
 
 	"github.com/iotexproject/iotex-core/pkg/prometheustimer"
@@ -18,6 +19,7 @@ import (
 	"github.com/iotexproject/iotex-address/address"
 	"github.com/iotexproject/iotex-core/action"
 	"github.com/iotexproject/iotex-core/action/protocol"
+	"github.com/iotexproject/iotex-core/actpool/actioniterator"
 	"github.com/iotexproject/iotex-core/blockchain"
 	"github.com/iotexproject/iotex-core/config"
 	"github.com/iotexproject/iotex-core/pkg/log"
@@ -29,6 +31,8 @@ type ActPool interface {
 	Reset()
 	// PendingActionMap returns an action map with all accepted actions
 	PendingActionMap() map[string][]action.SealedEnvelope
+	// PendingActionIterator returns an iterator of all accepted actions in the order of the ordering strategy
+	PendingActionIterator() actioniterator.ActionIterator
 	// Add adds an action into the pool after passing validation
 	Add(act action.SealedEnvelope) error
 	// GetPendingNonce returns pending nonce in pool given an account address
@@ -49,6 +53,10 @@ type ActPool interface {
 	AddActionValidators(...protocol.ActionValidator)
 
 	AddActionEnvelopeValidators(...protocol.ActionEnvelopeValidator)
+	// Dump writes the actions in pool to the writer, such that they could be restored by Load after a restart
+	Dump(w io.Writer) error
+	// Load adds the actions dumped by Dump into pool after validating them against the current state
+	Load(r io.Reader) error
 }
 
 // Option sets action pool construction parameter
@@ -62,6 +70,18 @@ func EnableExperimentalActions() Option {
 	}
 }
 
+// WithOrderingStrategy sets the order of picking the pending actions of different accounts, in place of the one of
+// the config
+func WithOrderingStrategy(ordering OrderingStrategy) Option {
+	return func(pool *actPool) error {
+		if ordering == nil {
+			return errors.New("ordering strategy cannot be nil")
+		}
+		pool.ordering = ordering
+		return nil
+	}
+}
+
 // actPool implements ActPool interface
 type actPool struct {
 	mutex                     sync.RWMutex
@@ -69,6 +89,9 @@ type actPool struct {
 	bc                        blockchain.Blockchain
 	accountActs               map[string]ActQueue
 	allActions                map[hash.Hash256]action.SealedEnvelope
+	arrivals                  map[hash.Hash256]uint64
+	arrivalSeq                uint64
+	ordering                  OrderingStrategy
 	gasInPool                 uint64
 	actionEnvelopeValidators  []protocol.ActionEnvelopeValidator
 	validators                []protocol.ActionValidator
@@ -83,6 +106,10 @@ func NewActPool(bc blockchain.Blockchain, cfg config.ActPool, opts ...Option) (A
 		return nil, errors.New("Try to attach a nil blockchain")
 	}
 
+	ordering, err := orderingStrategy(cfg)
+	if err != nil {
+		return nil, err
+	}
 	senderBlackList := make(map[string]bool)
 	for _, bannedSender := range cfg.BlackList {
 		senderBlackList[bannedSender] = true
@@ -94,6 +121,8 @@ func NewActPool(bc blockchain.Blockchain, cfg config.ActPool, opts ...Option) (A
 		senderBlackList: senderBlackList,
 		accountActs:     make(map[string]ActQueue),
 		allActions:      make(map[hash.Hash256]action.SealedEnvelope),
+		arrivals:        make(map[hash.Hash256]uint64),
+		ordering:        ordering,
 	}
 	for _, opt := range opts {
 		if err := opt(ap); err != nil {
@@ -137,19 +166,42 @@ func (ap *actPool) Reset() {
 	ap.reset()
 }
 
-// PendingActionIterator returns an action interator with all accepted actions
+// PendingActionMap returns an action map with all accepted actions
 func (ap *actPool) PendingActionMap() map[string][]action.SealedEnvelope {
 	ap.mutex.Lock()
 	defer ap.mutex.Unlock()
 
-	// Remove the actions that are already timeout
-	ap.reset()
+	return ap.pendingActionMap()
+}
 
-	actionMap := make(map[string][]action.SealedEnvelope)
-	for from, queue := range ap.accountActs {
-		actionMap[from] = append(actionMap[from], queue.PendingActs()...)
+// PendingActionIterator returns an iterator of all accepted actions in the order of the ordering strategy
+func (ap *actPool) PendingActionIterator() actioniterator.ActionIterator {
+	ap.mutex.Lock()
+	defer ap.mutex.Unlock()
+
+	actionMap := ap.pendingActionMap()
+	// snapshot the arrivals, as the pool keeps changing while the iterator is in use. They are keyed by the sender and
+	// the nonce, which identify a pending action without hashing it on every comparison.
+	type senderNonce struct {
+		sender string
+		nonce  uint64
 	}
-	return actionMap
+	keyOf := func(act action.SealedEnvelope) senderNonce {
+		return senderNonce{sender: string(act.SrcPubkey().Bytes()), nonce: act.Nonce()}
+	}
+	arrivals := make(map[senderNonce]uint64)
+	for _, acts := range actionMap {
+		for _, act := range acts {
+			arrivals[keyOf(act)] = ap.arrivals[act.Hash()]
+		}
+	}
+	ordering := ap.ordering
+	return actioniterator.NewActionIteratorWithComparator(actionMap, func(a, b action.SealedEnvelope) bool {
+		return ordering(
+			PendingAction{SealedEnvelope: a, Arrival: arrivals[keyOf(a)]},
+			PendingAction{SealedEnvelope: b, Arrival: arrivals[keyOf(b)]},
+		)
+	})
 }
 
 func (ap *actPool) Add(act action.SealedEnvelope) error {
@@ -365,6 +417,8 @@ func (ap *actPool) enqueueAction(sender string, act action.SealedEnvelope, hash
 		return errors.Wrapf(err, "cannot put action %x into ActQueue", hash)
 	}
 	ap.allActions[hash] = act
+	ap.arrivalSeq++
+	ap.arrivals[hash] = ap.arrivalSeq
 
 	intrinsicGas, _ := act.IntrinsicGas()
 	ap.gasInPool += intrinsicGas
@@ -376,6 +430,17 @@ func (ap *actPool) enqueueAction(sender string, act action.SealedEnvelope, hash
 	return nil
 }
 
+func (ap *actPool) pendingActionMap() map[string][]action.SealedEnvelope {
+	// Remove the actions that are already timeout
+	ap.reset()
+
+	actionMap := make(map[string][]action.SealedEnvelope)
+	for from, queue := range ap.accountActs {
+		actionMap[from] = append(actionMap[from], queue.PendingActs()...)
+	}
+	return actionMap
+}
+
 // removeConfirmedActs removes processed (committed to block) actions from pool
 func (ap *actPool) removeConfirmedActs() {
 	for from, queue := range ap.accountActs {
@@ -401,6 +466,7 @@ func (ap *actPool) removeInvalidActs(acts []action.SealedEnvelope) {
 		hash := act.Hash()
 		log.L().Debug("Removed invalidated action.", log.Hex("hash", hash[:]))
 		delete(ap.allActions, hash)
+		delete(ap.arrivals, hash)
 		intrinsicGas, _ := act.IntrinsicGas()
 		ap.gasInPool -= intrinsicGas
 	}
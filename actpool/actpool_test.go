@@ -404,6 +404,64 @@ func TestActPool_PickActs(t *testing.T) {
 	})
 }
 
+func TestActPool_PendingActionIterator(t *testing.T) {
+	require := require.New(t)
+	bc := blockchain.NewBlockchain(
+		config.Default,
+		blockchain.InMemStateFactoryOption(),
+		blockchain.InMemDaoOption(),
+	)
+	require.NoError(bc.Start(context.Background()))
+	defer func() {
+		require.NoError(bc.Stop(context.Background()))
+	}()
+	for _, addr := range []string{addr1, addr2, addr3} {
+		_, err := bc.CreateState(addr, big.NewInt(1000000))
+		require.NoError(err)
+	}
+	// the actions of different accounts arrive at the pool in the order of gas price 1, 3 and 2
+	tsf1, err := testutil.SignedTransfer(addr4, priKey1, uint64(1), big.NewInt(10), []byte{}, uint64(10000), big.NewInt(1))
+	require.NoError(err)
+	tsf2, err := testutil.SignedTransfer(addr4, priKey2, uint64(1), big.NewInt(10), []byte{}, uint64(10000), big.NewInt(3))
+	require.NoError(err)
+	tsf3, err := testutil.SignedTransfer(addr4, priKey3, uint64(1), big.NewInt(10), []byte{}, uint64(10000), big.NewInt(2))
+	require.NoError(err)
+	pickAll := func(ordering string, opts ...Option) []action.SealedEnvelope {
+		cfg := getActPoolCfg()
+		cfg.Ordering = ordering
+		ap, err := NewActPool(bc, cfg, opts...)
+		require.NoError(err)
+		for _, tsf := range []action.SealedEnvelope{tsf1, tsf2, tsf3} {
+			require.NoError(ap.Add(tsf))
+		}
+		var picked []action.SealedEnvelope
+		iter := ap.PendingActionIterator()
+		for {
+			act, ok := iter.Next()
+			if !ok {
+				return picked
+			}
+			picked = append(picked, act)
+		}
+	}
+
+	require.Equal([]action.SealedEnvelope{tsf2, tsf3, tsf1}, pickAll(""))
+	require.Equal([]action.SealedEnvelope{tsf2, tsf3, tsf1}, pickAll(config.GasPriceOrdering))
+	require.Equal([]action.SealedEnvelope{tsf1, tsf2, tsf3}, pickAll(config.FIFOOrdering))
+	lowestPriceFirst := func(a, b PendingAction) bool { return a.GasPrice().Cmp(b.GasPrice()) < 0 }
+	require.Equal(
+		[]action.SealedEnvelope{tsf1, tsf3, tsf2},
+		pickAll(config.FIFOOrdering, WithOrderingStrategy(lowestPriceFirst)),
+	)
+
+	_, err = NewActPool(bc, getActPoolCfg(), WithOrderingStrategy(nil))
+	require.Error(err)
+	cfg := getActPoolCfg()
+	cfg.Ordering = "lifo"
+	_, err = NewActPool(bc, cfg)
+	require.Error(err)
+}
+
 func TestActPool_removeConfirmedActs(t *testing.T) {
 	require := require.New(t)
 	bc := blockchain.NewBlockchain(
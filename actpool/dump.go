@@ -0,0 +1,138 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package actpool
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/action"
+	"github.com/iotexproject/iotex-core/pkg/log"
+)
+
+// maxDumpedActionSize is the max size of an action read from a dump, which guards against allocating a huge buffer
+// for a corrupted length
+const maxDumpedActionSize = 32 << 20
+
+// Dump writes the actions in the pool to the writer in the order of their arrivals, each of which is the serialized
+// action prefixed by its length in 4 bytes
+func (ap *actPool) Dump(w io.Writer) error {
+	if w == nil {
+		return errors.New("writer is nil")
+	}
+	ap.mutex.RLock()
+	acts := make([]action.SealedEnvelope, 0, len(ap.allActions))
+	arrivals := make([]uint64, 0, len(ap.allActions))
+	for hash, act := range ap.allActions {
+		acts = append(acts, act)
+		arrivals = append(arrivals, ap.arrivals[hash])
+	}
+	ap.mutex.RUnlock()
+
+	sort.Sort(&actsByArrival{acts: acts, arrivals: arrivals})
+	lenBuf := make([]byte, 4)
+	for _, act := range acts {
+		data, err := proto.Marshal(act.Proto())
+		if err != nil {
+			return errors.Wrapf(err, "failed to serialize action %x", act.Hash())
+		}
+		binary.BigEndian.PutUint32(lenBuf, uint32(len(data)))
+		if _, err := w.Write(lenBuf); err != nil {
+			return errors.Wrap(err, "failed to write the dump")
+		}
+		if _, err := w.Write(data); err != nil {
+			return errors.Wrap(err, "failed to write the dump")
+		}
+	}
+	return nil
+}
+
+// Load reads the actions dumped by Dump and adds them into the pool. The actions are validated as the ones added by
+// Add, against the current state, hence an action whose nonce has been confirmed, or which isn't valid any more, is
+// dropped. It fails only if the dump is corrupted or can't be read, in which case the actions read so far are kept.
+func (ap *actPool) Load(r io.Reader) error {
+	if r == nil {
+		return errors.New("reader is nil")
+	}
+	var (
+		lenBuf  = make([]byte, 4)
+		loaded  int
+		dropped int
+	)
+	defer func() {
+		log.L().Info("Loaded the dumped actions.", zap.Int("loaded", loaded), zap.Int("dropped", dropped))
+	}()
+	for {
+		if _, err := io.ReadFull(r, lenBuf); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errors.Wrap(err, "failed to read the length of a dumped action")
+		}
+		size := binary.BigEndian.Uint32(lenBuf)
+		if size > maxDumpedActionSize {
+			return errors.Errorf("dumped action of size %d is above the max size %d", size, maxDumpedActionSize)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return errors.Wrap(err, "failed to read a dumped action")
+		}
+		var pb iotextypes.Action
+		if err := proto.Unmarshal(data, &pb); err != nil {
+			return errors.Wrap(err, "failed to deserialize a dumped action")
+		}
+		var act action.SealedEnvelope
+		if err := act.LoadProto(&pb); err != nil {
+			return errors.Wrap(err, "failed to load a dumped action")
+		}
+		if err := ap.loadAction(act); err != nil {
+			h := act.Hash()
+			log.L().Debug("Dropped a dumped action.", log.Hex("hash", h[:]), zap.Error(err))
+			dropped++
+			continue
+		}
+		loaded++
+	}
+}
+
+// loadAction adds a dumped action into the pool, unless its nonce has been confirmed since it was dumped
+func (ap *actPool) loadAction(act action.SealedEnvelope) error {
+	sender, err := address.FromBytes(act.SrcPubkey().Hash())
+	if err != nil {
+		return errors.Wrap(err, "failed to get address from bytes")
+	}
+	confirmedNonce, err := ap.bc.Nonce(sender.String())
+	if err != nil {
+		return errors.Wrap(err, "failed to get sender's nonce")
+	}
+	if act.Nonce() <= confirmedNonce {
+		return errors.Wrapf(action.ErrNonce, "nonce %d has been confirmed up to %d", act.Nonce(), confirmedNonce)
+	}
+	return ap.Add(act)
+}
+
+// actsByArrival sorts the actions by their arrivals
+type actsByArrival struct {
+	acts     []action.SealedEnvelope
+	arrivals []uint64
+}
+
+func (s *actsByArrival) Len() int { return len(s.acts) }
+
+func (s *actsByArrival) Less(i, j int) bool { return s.arrivals[i] < s.arrivals[j] }
+
+func (s *actsByArrival) Swap(i, j int) {
+	s.acts[i], s.acts[j] = s.acts[j], s.acts[i]
+	s.arrivals[i], s.arrivals[j] = s.arrivals[j], s.arrivals[i]
+}
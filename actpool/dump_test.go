@@ -0,0 +1,115 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package actpool
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/action"
+	"github.com/iotexproject/iotex-core/action/protocol"
+	"github.com/iotexproject/iotex-core/action/protocol/account"
+	"github.com/iotexproject/iotex-core/action/protocol/execution"
+	"github.com/iotexproject/iotex-core/blockchain"
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/test/identityset"
+	"github.com/iotexproject/iotex-core/testutil"
+)
+
+func TestActPool_DumpAndLoad(t *testing.T) {
+	require := require.New(t)
+	bc := blockchain.NewBlockchain(
+		config.Default,
+		blockchain.InMemStateFactoryOption(),
+		blockchain.InMemDaoOption(),
+		blockchain.EnableExperimentalActions(),
+	)
+	hu := config.NewHeightUpgrade(config.Default)
+	bc.GetFactory().AddActionHandlers(account.NewProtocol(hu), execution.NewProtocol(bc, hu))
+	require.NoError(bc.Start(context.Background()))
+	defer func() {
+		require.NoError(bc.Stop(context.Background()))
+	}()
+	_, err := bc.CreateState(addr1, big.NewInt(100))
+	require.NoError(err)
+	_, err = bc.CreateState(addr2, big.NewInt(100))
+	require.NoError(err)
+	newPool := func() *actPool {
+		ap, err := NewActPool(bc, getActPoolCfg(), EnableExperimentalActions())
+		require.NoError(err)
+		ap.AddActionEnvelopeValidators(protocol.NewGenericValidator(bc))
+		ap.AddActionValidators(account.NewProtocol(hu), execution.NewProtocol(bc, hu))
+		return ap.(*actPool)
+	}
+
+	tsf1, err := testutil.SignedTransfer(addr2, priKey1, uint64(1), big.NewInt(10), []byte{}, uint64(100000), big.NewInt(0))
+	require.NoError(err)
+	tsf2, err := testutil.SignedTransfer(addr2, priKey1, uint64(2), big.NewInt(20), []byte{}, uint64(100000), big.NewInt(0))
+	require.NoError(err)
+	// a gap in the nonces, which is queued but not pending
+	tsf3, err := testutil.SignedTransfer(addr2, priKey1, uint64(4), big.NewInt(30), []byte{}, uint64(100000), big.NewInt(0))
+	require.NoError(err)
+	tsf4, err := testutil.SignedTransfer(addr1, priKey2, uint64(1), big.NewInt(40), []byte{}, uint64(100000), big.NewInt(0))
+	require.NoError(err)
+	ap := newPool()
+	for _, act := range []action.SealedEnvelope{tsf1, tsf2, tsf3, tsf4} {
+		require.NoError(ap.Add(act))
+	}
+
+	var dump bytes.Buffer
+	require.NoError(ap.Dump(&dump))
+	require.Error(ap.Dump(nil))
+	var empty bytes.Buffer
+	require.NoError(newPool().Dump(&empty))
+	require.Zero(empty.Len())
+
+	// the first action of addr1 is committed before the restart
+	ws, err := bc.GetFactory().NewWorkingSet()
+	require.NoError(err)
+	ctx := protocol.WithRunActionsCtx(context.Background(), protocol.RunActionsCtx{
+		Producer: identityset.Address(27),
+		GasLimit: uint64(1000000),
+	})
+	_, err = ws.RunActions(ctx, 0, []action.SealedEnvelope{tsf1})
+	require.NoError(err)
+	require.NoError(bc.GetFactory().Commit(ws))
+
+	// the still valid actions are restored into a fresh pool
+	restored := newPool()
+	require.NoError(restored.Load(bytes.NewReader(dump.Bytes())))
+	require.Equal(uint64(3), restored.GetSize())
+	_, err = restored.GetActionByHash(tsf1.Hash())
+	require.Equal(action.ErrNotFound, errors.Cause(err))
+	for _, act := range []action.SealedEnvelope{tsf2, tsf3, tsf4} {
+		loaded, err := restored.GetActionByHash(act.Hash())
+		require.NoError(err)
+		require.Equal(act.Hash(), loaded.Hash())
+	}
+	pendingNonce, err := restored.GetPendingNonce(addr1)
+	require.NoError(err)
+	require.Equal(uint64(3), pendingNonce)
+	pendingNonce, err = restored.GetPendingNonce(addr2)
+	require.NoError(err)
+	require.Equal(uint64(2), pendingNonce)
+
+	// loading the dump again drops the duplicates
+	require.NoError(restored.Load(bytes.NewReader(dump.Bytes())))
+	require.Equal(uint64(3), restored.GetSize())
+
+	// a corrupted dump fails, keeping the actions read before
+	corrupted := newPool()
+	require.Error(corrupted.Load(bytes.NewReader(dump.Bytes()[:dump.Len()-1])))
+	require.Equal(uint64(2), corrupted.GetSize())
+	require.Error(newPool().Load(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff})))
+	require.Error(newPool().Load(bytes.NewReader([]byte{0, 0, 0, 2, 0xff, 0xff})))
+	require.Error(newPool().Load(nil))
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package actpool
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/action"
+	"github.com/iotexproject/iotex-core/config"
+)
+
+type (
+	// PendingAction is an action accepted by the pool along with the sequence number of its arrival
+	PendingAction struct {
+		action.SealedEnvelope
+		Arrival uint64
+	}
+
+	// OrderingStrategy tells whether a pending action is picked before another one of a different account for the
+	// block assembly. The actions of an account are always picked in the order of their nonces.
+	OrderingStrategy func(a, b PendingAction) bool
+)
+
+// GasPriceOrdering picks the action of the highest gas price first, and the earliest arrival on a tie
+func GasPriceOrdering(a, b PendingAction) bool {
+	if c := a.GasPrice().Cmp(b.GasPrice()); c != 0 {
+		return c > 0
+	}
+	return a.Arrival < b.Arrival
+}
+
+// FIFOOrdering picks the action of the earliest arrival first
+func FIFOOrdering(a, b PendingAction) bool {
+	return a.Arrival < b.Arrival
+}
+
+// orderingStrategy returns the ordering strategy of the config
+func orderingStrategy(cfg config.ActPool) (OrderingStrategy, error) {
+	switch cfg.Ordering {
+	case "", config.GasPriceOrdering:
+		return GasPriceOrdering, nil
+	case config.FIFOOrdering:
+		return FIFOOrdering, nil
+	default:
+		return nil, errors.Errorf("unknown action ordering %s", cfg.Ordering)
+	}
+}
@@ -169,7 +169,7 @@ func (api *Server) GetAccount(ctx context.Context, in *iotexapi.GetAccountReques
 
 // GetActions returns actions
 func (api *Server) GetActions(ctx context.Context, in *iotexapi.GetActionsRequest) (*iotexapi.GetActionsResponse, error) {
-	if !api.hasActionIndex && (in.GetByHash() != nil || in.GetByAddr() != nil) {
+	if !api.hasActionIndex && in.GetByHash() != nil {
 		return nil, status.Error(codes.NotFound, "Action index is not available.")
 	}
 	switch {
@@ -181,6 +181,9 @@ func (api *Server) GetActions(ctx context.Context, in *iotexapi.GetActionsReques
 		return api.getSingleAction(request.ActionHash, request.CheckPending)
 	case in.GetByAddr() != nil:
 		request := in.GetByAddr()
+		if !api.hasActionIndex {
+			return api.getActionsByAddressFromBlocks(request.Address, request.Start, request.Count)
+		}
 		return api.getActionsByAddress(request.Address, request.Start, request.Count)
 	case in.GetUnconfirmedByAddr() != nil:
 		request := in.GetUnconfirmedByAddr()
@@ -535,15 +538,11 @@ func (api *Server) GetLogs(
 	switch {
 	case in.GetByBlock() != nil:
 		req := in.GetByBlock()
-		h, err := api.bc.GetHeightByHash(hash.BytesToHash256(req.BlockHash))
-		if err != nil {
-			return nil, status.Error(codes.InvalidArgument, "invalid block hash")
-		}
 		filter, ok := NewLogFilter(in.Filter, nil, nil).(*LogFilter)
 		if !ok {
 			return nil, status.Error(codes.Internal, "cannot convert to *LogFilter")
 		}
-		logs, err := api.getLogsInBlock(filter, h, 1)
+		logs, err := api.getLogsByBlockHash(filter, hash.BytesToHash256(req.BlockHash))
 		return &iotexapi.GetLogsResponse{Logs: logs}, err
 	case in.GetByRange() != nil:
 		req := in.GetByRange()
@@ -652,6 +651,24 @@ func (api *Server) GetVotes(
 	return response, nil
 }
 
+// ConsensusMetaByHeight returns the consensus metadata of the block of a height, i.e., the round in which the block was
+// committed, its proposer and the number of its COMMIT endorsements. The round is blockchain.UnknownRound if the block
+// wasn't committed by the consensus of this node.
+func (api *Server) ConsensusMetaByHeight(height uint64) (*blockchain.ConsensusMeta, error) {
+	if height == 0 || height > api.bc.TipHeight() {
+		return nil, status.Errorf(codes.NotFound, "block with height %d is not committed", height)
+	}
+	meta, err := api.bc.ConsensusMetaByHeight(height)
+	switch errors.Cause(err) {
+	case nil:
+		return meta, nil
+	case db.ErrNotExist:
+		return nil, status.Error(codes.NotFound, err.Error())
+	default:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+}
+
 // Start starts the API server
 func (api *Server) Start() error {
 	portStr := ":" + strconv.Itoa(api.cfg.API.Port)
@@ -780,6 +797,71 @@ func (api *Server) getActions(start uint64, count uint64) (*iotexapi.GetActionsR
 	}, nil
 }
 
+// getActionsByAddressFromBlocks returns the actions associated with an address in the order of the blocks, without the
+// action index. Only the blocks carrying the action addresses in the logs bloom filter are scanned, up to the latest
+// ActionScanBlockLimit of them, and the ones of which the filter rules out the address are skipped without being read.
+// The scan stops once the page is full, so Total counts the actions of the address found in the blocks scanned.
+func (api *Server) getActionsByAddressFromBlocks(
+	addrStr string,
+	start uint64,
+	count uint64,
+) (*iotexapi.GetActionsResponse, error) {
+	if count > api.cfg.API.RangeQueryLimit {
+		return nil, status.Error(codes.InvalidArgument, "range exceeds the limit")
+	}
+	addr, err := address.FromString(addrStr)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	g := api.cfg.Genesis
+	if !g.EnableActionAddressBloom {
+		return nil, status.Error(codes.NotFound, "Action index is not available.")
+	}
+	startHeight := g.ActionAddressBloomBlockHeight
+	if startHeight < g.AleutianBlockHeight {
+		startHeight = g.AleutianBlockHeight
+	}
+	if startHeight < 1 {
+		startHeight = 1
+	}
+	tipHeight := api.bc.TipHeight()
+	if limit := api.cfg.API.ActionScanBlockLimit; limit > 0 && tipHeight >= limit && tipHeight-limit+1 > startHeight {
+		startHeight = tipHeight - limit + 1
+	}
+	res := &iotexapi.GetActionsResponse{}
+	for height := startHeight; height <= tipHeight && uint64(len(res.ActionInfo)) < count; height++ {
+		header, err := api.bc.BlockHeaderByHeight(height)
+		if err != nil {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		if !header.MayContainAddress(addr) {
+			continue
+		}
+		blk, err := api.bc.GetBlockByHeight(height)
+		if err != nil {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		for i, selp := range blk.Actions {
+			sender, _ := address.FromBytes(selp.SrcPubkey().Hash())
+			dst, _ := selp.Destination()
+			if (sender == nil || sender.String() != addrStr) && dst != addrStr {
+				continue
+			}
+			if uint64(len(res.ActionInfo)) >= count {
+				break
+			}
+			if res.Total >= start {
+				res.ActionInfo = append(res.ActionInfo, api.actionsInBlock(blk, uint64(i), 1)...)
+			}
+			res.Total++
+		}
+	}
+	if res.Total != 0 && start >= res.Total {
+		return nil, status.Error(codes.InvalidArgument, "start exceeds the limit")
+	}
+	return res, nil
+}
+
 // getSingleAction returns action by action hash
 func (api *Server) getSingleAction(actionHash string, checkPending bool) (*iotexapi.GetActionsResponse, error) {
 	actHash, err := hash.HexStringToHash256(actionHash)
@@ -1207,6 +1289,23 @@ func (api *Server) reverseActionsInBlock(blk *block.Block, reverseStart, count u
 	return res
 }
 
+// getLogsByBlockHash returns the logs of a block matching the filter, where the receipts are not read if the logs
+// bloom filter in the header of the block rules out the filter
+func (api *Server) getLogsByBlockHash(filter *LogFilter, blkHash hash.Hash256) ([]*iotextypes.Log, error) {
+	header, err := api.bc.BlockHeaderByHash(blkHash)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid block hash")
+	}
+	if !header.MayContainLogKeys(filter.bloomKeys()) {
+		return nil, nil
+	}
+	receipts, err := api.bc.GetReceiptsByBlockHash(blkHash)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return filter.MatchLogs(receipts), nil
+}
+
 func (api *Server) getLogsInBlock(filter *LogFilter, start, count uint64) ([]*iotextypes.Log, error) {
 	// filter logs within start --> end
 	var logs []*iotextypes.Log
@@ -1214,7 +1313,15 @@ func (api *Server) getLogsInBlock(filter *LogFilter, start, count uint64) ([]*io
 	if end > api.bc.TipHeight() {
 		end = api.bc.TipHeight()
 	}
-	for i := start; i <= end; i++ {
+	if start > end {
+		return logs, nil
+	}
+	// only read the receipts of the blocks of which the logs bloom filter may match
+	heights, err := api.bc.BlocksMatchingBloom(start, end, filter.bloomKeys())
+	if err != nil {
+		return logs, status.Error(codes.InvalidArgument, err.Error())
+	}
+	for _, i := range heights {
 		receipts, err := api.bc.GetReceiptsByHeight(i)
 		if err != nil {
 			return logs, status.Error(codes.InvalidArgument, err.Error())
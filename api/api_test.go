@@ -21,6 +21,8 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/iotexproject/go-pkgs/hash"
 	"github.com/iotexproject/iotex-core/action"
@@ -33,6 +35,7 @@ import (
 	"github.com/iotexproject/iotex-core/action/protocol/rolldpos"
 	"github.com/iotexproject/iotex-core/actpool"
 	"github.com/iotexproject/iotex-core/blockchain"
+	"github.com/iotexproject/iotex-core/blockchain/block"
 	"github.com/iotexproject/iotex-core/blockchain/genesis"
 	"github.com/iotexproject/iotex-core/config"
 	"github.com/iotexproject/iotex-core/gasstation"
@@ -766,6 +769,79 @@ func TestServer_GetActionsByAddress(t *testing.T) {
 	}
 }
 
+func TestServer_GetActionsByAddressFromBlocks(t *testing.T) {
+	require := require.New(t)
+	cfg := newConfig()
+	cfg.Genesis.AleutianBlockHeight = 1
+	cfg.Genesis.EnableActionAddressBloom = true
+	cfg.Genesis.ActionAddressBloomBlockHeight = 1
+
+	svr, err := createServer(cfg, false)
+	require.NoError(err)
+	byAddr := func(address string, start, count uint64) *iotexapi.GetActionsRequest {
+		return &iotexapi.GetActionsRequest{
+			Lookup: &iotexapi.GetActionsRequest_ByAddr{
+				ByAddr: &iotexapi.GetActionsByAddressRequest{
+					Address: address,
+					Start:   start,
+					Count:   count,
+				},
+			},
+		}
+	}
+
+	for _, test := range getActionsByAddressTests {
+		request := byAddr(test.address, test.start, test.count)
+		svr.hasActionIndex = true
+		expected, err := svr.GetActions(context.Background(), request)
+		require.NoError(err)
+		// the same actions are found in the blocks without the index
+		svr.hasActionIndex = false
+		res, err := svr.GetActions(context.Background(), request)
+		require.NoError(err)
+		require.Equal(test.numActions, len(res.ActionInfo))
+		require.True(res.Total <= expected.Total)
+		hashes := make(map[string]bool)
+		for _, act := range expected.ActionInfo {
+			hashes[act.ActHash] = true
+		}
+		for _, act := range res.ActionInfo {
+			require.True(hashes[act.ActHash])
+		}
+	}
+
+	addr := identityset.Address(30).String()
+	// the scan stops once the page is full
+	res, err := svr.GetActions(context.Background(), byAddr(addr, 1, 2))
+	require.NoError(err)
+	require.Equal(2, len(res.ActionInfo))
+	require.Equal(uint64(3), res.Total)
+	all, err := svr.GetActions(context.Background(), byAddr(addr, 0, 100))
+	require.NoError(err)
+	require.True(all.Total > res.Total)
+	// only the latest blocks are scanned
+	tipHeight := svr.bc.TipHeight()
+	svr.cfg.API.ActionScanBlockLimit = 1
+	res, err = svr.GetActions(context.Background(), byAddr(addr, 0, 100))
+	require.NoError(err)
+	require.NotEmpty(res.ActionInfo)
+	require.True(len(res.ActionInfo) < len(all.ActionInfo))
+	for _, act := range res.ActionInfo {
+		require.Equal(tipHeight, act.BlkHeight)
+	}
+	svr.cfg.API.ActionScanBlockLimit = config.Default.API.ActionScanBlockLimit
+	// the blocks before the action address bloom aren't scanned
+	svr.cfg.Genesis.ActionAddressBloomBlockHeight = tipHeight
+	res, err = svr.GetActions(context.Background(), byAddr(addr, 0, 100))
+	require.NoError(err)
+	for _, act := range res.ActionInfo {
+		require.Equal(tipHeight, act.BlkHeight)
+	}
+	svr.cfg.Genesis.EnableActionAddressBloom = false
+	_, err = svr.GetActions(context.Background(), byAddr(addr, 0, 100))
+	require.Equal(codes.NotFound, status.Code(err))
+}
+
 func TestServer_GetUnconfirmedActionsByAddress(t *testing.T) {
 	require := require.New(t)
 	cfg := newConfig()
@@ -891,6 +967,35 @@ func TestServer_GetBlockMetaUpgrade(t *testing.T) {
 	require.Equal(action.ErrNotFound, errors.Cause(err))
 }
 
+func TestServer_ConsensusMetaByHeight(t *testing.T) {
+	require := require.New(t)
+	cfg := newConfig()
+
+	svr, err := createServer(cfg, false)
+	require.NoError(err)
+
+	// the blocks of the test chain aren't committed by the consensus
+	header, err := svr.bc.BlockHeaderByHeight(1)
+	require.NoError(err)
+	meta, err := svr.ConsensusMetaByHeight(1)
+	require.NoError(err)
+	require.Equal(uint64(1), meta.Height)
+	require.Equal(header.HashBlock(), meta.BlockHash)
+	require.Equal(uint32(blockchain.UnknownRound), meta.Round)
+	require.Equal(header.ProducerAddress(), meta.Proposer)
+
+	meta.Round = 2
+	require.NoError(svr.bc.PutConsensusMeta(meta))
+	meta, err = svr.ConsensusMetaByHeight(1)
+	require.NoError(err)
+	require.Equal(uint32(2), meta.Round)
+
+	for _, height := range []uint64{0, svr.bc.TipHeight() + 1} {
+		_, err = svr.ConsensusMetaByHeight(height)
+		require.Equal(codes.NotFound, status.Code(err))
+	}
+}
+
 func TestServer_GetChainMeta(t *testing.T) {
 	require := require.New(t)
 	cfg := newConfig()
@@ -1459,6 +1564,66 @@ func TestServer_GetLogs(t *testing.T) {
 	}
 }
 
+func TestServer_GetLogsByBlock(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mbc := mock_blockchain.NewMockBlockchain(ctrl)
+	svr := Server{bc: mbc}
+	topic := hash.Hash256b([]byte("topic"))
+	newBlock := func(height uint64, topic hash.Hash256) (hash.Hash256, []*action.Receipt) {
+		receipts := []*action.Receipt{{
+			Logs: []*action.Log{{
+				Address:     identityset.Address(27).String(),
+				Topics:      []hash.Hash256{topic},
+				BlockHeight: height,
+			}},
+		}}
+		ra := block.NewRunnableActionsBuilder().SetHeight(height).Build(identityset.PrivateKey(27).PublicKey())
+		blk, err := block.NewBuilder(ra).
+			SetReceipts(receipts).
+			SetLogsBloom((&block.Block{Receipts: receipts}).ComputeLogsBloom(block.LogsBloomKeys{})).
+			SignAndBuild(identityset.PrivateKey(27))
+		require.NoError(err)
+		mbc.EXPECT().BlockHeaderByHash(blk.HashBlock()).Return(&blk.Header, nil).Times(1)
+		return blk.HashBlock(), receipts
+	}
+	matching, receipts := newBlock(1, topic)
+	mbc.EXPECT().GetReceiptsByBlockHash(matching).Return(receipts, nil).Times(1)
+	// the receipts of the block whose bloom filter excludes the topic are not read
+	excluded, _ := newBlock(2, hash.Hash256b([]byte("another topic")))
+	mbc.EXPECT().GetReceiptsByBlockHash(excluded).Times(0)
+
+	for _, test := range []struct {
+		blkHash hash.Hash256
+		numLogs int
+	}{
+		{matching, 1},
+		{excluded, 0},
+	} {
+		res, err := svr.GetLogs(context.Background(), &iotexapi.GetLogsRequest{
+			Filter: &iotexapi.LogsFilter{
+				Topics: []*iotexapi.Topics{{Topic: [][]byte{topic[:]}}},
+			},
+			Lookup: &iotexapi.GetLogsRequest_ByBlock{
+				ByBlock: &iotexapi.GetLogsByBlock{BlockHash: test.blkHash[:]},
+			},
+		})
+		require.NoError(err)
+		require.Equal(test.numLogs, len(res.Logs))
+	}
+
+	mbc.EXPECT().BlockHeaderByHash(gomock.Any()).Return(nil, errors.New("not exist")).Times(1)
+	_, err := svr.GetLogs(context.Background(), &iotexapi.GetLogsRequest{
+		Filter: &iotexapi.LogsFilter{},
+		Lookup: &iotexapi.GetLogsRequest_ByBlock{
+			ByBlock: &iotexapi.GetLogsByBlock{BlockHash: hash.ZeroHash256[:]},
+		},
+	})
+	require.Error(err)
+}
+
 func addProducerToFactory(sf factory.Factory) error {
 	ws, err := sf.NewWorkingSet()
 	if err != nil {
@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 
+	"github.com/iotexproject/go-pkgs/hash"
 	"github.com/iotexproject/iotex-proto/golang/iotexapi"
 	"github.com/iotexproject/iotex-proto/golang/iotextypes"
 	"go.uber.org/zap"
@@ -77,6 +78,19 @@ func (l *LogFilter) MatchLogs(receipts []*action.Receipt) []*iotextypes.Log {
 	return logs
 }
 
+// bloomKeys returns the topics every matching log has, i.e., the ones of the positions matching a single topic, which
+// a block holding any matching log has in its logs bloom filter
+func (l *LogFilter) bloomKeys() []hash.Hash256 {
+	var keys []hash.Hash256
+	for _, e := range l.Topics {
+		if e == nil || len(e.Topic) != 1 || len(e.Topic[0]) != len(hash.ZeroHash256) {
+			continue
+		}
+		keys = append(keys, hash.BytesToHash256(e.Topic[0]))
+	}
+	return keys
+}
+
 // match checks if a given log matches the filter
 func (l *LogFilter) match(log *iotextypes.Log) bool {
 	addrMatch := len(l.Address) == 0
@@ -156,3 +156,24 @@ func TestLogFilter_MatchBlock(t *testing.T) {
 		}
 	}
 }
+
+func TestLogFilter_BloomKeys(t *testing.T) {
+	require := require.New(t)
+
+	expected := [][]hash.Hash256{nil, nil, nil, nil, nil}
+	for i, q := range testFilter {
+		f, ok := NewLogFilter(q, nil, nil).(*LogFilter)
+		require.True(ok)
+		require.Equal(expected[i], f.bloomKeys())
+	}
+	f, ok := NewLogFilter(&iotexapi.LogsFilter{
+		Topics: []*iotexapi.Topics{
+			nil,
+			&iotexapi.Topics{Topic: [][]byte{topic1[:]}},
+			&iotexapi.Topics{Topic: [][]byte{topicA[:], topicB[:]}},
+			&iotexapi.Topics{Topic: [][]byte{topic2[:]}},
+		},
+	}, nil, nil).(*LogFilter)
+	require.True(ok)
+	require.Equal([]hash.Hash256{topic1, topic2}, f.bloomKeys())
+}
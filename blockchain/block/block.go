@@ -11,11 +11,15 @@ import (
 	"time"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/iotex-address/address"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 
+	"github.com/iotexproject/go-pkgs/bloom"
 	"github.com/iotexproject/go-pkgs/hash"
 	"github.com/iotexproject/iotex-core/action"
 	"github.com/iotexproject/iotex-core/endorsement"
+	iobloom "github.com/iotexproject/iotex-core/pkg/bloom"
 	"github.com/iotexproject/iotex-core/pkg/log"
 	"github.com/iotexproject/iotex-core/state/factory"
 	"github.com/iotexproject/iotex-proto/golang/iotextypes"
@@ -30,6 +34,9 @@ type Block struct {
 	// TODO: move receipts out of block struct
 	Receipts   []*action.Receipt
 	WorkingSet factory.WorkingSet
+
+	// serializedSize caches the size of the serialized block, 0 if not computed yet
+	serializedSize int
 }
 
 // ConvertToBlockHeaderPb converts BlockHeader to BlockHeader
@@ -55,8 +62,18 @@ func (b *Block) Serialize() ([]byte, error) {
 	return proto.Marshal(b.ConvertToBlockPb())
 }
 
+// SerializedSize returns the size of the serialized block, which is computed once and cached until the block is
+// finalized
+func (b *Block) SerializedSize() int {
+	if b.serializedSize == 0 {
+		b.serializedSize = proto.Size(b.ConvertToBlockPb())
+	}
+	return b.serializedSize
+}
+
 // ConvertFromBlockPb converts Block to Block
 func (b *Block) ConvertFromBlockPb(pbBlock *iotextypes.Block) error {
+	b.serializedSize = 0
 	b.Header = Header{}
 	if err := b.Header.LoadFromBlockHeaderProto(pbBlock.GetHeader()); err != nil {
 		return err
@@ -109,6 +126,89 @@ func (b *Block) VerifyReceiptRoot(root hash.Hash256) error {
 	return nil
 }
 
+// LogsBloomKeys tells the keys added into the logs bloom filter of a block besides the log topics, each of which is
+// added from its own height
+type LogsBloomKeys struct {
+	// LogAddresses is whether the address of the contract emitting each log is added
+	LogAddresses bool
+	// ActionAddresses is whether the sender and recipient of each action are added, such that MayContainAddress tells
+	// the blocks an address is active in
+	ActionAddresses bool
+}
+
+// ComputeLogsBloom returns the bloom filter of the logs in the receipts of the block, which holds each of the log
+// topics along with the keys given. Both minting and validating a block derive the filter from it.
+func (b *Block) ComputeLogsBloom(keys LogsBloomKeys) bloom.BloomFilter {
+	f, err := bloom.NewBloomFilter(logsBloomNumBits, logsBloomNumHash)
+	if err != nil {
+		log.L().Panic("failed to create logs bloom filter", zap.Error(err))
+	}
+	b.addToBloom(f, keys)
+	return f
+}
+
+// addToBloom adds each of the log topics and the keys given into the filter
+func (b *Block) addToBloom(f bloom.BloomFilter, keys LogsBloomKeys) {
+	b.addLogsToBloom(f, keys.LogAddresses)
+	if keys.ActionAddresses {
+		b.addActionsToBloom(f)
+	}
+}
+
+// addLogsToBloom adds each of the log topics into the filter, along with the address of the contract emitting the log
+// if withAddresses
+func (b *Block) addLogsToBloom(f bloom.BloomFilter, withAddresses bool) {
+	for _, receipt := range b.Receipts {
+		for _, l := range receipt.Logs {
+			if withAddresses {
+				if addr, err := address.FromString(l.Address); err == nil {
+					f.Add(addr.Bytes())
+				}
+			}
+			for _, topic := range l.Topics {
+				f.Add(topic[:])
+			}
+		}
+	}
+}
+
+// addActionsToBloom adds the sender and recipient of each action into the filter
+func (b *Block) addActionsToBloom(f bloom.BloomFilter) {
+	for _, selp := range b.Actions {
+		if sender, err := address.FromBytes(selp.SrcPubkey().Hash()); err == nil {
+			f.Add(addressBloomKey(sender))
+		}
+		dst, ok := selp.Destination()
+		if !ok {
+			continue
+		}
+		if recipient, err := address.FromString(dst); err == nil {
+			f.Add(addressBloomKey(recipient))
+		}
+	}
+}
+
+// VerifyLogsBloom verifies the logs bloom filter in header against the receipts, along with the actions if the
+// addresses of the actions are in the filter
+func (b *Block) VerifyLogsBloom(keys LogsBloomKeys) error {
+	// the expected filter is only compared against, hence borrowed from the pool
+	expected := iobloom.GetBloomFilter()
+	defer iobloom.PutBloomFilter(expected)
+	return b.verifyLogsBloom(expected, keys)
+}
+
+// verifyLogsBloom adds the receipts of the block into the empty filter expected, and compares it to the header's
+func (b *Block) verifyLogsBloom(expected bloom.BloomFilter, keys LogsBloomKeys) error {
+	if b.Header.logsBloom == nil {
+		return errors.New("logs bloom filter is missing")
+	}
+	b.addToBloom(expected, keys)
+	if !bytes.Equal(b.Header.logsBloom.Bytes(), expected.Bytes()) {
+		return errors.New("logs bloom filter does not match")
+	}
+	return nil
+}
+
 // RunnableActions abstructs RunnableActions from a Block.
 func (b *Block) RunnableActions() RunnableActions {
 	return RunnableActions{
@@ -127,6 +227,7 @@ func (b *Block) Finalize(endorsements []*endorsement.Endorsement, ts time.Time)
 	}
 	b.endorsements = endorsements
 	b.commitTime = ts
+	b.serializedSize = 0
 
 	return nil
 }
@@ -20,6 +20,8 @@ import (
 
 	"github.com/iotexproject/go-pkgs/hash"
 	"github.com/iotexproject/iotex-core/action"
+	"github.com/iotexproject/iotex-core/endorsement"
+	iobloom "github.com/iotexproject/iotex-core/pkg/bloom"
 	"github.com/iotexproject/iotex-core/pkg/compress"
 	"github.com/iotexproject/iotex-core/pkg/log"
 	"github.com/iotexproject/iotex-core/pkg/unit"
@@ -213,3 +215,172 @@ func makeBlock(tb testing.TB, n int) *Block {
 	require.NoError(tb, err)
 	return &blk
 }
+
+func TestComputeLogsBloom(t *testing.T) {
+	require := require.New(t)
+	topics := []hash.Hash256{
+		hash.Hash256b([]byte("Set(uint256)")),
+		hash.Hash256b([]byte("Get(address,uint256)")),
+		hash.Hash256b([]byte("topic")),
+	}
+	logs := []*action.Log{
+		{Address: identityset.Address(0).String(), Topics: topics[:2]},
+		{Address: identityset.Address(1).String(), Topics: topics[2:]},
+		{Address: identityset.Address(1).String(), Topics: topics[:1]},
+	}
+	blk, err := NewTestingBuilder().
+		SetHeight(1).
+		SetReceipts([]*action.Receipt{{Logs: logs[:2]}, {Logs: logs[2:]}}).
+		SignAndBuild(identityset.PrivateKey(27))
+	require.NoError(err)
+
+	// only the topics are in the filter unless the log addresses are added
+	f := blk.ComputeLogsBloom(LogsBloomKeys{})
+	for _, topic := range topics {
+		require.True(f.Exist(topic[:]))
+	}
+	for i := 0; i < 2; i++ {
+		require.False(f.Exist(identityset.Address(i).Bytes()))
+	}
+	withAddresses := LogsBloomKeys{LogAddresses: true}
+	f2 := blk.ComputeLogsBloom(withAddresses)
+	for _, topic := range topics {
+		require.True(f2.Exist(topic[:]))
+	}
+	// the contract addresses are added in bytes
+	for i := 0; i < 2; i++ {
+		require.True(f2.Exist(identityset.Address(i).Bytes()))
+	}
+	require.Equal(f2.Bytes(), blk.ComputeLogsBloom(withAddresses).Bytes())
+
+	// the filter of the block header is verified against the receipts
+	require.Error(blk.VerifyLogsBloom(LogsBloomKeys{}))
+	blk.Header.logsBloom = f
+	require.NoError(blk.VerifyLogsBloom(LogsBloomKeys{}))
+	require.Error(blk.VerifyLogsBloom(withAddresses))
+	blk.Header.logsBloom = f2
+	require.NoError(blk.VerifyLogsBloom(withAddresses))
+	require.Error(blk.VerifyLogsBloom(LogsBloomKeys{}))
+	blk.Receipts = blk.Receipts[1:]
+	require.Error(blk.VerifyLogsBloom(withAddresses))
+}
+
+func BenchmarkLogsBloom(b *testing.B) {
+	// a block of transfers to a contract emitting a few events each
+	receipts := make([]*action.Receipt, 100)
+	for i := range receipts {
+		topics := make([]hash.Hash256, 4)
+		for j := range topics {
+			topics[j] = hash.Hash256b([]byte(fmt.Sprintf("topic-%d-%d", i, j)))
+		}
+		receipts[i] = &action.Receipt{Logs: []*action.Log{{Address: identityset.Address(i % identityset.Size()).String(), Topics: topics}}}
+	}
+	blk := makeBlock(b, len(receipts))
+	blk.Receipts = receipts
+	keys := LogsBloomKeys{LogAddresses: true, ActionAddresses: true}
+	// mint computes the filter of the header, which is then verified when the receipts are indexed
+	mint := func() {
+		blk.Header.logsBloom = blk.ComputeLogsBloom(keys)
+	}
+	b.Run("new-filter", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			mint()
+			expected, err := iobloom.NewBloomFilter(logsBloomNumBits, logsBloomNumHash)
+			require.NoError(b, err)
+			require.NoError(b, blk.verifyLogsBloom(expected, keys))
+		}
+	})
+	b.Run("pool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			mint()
+			require.NoError(b, blk.VerifyLogsBloom(keys))
+		}
+	})
+}
+
+func TestComputeLogsBloomWithAddresses(t *testing.T) {
+	require := require.New(t)
+	topic := hash.Hash256b([]byte("topic"))
+	logs := []*action.Log{{Address: identityset.Address(2).String(), Topics: []hash.Hash256{topic}}}
+	tsf0, err := testutil.SignedTransfer(identityset.Address(1).String(), identityset.PrivateKey(0), 1, big.NewInt(1), nil, 100, big.NewInt(0))
+	require.NoError(err)
+	tsf1, err := testutil.SignedTransfer(identityset.Address(3).String(), identityset.PrivateKey(1), 1, big.NewInt(1), nil, 100, big.NewInt(0))
+	require.NoError(err)
+	blk, err := NewTestingBuilder().
+		SetHeight(1).
+		AddActions(tsf0, tsf1).
+		SetReceipts([]*action.Receipt{{Logs: logs}}).
+		SignAndBuild(identityset.PrivateKey(27))
+	require.NoError(err)
+
+	// a block without the filter may contain any address
+	require.True(blk.MayContainAddress(identityset.Address(4)))
+	withLogAddresses := LogsBloomKeys{LogAddresses: true}
+	blk.Header.logsBloom = blk.ComputeLogsBloom(withLogAddresses)
+	for i := 0; i < 4; i++ {
+		require.False(blk.MayContainAddress(identityset.Address(i)))
+	}
+	require.NoError(blk.VerifyLogsBloom(withLogAddresses))
+	withAddresses := LogsBloomKeys{LogAddresses: true, ActionAddresses: true}
+	require.Error(blk.VerifyLogsBloom(withAddresses))
+
+	f := blk.ComputeLogsBloom(withAddresses)
+	// the logs are still in the filter
+	require.True(f.Exist(topic[:]))
+	require.True(f.Exist(identityset.Address(2).Bytes()))
+	blk.Header.logsBloom = f
+	require.NoError(blk.VerifyLogsBloom(withAddresses))
+	require.Error(blk.VerifyLogsBloom(withLogAddresses))
+	// the senders and recipients, but not the contract emitting the log
+	for _, i := range []int{0, 1, 3} {
+		require.True(blk.MayContainAddress(identityset.Address(i)))
+	}
+	require.False(blk.MayContainAddress(identityset.Address(2)))
+	require.False(blk.MayContainAddress(identityset.Address(4)))
+	// the header read from the proto tells the same
+	var header Header
+	require.NoError(header.LoadFromBlockHeaderProto(blk.Header.BlockHeaderProto()))
+	require.True(header.MayContainAddress(identityset.Address(3)))
+	require.False(header.MayContainAddress(identityset.Address(4)))
+}
+
+func TestSerializedSize(t *testing.T) {
+	require := require.New(t)
+	tsf, err := testutil.SignedTransfer(
+		identityset.Address(28).String(), identityset.PrivateKey(27), 1, big.NewInt(10), nil, 100, big.NewInt(0),
+	)
+	require.NoError(err)
+	blk, err := NewTestingBuilder().
+		SetHeight(1).
+		SetTimeStamp(testutil.TimestampNow()).
+		AddActions(tsf).
+		SignAndBuild(identityset.PrivateKey(27))
+	require.NoError(err)
+	serialized, err := blk.Serialize()
+	require.NoError(err)
+	require.Equal(len(serialized), blk.SerializedSize())
+
+	// the size is cached
+	blk.Actions = append(blk.Actions, tsf)
+	require.Equal(len(serialized), blk.SerializedSize())
+	blk.Actions = blk.Actions[:1]
+
+	// and computed again once finalized, which adds the footer
+	en := endorsement.NewEndorsement(testutil.TimestampNow(), identityset.PrivateKey(27).PublicKey(), []byte("signature"))
+	require.NoError(blk.Finalize([]*endorsement.Endorsement{en}, testutil.TimestampNow()))
+	serialized, err = blk.Serialize()
+	require.NoError(err)
+	require.Equal(len(serialized), blk.SerializedSize())
+
+	// as well as once loaded from the proto
+	var loaded Block
+	require.NoError(loaded.Deserialize(serialized))
+	require.Equal(len(serialized), loaded.SerializedSize())
+	require.NoError(loaded.ConvertFromBlockPb(&iotextypes.Block{
+		Header: blk.ConvertToBlockHeaderPb(),
+		Body:   &iotextypes.BlockBody{},
+	}))
+	require.True(loaded.SerializedSize() < len(serialized))
+}
@@ -23,6 +23,13 @@ import (
 	"github.com/iotexproject/iotex-core/pkg/util/byteutil"
 )
 
+const (
+	// logsBloomNumBits is the number of bits of the logs bloom filter
+	logsBloomNumBits = 2048
+	// logsBloomNumHash is the number of hash functions of the logs bloom filter
+	logsBloomNumHash = 3
+)
+
 // Header defines the struct of block header
 // make sure the variable type and order of this struct is same as "BlockHeaderPb" in blockchain.pb.go
 type Header struct {
@@ -68,6 +75,31 @@ func (h *Header) HashBlock() hash.Hash256 { return h.HashHeader() }
 // LogsBloomfilter return the bloom filter for all contract log events
 func (h *Header) LogsBloomfilter() bloom.BloomFilter { return h.logsBloom }
 
+// MayContainAddress returns whether any action of the block may be sent from or to the address, with false positives
+// but no false negatives, given the block is minted after the activation of the action address bloom. A block without
+// the logs bloom filter may contain any address.
+func (h *Header) MayContainAddress(addr address.Address) bool {
+	if h.logsBloom == nil {
+		return true
+	}
+	return h.logsBloom.Exist(addressBloomKey(addr))
+}
+
+// MayContainLogKeys returns whether the logs of the block may hold all the keys, e.g., the log topics, with false
+// positives but no false negatives. A block without the logs bloom filter may hold any key, so that the receipts of a
+// block only need to be read if it returns true.
+func (h *Header) MayContainLogKeys(keys []hash.Hash256) bool {
+	if h.logsBloom == nil {
+		return true
+	}
+	for _, k := range keys {
+		if !h.logsBloom.Exist(k[:]) {
+			return false
+		}
+	}
+	return true
+}
+
 // BlockHeaderProto returns BlockHeader proto.
 func (h *Header) BlockHeaderProto() *iotextypes.BlockHeader {
 	return &iotextypes.BlockHeader{
@@ -127,7 +159,7 @@ func (h *Header) loadFromBlockHeaderCoreProto(pb *iotextypes.BlockHeaderCore) er
 	copy(h.deltaStateDigest[:], pb.GetDeltaStateDigest())
 	copy(h.receiptRoot[:], pb.GetReceiptRoot())
 	if pb.GetLogsBloom() != nil {
-		h.logsBloom, err = bloom.BloomFilterFromBytes(pb.GetLogsBloom(), 2048, 3)
+		h.logsBloom, err = bloom.BloomFilterFromBytes(pb.GetLogsBloom(), logsBloomNumBits, logsBloomNumHash)
 	}
 	return err
 }
@@ -189,3 +221,10 @@ func (h *Header) HeaderLogger(l *zap.Logger) *zap.Logger {
 		log.Hex("deltaStateDigest", h.deltaStateDigest[:]),
 	)
 }
+
+// addressBloomKey returns the key of an address in the logs bloom filter, which is the hash of the address bytes such
+// that it is never mistaken for the address of a contract emitting a log
+func addressBloomKey(addr address.Address) []byte {
+	h := hash.Hash256b(addr.Bytes())
+	return h[:]
+}
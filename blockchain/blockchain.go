@@ -17,6 +17,7 @@ import (
 
 	"github.com/facebookgo/clock"
 	"github.com/iotexproject/go-pkgs/bloom"
+	"github.com/iotexproject/go-pkgs/crypto"
 	"github.com/iotexproject/go-pkgs/hash"
 	"github.com/iotexproject/iotex-address/address"
 	"github.com/pkg/errors"
@@ -36,7 +37,7 @@ import (
 	"github.com/iotexproject/iotex-core/actpool/actioniterator"
 	"github.com/iotexproject/iotex-core/blockchain/block"
 	"github.com/iotexproject/iotex-core/config"
-	"github.com/iotexproject/iotex-core/crypto"
+	cp "github.com/iotexproject/iotex-core/crypto"
 	"github.com/iotexproject/iotex-core/db"
 	"github.com/iotexproject/iotex-core/pkg/lifecycle"
 	"github.com/iotexproject/iotex-core/pkg/log"
@@ -114,8 +115,20 @@ type Blockchain interface {
 	GetBlockHashByActionHash(h hash.Hash256) (hash.Hash256, error)
 	// GetReceiptsByHeight returns action receipts by block height
 	GetReceiptsByHeight(height uint64) ([]*action.Receipt, error)
+	// GetReceiptsByBlockHash returns action receipts by block hash. The header of the block, which is cheaper to read,
+	// tells by MayContainLogKeys whether the receipts are worth reading for the logs.
+	GetReceiptsByBlockHash(h hash.Hash256) ([]*action.Receipt, error)
+	// BlocksMatchingBloom returns the heights in [start, end] of which the logs bloom filter may hold all the keys,
+	// such that the receipts of the other blocks don't have to be read. The candidates have to be verified against the
+	// actual logs, because of false positives
+	BlocksMatchingBloom(start, end uint64, keys []hash.Hash256) ([]uint64, error)
+	// ConsensusMetaByHeight returns the consensus metadata of the block of a height, of which the round is unknown if
+	// the block wasn't committed by the local consensus
+	ConsensusMetaByHeight(height uint64) (*ConsensusMeta, error)
 	// GetFactory returns the state factory
 	GetFactory() factory.Factory
+	// KVStore returns the KV store of the chain DB
+	KVStore() db.KVStore
 	// GetChainID returns the chain ID
 	ChainID() uint32
 	// ChainAddress returns chain address on parent chain, the root chain return empty.
@@ -138,8 +151,18 @@ type Blockchain interface {
 		actionMap map[string][]action.SealedEnvelope,
 		timestamp time.Time,
 	) (*block.Block, error)
+	// MintNewBlockWithActionIterator creates a new block with the actions picked in the order of the iterator
+	MintNewBlockWithActionIterator(
+		actionIterator actioniterator.ActionIterator,
+		timestamp time.Time,
+	) (*block.Block, error)
+	// SetProducerPrivateKey sets the private key signing the blocks minted afterwards, in place of the configured one
+	SetProducerPrivateKey(sk crypto.PrivateKey)
 	// CommitBlock validates and appends a block to the chain
 	CommitBlock(blk *block.Block) error
+	// PutConsensusMeta records the consensus metadata of a committed block in place of the one recorded on the commit,
+	// which is called by the consensus committing the block
+	PutConsensusMeta(meta *ConsensusMeta) error
 	// ValidateBlock validates a new block before adding it to the blockchain
 	ValidateBlock(blk *block.Block) error
 
@@ -153,27 +176,36 @@ type Blockchain interface {
 	// ExecuteContractRead runs a read-only smart contract operation, this is done off the network since it does not
 	// cause any state change
 	ExecuteContractRead(caller address.Address, ex *action.Execution) ([]byte, *action.Receipt, error)
+	// SimulateExecution runs an action against the state of the tip without committing it, and returns its return
+	// value and receipt. Neither the state nor the action pool is changed.
+	SimulateExecution(caller address.Address, act action.Action) ([]byte, *action.Receipt, error)
 
 	// AddSubscriber make you listen to every single produced block
 	AddSubscriber(BlockCreationSubscriber) error
 
 	// RemoveSubscriber make you listen to every single produced block
 	RemoveSubscriber(BlockCreationSubscriber) error
+	// SubscribeBlockCommit returns a channel receiving each block right after it is committed, either by the consensus
+	// or by the block sync, in the order of the heights. A block is dropped if the channel of the given buffer size is
+	// full. The returned func unsubscribes and closes the channel, and is safe to call more than once.
+	SubscribeBlockCommit(buffer int) (<-chan *block.Block, func())
 	// GetActionHashFromIndex returns action hash from index
 	GetActionHashFromIndex(index uint64) (hash.Hash256, error)
 }
 
 // blockchain implements the Blockchain interface
 type blockchain struct {
-	mu            sync.RWMutex // mutex to protect utk, tipHeight and tipHash
+	mu            sync.RWMutex // mutex to protect utk, tipHeight, tipHash and producerKey
 	dao           *blockDAO
 	config        config.Config
+	producerKey   crypto.PrivateKey
 	tipHeight     uint64
 	tipHash       hash.Hash256
 	validator     Validator
 	lifecycle     lifecycle.Lifecycle
 	clk           clock.Clock
 	blocklistener []BlockCreationSubscriber
+	commitFeed    *blockCommitFeed
 	timerFactory  *prometheustimer.TimerFactory
 
 	// used by account-based model
@@ -233,13 +265,14 @@ func PrecreatedDaoOption(dao *blockDAO) Option {
 	}
 }
 
-// BoltDBDaoOption sets blockchain's dao with BoltDB from config.Chain.ChainDBPath
+// BoltDBDaoOption sets blockchain's dao with the KV store of config.DB.Backend (BoltDB by default) at
+// config.Chain.ChainDBPath
 func BoltDBDaoOption() Option {
 	return func(bc *blockchain, cfg config.Config) error {
 		cfg.DB.DbPath = cfg.Chain.ChainDBPath // TODO: remove this after moving TrieDBPath from cfg.Chain to cfg.DB
 		_, gateway := cfg.Plugins[config.GatewayPlugin]
 		bc.dao = newBlockDAO(
-			db.NewBoltDB(cfg.DB),
+			db.NewKVStore(cfg.DB),
 			gateway && !cfg.Chain.EnableAsyncIndexWrite,
 			cfg.Chain.CompressBlock,
 			cfg.Chain.MaxCacheSize,
@@ -294,8 +327,9 @@ func EnableExperimentalActions() Option {
 func NewBlockchain(cfg config.Config, opts ...Option) Blockchain {
 	// create the Blockchain
 	chain := &blockchain{
-		config: cfg,
-		clk:    clock.New(),
+		config:     cfg,
+		clk:        clock.New(),
+		commitFeed: newBlockCommitFeed(),
 	}
 	for _, opt := range opts {
 		if err := opt(chain, cfg); err != nil {
@@ -489,6 +523,27 @@ func (bc *blockchain) BlockFooterByHash(h hash.Hash256) (*block.Footer, error) {
 	return bc.dao.Footer(h)
 }
 
+// ConsensusMetaByHeight returns the consensus metadata of the block of a height. The metadata of the blocks committed
+// before it was recorded is made of the blocks, with the round unknown.
+func (bc *blockchain) ConsensusMetaByHeight(height uint64) (*ConsensusMeta, error) {
+	if height > bc.TipHeight() {
+		return nil, errors.Wrapf(db.ErrNotExist, "block with height %d is not committed", height)
+	}
+	meta, err := bc.dao.getConsensusMeta(height)
+	if errors.Cause(err) != db.ErrNotExist {
+		return meta, err
+	}
+	header, err := bc.blockHeaderByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	footer, err := bc.blockFooterByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	return newUnknownRoundConsensusMeta(header, footer), nil
+}
+
 // GetTotalActions returns the total number of actions
 func (bc *blockchain) GetTotalActions() (uint64, error) {
 	return bc.dao.getTotalActions()
@@ -582,11 +637,50 @@ func (bc *blockchain) GetReceiptsByHeight(height uint64) ([]*action.Receipt, err
 	return bc.dao.getReceipts(height)
 }
 
+// GetReceiptsByBlockHash returns action receipts by block hash
+func (bc *blockchain) GetReceiptsByBlockHash(h hash.Hash256) ([]*action.Receipt, error) {
+	height, err := bc.dao.getBlockHeight(h)
+	if err != nil {
+		return nil, err
+	}
+	return bc.dao.getReceipts(height)
+}
+
+// BlocksMatchingBloom returns the heights in [start, end] of which the logs bloom filter may hold all the keys. A block
+// without the filter, i.e., one before the Aleutian height, is always a candidate.
+func (bc *blockchain) BlocksMatchingBloom(start, end uint64, keys []hash.Hash256) ([]uint64, error) {
+	if start == 0 {
+		start = 1
+	}
+	if start > end {
+		return nil, errors.Errorf("invalid range [%d, %d]", start, end)
+	}
+	if tip := bc.TipHeight(); end > tip {
+		return nil, errors.Errorf("end height %d is beyond the tip %d", end, tip)
+	}
+	var heights []uint64
+	for height := start; height <= end; height++ {
+		header, err := bc.blockHeaderByHeight(height)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the header of block %d", height)
+		}
+		if header.MayContainLogKeys(keys) {
+			heights = append(heights, height)
+		}
+	}
+	return heights, nil
+}
+
 // GetFactory returns the state factory
 func (bc *blockchain) GetFactory() factory.Factory {
 	return bc.sf
 }
 
+// KVStore returns the KV store of the chain DB
+func (bc *blockchain) KVStore() db.KVStore {
+	return bc.dao.kvstore
+}
+
 // TipHash returns tip block's hash
 func (bc *blockchain) TipHash() hash.Hash256 {
 	bc.mu.RLock()
@@ -611,6 +705,13 @@ func (bc *blockchain) ValidateBlock(blk *block.Block) error {
 func (bc *blockchain) MintNewBlock(
 	actionMap map[string][]action.SealedEnvelope,
 	timestamp time.Time,
+) (*block.Block, error) {
+	return bc.MintNewBlockWithActionIterator(actioniterator.NewActionIterator(actionMap), timestamp)
+}
+
+func (bc *blockchain) MintNewBlockWithActionIterator(
+	actionIterator actioniterator.ActionIterator,
+	timestamp time.Time,
 ) (*block.Block, error) {
 	bc.mu.RLock()
 	defer bc.mu.RUnlock()
@@ -624,12 +725,17 @@ func (bc *blockchain) MintNewBlock(
 		return nil, errors.Wrap(err, "Failed to obtain working set from state factory")
 	}
 
+	sk := bc.producerPrivateKey()
+	producer, err := address.FromBytes(sk.PublicKey().Hash())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the producer address")
+	}
 	gasLimitForContext := bc.config.Genesis.BlockGasLimit
 	ctx := protocol.WithRunActionsCtx(context.Background(),
 		protocol.RunActionsCtx{
 			BlockHeight:    newblockHeight,
 			BlockTimeStamp: timestamp,
-			Producer:       bc.config.ProducerAddress(),
+			Producer:       producer,
 			GasLimit:       gasLimitForContext,
 			Registry:       bc.registry,
 		})
@@ -639,14 +745,13 @@ func (bc *blockchain) MintNewBlock(
 			return nil, err
 		}
 	}
-	_, rc, actions, err := bc.pickAndRunActions(ctx, actionMap, ws)
+	_, rc, actions, err := bc.pickAndRunActions(ctx, actionIterator, ws)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to update state changes in new block %d", newblockHeight)
 	}
 
 	blockMtc.WithLabelValues("numActions").Set(float64(len(actions)))
 
-	sk := bc.config.ProducerPrivateKey()
 	ra := block.NewRunnableActionsBuilder().
 		SetHeight(newblockHeight).
 		SetTimeStamp(timestamp).
@@ -664,7 +769,7 @@ func (bc *blockchain) MintNewBlock(
 		SetDeltaStateDigest(ws.Digest()).
 		SetReceipts(rc).
 		SetReceiptRoot(calculateReceiptRoot(rc)).
-		SetLogsBloom(calculateLogsBloom(bc.config, newblockHeight, rc)).
+		SetLogsBloom(calculateLogsBloom(bc.config, newblockHeight, actions, rc)).
 		SignAndBuild(sk)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create block")
@@ -684,6 +789,29 @@ func (bc *blockchain) CommitBlock(blk *block.Block) error {
 	return bc.commitBlock(blk)
 }
 
+// PutConsensusMeta records the consensus metadata of a committed block
+func (bc *blockchain) PutConsensusMeta(meta *ConsensusMeta) error {
+	if meta == nil {
+		return errors.New("consensus meta is nil")
+	}
+	// hold the lock such that the block is not deleted by a recovery meanwhile
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	blkHash, err := bc.dao.getBlockHash(meta.Height)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the block with height %d", meta.Height)
+	}
+	if blkHash != meta.BlockHash {
+		return errors.Errorf(
+			"block %x of height %d is committed in place of %x",
+			blkHash,
+			meta.Height,
+			meta.BlockHash,
+		)
+	}
+	return bc.dao.putConsensusMeta(meta)
+}
+
 // StateByAddr returns the account of an address
 func (bc *blockchain) StateByAddr(address string) (*state.Account, error) {
 	if bc.sf != nil {
@@ -736,6 +864,10 @@ func (bc *blockchain) RemoveSubscriber(s BlockCreationSubscriber) error {
 	return errors.New("cannot find subscription")
 }
 
+func (bc *blockchain) SubscribeBlockCommit(buffer int) (<-chan *block.Block, func()) {
+	return bc.commitFeed.subscribe(buffer)
+}
+
 //======================================
 // internal functions
 //=====================================
@@ -777,6 +909,60 @@ func (bc *blockchain) ExecuteContractRead(caller address.Address, ex *action.Exe
 	)
 }
 
+// SimulateExecution runs an action against the state of the tip without committing it
+func (bc *blockchain) SimulateExecution(caller address.Address, act action.Action) ([]byte, *action.Receipt, error) {
+	if ex, ok := act.(*action.Execution); ok {
+		return bc.ExecuteContractRead(caller, ex)
+	}
+	if bc.registry == nil {
+		return nil, nil, errors.New("protocol registry is empty")
+	}
+	header, err := bc.BlockHeaderByHeight(bc.TipHeight())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get block in SimulateExecution")
+	}
+	// the working set is discarded afterwards, hence the changes of the action are never committed
+	ws, err := bc.sf.NewWorkingSet()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to obtain working set from state factory")
+	}
+	producer, err := address.FromString(header.ProducerAddress())
+	if err != nil {
+		return nil, nil, err
+	}
+	raCtx := protocol.RunActionsCtx{
+		BlockHeight:    header.Height(),
+		BlockTimeStamp: header.Timestamp(),
+		Producer:       producer,
+		Caller:         caller,
+		GasLimit:       bc.config.Genesis.BlockGasLimit,
+		GasPrice:       big.NewInt(0),
+		Registry:       bc.registry,
+	}
+	if a, ok := act.(interface{ IntrinsicGas() (uint64, error) }); ok {
+		if raCtx.IntrinsicGas, err = a.IntrinsicGas(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if a, ok := act.(interface{ GasPrice() *big.Int }); ok {
+		raCtx.GasPrice = a.GasPrice()
+	}
+	if a, ok := act.(interface{ Nonce() uint64 }); ok {
+		raCtx.Nonce = a.Nonce()
+	}
+	ctx := protocol.WithRunActionsCtx(context.Background(), raCtx)
+	for _, p := range bc.registry.All() {
+		receipt, err := p.Handle(ctx, act, ws)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to simulate the action")
+		}
+		if receipt != nil {
+			return nil, receipt, nil
+		}
+	}
+	return nil, nil, errors.New("no protocol handles the action")
+}
+
 // CreateState adds a new account with initial balance to the factory
 func (bc *blockchain) CreateState(addr string, init *big.Int) (*state.Account, error) {
 	if bc.sf == nil {
@@ -995,6 +1181,12 @@ func (bc *blockchain) validateBlock(blk *block.Block) error {
 	}
 
 	blk.Receipts = receipts
+	// the blocks carry the logs bloom filter since the Aleutian height
+	if blk.Height() >= bc.config.Genesis.AleutianBlockHeight {
+		if err = blk.VerifyLogsBloom(logsBloomKeys(bc.config, blk.Height())); err != nil {
+			return errors.Wrap(err, "Failed to verify logs bloom filter")
+		}
+	}
 
 	// attach working set to be committed to state factory
 	blk.WorkingSet = ws
@@ -1047,6 +1239,8 @@ func (bc *blockchain) commitBlock(blk *block.Block) error {
 
 	// emit block to all block subscribers
 	bc.emitToSubscribers(blk)
+	// the blocks are published under the lock of the commit, hence in the order of the heights
+	bc.commitFeed.publish(blk)
 	return nil
 }
 
@@ -1081,7 +1275,7 @@ func (bc *blockchain) runActions(
 	return ws.RunActions(ctx, acts.BlockHeight(), acts.Actions())
 }
 
-func (bc *blockchain) pickAndRunActions(ctx context.Context, actionMap map[string][]action.SealedEnvelope,
+func (bc *blockchain) pickAndRunActions(ctx context.Context, actionIterator actioniterator.ActionIterator,
 	ws factory.WorkingSet) (hash.Hash256, []*action.Receipt, []action.SealedEnvelope, error) {
 	if bc.sf == nil {
 		return hash.ZeroHash256, nil, nil, errors.New("statefactory cannot be nil")
@@ -1092,8 +1286,6 @@ func (bc *blockchain) pickAndRunActions(ctx context.Context, actionMap map[strin
 
 	raCtx := protocol.MustGetRunActionsCtx(ctx)
 
-	// initial action iterator
-	actionIterator := actioniterator.NewActionIterator(actionMap)
 	for {
 		nextAction, ok := actionIterator.Next()
 		if !ok {
@@ -1248,7 +1440,7 @@ func (bc *blockchain) createPutPollResultAction(height uint64) (skip bool, se ac
 	default:
 		return
 	}
-	sk := bc.config.ProducerPrivateKey()
+	sk := bc.producerPrivateKey()
 	nonce := uint64(0)
 	pollAction := action.NewPutPollResult(nonce, nextEpochHeight, l)
 	builder := action.EnvelopeBuilder{}
@@ -1315,10 +1507,26 @@ func (bc *blockchain) createGrantRewardAction(rewardType int, height uint64) (ac
 		SetGasLimit(grant.GasLimit()).
 		SetAction(&grant).
 		Build()
-	sk := bc.config.ProducerPrivateKey()
+	sk := bc.producerPrivateKey()
 	return action.Sign(envelope, sk)
 }
 
+// SetProducerPrivateKey sets the private key signing the blocks minted afterwards, in place of the configured one
+func (bc *blockchain) SetProducerPrivateKey(sk crypto.PrivateKey) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	bc.producerKey = sk
+}
+
+// producerPrivateKey returns the private key signing the blocks, which must be called with the lock held
+func (bc *blockchain) producerPrivateKey() crypto.PrivateKey {
+	if bc.producerKey != nil {
+		return bc.producerKey
+	}
+	return bc.config.ProducerPrivateKey()
+}
+
 func (bc *blockchain) createGenesisStates(ws factory.WorkingSet) error {
 	if bc.registry == nil {
 		// TODO: return nil to avoid test cases to blame on missing rewarding protocol
@@ -1420,21 +1628,27 @@ func calculateReceiptRoot(receipts []*action.Receipt) hash.Hash256 {
 	for _, receipt := range receipts {
 		h = append(h, receipt.Hash())
 	}
-	res := crypto.NewMerkleTree(h).HashTree()
+	res := cp.NewMerkleTree(h).HashTree()
 	return res
 }
 
-func calculateLogsBloom(cfg config.Config, height uint64, receipts []*action.Receipt) bloom.BloomFilter {
+func calculateLogsBloom(
+	cfg config.Config,
+	height uint64,
+	actions []action.SealedEnvelope,
+	receipts []*action.Receipt,
+) bloom.BloomFilter {
 	if height < cfg.Genesis.AleutianBlockHeight {
 		return nil
 	}
-	bloom, _ := bloom.NewBloomFilter(2048, 3)
-	for _, receipt := range receipts {
-		for _, log := range receipt.Logs {
-			for _, topic := range log.Topics {
-				bloom.Add(topic[:])
-			}
-		}
+	blk := block.Block{Body: block.Body{Actions: actions}, Receipts: receipts}
+	return blk.ComputeLogsBloom(logsBloomKeys(cfg, height))
+}
+
+// logsBloomKeys returns the keys in the logs bloom filter of the block at the height besides the log topics
+func logsBloomKeys(cfg config.Config, height uint64) block.LogsBloomKeys {
+	return block.LogsBloomKeys{
+		LogAddresses:    cfg.Genesis.IsLogAddressBloomOn(height),
+		ActionAddresses: cfg.Genesis.IsActionAddressBloomOn(height),
 	}
-	return bloom
 }
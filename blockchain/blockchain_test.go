@@ -17,7 +17,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/iotexproject/go-pkgs/crypto"
 	"github.com/iotexproject/iotex-address/address"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 
@@ -33,6 +35,7 @@ import (
 	"github.com/iotexproject/iotex-core/blockchain/block"
 	"github.com/iotexproject/iotex-core/blockchain/genesis"
 	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/db"
 	"github.com/iotexproject/iotex-core/pkg/unit"
 	"github.com/iotexproject/iotex-core/state/factory"
 	"github.com/iotexproject/iotex-core/test/identityset"
@@ -1013,6 +1016,56 @@ func TestBlockchainInitialCandidate(t *testing.T) {
 	require.Equal(24, len(candidate))
 }
 
+func TestBlockchain_SimulateExecution(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	cfg := config.Default
+	cfg.Genesis.EnableGravityChainVoting = false
+	registry := protocol.Registry{}
+	acc := account.NewProtocol(config.NewHeightUpgrade(cfg))
+	require.NoError(registry.Register(account.ProtocolID, acc))
+	bc := NewBlockchain(cfg, InMemStateFactoryOption(), InMemDaoOption(), RegistryOption(&registry))
+	bc.GetFactory().AddActionHandlers(acc)
+	require.NoError(bc.Start(ctx))
+	defer func() {
+		require.NoError(bc.Stop(ctx))
+	}()
+	// the simulation runs on top of the tip block
+	blk, err := bc.MintNewBlock(nil, testutil.TimestampNow())
+	require.NoError(err)
+	require.NoError(bc.CommitBlock(blk))
+
+	sender := identityset.Address(27)
+	recipient := identityset.Address(28)
+	_, err = bc.CreateState(sender.String(), big.NewInt(1000))
+	require.NoError(err)
+	balance := func(addr address.Address) *big.Int {
+		s, err := bc.StateByAddr(addr.String())
+		require.NoError(err)
+		return s.Balance
+	}
+	senderBalance := balance(sender)
+	recipientBalance := balance(recipient)
+
+	tsf, err := action.NewTransfer(1, big.NewInt(100), recipient.String(), []byte{}, uint64(100000), big.NewInt(0))
+	require.NoError(err)
+	intrinsicGas, err := tsf.IntrinsicGas()
+	require.NoError(err)
+	_, receipt, err := bc.SimulateExecution(sender, tsf)
+	require.NoError(err)
+	require.Equal(uint64(iotextypes.ReceiptStatus_Success), receipt.Status)
+	require.Equal(intrinsicGas, receipt.GasConsumed)
+	// the balances are unchanged
+	require.Equal(senderBalance, balance(sender))
+	require.Equal(recipientBalance, balance(recipient))
+
+	// the transfer fails if the sender can't afford it
+	tsf, err = action.NewTransfer(1, big.NewInt(0).Add(senderBalance, big.NewInt(1)), recipient.String(), []byte{}, uint64(100000), big.NewInt(0))
+	require.NoError(err)
+	_, _, err = bc.SimulateExecution(sender, tsf)
+	require.Error(err)
+}
+
 func TestBlockchain_StateByAddr(t *testing.T) {
 	require := require.New(t)
 
@@ -1180,3 +1233,260 @@ func addCreatorToFactory(sf factory.Factory) error {
 	}
 	return sf.Commit(ws)
 }
+
+func TestBlocksMatchingBloom(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default
+	// the blocks before height 3 have no logs bloom filter
+	cfg.Genesis.AleutianBlockHeight = 3
+	ctx := context.Background()
+	bc := NewBlockchain(cfg, InMemDaoOption(), InMemStateFactoryOption())
+	require.NoError(bc.Start(ctx))
+	defer func() {
+		require.NoError(bc.Stop(ctx))
+	}()
+
+	topics := make([]hash.Hash256, 20)
+	for i := range topics {
+		topics[i] = hash.Hash256b([]byte(fmt.Sprintf("topic%d", i)))
+	}
+	// the block of height i has a log of topics i and i+10, and the one of height 7 has no log at all
+	for height := uint64(1); height <= 9; height++ {
+		var receipts []*action.Receipt
+		if height != 7 {
+			receipts = append(receipts, &action.Receipt{
+				Logs: []*action.Log{{
+					Address:     identityset.Address(0).String(),
+					Topics:      []hash.Hash256{topics[height], topics[height+10]},
+					BlockHeight: height,
+				}},
+			})
+		}
+		ra := block.NewRunnableActionsBuilder().
+			SetHeight(height).
+			SetTimeStamp(time.Unix(cfg.Genesis.Timestamp+int64(height), 0)).
+			Build(identityset.PrivateKey(0).PublicKey())
+		blk, err := block.NewBuilder(ra).
+			SetPrevBlockHash(bc.TipHash()).
+			SetReceipts(receipts).
+			SetLogsBloom(calculateLogsBloom(cfg, height, nil, receipts)).
+			SignAndBuild(identityset.PrivateKey(0))
+		require.NoError(err)
+		blk.WorkingSet, err = bc.GetFactory().NewWorkingSet()
+		require.NoError(err)
+		require.NoError(bc.CommitBlock(&blk))
+	}
+	require.Equal(uint64(9), bc.TipHeight())
+
+	contains := func(heights []uint64, height uint64) bool {
+		for _, h := range heights {
+			if h == height {
+				return true
+			}
+		}
+		return false
+	}
+	// false positives are allowed, but a block having all the keys is never left out
+	heights, err := bc.BlocksMatchingBloom(1, 9, []hash.Hash256{topics[5]})
+	require.NoError(err)
+	require.True(contains(heights, 5))
+	// the blocks without the filter are always candidates
+	require.True(contains(heights, 1))
+	require.True(contains(heights, 2))
+	require.True(len(heights) < 9)
+	heights, err = bc.BlocksMatchingBloom(3, 9, []hash.Hash256{topics[6], topics[16]})
+	require.NoError(err)
+	require.True(contains(heights, 6))
+	require.False(contains(heights, 7))
+	heights, err = bc.BlocksMatchingBloom(3, 9, []hash.Hash256{topics[6], topics[15]})
+	require.NoError(err)
+	require.False(contains(heights, 5))
+	require.False(contains(heights, 6))
+	// no key matches every block
+	heights, err = bc.BlocksMatchingBloom(0, 9, nil)
+	require.NoError(err)
+	require.Equal([]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9}, heights)
+
+	_, err = bc.BlocksMatchingBloom(5, 4, nil)
+	require.Error(err)
+	_, err = bc.BlocksMatchingBloom(1, 10, nil)
+	require.Error(err)
+
+	// the header of a block tells whether its receipts are worth reading
+	blkHash, err := bc.GetHashByHeight(5)
+	require.NoError(err)
+	header, err := bc.BlockHeaderByHash(blkHash)
+	require.NoError(err)
+	require.True(header.MayContainLogKeys([]hash.Hash256{topics[5], topics[15]}))
+	receipts, err := bc.GetReceiptsByBlockHash(blkHash)
+	require.NoError(err)
+	require.Equal(1, len(receipts))
+	require.Equal(topics[5], receipts[0].Logs[0].Topics[0])
+	_, err = bc.GetReceiptsByBlockHash(hash.ZeroHash256)
+	require.Error(err)
+}
+
+func TestActionAddressBloom(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	cfg := config.Default
+	cfg.Genesis.EnableGravityChainVoting = false
+	cfg.Genesis.AleutianBlockHeight = 1
+	// the addresses are in the filter of the blocks since height 101
+	cfg.Genesis.EnableActionAddressBloom = true
+	cfg.Genesis.ActionAddressBloomBlockHeight = 101
+	registry := protocol.Registry{}
+	acc := account.NewProtocol(config.NewHeightUpgrade(cfg))
+	require.NoError(registry.Register(account.ProtocolID, acc))
+	rp := rolldpos.NewProtocol(cfg.Genesis.NumCandidateDelegates, cfg.Genesis.NumDelegates, cfg.Genesis.NumSubEpochs)
+	require.NoError(registry.Register(rolldpos.ProtocolID, rp))
+	bc := NewBlockchain(cfg, InMemStateFactoryOption(), InMemDaoOption(), RegistryOption(&registry))
+	bc.Validator().AddActionEnvelopeValidators(protocol.NewGenericValidator(bc))
+	bc.Validator().AddActionValidators(acc)
+	bc.GetFactory().AddActionHandlers(acc)
+	require.NoError(bc.Start(ctx))
+	defer func() {
+		require.NoError(bc.Stop(ctx))
+	}()
+
+	// each block has 2 transfers from the funded accounts to new accounts
+	numSenders := 24
+	nonces := make([]uint64, numSenders)
+	active := make(map[uint64][]address.Address)
+	for height := uint64(1); height <= 200; height++ {
+		actionMap := make(map[string][]action.SealedEnvelope)
+		for i := 0; i < 2; i++ {
+			sender := int(2*height+uint64(i)) % numSenders
+			sk, err := crypto.GenerateKey()
+			require.NoError(err)
+			recipient, err := address.FromBytes(sk.PublicKey().Hash())
+			require.NoError(err)
+			nonces[sender]++
+			tsf, err := testutil.SignedTransfer(
+				recipient.String(),
+				identityset.PrivateKey(sender),
+				nonces[sender],
+				big.NewInt(1),
+				nil,
+				testutil.TestGasLimit,
+				big.NewInt(0),
+			)
+			require.NoError(err)
+			actionMap[identityset.Address(sender).String()] = []action.SealedEnvelope{tsf}
+			active[height] = append(active[height], identityset.Address(sender), recipient)
+		}
+		blk, err := bc.MintNewBlock(actionMap, testutil.TimestampNow())
+		require.NoError(err)
+		// along with the grant of the block reward
+		require.Equal(3, len(blk.Actions))
+		require.NoError(bc.ValidateBlock(blk))
+		require.NoError(bc.CommitBlock(blk))
+	}
+
+	probes := make([]address.Address, 100)
+	for i := range probes {
+		sk, err := crypto.GenerateKey()
+		require.NoError(err)
+		probes[i], err = address.FromBytes(sk.PublicKey().Hash())
+		require.NoError(err)
+	}
+	falsePositives := 0
+	for height := uint64(1); height <= 200; height++ {
+		header, err := bc.BlockHeaderByHeight(height)
+		require.NoError(err)
+		if height <= 100 {
+			// the old blocks are unaffected
+			blk, err := bc.GetBlockByHeight(height)
+			require.NoError(err)
+			blk.Receipts, err = bc.GetReceiptsByHeight(height)
+			require.NoError(err)
+			require.NoError(blk.VerifyLogsBloom(block.LogsBloomKeys{}))
+			continue
+		}
+		// no false negative
+		for _, addr := range active[height] {
+			require.True(header.MayContainAddress(addr))
+		}
+		for _, addr := range probes {
+			if header.MayContainAddress(addr) {
+				falsePositives++
+			}
+		}
+	}
+	rate := float64(falsePositives) / float64(100*len(probes))
+	t.Logf("false positive rate of the 2048-bit filter: %f", rate)
+	require.True(rate < 0.01)
+}
+
+func TestConsensusMeta(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default
+	ctx := context.Background()
+	bc := NewBlockchain(cfg, InMemDaoOption(), InMemStateFactoryOption())
+	require.NoError(bc.Start(ctx))
+	defer func() {
+		require.NoError(bc.Stop(ctx))
+	}()
+
+	// the blocks are committed as by the block sync
+	for height := uint64(1); height <= 3; height++ {
+		ra := block.NewRunnableActionsBuilder().
+			SetHeight(height).
+			SetTimeStamp(time.Unix(cfg.Genesis.Timestamp+int64(height), 0)).
+			Build(identityset.PrivateKey(0).PublicKey())
+		blk, err := block.NewBuilder(ra).
+			SetPrevBlockHash(bc.TipHash()).
+			SignAndBuild(identityset.PrivateKey(0))
+		require.NoError(err)
+		blk.WorkingSet, err = bc.GetFactory().NewWorkingSet()
+		require.NoError(err)
+		require.NoError(bc.CommitBlock(&blk))
+	}
+	hashes := make(map[uint64]hash.Hash256)
+	for height := uint64(1); height <= 3; height++ {
+		meta, err := bc.ConsensusMetaByHeight(height)
+		require.NoError(err)
+		hashes[height], err = bc.GetHashByHeight(height)
+		require.NoError(err)
+		require.Equal(height, meta.Height)
+		require.Equal(hashes[height], meta.BlockHash)
+		require.False(meta.RoundKnown())
+		require.Equal(identityset.Address(0).String(), meta.Proposer)
+		require.Equal(uint32(0), meta.NumCommitEndorsements)
+		require.True(meta.RoundStartTime.IsZero())
+	}
+	_, err := bc.ConsensusMetaByHeight(4)
+	require.Equal(db.ErrNotExist, errors.Cause(err))
+
+	// the consensus records its round
+	roundStart := time.Unix(cfg.Genesis.Timestamp, 123)
+	committed := &ConsensusMeta{
+		Height:                2,
+		BlockHash:             hashes[2],
+		Round:                 1,
+		Proposer:              identityset.Address(1).String(),
+		NumCommitEndorsements: 17,
+		RoundStartTime:        roundStart,
+	}
+	require.NoError(bc.PutConsensusMeta(committed))
+	meta, err := bc.ConsensusMetaByHeight(2)
+	require.NoError(err)
+	require.True(meta.RoundKnown())
+	require.True(roundStart.Equal(meta.RoundStartTime))
+	meta.RoundStartTime = roundStart
+	require.Equal(committed, meta)
+	// but not of a block which isn't committed
+	require.Error(bc.PutConsensusMeta(&ConsensusMeta{Height: 3, BlockHash: hashes[2]}))
+	require.Error(bc.PutConsensusMeta(&ConsensusMeta{Height: 4}))
+
+	// the metadata of the blocks committed before it was recorded is made of the blocks
+	require.NoError(bc.KVStore().Delete(consensusMetaNS, nil))
+	for height := uint64(1); height <= 3; height++ {
+		meta, err := bc.ConsensusMetaByHeight(height)
+		require.NoError(err)
+		require.Equal(hashes[height], meta.BlockHash)
+		require.False(meta.RoundKnown())
+		require.Equal(identityset.Address(0).String(), meta.Proposer)
+	}
+	require.Error((&ConsensusMeta{}).Deserialize([]byte{consensusMetaVersion}))
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/iotexproject/iotex-core/blockchain/block"
+)
+
+var blockCommitDroppedMtc = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "iotex_block_commit_dropped",
+		Help: "Number of committed blocks dropped for the subscribers with a full channel.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(blockCommitDroppedMtc)
+}
+
+// blockCommitFeed publishes the committed blocks to the subscribed channels. A block is dropped for a subscriber
+// whose channel is full, such that a slow subscriber can't stall the commit of the blocks.
+type blockCommitFeed struct {
+	mutex  sync.RWMutex
+	nextID uint64
+	subs   map[uint64]chan *block.Block
+}
+
+func newBlockCommitFeed() *blockCommitFeed {
+	return &blockCommitFeed{subs: make(map[uint64]chan *block.Block)}
+}
+
+// subscribe returns a channel of the given buffer size receiving the published blocks, and the func to unsubscribe,
+// which closes the channel
+func (f *blockCommitFeed) subscribe(buffer int) (<-chan *block.Block, func()) {
+	if buffer < 0 {
+		buffer = 0
+	}
+	ch := make(chan *block.Block, buffer)
+	f.mutex.Lock()
+	id := f.nextID
+	f.nextID++
+	f.subs[id] = ch
+	f.mutex.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			f.mutex.Lock()
+			defer f.mutex.Unlock()
+
+			delete(f.subs, id)
+			close(ch)
+		})
+	}
+}
+
+// publish sends the block to each subscriber without blocking
+func (f *blockCommitFeed) publish(blk *block.Block) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	for _, ch := range f.subs {
+		select {
+		case ch <- blk:
+		default:
+			blockCommitDroppedMtc.Inc()
+		}
+	}
+}
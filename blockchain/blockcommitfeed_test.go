@@ -0,0 +1,49 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/blockchain/block"
+)
+
+func TestBlockCommitFeed(t *testing.T) {
+	require := require.New(t)
+	f := newBlockCommitFeed()
+	blks := []*block.Block{{}, {}, {}}
+
+	fast, unsubscribeFast := f.subscribe(len(blks))
+	slow, unsubscribeSlow := f.subscribe(1)
+	// a negative buffer size is taken as unbuffered
+	unbuffered, unsubscribeUnbuffered := f.subscribe(-1)
+	defer unsubscribeUnbuffered()
+	dropped := promtestutil.ToFloat64(blockCommitDroppedMtc)
+	for _, blk := range blks {
+		f.publish(blk)
+	}
+	// the blocks beyond the buffer of the slow subscriber are dropped
+	require.Equal(dropped+float64(2+len(blks)), promtestutil.ToFloat64(blockCommitDroppedMtc))
+	for _, blk := range blks {
+		require.True(blk == <-fast)
+	}
+	require.True(blks[0] == <-slow)
+	require.Equal(0, len(unbuffered))
+
+	// unsubscribing closes the channel, and is safe to call twice
+	unsubscribeFast()
+	unsubscribeFast()
+	_, ok := <-fast
+	require.False(ok)
+	f.publish(blks[0])
+	require.True(blks[0] == <-slow)
+	unsubscribeSlow()
+	require.Equal(1, len(f.subs))
+}
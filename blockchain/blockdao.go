@@ -51,6 +51,7 @@ const (
 	receiptsNS                       = "rpt"
 	numActionsNS                     = "nac"
 	transferAmountNS                 = "tfa"
+	consensusMetaNS                  = "csm"
 
 	hashOffset = 12
 )
@@ -546,6 +547,10 @@ func (dao *blockDAO) putBlock(blk *block.Block) error {
 	transferAmountBytes := transferAmount.Bytes()
 	batch.Put(transferAmountNS, heightKey, transferAmountBytes, "Failed to put transfer amount of block %d", blk.Height())
 
+	// the consensus overwrites the metadata with the one of its round, if the block is committed by the consensus
+	consensusMeta := newUnknownRoundConsensusMeta(&blk.Header, &blk.Footer)
+	batch.Put(consensusMetaNS, heightKey, consensusMeta.Serialize(), "Failed to put consensus meta of block %d", blk.Height())
+
 	if !dao.writeIndex {
 		return dao.kvstore.Commit(batch)
 	}
@@ -589,6 +594,30 @@ func (dao *blockDAO) getTranferAmount(height uint64) (*big.Int, error) {
 	return new(big.Int).SetBytes(value), nil
 }
 
+// putConsensusMeta stores the consensus metadata of a block
+func (dao *blockDAO) putConsensusMeta(meta *ConsensusMeta) error {
+	heightKey := append(heightPrefix, byteutil.Uint64ToBytes(meta.Height)...)
+	return dao.kvstore.Put(consensusMetaNS, heightKey, meta.Serialize())
+}
+
+// getConsensusMeta returns the consensus metadata by height, which is missing for the blocks committed before it was
+// recorded
+func (dao *blockDAO) getConsensusMeta(height uint64) (*ConsensusMeta, error) {
+	heightKey := append(heightPrefix, byteutil.Uint64ToBytes(height)...)
+	value, err := dao.kvstore.Get(consensusMetaNS, heightKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get consensus meta")
+	}
+	if len(value) == 0 {
+		return nil, errors.Wrapf(db.ErrNotExist, "consensus meta missing for block with height %d", height)
+	}
+	meta := &ConsensusMeta{}
+	if err := meta.Deserialize(value); err != nil {
+		return nil, errors.Wrapf(err, "failed to deserialize consensus meta of block with height %d", height)
+	}
+	return meta, nil
+}
+
 // putReceipts store receipt into db
 func (dao *blockDAO) putReceipts(blkHeight uint64, blkReceipts []*action.Receipt) error {
 	kvstore, err := dao.getTopDBOfOpened(blkHeight)
@@ -715,6 +744,9 @@ func (dao *blockDAO) deleteTipBlock() error {
 	topHeightValue := byteutil.Uint64ToBytes(topHeight)
 	batch.Put(blockNS, topHeightKey, topHeightValue, "failed to put top height")
 
+	// Delete consensus meta
+	batch.Delete(consensusMetaNS, heightKey, "failed to delete consensus meta")
+
 	if !dao.writeIndex {
 		return dao.kvstore.Commit(batch)
 	}
@@ -883,7 +915,7 @@ func (dao *blockDAO) openDB(idx uint64) (kvstore db.KVStore, index uint64, err e
 
 	// open or create this db file
 	cfg.DbPath = path.Dir(cfg.DbPath) + "/" + name
-	kvstore = db.NewBoltDB(cfg)
+	kvstore = db.NewKVStore(cfg)
 	dao.kvstores.Store(idx, kvstore)
 	err = kvstore.Start(context.Background())
 	if err != nil {
@@ -0,0 +1,95 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"math"
+	"time"
+
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/blockchain/block"
+	"github.com/iotexproject/iotex-core/pkg/enc"
+)
+
+// UnknownRound is the round number of the blocks which weren't committed by the local consensus, e.g., the blocks
+// committed by the block sync, or before the consensus metadata was recorded
+const UnknownRound = math.MaxUint32
+
+// consensusMetaVersion is the version of the serialized consensus metadata
+const consensusMetaVersion = 1
+
+// consensusMetaFixedSize is the size of the serialized consensus metadata, but the proposer address, i.e., the
+// version, height, block hash, round number, number of endorsements and round start time
+const consensusMetaFixedSize = 1 + 8 + 32 + 4 + 4 + 8
+
+// ConsensusMeta is the metadata of the consensus reaching the commit of a block, which goes away with the round once
+// the block is committed, hence is recorded for the explorers
+type ConsensusMeta struct {
+	Height    uint64
+	BlockHash hash.Hash256
+	// Round is the number of the round in which the block was committed, 0 being the first round, or UnknownRound
+	Round uint32
+	// Proposer is the address of the delegate proposing the block
+	Proposer string
+	// NumCommitEndorsements is the number of the COMMIT endorsements of the block
+	NumCommitEndorsements uint32
+	// RoundStartTime is the start time of the round, which is zero if the round is unknown
+	RoundStartTime time.Time
+}
+
+// newUnknownRoundConsensusMeta returns the consensus metadata of a block without a local round, which is all told by
+// the block itself
+func newUnknownRoundConsensusMeta(header *block.Header, footer *block.Footer) *ConsensusMeta {
+	return &ConsensusMeta{
+		Height:                header.Height(),
+		BlockHash:             header.HashBlock(),
+		Round:                 UnknownRound,
+		Proposer:              header.ProducerAddress(),
+		NumCommitEndorsements: uint32(len(footer.Endorsements())),
+	}
+}
+
+// RoundKnown returns whether the block was committed in a round of the local consensus
+func (m *ConsensusMeta) RoundKnown() bool {
+	return m.Round != UnknownRound
+}
+
+// Serialize returns the serialized bytes of the consensus metadata
+func (m *ConsensusMeta) Serialize() []byte {
+	buf := make([]byte, consensusMetaFixedSize, consensusMetaFixedSize+len(m.Proposer))
+	buf[0] = consensusMetaVersion
+	enc.MachineEndian.PutUint64(buf[1:], m.Height)
+	copy(buf[9:], m.BlockHash[:])
+	enc.MachineEndian.PutUint32(buf[41:], m.Round)
+	enc.MachineEndian.PutUint32(buf[45:], m.NumCommitEndorsements)
+	if !m.RoundStartTime.IsZero() {
+		enc.MachineEndian.PutUint64(buf[49:], uint64(m.RoundStartTime.UnixNano()))
+	}
+	return append(buf, m.Proposer...)
+}
+
+// Deserialize loads the consensus metadata from the serialized bytes
+func (m *ConsensusMeta) Deserialize(buf []byte) error {
+	if len(buf) < consensusMetaFixedSize {
+		return errors.Errorf("consensus metadata of %d bytes is too short", len(buf))
+	}
+	if buf[0] != consensusMetaVersion {
+		return errors.Errorf("unsupported version %d of consensus metadata", buf[0])
+	}
+	m.Height = enc.MachineEndian.Uint64(buf[1:])
+	copy(m.BlockHash[:], buf[9:41])
+	m.Round = enc.MachineEndian.Uint32(buf[41:])
+	m.NumCommitEndorsements = enc.MachineEndian.Uint32(buf[45:])
+	m.RoundStartTime = time.Time{}
+	if ts := enc.MachineEndian.Uint64(buf[49:]); ts != 0 {
+		m.RoundStartTime = time.Unix(0, int64(ts))
+	}
+	m.Proposer = string(buf[consensusMetaFixedSize:])
+	return nil
+}
@@ -121,6 +121,18 @@ type (
 		AleutianBlockHeight uint64 `yaml:"aleutianHeight"`
 		// BeringBlockHeight is the start height of reducing block interval to 5 seconds
 		BeringBlockHeight uint64 `yaml:"beringHeight"`
+		// EnableLogAddressBloom is the flag to add the address of the contract emitting each log into the logs bloom
+		// filter of the block header, along with the log topics
+		// TODO: the log address bloom is not added into protobuf definition for backward compatibility
+		EnableLogAddressBloom bool `yaml:"enableLogAddressBloom"`
+		// LogAddressBloomBlockHeight is the start height of adding the log addresses into the logs bloom filter
+		LogAddressBloomBlockHeight uint64 `yaml:"logAddressBloomHeight"`
+		// EnableActionAddressBloom is the flag to add the sender and recipient of each action into the logs bloom
+		// filter of the block header
+		// TODO: the action address bloom is not added into protobuf definition for backward compatibility
+		EnableActionAddressBloom bool `yaml:"enableActionAddressBloom"`
+		// ActionAddressBloomBlockHeight is the start height of adding the action addresses into the logs bloom filter
+		ActionAddressBloomBlockHeight uint64 `yaml:"actionAddressBloomHeight"`
 	}
 	// Account contains the configs for account protocol
 	Account struct {
@@ -276,6 +288,22 @@ func (g *Genesis) Hash() hash.Hash256 {
 	return hash.Hash256b(b)
 }
 
+// IsLogAddressBloomOn returns whether the address of the contract emitting each log of the block at the height is in
+// the logs bloom filter, which only exists since the Aleutian height
+func (b *Blockchain) IsLogAddressBloomOn(height uint64) bool {
+	return b.EnableLogAddressBloom &&
+		height >= b.LogAddressBloomBlockHeight &&
+		height >= b.AleutianBlockHeight
+}
+
+// IsActionAddressBloomOn returns whether the sender and recipient of each action of the block at the height are in the
+// logs bloom filter, which only exists since the Aleutian height
+func (b *Blockchain) IsActionAddressBloomOn(height uint64) bool {
+	return b.EnableActionAddressBloom &&
+		height >= b.ActionAddressBloomBlockHeight &&
+		height >= b.AleutianBlockHeight
+}
+
 // InitBalances returns the address that have initial balances and the corresponding amounts. The i-th amount is the
 // i-th address' balance.
 func (a *Account) InitBalances() ([]address.Address, []*big.Int) {
@@ -42,6 +42,7 @@ type IndexBuilder struct {
 	store        db.KVStore
 	pendingBlks  chan *block.Block
 	cancelChan   chan interface{}
+	flushChan    chan chan struct{}
 	timerFactory *prometheustimer.TimerFactory
 	dao          *blockDAO
 	reindex      bool
@@ -71,6 +72,7 @@ func NewIndexBuilder(chain Blockchain, reindex bool) (*IndexBuilder, error) {
 		store:        bc.dao.kvstore,
 		pendingBlks:  make(chan *block.Block, 64), // Actually 1 should be enough
 		cancelChan:   make(chan interface{}),
+		flushChan:    make(chan chan struct{}),
 		timerFactory: timerFactory,
 		dao:          bc.dao,
 		reindex:      reindex,
@@ -88,26 +90,13 @@ func (ib *IndexBuilder) Start(_ context.Context) error {
 			case <-ib.cancelChan:
 				return
 			case blk := <-ib.pendingBlks:
-				timer := ib.timerFactory.NewTimer("indexBlock")
-				batch := db.NewBatch()
-				if err := indexBlock(ib.store, blk, batch); err != nil {
-					log.L().Info(
-						"Error when indexing the block",
-						zap.Uint64("height", blk.Height()),
-						zap.Error(err),
-					)
+				ib.index(blk)
+			case done := <-ib.flushChan:
+				// this is the only goroutine receiving the pending blocks
+				for len(ib.pendingBlks) > 0 {
+					ib.index(<-ib.pendingBlks)
 				}
-				// index receipts
-				putReceipts(blk.Height(), blk.Receipts, batch)
-				batchSizeMtc.WithLabelValues().Set(float64(batch.Size()))
-				if err := ib.store.Commit(batch); err != nil {
-					log.L().Info(
-						"Error when indexing the block",
-						zap.Uint64("height", blk.Height()),
-						zap.Error(err),
-					)
-				}
-				timer.End()
+				close(done)
 			}
 		}
 	}()
@@ -120,6 +109,47 @@ func (ib *IndexBuilder) Stop(_ context.Context) error {
 	return nil
 }
 
+// Flush waits until the blocks handled so far are indexed, or the context is done
+func (ib *IndexBuilder) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case ib.flushChan <- done:
+	case <-ib.cancelChan:
+		return errors.New("index builder has been stopped")
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "error when flushing the index builder")
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "error when flushing the index builder")
+	}
+}
+
+func (ib *IndexBuilder) index(blk *block.Block) {
+	timer := ib.timerFactory.NewTimer("indexBlock")
+	defer timer.End()
+	batch := db.NewBatch()
+	if err := indexBlock(ib.store, blk, batch); err != nil {
+		log.L().Info(
+			"Error when indexing the block",
+			zap.Uint64("height", blk.Height()),
+			zap.Error(err),
+		)
+	}
+	// index receipts
+	putReceipts(blk.Height(), blk.Receipts, batch)
+	batchSizeMtc.WithLabelValues().Set(float64(batch.Size()))
+	if err := ib.store.Commit(batch); err != nil {
+		log.L().Info(
+			"Error when indexing the block",
+			zap.Uint64("height", blk.Height()),
+			zap.Error(err),
+		)
+	}
+}
+
 // HandleBlock handles the block and create the indices for the actions and receipts in it
 func (ib *IndexBuilder) HandleBlock(blk *block.Block) error {
 	ib.pendingBlks <- blk
@@ -9,8 +9,10 @@ package chainservice
 import (
 	"context"
 	"os"
+	"time"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/go-pkgs/crypto"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
@@ -27,6 +29,8 @@ import (
 	"github.com/iotexproject/iotex-core/blocksync"
 	"github.com/iotexproject/iotex-core/config"
 	"github.com/iotexproject/iotex-core/consensus"
+	"github.com/iotexproject/iotex-core/consensus/scheme"
+	rolldposscheme "github.com/iotexproject/iotex-core/consensus/scheme/rolldpos"
 	"github.com/iotexproject/iotex-core/db"
 	"github.com/iotexproject/iotex-core/dispatcher"
 	"github.com/iotexproject/iotex-core/p2p"
@@ -35,9 +39,22 @@ import (
 	"github.com/iotexproject/iotex-proto/golang/iotextypes"
 )
 
+// The phases of stopping a chain service, which stops the components in the order of their dependencies, after the
+// consensus
+const (
+	// StopBlockSync stops the block sync
+	StopBlockSync = "blocksync"
+	// StopChain stops the index builder and the API serving the chain
+	StopChain = "chain"
+	// StopDB stops the blockchain, which closes its DBs
+	StopDB = "db"
+)
+
 // ChainService is a blockchain service with all blockchain components.
 type ChainService struct {
-	actpool           actpool.ActPool
+	actpool actpool.ActPool
+	// actPoolDumpPath is the file the action pool is dumped to and loaded from, which is empty if disabled
+	actPoolDumpPath   string
 	blocksync         blocksync.BlockSync
 	consensus         consensus.Consensus
 	chain             blockchain.Blockchain
@@ -50,7 +67,8 @@ type ChainService struct {
 }
 
 type optionParams struct {
-	isTesting bool
+	isTesting    bool
+	peerReporter scheme.PeerScoreReporter
 }
 
 // Option sets ChainService construction parameter.
@@ -64,6 +82,14 @@ func WithTesting() Option {
 	}
 }
 
+// WithPeerScoreReporter is an option to report the outcomes of validating the consensus messages relayed by the peers
+func WithPeerScoreReporter(reporter scheme.PeerScoreReporter) Option {
+	return func(ops *optionParams) error {
+		ops.peerReporter = reporter
+		return nil
+	}
+}
+
 // New creates a ChainService from config and network.Overlay and dispatcher.Dispatcher.
 func New(
 	cfg config.Config,
@@ -105,7 +131,7 @@ func New(
 		committeeConfig.StakingContractAddress = cfg.Genesis.StakingContractAddress
 		committeeConfig.SelfStakingThreshold = cfg.Genesis.SelfStakingThreshold
 
-		kvstore := db.NewBoltDB(cfg.Chain.GravityChainDB)
+		kvstore := db.NewKVStore(cfg.Chain.GravityChainDB)
 		if committeeConfig.GravityChainStartHeight != 0 {
 			if electionCommittee, err = committee.NewCommitteeWithKVStoreWithNamespace(
 				kvstore,
@@ -168,6 +194,9 @@ func New(
 		}),
 		consensus.WithRollDPoSProtocol(rDPoSProtocol),
 	}
+	if ops.peerReporter != nil {
+		copts = append(copts, consensus.WithPeerScoreReporter(ops.peerReporter))
+	}
 	// TODO: explorer dependency deleted at #1085, need to revive by migrating to api
 	consensus, err := consensus.NewConsensus(cfg, chain, actPool, copts...)
 	if err != nil {
@@ -205,8 +234,9 @@ func New(
 		return nil, err
 	}
 
-	return &ChainService{
+	cs := &ChainService{
 		actpool:           actPool,
+		actPoolDumpPath:   cfg.ActPool.DumpPath,
 		chain:             chain,
 		blocksync:         bs,
 		consensus:         consensus,
@@ -215,7 +245,11 @@ func New(
 		indexBuilder:      indexBuilder,
 		api:               apiSvr,
 		registry:          &registry,
-	}, nil
+	}
+	if r, ok := cs.rollDPoS(); ok {
+		r.SetSyncTargetFunc(bs.TargetHeight)
+	}
+	return cs, nil
 }
 
 // Start starts the server
@@ -228,6 +262,7 @@ func (cs *ChainService) Start(ctx context.Context) error {
 	if err := cs.chain.Start(ctx); err != nil {
 		return errors.Wrap(err, "error when starting blockchain")
 	}
+	cs.loadActPool()
 	if err := cs.consensus.Start(ctx); err != nil {
 		return errors.Wrap(err, "error when starting consensus")
 	}
@@ -251,6 +286,21 @@ func (cs *ChainService) Start(ctx context.Context) error {
 
 // Stop stops the server
 func (cs *ChainService) Stop(ctx context.Context) error {
+	return cs.StopInPhases(ctx, func(string) {})
+}
+
+// StopInPhases stops the server as Stop does, in the order of the dependencies of the components, i.e., the
+// consensus, the block sync, the index builder and the API, and the blockchain along with its DBs. The consensus is
+// stopped right away, and enter is called with the phase before stopping each of the others.
+func (cs *ChainService) StopInPhases(ctx context.Context, enter func(phase string)) error {
+	if err := cs.consensus.Stop(ctx); err != nil {
+		return errors.Wrap(err, "error when stopping consensus")
+	}
+	enter(StopBlockSync)
+	if err := cs.blocksync.Stop(ctx); err != nil {
+		return errors.Wrap(err, "error when stopping blocksync")
+	}
+	enter(StopChain)
 	if cs.indexBuilder != nil {
 		if err := cs.indexBuilder.Stop(ctx); err != nil {
 			return errors.Wrap(err, "error when stopping index builder")
@@ -262,18 +312,124 @@ func (cs *ChainService) Stop(ctx context.Context) error {
 			return errors.Wrap(err, "error when stopping API server")
 		}
 	}
-	if err := cs.consensus.Stop(ctx); err != nil {
-		return errors.Wrap(err, "error when stopping consensus")
-	}
-	if err := cs.blocksync.Stop(ctx); err != nil {
-		return errors.Wrap(err, "error when stopping blocksync")
-	}
+	enter(StopDB)
+	cs.dumpActPool()
 	if err := cs.chain.Stop(ctx); err != nil {
 		return errors.Wrap(err, "error when stopping blockchain")
 	}
 	return nil
 }
 
+// loadActPool adds the actions dumped at the last stop into the action pool, which is validated against the chain
+// started. A dump failing to load doesn't stop the node from starting, as the actions are only lost as on a restart
+// without the dump.
+func (cs *ChainService) loadActPool() {
+	path := cs.actPoolDumpPath
+	if path == "" {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.L().Warn("Failed to open the action pool dump.", zap.String("path", path), zap.Error(err))
+		}
+		return
+	}
+	defer f.Close()
+	if err := cs.actpool.Load(f); err != nil {
+		log.L().Warn("Failed to load the action pool dump.", zap.String("path", path), zap.Error(err))
+	}
+}
+
+// dumpActPool dumps the actions in the action pool, once nothing adds into it any more. The dump is written into a
+// temporary file renamed into place, such that a dump interrupted doesn't replace the last one.
+func (cs *ChainService) dumpActPool() {
+	path := cs.actPoolDumpPath
+	if path == "" {
+		return
+	}
+	if err := dumpActPool(cs.actpool, path); err != nil {
+		log.L().Warn("Failed to dump the action pool.", zap.String("path", path), zap.Error(err))
+	}
+}
+
+func dumpActPool(ap actpool.ActPool, path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := ap.Dump(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// StopConsensusGracefully prepares the roll-DPoS consensus for the shutdown, which waits up to the timeout for the
+// block being committed, if any. See RollDPoS.StopGracefully. Other schemes have nothing to prepare.
+func (cs *ChainService) StopConsensusGracefully(timeout time.Duration) error {
+	r, ok := cs.rollDPoS()
+	if !ok {
+		return nil
+	}
+	return r.StopGracefully(timeout)
+}
+
+// Flush waits until the blocks committed so far are indexed. The action pool is kept in memory, and only dumped when
+// the service stops.
+func (cs *ChainService) Flush(ctx context.Context) error {
+	if cs.indexBuilder == nil {
+		return nil
+	}
+	return cs.indexBuilder.Flush(ctx)
+}
+
+// RotateKey replaces the delegate key the roll-DPoS consensus signs the endorsements and the blocks with, which takes
+// effect at the next round. The endorsements signed by the old key so far remain valid.
+func (cs *ChainService) RotateKey(priKey crypto.PrivateKey) error {
+	r, ok := cs.rollDPoS()
+	if !ok {
+		return errors.New("key rotation is only supported by roll-DPoS consensus")
+	}
+	return r.RotateKey(priKey)
+}
+
+// Signer returns the signer with the delegate key of the roll-DPoS consensus
+func (cs *ChainService) Signer() (rolldposscheme.Signer, error) {
+	r, ok := cs.rollDPoS()
+	if !ok {
+		return nil, errors.New("signer is only supported by roll-DPoS consensus")
+	}
+	signer := r.Signer()
+	if signer == nil {
+		return nil, errors.New("roll-DPoS consensus is in the observer mode without a key")
+	}
+	return signer, nil
+}
+
+// Delegates returns the delegates of the current roll-DPoS consensus round
+func (cs *ChainService) Delegates() ([]string, error) {
+	r, ok := cs.rollDPoS()
+	if !ok {
+		return nil, errors.New("delegates are only supported by roll-DPoS consensus")
+	}
+	return r.Delegates(), nil
+}
+
+// rollDPoS returns the roll-DPoS scheme of the consensus, or false if the chain runs another consensus scheme
+func (cs *ChainService) rollDPoS() (*rolldposscheme.RollDPoS, bool) {
+	c, ok := cs.consensus.(*consensus.IotxConsensus)
+	if !ok {
+		return nil, false
+	}
+	r, ok := c.Scheme().(*rolldposscheme.RollDPoS)
+	return r, ok
+}
+
 // HandleAction handles incoming action request.
 func (cs *ChainService) HandleAction(_ context.Context, actPb *iotextypes.Action) error {
 	var act action.SealedEnvelope
@@ -307,8 +463,8 @@ func (cs *ChainService) HandleSyncRequest(ctx context.Context, peer peerstore.Pe
 }
 
 // HandleConsensusMsg handles incoming consensus message.
-func (cs *ChainService) HandleConsensusMsg(msg *iotextypes.ConsensusMessage) error {
-	return cs.consensus.HandleConsensusMsg(msg)
+func (cs *ChainService) HandleConsensusMsg(ctx context.Context, msg *iotextypes.ConsensusMessage) error {
+	return cs.consensus.HandleConsensusMsg(ctx, msg)
 }
 
 // ChainID returns ChainID.
@@ -57,6 +57,37 @@ const (
 	NOOPScheme = "NOOP"
 )
 
+const (
+	// SnappyCompression compresses the P2P messages with snappy
+	SnappyCompression = "snappy"
+	// GzipCompression compresses the P2P messages with gzip
+	GzipCompression = "gzip"
+)
+
+const (
+	// BoltDBBackend stores the DB in a bolt DB file
+	BoltDBBackend = "bolt"
+	// MemDBBackend stores the DB in memory, which is lost once the node stops
+	MemDBBackend = "memory"
+)
+
+const (
+	// SyncDurability fsyncs the DB file on every commit, such that a commit which has returned survives a crash of
+	// the OS or a power loss
+	SyncDurability = "sync"
+	// NoSyncDurability never fsyncs the DB file but upon stop, leaving it to the OS to flush the writes
+	NoSyncDurability = "noSync"
+	// BatchedDurability fsyncs the DB file once for all the commits within a sync interval
+	BatchedDurability = "batched"
+)
+
+const (
+	// GasPriceOrdering picks the pending action of the highest gas price first, and the earliest arrival on a tie
+	GasPriceOrdering = "gasPrice"
+	// FIFOOrdering picks the pending action of the earliest arrival first
+	FIFOOrdering = "fifo"
+)
+
 const (
 	// GatewayPlugin is the plugin of accepting user API requests and serving blockchain data to users
 	GatewayPlugin = iota
@@ -79,14 +110,24 @@ var (
 		Plugins: make(map[int]interface{}),
 		SubLogs: make(map[string]log.GlobalConfig),
 		Network: Network{
-			Host:            "0.0.0.0",
-			Port:            4689,
-			ExternalHost:    "",
-			ExternalPort:    4689,
-			BootstrapNodes:  []string{},
-			MasterKey:       "",
-			RateLimit:       p2p.DefaultRatelimitConfig,
-			EnableRateLimit: true,
+			Host:             "0.0.0.0",
+			Port:             4689,
+			ExternalHost:     "",
+			ExternalPort:     4689,
+			BootstrapNodes:   []string{},
+			MasterKey:        "",
+			RateLimit:        p2p.DefaultRatelimitConfig,
+			EnableRateLimit:  true,
+			PeerBanThreshold: -10,
+			PeerBanDuration:  30 * time.Minute,
+			Compression:      SnappyCompression,
+
+			PeerScoreWindow: time.Minute,
+			MinBanInterval:  10 * time.Second,
+
+			BroadcastDedupSize:   1024,
+			BroadcastDedupWindow: time.Second,
+			BroadcastFanout:      0,
 		},
 		Chain: Chain{
 			ChainDBPath:     "./chain.db",
@@ -106,6 +147,11 @@ var (
 			AllowedBlockGasResidue:        10000,
 			MaxCacheSize:                  0,
 			PollInitialCandidatesInterval: 10 * time.Second,
+			Pruning: Pruning{
+				Interval:  0,
+				BatchSize: 10000,
+				Buckets:   []PruningBucket{},
+			},
 		},
 		ActPool: ActPool{
 			MaxNumActsPerPool:  32000,
@@ -127,8 +173,33 @@ var (
 					CommitTTL:                    2 * time.Second,
 					EventChanSize:                10000,
 				},
-				ToleratedOvertime: 2 * time.Second,
-				Delay:             5 * time.Second,
+				ToleratedOvertime:      2 * time.Second,
+				Delay:                  5 * time.Second,
+				BroadcastMaxAttempts:   3,
+				BroadcastRetryInterval: 200 * time.Millisecond,
+				SuppressEmptyBlock:     false,
+				MaxIdleInterval:        time.Minute,
+				UnlockProofHeight:      0,
+				AggregateProofHeight:   0,
+				HealthMaxLag:           2,
+				HealthStallIntervals:   5,
+				WatchdogStallIntervals: 10,
+				EnableForceState:       false,
+				MaxFutureRounds:        10,
+				ParticipationWindow:    1,
+				VoteVerifierWorkers:    0,
+				VoteVerifierQueueSize:  1000,
+				ProbationHeight:        0,
+				CountProbated:          true,
+				RotationSeedHeight:     0,
+				AdaptiveAcceptBlockTTL: false,
+				MinAcceptBlockTTL:      2 * time.Second,
+				MaxAcceptBlockTTL:      6 * time.Second,
+				ProposalLatencyWindow:  20,
+				MaxMessageSize:         4 << 20,
+				ProposalMaxSize:        3 << 20,
+				RoundStartJitter:       5 * time.Millisecond,
+				ClockSkewThreshold:     2 * time.Second,
 			},
 		},
 		BlockSync: BlockSync{
@@ -139,7 +210,8 @@ var (
 			RepeatDecayStep: 1,
 		},
 		Dispatcher: Dispatcher{
-			EventChanSize: 10000,
+			EventChanSize:      10000,
+			TelemetryRateLimit: 30 * time.Second,
 		},
 		API: API{
 			UseRDS:    false,
@@ -150,18 +222,27 @@ var (
 				DefaultGas:         uint64(unit.Qev),
 				Percentile:         60,
 			},
-			RangeQueryLimit: 1000,
+			RangeQueryLimit:      1000,
+			ActionScanBlockLimit: 10000,
 		},
 		System: System{
 			Active:                    true,
 			HeartbeatInterval:         10 * time.Second,
+			TelemetryInterval:         6,
 			HTTPStatsPort:             8080,
 			HTTPAdminPort:             9009,
 			StartSubChainInterval:     10 * time.Second,
+			ShutdownCommitTimeout:     10 * time.Second,
+			ReadinessMaxSyncLag:       3,
+			ActPoolBackpressureRatio:  0.9,
+			MinPeerVersion:            "",
+			OutdatedPeerRatio:         0.5,
 			EnableExperimentalActions: false,
 		},
 		DB: DB{
-			NumRetries: 3,
+			NumRetries:   3,
+			Durability:   SyncDurability,
+			SyncInterval: 100 * time.Millisecond,
 			SQLITE3: SQLITE3{
 				SQLite3File: "./explorer.db",
 			},
@@ -181,6 +262,8 @@ var (
 		ValidateDispatcher,
 		ValidateAPI,
 		ValidateActPool,
+		ValidateNetwork,
+		ValidateDB,
 	}
 
 	// PrivateKey is a randomly generated producer's key for testing purpose
@@ -201,6 +284,30 @@ type (
 		RelayType       string              `yaml:"relayType"`
 		RateLimit       p2p.RateLimitConfig `yaml:"rateLimit"`
 		EnableRateLimit bool                `yaml:"enableRateLimit"`
+		// PeerBanThreshold is the score at or below which a peer is automatically banned. Each invalid message received from
+		// a peer decrements its score by 1. A non-negative value disables automatic banning.
+		PeerBanThreshold int `yaml:"peerBanThreshold"`
+		// PeerBanDuration is how long a peer is banned after its score reaches PeerBanThreshold
+		PeerBanDuration time.Duration `yaml:"peerBanDuration"`
+		// Compression is the algorithm compressing the unicast messages, which is negotiated with the peers, such that a
+		// peer not supporting it receives the messages uncompressed. Two algorithms are supported: snappy, gzip. An empty
+		// value disables the compression.
+		Compression string `yaml:"compression"`
+		// PeerScoreWindow is how long an invalid message received from a peer, including a consensus message relayed by it
+		// failing validation, counts toward its score. A non-positive value counts it until the peer is banned.
+		PeerScoreWindow time.Duration `yaml:"peerScoreWindow"`
+		// MinBanInterval is the minimum interval between two automatic bans, which keeps a burst of invalid messages from
+		// disconnecting many peers at once
+		MinBanInterval time.Duration `yaml:"minBanInterval"`
+		// BroadcastDedupSize is the number of the messages recently broadcast remembered by their hashes, such that the
+		// same message broadcast again within BroadcastDedupWindow is suppressed. A non-positive value disables it.
+		BroadcastDedupSize int `yaml:"broadcastDedupSize"`
+		// BroadcastDedupWindow is the window within which a message broadcast again is suppressed. It is kept short of
+		// the intervals the consensus re-broadcasts its endorsements at.
+		BroadcastDedupWindow time.Duration `yaml:"broadcastDedupWindow"`
+		// BroadcastFanout is the number of the peers a broadcast message is gossiped to, 0 for the default of the
+		// gossip router. It applies to all the agents in the process, and is set by the first one created.
+		BroadcastFanout int `yaml:"broadcastFanout"`
 	}
 
 	// Chain is the config struct for blockchain package
@@ -226,6 +333,8 @@ type (
 		MaxCacheSize int `yaml:"maxCacheSize"`
 		// PollInitialCandidatesInterval is the config for committee init db
 		PollInitialCandidatesInterval time.Duration `yaml:"pollInitialCandidatesInterval"`
+		// Pruning is the config for pruning the chain DB
+		Pruning Pruning `yaml:"pruning"`
 	}
 
 	// Consensus is the config struct for consensus package
@@ -251,11 +360,127 @@ type (
 		FSM               consensusfsm.Config `yaml:"fsm"`
 		ToleratedOvertime time.Duration       `yaml:"toleratedOvertime"`
 		Delay             time.Duration       `yaml:"delay"`
+		// BroadcastMaxAttempts is the max number of attempts to broadcast a consensus message or a committed block
+		BroadcastMaxAttempts int `yaml:"broadcastMaxAttempts"`
+		// BroadcastRetryInterval is the initial backoff between two broadcast attempts, which doubles after each retry
+		BroadcastRetryInterval time.Duration `yaml:"broadcastRetryInterval"`
+		// FaultInjection allows injecting byzantine behaviors for testing, which also requires an environment variable
+		FaultInjection bool `yaml:"faultInjection"`
+		// SuppressEmptyBlock skips proposing a block when there is no pending action, until MaxIdleInterval elapses
+		SuppressEmptyBlock bool `yaml:"suppressEmptyBlock"`
+		// MaxIdleInterval is the max interval between two blocks when empty blocks are suppressed
+		MaxIdleInterval time.Duration `yaml:"maxIdleInterval"`
+		// UnlockProofHeight is the height from which block proposals tell the proof of lock from the proof of unlock,
+		// and a proposal abandoning a locked block has to carry a valid proof of unlock. Proposals in the old format
+		// are still accepted below this height. Typed proposals carry a versioned header the nodes not upgraded can't
+		// parse, hence they are only sent from this height, and rejected below it.
+		UnlockProofHeight uint64 `yaml:"unlockProofHeight"`
+		// AggregateProofHeight is the height from which a block proposal may carry its proof of lock in the aggregated
+		// form, 0 to disable. Until then, such a proposal is rejected, as the nodes not upgraded can't verify it. The
+		// aggregated proof goes with the header of typed proposals, so it takes effect no earlier than UnlockProofHeight.
+		AggregateProofHeight uint64 `yaml:"aggregateProofHeight"`
+		// HealthMaxLag is the max number of heights the consensus round may lag behind the chain tip before the node
+		// is reported as syncing
+		HealthMaxLag uint64 `yaml:"healthMaxLag"`
+		// HealthStallIntervals is the number of block intervals without a committed block, after which the node is
+		// reported as stalled, 0 to disable. MaxIdleInterval is added on top if empty blocks are suppressed.
+		HealthStallIntervals uint64 `yaml:"healthStallIntervals"`
+		// WatchdogStallIntervals is the number of block intervals the consensus FSM may stay in a state other than
+		// prepare without a block committed, after which it is moved back to prepare, 0 to disable. The watchdog
+		// doesn't fire while the node is inactive or syncing.
+		WatchdogStallIntervals uint64 `yaml:"watchdogStallIntervals"`
+		// EnableForceState allows an operator to force the consensus FSM into a state, e.g., to recover a node wedged
+		// in a state, which is disabled by default
+		EnableForceState bool `yaml:"enableForceState"`
+		// MaxFutureRounds is the max number of rounds a consensus message of the current height may be ahead of the
+		// current round by its endorsement timestamp, beyond which it is dropped rather than fed to the FSM, 0 for no
+		// limit
+		MaxFutureRounds uint32 `yaml:"maxFutureRounds"`
+		// ParticipationWindow is the number of epochs over which the endorsement participation of the delegates is
+		// accounted, 0 to disable
+		ParticipationWindow uint64 `yaml:"participationWindow"`
+		// ProposalMaxActions is the soft cap of the number of actions picked from the action pool for a block
+		// proposal, 0 for no cap. The system actions, e.g., granting the block reward, are not counted.
+		ProposalMaxActions uint64 `yaml:"proposalMaxActions"`
+		// ProposalMaxGas is the soft cap of the sum of the gas limits of the actions picked from the action pool for a
+		// block proposal, 0 for no cap. It never exceeds the block gas limit of the genesis.
+		ProposalMaxGas uint64 `yaml:"proposalMaxGas"`
+		// ProposalMaxSize is the cap of the serialized size of a block proposed, 0 for no cap. The actions picked from
+		// the action pool are trimmed to fit, and a block exceeding it all the same, e.g., due to the system actions,
+		// isn't proposed. It is below MaxMessageSize, which leaves room for the proof of lock of the proposal.
+		ProposalMaxSize uint64 `yaml:"proposalMaxSize"`
+		// MinBlockInterval is the min interval between the timestamps of two consecutive blocks, 0 to disable. The
+		// rounds within the interval pass without a proposal, and the blocks within it are neither endorsed nor
+		// committed, such that the rounds could be shorter than the spacing of the blocks.
+		MinBlockInterval time.Duration `yaml:"minBlockInterval"`
+		// VerboseLogging logs the endorsement stats of the round along with each consensus event, which is costly and
+		// only meant for debugging. Otherwise, the endorsements of a round are summarized in a line at the end of it.
+		VerboseLogging bool `yaml:"verboseLogging"`
+		// VoteVerifierWorkers is the number of workers verifying the signatures of the incoming votes before they are
+		// fed to the consensus FSM, 0 to verify them on the goroutines handling the messages and driving the FSM
+		VoteVerifierWorkers int `yaml:"voteVerifierWorkers"`
+		// VoteVerifierQueueSize is the size of the queue of the votes to verify, which blocks the handling of the
+		// incoming votes once full
+		VoteVerifierQueueSize int `yaml:"voteVerifierQueueSize"`
+		// ProbationHeight is the height from which the probated delegates are removed from the proposer rotation, 0
+		// to disable. The probation applies from the first epoch starting at or after this height, such that the
+		// rotation doesn't change within an epoch.
+		ProbationHeight uint64 `yaml:"probationHeight"`
+		// CountProbated tells whether the probated delegates still endorse and count toward the majority of the
+		// endorsements. Otherwise, the majority is out of the delegates not on probation.
+		CountProbated bool `yaml:"countProbated"`
+		// RotationSeedHeight is the height from which the proposer rotation of an epoch is shuffled by the seed of the
+		// epoch, which is the hash of the last block of the previous epoch unless another seed is supplied, 0 to
+		// disable. It applies from the first epoch starting at or after this height.
+		RotationSeedHeight uint64 `yaml:"rotationSeedHeight"`
+		// AdaptiveAcceptBlockTTL nudges AcceptBlockTTL toward the latency of the block proposals observed over the
+		// recent rounds, within [MinAcceptBlockTTL, MaxAcceptBlockTTL], to avoid rotating the rounds whose proposals
+		// merely arrive late. The other phases are shortened or lengthened in proportion to their TTLs, such that the
+		// sum of the TTLs stays the same.
+		AdaptiveAcceptBlockTTL bool          `yaml:"adaptiveAcceptBlockTTL"`
+		MinAcceptBlockTTL      time.Duration `yaml:"minAcceptBlockTTL"`
+		MaxAcceptBlockTTL      time.Duration `yaml:"maxAcceptBlockTTL"`
+		// ProposalLatencyWindow is the number of recent rounds whose proposal latency the adaptive AcceptBlockTTL
+		// is based on
+		ProposalLatencyWindow int `yaml:"proposalLatencyWindow"`
+		// EndorsementThreshold is the fraction of the delegates whose endorsements make a majority, which applies from
+		// the first epoch starting at or after EndorsementThresholdHeight, 0 to disable. Otherwise, a majority is
+		// more than 2/3 of the delegates. A threshold not above 2/3 allows two conflicting blocks to be both endorsed
+		// by a majority, and is rejected unless UnsafeEndorsementThreshold is set, e.g., for a trusted consortium.
+		EndorsementThresholdHeight uint64               `yaml:"endorsementThresholdHeight"`
+		EndorsementThreshold       EndorsementThreshold `yaml:"endorsementThreshold"`
+		UnsafeEndorsementThreshold bool                 `yaml:"unsafeEndorsementThreshold"`
+		// WeightedVotingHeight is the height from which the endorsements of the delegates are weighted by their votes,
+		// such that a majority is a fraction of the total votes of the delegates rather than of their number, 0 to
+		// disable. It applies from the first epoch starting at or after this height.
+		WeightedVotingHeight uint64 `yaml:"weightedVotingHeight"`
+		// Observer runs the consensus FSM to follow and validate the rounds without proposing or endorsing, e.g., on
+		// an analytics node, which needs no producer private key and never signs. It relays the valid consensus
+		// messages of the peers once per round, such that they propagate among the delegates not connected directly.
+		Observer bool `yaml:"observer"`
+		// MaxMessageSize is the max serialized size of an inbound consensus message, which is dropped before being
+		// decoded or verified if larger, 0 for no limit
+		MaxMessageSize uint64 `yaml:"maxMessageSize"`
+		// RoundStartJitter is the max random delay of a delegate after the start of a round, which spreads out the
+		// proposals and the endorsements sent right away by the delegates, 0 to disable. The delay never passes the end
+		// of the block proposal phase.
+		RoundStartJitter time.Duration `yaml:"roundStartJitter"`
+		// ClockSkewThreshold is the skew of the local clock against the majority of the delegates, measured out of
+		// the times their votes are received at, beyond which a warning is logged at the start of a round, 0 to disable
+		ClockSkewThreshold time.Duration `yaml:"clockSkewThreshold"`
+	}
+
+	// EndorsementThreshold is a fraction of the delegates, e.g., 15/21 or 1/1 for the unanimity
+	EndorsementThreshold struct {
+		Numerator   uint64 `yaml:"numerator"`
+		Denominator uint64 `yaml:"denominator"`
 	}
 
 	// Dispatcher is the dispatcher config
 	Dispatcher struct {
 		EventChanSize uint `yaml:"eventChanSize"`
+		// TelemetryRateLimit is the minimum interval between two telemetry reports accepted from the same peer
+		TelemetryRateLimit time.Duration `yaml:"telemetryRateLimit"`
 		// TODO: explorer dependency deleted at #1085, need to revive by migrating to api
 	}
 
@@ -266,6 +491,9 @@ type (
 		TpsWindow       int        `yaml:"tpsWindow"`
 		GasStation      GasStation `yaml:"gasStation"`
 		RangeQueryLimit uint64     `yaml:"rangeQueryLimit"`
+		// ActionScanBlockLimit is the max number of the latest blocks scanned for the actions of an address without the
+		// action index
+		ActionScanBlockLimit uint64 `yaml:"actionScanBlockLimit"`
 	}
 
 	// GasStation is the gas station config
@@ -280,11 +508,28 @@ type (
 		// Active is the status of the node. True means active and false means stand-by
 		Active            bool          `yaml:"active"`
 		HeartbeatInterval time.Duration `yaml:"heartbeatInterval"`
+		// TelemetryInterval is the number of heartbeats between two signed status reports published by a delegate. It
+		// is 0 to disable publishing the reports
+		TelemetryInterval uint `yaml:"telemetryInterval"`
 		// HTTPProfilingPort is the port number to access golang performance profiling data of a blockchain node. It is
 		// 0 by default, meaning performance profiling has been disabled
 		HTTPAdminPort         int           `yaml:"httpAdminPort"`
 		HTTPStatsPort         int           `yaml:"httpStatsPort"`
 		StartSubChainInterval time.Duration `yaml:"startSubChainInterval"`
+		// ShutdownCommitTimeout is the max time the shutdown waits for the block being committed by the consensus,
+		// before stopping the network and the chain
+		ShutdownCommitTimeout time.Duration `yaml:"shutdownCommitTimeout"`
+		// ReadinessMaxSyncLag is the max number of blocks the chain tip may lag behind the target height of the block
+		// sync for the node to be reported as ready
+		ReadinessMaxSyncLag uint64 `yaml:"readinessMaxSyncLag"`
+		// ActPoolBackpressureRatio is the utilization of the actpool, i.e., its size over its capacity, above which the
+		// heartbeat flags the actpool as under backpressure. It is 0 to disable the flag
+		ActPoolBackpressureRatio float64 `yaml:"actPoolBackpressureRatio"`
+		// MinPeerVersion is the min software version of the neighbors, e.g., the first release activating an upcoming
+		// hard fork, which is empty to disable the check. The heartbeat warns once more than OutdatedPeerRatio of the
+		// neighbors advertise a version below it.
+		MinPeerVersion    string  `yaml:"minPeerVersion"`
+		OutdatedPeerRatio float64 `yaml:"outdatedPeerRatio"`
 		// EnableExperimentalActions is the flag to enable experimental actions
 		EnableExperimentalActions bool `yaml:"enableExperimentalActions"`
 	}
@@ -303,13 +548,32 @@ type (
 		MinGasPriceStr string `yaml:"minGasPrice"`
 		// BlackList lists the account address that are banned from initiating actions
 		BlackList []string `yaml:"blackList"`
+		// Ordering is the order of picking the pending actions of different accounts for the block assembly, which is
+		// either gasPrice (default) or fifo
+		Ordering string `yaml:"ordering"`
+		// DumpPath is the file the pending actions are dumped to when the node stops, and loaded from when it starts,
+		// such that they survive a restart. An empty value disables it.
+		DumpPath string `yaml:"dumpPath"`
 	}
 
 	// DB is the config for database
 	DB struct {
-		DbPath string `yaml:"dbPath"`
+		// Backend is the KV store backing the DB, which is either bolt (default) or memory
+		Backend string `yaml:"backend"`
+		DbPath  string `yaml:"dbPath"`
 		// NumRetries is the number of retries
 		NumRetries uint8 `yaml:"numRetries"`
+		// Durability is when the bolt DB file is fsynced, which is either sync (default), noSync or batched. A crash of
+		// the process alone loses no commit in any mode. Upon a crash of the OS or a power loss, noSync and batched
+		// lose the commits which have not been fsynced yet, i.e., those within the last sync interval in the batched
+		// mode, and may even leave a corrupted file, since bolt relies on the fsync to write the pages of a commit
+		// before its meta page. Hence they trade the durability for a higher write throughput, e.g., during the sync.
+		Durability string `yaml:"durability"`
+		// SyncInterval is the window of the commits grouped into one fsync in the batched durability mode
+		SyncInterval time.Duration `yaml:"syncInterval"`
+		// ReadOnly opens an existing bolt DB file for reads only, which shares the file lock with the other readers,
+		// e.g., for an analytics tool. All the writes fail with ErrReadOnly.
+		ReadOnly bool `yaml:"readOnly"`
 
 		// RDS is the config for rds
 		RDS RDS `yaml:"RDS"`
@@ -324,6 +588,28 @@ type (
 		SplitDBHeight uint64 `yaml:"splitDBHeight"`
 	}
 
+	// Pruning is the config for pruning DB buckets periodically
+	Pruning struct {
+		// Interval is the interval between two prunings, 0 to disable pruning
+		Interval time.Duration `yaml:"interval"`
+		// BatchSize is the max number of deletes in a transaction
+		BatchSize int `yaml:"batchSize"`
+		// Buckets are the retention policies of the buckets to prune
+		Buckets []PruningBucket `yaml:"buckets"`
+	}
+
+	// PruningBucket is the retention policy of a bucket
+	PruningBucket struct {
+		// Name is the name of the bucket
+		Name string `yaml:"name"`
+		// KeepEntries is the number of latest entries to keep, 0 for no limit
+		KeepEntries uint64 `yaml:"keepEntries"`
+		// KeepHeights is the number of latest heights to keep, 0 for no limit
+		KeepHeights uint64 `yaml:"keepHeights"`
+		// Counting indicates the bucket is a counting index, whose entries keep their positions after pruning
+		Counting bool `yaml:"counting"`
+	}
+
 	// RDS is the cloud rds config
 	RDS struct {
 		// AwsRDSEndpoint is the endpoint of aws rds
@@ -507,6 +793,17 @@ func ValidateRollDPoS(cfg Config) error {
 	if fsm.EventChanSize <= 0 {
 		return errors.Wrap(ErrInvalidCfg, "roll-DPoS event chan size should be greater than 0")
 	}
+	if maxSize := rollDPoS.MaxMessageSize; maxSize > 0 && rollDPoS.ProposalMaxSize >= maxSize {
+		return errors.Wrapf(
+			ErrInvalidCfg,
+			"proposal max size %d is not below the max message size %d",
+			rollDPoS.ProposalMaxSize,
+			maxSize,
+		)
+	}
+	if rollDPoS.RoundStartJitter < 0 {
+		return errors.Wrapf(ErrInvalidCfg, "round start jitter %s is negative", rollDPoS.RoundStartJitter)
+	}
 	return nil
 }
 
@@ -518,6 +815,35 @@ func ValidateAPI(cfg Config) error {
 	return nil
 }
 
+// ValidateNetwork validates the network configs
+func ValidateNetwork(cfg Config) error {
+	switch cfg.Network.Compression {
+	case "", SnappyCompression, GzipCompression:
+	default:
+		return errors.Wrapf(ErrInvalidCfg, "unknown P2P compression %s", cfg.Network.Compression)
+	}
+	return nil
+}
+
+// ValidateDB validates the DB configs
+func ValidateDB(cfg Config) error {
+	switch cfg.DB.Backend {
+	case "", BoltDBBackend, MemDBBackend:
+	default:
+		return errors.Wrapf(ErrInvalidCfg, "unknown DB backend %s", cfg.DB.Backend)
+	}
+	switch cfg.DB.Durability {
+	case "", SyncDurability, NoSyncDurability:
+	case BatchedDurability:
+		if cfg.DB.SyncInterval <= 0 {
+			return errors.Wrapf(ErrInvalidCfg, "sync interval %s is not positive", cfg.DB.SyncInterval)
+		}
+	default:
+		return errors.Wrapf(ErrInvalidCfg, "unknown DB durability %s", cfg.DB.Durability)
+	}
+	return nil
+}
+
 // ValidateActPool validates the given config
 func ValidateActPool(cfg Config) error {
 	maxNumActPerPool := cfg.ActPool.MaxNumActsPerPool
@@ -534,6 +860,11 @@ func ValidateActPool(cfg Config) error {
 			"maximum number of actions per pool cannot be less than maximum number of actions per account",
 		)
 	}
+	switch cfg.ActPool.Ordering {
+	case "", GasPriceOrdering, FIFOOrdering:
+	default:
+		return errors.Wrapf(ErrInvalidCfg, "unknown action ordering %s", cfg.ActPool.Ordering)
+	}
 	return nil
 }
 
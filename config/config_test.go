@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/iotexproject/go-pkgs/crypto"
 	"github.com/pkg/errors"
@@ -173,6 +174,21 @@ func TestValidateRollDPoS(t *testing.T) {
 		t,
 		strings.Contains(err.Error(), "roll-DPoS event chan size should be greater than 0"),
 	)
+
+	cfg.Consensus.RollDPoS.FSM.EventChanSize = Default.Consensus.RollDPoS.FSM.EventChanSize
+	cfg.Consensus.RollDPoS.RoundStartJitter = -time.Millisecond
+	err = ValidateRollDPoS(cfg)
+	require.Equal(t, ErrInvalidCfg, errors.Cause(err))
+	require.Contains(t, err.Error(), "round start jitter")
+	cfg.Consensus.RollDPoS.RoundStartJitter = 0
+	require.NoError(t, ValidateRollDPoS(cfg))
+
+	cfg.Consensus.RollDPoS.ProposalMaxSize = cfg.Consensus.RollDPoS.MaxMessageSize
+	err = ValidateRollDPoS(cfg)
+	require.Equal(t, ErrInvalidCfg, errors.Cause(err))
+	require.Contains(t, err.Error(), "proposal max size")
+	cfg.Consensus.RollDPoS.MaxMessageSize = 0
+	require.NoError(t, ValidateRollDPoS(cfg))
 }
 
 func TestValidateActPool(t *testing.T) {
@@ -213,4 +229,52 @@ func TestValidateActPool(t *testing.T) {
 			"maximum number of actions per pool cannot be less than maximum number of actions per account",
 		),
 	)
+
+	cfg.ActPool.MaxNumActsPerPool = 100
+	for _, ordering := range []string{"", GasPriceOrdering, FIFOOrdering} {
+		cfg.ActPool.Ordering = ordering
+		require.NoError(t, ValidateActPool(cfg))
+	}
+	cfg.ActPool.Ordering = "lifo"
+	err = ValidateActPool(cfg)
+	require.Equal(t, ErrInvalidCfg, errors.Cause(err))
+	require.Contains(t, err.Error(), "unknown action ordering lifo")
+}
+
+func TestValidateNetwork(t *testing.T) {
+	cfg := Default
+	require.NoError(t, ValidateNetwork(cfg))
+	cfg.Network.Compression = GzipCompression
+	require.NoError(t, ValidateNetwork(cfg))
+	cfg.Network.Compression = ""
+	require.NoError(t, ValidateNetwork(cfg))
+	cfg.Network.Compression = "lz4"
+	err := ValidateNetwork(cfg)
+	require.Error(t, err)
+	require.Equal(t, ErrInvalidCfg, errors.Cause(err))
+}
+
+func TestValidateDB(t *testing.T) {
+	cfg := Default
+	require.NoError(t, ValidateDB(cfg))
+	cfg.DB.Backend = MemDBBackend
+	require.NoError(t, ValidateDB(cfg))
+	cfg.DB.Backend = BoltDBBackend
+	require.NoError(t, ValidateDB(cfg))
+	cfg.DB.Backend = "leveldb"
+	err := ValidateDB(cfg)
+	require.Error(t, err)
+	require.Equal(t, ErrInvalidCfg, errors.Cause(err))
+
+	cfg = Default
+	cfg.DB.Durability = NoSyncDurability
+	require.NoError(t, ValidateDB(cfg))
+	cfg.DB.Durability = BatchedDurability
+	require.NoError(t, ValidateDB(cfg))
+	cfg.DB.SyncInterval = 0
+	err = ValidateDB(cfg)
+	require.Equal(t, ErrInvalidCfg, errors.Cause(err))
+	cfg.DB.Durability = "fsync"
+	err = ValidateDB(cfg)
+	require.Equal(t, ErrInvalidCfg, errors.Cause(err))
 }
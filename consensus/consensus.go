@@ -29,7 +29,7 @@ import (
 type Consensus interface {
 	lifecycle.StartStopper
 
-	HandleConsensusMsg(*iotextypes.ConsensusMessage) error
+	HandleConsensusMsg(context.Context, *iotextypes.ConsensusMessage) error
 	Calibrate(uint64)
 	ValidateBlockFooter(*block.Block) error
 	Metrics() (scheme.ConsensusMetrics, error)
@@ -46,6 +46,7 @@ type IotxConsensus struct {
 type optionParams struct {
 	broadcastHandler scheme.Broadcast
 	rp               *rp.Protocol
+	peerReporter     scheme.PeerScoreReporter
 }
 
 // Option sets Consensus construction parameter.
@@ -67,6 +68,14 @@ func WithRollDPoSProtocol(rp *rp.Protocol) Option {
 	}
 }
 
+// WithPeerScoreReporter is an option to report the outcomes of validating the consensus messages relayed by the peers
+func WithPeerScoreReporter(reporter scheme.PeerScoreReporter) Option {
+	return func(ops *optionParams) error {
+		ops.peerReporter = reporter
+		return nil
+	}
+}
+
 // NewConsensus creates a IotxConsensus struct.
 func NewConsensus(
 	cfg config.Config,
@@ -122,14 +131,16 @@ func NewConsensus(
 			SetPriKey(cfg.ProducerPrivateKey()).
 			SetConfig(cfg).
 			SetBlockchain(bc).
+			SetRoundStateStore(bc.KVStore()).
 			SetActPool(ap).
 			SetClock(clock).
 			SetBroadcast(ops.broadcastHandler).
+			SetPeerScoreReporter(ops.peerReporter).
 			RegisterProtocol(ops.rp)
 		// TODO: explorer dependency deleted here at #1085, need to revive by migrating to api
 		cs.scheme, err = bd.Build()
 		if err != nil {
-			log.Logger("consensus").Panic("Error when constructing RollDPoS.", zap.Error(err))
+			return nil, err
 		}
 	case config.NOOPScheme:
 		cs.scheme = scheme.NewNoop()
@@ -176,8 +187,8 @@ func (c *IotxConsensus) Metrics() (scheme.ConsensusMetrics, error) {
 }
 
 // HandleConsensusMsg handles consensus messages
-func (c *IotxConsensus) HandleConsensusMsg(msg *iotextypes.ConsensusMessage) error {
-	return c.scheme.HandleConsensusMsg(msg)
+func (c *IotxConsensus) HandleConsensusMsg(ctx context.Context, msg *iotextypes.ConsensusMessage) error {
+	return c.scheme.HandleConsensusMsg(ctx, msg)
 }
 
 // Calibrate triggers an event to calibrate consensus context
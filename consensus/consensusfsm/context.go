@@ -34,8 +34,20 @@ type Context interface {
 	Proposal() (interface{}, error)
 	WaitUntilRoundStart() time.Duration
 	PreCommitEndorsement() interface{}
+	ReceiveBlock(interface{}) error
 	NewProposalEndorsement(interface{}) (interface{}, error)
 	NewLockEndorsement(interface{}) (interface{}, error)
 	NewPreCommitEndorsement(interface{}) (interface{}, error)
 	Commit(interface{}) (bool, error)
 }
+
+// Observer is implemented by the contexts which may follow the consensus without participating in it
+type Observer interface {
+	// IsObserver returns true if the node goes through the rounds to validate them, but never proposes or endorses
+	IsObserver() bool
+}
+
+func isObserver(ctx Context) bool {
+	o, ok := ctx.(Observer)
+	return ok && o.IsObserver()
+}
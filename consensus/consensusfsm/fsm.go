@@ -49,6 +49,7 @@ const (
 	ePrepare                           fsm.EventType = "E_PREPARE"
 	eReceiveBlock                      fsm.EventType = "E_RECEIVE_BLOCK"
 	eFailedToReceiveBlock              fsm.EventType = "E_FAILED_TO_RECEIVE_BLOCK"
+	eEndorseProposal                   fsm.EventType = "E_ENDORSE_PROPOSAL"
 	eReceiveProposalEndorsement        fsm.EventType = "E_RECEIVE_PROPOSAL_ENDORSEMENT"
 	eStopReceivingProposalEndorsement  fsm.EventType = "E_STOP_RECEIVING_PROPOSAL_ENDORSEMENT"
 	eReceiveLockEndorsement            fsm.EventType = "E_RECEIVE_LOCK_ENDORSEMENT"
@@ -73,6 +74,8 @@ var (
 	ErrEvtConvert = errors.New("error when converting the event from/to the proto message")
 	// ErrEvtType represents an unexpected event type error
 	ErrEvtType = errors.New("error when check the event type")
+	// ErrUndefinedState indicates a state not defined by the consensus FSM
+	ErrUndefinedState = errors.New("undefined consensus state")
 
 	// consensusStates is a slice consisting of all consensus states
 	consensusStates = []fsm.State{
@@ -93,6 +96,11 @@ type Config struct {
 	AcceptProposalEndorsementTTL time.Duration `yaml:"acceptProposalEndorsementTTL"`
 	AcceptLockEndorsementTTL     time.Duration `yaml:"acceptLockEndorsementTTL"`
 	CommitTTL                    time.Duration `yaml:"commitTTL"`
+	// ProposalBufferTTL is how long the accept-block phase keeps receiving competing block proposals after the first
+	// valid one, before endorsing the winner of them. With 0, the first valid proposal is endorsed upon receiving,
+	// which adds no latency to the round, but the nodes receiving the proposals of an equivocating proposer in
+	// different orders may endorse different blocks.
+	ProposalBufferTTL time.Duration `yaml:"proposalBufferTTL"`
 }
 
 // ConsensusFSM wraps over the general purpose FSM and implements the consensus logic
@@ -101,9 +109,15 @@ type ConsensusFSM struct {
 	evtq  chan *ConsensusEvent
 	close chan interface{}
 	clock clock.Clock
-	cfg   Config
 	ctx   Context
 	wg    sync.WaitGroup
+
+	cfgMutex sync.RWMutex
+	cfg      Config
+
+	// stateSince is the time the FSM entered the current state
+	stateMutex sync.RWMutex
+	stateSince time.Time
 }
 
 // NewConsensusFSM returns a new fsm
@@ -133,15 +147,22 @@ func NewConsensusFSM(cfg Config, ctx Context, clock clock.Clock) (*ConsensusFSM,
 			eReceiveBlock,
 			cm.onReceiveBlock,
 			[]fsm.State{
-				sAcceptBlockProposal,       // proposed block invalid
+				sAcceptBlockProposal,       // proposed block invalid, or buffering competing blocks
 				sAcceptProposalEndorsement, // receive valid block, jump to next step
 			}).
+		AddTransition(
+			sAcceptBlockProposal,
+			eEndorseProposal,
+			cm.onEndorseProposal,
+			[]fsm.State{
+				sAcceptProposalEndorsement, // endorse the winner of the blocks buffered, jump to next step
+			}).
 		AddTransition(
 			sAcceptBlockProposal,
 			eFailedToReceiveBlock,
 			cm.onFailedToReceiveBlock,
 			[]fsm.State{
-				sAcceptProposalEndorsement, // no valid block, jump to next step
+				sAcceptProposalEndorsement, // endorse the block received if any, jump to next step
 			}).
 		AddTransition(
 			sAcceptProposalEndorsement,
@@ -228,6 +249,7 @@ func NewConsensusFSM(cfg Config, ctx Context, clock clock.Clock) (*ConsensusFSM,
 
 // Start starts the fsm and get in initial state
 func (m *ConsensusFSM) Start(c context.Context) error {
+	m.setStateSince(m.clock.Now())
 	m.wg.Add(1)
 	go func() {
 		running := true
@@ -261,11 +283,43 @@ func (m *ConsensusFSM) CurrentState() fsm.State {
 	return m.fsm.CurrentState()
 }
 
+// CurrentStateSince returns the current state along with the time the FSM entered it, which isn't reset by the
+// transitions from the state to itself
+func (m *ConsensusFSM) CurrentStateSince() (fsm.State, time.Time) {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.fsm.CurrentState(), m.stateSince
+}
+
+func (m *ConsensusFSM) setStateSince(t time.Time) {
+	m.stateMutex.Lock()
+	defer m.stateMutex.Unlock()
+
+	m.stateSince = t
+}
+
 // NumPendingEvents returns the number of pending events
 func (m *ConsensusFSM) NumPendingEvents() int {
 	return len(m.evtq)
 }
 
+// SetConfig replaces the time durations, which take effect since the next round. The event queue is not resized.
+func (m *ConsensusFSM) SetConfig(cfg Config) {
+	m.cfgMutex.Lock()
+	defer m.cfgMutex.Unlock()
+
+	cfg.EventChanSize = m.cfg.EventChanSize
+	m.cfg = cfg
+}
+
+func (m *ConsensusFSM) config() Config {
+	m.cfgMutex.RLock()
+	defer m.cfgMutex.RUnlock()
+
+	return m.cfg
+}
+
 // Calibrate calibrates the state if necessary
 func (m *ConsensusFSM) Calibrate(height uint64) {
 	m.produce(m.ctx.NewConsensusEvent(eCalibrate, height), 0)
@@ -278,6 +332,35 @@ func (m *ConsensusFSM) BackToPrepare(delay time.Duration) (fsm.State, error) {
 	return sPrepare, nil
 }
 
+// Unstick moves the FSM back to the prepare state by a backdoor event right away, followed by an ePrepare event to
+// start over the round
+func (m *ConsensusFSM) Unstick() {
+	m.produce(m.ctx.NewBackdoorEvt(sPrepare), 0)
+	m.produceConsensusEvent(ePrepare, 0)
+}
+
+// ForceState moves the FSM into a state by a backdoor event right away, regardless of the current state. Forcing it
+// into the prepare state starts over the round as Unstick does. It returns ErrUndefinedState for a state not defined
+// by the FSM.
+func (m *ConsensusFSM) ForceState(dst fsm.State) error {
+	defined := false
+	for _, state := range consensusStates {
+		if state == dst {
+			defined = true
+			break
+		}
+	}
+	if !defined {
+		return errors.Wrapf(ErrUndefinedState, "failed to force the FSM into state %s", dst)
+	}
+	if dst == sPrepare {
+		m.Unstick()
+		return nil
+	}
+	m.produce(m.ctx.NewBackdoorEvt(dst), 0)
+	return nil
+}
+
 // ProduceReceiveBlockEvent produces an eReceiveBlock event after delay
 func (m *ConsensusFSM) ProduceReceiveBlockEvent(block interface{}) {
 	m.produce(m.ctx.NewConsensusEvent(eReceiveBlock, block), 0)
@@ -332,7 +415,7 @@ func (m *ConsensusFSM) handle(evt *ConsensusEvent) error {
 	if m.ctx.IsFutureEvent(evt) {
 		m.ctx.Logger().Debug("future event", zap.Any("event", evt.Type()))
 		// TODO: find a more appropriate delay
-		m.produce(evt, m.cfg.UnmatchedEventInterval)
+		m.produce(evt, m.config().UnmatchedEventInterval)
 		consensusEvtsMtc.WithLabelValues(string(evt.Type()), "backoff").Inc()
 		return nil
 	}
@@ -340,6 +423,9 @@ func (m *ConsensusFSM) handle(evt *ConsensusEvent) error {
 	err := m.fsm.Handle(evt)
 	switch errors.Cause(err) {
 	case nil:
+		if dst := m.fsm.CurrentState(); dst != src {
+			m.setStateSince(m.clock.Now())
+		}
 		m.ctx.Logger().Debug(
 			"consensus state transition happens",
 			zap.String("src", string(src)),
@@ -352,7 +438,7 @@ func (m *ConsensusFSM) handle(evt *ConsensusEvent) error {
 			consensusEvtsMtc.WithLabelValues(string(evt.Type()), "stale").Inc()
 			return nil
 		}
-		m.produce(evt, m.cfg.UnmatchedEventInterval)
+		m.produce(evt, m.config().UnmatchedEventInterval)
 		m.ctx.Logger().Debug(
 			"consensus state transition could find the match",
 			zap.String("src", string(src)),
@@ -397,7 +483,7 @@ func (m *ConsensusFSM) prepare(_ fsm.Event) (fsm.State, error) {
 		m.ctx.Logger().Error("Error during prepare", zap.Error(err))
 		return m.BackToPrepare(0)
 	}
-	if !m.ctx.IsDelegate() {
+	if !m.ctx.IsDelegate() && !isObserver(m.ctx) {
 		return m.BackToPrepare(0)
 	}
 	proposal, err := m.ctx.Proposal()
@@ -411,7 +497,9 @@ func (m *ConsensusFSM) prepare(_ fsm.Event) (fsm.State, error) {
 		m.ctx.Broadcast(proposal)
 		m.ProduceReceiveBlockEvent(proposal)
 	}
-	ttl := m.cfg.AcceptBlockTTL
+	// the config reloaded by the context takes effect since this round
+	cfg := m.config()
+	ttl := cfg.AcceptBlockTTL
 	if overtime > 0 {
 		ttl -= overtime
 	}
@@ -419,26 +507,29 @@ func (m *ConsensusFSM) prepare(_ fsm.Event) (fsm.State, error) {
 	if preCommitEndorsement := m.ctx.PreCommitEndorsement(); preCommitEndorsement != nil {
 		cEvt := m.ctx.NewConsensusEvent(eBroadcastPreCommitEndorsement, preCommitEndorsement)
 		m.produce(cEvt, ttl)
-		ttl += m.cfg.AcceptProposalEndorsementTTL
+		ttl += cfg.AcceptProposalEndorsementTTL
 		m.produce(cEvt, ttl)
-		ttl += m.cfg.AcceptLockEndorsementTTL
+		ttl += cfg.AcceptLockEndorsementTTL
 		m.produce(cEvt, ttl)
-		ttl += m.cfg.CommitTTL
+		ttl += cfg.CommitTTL
 		m.produceConsensusEvent(eStopReceivingPreCommitEndorsement, ttl)
 
 		return sAcceptPreCommitEndorsement, nil
 	}
 	m.produceConsensusEvent(eFailedToReceiveBlock, ttl)
-	ttl += m.cfg.AcceptProposalEndorsementTTL
+	ttl += cfg.AcceptProposalEndorsementTTL
 	m.produceConsensusEvent(eStopReceivingProposalEndorsement, ttl)
-	ttl += m.cfg.AcceptLockEndorsementTTL
+	ttl += cfg.AcceptLockEndorsementTTL
 	m.produceConsensusEvent(eStopReceivingLockEndorsement, ttl)
-	ttl += m.cfg.CommitTTL
+	ttl += cfg.CommitTTL
 	m.produceConsensusEvent(eStopReceivingPreCommitEndorsement, ttl)
 
 	return sAcceptBlockProposal, nil
 }
 
+// onReceiveBlock handles a block proposal received in the accept-block phase. Without a proposal buffer, a valid
+// proposal is endorsed right away. Otherwise, the context keeps the winner of the proposals received, which is
+// endorsed once the buffer expires, such that the node never endorses two competing proposals in a round.
 func (m *ConsensusFSM) onReceiveBlock(evt fsm.Event) (fsm.State, error) {
 	m.ctx.Logger().Debug("Receive block")
 	cEvt, ok := evt.(*ConsensusEvent)
@@ -446,12 +537,23 @@ func (m *ConsensusFSM) onReceiveBlock(evt fsm.Event) (fsm.State, error) {
 		m.ctx.Logger().Error("invalid fsm event", zap.Any("event", evt))
 		return sAcceptBlockProposal, nil
 	}
-	if err := m.processBlock(cEvt.Data()); err != nil {
-		m.ctx.Logger().Debug("Failed to generate proposal endorsement", zap.Error(err))
+	bufferTTL := m.config().ProposalBufferTTL
+	if bufferTTL <= 0 {
+		if err := m.processBlock(cEvt.Data()); err != nil {
+			m.ctx.Logger().Debug("Failed to generate proposal endorsement", zap.Error(err))
+			return sAcceptBlockProposal, nil
+		}
+
+		return sAcceptProposalEndorsement, nil
+	}
+	if err := m.ctx.ReceiveBlock(cEvt.Data()); err != nil {
+		m.ctx.Logger().Debug("Failed to accept block proposal", zap.Error(err))
 		return sAcceptBlockProposal, nil
 	}
+	// the buffer of the first valid proposal expires first, the events of the later ones find no match and go stale
+	m.produceConsensusEvent(eEndorseProposal, bufferTTL)
 
-	return sAcceptProposalEndorsement, nil
+	return sAcceptBlockProposal, nil
 }
 
 func (m *ConsensusFSM) processBlock(block interface{}) error {
@@ -464,8 +566,9 @@ func (m *ConsensusFSM) processBlock(block interface{}) error {
 	return nil
 }
 
-func (m *ConsensusFSM) onFailedToReceiveBlock(evt fsm.Event) (fsm.State, error) {
-	m.ctx.Logger().Warn("didn't receive the proposed block before timeout")
+// onEndorseProposal ends the accept-block phase once the proposal buffer expires, endorsing the block proposal kept by
+// the context
+func (m *ConsensusFSM) onEndorseProposal(evt fsm.Event) (fsm.State, error) {
 	if err := m.processBlock(nil); err != nil {
 		m.ctx.Logger().Debug("Failed to generate proposal endorsement", zap.Error(err))
 	}
@@ -473,6 +576,11 @@ func (m *ConsensusFSM) onFailedToReceiveBlock(evt fsm.Event) (fsm.State, error)
 	return sAcceptProposalEndorsement, nil
 }
 
+// onFailedToReceiveBlock ends the accept-block phase, endorsing the block proposal kept by the context if any
+func (m *ConsensusFSM) onFailedToReceiveBlock(evt fsm.Event) (fsm.State, error) {
+	return m.onEndorseProposal(evt)
+}
+
 func (m *ConsensusFSM) onReceiveProposalEndorsement(evt fsm.Event) (fsm.State, error) {
 	cEvt, ok := evt.(*ConsensusEvent)
 	if !ok {
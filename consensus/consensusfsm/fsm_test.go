@@ -62,6 +62,114 @@ func TestBackdoorEvt(t *testing.T) {
 	}
 }
 
+func TestUnstick(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClock := clock.NewMock()
+	mockCtx := NewMockContext(ctrl)
+	mockCtx.EXPECT().IsFutureEvent(gomock.Any()).Return(false).AnyTimes()
+	mockCtx.EXPECT().IsStaleEvent(gomock.Any()).Return(false).AnyTimes()
+	mockCtx.EXPECT().Logger().Return(log.Logger("consensus")).AnyTimes()
+	mockCtx.EXPECT().NewConsensusEvent(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(eventType fsm.EventType, data interface{}) *ConsensusEvent {
+			return &ConsensusEvent{
+				eventType: eventType,
+				data:      data,
+			}
+		}).AnyTimes()
+	mockCtx.EXPECT().NewBackdoorEvt(gomock.Any()).DoAndReturn(
+		func(dst fsm.State) *ConsensusEvent {
+			return &ConsensusEvent{
+				eventType: BackdoorEvent,
+				data:      dst,
+			}
+		}).AnyTimes()
+	cfsm, err := NewConsensusFSM(Config{
+		EventChanSize:                10,
+		AcceptBlockTTL:               4 * time.Second,
+		AcceptProposalEndorsementTTL: 2 * time.Second,
+		AcceptLockEndorsementTTL:     2 * time.Second,
+		CommitTTL:                    2 * time.Second,
+	}, mockCtx, mockClock)
+	require.NoError(err)
+	require.NoError(cfsm.Start(context.Background()))
+	defer func() {
+		require.NoError(cfsm.Stop(context.Background()))
+	}()
+	state, since := cfsm.CurrentStateSince()
+	require.Equal(sPrepare, state)
+	require.Equal(mockClock.Now(), since)
+
+	// the FSM gets stuck accepting the lock endorsements, which isn't reset by a transition to the same state
+	mockClock.Add(time.Minute)
+	stuckSince := mockClock.Now()
+	cfsm.produce(mockCtx.NewBackdoorEvt(sAcceptLockEndorsement), 0)
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, 100*time.Millisecond, func() (bool, error) {
+		return cfsm.CurrentState() == sAcceptLockEndorsement, nil
+	}))
+	mockClock.Add(time.Minute)
+	cfsm.produce(mockCtx.NewBackdoorEvt(sAcceptLockEndorsement), 0)
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, 100*time.Millisecond, func() (bool, error) {
+		return cfsm.NumPendingEvents() == 0, nil
+	}))
+	state, since = cfsm.CurrentStateSince()
+	require.Equal(sAcceptLockEndorsement, state)
+	require.Equal(stuckSince, since)
+
+	// unsticking it starts over a round from the prepare state
+	mockClock.Add(10 * time.Minute)
+	mockCtx.EXPECT().Prepare().Return(nil).Times(1)
+	mockCtx.EXPECT().IsDelegate().Return(true).Times(1)
+	mockCtx.EXPECT().Proposal().Return(nil, nil).Times(1)
+	mockCtx.EXPECT().WaitUntilRoundStart().Return(time.Duration(0)).Times(1)
+	mockCtx.EXPECT().PreCommitEndorsement().Return(nil).Times(1)
+	cfsm.Unstick()
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, 100*time.Millisecond, func() (bool, error) {
+		return cfsm.CurrentState() == sAcceptBlockProposal, nil
+	}))
+	_, since = cfsm.CurrentStateSince()
+	require.Equal(mockClock.Now(), since)
+}
+
+func TestForceState(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCtx := NewMockContext(ctrl)
+	mockCtx.EXPECT().IsFutureEvent(gomock.Any()).Return(false).AnyTimes()
+	mockCtx.EXPECT().IsStaleEvent(gomock.Any()).Return(false).AnyTimes()
+	mockCtx.EXPECT().Logger().Return(log.Logger("consensus")).AnyTimes()
+	mockCtx.EXPECT().NewBackdoorEvt(gomock.Any()).DoAndReturn(
+		func(dst fsm.State) *ConsensusEvent {
+			return &ConsensusEvent{
+				eventType: BackdoorEvent,
+				data:      dst,
+			}
+		}).AnyTimes()
+	cfsm, err := NewConsensusFSM(Config{EventChanSize: 10}, mockCtx, clock.NewMock())
+	require.NoError(err)
+	require.NoError(cfsm.Start(context.Background()))
+	defer func() {
+		require.NoError(cfsm.Stop(context.Background()))
+	}()
+
+	// an undefined state is rejected without touching the FSM
+	err = cfsm.ForceState("S_UNDEFINED")
+	require.Equal(ErrUndefinedState, errors.Cause(err))
+	require.Zero(cfsm.NumPendingEvents())
+	require.Equal(sPrepare, cfsm.CurrentState())
+
+	for _, state := range []fsm.State{sAcceptLockEndorsement, sAcceptBlockProposal} {
+		require.NoError(cfsm.ForceState(state))
+		require.NoError(testutil.WaitUntil(10*time.Millisecond, 100*time.Millisecond, func() (bool, error) {
+			return cfsm.CurrentState() == state, nil
+		}))
+	}
+}
+
 func TestStateTransitionFunctions(t *testing.T) {
 	t.Parallel()
 	require := require.New(t)
@@ -217,14 +325,46 @@ func TestStateTransitionFunctions(t *testing.T) {
 			require.Equal(sAcceptBlockProposal, state)
 		})
 		t.Run("success", func(t *testing.T) {
+			// without a proposal buffer, the block is endorsed right away
 			mockCtx.EXPECT().NewProposalEndorsement(gomock.Any()).Return(NewMockEndorsement(ctrl), nil).Times(1)
 			mockCtx.EXPECT().Broadcast(gomock.Any()).Return().Times(1)
 			state, err := cfsm.onReceiveBlock(&ConsensusEvent{data: NewMockEndorsement(ctrl)})
 			require.NoError(err)
 			require.Equal(sAcceptProposalEndorsement, state)
+			require.Equal(1, cfsm.NumPendingEvents())
 			evt := <-cfsm.evtq
 			require.Equal(eReceiveProposalEndorsement, evt.Type())
 		})
+		t.Run("competing-blocks", func(t *testing.T) {
+			cfg := cfsm.config()
+			bufferCfg := cfg
+			bufferCfg.ProposalBufferTTL = 500 * time.Millisecond
+			cfsm.SetConfig(bufferCfg)
+			defer cfsm.SetConfig(cfg)
+			// the blocks received are buffered, the invalid ones don't extend the buffer
+			mockCtx.EXPECT().ReceiveBlock(gomock.Any()).Return(nil).Times(1)
+			mockCtx.EXPECT().ReceiveBlock(gomock.Any()).Return(errors.New("some error")).Times(1)
+			state, err := cfsm.onReceiveBlock(&ConsensusEvent{data: NewMockEndorsement(ctrl)})
+			require.NoError(err)
+			require.Equal(sAcceptBlockProposal, state)
+			state, err = cfsm.onReceiveBlock(&ConsensusEvent{data: NewMockEndorsement(ctrl)})
+			require.NoError(err)
+			require.Equal(sAcceptBlockProposal, state)
+			require.Equal(0, cfsm.NumPendingEvents())
+			// the winner is endorsed once the buffer expires, well ahead of the accept-block timeout
+			time.Sleep(100 * time.Millisecond)
+			mockClock.Add(bufferCfg.ProposalBufferTTL)
+			evt := <-cfsm.evtq
+			require.Equal(eEndorseProposal, evt.Type())
+			mockCtx.EXPECT().NewProposalEndorsement(nil).Return(NewMockEndorsement(ctrl), nil).Times(1)
+			mockCtx.EXPECT().Broadcast(gomock.Any()).Return().Times(1)
+			state, err = cfsm.onEndorseProposal(evt)
+			require.NoError(err)
+			require.Equal(sAcceptProposalEndorsement, state)
+			evt = <-cfsm.evtq
+			require.Equal(eReceiveProposalEndorsement, evt.Type())
+			require.Equal(0, cfsm.NumPendingEvents())
+		})
 	})
 	t.Run("onFailedToReceiveBlock", func(t *testing.T) {
 		mockCtx.EXPECT().NewProposalEndorsement(nil).Return(NewMockEndorsement(ctrl), nil).Times(1)
@@ -483,3 +623,20 @@ func TestStateTransitionFunctions(t *testing.T) {
 		})
 	})
 }
+
+type observerContext struct {
+	Context
+	observer bool
+}
+
+func (ctx *observerContext) IsObserver() bool { return ctx.observer }
+
+func TestIsObserver(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCtx := NewMockContext(ctrl)
+	require.False(isObserver(mockCtx))
+	require.False(isObserver(&observerContext{Context: mockCtx}))
+	require.True(isObserver(&observerContext{Context: mockCtx, observer: true}))
+}
@@ -242,6 +242,20 @@ func (mr *MockContextMockRecorder) PreCommitEndorsement() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PreCommitEndorsement", reflect.TypeOf((*MockContext)(nil).PreCommitEndorsement))
 }
 
+// ReceiveBlock mocks base method
+func (m *MockContext) ReceiveBlock(arg0 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReceiveBlock", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReceiveBlock indicates an expected call of ReceiveBlock
+func (mr *MockContextMockRecorder) ReceiveBlock(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceiveBlock", reflect.TypeOf((*MockContext)(nil).ReceiveBlock), arg0)
+}
+
 // NewProposalEndorsement mocks base method
 func (m *MockContext) NewProposalEndorsement(arg0 interface{}) (interface{}, error) {
 	m.ctrl.T.Helper()
@@ -32,7 +32,7 @@ func (n *Noop) Start(_ context.Context) error { return nil }
 func (n *Noop) Stop(_ context.Context) error { return nil }
 
 // HandleConsensusMsg handles incoming consensus message
-func (n *Noop) HandleConsensusMsg(*iotextypes.ConsensusMessage) error {
+func (n *Noop) HandleConsensusMsg(context.Context, *iotextypes.ConsensusMessage) error {
 	log.Logger("consensus").Warn("Noop scheme does not handle incoming consensus message.")
 	return nil
 }
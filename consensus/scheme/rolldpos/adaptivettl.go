@@ -0,0 +1,173 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/consensus/consensusfsm"
+)
+
+var acceptBlockTTLMtc = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "iotex_consensus_accept_block_ttl",
+		Help: "AcceptBlockTTL adapted to the latency of the block proposals, in seconds",
+	},
+	[]string{},
+)
+
+func init() {
+	prometheus.MustRegister(acceptBlockTTLMtc)
+}
+
+// adaptiveTTL tracks the latency of the block proposals, i.e., the time from the start of a round to receiving its
+// proposal, over the recent rounds, and nudges AcceptBlockTTL toward it. The other phases of the round borrow from
+// or lend to AcceptBlockTTL in proportion to their configured TTLs, such that the sum of the TTLs is kept.
+type adaptiveTTL struct {
+	mutex sync.Mutex
+	min   time.Duration
+	max   time.Duration
+	// base is the configured FSM config to adapt
+	base consensusfsm.Config
+	// latencies is the ring of the latencies of the recent rounds
+	latencies []time.Duration
+	next      int
+	full      bool
+	// observed is the round whose latency is observed already
+	observed *roundCtx
+}
+
+// newAdaptiveTTL returns the adaptive AcceptBlockTTL of the config, or nil if it is disabled
+func newAdaptiveTTL(cfg config.RollDPoS) (*adaptiveTTL, error) {
+	if !cfg.AdaptiveAcceptBlockTTL {
+		return nil, nil
+	}
+	if cfg.MinAcceptBlockTTL <= 0 || cfg.MinAcceptBlockTTL > cfg.MaxAcceptBlockTTL {
+		return nil, errors.Errorf(
+			"invalid adaptive accept block ttl range [%s, %s]",
+			cfg.MinAcceptBlockTTL,
+			cfg.MaxAcceptBlockTTL,
+		)
+	}
+	if cfg.ProposalLatencyWindow <= 0 {
+		return nil, errors.Errorf("invalid proposal latency window %d", cfg.ProposalLatencyWindow)
+	}
+	a := &adaptiveTTL{
+		min:       cfg.MinAcceptBlockTTL,
+		max:       cfg.MaxAcceptBlockTTL,
+		latencies: make([]time.Duration, cfg.ProposalLatencyWindow),
+	}
+	if err := a.Validate(cfg.FSM); err != nil {
+		return nil, err
+	}
+	a.SetBase(cfg.FSM)
+	return a, nil
+}
+
+// Validate makes sure that the other phases than accepting block are long enough to lend the max AcceptBlockTTL
+func (a *adaptiveTTL) Validate(cfg consensusfsm.Config) error {
+	if a == nil {
+		return nil
+	}
+	if total := sumOfTTLs(cfg); a.max >= total || cfg.AcceptBlockTTL >= total {
+		return errors.Errorf(
+			"invalid ttl config, the sum of ttls %s leaves no time for the other phases than accepting block, with "+
+				"acceptBlockTTL %s and max adaptive acceptBlockTTL %s",
+			total,
+			cfg.AcceptBlockTTL,
+			a.max,
+		)
+	}
+	return nil
+}
+
+// SetBase replaces the configured FSM config to adapt
+func (a *adaptiveTTL) SetBase(cfg consensusfsm.Config) {
+	if a == nil {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.base = cfg
+}
+
+// Observe records the latency of the block proposal of the round, which is only observed once
+func (a *adaptiveTTL) Observe(round *roundCtx, latency time.Duration) {
+	if a == nil {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.observed == round {
+		return
+	}
+	a.observed = round
+	a.latencies[a.next] = latency
+	a.next = (a.next + 1) % len(a.latencies)
+	if a.next == 0 {
+		a.full = true
+	}
+}
+
+// Adapt returns the FSM config, whose AcceptBlockTTL moves halfway from the current one to the max latency of the
+// recent rounds with a headroom of a quarter, within the range
+func (a *adaptiveTTL) Adapt(current time.Duration) consensusfsm.Config {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	n := a.next
+	if a.full {
+		n = len(a.latencies)
+	}
+	ttl := current
+	if n > 0 {
+		var maxLatency time.Duration
+		for _, latency := range a.latencies[:n] {
+			if latency > maxLatency {
+				maxLatency = latency
+			}
+		}
+		target := maxLatency + maxLatency/4
+		ttl = current + (target-current)/2
+	}
+	if ttl < a.min {
+		ttl = a.min
+	}
+	if ttl > a.max {
+		ttl = a.max
+	}
+	acceptBlockTTLMtc.WithLabelValues().Set(ttl.Seconds())
+	return withAcceptBlockTTL(a.base, ttl)
+}
+
+// withAcceptBlockTTL returns the config with the AcceptBlockTTL, whose difference from the configured one is taken
+// from or given to the other phases in proportion, such that the sum of the TTLs is kept
+func withAcceptBlockTTL(cfg consensusfsm.Config, ttl time.Duration) consensusfsm.Config {
+	total := sumOfTTLs(cfg)
+	others := total - cfg.AcceptBlockTTL
+	if others <= 0 || ttl == cfg.AcceptBlockTTL {
+		return cfg
+	}
+	scale := float64(total-ttl) / float64(others)
+	cfg.AcceptBlockTTL = ttl
+	cfg.AcceptProposalEndorsementTTL = time.Duration(float64(cfg.AcceptProposalEndorsementTTL) * scale)
+	cfg.AcceptLockEndorsementTTL = time.Duration(float64(cfg.AcceptLockEndorsementTTL) * scale)
+	// the rounding error goes to the commit phase
+	cfg.CommitTTL = total - ttl - cfg.AcceptProposalEndorsementTTL - cfg.AcceptLockEndorsementTTL
+	return cfg
+}
+
+func sumOfTTLs(cfg consensusfsm.Config) time.Duration {
+	return cfg.AcceptBlockTTL + cfg.AcceptProposalEndorsementTTL + cfg.AcceptLockEndorsementTTL + cfg.CommitTTL
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/blockchain/block"
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/consensus/consensusfsm"
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/state"
+	"github.com/iotexproject/iotex-core/test/identityset"
+	"github.com/iotexproject/iotex-core/test/mock/mock_factory"
+)
+
+func TestAdaptiveTTL(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	a, err := newAdaptiveTTL(cfg)
+	require.NoError(err)
+	require.Nil(a)
+	a.Observe(&roundCtx{}, time.Second)
+	require.NoError(a.Validate(cfg.FSM))
+
+	cfg.AdaptiveAcceptBlockTTL = true
+	cfg.MinAcceptBlockTTL = 2 * time.Second
+	cfg.MaxAcceptBlockTTL = 6 * time.Second
+	cfg.ProposalLatencyWindow = 5
+	for _, invalid := range []func(*config.RollDPoS){
+		func(cfg *config.RollDPoS) { cfg.MinAcceptBlockTTL = 0 },
+		func(cfg *config.RollDPoS) { cfg.MaxAcceptBlockTTL = time.Second },
+		func(cfg *config.RollDPoS) { cfg.ProposalLatencyWindow = 0 },
+		// no time is left for the other phases
+		func(cfg *config.RollDPoS) { cfg.MaxAcceptBlockTTL = 10 * time.Second },
+	} {
+		invalidCfg := cfg
+		invalid(&invalidCfg)
+		_, err = newAdaptiveTTL(invalidCfg)
+		require.Error(err)
+	}
+	a, err = newAdaptiveTTL(cfg)
+	require.NoError(err)
+	total := sumOfTTLs(cfg.FSM)
+
+	// nothing changes without any latency observed
+	require.Equal(cfg.FSM, a.Adapt(cfg.FSM.AcceptBlockTTL))
+
+	// the proposals arrive 5s after the round start, beyond the 4s AcceptBlockTTL
+	ttl := cfg.FSM.AcceptBlockTTL
+	for i := 0; i < 10; i++ {
+		round := &roundCtx{}
+		a.Observe(round, 5*time.Second)
+		// a round is observed once
+		a.Observe(round, time.Second)
+		fsmCfg := a.Adapt(ttl)
+		require.True(fsmCfg.AcceptBlockTTL > ttl || fsmCfg.AcceptBlockTTL == cfg.MaxAcceptBlockTTL)
+		require.True(fsmCfg.AcceptBlockTTL <= cfg.MaxAcceptBlockTTL)
+		// the other phases lend the time in proportion
+		require.Equal(total, sumOfTTLs(fsmCfg))
+		require.True(fsmCfg.AcceptProposalEndorsementTTL < cfg.FSM.AcceptProposalEndorsementTTL)
+		require.Equal(fsmCfg.AcceptProposalEndorsementTTL, fsmCfg.AcceptLockEndorsementTTL)
+		require.Equal(cfg.FSM.UnmatchedEventTTL, fsmCfg.UnmatchedEventTTL)
+		ttl = fsmCfg.AcceptBlockTTL
+	}
+	// the headroom of a quarter over 5s is capped by the max
+	require.Equal(cfg.MaxAcceptBlockTTL, ttl)
+	require.Equal(time.Duration(float64(2*time.Second)*float64(total-ttl)/float64(total-4*time.Second)),
+		a.Adapt(ttl).AcceptProposalEndorsementTTL)
+
+	// the ttl shrinks back once the proposals arrive early in the window
+	for i := 0; i < 5; i++ {
+		a.Observe(&roundCtx{}, 100*time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		fsmCfg := a.Adapt(ttl)
+		require.True(fsmCfg.AcceptBlockTTL < ttl || fsmCfg.AcceptBlockTTL == cfg.MinAcceptBlockTTL)
+		require.Equal(total, sumOfTTLs(fsmCfg))
+		ttl = fsmCfg.AcceptBlockTTL
+	}
+	require.Equal(cfg.MinAcceptBlockTTL, ttl)
+
+	// a reloaded config is adapted since
+	reloaded := cfg.FSM
+	reloaded.CommitTTL = 4 * time.Second
+	require.NoError(a.Validate(reloaded))
+	a.SetBase(reloaded)
+	require.Equal(total+2*time.Second, sumOfTTLs(a.Adapt(ttl)))
+	reloaded.AcceptProposalEndorsementTTL = 0
+	reloaded.AcceptLockEndorsementTTL = 0
+	reloaded.CommitTTL = 0
+	require.Error(a.Validate(reloaded))
+}
+
+func TestAdaptiveAcceptBlockTTL(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	cfg.AdaptiveAcceptBlockTTL = true
+	cfg.ProposalLatencyWindow = 3
+	blockInterval := 20 * time.Second
+	b, rp := makeChain(t)
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	rctx, err := newRollDPoSCtx(
+		cfg, true, blockInterval, time.Second, true, b, nil, rp, nil, candidatesByHeight,
+		identityset.Address(0).String(), identityset.PrivateKey(0), c,
+	)
+	require.NoError(err)
+	var fsmCfg *consensusfsm.Config
+	rctx.reloadFSM = func(cfg consensusfsm.Config) { fsmCfg = &cfg }
+	require.NoError(rctx.Prepare())
+	require.Nil(fsmCfg)
+
+	// the proposal of another delegate arrives 5s into the round, after the proposal phase
+	propose := func() {
+		c.Add(rctx.round.StartTime().Add(5 * time.Second).Sub(c.Now()))
+		blk, err := block.NewTestingBuilder().
+			SetHeight(rctx.round.Height()).
+			SetTimeStamp(rctx.round.StartTime()).
+			SignAndBuild(identityset.PrivateKey(1))
+		require.NoError(err)
+		blk.WorkingSet = mock_factory.NewMockWorkingSet(ctrl)
+		bp := newBlockProposal(&blk, nil)
+		en, err := endorsement.Endorse(identityset.PrivateKey(1), bp, rctx.round.StartTime())
+		require.NoError(err)
+		_, err = rctx.NewProposalEndorsement(NewEndorsedConsensusMessage(rctx.round.Height(), bp, en))
+		require.NoError(err)
+	}
+	ttl := cfg.FSM.AcceptBlockTTL
+	total := sumOfTTLs(cfg.FSM)
+	for i := 0; i < 10; i++ {
+		propose()
+		c.Add(blockInterval)
+		require.NoError(rctx.Prepare())
+		require.NotNil(fsmCfg)
+		require.Equal(*fsmCfg, rctx.cfg.FSM)
+		require.True(fsmCfg.AcceptBlockTTL > ttl || fsmCfg.AcceptBlockTTL == cfg.MaxAcceptBlockTTL)
+		require.True(fsmCfg.AcceptBlockTTL <= cfg.MaxAcceptBlockTTL)
+		require.Equal(total, sumOfTTLs(*fsmCfg))
+		require.Equal(rctx.round.StartTime().Add(fsmCfg.AcceptBlockTTL), rctx.deadlines.acceptBlock)
+		ttl = fsmCfg.AcceptBlockTTL
+	}
+	require.Equal(cfg.MaxAcceptBlockTTL, ttl)
+	require.True(ttl > 5*time.Second)
+
+	// nothing changes once it settles
+	fsmCfg = nil
+	propose()
+	c.Add(blockInterval)
+	require.NoError(rctx.Prepare())
+	require.Nil(fsmCfg)
+}
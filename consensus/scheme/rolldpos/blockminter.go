@@ -0,0 +1,54 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"time"
+
+	"github.com/iotexproject/iotex-core/action"
+	"github.com/iotexproject/iotex-core/actpool/actioniterator"
+	"github.com/iotexproject/iotex-core/blockchain"
+	"github.com/iotexproject/iotex-core/blockchain/block"
+)
+
+type (
+	// BlockMinter mints the block to propose out of the pending actions. A chain variant could inject its own
+	// minter, e.g., to include a system action in every block, while the blocks received are still accepted or
+	// rejected by the blockchain validation only.
+	BlockMinter interface {
+		Mint(actionMap map[string][]action.SealedEnvelope, ts time.Time) (*block.Block, error)
+	}
+
+	// ActionIteratorMinter is a BlockMinter which mints the block out of the actions picked in the order of an
+	// iterator, such that the block assembly honors the ordering strategy of the action pool
+	ActionIteratorMinter interface {
+		MintWithActionIterator(actionIterator actioniterator.ActionIterator, ts time.Time) (*block.Block, error)
+	}
+
+	// chainMinter is the default block minter, which mints a new block on the blockchain
+	chainMinter struct {
+		chain blockchain.Blockchain
+	}
+)
+
+// NewBlockMinter returns the default block minter of the blockchain
+func NewBlockMinter(chain blockchain.Blockchain) BlockMinter {
+	return &chainMinter{chain: chain}
+}
+
+// Mint mints a new block on the blockchain
+func (m *chainMinter) Mint(actionMap map[string][]action.SealedEnvelope, ts time.Time) (*block.Block, error) {
+	return m.chain.MintNewBlock(actionMap, ts)
+}
+
+// MintWithActionIterator mints a new block on the blockchain out of the actions picked in the order of the iterator
+func (m *chainMinter) MintWithActionIterator(
+	actionIterator actioniterator.ActionIterator,
+	ts time.Time,
+) (*block.Block, error) {
+	return m.chain.MintNewBlockWithActionIterator(actionIterator, ts)
+}
@@ -7,17 +7,52 @@
 package rolldpos
 
 import (
+	"bytes"
+
 	"github.com/golang/protobuf/proto"
 	"github.com/iotexproject/go-pkgs/hash"
 	"github.com/iotexproject/iotex-core/blockchain/block"
 	"github.com/iotexproject/iotex-core/endorsement"
 	"github.com/iotexproject/iotex-core/pkg/util/byteutil"
 	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/pkg/errors"
+)
+
+// proofType tells what the endorsements attached to a block proposal prove
+type proofType byte
+
+const (
+	// unspecifiedProof is used by the proposals in the old format, where the proof is taken as a proof of lock if the
+	// block is forwarded by a proposer other than its producer
+	unspecifiedProof proofType = iota
+	// lockProof shows that a majority of the delegates endorsed the block in a previous round
+	lockProof
+	// unlockProof shows that a previously locked block may be abandoned for the new block
+	unlockProof
 )
 
+const (
+	// proposalHeaderMagic starts the signature of the header of a block proposal, i.e., a leading endorsement without
+	// endorser, which no genuine endorsement could be taken for
+	proposalHeaderMagic = "iotex-bp"
+	// proposalHeaderVersion is the version of the header of a block proposal
+	proposalHeaderVersion = 1
+	// proposalHeaderLen is the length of the signature of the header, i.e., the magic, the version, the proof type and
+	// whether the proof is aggregated
+	proposalHeaderLen = len(proposalHeaderMagic) + 3
+)
+
+// blockProposal is a block along with the proof of lock or unlock attached by its proposer. A proposal with a typed or
+// an aggregated proof carries a versioned header ahead of the endorsements of the proof, as the protobuf message of
+// the block proposal has no field of its own for either. The nodes not upgraded can't parse the header, hence the
+// proposals with a header are only sent from UnlockProofHeight, before which those in the old format are sent.
 type blockProposal struct {
-	block       *block.Block
+	block *block.Block
+	// proofOfLock is either a proof of lock or a proof of unlock, depending on proofType
 	proofOfLock []*endorsement.Endorsement
+	// aggregatedProofOfLock is the compact form of proof of lock, which is used in place of proofOfLock if not nil
+	aggregatedProofOfLock *endorsement.Aggregate
+	proofType             proofType
 }
 
 func newBlockProposal(blk *block.Block, pol []*endorsement.Endorsement) *blockProposal {
@@ -27,6 +62,22 @@ func newBlockProposal(blk *block.Block, pol []*endorsement.Endorsement) *blockPr
 	}
 }
 
+func newBlockProposalWithProof(blk *block.Block, proof []*endorsement.Endorsement, t proofType) *blockProposal {
+	return &blockProposal{
+		block:       blk,
+		proofOfLock: proof,
+		proofType:   t,
+	}
+}
+
+func newCompactBlockProposal(blk *block.Block, pol *endorsement.Aggregate) *blockProposal {
+	return &blockProposal{
+		block:                 blk,
+		aggregatedProofOfLock: pol,
+		proofType:             lockProof,
+	}
+}
+
 func (bp *blockProposal) Height() uint64 {
 	return bp.block.Height()
 }
@@ -34,6 +85,21 @@ func (bp *blockProposal) Height() uint64 {
 func (bp *blockProposal) Proto() (*iotextypes.BlockProposal, error) {
 	bPb := bp.block.ConvertToBlockPb()
 	endorsements := []*iotextypes.Endorsement{}
+	if bp.hasHeader() {
+		header := make([]byte, 0, proposalHeaderLen)
+		header = append(header, proposalHeaderMagic...)
+		header = append(header, proposalHeaderVersion, byte(bp.proofType), 0)
+		if bp.aggregatedProofOfLock != nil {
+			header[proposalHeaderLen-1] = 1
+		}
+		endorsements = append(endorsements, &iotextypes.Endorsement{Signature: header})
+	}
+	if bp.aggregatedProofOfLock != nil {
+		// an aggregated proof of lock is carried as a single endorsement without endorser
+		endorsements = append(endorsements, &iotextypes.Endorsement{
+			Signature: bp.aggregatedProofOfLock.Bytes(),
+		})
+	}
 	for _, en := range bp.proofOfLock {
 		ePb, err := en.Proto()
 		if err != nil {
@@ -67,7 +133,47 @@ func (bp *blockProposal) LoadProto(msg *iotextypes.BlockProposal) error {
 		return err
 	}
 	bp.proofOfLock = []*endorsement.Endorsement{}
-	for _, ePb := range msg.Endorsements {
+	bp.aggregatedProofOfLock = nil
+	bp.proofType = unspecifiedProof
+	endorsements := msg.Endorsements
+	aggregated := false
+	if len(endorsements) > 0 &&
+		len(endorsements[0].Endorser) == 0 &&
+		bytes.HasPrefix(endorsements[0].Signature, []byte(proposalHeaderMagic)) {
+		header := endorsements[0].Signature
+		if len(header) != proposalHeaderLen {
+			return errors.Errorf("wrong block proposal header length %d, expecting %d", len(header), proposalHeaderLen)
+		}
+		if v := header[len(proposalHeaderMagic)]; v != proposalHeaderVersion {
+			return errors.Errorf("block proposal version %d not supported", v)
+		}
+		switch t := proofType(header[len(proposalHeaderMagic)+1]); t {
+		case unspecifiedProof, lockProof, unlockProof:
+			bp.proofType = t
+		default:
+			return errors.Errorf("invalid proof type %d", t)
+		}
+		switch header[proposalHeaderLen-1] {
+		case 0:
+		case 1:
+			aggregated = true
+		default:
+			return errors.Errorf("invalid proof encoding %d", header[proposalHeaderLen-1])
+		}
+		endorsements = endorsements[1:]
+	}
+	if aggregated {
+		if len(endorsements) != 1 {
+			return errors.Errorf("expecting 1 aggregated proof of lock, %d given", len(endorsements))
+		}
+		pol, err := endorsement.AggregateFromBytes(endorsements[0].Signature)
+		if err != nil {
+			return err
+		}
+		bp.aggregatedProofOfLock = pol
+		return nil
+	}
+	for _, ePb := range endorsements {
 		en := &endorsement.Endorsement{}
 		if err := en.LoadProto(ePb); err != nil {
 			return err
@@ -76,3 +182,8 @@ func (bp *blockProposal) LoadProto(msg *iotextypes.BlockProposal) error {
 	}
 	return nil
 }
+
+// hasHeader tells whether the proposal carries the header, i.e., its proof is typed or aggregated
+func (bp *blockProposal) hasHeader() bool {
+	return bp.proofType != unspecifiedProof || bp.aggregatedProofOfLock != nil
+}
@@ -8,12 +8,14 @@ package rolldpos
 
 import (
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/iotexproject/iotex-proto/golang/iotextypes"
 	"github.com/stretchr/testify/require"
 
 	"github.com/iotexproject/iotex-core/blockchain/block"
+	"github.com/iotexproject/iotex-core/endorsement"
 	"github.com/iotexproject/iotex-core/test/identityset"
 )
 
@@ -43,6 +45,51 @@ func TestNewBlockProposal(t *testing.T) {
 	pro3, err := bp3.Proto()
 	require.NoError(err)
 	require.EqualValues(pro, pro3)
+
+	// aggregated proof of lock
+	en, err := endorsement.Endorse(identityset.PrivateKey(1), NewConsensusVote(h, COMMIT), time.Unix(10, 0))
+	require.NoError(err)
+	agg, err := endorsement.NewAggregate([]string{identityset.Address(1).String()}, []*endorsement.Endorsement{en})
+	require.NoError(err)
+	bp4 := newCompactBlockProposal(&b, agg)
+	pro4, err := bp4.Proto()
+	require.NoError(err)
+	require.Equal(2, len(pro4.Endorsements))
+	require.Empty(pro4.Endorsements[0].Endorser)
+	bp5 := newBlockProposal(nil, nil)
+	require.NoError(bp5.LoadProto(pro4))
+	require.Empty(bp5.proofOfLock)
+	require.Equal(agg.Bytes(), bp5.aggregatedProofOfLock.Bytes())
+	require.Equal(lockProof, bp5.proofType)
+
+	// proof type
+	for _, pt := range []proofType{lockProof, unlockProof} {
+		bp6 := newBlockProposalWithProof(&b, []*endorsement.Endorsement{en}, pt)
+		pro6, err := bp6.Proto()
+		require.NoError(err)
+		require.Equal(2, len(pro6.Endorsements))
+		bp7 := newBlockProposal(nil, nil)
+		require.NoError(bp7.LoadProto(pro6))
+		require.Equal(pt, bp7.proofType)
+		require.Equal(1, len(bp7.proofOfLock))
+		pro7, err := bp7.Proto()
+		require.NoError(err)
+		require.EqualValues(pro6, pro7)
+	}
+	pro6, err := newBlockProposalWithProof(&b, nil, proofType(3)).Proto()
+	require.NoError(err)
+	require.Error(newBlockProposal(nil, nil).LoadProto(pro6))
+
+	// header of an unknown version or a wrong length
+	pro6, err = newBlockProposalWithProof(&b, []*endorsement.Endorsement{en}, lockProof).Proto()
+	require.NoError(err)
+	header := pro6.Endorsements[0].Signature
+	header[len(proposalHeaderMagic)] = proposalHeaderVersion + 1
+	require.Error(newBlockProposal(nil, nil).LoadProto(pro6))
+	header[len(proposalHeaderMagic)] = proposalHeaderVersion
+	require.NoError(newBlockProposal(nil, nil).LoadProto(pro6))
+	pro6.Endorsements[0].Signature = header[:proposalHeaderLen-1]
+	require.Error(newBlockProposal(nil, nil).LoadProto(pro6))
 }
 func getBlock(t *testing.T) block.Block {
 	require := require.New(t)
@@ -0,0 +1,132 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// clockSkewWindowRounds is the number of block intervals the endorsements are sampled over to measure the clock skew
+const clockSkewWindowRounds = 10
+
+var clockSkewMtc = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "iotex_consensus_clock_skew_seconds",
+		Help: "Skew of the local clock against the delegates, positive if ahead of them",
+	},
+	[]string{},
+)
+
+func init() {
+	prometheus.MustRegister(clockSkewMtc)
+}
+
+type (
+	// clockSample is the latest skew observed against a delegate, and the local time it was observed at
+	clockSample struct {
+		skew time.Duration
+		at   time.Time
+	}
+
+	// clockSkewDetector measures the skew of the local clock against the majority of the delegates, out of the times
+	// their valid vote endorsements are received at
+	clockSkewDetector struct {
+		mutex     sync.Mutex
+		threshold time.Duration
+		window    time.Duration
+		samples   map[string]clockSample
+	}
+)
+
+// newClockSkewDetector returns a detector warning of a skew beyond the threshold, which is nil if the threshold is 0
+func newClockSkewDetector(threshold, blockInterval time.Duration) *clockSkewDetector {
+	if threshold <= 0 {
+		return nil
+	}
+	return &clockSkewDetector{
+		threshold: threshold,
+		window:    clockSkewWindowRounds * blockInterval,
+		samples:   make(map[string]clockSample),
+	}
+}
+
+// endorsementSkew returns the skew of the local clock against the endorser of a vote received at a local time. A vote
+// is endorsed with the deadline of its phase, and is sent between the start of its round and the deadline, such that
+// receiving it out of that span tells how far the local clock is off at least.
+func endorsementSkew(receivedAt, roundStart, timestamp time.Time) time.Duration {
+	switch {
+	case receivedAt.Before(roundStart):
+		return receivedAt.Sub(roundStart)
+	case receivedAt.After(timestamp):
+		return receivedAt.Sub(timestamp)
+	default:
+		return 0
+	}
+}
+
+// Observe records the skew against an endorser, replacing the one observed before
+func (d *clockSkewDetector) Observe(endorser string, skew time.Duration, now time.Time) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.samples[endorser] = clockSample{skew: skew, at: now}
+}
+
+// Skew returns the median of the skews against the delegates observed within the window, which is measured only if
+// they are more than half of the delegates
+func (d *clockSkewDetector) Skew(delegates []string, now time.Time) (time.Duration, bool) {
+	if d == nil {
+		return 0, false
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for endorser, sample := range d.samples {
+		if now.Sub(sample.at) > d.window {
+			delete(d.samples, endorser)
+		}
+	}
+	skews := make([]time.Duration, 0, len(delegates))
+	for _, delegate := range delegates {
+		if sample, ok := d.samples[delegate]; ok {
+			skews = append(skews, sample.skew)
+		}
+	}
+	if len(skews)*2 <= len(delegates) {
+		return 0, false
+	}
+	sort.Slice(skews, func(i, j int) bool { return skews[i] < skews[j] })
+	mid := len(skews) / 2
+	if len(skews)%2 == 1 {
+		return skews[mid], true
+	}
+	return (skews[mid-1] + skews[mid]) / 2, true
+}
+
+// Check sets the skew gauge if the skew is measured, and warns if it is beyond the threshold
+func (d *clockSkewDetector) Check(l *zap.Logger, delegates []string, now time.Time) {
+	skew, ok := d.Skew(delegates, now)
+	if !ok {
+		return
+	}
+	clockSkewMtc.WithLabelValues().Set(skew.Seconds())
+	if skew > d.threshold || skew < -d.threshold {
+		l.Warn(
+			"The local clock is off from the majority of the delegates.",
+			zap.Duration("skew", skew),
+			zap.Duration("threshold", d.threshold),
+		)
+	}
+}
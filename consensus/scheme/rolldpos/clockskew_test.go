@@ -0,0 +1,121 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/go-fsm"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/state"
+	"github.com/iotexproject/iotex-core/test/identityset"
+)
+
+func TestClockSkewDetector(t *testing.T) {
+	require := require.New(t)
+
+	require.Nil(newClockSkewDetector(0, 10*time.Second))
+	// the samples are kept for 10 block intervals
+	d := newClockSkewDetector(time.Second, time.Second)
+	delegates := []string{"a", "b", "c", "d"}
+	now := time.Unix(1562382372, 0)
+	d.Observe("a", -3*time.Second, now)
+	d.Observe("b", -5*time.Second, now)
+	// not measured without a majority of the delegates
+	_, ok := d.Skew(delegates, now)
+	require.False(ok)
+	// the samples of the non-delegates aren't counted
+	d.Observe("x", time.Hour, now)
+	_, ok = d.Skew(delegates, now)
+	require.False(ok)
+	d.Observe("c", 0, now)
+	skew, ok := d.Skew(delegates, now)
+	require.True(ok)
+	require.Equal(-3*time.Second, skew)
+	d.Observe("d", -7*time.Second, now)
+	skew, ok = d.Skew(delegates, now)
+	require.True(ok)
+	require.Equal(-4*time.Second, skew)
+	// the samples beyond the window are dropped
+	d.Observe("a", time.Second, now.Add(5*time.Second))
+	_, ok = d.Skew(delegates, now.Add(11*time.Second))
+	require.False(ok)
+
+	roundStart := now
+	deadline := now.Add(4 * time.Second)
+	require.Equal(-2*time.Second, endorsementSkew(now.Add(-2*time.Second), roundStart, deadline))
+	require.Zero(endorsementSkew(now.Add(time.Second), roundStart, deadline))
+	require.Equal(3*time.Second, endorsementSkew(now.Add(7*time.Second), roundStart, deadline))
+}
+
+func TestClockSkew(t *testing.T) {
+	require := require.New(t)
+
+	cfg := config.Default.Consensus.RollDPoS
+	cfg.ClockSkewThreshold = time.Second
+	b, rp := makeChain(t)
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	broadcastHandler := func(proto.Message) error { return nil }
+	rctx, err := newRollDPoSCtx(
+		cfg, true, 20*time.Second, time.Second, true, b, nil, rp, broadcastHandler, candidatesByHeight,
+		identityset.Address(0).String(), identityset.PrivateKey(0), c,
+	)
+	require.NoError(err)
+	require.NotNil(rctx.clockSkew)
+	require.NoError(rctx.Prepare())
+	blk, err := b.MintNewBlock(nil, rctx.round.StartTime())
+	require.NoError(err)
+	require.NoError(rctx.round.AddBlock(blk))
+	blkHash := blk.HashBlock()
+
+	// the delegates endorse the block 2 rounds ahead of the local clock
+	aheadStart := rctx.round.StartTime().Add(40 * time.Second)
+	timestamp := aheadStart.Add(cfg.FSM.AcceptBlockTTL + cfg.FSM.AcceptProposalEndorsementTTL)
+	vote := NewConsensusVote(blkHash[:], LOCK)
+	delegates := rctx.round.Delegates()
+	majority := len(delegates)/2 + 1
+	receivedAt := c.Now()
+	for i := 1; i <= majority; i++ {
+		en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, timestamp)
+		require.NoError(err)
+		msg := NewEndorsedConsensusMessage(blk.Height(), vote, en)
+		require.NotNil(rctx.NewConsensusEvent(fsm.EventType("E_RECEIVE_LOCK_ENDORSEMENT"), msg))
+		// the vote is processed later than it is received
+		c.Add(time.Second)
+		_, err = rctx.NewPreCommitEndorsement(msg)
+		require.NoError(err)
+	}
+
+	clockSkewMtc.WithLabelValues().Set(0)
+	rctx.clockSkew.Check(zap.NewNop(), delegates, c.Now())
+	skew := promtestutil.ToFloat64(clockSkewMtc.WithLabelValues())
+	require.True(skew < 0, "the local clock is behind")
+	// the median is the skew at the time the vote of the median delegate is received
+	expected := receivedAt.Add(time.Duration(majority/2) * time.Second).Sub(aheadStart)
+	require.Equal(expected.Seconds(), skew)
+}
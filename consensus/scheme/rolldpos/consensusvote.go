@@ -7,6 +7,8 @@
 package rolldpos
 
 import (
+	"fmt"
+
 	"github.com/golang/protobuf/proto"
 	blake2b "github.com/minio/blake2b-simd"
 	"github.com/pkg/errors"
@@ -26,6 +28,20 @@ const (
 	COMMIT ConsensusVoteTopic = 2
 )
 
+// String returns the name of the topic
+func (t ConsensusVoteTopic) String() string {
+	switch t {
+	case PROPOSAL:
+		return "PROPOSAL"
+	case LOCK:
+		return "LOCK"
+	case COMMIT:
+		return "COMMIT"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", uint8(t))
+	}
+}
+
 // ConsensusVote is a vote on a given topic for a block on a specific height
 type ConsensusVote struct {
 	blkHash []byte
@@ -7,6 +7,8 @@
 package rolldpos
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 
 	"github.com/iotexproject/iotex-core/endorsement"
@@ -18,6 +20,11 @@ type EndorsedConsensusMessage struct {
 	height      uint64
 	message     endorsement.Document
 	endorsement *endorsement.Endorsement
+	// verified is set once the signature of the endorsement is verified by the vote verifier, which is never loaded
+	// from a proto
+	verified bool
+	// receivedAt is the local time the message is received at, which is zero if it isn't received from a peer
+	receivedAt time.Time
 }
 
 // NewEndorsedConsensusMessage creates an EndorsedConsensusMessage for an consensus vote
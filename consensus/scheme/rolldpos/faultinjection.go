@@ -0,0 +1,143 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/consensus/scheme"
+	"github.com/iotexproject/iotex-core/pkg/log"
+	"github.com/iotexproject/iotex-core/pkg/util/byteutil"
+)
+
+// FaultInjectionEnv is the environment variable which has to be set to "true", in addition to the FaultInjection
+// config flag, to make a node follow its fault plan
+const FaultInjectionEnv = "IOTEX_ROLLDPOS_FAULT_INJECTION"
+
+type (
+	// FaultPlan defines the byzantine behaviors of a node, which is meant for testing the safety of the consensus only
+	FaultPlan struct {
+		// Equivocate makes the node propose two different blocks in the same round
+		Equivocate bool
+		// CommitUnproposed makes the node send a COMMIT endorsement for a block hash never proposed in each round
+		CommitUnproposed bool
+		// EndorsementDelay delays the broadcast of the node's endorsements
+		EndorsementDelay time.Duration
+		// DropRate is the fraction of outgoing messages to drop
+		DropRate float64
+	}
+
+	faultInjector struct {
+		plan  FaultPlan
+		mutex sync.Mutex
+		rand  *rand.Rand
+	}
+)
+
+func faultInjectionEnabled(cfg config.RollDPoS) bool {
+	return cfg.FaultInjection && os.Getenv(FaultInjectionEnv) == "true"
+}
+
+func newFaultInjector(plan FaultPlan) *faultInjector {
+	return &faultInjector{
+		plan: plan,
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// wrapBroadcast returns a broadcast handler which drops a fraction of the outgoing messages, and delays the
+// endorsements. A dropped message is reported as sent, the same as one lost in the network.
+func (f *faultInjector) wrapBroadcast(handler scheme.Broadcast) scheme.Broadcast {
+	return func(msg proto.Message) error {
+		if f.drop() {
+			log.Logger("consensus").Debug("fault injection: drop outgoing message")
+			return nil
+		}
+		cMsg, ok := msg.(*iotextypes.ConsensusMessage)
+		if !ok || cMsg.GetVote() == nil || f.plan.EndorsementDelay <= 0 {
+			return handler(msg)
+		}
+		time.AfterFunc(f.plan.EndorsementDelay, func() {
+			if err := handler(msg); err != nil {
+				log.Logger("consensus").Debug("fault injection: failed to broadcast delayed endorsement", zap.Error(err))
+			}
+		})
+		return nil
+	}
+}
+
+func (f *faultInjector) drop() bool {
+	if f.plan.DropRate <= 0 {
+		return false
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.rand.Float64() < f.plan.DropRate
+}
+
+// injectEquivocation broadcasts another block proposal for the current round, if the fault plan says so
+func (ctx *rollDPoSCtx) injectEquivocation() {
+	if ctx.faults == nil || !ctx.faults.plan.Equivocate {
+		return
+	}
+	// shift the timestamp to mint a different block within the same round
+	blk, err := ctx.chain.MintNewBlock(ctx.actPool.PendingActionMap(), ctx.round.StartTime().Add(time.Millisecond))
+	if err != nil {
+		ctx.logger().Debug("fault injection: failed to mint another block", zap.Error(err))
+		return
+	}
+	ecm, err := ctx.endorseBlockProposal(newBlockProposal(blk, nil))
+	if err != nil {
+		ctx.logger().Debug("fault injection: failed to endorse another block", zap.Error(err))
+		return
+	}
+	ctx.injectMessage(ecm)
+}
+
+// injectUnproposedCommit broadcasts a COMMIT endorsement for a block hash never proposed, if the fault plan says so
+func (ctx *rollDPoSCtx) injectUnproposedCommit() {
+	if ctx.faults == nil || !ctx.faults.plan.CommitUnproposed {
+		return
+	}
+	blkHash := hash.Hash256b(append(
+		byteutil.Uint64ToBytes(ctx.round.Height()),
+		byteutil.Uint32ToBytes(ctx.round.Number())...,
+	))
+	ecm, err := ctx.newEndorsement(
+		blkHash[:],
+		COMMIT,
+		ctx.round.StartTime().Add(
+			ctx.cfg.FSM.AcceptBlockTTL+ctx.cfg.FSM.AcceptProposalEndorsementTTL+ctx.cfg.FSM.AcceptLockEndorsementTTL,
+		),
+	)
+	if err != nil {
+		ctx.logger().Debug("fault injection: failed to endorse unproposed block", zap.Error(err))
+		return
+	}
+	ctx.injectMessage(ecm)
+}
+
+func (ctx *rollDPoSCtx) injectMessage(ecm *EndorsedConsensusMessage) {
+	msg, err := ecm.Proto()
+	if err != nil {
+		ctx.logger().Debug("fault injection: failed to generate protobuf message", zap.Error(err))
+		return
+	}
+	if err := ctx.broadcastHandler(msg); err != nil {
+		ctx.logger().Debug("fault injection: failed to broadcast", zap.Error(err))
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/testutil"
+)
+
+func TestFaultInjectionEnabled(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	require.NoError(os.Unsetenv(FaultInjectionEnv))
+	require.False(faultInjectionEnabled(cfg))
+	cfg.FaultInjection = true
+	require.False(faultInjectionEnabled(cfg))
+
+	require.NoError(os.Setenv(FaultInjectionEnv, "true"))
+	defer func() {
+		require.NoError(os.Unsetenv(FaultInjectionEnv))
+	}()
+	require.True(faultInjectionEnabled(cfg))
+	cfg.FaultInjection = false
+	require.False(faultInjectionEnabled(cfg))
+}
+
+func TestFaultInjectorBroadcast(t *testing.T) {
+	require := require.New(t)
+	var sent int32
+	handler := func(proto.Message) error {
+		atomic.AddInt32(&sent, 1)
+		return nil
+	}
+	vote := &iotextypes.ConsensusMessage{
+		Msg: &iotextypes.ConsensusMessage_Vote{Vote: &iotextypes.ConsensusVote{}},
+	}
+	proposal := &iotextypes.ConsensusMessage{
+		Msg: &iotextypes.ConsensusMessage_BlockProposal{BlockProposal: &iotextypes.BlockProposal{}},
+	}
+
+	t.Run("drop-all", func(t *testing.T) {
+		atomic.StoreInt32(&sent, 0)
+		broadcast := newFaultInjector(FaultPlan{DropRate: 1}).wrapBroadcast(handler)
+		for i := 0; i < 10; i++ {
+			require.NoError(broadcast(proposal))
+		}
+		require.Equal(int32(0), atomic.LoadInt32(&sent))
+	})
+	t.Run("drop-none", func(t *testing.T) {
+		atomic.StoreInt32(&sent, 0)
+		broadcast := newFaultInjector(FaultPlan{}).wrapBroadcast(handler)
+		for i := 0; i < 10; i++ {
+			require.NoError(broadcast(vote))
+		}
+		require.Equal(int32(10), atomic.LoadInt32(&sent))
+	})
+	t.Run("delay-endorsement", func(t *testing.T) {
+		atomic.StoreInt32(&sent, 0)
+		broadcast := newFaultInjector(FaultPlan{EndorsementDelay: 100 * time.Millisecond}).wrapBroadcast(handler)
+		require.NoError(broadcast(proposal))
+		require.Equal(int32(1), atomic.LoadInt32(&sent))
+		require.NoError(broadcast(vote))
+		require.Equal(int32(1), atomic.LoadInt32(&sent))
+		require.NoError(testutil.WaitUntil(10*time.Millisecond, time.Second, func() (bool, error) {
+			return atomic.LoadInt32(&sent) == 2, nil
+		}))
+	})
+}
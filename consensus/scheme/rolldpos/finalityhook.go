@@ -0,0 +1,46 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"sync"
+
+	"github.com/iotexproject/iotex-core/blockchain/block"
+)
+
+type (
+	// FinalityHook is called with a block right after it is finalized by the consensus and committed to the chain
+	FinalityHook func(*block.Block)
+
+	// finalityHooks holds the hooks to call on the finalized blocks
+	finalityHooks struct {
+		mutex sync.RWMutex
+		hooks []FinalityHook
+	}
+)
+
+// Register adds a hook to call on the finalized blocks
+func (h *finalityHooks) Register(hook FinalityHook) {
+	if hook == nil {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.hooks = append(h.hooks, hook)
+}
+
+// Notify calls each hook with the finalized block in a goroutine of its own, such that a slow hook can't stall the
+// consensus. Hence the hooks may be called concurrently, and not in the order of the heights.
+func (h *finalityHooks) Notify(blk *block.Block) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for _, hook := range h.hooks {
+		go hook(blk)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"sync"
+	"time"
+
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// forkEventsLimit is the number of the recent fork events kept
+const forkEventsLimit = 100
+
+var forkEventMtc = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "iotex_consensus_fork_events",
+		Help: "Number of the blocks endorsed by the consensus but losing to a competing block of the same height",
+	},
+	[]string{},
+)
+
+func init() {
+	prometheus.MustRegister(forkEventMtc)
+}
+
+type (
+	// ForkEvent is a near-fork, where the block endorsed by the consensus fails to commit, because a competing block
+	// of the same height has been committed by the block sync
+	ForkEvent struct {
+		Height        uint64
+		Time          time.Time
+		OurHash       hash.Hash256
+		CanonicalHash hash.Hash256
+		// Endorsements is the number of the commit endorsements of our block
+		Endorsements int
+	}
+
+	// forkEvents keeps the recent fork events, dropping the oldest ones beyond the limit
+	forkEvents struct {
+		mutex  sync.RWMutex
+		limit  int
+		events []ForkEvent
+	}
+)
+
+func newForkEvents(limit int) *forkEvents {
+	return &forkEvents{limit: limit}
+}
+
+// Add records a fork event
+func (f *forkEvents) Add(e ForkEvent) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.events = append(f.events, e)
+	if len(f.events) > f.limit {
+		f.events = append([]ForkEvent(nil), f.events[len(f.events)-f.limit:]...)
+	}
+	forkEventMtc.WithLabelValues().Inc()
+}
+
+// Recent returns the recent fork events, from the oldest to the newest
+func (f *forkEvents) Recent() []ForkEvent {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	return append([]ForkEvent(nil), f.events...)
+}
+
+// CountSince returns the number of the fork events kept which occurred at or after a time
+func (f *forkEvents) CountSince(t time.Time) int {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	count := 0
+	for _, e := range f.events {
+		if !e.Time.Before(t) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/blockchain"
+	"github.com/iotexproject/iotex-core/blockchain/block"
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/state"
+	"github.com/iotexproject/iotex-core/test/identityset"
+	"github.com/iotexproject/iotex-core/test/mock/mock_actpool"
+	"github.com/iotexproject/iotex-core/test/mock/mock_blockchain"
+)
+
+// forkedChain is a chain on which the block sync has committed a competing block at the height of the canonical block
+type forkedChain struct {
+	blockchain.Blockchain
+	canonical *block.Block
+}
+
+func (c *forkedChain) CommitBlock(blk *block.Block) error {
+	if blk.Height() == c.canonical.Height() {
+		return errors.Wrapf(blockchain.ErrInvalidTipHeight, "block %d has been committed", blk.Height())
+	}
+	return c.Blockchain.CommitBlock(blk)
+}
+
+func (c *forkedChain) BlockHeaderByHeight(height uint64) (*block.Header, error) {
+	if height == c.canonical.Height() {
+		return &c.canonical.Header, nil
+	}
+	return c.Blockchain.BlockHeaderByHeight(height)
+}
+
+func TestForkEvents(t *testing.T) {
+	require := require.New(t)
+	forks := newForkEvents(3)
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		forks.Add(ForkEvent{Height: uint64(i), Time: now.Add(time.Duration(i) * time.Minute)})
+	}
+	// the oldest events beyond the limit are dropped
+	events := forks.Recent()
+	require.Equal(3, len(events))
+	for i, e := range events {
+		require.Equal(uint64(i+2), e.Height)
+	}
+	events[0].Height = 100
+	require.Equal(uint64(2), forks.Recent()[0].Height)
+	require.Equal(2, forks.CountSince(now.Add(3*time.Minute)))
+	require.Equal(3, forks.CountSince(now))
+	require.Zero(forks.CountSince(now.Add(time.Hour)))
+}
+
+func TestDetectFork(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	chain := mock_blockchain.NewMockBlockchain(ctrl)
+	c := clock.NewMock()
+	rctx := &rollDPoSCtx{chain: chain, clock: c, forks: newForkEvents(forkEventsLimit), round: &roundCtx{}}
+	ts := time.Unix(1562382392, 0)
+	ours, err := block.NewTestingBuilder().SetHeight(5).SetTimeStamp(ts).SignAndBuild(identityset.PrivateKey(1))
+	require.NoError(err)
+	canonical, err := block.NewTestingBuilder().
+		SetHeight(5).
+		SetTimeStamp(ts.Add(time.Second)).
+		SignAndBuild(identityset.PrivateKey(2))
+	require.NoError(err)
+	forkCount := func() float64 {
+		return promtestutil.ToFloat64(forkEventMtc.WithLabelValues())
+	}
+	count := forkCount()
+
+	// the chain is behind the height
+	chain.EXPECT().BlockHeaderByHeight(uint64(5)).Return(nil, errors.New("not exist")).Times(1)
+	rctx.detectFork(&ours)
+	// the same block has been committed by the block sync
+	chain.EXPECT().BlockHeaderByHeight(uint64(5)).Return(&ours.Header, nil).Times(1)
+	rctx.detectFork(&ours)
+	require.Empty(rctx.RecentForkEvents())
+	require.Equal(count, forkCount())
+
+	// a competing block has been committed
+	chain.EXPECT().BlockHeaderByHeight(uint64(5)).Return(&canonical.Header, nil).Times(1)
+	rctx.detectFork(&ours)
+	require.Equal([]ForkEvent{{
+		Height:        5,
+		Time:          c.Now(),
+		OurHash:       ours.HashBlock(),
+		CanonicalHash: canonical.HashBlock(),
+		Endorsements:  0,
+	}}, rctx.RecentForkEvents())
+	require.Equal(count+1, forkCount())
+	require.Equal(1, rctx.NumForkEventsSince(c.Now().Add(-time.Hour)))
+}
+
+func TestCommitLosingBlock(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	// the block sync commits a competing block of the height before the consensus does
+	competing, err := block.NewTestingBuilder().
+		SetHeight(b.TipHeight() + 1).
+		SetTimeStamp(c.Now()).
+		SignAndBuild(identityset.PrivateKey(2))
+	require.NoError(err)
+	chain := &forkedChain{Blockchain: b, canonical: &competing}
+	actPool := mock_actpool.NewMockActPool(ctrl)
+	broadcastHandler := func(proto.Message) error { return nil }
+	rctx, err := newRollDPoSCtx(
+		cfg, true, time.Second*20, time.Second, true, chain, actPool, rp, broadcastHandler, candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+
+	blk, err := b.MintNewBlock(nil, rctx.round.StartTime())
+	require.NoError(err)
+	require.NoError(rctx.round.AddBlock(blk))
+
+	blkHash := blk.HashBlock()
+	vote := NewConsensusVote(blkHash[:], COMMIT)
+	committed := false
+	for i := 0; i < len(candidates) && !committed; i++ {
+		en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, rctx.round.StartTime())
+		require.NoError(err)
+		committed, err = rctx.Commit(NewEndorsedConsensusMessage(blk.Height(), vote, en))
+		require.NoError(err)
+	}
+	require.True(committed)
+	events := rctx.RecentForkEvents()
+	require.Equal(1, len(events))
+	require.Equal(blk.Height(), events[0].Height)
+	require.Equal(blkHash, events[0].OurHash)
+	require.Equal(competing.HashBlock(), events[0].CanonicalHash)
+	require.Equal(len(rctx.round.Endorsements(blkHash[:], []ConsensusVoteTopic{COMMIT})), events[0].Endorsements)
+}
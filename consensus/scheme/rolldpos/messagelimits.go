@@ -0,0 +1,92 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/iotexproject/iotex-core/action"
+)
+
+const (
+	// proofSlack is the number of the entries of a proof of lock allowed on top of one endorsement per delegate, i.e.,
+	// the proof type and the aggregated signature
+	proofSlack = 2
+	// systemActionsSlack is the number of the actions of a block allowed on top of those the block gas limit affords,
+	// i.e., the system actions consuming no gas, e.g., granting the block reward
+	systemActionsSlack = 8
+)
+
+var (
+	// ErrMessageTooLarge indicates that a consensus message is larger than the max message size
+	ErrMessageTooLarge = errors.New("consensus message is too large")
+	// ErrProofTooLarge indicates that the proof of lock of a block proposal carries far more endorsements than the
+	// delegates could make
+	ErrProofTooLarge = errors.New("proof of lock is too large")
+	// ErrBlockTooLarge indicates that a proposed block carries more actions than the block gas limit affords
+	ErrBlockTooLarge = errors.New("proposed block is too large")
+
+	oversizedMessageMtc = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "iotex_consensus_oversized_messages",
+			Help: "Number of inbound consensus messages dropped by the size limits before being decoded",
+		},
+		[]string{"reason"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(oversizedMessageMtc)
+}
+
+// CheckMessageLimits checks the size of an inbound consensus message, the number of the endorsements of the proof of
+// lock and the number of the actions of the proposed block, on the protobuf message before it is decoded or verified.
+// The limits are never reloaded, hence they are read without the lock, which keeps the dropping of the oversized
+// messages off the contention of the round.
+func (ctx *rollDPoSCtx) CheckMessageLimits(msg *iotextypes.ConsensusMessage) error {
+	err := ctx.checkMessageLimits(msg)
+	if err != nil {
+		oversizedMessageMtc.WithLabelValues(RejectionReasonOf(err).String()).Inc()
+	}
+	return err
+}
+
+func (ctx *rollDPoSCtx) checkMessageLimits(msg *iotextypes.ConsensusMessage) error {
+	if maxSize := ctx.cfg.MaxMessageSize; maxSize > 0 {
+		if size := uint64(proto.Size(msg)); size > maxSize {
+			return errors.Wrapf(ErrMessageTooLarge, "message of %d bytes, the max is %d", size, maxSize)
+		}
+	}
+	proposal := msg.GetBlockProposal()
+	if proposal == nil {
+		return nil
+	}
+	maxProof := ctx.roundCalc.rp.NumDelegates() + proofSlack
+	if n := uint64(len(proposal.Endorsements)); n > maxProof {
+		return errors.Wrapf(ErrProofTooLarge, "proof of %d endorsements, the max is %d", n, maxProof)
+	}
+	if ctx.blockGasLimit == 0 {
+		return nil
+	}
+	// the actions consuming the least intrinsic gas bound the number of the actions of a block
+	maxActions := ctx.blockGasLimit/minIntrinsicGas() + systemActionsSlack
+	if n := uint64(len(proposal.GetBlock().GetBody().GetActions())); n > maxActions {
+		return errors.Wrapf(ErrBlockTooLarge, "block of %d actions, the max is %d", n, maxActions)
+	}
+	return nil
+}
+
+// minIntrinsicGas is the least intrinsic gas of the actions paying for gas
+func minIntrinsicGas() uint64 {
+	if action.ExecutionBaseIntrinsicGas < action.TransferBaseIntrinsicGas {
+		return action.ExecutionBaseIntrinsicGas
+	}
+	return action.TransferBaseIntrinsicGas
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"context"
+	goerrors "errors"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/pkg/errors"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/state"
+	"github.com/iotexproject/iotex-core/test/identityset"
+)
+
+func TestCheckMessageLimits(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	rctx, err := newRollDPoSCtx(
+		cfg, true, 20*time.Second, time.Second, true, b, nil, rp, nil, candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+	// a block of at most 10 actions paying for gas
+	rctx.blockGasLimit = 100000
+	r := &RollDPoS{ctx: rctx, ready: make(chan interface{})}
+	close(r.ready)
+
+	height := rctx.round.Height()
+	blk := getBlockforctx(t, 5, true)
+	en, err := endorsement.Endorse(identityset.PrivateKey(5), newBlockProposal(&blk, nil), c.Now())
+	require.NoError(err)
+	proposal := func() *iotextypes.ConsensusMessage {
+		msg, err := NewEndorsedConsensusMessage(height, newBlockProposal(&blk, nil), en).Proto()
+		require.NoError(err)
+		return msg
+	}
+	dropped := func(reason RejectionReason) float64 {
+		return promtestutil.ToFloat64(oversizedMessageMtc.WithLabelValues(reason.String()))
+	}
+	requireDropped := func(reason RejectionReason, sentinel error, msg *iotextypes.ConsensusMessage) {
+		count := dropped(reason)
+		err := r.HandleConsensusMsg(context.Background(), msg)
+		require.Error(err)
+		require.Equal(sentinel, errors.Cause(err))
+		require.True(goerrors.Is(err, sentinel))
+		require.Equal(reason, RejectionReasonOf(err))
+		require.True(reason.BlamesSender())
+		require.Equal(count+1, dropped(reason))
+	}
+	require.NoError(rctx.CheckMessageLimits(proposal()))
+
+	t.Run("proof of lock", func(t *testing.T) {
+		// the proof carries more endorsements than the delegates could make, whose signatures are never verified
+		msg := proposal()
+		bp := msg.GetBlockProposal()
+		for i := uint64(0); i < rp.NumDelegates()+proofSlack; i++ {
+			bp.Endorsements = append(bp.Endorsements, &iotextypes.Endorsement{
+				Endorser:  identityset.PrivateKey(0).PublicKey().Bytes(),
+				Signature: []byte("invalid signature"),
+			})
+		}
+		require.NoError(rctx.CheckMessageLimits(msg))
+		bp.Endorsements = append(bp.Endorsements, bp.Endorsements[0])
+		requireDropped(ReasonProofTooLarge, ErrProofTooLarge, msg)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		msg := proposal()
+		body := msg.GetBlockProposal().Block.Body
+		for i := 0; i < 10+systemActionsSlack; i++ {
+			body.Actions = append(body.Actions, &iotextypes.Action{})
+		}
+		require.NoError(rctx.CheckMessageLimits(msg))
+		body.Actions = append(body.Actions, &iotextypes.Action{})
+		requireDropped(ReasonBlockTooLarge, ErrBlockTooLarge, msg)
+		// the number of the actions isn't capped if the block gas limit is unknown
+		rctx.blockGasLimit = 0
+		defer func() {
+			rctx.blockGasLimit = 100000
+		}()
+		require.NoError(rctx.CheckMessageLimits(msg))
+	})
+
+	t.Run("message", func(t *testing.T) {
+		msg := proposal()
+		size := uint64(proto.Size(msg))
+		rctx.cfg.MaxMessageSize = size
+		require.NoError(rctx.CheckMessageLimits(msg))
+		rctx.cfg.MaxMessageSize = size - 1
+		requireDropped(ReasonMessageTooLarge, ErrMessageTooLarge, msg)
+		// a vote is subject to the max message size as well
+		vote, err := NewEndorsedConsensusMessage(height, NewConsensusVote([]byte("block"), COMMIT), en).Proto()
+		require.NoError(err)
+		rctx.cfg.MaxMessageSize = uint64(proto.Size(vote)) - 1
+		requireDropped(ReasonMessageTooLarge, ErrMessageTooLarge, vote)
+		rctx.cfg.MaxMessageSize = 0
+		require.NoError(rctx.CheckMessageLimits(msg))
+	})
+}
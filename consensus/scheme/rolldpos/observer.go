@@ -0,0 +1,103 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/pkg/log"
+)
+
+// relayedProposalTopic tells the key of a block proposal apart from the ones of the votes in the set of the messages
+// relayed, since the proposer endorses the block it proposes as well
+const relayedProposalTopic ConsensusVoteTopic = 0xff
+
+var relayedMessageMtc = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "iotex_consensus_relayed_messages",
+		Help: "Number of consensus messages relayed by the observer",
+	},
+	[]string{"type"},
+)
+
+func init() {
+	prometheus.MustRegister(relayedMessageMtc)
+}
+
+// observedVote is the vote an observer would endorse at the end of a phase. It is neither signed nor broadcast, nor
+// added to the round, but moves the FSM of the observer into the next phase as an endorsement of the node does.
+type observedVote struct {
+	vote *ConsensusVote
+}
+
+// IsObserver returns true if the node follows the rounds in the observer mode, without proposing or endorsing
+func (ctx *rollDPoSCtx) IsObserver() bool {
+	return ctx.observer
+}
+
+// newVote endorses a vote of the node, or returns the vote unsigned as an observedVote in the observer mode
+func (ctx *rollDPoSCtx) newVote(blkHash []byte, topic ConsensusVoteTopic, timestamp time.Time) (interface{}, error) {
+	if ctx.observer {
+		return &observedVote{vote: NewConsensusVote(blkHash, topic)}, nil
+	}
+	if ctx.conflictsRecoveredVote(blkHash) {
+		ctx.logger().Warn(
+			"Skip a vote conflicting with the one endorsed before the restart.",
+			zap.Uint8("topic", uint8(topic)),
+			log.Hex("block", blkHash),
+		)
+		return nil, nil
+	}
+	return ctx.newEndorsement(blkHash, topic, timestamp)
+}
+
+// verifyObservedVote checks if the block of an observed vote is endorsed by a majority of the delegates on the topics
+func (ctx *rollDPoSCtx) verifyObservedVote(observed *observedVote, topics []ConsensusVoteTopic) ([]byte, error) {
+	blkHash := observed.vote.BlockHash()
+	return blkHash, ctx.round.checkMajority(blkHash, topics)
+}
+
+// Relay broadcasts a consensus message received from a peer, which has passed the verification, in the observer mode,
+// such that the messages propagate through the observer even if the delegates aren't connected to each other. Each
+// message is relayed once in a round, and a message failing to be broadcast isn't retried.
+func (ctx *rollDPoSCtx) Relay(msg *EndorsedConsensusMessage) {
+	if !ctx.observer || ctx.broadcastHandler == nil {
+		return
+	}
+	var (
+		key     string
+		msgType string
+	)
+	switch doc := msg.Document().(type) {
+	case *ConsensusVote:
+		key, msgType = seenEndorsementKey(doc, msg.Endorsement()), "endorsement"
+	case *blockProposal:
+		blkHash := doc.block.HashBlock()
+		key, msgType = seenEndorsementKey(NewConsensusVote(blkHash[:], relayedProposalTopic), msg.Endorsement()), "proposal"
+	default:
+		return
+	}
+	ctx.mutex.RLock()
+	relayed := ctx.relayed
+	ctx.mutex.RUnlock()
+	if !relayed.tryAdd(key) {
+		return
+	}
+	pb, err := msg.Proto()
+	if err != nil {
+		ctx.logger().Error("Failed to generate protobuf message to relay.", zap.Error(err))
+		return
+	}
+	if err := ctx.broadcastHandler(pb); err != nil {
+		ctx.logger().Debug("Failed to relay consensus message.", zap.String("type", msgType), zap.Error(err))
+		return
+	}
+	relayedMessageMtc.WithLabelValues(msgType).Inc()
+}
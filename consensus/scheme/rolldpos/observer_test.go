@@ -0,0 +1,228 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/go-pkgs/crypto"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/blockchain/block"
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/consensus/consensusfsm"
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/state"
+	"github.com/iotexproject/iotex-core/test/identityset"
+	"github.com/iotexproject/iotex-core/test/mock/mock_actpool"
+	"github.com/iotexproject/iotex-core/test/mock/mock_factory"
+)
+
+func TestObserver(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	cfg.Observer = true
+	b, rp := makeChain(t)
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	actPool := mock_actpool.NewMockActPool(ctrl)
+	actPool.EXPECT().Reset().Times(1)
+	broadcastHandler := func(msg proto.Message) error {
+		// only the committed block is broadcast
+		_, ok := msg.(*iotextypes.Block)
+		require.True(ok, "an observer broadcasts %T", msg)
+		return nil
+	}
+	// the key given is ignored
+	rctx, err := newRollDPoSCtx(
+		cfg, true, 20*time.Second, time.Second, true, b, actPool, rp, broadcastHandler, candidatesByHeight,
+		identityset.Address(0).String(), identityset.PrivateKey(0), c,
+	)
+	require.NoError(err)
+	require.True(rctx.IsObserver())
+	require.Nil(rctx.PublicKey())
+	_, err = rctx.Sign([]byte("hash"))
+	require.Error(err)
+	require.Error(rctx.RotateKey(identityset.PrivateKey(1)))
+
+	require.NoError(rctx.Prepare())
+	require.False(rctx.IsDelegate())
+	// the FSM goes through the round as the context is an observer
+	var _ consensusfsm.Observer = rctx
+	proposal, err := rctx.Proposal()
+	require.NoError(err)
+	require.Nil(proposal)
+	require.Nil(rctx.PreCommitEndorsement())
+
+	// the observer validates the proposed block without endorsing it
+	blk, err := b.MintNewBlock(nil, rctx.round.StartTime())
+	require.NoError(err)
+	bp := newBlockProposal(blk, nil)
+	en, err := endorsement.Endorse(identityset.PrivateKey(1), bp, rctx.round.StartTime())
+	require.NoError(err)
+	vote, err := rctx.NewProposalEndorsement(NewEndorsedConsensusMessage(blk.Height(), bp, en))
+	require.NoError(err)
+	blkHash := blk.HashBlock()
+	requireObserved := func(topic ConsensusVoteTopic, vote interface{}) {
+		observed, ok := vote.(*observedVote)
+		require.True(ok)
+		require.Equal(topic, observed.vote.Topic())
+		require.Equal(blkHash[:], observed.vote.BlockHash())
+		// an observed vote is never broadcast
+		rctx.Broadcast(vote)
+	}
+	requireObserved(PROPOSAL, vote)
+
+	// the observer moves along with the endorsements of the delegates as the FSM does
+	endorse := func(topic ConsensusVoteTopic, i int) *EndorsedConsensusMessage {
+		vote := NewConsensusVote(blkHash[:], topic)
+		en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, rctx.round.StartTime())
+		require.NoError(err)
+		return NewEndorsedConsensusMessage(blk.Height(), vote, en)
+	}
+	next := []func(interface{}) (interface{}, error){rctx.NewLockEndorsement, rctx.NewPreCommitEndorsement}
+	for i, topic := range []ConsensusVoteTopic{PROPOSAL, LOCK} {
+		observed, err := next[i](vote)
+		require.NoError(err)
+		require.Nil(observed)
+		for j := 0; j < len(candidates) && observed == nil; j++ {
+			observed, err = next[i](endorse(topic, j))
+			require.NoError(err)
+		}
+		requireObserved(topic+1, observed)
+		vote = observed
+	}
+	committed, err := rctx.Commit(vote)
+	require.NoError(err)
+	require.False(committed)
+	for i := 0; i < len(candidates) && !committed; i++ {
+		committed, err = rctx.Commit(endorse(COMMIT, i))
+		require.NoError(err)
+	}
+	require.True(committed)
+	require.Equal(blk.Height(), b.TipHeight())
+}
+
+func TestObserver_Relay(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	cfg.Observer = true
+	b, rp := makeChain(t)
+	candidates := []*state.Candidate{}
+	keys := map[string]crypto.PrivateKey{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			RewardAddress: identityset.Address(i).String(),
+		})
+		keys[identityset.Address(i).String()] = identityset.PrivateKey(i)
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	var relayed []*iotextypes.ConsensusMessage
+	broadcastHandler := func(msg proto.Message) error {
+		cm, ok := msg.(*iotextypes.ConsensusMessage)
+		require.True(ok, "an observer broadcasts %T", msg)
+		relayed = append(relayed, cm)
+		return nil
+	}
+	rctx, err := newRollDPoSCtx(
+		cfg, true, 20*time.Second, time.Second, true, b, nil, rp, broadcastHandler, candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+	cfsm, err := consensusfsm.NewConsensusFSM(cfg.FSM, rctx, c)
+	require.NoError(err)
+	r := &RollDPoS{cfsm: cfsm, ctx: rctx, ready: make(chan interface{})}
+	close(r.ready)
+	require.NoError(rctx.Prepare())
+
+	// the observer relays the proposal and the endorsements of the delegates, each only once in a round
+	endorse := func(height uint64, key crypto.PrivateKey, doc endorsement.Document) *iotextypes.ConsensusMessage {
+		en, err := endorsement.Endorse(key, doc, rctx.round.StartTime())
+		require.NoError(err)
+		msg, err := NewEndorsedConsensusMessage(height, doc, en).Proto()
+		require.NoError(err)
+		return msg
+	}
+	receive := func(height uint64, key crypto.PrivateKey, doc endorsement.Document) *iotextypes.ConsensusMessage {
+		msg := endorse(height, key, doc)
+		for i := 0; i < 2; i++ {
+			require.NoError(r.HandleConsensusMsg(context.Background(), msg))
+		}
+		return msg
+	}
+	height := rctx.Height()
+	blk, err := block.NewTestingBuilder().
+		SetHeight(height).
+		SetTimeStamp(rctx.round.StartTime()).
+		SignAndBuild(keys[rctx.round.Proposer()])
+	require.NoError(err)
+	blk.WorkingSet = mock_factory.NewMockWorkingSet(ctrl)
+	blkHash := blk.HashBlock()
+	expected := []*iotextypes.ConsensusMessage{
+		receive(height, keys[rctx.round.Proposer()], newBlockProposal(&blk, nil)),
+	}
+	for _, delegate := range rctx.round.Delegates() {
+		expected = append(expected, receive(height, keys[delegate], NewConsensusVote(blkHash[:], PROPOSAL)))
+	}
+	// an invalid message isn't relayed
+	require.Error(r.HandleConsensusMsg(
+		context.Background(),
+		endorse(height, identityset.PrivateKey(27), NewConsensusVote(blkHash[:], PROPOSAL)),
+	))
+	// the messages relayed are the ones received, as the observer never endorses
+	require.Equal(len(expected), len(relayed))
+	for i := range expected {
+		require.True(proto.Equal(expected[i], relayed[i]))
+	}
+	proposal, err := rctx.Proposal()
+	require.NoError(err)
+	require.Nil(proposal)
+	require.Nil(rctx.PublicKey())
+
+	// the observer follows the chain to the next height, where the messages are relayed again
+	next, err := b.MintNewBlock(nil, rctx.round.StartTime())
+	require.NoError(err)
+	require.NoError(b.CommitBlock(next))
+	c.Add(20 * time.Second)
+	require.NoError(rctx.Prepare())
+	require.Equal(height+1, rctx.Height())
+	relayed = nil
+	vote := NewConsensusVote(blkHash[:], PROPOSAL)
+	expected = []*iotextypes.ConsensusMessage{receive(height+1, keys[rctx.round.Delegates()[0]], vote)}
+	require.Equal(1, len(relayed))
+	require.True(proto.Equal(expected[0], relayed[0]))
+}
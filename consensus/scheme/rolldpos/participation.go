@@ -0,0 +1,207 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"sync"
+
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/pkg/log"
+)
+
+var participationMtc = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "iotex_consensus_delegate_participation",
+		Help: "Fraction of the finalized blocks in the participation window endorsed by a delegate",
+	},
+	[]string{"delegate"},
+)
+
+func init() {
+	prometheus.MustRegister(participationMtc)
+}
+
+type (
+	// ParticipationStats is the participation of a delegate in the blocks finalized in an epoch
+	ParticipationStats struct {
+		// Proposals is the number of the finalized blocks proposed by the delegate
+		Proposals int
+		// Endorsements is the number of the finalized blocks whose commit endorsements include the delegate's
+		Endorsements int
+	}
+
+	// participationRecord is the snapshot of a finalized block, taken while holding the ctx mutex, such that the
+	// endorsements could be accounted after releasing it
+	participationRecord struct {
+		height       uint64
+		epochNum     uint64
+		proposer     string
+		delegates    []string
+		endorsements []*endorsement.Endorsement
+	}
+
+	// participationEntry is the accounted participation at a height
+	participationEntry struct {
+		height    uint64
+		delegates map[string]struct{}
+		endorsers map[string]struct{}
+	}
+
+	// epochParticipation is the participation of each delegate of an epoch in the blocks finalized in it
+	epochParticipation struct {
+		epochNum uint64
+		stats    map[string]ParticipationStats
+	}
+
+	// participationTracker accounts which delegates endorsed the finalized blocks over a sliding window of heights,
+	// along with the proposals and the endorsements of each delegate over the current and the previous epochs
+	participationTracker struct {
+		mutex      sync.RWMutex
+		window     uint64
+		entries    []*participationEntry
+		lastHeight uint64
+		epoch      *epochParticipation
+		lastEpoch  *epochParticipation
+	}
+)
+
+// newParticipationTracker returns a tracker over a window of heights, which accounts no rates if the window is 0
+func newParticipationTracker(window uint64) *participationTracker {
+	return &participationTracker{window: window}
+}
+
+// Record accounts the endorsements in the footer of a finalized block, and updates the participation gauges. The
+// entries fallen out of the window are dropped, so are the delegates no longer in the delegate set. The epoch stats
+// restart with the first block of a new epoch, where those of the ending epoch are kept for the report.
+func (t *participationTracker) Record(record *participationRecord) {
+	if record == nil {
+		return
+	}
+	entry := &participationEntry{
+		height:    record.height,
+		delegates: make(map[string]struct{}, len(record.delegates)),
+		endorsers: make(map[string]struct{}, len(record.endorsements)),
+	}
+	for _, d := range record.delegates {
+		entry.delegates[d] = struct{}{}
+	}
+	for _, en := range record.endorsements {
+		endorserAddr, err := address.FromBytes(en.Endorser().Hash())
+		if err != nil {
+			log.Logger("consensus").Error("Failed to get the endorser address.", zap.Error(err))
+			continue
+		}
+		if _, ok := entry.delegates[endorserAddr.String()]; ok {
+			entry.endorsers[endorserAddr.String()] = struct{}{}
+		}
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if entry.height <= t.lastHeight {
+		// a height is finalized once, an older one is a replay
+		return
+	}
+	t.lastHeight = entry.height
+	t.recordEpoch(record.epochNum, record.proposer, entry)
+	if t.window == 0 {
+		return
+	}
+	if n := len(t.entries); n > 0 {
+		last := t.entries[n-1]
+		for d := range last.delegates {
+			if _, ok := entry.delegates[d]; !ok {
+				participationMtc.DeleteLabelValues(d)
+			}
+		}
+	}
+	t.entries = append(t.entries, entry)
+	start := 0
+	for start < len(t.entries) && t.entries[start].height+t.window <= entry.height {
+		start++
+	}
+	t.entries = t.entries[start:]
+	for d, rate := range t.rates() {
+		participationMtc.WithLabelValues(d).Set(rate)
+	}
+}
+
+// Rates returns the participation rate of each delegate of the latest finalized block
+func (t *participationTracker) Rates() map[string]float64 {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return t.rates()
+}
+
+// rates computes the fraction of the blocks endorsed by a delegate among those of the heights it is a delegate of
+func (t *participationTracker) rates() map[string]float64 {
+	rates := map[string]float64{}
+	if len(t.entries) == 0 {
+		return rates
+	}
+	for d := range t.entries[len(t.entries)-1].delegates {
+		var eligible, endorsed int
+		for _, entry := range t.entries {
+			if _, ok := entry.delegates[d]; !ok {
+				continue
+			}
+			eligible++
+			if _, ok := entry.endorsers[d]; ok {
+				endorsed++
+			}
+		}
+		rates[d] = float64(endorsed) / float64(eligible)
+	}
+	return rates
+}
+
+// EpochParticipation returns the proposals and the endorsements of each delegate in the blocks finalized in the
+// current or the previous epoch, which only counts those finalized since the node started
+func (t *participationTracker) EpochParticipation(epochNum uint64) (map[string]ParticipationStats, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	for _, epoch := range []*epochParticipation{t.epoch, t.lastEpoch} {
+		if epoch == nil || epoch.epochNum != epochNum {
+			continue
+		}
+		stats := make(map[string]ParticipationStats, len(epoch.stats))
+		for d, s := range epoch.stats {
+			stats[d] = s
+		}
+		return stats, nil
+	}
+	return nil, errors.Errorf("participation of epoch %d is not tracked", epochNum)
+}
+
+// recordEpoch accounts the proposal and the endorsements of a finalized block in the stats of its epoch, starting
+// over if it is of a new epoch. The delegates neither proposing nor endorsing are reported with zero counts.
+func (t *participationTracker) recordEpoch(epochNum uint64, proposer string, entry *participationEntry) {
+	if t.epoch == nil || t.epoch.epochNum != epochNum {
+		t.lastEpoch = t.epoch
+		t.epoch = &epochParticipation{
+			epochNum: epochNum,
+			stats:    make(map[string]ParticipationStats, len(entry.delegates)),
+		}
+	}
+	for d := range entry.delegates {
+		stats := t.epoch.stats[d]
+		if d == proposer {
+			stats.Proposals++
+		}
+		if _, ok := entry.endorsers[d]; ok {
+			stats.Endorsements++
+		}
+		t.epoch.stats[d] = stats
+	}
+}
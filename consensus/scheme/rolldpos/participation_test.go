@@ -0,0 +1,138 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"testing"
+	"time"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/test/identityset"
+)
+
+func TestParticipationTracker(t *testing.T) {
+	require := require.New(t)
+
+	record := func(height uint64, delegates []int, endorsers ...int) *participationRecord {
+		r := &participationRecord{height: height}
+		for _, i := range delegates {
+			r.delegates = append(r.delegates, identityset.Address(i).String())
+		}
+		for _, i := range endorsers {
+			r.endorsements = append(
+				r.endorsements,
+				endorsement.NewEndorsement(time.Unix(1562382392, 0), identityset.PrivateKey(i).PublicKey(), nil),
+			)
+		}
+		return r
+	}
+	addr := func(i int) string { return identityset.Address(i).String() }
+
+	// nothing is accounted with a zero window
+	tracker := newParticipationTracker(0)
+	tracker.Record(record(1, []int{0, 1, 2}, 0, 1))
+	require.Empty(tracker.Rates())
+
+	tracker = newParticipationTracker(3)
+	require.Empty(tracker.Rates())
+	tracker.Record(nil)
+	delegates := []int{0, 1, 2}
+	tracker.Record(record(1, delegates, 0, 1))
+	// endorsements of a non-delegate are ignored
+	tracker.Record(record(2, delegates, 0, 3))
+	tracker.Record(record(3, delegates, 0, 2))
+	require.Equal(map[string]float64{addr(0): 1, addr(1): 1. / 3, addr(2): 1. / 3}, tracker.Rates())
+
+	// height 1 slides out of the window
+	tracker.Record(record(4, delegates, 0, 2))
+	require.Equal(map[string]float64{addr(0): 1, addr(1): 0, addr(2): 2. / 3}, tracker.Rates())
+	require.Equal(2./3, promtestutil.ToFloat64(participationMtc.WithLabelValues(addr(2))))
+	// a replayed height is ignored
+	tracker.Record(record(4, delegates, 1))
+	require.Equal(float64(0), tracker.Rates()[addr(1)])
+
+	// a departed delegate is dropped, and a new one is only accounted at the heights it is a delegate of
+	tracker.Record(record(5, []int{0, 1, 3}, 3))
+	require.Equal(map[string]float64{addr(0): 2. / 3, addr(1): 0, addr(3): 1}, tracker.Rates())
+	require.False(participationMtc.DeleteLabelValues(addr(2)))
+	require.Equal(float64(1), promtestutil.ToFloat64(participationMtc.WithLabelValues(addr(3))))
+}
+
+func TestParticipationTracker_EpochParticipation(t *testing.T) {
+	require := require.New(t)
+
+	addr := func(i int) string { return identityset.Address(i).String() }
+	delegates := []int{0, 1, 2, 3}
+	// round simulates a block of an epoch finalized with the endorsements of the endorsers
+	round := func(height, epochNum uint64, proposer int, endorsers ...int) *participationRecord {
+		r := &participationRecord{height: height, epochNum: epochNum, proposer: addr(proposer)}
+		for _, i := range delegates {
+			r.delegates = append(r.delegates, addr(i))
+		}
+		for _, i := range endorsers {
+			r.endorsements = append(
+				r.endorsements,
+				endorsement.NewEndorsement(time.Unix(1562382392, 0), identityset.PrivateKey(i).PublicKey(), nil),
+			)
+		}
+		return r
+	}
+
+	// the epoch stats are accounted with a zero window
+	tracker := newParticipationTracker(0)
+	_, err := tracker.EpochParticipation(1)
+	require.Error(err)
+	tracker.Record(round(1, 1, 0, 0, 1, 2))
+	tracker.Record(round(2, 1, 1, 0, 1, 3))
+	tracker.Record(round(3, 1, 2, 0, 2, 3))
+	// endorsements of a non-delegate are ignored
+	tracker.Record(round(4, 1, 0, 0, 1, 2, 5))
+	// a replayed height is ignored
+	tracker.Record(round(4, 1, 3, 3))
+	require.Empty(tracker.Rates())
+	epoch1 := map[string]ParticipationStats{
+		addr(0): {Proposals: 2, Endorsements: 4},
+		addr(1): {Proposals: 1, Endorsements: 3},
+		addr(2): {Proposals: 1, Endorsements: 3},
+		// a delegate taking no part is reported all the same
+		addr(3): {Proposals: 0, Endorsements: 2},
+	}
+	stats, err := tracker.EpochParticipation(1)
+	require.NoError(err)
+	require.Equal(epoch1, stats)
+	// the stats returned is a copy
+	stats[addr(0)] = ParticipationStats{}
+	stats, err = tracker.EpochParticipation(1)
+	require.NoError(err)
+	require.Equal(epoch1, stats)
+
+	// the stats restart with the next epoch, while the ending epoch is still reported
+	tracker.Record(round(5, 2, 3, 1, 2, 3))
+	stats, err = tracker.EpochParticipation(2)
+	require.NoError(err)
+	require.Equal(map[string]ParticipationStats{
+		addr(0): {},
+		addr(1): {Endorsements: 1},
+		addr(2): {Endorsements: 1},
+		addr(3): {Proposals: 1, Endorsements: 1},
+	}, stats)
+	stats, err = tracker.EpochParticipation(1)
+	require.NoError(err)
+	require.Equal(epoch1, stats)
+
+	// only the current and the previous epochs are kept
+	tracker.Record(round(6, 3, 0, 0, 1, 2))
+	_, err = tracker.EpochParticipation(1)
+	require.Error(err)
+	_, err = tracker.EpochParticipation(2)
+	require.NoError(err)
+	_, err = tracker.EpochParticipation(4)
+	require.Error(err)
+}
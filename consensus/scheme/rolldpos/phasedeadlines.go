@@ -0,0 +1,78 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/consensus/consensusfsm"
+)
+
+var latePhaseMtc = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "iotex_consensus_late_phase_skipped",
+		Help: "Number of block proposals and endorsements skipped as their phases have passed",
+	},
+	[]string{"phase"},
+)
+
+func init() {
+	prometheus.MustRegister(latePhaseMtc)
+}
+
+// phaseDeadlines are the ends of the phases of a round, after which the block proposal or the endorsements made in
+// the phases are useless
+type phaseDeadlines struct {
+	acceptBlock               time.Time
+	acceptProposalEndorsement time.Time
+	acceptLockEndorsement     time.Time
+	commit                    time.Time
+}
+
+func newPhaseDeadlines(roundStartTime time.Time, cfg consensusfsm.Config) phaseDeadlines {
+	acceptBlock := roundStartTime.Add(cfg.AcceptBlockTTL)
+	acceptProposalEndorsement := acceptBlock.Add(cfg.AcceptProposalEndorsementTTL)
+	acceptLockEndorsement := acceptProposalEndorsement.Add(cfg.AcceptLockEndorsementTTL)
+	return phaseDeadlines{
+		acceptBlock:               acceptBlock,
+		acceptProposalEndorsement: acceptProposalEndorsement,
+		acceptLockEndorsement:     acceptLockEndorsement,
+		commit:                    acceptLockEndorsement.Add(cfg.CommitTTL),
+	}
+}
+
+// of returns the end of the phase in which the endorsements of the topic are collected
+func (d phaseDeadlines) of(topic ConsensusVoteTopic) time.Time {
+	switch topic {
+	case PROPOSAL:
+		return d.acceptProposalEndorsement
+	case LOCK:
+		return d.acceptLockEndorsement
+	default:
+		return d.commit
+	}
+}
+
+// skipLatePhase returns true if the phase has passed by more than the tolerated overtime, e.g., when the node wakes up
+// late in the round, such that the block proposal or the endorsement of the phase is not worth signing
+func (ctx *rollDPoSCtx) skipLatePhase(phase string, deadline time.Time) bool {
+	now := ctx.clock.Now()
+	if !now.After(deadline.Add(ctx.roundCalc.toleratedOvertime)) {
+		return false
+	}
+	latePhaseMtc.WithLabelValues(phase).Inc()
+	ctx.logger().Info(
+		"skipped late phase",
+		zap.String("phase", phase),
+		zap.Time("deadline", deadline),
+		zap.Time("now", now),
+	)
+	return true
+}
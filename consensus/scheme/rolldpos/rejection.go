@@ -0,0 +1,267 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// ErrInvalidMessage indicates a malformed consensus message, or one of an unexpected type
+	ErrInvalidMessage = errors.New("invalid consensus message")
+	// ErrInvalidSignature indicates a bad signature of an endorsement or a block
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrNotDelegate indicates an endorser not being a delegate of the height
+	ErrNotDelegate = errors.New("not a delegate")
+	// ErrNotProposer indicates a block or a proposal not made by the proposer of the round
+	ErrNotProposer = errors.New("not the proposer")
+	// ErrHeightMismatch indicates a block not of the height of the message
+	ErrHeightMismatch = errors.New("height mismatch")
+	// ErrInvalidBlock indicates a proposed block failing the validation
+	ErrInvalidBlock = errors.New("invalid block")
+	// ErrBlockNotReceived indicates an endorsement of a block not received yet
+	ErrBlockNotReceived = errors.New("block not received")
+	// ErrInvalidProof indicates a missing or invalid proof of lock or unlock of a block proposal
+	ErrInvalidProof = errors.New("invalid proof")
+	// ErrTimestampOutOfWindow indicates an endorsement timestamped out of the window of the height or the round
+	ErrTimestampOutOfWindow = errors.New("timestamp out of window")
+
+	rejectedMessageMtc = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "iotex_consensus_rejected_messages",
+			Help: "Number of inbound consensus messages rejected, by the code of the rejection",
+		},
+		[]string{"code"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(rejectedMessageMtc)
+}
+
+// RejectionReason is the reason why a consensus message, e.g., a block proposal or an endorsement, is rejected
+type RejectionReason int
+
+const (
+	// ReasonUnknown means the error isn't a rejection of a known reason
+	ReasonUnknown RejectionReason = iota
+	// ReasonInvalidMessage means the message is malformed or of an unexpected type
+	ReasonInvalidMessage
+	// ReasonInvalidSignature means the signature of the endorsement or the block is invalid
+	ReasonInvalidSignature
+	// ReasonNotDelegate means the endorser isn't a delegate of the height
+	ReasonNotDelegate
+	// ReasonNotProposer means the block or the proposal isn't made by the proposer of the round
+	ReasonNotProposer
+	// ReasonHeightMismatch means the block isn't of the height of the message
+	ReasonHeightMismatch
+	// ReasonInvalidBlock means the proposed block fails the validation
+	ReasonInvalidBlock
+	// ReasonBlockNotReceived means the endorsed block hasn't been received
+	ReasonBlockNotReceived
+	// ReasonExpiredEndorsement means the endorsement has been replaced by a later one of the endorser
+	ReasonExpiredEndorsement
+	// ReasonInsufficientEndorsements means the endorsements are short of a majority of the delegates
+	ReasonInsufficientEndorsements
+	// ReasonTooManyEndorsements means a proof carries more endorsements than the delegates
+	ReasonTooManyEndorsements
+	// ReasonDuplicateEndorser means a proof carries more than one endorsement of an endorser
+	ReasonDuplicateEndorser
+	// ReasonInvalidProof means the proof of lock or unlock of a block proposal is missing or invalid
+	ReasonInvalidProof
+	// ReasonLosingProposal means the block proposal isn't preferred over the one already received
+	ReasonLosingProposal
+	// ReasonBlockTooEarly means the block is within the min block interval after the previous block
+	ReasonBlockTooEarly
+	// ReasonDuplicateEndorsement means the endorser has endorsed the same vote in the round already
+	ReasonDuplicateEndorsement
+	// ReasonMessageTooLarge means the message is larger than the max message size
+	ReasonMessageTooLarge
+	// ReasonProofTooLarge means the proof of lock carries far more endorsements than the delegates could make
+	ReasonProofTooLarge
+	// ReasonBlockTooLarge means the proposed block carries more actions than the block gas limit affords
+	ReasonBlockTooLarge
+	// ReasonBlockBeforeParent means the proposed block isn't timestamped after its parent block
+	ReasonBlockBeforeParent
+	// ReasonTimestampOutOfWindow means an endorsement is timestamped out of the window of the height or the round
+	ReasonTimestampOutOfWindow
+)
+
+// String returns the name of the rejection reason
+func (r RejectionReason) String() string {
+	switch r {
+	case ReasonInvalidMessage:
+		return "invalidMessage"
+	case ReasonInvalidSignature:
+		return "invalidSignature"
+	case ReasonNotDelegate:
+		return "notDelegate"
+	case ReasonNotProposer:
+		return "notProposer"
+	case ReasonHeightMismatch:
+		return "heightMismatch"
+	case ReasonInvalidBlock:
+		return "invalidBlock"
+	case ReasonBlockNotReceived:
+		return "blockNotReceived"
+	case ReasonExpiredEndorsement:
+		return "expiredEndorsement"
+	case ReasonInsufficientEndorsements:
+		return "insufficientEndorsements"
+	case ReasonTooManyEndorsements:
+		return "tooManyEndorsements"
+	case ReasonDuplicateEndorser:
+		return "duplicateEndorser"
+	case ReasonInvalidProof:
+		return "invalidProof"
+	case ReasonLosingProposal:
+		return "losingProposal"
+	case ReasonBlockTooEarly:
+		return "blockTooEarly"
+	case ReasonDuplicateEndorsement:
+		return "duplicateEndorsement"
+	case ReasonMessageTooLarge:
+		return "messageTooLarge"
+	case ReasonProofTooLarge:
+		return "proofTooLarge"
+	case ReasonBlockTooLarge:
+		return "blockTooLarge"
+	case ReasonBlockBeforeParent:
+		return "blockBeforeParent"
+	case ReasonTimestampOutOfWindow:
+		return "timestampOutOfWindow"
+	default:
+		return "unknown"
+	}
+}
+
+// BlamesSender tells whether the rejection is due to the message itself, such that the peer relaying it is to blame,
+// rather than to the local state of the round, e.g., an endorsement of a block not received yet
+func (r RejectionReason) BlamesSender() bool {
+	switch r {
+	case ReasonInvalidMessage, ReasonInvalidSignature, ReasonNotDelegate, ReasonNotProposer, ReasonHeightMismatch,
+		ReasonInvalidBlock, ReasonTooManyEndorsements, ReasonDuplicateEndorser, ReasonInvalidProof,
+		ReasonMessageTooLarge, ReasonProofTooLarge, ReasonBlockTooLarge, ReasonBlockBeforeParent,
+		ReasonTimestampOutOfWindow:
+		return true
+	default:
+		return false
+	}
+}
+
+// RejectionError is an error rejecting a consensus message for a reason. The message of the error is kept for the
+// logs, and errors.Cause sees through it, such that the sentinel errors it wraps are still recognized. The standard
+// errors.Is tells it apart by the sentinel error of its reason, and errors.As finds it as long as it is the outermost
+// error, which reject and wrapRejection keep it.
+type RejectionError struct {
+	reason RejectionReason
+	err    error
+}
+
+// Reason returns the reason of the rejection
+func (e *RejectionError) Reason() RejectionReason { return e.reason }
+
+// Error returns the message of the error
+func (e *RejectionError) Error() string { return e.err.Error() }
+
+// Code returns the name of the reason, which labels the error in the logs and the metrics
+func (e *RejectionError) Code() string { return e.reason.String() }
+
+// Cause returns the error wrapped
+func (e *RejectionError) Cause() error { return e.err }
+
+// Unwrap returns the error wrapped
+func (e *RejectionError) Unwrap() error { return e.err }
+
+// Is tells whether the target is the sentinel error of the reason
+func (e *RejectionError) Is(target error) bool {
+	for sentinel, reason := range sentinelReasons {
+		if sentinel == target {
+			return reason == e.reason
+		}
+	}
+	return false
+}
+
+// sentinelReasons are the reasons of the sentinel errors, which are rejections wherever they are returned
+var sentinelReasons = map[error]RejectionReason{
+	ErrInsufficientEndorsements: ReasonInsufficientEndorsements,
+	ErrTooManyEndorsements:      ReasonTooManyEndorsements,
+	ErrDuplicateEndorser:        ReasonDuplicateEndorser,
+	ErrLosingProposal:           ReasonLosingProposal,
+	ErrExpiredEndorsement:       ReasonExpiredEndorsement,
+	ErrBlockTooEarly:            ReasonBlockTooEarly,
+	ErrDuplicateEndorsement:     ReasonDuplicateEndorsement,
+	ErrMessageTooLarge:          ReasonMessageTooLarge,
+	ErrProofTooLarge:            ReasonProofTooLarge,
+	ErrBlockTooLarge:            ReasonBlockTooLarge,
+	ErrBlockBeforeParent:        ReasonBlockBeforeParent,
+	ErrInvalidMessage:           ReasonInvalidMessage,
+	ErrInvalidSignature:         ReasonInvalidSignature,
+	ErrNotDelegate:              ReasonNotDelegate,
+	ErrNotProposer:              ReasonNotProposer,
+	ErrHeightMismatch:           ReasonHeightMismatch,
+	ErrInvalidBlock:             ReasonInvalidBlock,
+	ErrBlockNotReceived:         ReasonBlockNotReceived,
+	ErrInvalidProof:             ReasonInvalidProof,
+	ErrTimestampOutOfWindow:     ReasonTimestampOutOfWindow,
+}
+
+// RejectionReasonOf returns the reason of the outermost rejection in the chain of the error, or the one of the
+// sentinel error it wraps. It returns ReasonUnknown if the error isn't a rejection.
+func RejectionReasonOf(err error) RejectionReason {
+	type causer interface {
+		Cause() error
+	}
+	for err != nil {
+		if e, ok := err.(*RejectionError); ok {
+			return e.reason
+		}
+		if reason, ok := sentinelReasons[err]; ok {
+			return reason
+		}
+		c, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = c.Cause()
+	}
+	return ReasonUnknown
+}
+
+// ClassifyConsensusError returns the code of the rejection of a consensus message, which is the name of its reason,
+// or "unknown" if the error isn't a rejection
+func ClassifyConsensusError(err error) string {
+	return RejectionReasonOf(err).String()
+}
+
+// reject attaches the reason to the error, unless the error is a rejection of a known reason already, in which case
+// the known reason is kept. The rejection is returned as the outermost error, such that errors.As finds it.
+func reject(reason RejectionReason, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*RejectionError); ok {
+		return err
+	}
+	if known := RejectionReasonOf(err); known != ReasonUnknown {
+		reason = known
+	}
+	if reason == ReasonUnknown {
+		return err
+	}
+	return &RejectionError{reason: reason, err: err}
+}
+
+// wrapRejection annotates the error with the message, keeping the rejection, if any, as the outermost error
+func wrapRejection(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	return reject(ReasonUnknown, errors.Wrap(err, message))
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	goerrors "errors"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/test/identityset"
+)
+
+func TestRejectionReasonOf(t *testing.T) {
+	require := require.New(t)
+	require.Equal(ReasonUnknown, RejectionReasonOf(nil))
+	require.Equal(ReasonUnknown, RejectionReasonOf(errors.New("error")))
+	require.NoError(reject(ReasonInvalidMessage, nil))
+
+	err := reject(ReasonNotDelegate, errors.New("not delegate"))
+	require.Equal(ReasonNotDelegate, RejectionReasonOf(err))
+	require.Equal("not delegate", err.Error())
+	require.Equal("notDelegate", ReasonNotDelegate.String())
+	require.True(ReasonNotDelegate.BlamesSender())
+	require.False(ReasonBlockNotReceived.BlamesSender())
+	require.False(ReasonUnknown.BlamesSender())
+	// the reason is found through the wrappers, and a later reason doesn't override it
+	err = errors.Wrap(err, "failed to verify vote")
+	require.Equal(ReasonNotDelegate, RejectionReasonOf(err))
+	require.Equal(ReasonNotDelegate, RejectionReasonOf(reject(ReasonInvalidProof, err)))
+
+	// the sentinel errors are rejections themselves, and are still seen by errors.Cause
+	err = reject(ReasonInvalidProof, errors.Wrap(ErrInsufficientEndorsements, "failed to verify proof of lock"))
+	require.Equal(ReasonInsufficientEndorsements, RejectionReasonOf(err))
+	require.Equal(ErrInsufficientEndorsements, errors.Cause(err))
+	require.Equal(ReasonBlockTooEarly, RejectionReasonOf(errors.Wrap(ErrBlockTooEarly, "too early")))
+}
+
+func TestRejectionError(t *testing.T) {
+	require := require.New(t)
+	require.Equal("unknown", ClassifyConsensusError(nil))
+	require.Equal("unknown", ClassifyConsensusError(errors.New("error")))
+	require.NoError(wrapRejection(nil, "failed"))
+
+	// a rejection is told apart by the sentinel error of its reason, with its message kept
+	err := reject(ReasonNotDelegate, errors.New("io1abc is not delegate"))
+	require.True(goerrors.Is(err, ErrNotDelegate))
+	require.False(goerrors.Is(err, ErrNotProposer))
+	require.Equal("io1abc is not delegate", err.Error())
+	require.Equal("notDelegate", ClassifyConsensusError(err))
+
+	// the rejection is kept as the outermost error through the wrappers
+	err = wrapRejection(err, "failed to verify vote")
+	require.Equal("failed to verify vote: io1abc is not delegate", err.Error())
+	var rejection *RejectionError
+	require.True(goerrors.As(err, &rejection))
+	require.Equal("notDelegate", rejection.Code())
+	require.True(goerrors.Is(err, ErrNotDelegate))
+
+	// so is a sentinel error wrapped, which gets the reason of the sentinel
+	err = wrapRejection(errors.Wrap(ErrTooManyEndorsements, "30 endorsements"), "failed to verify block proposal")
+	require.True(goerrors.Is(err, ErrTooManyEndorsements))
+	require.True(goerrors.As(err, &rejection))
+	require.Equal(ReasonTooManyEndorsements, rejection.Reason())
+	require.Equal(ErrTooManyEndorsements, errors.Cause(err))
+
+	// an error of no reason stays as it is
+	plain := errors.New("error")
+	require.Equal(plain, reject(ReasonUnknown, plain))
+	require.False(goerrors.As(wrapRejection(plain, "failed"), &rejection))
+}
+
+func TestRejectionReasons(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	cfg.UnlockProofHeight = 22
+	b, rp := makeChain(t)
+	rctx, err := newRollDPoSCtx(cfg, true, time.Second*20, time.Second, true, b, nil, rp, nil, nil, "", nil, clock.New())
+	require.NoError(err)
+	requireReason := func(reason RejectionReason, err error) {
+		require.Error(err)
+		require.Equal(reason, RejectionReasonOf(err), err.Error())
+		// the standard errors see the rejection and its sentinel error, as does the ingress wrapping it
+		for _, err := range []error{err, wrapRejection(err, "failed to verify consensus message")} {
+			var rejection *RejectionError
+			require.True(goerrors.As(err, &rejection), err.Error())
+			require.Equal(reason.String(), rejection.Code())
+			require.Equal(reason.String(), ClassifyConsensusError(err))
+			require.True(goerrors.Is(err, sentinelOf(reason)), err.Error())
+		}
+	}
+
+	// votes
+	en := endorsement.NewEndorsement(time.Now(), identityset.PrivateKey(0).PublicKey(), nil)
+	requireReason(ReasonNotDelegate, rctx.CheckVoteEndorser(0, nil, en))
+	_, err = rctx.verifyVote("vote", []ConsensusVoteTopic{COMMIT})
+	requireReason(ReasonInvalidMessage, err)
+	blkHash := hash.Hash256b([]byte("block"))
+	vote := NewConsensusVote(blkHash[:], COMMIT)
+	_, err = rctx.verifyVote(NewEndorsedConsensusMessage(1, vote, en), []ConsensusVoteTopic{COMMIT})
+	requireReason(ReasonInvalidSignature, err)
+	en, err = endorsement.Endorse(identityset.PrivateKey(0), vote, time.Now())
+	require.NoError(err)
+	_, err = rctx.verifyVote(NewEndorsedConsensusMessage(1, vote, en), []ConsensusVoteTopic{COMMIT})
+	requireReason(ReasonBlockNotReceived, err)
+
+	// block proposals
+	blk := getBlockforctx(t, 5, false)
+	en = endorsement.NewEndorsement(time.Unix(1562382392, 0), identityset.PrivateKey(5).PublicKey(), nil)
+	bp := newBlockProposal(&blk, []*endorsement.Endorsement{en})
+	requireReason(ReasonHeightMismatch, rctx.CheckBlockProposer(1, bp, en))
+	requireReason(ReasonInvalidSignature, rctx.CheckBlockProposer(21, bp, en))
+	en2 := endorsement.NewEndorsement(time.Unix(1562382392, 0), identityset.PrivateKey(0).PublicKey(), nil)
+	requireReason(ReasonNotProposer, rctx.CheckBlockProposer(21, bp, en2))
+	blk = getBlockforctx(t, 0, true)
+	bp = newBlockProposal(&blk, []*endorsement.Endorsement{en})
+	requireReason(ReasonNotProposer, rctx.CheckBlockProposer(21, bp, en))
+
+	blk = getBlockforctx(t, 5, true)
+	blkHash = blk.HashBlock()
+	en2, err = endorsement.Endorse(identityset.PrivateKey(7), NewConsensusVote(blkHash[:], COMMIT), time.Unix(1562382592, 0))
+	require.NoError(err)
+	bp = newBlockProposal(&blk, []*endorsement.Endorsement{en2})
+	requireReason(ReasonInsufficientEndorsements, rctx.CheckBlockProposer(21, bp, en2))
+	en3, err := endorsement.Endorse(identityset.PrivateKey(7), NewConsensusVote(blkHash[:], PROPOSAL), time.Unix(1562382592, 0))
+	require.NoError(err)
+	bp = newBlockProposal(&blk, []*endorsement.Endorsement{en2, en3})
+	requireReason(ReasonDuplicateEndorser, rctx.CheckBlockProposer(21, bp, en2))
+	oversized := make([]*endorsement.Endorsement, rp.NumDelegates()+1)
+	for i := range oversized {
+		oversized[i] = en2
+	}
+	bp = newBlockProposal(&blk, oversized)
+	requireReason(ReasonTooManyEndorsements, rctx.CheckBlockProposer(21, bp, en2))
+	bp = newBlockProposalWithProof(&blk, []*endorsement.Endorsement{en2}, unlockProof)
+	requireReason(ReasonInvalidProof, rctx.CheckBlockProposer(21, bp, en2))
+	// the endorsements of a proof of unlock are made before the proposal
+	requireReason(ReasonTimestampOutOfWindow, rctx.verifyProofOfUnlock(21, bp, en2.Timestamp()))
+	rctx.cfg.UnlockProofHeight = 0
+	bp = newBlockProposal(&blk, []*endorsement.Endorsement{en2})
+	requireReason(ReasonInvalidProof, rctx.CheckBlockProposer(21, bp, en2))
+
+	_, err = rctx.NewProposalEndorsement("proposal")
+	requireReason(ReasonInvalidMessage, err)
+	_, err = rctx.NewProposalEndorsement(NewEndorsedConsensusMessage(21, vote, en))
+	requireReason(ReasonInvalidMessage, err)
+}
+
+// sentinelOf returns the sentinel error of the reason
+func sentinelOf(reason RejectionReason) error {
+	for sentinel, r := range sentinelReasons {
+		if r == reason {
+			return sentinel
+		}
+	}
+	return nil
+}
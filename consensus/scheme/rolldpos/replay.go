@@ -0,0 +1,163 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/action/protocol/rolldpos"
+	"github.com/iotexproject/iotex-core/blockchain"
+	"github.com/iotexproject/iotex-core/blockchain/genesis"
+	"github.com/iotexproject/iotex-core/state"
+)
+
+type (
+	// ProposerRecord is a block whose proposer can't be verified by replaying the proposer calculation
+	ProposerRecord struct {
+		Height    uint64
+		Timestamp time.Time
+		// Actual is the producer of the block recorded on chain
+		Actual string
+		// Expected is the proposer calculated for the block, which is empty if it can't be calculated
+		Expected string
+		// Skipped marks an epoch whose candidates are unavailable, which is skipped from the height on
+		Skipped bool
+		// Err tells why the block is recorded
+		Err error
+	}
+
+	// ReplayOption sets an option of the proposer replay
+	ReplayOption func(*roundCalculator)
+)
+
+// ReplayBlockIntervalOption sets the block interval of the chain, which is the one of the default genesis otherwise
+func ReplayBlockIntervalOption(blockInterval time.Duration) ReplayOption {
+	return func(c *roundCalculator) {
+		c.blockInterval = blockInterval
+	}
+}
+
+// ReplayTimeBasedRotationOption sets whether the proposers rotate by round, which is the flag of the default genesis
+// otherwise
+func ReplayTimeBasedRotationOption(timeBasedRotation bool) ReplayOption {
+	return func(c *roundCalculator) {
+		c.timeBasedRotation = timeBasedRotation
+	}
+}
+
+// ReplayCandidatesOption sets the func returning the candidates of a height, which is the one of the chain otherwise
+func ReplayCandidatesOption(candidatesByHeightFunc CandidatesByHeightFunc) ReplayOption {
+	return func(c *roundCalculator) {
+		c.candidatesByHeightFunc = candidatesByHeightFunc
+	}
+}
+
+// ReplayProbationOption sets the func returning the probated delegates of an epoch, along with the height from which
+// they are out of the proposer rotation, such that the replay agrees with a chain on which the probation is active
+func ReplayProbationOption(probationHeight uint64, probationListFunc ProbationListFunc) ReplayOption {
+	return func(c *roundCalculator) {
+		c.probationHeight = probationHeight
+		c.probationListFunc = probationListFunc
+	}
+}
+
+// ReplayRotationSeedOption sets the func returning the seed of the proposer rotation of an epoch, along with the height
+// from which the rotation is shuffled by it, such that the replay agrees with a chain on which the seeding is active
+func ReplayRotationSeedOption(rotationSeedHeight uint64, rotationSeedFunc RotationSeedFunc) ReplayOption {
+	return func(c *roundCalculator) {
+		c.rotationSeedHeight = rotationSeedHeight
+		c.rotationSeedFunc = rotationSeedFunc
+	}
+}
+
+// ReplayProposers recalculates the proposer of each block in [from, to] from its header timestamp, and compares it
+// against the producer of the block, e.g., to verify a new version against the chain before a release. It returns the
+// blocks of which the proposers mismatch or can't be calculated, along with a skipped record of each epoch whose
+// candidates are unavailable. The headers are read one height at a time.
+func ReplayProposers(
+	chain blockchain.Blockchain,
+	rp *rolldpos.Protocol,
+	from uint64,
+	to uint64,
+	opts ...ReplayOption,
+) ([]ProposerRecord, error) {
+	if chain == nil {
+		return nil, errors.New("blockchain is nil")
+	}
+	if rp == nil {
+		return nil, errors.New("rolldpos protocol is nil")
+	}
+	if from == 0 {
+		// the genesis block has no proposer
+		from = 1
+	}
+	if tip := chain.TipHeight(); to > tip {
+		return nil, errors.Errorf("height %d is beyond the tip %d", to, tip)
+	}
+	calc := &roundCalculator{
+		chain:                  chain,
+		blockInterval:          genesis.Default.BlockInterval,
+		timeBasedRotation:      genesis.Default.TimeBasedRotation,
+		rp:                     rp,
+		candidatesByHeightFunc: chain.CandidatesByHeight,
+	}
+	for _, opt := range opts {
+		opt(calc)
+	}
+	if calc.blockInterval <= 0 {
+		return nil, errors.Errorf("invalid block interval %s", calc.blockInterval)
+	}
+	calc.candidatesByHeightFunc = cacheCandidatesOfEpoch(calc.candidatesByHeightFunc)
+
+	var records []ProposerRecord
+	for height := from; height <= to; height++ {
+		header, err := chain.BlockHeaderByHeight(height)
+		if err != nil {
+			return records, errors.Wrapf(err, "failed to get the header of block %d", height)
+		}
+		record := ProposerRecord{
+			Height:    height,
+			Timestamp: header.Timestamp(),
+			Actual:    header.ProducerAddress(),
+		}
+		if _, err := calc.Delegates(height); err != nil {
+			record.Skipped = true
+			record.Err = err
+			records = append(records, record)
+			// continue from the start of the next epoch
+			height = rp.GetEpochHeight(rp.GetEpochNum(height)+1) - 1
+			continue
+		}
+		if err := calc.Validate(height, record.Timestamp, record.Actual); err != nil {
+			record.Expected = calc.Proposer(height, record.Timestamp)
+			record.Err = err
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// cacheCandidatesOfEpoch caches the candidates of the last height queried, which is the start height of the epoch
+// being replayed
+func cacheCandidatesOfEpoch(candidatesByHeightFunc CandidatesByHeightFunc) CandidatesByHeightFunc {
+	var (
+		cachedHeight     uint64
+		cachedCandidates []*state.Candidate
+		cachedErr        error
+		cached           bool
+	)
+	return func(height uint64) ([]*state.Candidate, error) {
+		if !cached || height != cachedHeight {
+			cachedCandidates, cachedErr = candidatesByHeightFunc(height)
+			cachedHeight = height
+			cached = true
+		}
+		return cachedCandidates, cachedErr
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/state"
+	"github.com/iotexproject/iotex-core/test/identityset"
+)
+
+func TestReplayProposers(t *testing.T) {
+	require := require.New(t)
+	bc, rp := makeChain(t)
+	blockInterval := 10 * time.Second
+	// distinct candidates, which are unavailable for the first epoch
+	candidates := []*state.Candidate{}
+	keys := map[string]int{}
+	for i := 0; i < int(rp.NumDelegates()); i++ {
+		keys[identityset.Address(i).String()] = i
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(height uint64) ([]*state.Candidate, error) {
+		if height == 1 {
+			return nil, errors.New("candidates are unavailable")
+		}
+		return candidates, nil
+	}
+	calc := &roundCalculator{bc, blockInterval, time.Second, true, rp, candidatesByHeight, nil, 0, false, 0, endorsementThreshold{}, 0, nil, 0}
+
+	// blocks proposed by the proposers of the rounds, except for one at height 53
+	for height := bc.TipHeight() + 1; height <= 56; height++ {
+		footer, err := bc.BlockFooterByHeight(height - 1)
+		require.NoError(err)
+		round, err := calc.NewRound(height, footer.CommitTime().Add(time.Nanosecond))
+		require.NoError(err)
+		ts := round.StartTime()
+		if height == 55 {
+			// a block of a later round
+			ts = ts.Add(blockInterval)
+			round, err = calc.NewRound(height, ts)
+			require.NoError(err)
+		}
+		require.NoError(calc.Validate(height, ts, round.Proposer()))
+		i := keys[round.Proposer()]
+		if height == 53 {
+			i = (i + 1) % len(candidates)
+		}
+		bc.SetProducerPrivateKey(identityset.PrivateKey(i))
+		blk, err := bc.MintNewBlock(nil, ts)
+		require.NoError(err)
+		require.NoError(blk.Finalize(nil, ts))
+		require.NoError(bc.CommitBlock(blk))
+	}
+
+	_, err := ReplayProposers(bc, rp, 1, 57)
+	require.Error(err)
+	records, err := ReplayProposers(
+		bc,
+		rp,
+		0,
+		56,
+		ReplayBlockIntervalOption(blockInterval),
+		ReplayTimeBasedRotationOption(true),
+		ReplayCandidatesOption(candidatesByHeight),
+	)
+	require.NoError(err)
+	// the first epoch is skipped, and the blocks 49 and 50 are produced by a random key
+	require.Equal(4, len(records))
+	require.True(records[0].Skipped)
+	require.Equal(uint64(1), records[0].Height)
+	require.Error(records[0].Err)
+	for i, height := range []uint64{49, 50, 53} {
+		record := records[i+1]
+		require.False(record.Skipped)
+		require.Equal(height, record.Height)
+		require.Equal(ErrProposerMismatch, errors.Cause(record.Err))
+		header, err := bc.BlockHeaderByHeight(height)
+		require.NoError(err)
+		require.Equal(header.ProducerAddress(), record.Actual)
+		require.NotEqual(record.Actual, record.Expected)
+		require.Equal(calc.Proposer(height, header.Timestamp()), record.Expected)
+	}
+
+	// nothing mismatches in the verified range
+	records, err = ReplayProposers(
+		bc,
+		rp,
+		54,
+		56,
+		ReplayBlockIntervalOption(blockInterval),
+		ReplayTimeBasedRotationOption(true),
+		ReplayCandidatesOption(candidatesByHeight),
+	)
+	require.NoError(err)
+	require.Equal(0, len(records))
+
+	// the block of a later round mismatches without the time based rotation
+	records, err = ReplayProposers(
+		bc,
+		rp,
+		54,
+		56,
+		ReplayBlockIntervalOption(blockInterval),
+		ReplayTimeBasedRotationOption(false),
+		ReplayCandidatesOption(candidatesByHeight),
+	)
+	require.NoError(err)
+	require.Equal(1, len(records))
+	require.Equal(uint64(55), records[0].Height)
+	require.Equal(ErrProposerMismatch, errors.Cause(records[0].Err))
+}
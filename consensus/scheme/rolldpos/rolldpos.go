@@ -8,6 +8,8 @@ package rolldpos
 
 import (
 	"context"
+	"sync/atomic"
+	"time"
 
 	"github.com/facebookgo/clock"
 	"github.com/iotexproject/go-fsm"
@@ -23,7 +25,9 @@ import (
 	"github.com/iotexproject/iotex-core/config"
 	"github.com/iotexproject/iotex-core/consensus/consensusfsm"
 	"github.com/iotexproject/iotex-core/consensus/scheme"
+	"github.com/iotexproject/iotex-core/db"
 	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/p2p"
 	"github.com/iotexproject/iotex-core/pkg/log"
 )
 
@@ -34,35 +38,157 @@ var (
 	ErrZeroDelegate = errors.New("zero delegates in the network")
 	// ErrNotEnoughCandidates indicates there are not enough candidates from the candidate pool
 	ErrNotEnoughCandidates = errors.New("Candidate pool does not have enough candidates")
+	// ErrForceStateDisabled indicates forcing the consensus FSM into a state while it isn't enabled in the config
+	ErrForceStateDisabled = errors.New("forcing the consensus state is disabled")
+	// ErrBlockTooEarly indicates a block timestamped within the min block interval after the previous block
+	ErrBlockTooEarly = errors.New("block is within the min block interval")
+	// ErrBlockBeforeParent indicates a block not timestamped after its parent block
+	ErrBlockBeforeParent = errors.New("block is not after its parent block")
+	// ErrProposerMismatch indicates the proposer calculated for a block differs from the expected one
+	ErrProposerMismatch = errors.New("proposer mismatch")
+	// ErrProposalTooLarge indicates the block minted to propose exceeds the proposal max size
+	ErrProposalTooLarge = errors.New("block to propose is too large")
 )
 
+// BuildError is the error of constructing RollDPoS out of a failure of the consensus context. errors.Cause returns
+// ErrNewRollDPoS from it as from the other errors of the builder, while the failure itself is kept in the message and
+// unwrapped by the standard errors package along with ErrNewRollDPoS.
+type BuildError struct {
+	err error
+}
+
+// Error returns the message of the error
+func (e *BuildError) Error() string { return ErrNewRollDPoS.Error() + ": " + e.err.Error() }
+
+// Cause returns ErrNewRollDPoS
+func (e *BuildError) Cause() error { return ErrNewRollDPoS }
+
+// Err returns the failure of constructing the consensus context
+func (e *BuildError) Err() error { return e.err }
+
+// Unwrap returns ErrNewRollDPoS and the failure of constructing the consensus context
+func (e *BuildError) Unwrap() []error { return []error{ErrNewRollDPoS, e.err} }
+
+// HealthStatus is the health status of the roll-DPoS consensus
+type HealthStatus int
+
+const (
+	// Participating means the node is active and keeps up with the chain
+	Participating HealthStatus = iota
+	// Standby means the node is not active
+	Standby
+	// Stalled means no block has been committed for too long
+	Stalled
+	// Syncing means the consensus round lags behind the chain tip
+	Syncing
+)
+
+// String returns the name of the health status
+func (s HealthStatus) String() string {
+	switch s {
+	case Participating:
+		return "participating"
+	case Standby:
+		return "standby"
+	case Stalled:
+		return "stalled"
+	case Syncing:
+		return "syncing"
+	default:
+		return "unknown"
+	}
+}
+
+// Healthy returns true if the node is participating or on purpose standing by
+func (s HealthStatus) Healthy() bool {
+	return s == Participating || s == Standby
+}
+
+// Signer signs on behalf of the delegate running the consensus, with the delegate key in use
+type Signer interface {
+	// PublicKey returns the public key to verify the signatures with
+	PublicKey() crypto.PublicKey
+	// Sign signs the hash of the data
+	Sign(hash []byte) ([]byte, error)
+}
+
 // RollDPoS is Roll-DPoS consensus main entrance
 type RollDPoS struct {
-	cfsm  *consensusfsm.ConsensusFSM
-	ctx   *rollDPoSCtx
-	ready chan interface{}
+	cfsm     *consensusfsm.ConsensusFSM
+	ctx      *rollDPoSCtx
+	verifier *voteVerifier
+	watchdog *watchdog
+	// peerReporter receives the outcomes of validating the messages relayed by the peers, and is nil if not needed
+	peerReporter scheme.PeerScoreReporter
+	ready        chan interface{}
+	// stopped is set once the consensus is stopped, which leaves the FSM in the state it was in
+	stopped int32
 }
 
 // Start starts RollDPoS consensus
 func (r *RollDPoS) Start(ctx context.Context) error {
+	if err := r.ctx.Start(ctx); err != nil {
+		return errors.Wrap(err, "error when starting the consensus context")
+	}
 	if err := r.cfsm.Start(ctx); err != nil {
 		return errors.Wrap(err, "error when starting the consensus FSM")
 	}
 	if _, err := r.cfsm.BackToPrepare(r.ctx.cfg.Delay); err != nil {
 		return err
 	}
+	if r.verifier != nil {
+		r.verifier.Start()
+	}
+	if r.watchdog != nil {
+		r.watchdog.Start()
+	}
 	close(r.ready)
 	return nil
 }
 
 // Stop stops RollDPoS consensus
 func (r *RollDPoS) Stop(ctx context.Context) error {
-	return errors.Wrap(r.cfsm.Stop(ctx), "error when stopping the consensus FSM")
+	if r.watchdog != nil {
+		r.watchdog.Stop()
+	}
+	if r.verifier != nil {
+		r.verifier.Stop()
+	}
+	if err := r.cfsm.Stop(ctx); err != nil {
+		return errors.Wrap(err, "error when stopping the consensus FSM")
+	}
+	atomic.StoreInt32(&r.stopped, 1)
+	return errors.Wrap(r.ctx.Stop(ctx), "error when stopping the consensus context")
+}
+
+// StopGracefully prepares the consensus for the shutdown of the node, before the network and the chain go away. It
+// deactivates the consensus for the rounds to come, waits up to the timeout for the block being committed, if any, to
+// be committed, and cancels the pending retries of the failed broadcasts. It returns an error if the block is still
+// being committed after the timeout, in which case the retries are cancelled all the same. The consensus is still to
+// be stopped by Stop.
+func (r *RollDPoS) StopGracefully(timeout time.Duration) error {
+	r.ctx.ShutDown()
+	committed := r.ctx.WaitCommit(timeout)
+	if n := r.ctx.CancelBroadcastRetries(); n > 0 {
+		log.Logger("consensus").Info("cancelled the broadcast retries on shutdown", zap.Int("retries", n))
+	}
+	if !committed {
+		return errors.Errorf("block is still being committed after %s", timeout)
+	}
+	return nil
 }
 
-// HandleConsensusMsg handles incoming consensus message
-func (r *RollDPoS) HandleConsensusMsg(msg *iotextypes.ConsensusMessage) error {
+// HandleConsensusMsg handles incoming consensus message. The outcome of the validation is reported to the peer
+// relaying the message, which is carried by the context.
+func (r *RollDPoS) HandleConsensusMsg(ctx context.Context, msg *iotextypes.ConsensusMessage) (err error) {
 	<-r.ready
+	peerID, _ := p2p.GetPeerID(ctx)
+	validated := false
+	defer func() {
+		if err != nil || validated {
+			r.reportPeer(peerID, err)
+		}
+	}()
 	consensusHeight := r.ctx.Height()
 	switch {
 	case consensusHeight == 0:
@@ -83,37 +209,80 @@ func (r *RollDPoS) HandleConsensusMsg(msg *iotextypes.ConsensusMessage) error {
 		)
 		return nil
 	}
+	// the limits are checked before decoding the message, which is costly for a huge block or proof of lock
+	if err := r.ctx.CheckMessageLimits(msg); err != nil {
+		return wrapRejection(err, "failed to check the limits of consensus message")
+	}
 	endorsedMessage := &EndorsedConsensusMessage{}
 	if err := endorsedMessage.LoadProto(msg); err != nil {
-		return errors.Wrapf(err, "failed to decode endorsed consensus message")
+		return reject(ReasonInvalidMessage, errors.Wrapf(err, "failed to decode endorsed consensus message"))
+	}
+	if vote, ok := endorsedMessage.Document().(*ConsensusVote); ok && r.verifier != nil {
+		if err := r.ctx.CheckVoteEndorser(endorsedMessage.Height(), vote, endorsedMessage.Endorsement()); err != nil {
+			return wrapRejection(err, "failed to verify vote")
+		}
+		// the signature is verified by the pool, which feeds the vote to the FSM and reports the peer
+		r.verifier.Submit(endorsedMessage, peerID)
+		return nil
 	}
 	if !endorsement.VerifyEndorsedDocument(endorsedMessage) {
-		return errors.New("failed to verify signature in endorsement")
+		return reject(ReasonInvalidSignature, errors.New("failed to verify signature in endorsement"))
 	}
 	en := endorsedMessage.Endorsement()
 	switch consensusMessage := endorsedMessage.Document().(type) {
 	case *blockProposal:
 		if err := r.ctx.CheckBlockProposer(endorsedMessage.Height(), consensusMessage, en); err != nil {
-			return errors.Wrap(err, "failed to verify block proposal")
+			return wrapRejection(err, "failed to verify block proposal")
 		}
+		validated = true
 		r.cfsm.ProduceReceiveBlockEvent(endorsedMessage)
+		r.ctx.Relay(endorsedMessage)
 		return nil
 	case *ConsensusVote:
 		if err := r.ctx.CheckVoteEndorser(endorsedMessage.Height(), consensusMessage, en); err != nil {
-			return errors.Wrapf(err, "failed to verify vote")
-		}
-		switch consensusMessage.Topic() {
-		case PROPOSAL:
-			r.cfsm.ProduceReceiveProposalEndorsementEvent(endorsedMessage)
-		case LOCK:
-			r.cfsm.ProduceReceiveLockEndorsementEvent(endorsedMessage)
-		case COMMIT:
-			r.cfsm.ProduceReceivePreCommitEndorsementEvent(endorsedMessage)
+			return wrapRejection(err, "failed to verify vote")
 		}
+		validated = true
+		r.produceVoteEvent(endorsedMessage)
 		return nil
 	// TODO: response block by hash, requestBlock.BlockHash
 	default:
-		return errors.Errorf("Invalid consensus message type %+v", msg)
+		return reject(ReasonInvalidMessage, errors.Errorf("Invalid consensus message type %+v", msg))
+	}
+}
+
+// reportPeer reports the outcome of validating a message relayed by a peer, where a rejection counts as invalid only
+// if it blames the message itself. A rejection is counted by its code, whether or not there is a peer to report.
+func (r *RollDPoS) reportPeer(peerID string, err error) {
+	if err != nil {
+		rejectedMessageMtc.WithLabelValues(ClassifyConsensusError(err)).Inc()
+	}
+	if r.peerReporter == nil || peerID == "" {
+		return
+	}
+	if err == nil {
+		r.peerReporter.ReportValid(peerID)
+		return
+	}
+	if reason := RejectionReasonOf(err); reason.BlamesSender() {
+		r.peerReporter.ReportInvalid(peerID, reason.String())
+	}
+}
+
+// produceVoteEvent feeds a vote to the FSM by its topic, and relays it in the observer mode
+func (r *RollDPoS) produceVoteEvent(msg *EndorsedConsensusMessage) {
+	vote, ok := msg.Document().(*ConsensusVote)
+	if !ok {
+		return
+	}
+	defer r.ctx.Relay(msg)
+	switch vote.Topic() {
+	case PROPOSAL:
+		r.cfsm.ProduceReceiveProposalEndorsementEvent(msg)
+	case LOCK:
+		r.cfsm.ProduceReceiveLockEndorsementEvent(msg)
+	case COMMIT:
+		r.cfsm.ProduceReceivePreCommitEndorsementEvent(msg)
 	}
 }
 
@@ -147,11 +316,7 @@ func (r *RollDPoS) ValidateBlockFooter(blk *block.Block) error {
 			return err
 		}
 	}
-	if !round.EndorsedByMajority(blkHash[:], []ConsensusVoteTopic{COMMIT}) {
-		return ErrInsufficientEndorsements
-	}
-
-	return nil
+	return round.checkMajority(blkHash[:], []ConsensusVoteTopic{COMMIT})
 }
 
 // Metrics returns RollDPoS consensus metrics
@@ -191,12 +356,105 @@ func (r *RollDPoS) CurrentState() fsm.State {
 	return r.cfsm.CurrentState()
 }
 
+// ForceState forces the consensus FSM into a state by a backdoor event, which is an administrative means to recover a
+// node wedged in a state. It returns ErrForceStateDisabled unless EnableForceState is set in the config, and fails for
+// a state not defined by the FSM.
+func (r *RollDPoS) ForceState(dst fsm.State) error {
+	if !r.ctx.cfg.EnableForceState {
+		return ErrForceStateDisabled
+	}
+	src := r.cfsm.CurrentState()
+	if err := r.cfsm.ForceState(dst); err != nil {
+		return err
+	}
+	log.Logger("consensus").Warn(
+		"Forcing the consensus FSM into a state by the operator.",
+		zap.String("from", string(src)),
+		zap.String("to", string(dst)),
+		zap.Uint64("height", r.ctx.Height()),
+	)
+	return nil
+}
+
 // Activate activates or pauses the roll-DPoS consensus. When it is deactivated, the node will finish the current
 // consensus round if it is doing the work and then return the the initial state
 func (r *RollDPoS) Activate(active bool) { r.ctx.Activate(active) }
 
-// Active is true if the roll-DPoS consensus is active, or false if it is stand-by
+// SetSyncTargetFunc sets the func returning the target height of the block sync, such that the watchdog of the
+// consensus FSM doesn't fire during the initial sync
+func (r *RollDPoS) SetSyncTargetFunc(syncTarget SyncTargetFunc) {
+	if r.watchdog != nil {
+		r.watchdog.SetSyncTarget(syncTarget)
+	}
+}
+
+// Health returns the health status of the roll-DPoS consensus
+func (r *RollDPoS) Health() HealthStatus {
+	if !r.Active() {
+		return Standby
+	}
+	return r.ctx.Health()
+}
+
+// ParticipationRates returns the fraction of the finalized blocks in the participation window endorsed by each of the
+// current delegates
+func (r *RollDPoS) ParticipationRates() map[string]float64 { return r.ctx.ParticipationRates() }
+
+// EpochParticipation returns the number of the blocks finalized in an epoch proposed and endorsed by each of its
+// delegates, for the reward and the penalty accounting at the epoch boundary. Only the current and the previous epochs
+// are kept.
+func (r *RollDPoS) EpochParticipation(epochNum uint64) (map[string]ParticipationStats, error) {
+	return r.ctx.EpochParticipation(epochNum)
+}
+
+// RecentForkEvents returns the recent blocks endorsed by the consensus but losing to a competing block of the same
+// height committed by the block sync, from the oldest to the newest
+func (r *RollDPoS) RecentForkEvents() []ForkEvent { return r.ctx.RecentForkEvents() }
+
+// NumForkEventsSince returns the number of the recent fork events which occurred at or after a time
+func (r *RollDPoS) NumForkEventsSince(t time.Time) int { return r.ctx.NumForkEventsSince(t) }
+
+// RegisterFinalityHook adds a hook to call asynchronously right after a block is finalized by the consensus and
+// committed to the chain. The blocks committed via block sync don't trigger the hooks.
+func (r *RollDPoS) RegisterFinalityHook(hook FinalityHook) { r.ctx.RegisterFinalityHook(hook) }
+
+// SubscribeBlockCommit returns a channel receiving the blocks committed to the chain in order, including those
+// committed via block sync, and the func to unsubscribe. See Blockchain.SubscribeBlockCommit.
+func (r *RollDPoS) SubscribeBlockCommit(buffer int) (<-chan *block.Block, func()) {
+	return r.ctx.chain.SubscribeBlockCommit(buffer)
+}
+
+// Reload replaces the FSM time durations and the tolerated overtime since the next round, e.g., on receiving a reload
+// signal. The config is validated against the block interval, and the other fields of it are ignored.
+func (r *RollDPoS) Reload(cfg config.RollDPoS) error { return r.ctx.Reload(cfg) }
+
+// RotateKey replaces the delegate key of the node since the next round, at the beginning of which the delegate status
+// is evaluated against the new key
+func (r *RollDPoS) RotateKey(priKey crypto.PrivateKey) error { return r.ctx.RotateKey(priKey) }
+
+// Signer returns the signer with the delegate key of the node, which follows the key rotation. It returns nil in the
+// observer mode, which has no key.
+func (r *RollDPoS) Signer() Signer {
+	if r.ctx.IsObserver() {
+		return nil
+	}
+	return r.ctx
+}
+
+// ProposerByHeight returns the proposer expected for the committed block of a height, without re-deriving the schedule
+// of the epoch by the caller
+func (r *RollDPoS) ProposerByHeight(height uint64) (string, error) {
+	return r.ctx.RoundCalc().ProposerByHeight(height)
+}
+
+// Delegates returns the delegates of the current consensus round
+func (r *RollDPoS) Delegates() []string { return r.ctx.Delegates() }
+
+// Active is true if the roll-DPoS consensus is active, or false if it is stand-by or stopped
 func (r *RollDPoS) Active() bool {
+	if atomic.LoadInt32(&r.stopped) == 1 {
+		return false
+	}
 	return r.ctx.Active() || r.cfsm.CurrentState() != consensusfsm.InitState
 }
 
@@ -213,6 +471,12 @@ type Builder struct {
 	// TODO: explorer dependency deleted at #1085, need to add api params
 	rp                     *rolldpos.Protocol
 	candidatesByHeightFunc CandidatesByHeightFunc
+	probationListFunc      ProbationListFunc
+	rotationSeedFunc       RotationSeedFunc
+	faultPlan              *FaultPlan
+	minter                 BlockMinter
+	peerReporter           scheme.PeerScoreReporter
+	roundStateStore        db.KVStore
 }
 
 // NewRollDPoSBuilder instantiates a Builder instance
@@ -270,12 +534,53 @@ func (b *Builder) SetCandidatesByHeightFunc(
 	return b
 }
 
+// SetProbationListFunc sets the func returning the probated delegates of an epoch, which are removed from the proposer
+// rotation from the probation height in the config
+func (b *Builder) SetProbationListFunc(probationListFunc ProbationListFunc) *Builder {
+	b.probationListFunc = probationListFunc
+	return b
+}
+
+// SetRotationSeedFunc sets the func returning the seed shuffling the proposer rotation of an epoch from the rotation
+// seed height in the config, which replaces the hash of the last block of the previous epoch, e.g., with a randomness
+// beacon
+func (b *Builder) SetRotationSeedFunc(rotationSeedFunc RotationSeedFunc) *Builder {
+	b.rotationSeedFunc = rotationSeedFunc
+	return b
+}
+
 // RegisterProtocol sets the rolldpos protocol
 func (b *Builder) RegisterProtocol(rp *rolldpos.Protocol) *Builder {
 	b.rp = rp
 	return b
 }
 
+// SetFaultPlan sets the byzantine behaviors to inject, which is for testing only and takes effect only if the fault
+// injection is enabled in both the config and the environment
+func (b *Builder) SetFaultPlan(plan *FaultPlan) *Builder {
+	b.faultPlan = plan
+	return b
+}
+
+// SetBlockMinter sets the minter of the blocks to propose, which mints a new block on the blockchain by default
+func (b *Builder) SetBlockMinter(minter BlockMinter) *Builder {
+	b.minter = minter
+	return b
+}
+
+// SetPeerScoreReporter sets the reporter of the outcomes of validating the consensus messages relayed by the peers
+func (b *Builder) SetPeerScoreReporter(reporter scheme.PeerScoreReporter) *Builder {
+	b.peerReporter = reporter
+	return b
+}
+
+// SetRoundStateStore sets the KV store persisting the round state, which lets a delegate restarting in the middle of
+// a height resume with its lock. The round state isn't persisted if not set.
+func (b *Builder) SetRoundStateStore(kvstore db.KVStore) *Builder {
+	b.roundStateStore = kvstore
+	return b
+}
+
 // Build builds a RollDPoS consensus module
 func (b *Builder) Build() (*RollDPoS, error) {
 	if b.chain == nil {
@@ -287,10 +592,37 @@ func (b *Builder) Build() (*RollDPoS, error) {
 	if b.broadcastHandler == nil {
 		return nil, errors.Wrap(ErrNewRollDPoS, "broadcast callback is nil")
 	}
+	if b.rp == nil {
+		return nil, errors.Wrap(ErrNewRollDPoS, "rolldpos protocol is not registered")
+	}
+	// an observer never signs, which needs no key
+	if b.priKey == nil && !b.cfg.Consensus.RollDPoS.Observer {
+		return nil, errors.Wrap(ErrNewRollDPoS, "private key is nil")
+	}
+	if b.encodedAddr == "" && !b.cfg.Consensus.RollDPoS.Observer {
+		return nil, errors.Wrap(ErrNewRollDPoS, "address is empty")
+	}
 	if b.clock == nil {
 		b.clock = clock.New()
 	}
-	ctx := newRollDPoSCtx(
+	broadcastHandler := b.broadcastHandler
+	var faults *faultInjector
+	if b.faultPlan != nil {
+		if b.cfg.Consensus.RollDPoS.Observer {
+			return nil, errors.Wrap(ErrNewRollDPoS, "an observer doesn't inject byzantine behaviors")
+		}
+		if !faultInjectionEnabled(b.cfg.Consensus.RollDPoS) {
+			return nil, errors.Wrapf(
+				ErrNewRollDPoS,
+				"fault injection requires both the config flag and the environment variable %s",
+				FaultInjectionEnv,
+			)
+		}
+		log.L().Warn("Byzantine behaviors are injected into RollDPoS", zap.Any("plan", b.faultPlan))
+		faults = newFaultInjector(*b.faultPlan)
+		broadcastHandler = faults.wrapBroadcast(broadcastHandler)
+	}
+	ctx, err := newRollDPoSCtx(
 		b.cfg.Consensus.RollDPoS,
 		b.cfg.System.Active,
 		b.cfg.Genesis.Blockchain.BlockInterval,
@@ -299,19 +631,41 @@ func (b *Builder) Build() (*RollDPoS, error) {
 		b.chain,
 		b.actPool,
 		b.rp,
-		b.broadcastHandler,
+		broadcastHandler,
 		b.candidatesByHeightFunc,
 		b.encodedAddr,
 		b.priKey,
 		b.clock,
 	)
+	if err != nil {
+		return nil, &BuildError{err: err}
+	}
+	ctx.faults = faults
+	ctx.stateStore = b.roundStateStore
+	ctx.roundCalc.probationListFunc = b.probationListFunc
+	ctx.roundCalc.rotationSeedFunc = b.rotationSeedFunc
+	if ctx.roundCalc.rotationSeedFunc == nil {
+		ctx.roundCalc.rotationSeedFunc = NewBlockHashRotationSeedFunc(b.chain, b.rp)
+	}
+	ctx.blockGasLimit = b.cfg.Genesis.BlockGasLimit
+	if b.minter != nil {
+		ctx.minter = b.minter
+	}
 	cfsm, err := consensusfsm.NewConsensusFSM(b.cfg.Consensus.RollDPoS.FSM, ctx, b.clock)
 	if err != nil {
 		return nil, errors.Wrap(err, "error when constructing the consensus FSM")
 	}
-	return &RollDPoS{
-		cfsm:  cfsm,
-		ctx:   ctx,
-		ready: make(chan interface{}),
-	}, nil
+	ctx.reloadFSM = cfsm.SetConfig
+	r := &RollDPoS{
+		cfsm:         cfsm,
+		ctx:          ctx,
+		peerReporter: b.peerReporter,
+		ready:        make(chan interface{}),
+		watchdog:     newWatchdog(cfsm, ctx, b.cfg.Consensus.RollDPoS.WatchdogStallIntervals),
+	}
+	if workers := b.cfg.Consensus.RollDPoS.VoteVerifierWorkers; workers > 0 {
+		r.verifier = newVoteVerifier(workers, b.cfg.Consensus.RollDPoS.VoteVerifierQueueSize, r.produceVoteEvent)
+		r.verifier.report = r.reportPeer
+	}
+	return r, nil
 }
@@ -8,9 +8,11 @@ package rolldpos
 
 import (
 	"encoding/hex"
+	goerrors "errors"
 	"fmt"
 	"math/big"
 	"net"
+	"os"
 	"sync"
 	"testing"
 	"time"
@@ -19,10 +21,12 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	fsm "github.com/iotexproject/go-fsm"
 	"github.com/iotexproject/go-pkgs/crypto"
 	"github.com/iotexproject/go-pkgs/hash"
 	"github.com/iotexproject/iotex-proto/golang/iotextypes"
 	"github.com/pkg/errors"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
@@ -35,8 +39,10 @@ import (
 	"github.com/iotexproject/iotex-core/blockchain"
 	"github.com/iotexproject/iotex-core/blockchain/block"
 	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/consensus/consensusfsm"
 	cp "github.com/iotexproject/iotex-core/crypto"
 	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/p2p"
 	"github.com/iotexproject/iotex-core/p2p/node"
 	"github.com/iotexproject/iotex-core/state"
 	"github.com/iotexproject/iotex-core/state/factory"
@@ -79,6 +85,33 @@ func TestNewRollDPoS(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotNil(t, r)
 	})
+	t.Run("observer", func(t *testing.T) {
+		observerCfg := cfg
+		observerCfg.Consensus.RollDPoS.Observer = true
+		r, err := NewRollDPoSBuilder().
+			SetConfig(observerCfg).
+			SetBlockchain(mock_blockchain.NewMockBlockchain(ctrl)).
+			SetActPool(mock_actpool.NewMockActPool(ctrl)).
+			SetBroadcast(func(_ proto.Message) error {
+				return nil
+			}).
+			RegisterProtocol(rp).
+			Build()
+		assert.NoError(t, err)
+		assert.True(t, r.ctx.IsObserver())
+		assert.Nil(t, r.Signer())
+		// the key is required otherwise
+		_, err = NewRollDPoSBuilder().
+			SetConfig(cfg).
+			SetBlockchain(mock_blockchain.NewMockBlockchain(ctrl)).
+			SetActPool(mock_actpool.NewMockActPool(ctrl)).
+			SetBroadcast(func(_ proto.Message) error {
+				return nil
+			}).
+			RegisterProtocol(rp).
+			Build()
+		assert.Error(t, err)
+	})
 	t.Run("mock-clock", func(t *testing.T) {
 		sk := identityset.PrivateKey(0)
 		r, err := NewRollDPoSBuilder().
@@ -99,6 +132,26 @@ func TestNewRollDPoS(t *testing.T) {
 		assert.True(t, ok)
 	})
 
+	t.Run("block-minter", func(t *testing.T) {
+		chain := mock_blockchain.NewMockBlockchain(ctrl)
+		minter := NewBlockMinter(chain)
+		r, err := NewRollDPoSBuilder().
+			SetConfig(cfg).
+			SetAddr(identityset.Address(0).String()).
+			SetPriKey(identityset.PrivateKey(0)).
+			SetBlockchain(chain).
+			SetActPool(mock_actpool.NewMockActPool(ctrl)).
+			SetBroadcast(func(_ proto.Message) error {
+				return nil
+			}).
+			SetClock(clock.NewMock()).
+			SetBlockMinter(minter).
+			RegisterProtocol(rp).
+			Build()
+		require.NoError(t, err)
+		require.True(t, r.ctx.minter == minter)
+	})
+
 	t.Run("root chain API", func(t *testing.T) {
 		sk := identityset.PrivateKey(0)
 		r, err := NewRollDPoSBuilder().
@@ -116,6 +169,25 @@ func TestNewRollDPoS(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotNil(t, r)
 	})
+	t.Run("standby", func(t *testing.T) {
+		standbyCfg := cfg
+		standbyCfg.System.Active = false
+		r, err := NewRollDPoSBuilder().
+			SetConfig(standbyCfg).
+			SetAddr(identityset.Address(0).String()).
+			SetPriKey(identityset.PrivateKey(0)).
+			SetBlockchain(mock_blockchain.NewMockBlockchain(ctrl)).
+			SetActPool(mock_actpool.NewMockActPool(ctrl)).
+			SetBroadcast(func(_ proto.Message) error {
+				return nil
+			}).
+			SetClock(clock.NewMock()).
+			RegisterProtocol(rp).
+			Build()
+		require.NoError(t, err)
+		require.Equal(t, Standby, r.Health())
+		require.True(t, r.Health().Healthy())
+	})
 	t.Run("missing-dep", func(t *testing.T) {
 		sk := identityset.PrivateKey(0)
 		r, err := NewRollDPoSBuilder().
@@ -131,6 +203,61 @@ func TestNewRollDPoS(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, r)
 	})
+	t.Run("invalid-config", func(t *testing.T) {
+		invalidTTL := cfg
+		invalidTTL.Consensus.RollDPoS.FSM.CommitTTL = cfg.Genesis.BlockInterval
+		for _, test := range []struct {
+			name   string
+			modify func(*Builder)
+			err    string
+		}{
+			{"nil blockchain", func(b *Builder) { b.chain = nil }, "blockchain APIs is nil"},
+			{"nil actpool", func(b *Builder) { b.actPool = nil }, "action pool APIs is nil"},
+			{"nil broadcast", func(b *Builder) { b.broadcastHandler = nil }, "broadcast callback is nil"},
+			{"nil protocol", func(b *Builder) { b.rp = nil }, "rolldpos protocol is not registered"},
+			{"nil private key", func(b *Builder) { b.priKey = nil }, "private key is nil"},
+			{"empty address", func(b *Builder) { b.encodedAddr = "" }, "address is empty"},
+			{"ttls exceed block interval", func(b *Builder) { b.SetConfig(invalidTTL) }, "invalid ttl config"},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				b := NewRollDPoSBuilder().
+					SetConfig(cfg).
+					SetAddr(identityset.Address(0).String()).
+					SetPriKey(identityset.PrivateKey(0)).
+					SetBlockchain(mock_blockchain.NewMockBlockchain(ctrl)).
+					SetActPool(mock_actpool.NewMockActPool(ctrl)).
+					SetBroadcast(func(_ proto.Message) error {
+						return nil
+					}).
+					SetClock(clock.NewMock()).
+					RegisterProtocol(rp)
+				test.modify(b)
+				r, err := b.Build()
+				require.Equal(t, ErrNewRollDPoS, errors.Cause(err))
+				require.Contains(t, err.Error(), test.err)
+				require.Nil(t, r)
+			})
+		}
+		// the failure of constructing the context is kept
+		_, err := NewRollDPoSBuilder().
+			SetConfig(invalidTTL).
+			SetAddr(identityset.Address(0).String()).
+			SetPriKey(identityset.PrivateKey(0)).
+			SetBlockchain(mock_blockchain.NewMockBlockchain(ctrl)).
+			SetActPool(mock_actpool.NewMockActPool(ctrl)).
+			SetBroadcast(func(_ proto.Message) error {
+				return nil
+			}).
+			SetClock(clock.NewMock()).
+			RegisterProtocol(rp).
+			Build()
+		var buildErr *BuildError
+		require.True(t, goerrors.As(err, &buildErr))
+		require.Equal(t, ErrNewRollDPoS, errors.Cause(err))
+		require.True(t, goerrors.Is(err, ErrNewRollDPoS))
+		require.Contains(t, buildErr.Err().Error(), "invalid ttl config")
+		require.True(t, goerrors.Is(err, buildErr.Err()))
+	})
 }
 func makeBlock(t *testing.T, accountIndex, numOfEndosements int, makeInvalidEndorse bool, height int) *block.Block {
 	unixTime := 1500000000
@@ -315,6 +442,106 @@ func TestRollDPoS_Metrics(t *testing.T) {
 	assert.Equal(t, candidates[1], m.LatestBlockProducer)
 }
 
+type fakePeerReporter struct {
+	reports chan string
+}
+
+func (f *fakePeerReporter) ReportValid(peerID string) { f.reports <- peerID + ":valid" }
+
+func (f *fakePeerReporter) ReportInvalid(peerID string, reason string) {
+	f.reports <- peerID + ":" + reason
+}
+
+func TestRollDPoS_ReportPeer(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	rctx, err := newRollDPoSCtx(
+		cfg, true, 20*time.Second, time.Second, true, b, nil, rp, nil, candidatesByHeight,
+		identityset.Address(0).String(), identityset.PrivateKey(0), c,
+	)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+
+	reporter := &fakePeerReporter{reports: make(chan string, 10)}
+	handled := make(chan *EndorsedConsensusMessage, 10)
+	r := &RollDPoS{ctx: rctx, peerReporter: reporter, ready: make(chan interface{})}
+	r.verifier = newVoteVerifier(1, 10, func(msg *EndorsedConsensusMessage) { handled <- msg })
+	r.verifier.report = r.reportPeer
+	r.verifier.Start()
+	defer r.verifier.Stop()
+	close(r.ready)
+
+	height := rctx.round.Height()
+	blkHash := hash.Hash256b([]byte("block"))
+	vote := NewConsensusVote(blkHash[:], PROPOSAL)
+	endorse := func(height uint64, key crypto.PrivateKey, doc endorsement.Document) *iotextypes.ConsensusMessage {
+		en, err := endorsement.Endorse(key, doc, rctx.round.StartTime())
+		require.NoError(err)
+		msg, err := NewEndorsedConsensusMessage(height, vote, en).Proto()
+		require.NoError(err)
+		return msg
+	}
+	ctx := p2p.WithPeerID(context.Background(), "peer")
+	requireReport := func(expected string) {
+		select {
+		case report := <-reporter.reports:
+			require.Equal(expected, report)
+		case <-time.After(5 * time.Second):
+			require.FailNow("no report of " + expected)
+		}
+	}
+
+	rejected := func(code string) float64 {
+		return promtestutil.ToFloat64(rejectedMessageMtc.WithLabelValues(code))
+	}
+	notDelegates, invalidMessages := rejected("notDelegate"), rejected("invalidMessage")
+
+	// an endorsement of a non-delegate
+	err = r.HandleConsensusMsg(ctx, endorse(height, identityset.PrivateKey(27), vote))
+	require.True(goerrors.Is(err, ErrNotDelegate))
+	var rejection *RejectionError
+	require.True(goerrors.As(err, &rejection))
+	require.Equal(ReasonNotDelegate, rejection.Reason())
+	requireReport("peer:notDelegate")
+	require.Equal(notDelegates+1, rejected("notDelegate"))
+	// a malformed message
+	err = r.HandleConsensusMsg(ctx, &iotextypes.ConsensusMessage{Height: height})
+	require.True(goerrors.Is(err, ErrInvalidMessage))
+	requireReport("peer:invalidMessage")
+	require.Equal(invalidMessages+1, rejected("invalidMessage"))
+	// an endorsement signing another vote, which is rejected by the vote verifier
+	require.NoError(r.HandleConsensusMsg(ctx, endorse(height, identityset.PrivateKey(1), NewConsensusVote(blkHash[:], LOCK))))
+	requireReport("peer:invalidSignature")
+	require.NoError(r.HandleConsensusMsg(ctx, endorse(height, identityset.PrivateKey(1), vote)))
+	requireReport("peer:valid")
+	<-handled
+
+	// the messages off the current height and the ones not relayed by a peer are not reported
+	require.NoError(r.HandleConsensusMsg(ctx, endorse(height-1, identityset.PrivateKey(27), vote)))
+	require.Error(r.HandleConsensusMsg(context.Background(), endorse(height, identityset.PrivateKey(27), vote)))
+	// nor is a rejection due to the local state
+	r.reportPeer("peer", ErrExpiredEndorsement)
+	// the reporter is optional
+	r.peerReporter = nil
+	require.Error(r.HandleConsensusMsg(ctx, endorse(height, identityset.PrivateKey(27), vote)))
+	require.Equal(0, len(reporter.reports))
+}
+
 // E2E RollDPoS tests bellow
 
 type directOverlay struct {
@@ -330,7 +557,7 @@ func (o *directOverlay) Broadcast(msg proto.Message) error {
 	// Only broadcast consensus message
 	if cMsg, ok := msg.(*iotextypes.ConsensusMessage); ok {
 		for _, r := range o.peers {
-			if err := r.HandleConsensusMsg(cMsg); err != nil {
+			if err := r.HandleConsensusMsg(p2p.WithPeerID(context.Background(), o.addr.String()), cMsg); err != nil {
 				return errors.Wrap(err, "error when handling consensus message directly")
 			}
 		}
@@ -350,9 +577,54 @@ func (o *directOverlay) GetPeers() []net.Addr {
 	return addrs
 }
 
+func TestRollDPoS_ForceState(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return b.CandidatesByHeight(1)
+	}
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	newRollDPoS := func(cfg config.RollDPoS) (*RollDPoS, func()) {
+		rctx, err := newRollDPoSCtx(
+			cfg, true, 20*time.Second, time.Second, true, b, nil, rp, nil, candidatesByHeight, "", nil, c,
+		)
+		require.NoError(err)
+		require.NoError(rctx.Prepare())
+		cfsm, err := consensusfsm.NewConsensusFSM(cfg.FSM, rctx, c)
+		require.NoError(err)
+		require.NoError(cfsm.Start(context.Background()))
+		return &RollDPoS{cfsm: cfsm, ctx: rctx}, func() {
+			require.NoError(cfsm.Stop(context.Background()))
+		}
+	}
+	lockState := fsm.State("S_ACCEPT_LOCK_ENDORSEMENT")
+
+	// disabled by default
+	r, stop := newRollDPoS(cfg)
+	require.Equal(ErrForceStateDisabled, r.ForceState(lockState))
+	require.Equal(consensusfsm.InitState, r.CurrentState())
+	stop()
+
+	cfg.EnableForceState = true
+	r, stop = newRollDPoS(cfg)
+	defer stop()
+	err = r.ForceState("S_UNDEFINED")
+	require.Equal(consensusfsm.ErrUndefinedState, errors.Cause(err))
+	require.NoError(r.ForceState(lockState))
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, time.Second, func() (bool, error) {
+		return r.CurrentState() == lockState, nil
+	}))
+}
+
 func TestRollDPoSConsensus(t *testing.T) {
-	newConsensusComponents := func(numNodes int) ([]*RollDPoS, []*directOverlay, []blockchain.Blockchain) {
+	// faults[i], if given, is the fault plan of the i-th node
+	newConsensusComponents := func(numNodes int, faults ...*FaultPlan) ([]*RollDPoS, []*directOverlay, []blockchain.Blockchain) {
 		cfg := config.Default
+		cfg.Consensus.RollDPoS.FaultInjection = len(faults) > 0
 		cfg.Consensus.RollDPoS.Delay = 300 * time.Millisecond
 		cfg.Consensus.RollDPoS.FSM.AcceptBlockTTL = 800 * time.Millisecond
 		cfg.Consensus.RollDPoS.FSM.AcceptProposalEndorsementTTL = 400 * time.Millisecond
@@ -446,7 +718,7 @@ func TestRollDPoSConsensus(t *testing.T) {
 			}
 			p2ps = append(p2ps, p2p)
 
-			consensus, err := NewRollDPoSBuilder().
+			builder := NewRollDPoSBuilder().
 				SetAddr(chainAddrs[i].encodedAddr).
 				SetPriKey(chainAddrs[i].priKey).
 				SetConfig(cfg).
@@ -454,8 +726,11 @@ func TestRollDPoSConsensus(t *testing.T) {
 				SetActPool(actPool).
 				SetBroadcast(p2p.Broadcast).
 				SetCandidatesByHeightFunc(candidatesByHeightFunc).
-				RegisterProtocol(rp).
-				Build()
+				RegisterProtocol(rp)
+			if i < len(faults) && faults[i] != nil {
+				builder = builder.SetFaultPlan(faults[i])
+			}
+			consensus, err := builder.Build()
 			require.NoError(t, err)
 
 			cs = append(cs, consensus)
@@ -697,5 +972,80 @@ func TestRollDPoSConsensus(t *testing.T) {
 				assert.NoError(t, err)
 			}
 		}
+		// the isolated node never endorses the blocks finalized by the others
+		accounted := false
+		for _, c := range cs[1:] {
+			rates := c.ParticipationRates()
+			if len(rates) == 0 {
+				continue
+			}
+			accounted = true
+			if rate, ok := rates[cs[0].ctx.encodedAddr]; ok {
+				assert.Equal(t, float64(0), rate)
+			}
+		}
+		assert.True(t, accounted)
+	})
+
+	t.Run("byzantine-node-safety", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("Skip the byzantine-node-safety test in short mode.")
+		}
+		require.NoError(t, os.Setenv(FaultInjectionEnv, "true"))
+		defer func() {
+			require.NoError(t, os.Unsetenv(FaultInjectionEnv))
+		}()
+		ctx := context.Background()
+		// node 0 is byzantine, the other 3 are honest
+		cs, p2ps, chains := newConsensusComponents(4, &FaultPlan{
+			Equivocate:       true,
+			CommitUnproposed: true,
+			EndorsementDelay: 300 * time.Millisecond,
+			DropRate:         0.2,
+		})
+
+		for i := 0; i < 4; i++ {
+			require.NoError(t, chains[i].Start(ctx))
+			require.NoError(t, p2ps[i].Start(ctx))
+		}
+		wg := sync.WaitGroup{}
+		wg.Add(4)
+		for i := 0; i < 4; i++ {
+			go func(idx int) {
+				defer wg.Done()
+				err := cs[idx].Start(ctx)
+				require.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		defer func() {
+			for i := 0; i < 4; i++ {
+				require.NoError(t, cs[i].Stop(ctx))
+				require.NoError(t, p2ps[i].Stop(ctx))
+				require.NoError(t, chains[i].Stop(ctx))
+			}
+		}()
+		// every node gets its turn to propose
+		assert.NoError(t, testutil.WaitUntil(200*time.Millisecond, 60*time.Second, func() (bool, error) {
+			for _, chain := range chains[1:] {
+				if chain.TipHeight() < 5 {
+					return false, nil
+				}
+			}
+			return true, nil
+		}))
+		for height := uint64(1); height <= 5; height++ {
+			var expected hash.Hash256
+			for i, chain := range chains[1:] {
+				h, err := chain.GetHashByHeight(height)
+				require.NoError(t, err)
+				if i == 0 {
+					expected = h
+					continue
+				}
+				require.Equal(t, expected, h, "honest nodes committed different blocks at height %d", height)
+			}
+		}
 	})
 }
@@ -7,10 +7,16 @@
 package rolldpos
 
 import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/facebookgo/clock"
+	"github.com/golang/protobuf/proto"
 	"github.com/iotexproject/go-fsm"
 	"github.com/iotexproject/go-pkgs/crypto"
 	"github.com/iotexproject/iotex-address/address"
@@ -18,12 +24,16 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
+	"github.com/iotexproject/iotex-core/action"
 	"github.com/iotexproject/iotex-core/action/protocol/rolldpos"
 	"github.com/iotexproject/iotex-core/actpool"
+	"github.com/iotexproject/iotex-core/actpool/actioniterator"
 	"github.com/iotexproject/iotex-core/blockchain"
+	"github.com/iotexproject/iotex-core/blockchain/block"
 	"github.com/iotexproject/iotex-core/config"
 	"github.com/iotexproject/iotex-core/consensus/consensusfsm"
 	"github.com/iotexproject/iotex-core/consensus/scheme"
+	"github.com/iotexproject/iotex-core/db"
 	"github.com/iotexproject/iotex-core/endorsement"
 	"github.com/iotexproject/iotex-core/pkg/log"
 	"github.com/iotexproject/iotex-core/state"
@@ -61,6 +71,31 @@ var (
 		},
 		[]string{},
 	)
+
+	broadcastFailureMtc = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "iotex_consensus_broadcast_failure",
+			Help: "Number of consensus messages and blocks failed to broadcast after all retries",
+		},
+		[]string{"type"},
+	)
+
+	roundsToCommitMtc = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "iotex_consensus_rounds_to_commit",
+			Help:    "Round number at which the blocks are committed",
+			Buckets: prometheus.LinearBuckets(0, 1, 10),
+		},
+		[]string{"chainID"},
+	)
+
+	futureRoundEventMtc = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "iotex_consensus_future_round_events",
+			Help: "Number of consensus messages dropped as their rounds are too far ahead of the current round",
+		},
+		[]string{},
+	)
 )
 
 func init() {
@@ -68,10 +103,25 @@ func init() {
 	prometheus.MustRegister(blockIntervalMtc)
 	prometheus.MustRegister(consensusDurationMtc)
 	prometheus.MustRegister(consensusHeightMtc)
+	prometheus.MustRegister(broadcastFailureMtc)
+	prometheus.MustRegister(roundsToCommitMtc)
+	prometheus.MustRegister(futureRoundEventMtc)
 }
 
+// broadcastRetryNamespace is the bucket of the broadcast retry queue
+const broadcastRetryNamespace = "broadcastRetry"
+
+// blockSizeSlack is the serialized size reserved for the header, the footer and the system actions of a block
+// proposed, e.g., the poll result of the next epoch, out of the proposal max size
+const blockSizeSlack = 16 << 10
+
 // CandidatesByHeightFunc defines a function to overwrite candidates
 type CandidatesByHeightFunc func(uint64) ([]*state.Candidate, error)
+
+// ProbationListFunc defines a function returning the probated delegates of an epoch, along with their probation
+// intensity rates
+type ProbationListFunc func(epochNum uint64) (map[string]uint32, error)
+
 type rollDPoSCtx struct {
 	cfg config.RollDPoS
 	// TODO: explorer dependency deleted at #1085, need to add api params here
@@ -79,13 +129,69 @@ type rollDPoSCtx struct {
 	actPool          actpool.ActPool
 	broadcastHandler scheme.Broadcast
 	roundCalc        *roundCalculator
+	// faults is the fault injector of byzantine behaviors, which is nil unless enabled for testing
+	faults *faultInjector
+	// participation accounts the proposals and the endorsements of the delegates in the finalized blocks
+	participation *participationTracker
+	// forks keeps the recent blocks endorsed by the consensus but losing to the ones committed by the block sync
+	forks *forkEvents
+	// minter mints the block to propose
+	minter BlockMinter
+	// blockGasLimit is the block gas limit of the genesis capping ProposalMaxGas, 0 if unknown
+	blockGasLimit uint64
+	// finalityHooks are called on the blocks committed by the consensus
+	finalityHooks finalityHooks
+	// summary aggregates the endorsements of the current round
+	summary *roundSummary
+	// seen records the vote endorsements added in the current round
+	seen *seenEndorsements
+	// relayed records the consensus messages relayed in the current round in the observer mode
+	relayed *seenEndorsements
+	// reloaded is the config to apply at the beginning of the next round, which is nil unless a reload is pending
+	reloaded *config.RollDPoS
+	// reloadFSM passes the reloaded time durations to the consensus FSM
+	reloadFSM func(consensusfsm.Config)
+	// adaptiveTTL adapts AcceptBlockTTL to the latency of the block proposals, which is nil unless enabled
+	adaptiveTTL *adaptiveTTL
+	// clockSkew measures the skew of the local clock against the delegates, which is nil unless enabled
+	clockSkew *clockSkewDetector
+	// retryQueue schedules the retries of the failed broadcasts, which are kept in retries by the task IDs
+	retryQueue *db.TaskQueue
+	retries    map[uint64]*broadcastRetry
+	retryMutex sync.Mutex
+	// retriesCancelled is set on shutdown, since when the failed broadcasts are no longer retried
+	retriesCancelled bool
+	// commitMutex is held while committing a block, which lets the shutdown wait for the commit in progress
+	commitMutex sync.Mutex
+	// shuttingDown is set on shutdown, which deactivates the node for good without waiting for the mutex held by the
+	// commit in progress
+	shuttingDown int32
 
+	// stateStore persists the round state across the restarts, which isn't persisted if nil
+	stateStore db.KVStore
+	stateMutex sync.Mutex
+	// persistedDigest is the digest of the round state persisted the last time
+	persistedDigest []byte
+	// recovered is the round state loaded on start, which is restored onto the round of its height
+	recovered *roundState
+	// lastVote is the last vote endorsed by this node
+	lastVote *ownVote
+	// recoveredVote is the vote endorsed in the current round before the restart, which no vote of the round
+	// conflicts with
+	recoveredVote *ownVote
+
+	// observer follows the rounds without proposing or endorsing, and has neither an address nor a private key
+	observer    bool
 	encodedAddr string
 	priKey      crypto.PrivateKey
-	round       *roundCtx
-	clock       clock.Clock
-	active      bool
-	mutex       sync.RWMutex
+	// rotatedKey is the private key to sign with since the next round, which is nil unless a rotation is pending
+	rotatedKey crypto.PrivateKey
+	round      *roundCtx
+	// deadlines are the ends of the phases of the round
+	deadlines phaseDeadlines
+	clock     clock.Clock
+	active    bool
+	mutex     sync.RWMutex
 }
 
 func newRollDPoSCtx(
@@ -102,36 +208,57 @@ func newRollDPoSCtx(
 	encodedAddr string,
 	priKey crypto.PrivateKey,
 	clock clock.Clock,
-) *rollDPoSCtx {
+) (*rollDPoSCtx, error) {
+	if chain == nil {
+		return nil, errors.New("blockchain is nil")
+	}
+	if rp == nil {
+		return nil, errors.New("rolldpos protocol is nil")
+	}
+	if clock == nil {
+		return nil, errors.New("clock is nil")
+	}
+	if err := validateTTLs(cfg.FSM, blockInterval); err != nil {
+		return nil, err
+	}
 	if candidatesByHeightFunc == nil {
 		candidatesByHeightFunc = chain.CandidatesByHeight
 	}
+	threshold, err := newEndorsementThreshold(cfg)
+	if err != nil {
+		return nil, err
+	}
 	roundCalc := &roundCalculator{
 		blockInterval:          blockInterval,
-		candidatesByHeightFunc: candidatesByHeightFunc,
+		candidatesByHeightFunc: shareCandidatesFetch(candidatesByHeightFunc),
 		chain:                  chain,
 		rp:                     rp,
 		timeBasedRotation:      timeBasedRotation,
 		toleratedOvertime:      toleratedOvertime,
+		probationHeight:        cfg.ProbationHeight,
+		rotationSeedHeight:     cfg.RotationSeedHeight,
+		countProbated:          cfg.CountProbated,
+		thresholdHeight:        cfg.EndorsementThresholdHeight,
+		threshold:              threshold,
+		weightedVotingHeight:   cfg.WeightedVotingHeight,
 	}
 	round, err := roundCalc.NewRoundWithToleration(0, clock.Now())
 	if err != nil {
-		log.Logger("consensus").Panic("failed to generate round context", zap.Error(err))
-	}
-	if cfg.FSM.AcceptBlockTTL+cfg.FSM.AcceptProposalEndorsementTTL+cfg.FSM.AcceptLockEndorsementTTL+cfg.FSM.CommitTTL > blockInterval {
-		log.Logger("consensus").Panic(
-			"invalid ttl config, the sum of ttls should be equal to block interval",
-			zap.Duration("acceptBlockTTL", cfg.FSM.AcceptBlockTTL),
-			zap.Duration("acceptProposalEndorsementTTL", cfg.FSM.AcceptProposalEndorsementTTL),
-			zap.Duration("acceptLockEndorsementTTL", cfg.FSM.AcceptLockEndorsementTTL),
-			zap.Duration("commitTTL", cfg.FSM.CommitTTL),
-			zap.Duration("blockInterval", blockInterval),
-		)
+		return nil, errors.Wrap(err, "failed to generate the initial round context")
+	}
+	adaptiveTTL, err := newAdaptiveTTL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Observer {
+		// an observer never signs, so the key is dropped in case one is given
+		encodedAddr, priKey = "", nil
 	}
 
-	return &rollDPoSCtx{
+	ctx := &rollDPoSCtx{
 		cfg:              cfg,
 		active:           active,
+		observer:         cfg.Observer,
 		encodedAddr:      encodedAddr,
 		priKey:           priKey,
 		chain:            chain,
@@ -140,7 +267,41 @@ func newRollDPoSCtx(
 		clock:            clock,
 		roundCalc:        roundCalc,
 		round:            round,
+		deadlines:        newPhaseDeadlines(round.StartTime(), cfg.FSM),
+		participation:    newParticipationTracker(cfg.ParticipationWindow * rp.NumDelegates() * rp.NumSubEpochs()),
+		forks:            newForkEvents(forkEventsLimit),
+		minter:           NewBlockMinter(chain),
+		summary:          newRoundSummary(round),
+		seen:             newSeenEndorsements(seenEndorsementsLimit),
+		relayed:          newSeenEndorsements(seenEndorsementsLimit),
+		adaptiveTTL:      adaptiveTTL,
+		clockSkew:        newClockSkewDetector(cfg.ClockSkewThreshold, blockInterval),
+		retries:          make(map[uint64]*broadcastRetry),
+	}
+	// the retries are useless after a restart, hence kept in memory
+	if ctx.retryQueue, err = db.NewTaskQueue(
+		db.NewMemKVStore(),
+		broadcastRetryNamespace,
+		ctx.retryBroadcast,
+		db.TaskClockOption(clock),
+	); err != nil {
+		return nil, errors.Wrap(err, "failed to create the broadcast retry queue")
+	}
+	return ctx, nil
+}
+
+// Start loads the persisted round state, which is restored on preparing the first round, and starts retrying the
+// failed broadcasts
+func (ctx *rollDPoSCtx) Start(c context.Context) error {
+	if err := ctx.loadRoundState(); err != nil {
+		return err
 	}
+	return ctx.retryQueue.Start(c)
+}
+
+// Stop stops retrying the failed broadcasts
+func (ctx *rollDPoSCtx) Stop(c context.Context) error {
+	return ctx.retryQueue.Stop(c)
 }
 
 func (ctx *rollDPoSCtx) CheckVoteEndorser(
@@ -152,15 +313,19 @@ func (ctx *rollDPoSCtx) CheckVoteEndorser(
 	defer ctx.mutex.RUnlock()
 	endorserAddr, err := address.FromBytes(en.Endorser().Hash())
 	if err != nil {
-		return err
+		return reject(ReasonInvalidMessage, err)
 	}
 	if !ctx.roundCalc.IsDelegate(endorserAddr.String(), height) {
-		return errors.Errorf("%s is not delegate of the corresponding round", endorserAddr)
+		return reject(ReasonNotDelegate, errors.Errorf("%s is not delegate of the corresponding round", endorserAddr))
 	}
 
 	return nil
 }
 
+// CheckBlockProposer verifies the proposer and the proof of lock of a block proposal. The proof of lock could be
+// either a list of endorsements or an aggregate over the delegates of the height. Multiple proposals of an
+// equivocating proposer could all pass the check, which one to endorse is decided by the total order in
+// roundCtx.AcceptProposal.
 func (ctx *rollDPoSCtx) CheckBlockProposer(
 	height uint64,
 	proposal *blockProposal,
@@ -169,61 +334,184 @@ func (ctx *rollDPoSCtx) CheckBlockProposer(
 	ctx.mutex.RLock()
 	defer ctx.mutex.RUnlock()
 	if height != proposal.block.Height() {
-		return errors.Errorf(
+		return reject(ReasonHeightMismatch, errors.Errorf(
 			"block height %d different from expected %d",
 			proposal.block.Height(),
 			height,
-		)
+		))
+	}
+	// each delegate endorses at most once in a proof, reject an oversized one before verifying any endorsement
+	if numDelegates := ctx.roundCalc.rp.NumDelegates(); uint64(len(proposal.proofOfLock)) > numDelegates {
+		return reject(ReasonTooManyEndorsements, errors.Wrapf(
+			ErrTooManyEndorsements,
+			"%d endorsements in proof, more than %d delegates",
+			len(proposal.proofOfLock),
+			numDelegates,
+		))
+	}
+	// a block not after its parent has no proposer, which is told apart from a block of another proposer
+	if err := ctx.checkParentTime(height, proposal.block.Timestamp()); err != nil {
+		return err
 	}
 	endorserAddr, err := address.FromBytes(en.Endorser().Hash())
 	if err != nil {
-		return err
+		return reject(ReasonInvalidMessage, err)
 	}
 	if ctx.roundCalc.Proposer(height, en.Timestamp()) != endorserAddr.String() {
-		return errors.Errorf(
+		return reject(ReasonNotProposer, errors.Errorf(
 			"%s is not proposer of the corresponding round, %s expected",
 			endorserAddr.String(),
 			ctx.roundCalc.Proposer(height, en.Timestamp()),
-		)
+		))
 	}
 	proposerAddr := proposal.ProposerAddress()
 	if ctx.roundCalc.Proposer(height, proposal.block.Timestamp()) != proposerAddr {
-		return errors.Errorf("%s is not proposer of the corresponding round", proposerAddr)
+		return reject(ReasonNotProposer, errors.Errorf("%s is not proposer of the corresponding round", proposerAddr))
 	}
 	if !proposal.block.VerifySignature() {
-		return errors.Errorf("invalid block signature")
+		return reject(ReasonInvalidSignature, errors.Errorf("invalid block signature"))
+	}
+	forwarded := proposerAddr != endorserAddr.String()
+	if proposal.hasHeader() && height < ctx.cfg.UnlockProofHeight {
+		return reject(ReasonInvalidProof, errors.Errorf(
+			"block proposal with a header is not accepted below height %d",
+			ctx.cfg.UnlockProofHeight,
+		))
+	}
+	switch proposal.proofType {
+	case lockProof:
+		return reject(ReasonInvalidProof, ctx.verifyProofOfLock(height, proposal, en.Timestamp()))
+	case unlockProof:
+		if forwarded {
+			return reject(
+				ReasonInvalidProof,
+				errors.Errorf("block proposed by %s cannot be forwarded with a proof of unlock", proposerAddr),
+			)
+		}
+		return reject(ReasonInvalidProof, ctx.verifyProofOfUnlock(height, proposal, en.Timestamp()))
+	}
+	if height < ctx.cfg.UnlockProofHeight {
+		// old format, the proof is a proof of lock if the block is forwarded
+		if forwarded {
+			return reject(ReasonInvalidProof, ctx.verifyProofOfLock(height, proposal, en.Timestamp()))
+		}
+		return nil
+	}
+	if forwarded || len(proposal.proofOfLock) != 0 || proposal.aggregatedProofOfLock != nil {
+		return reject(ReasonInvalidProof, errors.New("proof type of block proposal is missing"))
+	}
+	return nil
+}
+
+// verifyProofOfLock verifies that a majority of the delegates endorsed the block
+func (ctx *rollDPoSCtx) verifyProofOfLock(height uint64, proposal *blockProposal, proposedAt time.Time) error {
+	round, err := ctx.roundCalc.NewRound(height, proposedAt)
+	if err != nil {
+		return err
+	}
+	if err := round.AddBlock(proposal.block); err != nil {
+		return err
+	}
+	blkHash := proposal.block.HashBlock()
+	votes := []*ConsensusVote{
+		NewConsensusVote(blkHash[:], PROPOSAL),
+		NewConsensusVote(blkHash[:], COMMIT),
+	}
+	proofOfLock, err := ctx.proofEndorsements(height, proposal, votes)
+	if err != nil {
+		return errors.Wrap(err, "failed to verify aggregated proof of lock")
 	}
-	if proposerAddr != endorserAddr.String() {
-		round, err := ctx.roundCalc.NewRound(height, en.Timestamp())
+	return VerifyProofOfLock(round, blkHash[:], proofOfLock)
+}
+
+// verifyProofOfUnlock verifies that the endorsements made in the previous rounds of the height justify proposing a
+// new block, i.e., either a majority of the delegates endorsed the new block, or a majority endorsed no block, such
+// that no other block could have been committed
+func (ctx *rollDPoSCtx) verifyProofOfUnlock(height uint64, proposal *blockProposal, proposedAt time.Time) error {
+	round, err := ctx.roundCalc.NewRound(height, proposedAt)
+	if err != nil {
+		return err
+	}
+	if err := round.AddBlock(proposal.block); err != nil {
+		return err
+	}
+	blkHash := proposal.block.HashBlock()
+	votes := []*ConsensusVote{
+		NewConsensusVote(nil, PROPOSAL),
+		NewConsensusVote(nil, COMMIT),
+		NewConsensusVote(blkHash[:], PROPOSAL),
+	}
+	proofOfUnlock, err := ctx.proofEndorsements(height, proposal, votes)
+	if err != nil {
+		return errors.Wrap(err, "failed to verify aggregated proof of unlock")
+	}
+	for _, e := range proofOfUnlock {
+		if !e.Timestamp().Before(proposedAt) {
+			return reject(
+				ReasonTimestampOutOfWindow,
+				errors.Errorf("endorsement at %s is not made before the proposal", e.Timestamp()),
+			)
+		}
+		// an endorsement made before the last block belongs to a lower height
+		if _, err := ctx.roundCalc.NewRound(height, e.Timestamp()); err != nil {
+			return reject(
+				ReasonTimestampOutOfWindow,
+				errors.Wrapf(err, "endorsement at %s is not made at height %d", e.Timestamp(), height),
+			)
+		}
+		endorserAddr, err := address.FromBytes(e.Endorser().Hash())
 		if err != nil {
 			return err
 		}
-		if err := round.AddBlock(proposal.block); err != nil {
-			return err
+		if !round.IsDelegate(endorserAddr.String()) {
+			return reject(ReasonNotDelegate, errors.Errorf("%s is not delegate of height %d", endorserAddr, height))
 		}
-		blkHash := proposal.block.HashBlock()
-		for _, e := range proposal.proofOfLock {
-			if err := round.AddVoteEndorsement(
-				NewConsensusVote(blkHash[:], PROPOSAL),
-				e,
-			); err == nil {
-				continue
-			}
-			if err := round.AddVoteEndorsement(
-				NewConsensusVote(blkHash[:], COMMIT),
-				e,
-			); err != nil {
-				return err
+		added := false
+		for _, vote := range votes {
+			if err := round.AddVoteEndorsement(vote, e); err == nil {
+				added = true
+				break
 			}
 		}
-		if !round.EndorsedByMajority(blkHash[:], []ConsensusVoteTopic{PROPOSAL, COMMIT}) {
-			return errors.Wrap(ErrInsufficientEndorsements, "failed to verify proof of lock")
+		if !added {
+			return errors.Errorf("invalid endorsement of %s in proof of unlock", endorserAddr)
 		}
 	}
+	if !round.EndorsedByMajority(nil, []ConsensusVoteTopic{PROPOSAL, COMMIT}) &&
+		!round.EndorsedByMajority(blkHash[:], []ConsensusVoteTopic{PROPOSAL}) {
+		return errors.Wrap(ErrInsufficientEndorsements, "failed to verify proof of unlock")
+	}
 	return nil
 }
 
+// proofEndorsements returns the endorsements attached to the proposal, restoring them from the aggregate if any
+func (ctx *rollDPoSCtx) proofEndorsements(
+	height uint64,
+	proposal *blockProposal,
+	votes []*ConsensusVote,
+) ([]*endorsement.Endorsement, error) {
+	if proposal.aggregatedProofOfLock == nil {
+		return proposal.proofOfLock, nil
+	}
+	// the aggregated form is a protocol change, which the nodes accept from the same height
+	if ctx.cfg.AggregateProofHeight == 0 || height < ctx.cfg.AggregateProofHeight {
+		return nil, errors.Errorf("aggregated proof of lock is not accepted at height %d", height)
+	}
+	committee, err := ctx.roundCalc.Delegates(height)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]endorsement.Document, 0, len(votes))
+	for _, vote := range votes {
+		docs = append(docs, vote)
+	}
+	return proposal.aggregatedProofOfLock.Endorsements(committee, docs...)
+}
+
 func (ctx *rollDPoSCtx) RoundCalc() *roundCalculator {
+	ctx.mutex.RLock()
+	defer ctx.mutex.RUnlock()
+
 	return ctx.roundCalc
 }
 
@@ -260,11 +548,23 @@ func (ctx *rollDPoSCtx) Logger() *zap.Logger {
 func (ctx *rollDPoSCtx) Prepare() error {
 	ctx.mutex.Lock()
 	defer ctx.mutex.Unlock()
+
+	return ctx.prepare()
+}
+
+func (ctx *rollDPoSCtx) prepare() error {
+	if err := ctx.applyRotatedKey(); err != nil {
+		return err
+	}
+	ctx.applyReloadedConfig()
+	ctx.applyAdaptiveTTL()
 	height := ctx.chain.TipHeight() + 1
 	newRound, err := ctx.roundCalc.UpdateRound(ctx.round, height, ctx.clock.Now())
 	if err != nil {
 		return err
 	}
+	ctx.summary.Next(log.Logger("consensus"), ctx.clock.Now(), newRound)
+	ctx.clockSkew.Check(ctx.logger(), newRound.Delegates(), ctx.clock.Now())
 	ctx.logger().Debug(
 		"new round",
 		zap.Uint64("height", newRound.height),
@@ -275,6 +575,11 @@ func (ctx *rollDPoSCtx) Prepare() error {
 		zap.String("roundStartTime", newRound.roundStartTime.String()),
 	)
 	ctx.round = newRound
+	ctx.restoreRoundState()
+	ctx.persistRoundState()
+	ctx.deadlines = newPhaseDeadlines(newRound.StartTime(), ctx.cfg.FSM)
+	ctx.seen = newSeenEndorsements(seenEndorsementsLimit)
+	ctx.relayed = newSeenEndorsements(seenEndorsementsLimit)
 	consensusHeightMtc.WithLabelValues().Set(float64(ctx.round.height))
 	timeSlotMtc.WithLabelValues().Set(float64(ctx.round.roundNum))
 	return nil
@@ -283,43 +588,93 @@ func (ctx *rollDPoSCtx) Prepare() error {
 func (ctx *rollDPoSCtx) IsDelegate() bool {
 	ctx.mutex.RLock()
 	defer ctx.mutex.RUnlock()
-	if active := ctx.active; !active {
+	if active := ctx.active && !ctx.isShuttingDown(); !active {
 		ctx.logger().Info("current node is in standby mode")
 		return false
 	}
+	if ctx.observer {
+		return false
+	}
 	return ctx.round.IsDelegate(ctx.encodedAddr)
 }
 
 func (ctx *rollDPoSCtx) Proposal() (interface{}, error) {
 	ctx.mutex.RLock()
 	defer ctx.mutex.RUnlock()
-	if ctx.round.Proposer() != ctx.encodedAddr {
+	if ctx.observer || ctx.round.Proposer() != ctx.encodedAddr {
+		return nil, nil
+	}
+	if ctx.skipLatePhase("proposal", ctx.deadlines.acceptBlock) {
 		return nil, nil
 	}
 	if ctx.round.IsLocked() {
-		return ctx.endorseBlockProposal(newBlockProposal(
-			ctx.round.Block(ctx.round.HashOfBlockInLock()),
-			ctx.round.ProofOfLock(),
-		))
+		blk := ctx.round.Block(ctx.round.HashOfBlockInLock())
+		if blk.Height() < ctx.cfg.UnlockProofHeight {
+			return ctx.endorseBlockProposal(newBlockProposal(blk, ctx.round.ProofOfLock()))
+		}
+		return ctx.endorseBlockProposal(newBlockProposalWithProof(blk, ctx.round.ProofOfLock(), lockProof))
+	}
+	if ctx.votedBeforeRestart() {
+		// the block proposed before the restart, if any, is gone, and a new one would equivocate
+		ctx.logger().Info("Skip proposing a block in the round endorsed before the restart.")
+		return nil, nil
+	}
+	switch err := ctx.checkMinBlockInterval(ctx.round.Height(), ctx.round.StartTime()); errors.Cause(err) {
+	case nil:
+	case ErrBlockTooEarly:
+		ctx.logger().Debug("Skip proposing a block within the min block interval.", zap.Error(err))
+		return nil, nil
+	default:
+		return nil, err
+	}
+	if ctx.suppressEmptyBlock() {
+		return nil, nil
+	}
+	proposal, err := ctx.mintNewBlock()
+	if err != nil {
+		return nil, err
 	}
-	return ctx.mintNewBlock()
+	ctx.injectEquivocation()
+
+	return proposal, nil
 }
 
+// WaitUntilRoundStart waits until the round starts and then for a random jitter, and returns the time passed since
+// the round start, i.e., the overtime including the jitter
 func (ctx *rollDPoSCtx) WaitUntilRoundStart() time.Duration {
 	ctx.mutex.RLock()
 	defer ctx.mutex.RUnlock()
 	now := ctx.clock.Now()
 	startTime := ctx.round.StartTime()
 	if now.Before(startTime) {
-		time.Sleep(startTime.Sub(now))
+		jitter := ctx.roundStartJitter(startTime)
+		time.Sleep(startTime.Sub(now) + jitter)
+		return jitter
+	}
+	jitter := ctx.roundStartJitter(now)
+	time.Sleep(jitter)
+	return now.Sub(startTime) + jitter
+}
+
+// roundStartJitter returns a random delay from a time within the round start jitter, which never passes the end of
+// the block proposal phase
+func (ctx *rollDPoSCtx) roundStartJitter(from time.Time) time.Duration {
+	max := ctx.cfg.RoundStartJitter
+	if window := ctx.deadlines.acceptBlock.Sub(from); window < max {
+		max = window
+	}
+	if max <= 0 {
 		return 0
 	}
-	return now.Sub(startTime)
+	return time.Duration(rand.Int63n(int64(max)))
 }
 
 func (ctx *rollDPoSCtx) PreCommitEndorsement() interface{} {
 	ctx.mutex.RLock()
 	defer ctx.mutex.RUnlock()
+	if ctx.observer {
+		return nil
+	}
 	endorsement := ctx.round.ReadyToCommit(ctx.encodedAddr)
 	if endorsement == nil {
 		// DON'T CHANGE, this is on purpose, because endorsement as nil won't result in a nil "interface {}"
@@ -328,41 +683,107 @@ func (ctx *rollDPoSCtx) PreCommitEndorsement() interface{} {
 	return endorsement
 }
 
+// ReceiveBlock validates a block proposal received in the accept-block phase, and keeps it as the candidate to endorse
+// once the proposal buffer expires if it wins the tie-break against the proposals received before
+func (ctx *rollDPoSCtx) ReceiveBlock(msg interface{}) error {
+	ctx.mutex.RLock()
+	defer ctx.mutex.RUnlock()
+
+	return ctx.receiveBlock(msg)
+}
+
+// NewProposalEndorsement endorses the candidate block proposal of the round, or the block in lock if no valid proposal
+// has been received. A proposal passed in is received before, such that it is endorsed if it wins the tie-break. The
+// proposal endorsement is made once in a round, so that the node never endorses competing proposals.
 func (ctx *rollDPoSCtx) NewProposalEndorsement(msg interface{}) (interface{}, error) {
 	ctx.mutex.RLock()
 	defer ctx.mutex.RUnlock()
-	var blockHash []byte
 	if msg != nil {
-		ecm, ok := msg.(*EndorsedConsensusMessage)
-		if !ok {
-			return nil, errors.New("invalid endorsed block")
-		}
-		proposal, ok := ecm.Document().(*blockProposal)
-		if !ok {
-			return nil, errors.New("invalid endorsed block")
-		}
-		blkHash := proposal.block.HashBlock()
-		blockHash = blkHash[:]
-		if proposal.block.WorkingSet == nil {
-			if err := ctx.chain.ValidateBlock(proposal.block); err != nil {
-				return nil, errors.Wrapf(err, "error when validating the proposed block")
-			}
-		}
-		if err := ctx.round.AddBlock(proposal.block); err != nil {
+		if err := ctx.receiveBlock(msg); err != nil {
 			return nil, err
 		}
+	}
+	blockHash, err := ctx.round.EndorseProposal()
+	if err != nil {
+		return nil, err
+	}
+	if len(blockHash) != 0 {
 		ctx.loggerWithStats().Debug("accept block proposal", log.Hex("block", blockHash))
-	} else if ctx.round.IsLocked() {
-		blockHash = ctx.round.HashOfBlockInLock()
+	} else {
+		ctx.logger().Warn("didn't receive the proposed block before timeout")
+		if ctx.round.IsLocked() {
+			blockHash = ctx.round.HashOfBlockInLock()
+		}
 	}
 
-	return ctx.newEndorsement(
+	ctx.injectUnproposedCommit()
+
+	if ctx.skipLatePhase("proposalEndorsement", ctx.deadlines.of(PROPOSAL)) {
+		return nil, nil
+	}
+	return ctx.newVote(
 		blockHash,
 		PROPOSAL,
 		ctx.round.StartTime().Add(ctx.cfg.FSM.AcceptBlockTTL),
 	)
 }
 
+func (ctx *rollDPoSCtx) receiveBlock(msg interface{}) error {
+	ecm, ok := msg.(*EndorsedConsensusMessage)
+	if !ok {
+		return reject(ReasonInvalidMessage, errors.New("invalid endorsed block"))
+	}
+	proposal, ok := ecm.Document().(*blockProposal)
+	if !ok {
+		return reject(ReasonInvalidMessage, errors.New("invalid endorsed block"))
+	}
+	blkHash := proposal.block.HashBlock()
+	if proposal.block.WorkingSet == nil {
+		if err := ctx.chain.ValidateBlock(proposal.block); err != nil {
+			return reject(ReasonInvalidBlock, errors.Wrapf(err, "error when validating the proposed block"))
+		}
+	}
+	if err := ctx.checkMinBlockInterval(proposal.block.Height(), proposal.block.Timestamp()); err != nil {
+		return err
+	}
+	if err := ctx.checkUnlock(proposal, ecm.Endorsement().Timestamp()); err != nil {
+		return err
+	}
+	if err := ctx.round.AddBlock(proposal.block); err != nil {
+		return err
+	}
+	// a valid proposal arriving after the proposal phase tells the latency as well
+	if proposal.block.ProducerAddress() != ctx.encodedAddr {
+		ctx.adaptiveTTL.Observe(ctx.round, ctx.clock.Now().Sub(ctx.round.StartTime()))
+	}
+	if err := ctx.round.AcceptProposal(blkHash[:], proposal.block.Timestamp()); err != nil {
+		return err
+	}
+	ctx.loggerWithStats().Debug("receive block proposal", log.Hex("block", blkHash[:]))
+
+	return nil
+}
+
+// checkUnlock makes sure that a validator locked on a block only endorses another block with a valid proof of unlock
+func (ctx *rollDPoSCtx) checkUnlock(proposal *blockProposal, proposedAt time.Time) error {
+	height := proposal.block.Height()
+	if !ctx.round.IsLocked() || height < ctx.cfg.UnlockProofHeight {
+		return nil
+	}
+	blkHash := proposal.block.HashBlock()
+	if bytes.Equal(blkHash[:], ctx.round.HashOfBlockInLock()) {
+		return nil
+	}
+	if proposal.proofType != unlockProof {
+		return reject(ReasonInvalidProof, errors.Errorf(
+			"locked on block %x, no proof of unlock for block %x",
+			ctx.round.HashOfBlockInLock(),
+			blkHash,
+		))
+	}
+	return reject(ReasonInvalidProof, ctx.verifyProofOfUnlock(height, proposal, proposedAt))
+}
+
 func (ctx *rollDPoSCtx) NewLockEndorsement(
 	msg interface{},
 ) (interface{}, error) {
@@ -373,12 +794,15 @@ func (ctx *rollDPoSCtx) NewLockEndorsement(
 		[]ConsensusVoteTopic{PROPOSAL, COMMIT}, // commit is counted as one proposal
 	)
 	switch errors.Cause(err) {
-	case ErrInsufficientEndorsements:
+	case ErrInsufficientEndorsements, ErrDuplicateEndorsement:
 		return nil, nil
 	case nil:
 		if len(blkHash) != 0 {
 			ctx.loggerWithStats().Debug("Locked", log.Hex("block", blkHash))
-			return ctx.newEndorsement(
+			if ctx.skipLatePhase("lockEndorsement", ctx.deadlines.of(LOCK)) {
+				return nil, nil
+			}
+			return ctx.newVote(
 				blkHash,
 				LOCK,
 				ctx.round.StartTime().Add(
@@ -401,11 +825,14 @@ func (ctx *rollDPoSCtx) NewPreCommitEndorsement(
 		[]ConsensusVoteTopic{LOCK, COMMIT}, // commit endorse is counted as one lock endorse
 	)
 	switch errors.Cause(err) {
-	case ErrInsufficientEndorsements:
+	case ErrInsufficientEndorsements, ErrDuplicateEndorsement:
 		return nil, nil
 	case nil:
 		ctx.loggerWithStats().Debug("Ready to pre-commit")
-		return ctx.newEndorsement(
+		if ctx.skipLatePhase("preCommitEndorsement", ctx.deadlines.of(COMMIT)) {
+			return nil, nil
+		}
+		return ctx.newVote(
 			blkHash,
 			COMMIT,
 			ctx.round.StartTime().Add(
@@ -418,21 +845,162 @@ func (ctx *rollDPoSCtx) NewPreCommitEndorsement(
 }
 
 func (ctx *rollDPoSCtx) Commit(msg interface{}) (bool, error) {
+	ctx.commitMutex.Lock()
+	defer ctx.commitMutex.Unlock()
+	committed, record, err := ctx.commit(msg)
+	// the endorsements are accounted without holding the mutex, which is only needed to snapshot the footer
+	ctx.participation.Record(record)
+	return committed, err
+}
+
+// WaitCommit waits for the block being committed, if any, to be committed, and returns false if it is still being
+// committed after the timeout. The timeout is measured by the wall clock, as the shutdown is.
+func (ctx *rollDPoSCtx) WaitCommit(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		ctx.commitMutex.Lock()
+		defer ctx.commitMutex.Unlock()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// CancelBroadcastRetries cancels the pending retries of the failed broadcasts, and stops retrying the ones failing
+// since, which is done on shutdown before the network goes away. It returns the number of the retries cancelled.
+func (ctx *rollDPoSCtx) CancelBroadcastRetries() int {
+	ctx.retryMutex.Lock()
+	defer ctx.retryMutex.Unlock()
+
+	ctx.retriesCancelled = true
+	for id := range ctx.retries {
+		if err := ctx.retryQueue.Cancel(id); err != nil && errors.Cause(err) != db.ErrNotExist {
+			ctx.logger().Warn("fail to cancel the broadcast retry", zap.Uint64("task", id), zap.Error(err))
+		}
+	}
+	n := len(ctx.retries)
+	ctx.retries = make(map[uint64]*broadcastRetry)
+	return n
+}
+
+// ParticipationRates returns the fraction of the finalized blocks in the participation window endorsed by each of the
+// current delegates
+func (ctx *rollDPoSCtx) ParticipationRates() map[string]float64 {
+	return ctx.participation.Rates()
+}
+
+// EpochParticipation returns the number of the blocks finalized in an epoch proposed and endorsed by each of its
+// delegates, which is kept for the current and the previous epochs
+func (ctx *rollDPoSCtx) EpochParticipation(epochNum uint64) (map[string]ParticipationStats, error) {
+	return ctx.participation.EpochParticipation(epochNum)
+}
+
+// RecentForkEvents returns the recent fork events, from the oldest to the newest
+func (ctx *rollDPoSCtx) RecentForkEvents() []ForkEvent {
+	return ctx.forks.Recent()
+}
+
+// NumForkEventsSince returns the number of the recent fork events which occurred at or after a time
+func (ctx *rollDPoSCtx) NumForkEventsSince(t time.Time) int {
+	return ctx.forks.CountSince(t)
+}
+
+// RegisterFinalityHook adds a hook to call on the blocks committed by the consensus
+func (ctx *rollDPoSCtx) RegisterFinalityHook(hook FinalityHook) {
+	ctx.finalityHooks.Register(hook)
+}
+
+// RotateKey sets the private key to sign with, which takes effect at the beginning of the next round, such that the
+// endorsements of the current round are all signed by the same key
+func (ctx *rollDPoSCtx) RotateKey(priKey crypto.PrivateKey) error {
+	if priKey == nil {
+		return errors.New("private key is nil")
+	}
+	if ctx.observer {
+		return errors.New("an observer has no key to rotate")
+	}
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+
+	ctx.rotatedKey = priKey
+	return nil
+}
+
+// Reload sets the FSM time durations, including UnmatchedEventTTL, and the tolerated overtime to apply at the
+// beginning of the next round, such that a round is run with the same durations. The other fields of the config are
+// ignored, and so is the event chan size, as the FSM event queue can't be resized.
+func (ctx *rollDPoSCtx) Reload(cfg config.RollDPoS) error {
+	if cfg.ToleratedOvertime < 0 || cfg.FSM.UnmatchedEventTTL < 0 || cfg.FSM.UnmatchedEventInterval < 0 {
+		return errors.New("invalid config, durations should not be negative")
+	}
+	ctx.mutex.Lock()
+	defer ctx.mutex.Unlock()
+
+	if err := validateTTLs(cfg.FSM, ctx.roundCalc.BlockInterval()); err != nil {
+		return err
+	}
+	if err := ctx.adaptiveTTL.Validate(cfg.FSM); err != nil {
+		return err
+	}
+	ctx.reloaded = &cfg
+	return nil
+}
+
+// detectFork records a fork event if the block failing to commit differs from the one stored at the height, which has
+// been committed by the block sync
+func (ctx *rollDPoSCtx) detectFork(pendingBlock *block.Block) {
+	header, err := ctx.chain.BlockHeaderByHeight(pendingBlock.Height())
+	if err != nil {
+		// the chain is behind the height rather than having committed another block
+		ctx.logger().Debug("Failed to get the block header at the height.", zap.Error(err))
+		return
+	}
+	ourHash, canonicalHash := pendingBlock.HashBlock(), header.HashBlock()
+	if ourHash == canonicalHash {
+		return
+	}
+	event := ForkEvent{
+		Height:        pendingBlock.Height(),
+		Time:          ctx.clock.Now(),
+		OurHash:       ourHash,
+		CanonicalHash: canonicalHash,
+		Endorsements:  len(pendingBlock.Endorsements()),
+	}
+	ctx.forks.Add(event)
+	ctx.logger().Warn(
+		"The block endorsed by the consensus loses to another block committed at the height.",
+		log.Hex("ourHash", ourHash[:]),
+		log.Hex("canonicalHash", canonicalHash[:]),
+		zap.Int("endorsements", event.Endorsements),
+	)
+}
+
+// commit commits the block endorsed by a majority, and returns the snapshot of the finalized block if committed
+func (ctx *rollDPoSCtx) commit(msg interface{}) (bool, *participationRecord, error) {
 	ctx.mutex.Lock()
 	defer ctx.mutex.Unlock()
 	blkHash, err := ctx.verifyVote(msg, []ConsensusVoteTopic{COMMIT})
 	switch errors.Cause(err) {
-	case ErrInsufficientEndorsements:
-		return false, nil
+	case ErrInsufficientEndorsements, ErrDuplicateEndorsement:
+		return false, nil, nil
 	case nil:
 		ctx.loggerWithStats().Debug("Ready to commit")
 	default:
-		return false, err
+		return false, nil, err
 	}
 	// this is redudant check for now, as we only accept endorsements of the received blocks
 	pendingBlock := ctx.round.Block(blkHash)
 	if pendingBlock == nil {
-		return false, nil
+		return false, nil, nil
+	}
+	if err := ctx.checkMinBlockInterval(pendingBlock.Height(), pendingBlock.Timestamp()); err != nil {
+		return false, nil, err
 	}
 	ctx.logger().Info("consensus reached", zap.Uint64("blockHeight", ctx.round.Height()))
 	if err := pendingBlock.Finalize(
@@ -441,28 +1009,39 @@ func (ctx *rollDPoSCtx) Commit(msg interface{}) (bool, error) {
 			ctx.cfg.FSM.AcceptBlockTTL+ctx.cfg.FSM.AcceptProposalEndorsementTTL+ctx.cfg.FSM.AcceptLockEndorsementTTL,
 		),
 	); err != nil {
-		return false, errors.Wrap(err, "failed to add endorsements to block")
+		return false, nil, errors.Wrap(err, "failed to add endorsements to block")
 	}
 	// Commit and broadcast the pending block
 	switch err := ctx.chain.CommitBlock(pendingBlock); errors.Cause(err) {
 	case blockchain.ErrInvalidTipHeight:
-		return true, nil
+		ctx.detectFork(pendingBlock)
+		return true, nil, nil
 	case nil:
 		break
 	default:
-		return false, errors.Wrap(err, "error when committing a block")
+		return false, nil, errors.Wrap(err, "error when committing a block")
 	}
+	// the round goes away with the next height, hence it is recorded for the explorers
+	if err := ctx.chain.PutConsensusMeta(&blockchain.ConsensusMeta{
+		Height:                pendingBlock.Height(),
+		BlockHash:             pendingBlock.HashBlock(),
+		Round:                 ctx.round.Number(),
+		Proposer:              ctx.round.Proposer(),
+		NumCommitEndorsements: uint32(len(pendingBlock.Endorsements())),
+		RoundStartTime:        ctx.round.StartTime(),
+	}); err != nil {
+		ctx.logger().Warn("failed to record the consensus meta", zap.Error(err))
+	}
+	chainID := strconv.FormatUint(uint64(ctx.chain.ChainID()), 10)
+	roundsToCommitMtc.WithLabelValues(chainID).Observe(float64(ctx.round.Number()))
+	ctx.summary.Flush(log.Logger("consensus"), ctx.clock.Now())
+	ctx.finalityHooks.Notify(pendingBlock)
 	// Remove transfers in this block from ActPool and reset ActPool state
 	ctx.actPool.Reset()
 	// Broadcast the committed block to the network
 	if blkProto := pendingBlock.ConvertToBlockPb(); blkProto != nil {
-		if err := ctx.broadcastHandler(blkProto); err != nil {
-			ctx.logger().Error(
-				"error when broadcasting blkProto",
-				zap.Error(err),
-				zap.Uint64("block", pendingBlock.Height()),
-			)
-		}
+		// keep retrying even if the consensus moves to the next height, as peers need the committed block
+		ctx.broadcast(blkProto, "block", ctx.round.NextRoundStartTime(), nil)
 		// putblock to parent chain if the current node is proposer and current chain is a sub chain
 		if ctx.round.Proposer() == ctx.encodedAddr && ctx.chain.ChainAddress() != "" {
 			// TODO: explorer dependency deleted at #1085, need to call putblock related method
@@ -485,12 +1064,21 @@ func (ctx *rollDPoSCtx) Commit(msg interface{}) (bool, error) {
 		}
 		blockIntervalMtc.WithLabelValues().Set(float64(pendingBlock.Timestamp().Sub(prevBlkHeader.Timestamp())))
 	}
-	return true, nil
+	return true, &participationRecord{
+		height:       pendingBlock.Height(),
+		epochNum:     ctx.round.EpochNum(),
+		proposer:     ctx.round.Proposer(),
+		delegates:    append([]string{}, ctx.round.Delegates()...),
+		endorsements: append([]*endorsement.Endorsement{}, pendingBlock.Endorsements()...),
+	}, nil
 }
 
 func (ctx *rollDPoSCtx) Broadcast(endorsedMsg interface{}) {
 	ctx.mutex.RLock()
 	defer ctx.mutex.RUnlock()
+	if _, ok := endorsedMsg.(*observedVote); ok {
+		return
+	}
 	ecm, ok := endorsedMsg.(*EndorsedConsensusMessage)
 	if !ok {
 		ctx.loggerWithStats().Error("invalid message type", zap.Any("message", ecm))
@@ -501,9 +1089,14 @@ func (ctx *rollDPoSCtx) Broadcast(endorsedMsg interface{}) {
 		ctx.loggerWithStats().Error("failed to generate protobuf message", zap.Error(err))
 		return
 	}
-	if err := ctx.broadcastHandler(msg); err != nil {
-		ctx.loggerWithStats().Error("fail to broadcast", zap.Error(err))
-	}
+	height := ctx.round.Height()
+	roundNum := ctx.round.Number()
+	ctx.broadcast(msg, "endorsement", ctx.phaseDeadline(ecm), func() bool {
+		ctx.mutex.RLock()
+		defer ctx.mutex.RUnlock()
+		// endorsements of a previous round are useless
+		return ctx.round.Height() != height || ctx.round.Number() != roundNum
+	})
 }
 
 func (ctx *rollDPoSCtx) IsStaleEvent(evt *consensusfsm.ConsensusEvent) bool {
@@ -534,18 +1127,105 @@ func (ctx *rollDPoSCtx) Height() uint64 {
 	return ctx.round.Height()
 }
 
+// Activate switches the node between active and standby. A standby node may be rounds behind, so the round is
+// re-derived from the current tip right away on activation, instead of waiting for the next scheduled prepare.
 func (ctx *rollDPoSCtx) Activate(active bool) {
 	ctx.mutex.Lock()
 	defer ctx.mutex.Unlock()
 
+	wasActive := ctx.active
 	ctx.active = active
+	if !active || wasActive {
+		return
+	}
+	if err := ctx.prepare(); err != nil {
+		ctx.logger().Warn("failed to fast-forward the round on activation", zap.Error(err))
+	}
+}
+
+// PublicKey returns the public key of the delegate key in use
+func (ctx *rollDPoSCtx) PublicKey() crypto.PublicKey {
+	ctx.mutex.RLock()
+	defer ctx.mutex.RUnlock()
+	if ctx.priKey == nil {
+		return nil
+	}
+	return ctx.priKey.PublicKey()
+}
+
+// Sign signs the hash with the delegate key in use
+func (ctx *rollDPoSCtx) Sign(hash []byte) ([]byte, error) {
+	ctx.mutex.RLock()
+	defer ctx.mutex.RUnlock()
+	if ctx.priKey == nil {
+		return nil, errors.New("no private key to sign with")
+	}
+	return ctx.priKey.Sign(hash)
+}
+
+// Delegates returns a copy of the delegates of the current round
+func (ctx *rollDPoSCtx) Delegates() []string {
+	ctx.mutex.RLock()
+	defer ctx.mutex.RUnlock()
+
+	return append([]string{}, ctx.round.Delegates()...)
 }
 
 func (ctx *rollDPoSCtx) Active() bool {
 	ctx.mutex.RLock()
 	defer ctx.mutex.RUnlock()
 
-	return ctx.active
+	return ctx.active && !ctx.isShuttingDown()
+}
+
+// ShutDown deactivates the node for the rounds to come for good, without waiting for the block being committed
+func (ctx *rollDPoSCtx) ShutDown() {
+	atomic.StoreInt32(&ctx.shuttingDown, 1)
+}
+
+func (ctx *rollDPoSCtx) isShuttingDown() bool {
+	return atomic.LoadInt32(&ctx.shuttingDown) == 1
+}
+
+// Health returns the health status of an active node, derived from how far the round lags behind the chain tip and
+// how long ago the last block was committed
+func (ctx *rollDPoSCtx) Health() HealthStatus {
+	ctx.mutex.RLock()
+	defer ctx.mutex.RUnlock()
+
+	tipHeight := ctx.chain.TipHeight()
+	if ctx.round.Height()+ctx.cfg.HealthMaxLag < tipHeight+1 {
+		return Syncing
+	}
+	if ctx.cfg.HealthStallIntervals == 0 {
+		return Participating
+	}
+	lastCommitTime, err := ctx.lastCommitTime()
+	if err != nil {
+		ctx.logger().Warn("Failed to get the tip block footer.", zap.Error(err))
+		return Stalled
+	}
+	maxInterval := time.Duration(ctx.cfg.HealthStallIntervals) * ctx.roundCalc.blockInterval
+	if ctx.cfg.SuppressEmptyBlock {
+		maxInterval += ctx.cfg.MaxIdleInterval
+	}
+	if ctx.clock.Now().Sub(lastCommitTime) > maxInterval {
+		return Stalled
+	}
+	return Participating
+}
+
+// lastCommitTime returns the commit time of the tip block, or the genesis time if no block has been committed
+func (ctx *rollDPoSCtx) lastCommitTime() (time.Time, error) {
+	tipHeight := ctx.chain.TipHeight()
+	if tipHeight == 0 {
+		return time.Unix(ctx.chain.GenesisTimestamp(), 0), nil
+	}
+	footer, err := ctx.chain.BlockFooterByHeight(tipHeight)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return footer.CommitTime(), nil
 }
 
 ///////////////////////////////////////////
@@ -553,20 +1233,229 @@ func (ctx *rollDPoSCtx) Active() bool {
 ///////////////////////////////////////////
 
 func (ctx *rollDPoSCtx) mintNewBlock() (*EndorsedConsensusMessage, error) {
-	actionMap := ctx.actPool.PendingActionMap()
-	ctx.logger().Debug("Pick actions from the action pool.", zap.Int("action", len(actionMap)))
-	blk, err := ctx.chain.MintNewBlock(
-		actionMap,
-		ctx.round.StartTime(),
+	var (
+		blk *block.Block
+		err error
 	)
+	if minter, ok := ctx.minter.(ActionIteratorMinter); ok {
+		iter := newCappedActionIterator(
+			ctx.actPool.PendingActionIterator(),
+			ctx.cfg.ProposalMaxActions,
+			ctx.proposalMaxGas(),
+			ctx.proposalMaxActionBytes(),
+		)
+		blk, err = minter.MintWithActionIterator(iter, ctx.round.StartTime())
+	} else {
+		actionMap := capProposalActions(
+			ctx.actPool.PendingActionMap(),
+			ctx.cfg.ProposalMaxActions,
+			ctx.proposalMaxGas(),
+			ctx.proposalMaxActionBytes(),
+		)
+		ctx.logger().Debug("Pick actions from the action pool.", zap.Int("action", len(actionMap)))
+		blk, err = ctx.minter.Mint(actionMap, ctx.round.StartTime())
+	}
 	if err != nil {
 		return nil, err
 	}
+	if maxSize := ctx.cfg.ProposalMaxSize; maxSize != 0 {
+		if size := blk.SerializedSize(); uint64(size) > maxSize {
+			return nil, errors.Wrapf(ErrProposalTooLarge, "block of %d bytes, the max is %d", size, maxSize)
+		}
+	}
 	var proofOfUnlock []*endorsement.Endorsement
 	if ctx.round.IsUnlocked() {
 		proofOfUnlock = ctx.round.ProofOfLock()
 	}
-	return ctx.endorseBlockProposal(newBlockProposal(blk, proofOfUnlock))
+	if blk.Height() < ctx.cfg.UnlockProofHeight || len(proofOfUnlock) == 0 {
+		return ctx.endorseBlockProposal(newBlockProposal(blk, proofOfUnlock))
+	}
+	return ctx.endorseBlockProposal(newBlockProposalWithProof(blk, proofOfUnlock, unlockProof))
+}
+
+// proposalMaxGas returns the soft cap of the gas of a proposal, which never exceeds the block gas limit
+func (ctx *rollDPoSCtx) proposalMaxGas() uint64 {
+	maxGas := ctx.cfg.ProposalMaxGas
+	if ctx.blockGasLimit != 0 && (maxGas == 0 || maxGas > ctx.blockGasLimit) {
+		maxGas = ctx.blockGasLimit
+	}
+	return maxGas
+}
+
+// proposalMaxActionBytes returns the cap of the serialized size of the actions picked from the action pool for a
+// proposal, which leaves room for the header, the footer and the system actions of the block out of ProposalMaxSize
+func (ctx *rollDPoSCtx) proposalMaxActionBytes() uint64 {
+	maxSize := ctx.cfg.ProposalMaxSize
+	switch {
+	case maxSize == 0:
+		return 0
+	case maxSize <= blockSizeSlack:
+		// no room for any action, which is still a cap
+		return 1
+	default:
+		return maxSize - blockSizeSlack
+	}
+}
+
+// capProposalActions picks the actions to propose in the order the chain runs them, until maxActions actions are
+// picked, skipping the remaining actions of an account once the gas limit or the serialized size of its next action
+// exceeds the gas left of maxGas or the bytes left of maxBytes, such that the nonces of each account stay
+// consecutive. A cap of 0 means no cap.
+func capProposalActions(
+	actionMap map[string][]action.SealedEnvelope,
+	maxActions uint64,
+	maxGas uint64,
+	maxBytes uint64,
+) map[string][]action.SealedEnvelope {
+	if maxActions == 0 && maxGas == 0 && maxBytes == 0 {
+		return actionMap
+	}
+	// the iterator consumes the map it iterates
+	pending := make(map[string][]action.SealedEnvelope, len(actionMap))
+	for sender, acts := range actionMap {
+		pending[sender] = acts
+	}
+	picked := make(map[string][]action.SealedEnvelope)
+	iter := newCappedActionIterator(actioniterator.NewActionIterator(pending), maxActions, maxGas, maxBytes)
+	for {
+		act, ok := iter.Next()
+		if !ok {
+			break
+		}
+		sender, err := address.FromBytes(act.SrcPubkey().Hash())
+		if err != nil {
+			iter.PopAccount()
+			continue
+		}
+		picked[sender.String()] = append(picked[sender.String()], act)
+	}
+	return picked
+}
+
+// cappedActionIterator stops once maxActions actions are picked, and skips the remaining actions of an account once
+// the gas limit or the serialized size of its next action exceeds the gas left of maxGas or the bytes left of
+// maxBytes. A cap of 0 means no cap.
+type cappedActionIterator struct {
+	iter       actioniterator.ActionIterator
+	maxActions uint64
+	maxGas     uint64
+	maxBytes   uint64
+	numActions uint64
+	gas        uint64
+	bytes      uint64
+}
+
+func newCappedActionIterator(
+	iter actioniterator.ActionIterator,
+	maxActions uint64,
+	maxGas uint64,
+	maxBytes uint64,
+) actioniterator.ActionIterator {
+	if maxActions == 0 && maxGas == 0 && maxBytes == 0 {
+		return iter
+	}
+	return &cappedActionIterator{iter: iter, maxActions: maxActions, maxGas: maxGas, maxBytes: maxBytes}
+}
+
+func (it *cappedActionIterator) Next() (action.SealedEnvelope, bool) {
+	for it.maxActions == 0 || it.numActions < it.maxActions {
+		act, ok := it.iter.Next()
+		if !ok {
+			break
+		}
+		if it.maxGas != 0 && act.GasLimit() > it.maxGas-it.gas {
+			it.iter.PopAccount()
+			continue
+		}
+		var size uint64
+		if it.maxBytes != 0 {
+			if size = serializedActionSize(act); size > it.maxBytes-it.bytes {
+				it.iter.PopAccount()
+				continue
+			}
+		}
+		it.numActions++
+		it.gas += act.GasLimit()
+		it.bytes += size
+		return act, true
+	}
+	return action.SealedEnvelope{}, false
+}
+
+func (it *cappedActionIterator) PopAccount() { it.iter.PopAccount() }
+
+// serializedActionSize returns the number of the bytes an action adds to the serialized block, i.e., the serialized
+// action prefixed by its field key and its length
+func serializedActionSize(act action.SealedEnvelope) uint64 {
+	size := proto.Size(act.Proto())
+	return uint64(1 + proto.SizeVarint(uint64(size)) + size)
+}
+
+// suppressEmptyBlock returns true if empty blocks are suppressed, there is no pending action, and the max idle
+// interval since the last block hasn't elapsed yet. The round then advances without a proposal.
+func (ctx *rollDPoSCtx) suppressEmptyBlock() bool {
+	if !ctx.cfg.SuppressEmptyBlock || len(ctx.actPool.PendingActionMap()) != 0 {
+		return false
+	}
+	lastBlockTime := time.Unix(ctx.chain.GenesisTimestamp(), 0)
+	if height := ctx.round.Height(); height > 1 {
+		footer, err := ctx.chain.BlockFooterByHeight(height - 1)
+		if err != nil {
+			ctx.logger().Warn("Failed to get the last block footer.", zap.Error(err))
+			return false
+		}
+		lastBlockTime = footer.CommitTime()
+	}
+	if ctx.round.StartTime().Sub(lastBlockTime) >= ctx.cfg.MaxIdleInterval {
+		return false
+	}
+	ctx.logger().Debug("Skip proposing an empty block.", zap.Time("lastBlockTime", lastBlockTime))
+	return true
+}
+
+// checkParentTime returns ErrBlockBeforeParent unless a block of the height at the timestamp is strictly after its
+// parent block, which is the genesis block of the genesis timestamp for the first block
+func (ctx *rollDPoSCtx) checkParentTime(height uint64, ts time.Time) error {
+	parentTime := time.Unix(ctx.chain.GenesisTimestamp(), 0)
+	if height > 1 {
+		header, err := ctx.chain.BlockHeaderByHeight(height - 1)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the header of block %d", height-1)
+		}
+		parentTime = header.Timestamp()
+	}
+	if !ts.After(parentTime) {
+		return reject(ReasonBlockBeforeParent, errors.Wrapf(
+			ErrBlockBeforeParent,
+			"block %d at %s is not after its parent block at %s",
+			height,
+			ts,
+			parentTime,
+		))
+	}
+	return nil
+}
+
+// checkMinBlockInterval returns ErrBlockTooEarly if a block of the height at the timestamp is within the min block
+// interval after the previous block
+func (ctx *rollDPoSCtx) checkMinBlockInterval(height uint64, ts time.Time) error {
+	if ctx.cfg.MinBlockInterval <= 0 || height <= 1 {
+		return nil
+	}
+	header, err := ctx.chain.BlockHeaderByHeight(height - 1)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the header of block %d", height-1)
+	}
+	if interval := ts.Sub(header.Timestamp()); interval < ctx.cfg.MinBlockInterval {
+		return errors.Wrapf(
+			ErrBlockTooEarly,
+			"block %d is %s after the previous block, less than %s",
+			height,
+			interval,
+			ctx.cfg.MinBlockInterval,
+		)
+	}
+	return nil
 }
 
 func (ctx *rollDPoSCtx) endorseBlockProposal(proposal *blockProposal) (*EndorsedConsensusMessage, error) {
@@ -574,9 +1463,191 @@ func (ctx *rollDPoSCtx) endorseBlockProposal(proposal *blockProposal) (*Endorsed
 	if err != nil {
 		return nil, err
 	}
+	blkHash := proposal.block.HashBlock()
+	ctx.recordVote(PROPOSAL, blkHash[:])
 	return NewEndorsedConsensusMessage(proposal.block.Height(), proposal, en), nil
 }
 
+// broadcastRetry is a failed broadcast to retry
+type broadcastRetry struct {
+	msg      proto.Message
+	msgType  string
+	deadline time.Time
+	isStale  func() bool
+	logger   *zap.Logger
+	// attempt is the number of the attempts made, and interval is the backoff before the next one
+	attempt  int
+	interval time.Duration
+	err      error
+}
+
+// broadcast sends the message to the network. If it fails, the broadcast is retried on the retry queue with
+// exponential backoff, until it succeeds, the attempts are used up, the next retry would pass the deadline, or isStale
+// returns true.
+func (ctx *rollDPoSCtx) broadcast(msg proto.Message, msgType string, deadline time.Time, isStale func() bool) {
+	err := ctx.broadcastHandler(msg)
+	if err == nil {
+		return
+	}
+	logger := ctx.logger().With(zap.String("type", msgType))
+	logger.Warn("fail to broadcast, will retry", zap.Error(err))
+	ctx.scheduleBroadcastRetry(&broadcastRetry{
+		msg:      msg,
+		msgType:  msgType,
+		deadline: deadline,
+		isStale:  isStale,
+		logger:   logger,
+		attempt:  1,
+		interval: ctx.cfg.BroadcastRetryInterval,
+		err:      err,
+	})
+}
+
+// scheduleBroadcastRetry schedules the next attempt of the failed broadcast, unless the attempts are used up or the
+// next attempt would pass the deadline
+func (ctx *rollDPoSCtx) scheduleBroadcastRetry(r *broadcastRetry) {
+	if at := ctx.clock.Now().Add(r.interval); r.attempt < ctx.cfg.BroadcastMaxAttempts && !at.After(r.deadline) {
+		ctx.retryMutex.Lock()
+		defer ctx.retryMutex.Unlock()
+
+		if ctx.retriesCancelled {
+			r.logger.Debug("abort broadcasting on shutdown", zap.Error(r.err))
+			return
+		}
+		id, err := ctx.retryQueue.Schedule(at, nil)
+		if err == nil {
+			ctx.retries[id] = r
+			return
+		}
+		r.logger.Error("fail to schedule the broadcast retry", zap.Error(err))
+	}
+	broadcastFailureMtc.WithLabelValues(r.msgType).Inc()
+	r.logger.Error("fail to broadcast", zap.Error(r.err))
+}
+
+// retryBroadcast makes the next attempt of a failed broadcast once its task is due
+func (ctx *rollDPoSCtx) retryBroadcast(id uint64, _ []byte) {
+	ctx.retryMutex.Lock()
+	r, ok := ctx.retries[id]
+	delete(ctx.retries, id)
+	ctx.retryMutex.Unlock()
+
+	// the task is delivered at least once, and a retry made already is gone
+	if !ok {
+		return
+	}
+	if r.isStale != nil && r.isStale() {
+		r.logger.Debug("abort broadcasting stale message")
+		return
+	}
+	if r.err = ctx.broadcastHandler(r.msg); r.err == nil {
+		return
+	}
+	r.attempt++
+	r.logger.Warn("fail to broadcast, will retry", zap.Int("attempt", r.attempt), zap.Error(r.err))
+	r.interval *= 2
+	ctx.scheduleBroadcastRetry(r)
+}
+
+// phaseDeadline returns the end of the phase in which the endorsed message is useful
+func (ctx *rollDPoSCtx) phaseDeadline(ecm *EndorsedConsensusMessage) time.Time {
+	if vote, ok := ecm.Document().(*ConsensusVote); ok {
+		return ctx.deadlines.of(vote.Topic())
+	}
+	return ctx.deadlines.acceptBlock
+}
+
+// applyRotatedKey switches to the rotated key if any, along with the key the chain signs the minted blocks with
+func (ctx *rollDPoSCtx) applyRotatedKey() error {
+	if ctx.rotatedKey == nil {
+		return nil
+	}
+	addr, err := address.FromBytes(ctx.rotatedKey.PublicKey().Hash())
+	if err != nil {
+		return errors.Wrap(err, "failed to get the address of the rotated key")
+	}
+	ctx.logger().Info(
+		"rotate the delegate key",
+		zap.String("from", ctx.encodedAddr),
+		zap.String("to", addr.String()),
+	)
+	ctx.chain.SetProducerPrivateKey(ctx.rotatedKey)
+	ctx.priKey = ctx.rotatedKey
+	ctx.encodedAddr = addr.String()
+	ctx.rotatedKey = nil
+	return nil
+}
+
+// applyReloadedConfig switches to the reloaded config if any, along with the time durations of the consensus FSM
+func (ctx *rollDPoSCtx) applyReloadedConfig() {
+	if ctx.reloaded == nil {
+		return
+	}
+	fsmCfg := ctx.reloaded.FSM
+	fsmCfg.EventChanSize = ctx.cfg.FSM.EventChanSize
+	ctx.cfg.FSM = fsmCfg
+	ctx.adaptiveTTL.SetBase(fsmCfg)
+	ctx.cfg.ToleratedOvertime = ctx.reloaded.ToleratedOvertime
+	// the round calculator is copied, as it is used without holding the mutex
+	roundCalc := *ctx.roundCalc
+	roundCalc.toleratedOvertime = ctx.reloaded.ToleratedOvertime
+	ctx.roundCalc = &roundCalc
+	if ctx.reloadFSM != nil {
+		ctx.reloadFSM(fsmCfg)
+	}
+	ctx.logger().Info(
+		"reload config",
+		zap.Any("fsm", fsmCfg),
+		zap.Duration("toleratedOvertime", roundCalc.toleratedOvertime),
+	)
+	ctx.reloaded = nil
+}
+
+// applyAdaptiveTTL adapts AcceptBlockTTL to the latency of the block proposals observed in the recent rounds, along
+// with the time durations of the consensus FSM
+func (ctx *rollDPoSCtx) applyAdaptiveTTL() {
+	if ctx.adaptiveTTL == nil {
+		return
+	}
+	fsmCfg := ctx.adaptiveTTL.Adapt(ctx.cfg.FSM.AcceptBlockTTL)
+	fsmCfg.EventChanSize = ctx.cfg.FSM.EventChanSize
+	if fsmCfg == ctx.cfg.FSM {
+		return
+	}
+	ctx.cfg.FSM = fsmCfg
+	if ctx.reloadFSM != nil {
+		ctx.reloadFSM(fsmCfg)
+	}
+	ctx.logger().Debug(
+		"adapt accept block ttl",
+		zap.Duration("acceptBlockTTL", fsmCfg.AcceptBlockTTL),
+		zap.Duration("acceptProposalEndorsementTTL", fsmCfg.AcceptProposalEndorsementTTL),
+		zap.Duration("acceptLockEndorsementTTL", fsmCfg.AcceptLockEndorsementTTL),
+		zap.Duration("commitTTL", fsmCfg.CommitTTL),
+	)
+}
+
+// validateTTLs makes sure that a round fits in a block interval
+func validateTTLs(cfg consensusfsm.Config, blockInterval time.Duration) error {
+	if cfg.AcceptBlockTTL < 0 || cfg.AcceptProposalEndorsementTTL < 0 ||
+		cfg.AcceptLockEndorsementTTL < 0 || cfg.CommitTTL < 0 || cfg.ProposalBufferTTL < 0 {
+		return errors.New("invalid ttl config, ttls should not be negative")
+	}
+	if ttl := cfg.AcceptBlockTTL + cfg.AcceptProposalEndorsementTTL + cfg.AcceptLockEndorsementTTL + cfg.CommitTTL; ttl > blockInterval {
+		return errors.Errorf(
+			"invalid ttl config, the sum of ttls %s (acceptBlockTTL %s, acceptProposalEndorsementTTL %s, "+
+				"acceptLockEndorsementTTL %s, commitTTL %s) exceeds block interval %s",
+			ttl,
+			cfg.AcceptBlockTTL,
+			cfg.AcceptProposalEndorsementTTL,
+			cfg.AcceptLockEndorsementTTL,
+			cfg.CommitTTL,
+			blockInterval,
+		)
+	}
+	return nil
+}
+
 func (ctx *rollDPoSCtx) logger() *zap.Logger {
 	return ctx.round.Log(log.Logger("consensus"))
 }
@@ -587,6 +1658,9 @@ func (ctx *rollDPoSCtx) newConsensusEvent(
 ) *consensusfsm.ConsensusEvent {
 	switch ed := data.(type) {
 	case *EndorsedConsensusMessage:
+		if ed.receivedAt.IsZero() {
+			ed.receivedAt = ctx.clock.Now()
+		}
 		roundNum, _, err := ctx.roundCalc.RoundInfo(ed.Height(), ed.Endorsement().Timestamp())
 		if err != nil {
 			ctx.logger().Error(
@@ -599,6 +1673,17 @@ func (ctx *rollDPoSCtx) newConsensusEvent(
 			)
 			return nil
 		}
+		if ctx.isFutureRound(ed.Height(), roundNum) {
+			futureRoundEventMtc.WithLabelValues().Inc()
+			ctx.logger().Warn(
+				"Dropped a consensus event of a round too far ahead.",
+				zap.String("eventType", string(eventType)),
+				zap.Uint32("eventRound", roundNum),
+				zap.Uint32("maxFutureRounds", ctx.cfg.MaxFutureRounds),
+				zap.Time("timestamp", ed.Endorsement().Timestamp()),
+			)
+			return nil
+		}
 		return consensusfsm.NewConsensusEvent(
 			eventType,
 			data,
@@ -617,7 +1702,27 @@ func (ctx *rollDPoSCtx) newConsensusEvent(
 	}
 }
 
+// isFutureRound tells whether a message of the height and the round is timestamped more than the max future rounds
+// ahead of the current time, e.g., by a peer of a skewed clock, which would take the FSM to a wild round. The round is
+// compared against the clock rather than the round of the FSM, such that a node lagging behind isn't cut off.
+func (ctx *rollDPoSCtx) isFutureRound(height uint64, roundNum uint32) bool {
+	maxRounds := ctx.cfg.MaxFutureRounds
+	if maxRounds == 0 || height != ctx.round.Height() {
+		return false
+	}
+	currentRound, _, err := ctx.roundCalc.RoundInfo(height, ctx.clock.Now())
+	if err != nil {
+		return false
+	}
+	return roundNum > currentRound && roundNum-currentRound > maxRounds
+}
+
+// loggerWithStats returns the logger along with the endorsement stats of the round if verbose logging is on, which is
+// used on the paths of high frequency
 func (ctx *rollDPoSCtx) loggerWithStats() *zap.Logger {
+	if !ctx.cfg.VerboseLogging {
+		return ctx.logger()
+	}
 	return ctx.round.LogWithStats(log.Logger("consensus"))
 }
 
@@ -625,29 +1730,71 @@ func (ctx *rollDPoSCtx) verifyVote(
 	msg interface{},
 	topics []ConsensusVoteTopic,
 ) ([]byte, error) {
+	if observed, ok := msg.(*observedVote); ok {
+		return ctx.verifyObservedVote(observed, topics)
+	}
 	consensusMsg, ok := msg.(*EndorsedConsensusMessage)
 	if !ok {
-		return nil, errors.New("invalid msg")
+		err := reject(ReasonInvalidMessage, errors.New("invalid msg"))
+		ctx.summary.Reject(err)
+		return nil, err
 	}
 	vote, ok := consensusMsg.Document().(*ConsensusVote)
 	if !ok {
-		return nil, errors.New("invalid msg")
+		err := reject(ReasonInvalidMessage, errors.New("invalid msg"))
+		ctx.summary.Reject(err)
+		return nil, err
 	}
 	blkHash := vote.BlockHash()
 	endorsement := consensusMsg.Endorsement()
-	if err := ctx.round.AddVoteEndorsement(vote, endorsement); err != nil {
+	// a re-broadcast endorsement is dropped before verifying it again
+	if ctx.seen.Seen(vote, endorsement) {
+		duplicateEndorsementMtc.WithLabelValues().Inc()
+		return blkHash, ErrDuplicateEndorsement
+	}
+	addVoteEndorsement := ctx.round.AddVoteEndorsement
+	if consensusMsg.verified {
+		addVoteEndorsement = ctx.round.addVerifiedVoteEndorsement
+	}
+	if err := addVoteEndorsement(vote, endorsement); err != nil {
+		ctx.summary.Reject(err)
 		return blkHash, err
 	}
+	ctx.seen.Add(vote, endorsement)
+	ctx.observeClockSkew(consensusMsg)
+	// the vote may lock or unlock the round
+	ctx.persistRoundState()
+	ctx.summary.Receive(vote.Topic())
 	ctx.loggerWithStats().Debug(
 		"verified consensus vote",
 		log.Hex("block", blkHash),
 		zap.Uint8("topic", uint8(vote.Topic())),
 		zap.String("endorser", endorsement.Endorser().HexString()),
 	)
-	if !ctx.round.EndorsedByMajority(blkHash, topics) {
-		return blkHash, ErrInsufficientEndorsements
+	return blkHash, ctx.round.checkMajority(blkHash, topics)
+}
+
+// observeClockSkew samples the skew of the local clock against the endorser of a valid vote, out of the time the
+// vote is received at rather than verified at, since a vote of a future round waits for the FSM to enter the round
+func (ctx *rollDPoSCtx) observeClockSkew(msg *EndorsedConsensusMessage) {
+	if ctx.clockSkew == nil {
+		return
+	}
+	en := msg.Endorsement()
+	endorserAddr, err := address.FromBytes(en.Endorser().Hash())
+	if err != nil || endorserAddr.String() == ctx.encodedAddr {
+		return
+	}
+	_, roundStart, err := ctx.roundCalc.RoundInfo(msg.Height(), en.Timestamp())
+	if err != nil {
+		return
+	}
+	now := ctx.clock.Now()
+	receivedAt := msg.receivedAt
+	if receivedAt.IsZero() {
+		receivedAt = now
 	}
-	return blkHash, nil
+	ctx.clockSkew.Observe(endorserAddr.String(), endorsementSkew(receivedAt, roundStart, en.Timestamp()), now)
 }
 
 func (ctx *rollDPoSCtx) newEndorsement(
@@ -663,6 +1810,7 @@ func (ctx *rollDPoSCtx) newEndorsement(
 	if err != nil {
 		return nil, err
 	}
+	ctx.recordVote(topic, blkHash)
 
 	return NewEndorsedConsensusMessage(ctx.round.Height(), vote, en), nil
 }
@@ -7,58 +7,88 @@
 package rolldpos
 
 import (
+	"bytes"
+	"context"
+	"math/big"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/facebookgo/clock"
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/iotexproject/go-fsm"
+	"github.com/iotexproject/go-pkgs/crypto"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/iotexproject/iotex-address/address"
 	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 
+	"github.com/iotexproject/iotex-core/action"
 	"github.com/iotexproject/iotex-core/action/protocol/rolldpos"
+	"github.com/iotexproject/iotex-core/actpool/actioniterator"
+	"github.com/iotexproject/iotex-core/blockchain"
 	"github.com/iotexproject/iotex-core/blockchain/block"
 	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/consensus/consensusfsm"
+	"github.com/iotexproject/iotex-core/db"
 	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/state"
 	"github.com/iotexproject/iotex-core/test/identityset"
+	"github.com/iotexproject/iotex-core/test/mock/mock_actpool"
+	"github.com/iotexproject/iotex-core/test/mock/mock_factory"
+	"github.com/iotexproject/iotex-core/testutil"
 )
 
 func TestRollDPoSCtx(t *testing.T) {
 	require := require.New(t)
 	cfg := config.Default.Consensus.RollDPoS
-
-	// case 1:panic because of chain is nil
-	require.Panics(func() {
-		newRollDPoSCtx(cfg, true, time.Second*10, time.Second, true, nil, nil, nil, nil, nil, "", nil, nil)
-	}, "chain is nil")
-
-	// case 2:panic because of rp is nil
 	b, _ := makeChain(t)
-	require.Panics(func() {
-		newRollDPoSCtx(cfg, true, time.Second*10, time.Second, true, b, nil, nil, nil, nil, "", nil, nil)
-	}, "rp is nil")
-
-	// case 3:panic because of clock is nil
 	rp := rolldpos.NewProtocol(
 		config.Default.Genesis.NumCandidateDelegates,
 		config.Default.Genesis.NumDelegates,
 		config.Default.Genesis.NumSubEpochs,
 	)
-	require.Panics(func() {
-		newRollDPoSCtx(cfg, true, time.Second*10, time.Second, true, b, nil, rp, nil, nil, "", nil, nil)
-	}, "clock is nil")
-
-	// case 4:panic because of fsm time bigger than block interval
 	c := clock.New()
-	cfg.FSM.AcceptBlockTTL = time.Second * 10
-	cfg.FSM.AcceptProposalEndorsementTTL = time.Second
-	cfg.FSM.AcceptLockEndorsementTTL = time.Second
-	cfg.FSM.CommitTTL = time.Second
-	require.Panics(func() {
-		newRollDPoSCtx(cfg, true, time.Second*10, time.Second, true, b, nil, rp, nil, nil, "", nil, c)
-	}, "fsm's time is bigger than block interval")
+	invalidTTL := cfg
+	invalidTTL.FSM.AcceptBlockTTL = time.Second * 10
+	invalidTTL.FSM.AcceptProposalEndorsementTTL = time.Second
+	invalidTTL.FSM.AcceptLockEndorsementTTL = time.Second
+	invalidTTL.FSM.CommitTTL = time.Second
+	unsafeThreshold := cfg
+	unsafeThreshold.EndorsementThresholdHeight = 1
+	unsafeThreshold.EndorsementThreshold = config.EndorsementThreshold{Numerator: 3, Denominator: 5}
+
+	for _, test := range []struct {
+		name  string
+		cfg   config.RollDPoS
+		chain blockchain.Blockchain
+		rp    *rolldpos.Protocol
+		clock clock.Clock
+		err   string
+	}{
+		{"nil chain", cfg, nil, rp, c, "blockchain is nil"},
+		{"nil rp", cfg, b, nil, c, "rolldpos protocol is nil"},
+		{"nil clock", cfg, b, rp, nil, "clock is nil"},
+		{"ttls exceed block interval", invalidTTL, b, rp, c, "invalid ttl config"},
+		{"unsafe endorsement threshold", unsafeThreshold, b, rp, c, "unsafe"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rctx, err := newRollDPoSCtx(test.cfg, true, time.Second*10, time.Second, true, test.chain, nil, test.rp, nil, nil, "", nil, test.clock)
+			require.Error(err)
+			require.Contains(err.Error(), test.err)
+			require.Nil(rctx)
+		})
+	}
 
-	// case 5:normal
-	rctx := newRollDPoSCtx(cfg, true, time.Second*20, time.Second, true, b, nil, rp, nil, nil, "", nil, c)
+	rctx, err := newRollDPoSCtx(cfg, true, time.Second*20, time.Second, true, b, nil, rp, nil, nil, "", nil, c)
+	require.NoError(err)
 	require.NotNil(rctx)
 }
 
@@ -72,7 +102,8 @@ func TestCheckVoteEndorser(t *testing.T) {
 		config.Default.Genesis.NumSubEpochs,
 	)
 	c := clock.New()
-	rctx := newRollDPoSCtx(cfg, true, time.Second*20, time.Second, true, b, nil, rp, nil, nil, "", nil, c)
+	rctx, err := newRollDPoSCtx(cfg, true, time.Second*20, time.Second, true, b, nil, rp, nil, nil, "", nil, c)
+	require.NoError(err)
 	require.NotNil(rctx)
 
 	// case 1:endorser nil caused panic
@@ -87,12 +118,85 @@ func TestCheckVoteEndorser(t *testing.T) {
 	require.NoError(rctx.CheckVoteEndorser(1, nil, en))
 }
 
+func TestCheckVoteEndorserSharesCandidatesFetch(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{Address: identityset.Address(i).String()})
+	}
+	var (
+		calls    int32
+		release  chan struct{}
+		fetchErr error
+	)
+	// the fetch is slow, which blocks until released
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		atomic.AddInt32(&calls, 1)
+		if release != nil {
+			<-release
+		}
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		return candidates, nil
+	}
+	rctx, err := newRollDPoSCtx(
+		cfg, true, time.Second*20, time.Second, true, b, nil, rp, nil, candidatesByHeight, "", nil, clock.New(),
+	)
+	require.NoError(err)
+
+	// checkVotes checks the votes of 100 delegates concurrently, once all of them wait for the fetch
+	checkVotes := func() []error {
+		atomic.StoreInt32(&calls, 0)
+		release = make(chan struct{})
+		errs := make([]error, 100)
+		var started, done sync.WaitGroup
+		for i := range errs {
+			started.Add(1)
+			done.Add(1)
+			go func(i int) {
+				defer done.Done()
+				en := endorsement.NewEndorsement(time.Now(), identityset.PrivateKey(i%len(candidates)).PublicKey(), nil)
+				started.Done()
+				errs[i] = rctx.CheckVoteEndorser(1, nil, en)
+			}(i)
+		}
+		started.Wait()
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		done.Wait()
+		return errs
+	}
+	shared := promtestutil.ToFloat64(sharedCandidatesFetchMtc.WithLabelValues())
+	for _, err := range checkVotes() {
+		require.NoError(err)
+	}
+	require.Equal(int32(1), atomic.LoadInt32(&calls))
+	require.Equal(shared+99, promtestutil.ToFloat64(sharedCandidatesFetchMtc.WithLabelValues()))
+
+	// a failure of the shared fetch fails every waiter
+	fetchErr = errors.New("state is unavailable")
+	for _, err := range checkVotes() {
+		require.Error(err)
+		require.Equal(ReasonNotDelegate, RejectionReasonOf(err))
+	}
+	require.Equal(int32(1), atomic.LoadInt32(&calls))
+	release = nil
+	_, err = rctx.roundCalc.candidatesByHeightFunc(1)
+	require.Equal(fetchErr, errors.Cause(err))
+}
+
 func TestCheckBlockProposer(t *testing.T) {
 	require := require.New(t)
 	cfg := config.Default.Consensus.RollDPoS
+	// proposals in the old format are verified below the switch height
+	cfg.UnlockProofHeight = 22
 	b, rp := makeChain(t)
 	c := clock.New()
-	rctx := newRollDPoSCtx(cfg, true, time.Second*20, time.Second, true, b, nil, rp, nil, nil, "", nil, c)
+	rctx, err := newRollDPoSCtx(cfg, true, time.Second*20, time.Second, true, b, nil, rp, nil, nil, "", nil, c)
+	require.NoError(err)
 	require.NotNil(rctx)
 	block := getBlockforctx(t, 0, false)
 	en := endorsement.NewEndorsement(time.Unix(1562382392, 0), identityset.PrivateKey(10).PublicKey(), nil)
@@ -135,7 +239,7 @@ func TestCheckBlockProposer(t *testing.T) {
 	block = getBlockforctx(t, 5, true)
 	hash := block.HashBlock()
 	vote := NewConsensusVote(hash[:], COMMIT)
-	en2, err := endorsement.Endorse(identityset.PrivateKey(7), vote, time.Unix(1562382592, 0))
+	en2, err = endorsement.Endorse(identityset.PrivateKey(7), vote, time.Unix(1562382592, 0))
 	require.NoError(err)
 	bp = newBlockProposal(&block, []*endorsement.Endorsement{en2})
 	require.Error(rctx.CheckBlockProposer(21, bp, en2))
@@ -144,6 +248,1657 @@ func TestCheckBlockProposer(t *testing.T) {
 	block = getBlockforctx(t, 5, true)
 	bp = newBlockProposal(&block, []*endorsement.Endorsement{en})
 	require.NoError(rctx.CheckBlockProposer(21, bp, en))
+
+	// case 10:aggregated proof of lock, which is verified but insufficient as the test delegates contain duplicates
+	committee, err := rctx.roundCalc.Delegates(21)
+	require.NoError(err)
+	keys := map[string]crypto.PrivateKey{}
+	for i := 0; i < identityset.Size(); i++ {
+		keys[identityset.Address(i).String()] = identityset.PrivateKey(i)
+	}
+	endorse := func(vote *ConsensusVote) *endorsement.Aggregate {
+		pol := []*endorsement.Endorsement{}
+		endorsed := map[string]bool{}
+		for _, addr := range committee {
+			if endorsed[addr] {
+				continue
+			}
+			endorsed[addr] = true
+			en, err := endorsement.Endorse(keys[addr], vote, time.Unix(1562382592, 0))
+			require.NoError(err)
+			pol = append(pol, en)
+		}
+		agg, err := endorsement.NewAggregate(committee, pol)
+		require.NoError(err)
+		return agg
+	}
+	bp = newCompactBlockProposal(&block, endorse(vote))
+	// the aggregated form isn't accepted until the configured height
+	err = rctx.CheckBlockProposer(21, bp, en2)
+	require.Error(err)
+	require.NotEqual(ErrInsufficientEndorsements, errors.Cause(err))
+	rctx.cfg.AggregateProofHeight = 22
+	require.Error(rctx.CheckBlockProposer(21, bp, en2))
+	rctx.cfg.AggregateProofHeight = 21
+	// nor below the height from which proposals carry the header
+	require.Error(rctx.CheckBlockProposer(21, bp, en2))
+	rctx.cfg.UnlockProofHeight = 21
+	err = rctx.CheckBlockProposer(21, bp, en2)
+	require.Equal(ErrInsufficientEndorsements, errors.Cause(err))
+
+	// case 11:aggregated proof of lock on another block
+	bp = newCompactBlockProposal(&block, endorse(NewConsensusVote([]byte("another block"), COMMIT)))
+	err = rctx.CheckBlockProposer(21, bp, en2)
+	require.Error(err)
+	require.NotEqual(ErrInsufficientEndorsements, errors.Cause(err))
+	rctx.cfg.UnlockProofHeight = 22
+
+	// case 12:oversized proof of lock is rejected before verifying any endorsement, which would fail otherwise
+	oversized := make([]*endorsement.Endorsement, 100*rp.NumDelegates())
+	for i := range oversized {
+		oversized[i] = en
+	}
+	bp = newBlockProposal(&block, oversized)
+	err = rctx.CheckBlockProposer(21, bp, en2)
+	require.Equal(ErrTooManyEndorsements, errors.Cause(err))
+
+	// case 13:proof of lock with the same endorser appearing twice
+	en3, err := endorsement.Endorse(identityset.PrivateKey(7), NewConsensusVote(hash[:], PROPOSAL), time.Unix(1562382592, 0))
+	require.NoError(err)
+	bp = newBlockProposal(&block, []*endorsement.Endorsement{en2, en3})
+	err = rctx.CheckBlockProposer(21, bp, en2)
+	require.Equal(ErrDuplicateEndorser, errors.Cause(err))
+}
+
+func TestCheckBlockProposerParentTime(t *testing.T) {
+	require := require.New(t)
+	b, rp := makeChain(t)
+	rctx, err := newRollDPoSCtx(
+		config.Default.Consensus.RollDPoS, true, time.Second*20, time.Second, true, b, nil, rp, nil, nil, "", nil, clock.New(),
+	)
+	require.NoError(err)
+	keys := map[string]crypto.PrivateKey{}
+	for i := 0; i < identityset.Size(); i++ {
+		keys[identityset.Address(i).String()] = identityset.PrivateKey(i)
+	}
+	// check checks the proposal of a block of the height at the timestamp made by the proposer of the time, or by any
+	// delegate if there is no proposer
+	check := func(height uint64, ts time.Time) error {
+		key, ok := keys[rctx.roundCalc.Proposer(height, ts)]
+		if !ok {
+			key = identityset.PrivateKey(0)
+		}
+		blk, err := block.NewTestingBuilder().SetHeight(height).SetTimeStamp(ts).SignAndBuild(key)
+		require.NoError(err)
+		en := endorsement.NewEndorsement(ts, key.PublicKey(), nil)
+		return rctx.CheckBlockProposer(height, newBlockProposal(&blk, nil), en)
+	}
+
+	parent, err := b.BlockHeaderByHeight(20)
+	require.NoError(err)
+	for _, ts := range []time.Time{parent.Timestamp(), parent.Timestamp().Add(-10 * time.Second)} {
+		err = check(21, ts)
+		require.Equal(ErrBlockBeforeParent, errors.Cause(err))
+		require.Equal(ReasonBlockBeforeParent, RejectionReasonOf(err))
+		require.True(RejectionReasonOf(err).BlamesSender())
+	}
+	require.NoError(check(21, parent.Timestamp().Add(time.Second)))
+
+	// the parent of the first block is the genesis block
+	genesisTime := time.Unix(b.GenesisTimestamp(), 0)
+	require.Equal(ErrBlockBeforeParent, errors.Cause(check(1, genesisTime)))
+	require.NoError(check(1, genesisTime.Add(time.Second)))
+}
+
+func TestLockedValidatorReceivesNewProposal(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	blockInterval := 20 * time.Second
+	// distinct delegates, such that a majority is reachable
+	keys := map[string]crypto.PrivateKey{}
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		keys[identityset.Address(i).String()] = identityset.PrivateKey(i)
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	// move to a later round of the next height
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	genesis := time.Unix(b.GenesisTimestamp(), 0)
+	lastBlockTime := genesis.Add(footer.CommitTime().Sub(genesis) / blockInterval * blockInterval)
+	c := clock.NewMock()
+	c.Add(lastBlockTime.Add(50 * time.Second).Sub(c.Now()))
+	rctx, err := newRollDPoSCtx(
+		cfg, true, blockInterval, time.Second, true, b, nil, rp, nil, candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+	require.NotZero(rctx.round.Number())
+	height := rctx.round.Height()
+	delegates := rctx.round.Delegates()
+	rctx.encodedAddr = delegates[0]
+	rctx.priKey = keys[delegates[0]]
+	lastRound := rctx.round.StartTime().Add(-blockInterval + 5*time.Second)
+	proposer := rctx.round.Proposer()
+
+	newBlock := func(producer string, ts time.Time) *block.Block {
+		blk, err := block.NewTestingBuilder().
+			SetHeight(height).
+			SetTimeStamp(ts).
+			SignAndBuild(keys[producer])
+		require.NoError(err)
+		blk.WorkingSet = mock_factory.NewMockWorkingSet(ctrl)
+		return &blk
+	}
+	endorse := func(vote *ConsensusVote, endorsers []string) []*endorsement.Endorsement {
+		ens := []*endorsement.Endorsement{}
+		for _, addr := range endorsers {
+			en, err := endorsement.Endorse(keys[addr], vote, lastRound.Add(time.Second))
+			require.NoError(err)
+			ens = append(ens, en)
+		}
+		return ens
+	}
+	propose := func(bp *blockProposal) *EndorsedConsensusMessage {
+		en, err := endorsement.Endorse(keys[proposer], bp, rctx.round.StartTime())
+		require.NoError(err)
+		return NewEndorsedConsensusMessage(height, bp, en)
+	}
+
+	// the validator gets locked on the block proposed in the last round
+	locked := newBlock(rctx.roundCalc.Proposer(height, lastRound), lastRound)
+	lockedHash := locked.HashBlock()
+	require.NoError(rctx.round.AddBlock(locked))
+	proofOfLock := endorse(NewConsensusVote(lockedHash[:], PROPOSAL), delegates[:17])
+	for _, en := range proofOfLock {
+		require.NoError(rctx.round.AddVoteEndorsement(NewConsensusVote(lockedHash[:], PROPOSAL), en))
+	}
+	require.True(rctx.round.IsLocked())
+
+	// the locked block forwarded with a proof of lock is valid
+	forwarded := propose(newBlockProposalWithProof(locked, proofOfLock, lockProof))
+	require.NoError(rctx.CheckBlockProposer(height, forwarded.Document().(*blockProposal), forwarded.Endorsement()))
+	require.NoError(rctx.checkUnlock(forwarded.Document().(*blockProposal), rctx.round.StartTime()))
+	// a forwarded block has to flag its proof
+	forwarded = propose(newBlockProposal(locked, proofOfLock))
+	require.Error(rctx.CheckBlockProposer(height, forwarded.Document().(*blockProposal), forwarded.Endorsement()))
+
+	// a new block without proof of unlock is rejected
+	blk := newBlock(proposer, rctx.round.StartTime())
+	newHash := blk.HashBlock()
+	_, err = rctx.NewProposalEndorsement(propose(newBlockProposal(blk, nil)))
+	require.Error(err)
+
+	// a new block with insufficient proof of unlock is rejected
+	msg := propose(newBlockProposalWithProof(blk, endorse(NewConsensusVote(nil, PROPOSAL), delegates[7:10]), unlockProof))
+	require.Equal(
+		ErrInsufficientEndorsements,
+		errors.Cause(rctx.CheckBlockProposer(height, msg.Document().(*blockProposal), msg.Endorsement())),
+	)
+	_, err = rctx.NewProposalEndorsement(msg)
+	require.Equal(ErrInsufficientEndorsements, errors.Cause(err))
+
+	// endorsements of the proof of unlock have to be made before the proposal
+	en, err := endorsement.Endorse(keys[delegates[7]], NewConsensusVote(nil, PROPOSAL), rctx.round.StartTime())
+	require.NoError(err)
+	proofOfUnlock := append(endorse(NewConsensusVote(nil, PROPOSAL), delegates[8:24]), en)
+	msg = propose(newBlockProposalWithProof(blk, proofOfUnlock, unlockProof))
+	require.Error(rctx.CheckBlockProposer(height, msg.Document().(*blockProposal), msg.Endorsement()))
+
+	// a majority of endorsements for the new block unlocks as well
+	msg = propose(newBlockProposalWithProof(blk, endorse(NewConsensusVote(newHash[:], PROPOSAL), delegates[7:24]), unlockProof))
+	require.NoError(rctx.CheckBlockProposer(height, msg.Document().(*blockProposal), msg.Endorsement()))
+
+	// the proof of unlock has to come from the proposer itself
+	forwarded = propose(newBlockProposalWithProof(locked, proofOfLock, unlockProof))
+	require.Error(rctx.CheckBlockProposer(height, forwarded.Document().(*blockProposal), forwarded.Endorsement()))
+
+	// old format proposals are accepted below the switch height
+	rctx.cfg.UnlockProofHeight = height + 1
+	require.NoError(rctx.checkUnlock(newBlockProposal(blk, nil), rctx.round.StartTime()))
+	// while typed ones aren't
+	msg = propose(newBlockProposalWithProof(blk, endorse(NewConsensusVote(newHash[:], PROPOSAL), delegates[7:24]), unlockProof))
+	require.Error(rctx.CheckBlockProposer(height, msg.Document().(*blockProposal), msg.Endorsement()))
+	rctx.cfg.UnlockProofHeight = 0
+
+	// a new block with a majority of endorsements for no block is endorsed
+	msg = propose(newBlockProposalWithProof(blk, endorse(NewConsensusVote(nil, COMMIT), delegates[7:24]), unlockProof))
+	require.NoError(rctx.CheckBlockProposer(height, msg.Document().(*blockProposal), msg.Endorsement()))
+	res, err := rctx.NewProposalEndorsement(msg)
+	require.NoError(err)
+	vote := res.(*EndorsedConsensusMessage).Document().(*ConsensusVote)
+	require.Equal(newHash[:], vote.BlockHash())
+	require.Equal(PROPOSAL, vote.Topic())
+}
+
+func TestProbatedDelegate(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	cfg.ProbationHeight = 1
+	cfg.CountProbated = false
+	b, rp := makeChain(t)
+	keys := map[string]crypto.PrivateKey{}
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		keys[identityset.Address(i).String()] = identityset.PrivateKey(i)
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	rctx, err := newRollDPoSCtx(
+		cfg, true, 20*time.Second, time.Second, true, b, nil, rp, nil, candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+	// the proposer of the round without probation is on probation
+	round, err := rctx.roundCalc.NewRound(b.TipHeight()+1, c.Now())
+	require.NoError(err)
+	probatedAddr := round.Proposer()
+	rctx.roundCalc.probationListFunc = func(uint64) (map[string]uint32, error) {
+		return map[string]uint32{probatedAddr: 50}, nil
+	}
+	require.NoError(rctx.Prepare())
+	height := rctx.round.Height()
+	ts := rctx.round.StartTime()
+	proposer := rctx.round.Proposer()
+	require.NotEqual(probatedAddr, proposer)
+
+	propose := func(producer string) (*blockProposal, *endorsement.Endorsement) {
+		blk, err := block.NewTestingBuilder().
+			SetHeight(height).
+			SetTimeStamp(ts).
+			SignAndBuild(keys[producer])
+		require.NoError(err)
+		blk.WorkingSet = mock_factory.NewMockWorkingSet(ctrl)
+		bp := newBlockProposal(&blk, nil)
+		en, err := endorsement.Endorse(keys[producer], bp, ts)
+		require.NoError(err)
+		return bp, en
+	}
+
+	// the probated delegate attempts to propose in the slot it would have without probation
+	bp, en := propose(probatedAddr)
+	err = rctx.CheckBlockProposer(height, bp, en)
+	require.Error(err)
+	require.Equal(ReasonNotProposer, RejectionReasonOf(err))
+	bp, en = propose(proposer)
+	require.NoError(rctx.CheckBlockProposer(height, bp, en))
+
+	// the votes of the probated delegate are rejected unless the probated delegates count
+	blkHash := bp.block.HashBlock()
+	vote := NewConsensusVote(blkHash[:], PROPOSAL)
+	en, err = endorsement.Endorse(keys[probatedAddr], vote, ts)
+	require.NoError(err)
+	err = rctx.CheckVoteEndorser(height, vote, en)
+	require.Error(err)
+	require.Equal(ReasonNotDelegate, RejectionReasonOf(err))
+	require.False(rctx.round.IsDelegate(probatedAddr))
+	rctx.roundCalc.countProbated = true
+	require.NoError(rctx.CheckVoteEndorser(height, vote, en))
+}
+
+func TestActivate(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	c := clock.New()
+	// the test chain only has the candidates of the first epoch
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return b.CandidatesByHeight(1)
+	}
+	rctx, err := newRollDPoSCtx(cfg, false, time.Second*20, time.Second, true, b, nil, rp, nil, candidatesByHeight, "", nil, c)
+	require.NoError(err)
+	delegates, err := rctx.roundCalc.Delegates(b.TipHeight() + 1)
+	require.NoError(err)
+	rctx, err = newRollDPoSCtx(cfg, false, time.Second*20, time.Second, true, b, nil, rp, nil, candidatesByHeight, delegates[0], nil, c)
+	require.NoError(err)
+
+	// a standby node lags behind the tip
+	require.Equal(uint64(0), rctx.Height())
+	require.False(rctx.IsDelegate())
+
+	// jump to the current height right after activation
+	rctx.Activate(true)
+	require.True(rctx.Active())
+	require.Equal(b.TipHeight()+1, rctx.Height())
+	require.True(rctx.IsDelegate())
+
+	// no more prepare when already active
+	rctx.round.height = 0
+	rctx.Activate(true)
+	require.Equal(uint64(0), rctx.Height())
+	rctx.Activate(false)
+	require.False(rctx.IsDelegate())
+}
+
+func TestHealth(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	cfg.HealthMaxLag = 2
+	cfg.HealthStallIntervals = 5
+	blockInterval := time.Second * 20
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(time.Second).Sub(c.Now()))
+	// the test chain only has the candidates of the first epoch
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return b.CandidatesByHeight(1)
+	}
+	rctx, err := newRollDPoSCtx(cfg, true, blockInterval, time.Second, true, b, nil, rp, nil, candidatesByHeight, "", nil, c)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+	require.Equal(Participating, rctx.Health())
+	require.True(rctx.Health().Healthy())
+
+	// still participating right at the stall threshold
+	c.Add(time.Duration(cfg.HealthStallIntervals)*blockInterval - time.Second)
+	require.Equal(Participating, rctx.Health())
+
+	// stalled once no block is committed for the given number of intervals
+	c.Add(time.Second)
+	require.Equal(Stalled, rctx.Health())
+	require.False(rctx.Health().Healthy())
+
+	// stall detection is disabled with zero intervals
+	rctx.cfg.HealthStallIntervals = 0
+	require.Equal(Participating, rctx.Health())
+
+	// syncing if the round height falls behind the tip
+	rctx.round.height = b.TipHeight() + 1 - cfg.HealthMaxLag
+	require.Equal(Participating, rctx.Health())
+	rctx.round.height--
+	require.Equal(Syncing, rctx.Health())
+	require.Equal("syncing", rctx.Health().String())
+}
+
+func TestSuppressEmptyBlock(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	cfg.SuppressEmptyBlock = true
+	cfg.MaxIdleInterval = time.Minute
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	// the test chain only has the candidates of the first epoch
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return b.CandidatesByHeight(1)
+	}
+	actPool := mock_actpool.NewMockActPool(ctrl)
+	pending := map[string][]action.SealedEnvelope{}
+	actPool.EXPECT().PendingActionMap().DoAndReturn(func() map[string][]action.SealedEnvelope {
+		return pending
+	}).AnyTimes()
+	actPool.EXPECT().PendingActionIterator().DoAndReturn(func() actioniterator.ActionIterator {
+		actionMap := map[string][]action.SealedEnvelope{}
+		for sender, acts := range pending {
+			actionMap[sender] = acts
+		}
+		return actioniterator.NewActionIterator(actionMap)
+	}).AnyTimes()
+	rctx, err := newRollDPoSCtx(
+		cfg, true, time.Second*20, time.Second, true, b, actPool, rp, nil, candidatesByHeight, "", identityset.PrivateKey(0), c,
+	)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+	rctx.encodedAddr = rctx.round.Proposer()
+
+	// no block is proposed with an empty action pool
+	proposal, err := rctx.Proposal()
+	require.NoError(err)
+	require.Nil(proposal)
+
+	// a block is proposed once an action appears
+	tsf, err := testutil.SignedTransfer(identityset.Address(2).String(), identityset.PrivateKey(1), 1, big.NewInt(1), nil, 100000, big.NewInt(0))
+	require.NoError(err)
+	pending[identityset.Address(1).String()] = []action.SealedEnvelope{tsf}
+	proposal, err = rctx.Proposal()
+	require.NoError(err)
+	require.NotNil(proposal)
+	require.Equal(b.TipHeight()+1, proposal.(*EndorsedConsensusMessage).Height())
+
+	// an empty block is proposed after the max idle interval
+	delete(pending, identityset.Address(1).String())
+	c.Add(cfg.MaxIdleInterval)
+	require.NoError(rctx.Prepare())
+	rctx.encodedAddr = rctx.round.Proposer()
+	proposal, err = rctx.Proposal()
+	require.NoError(err)
+	require.NotNil(proposal)
+
+	// empty blocks are proposed if the suppression is off
+	cfg.SuppressEmptyBlock = false
+	c = clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	rctx, err = newRollDPoSCtx(
+		cfg, true, time.Second*20, time.Second, true, b, actPool, rp, nil, candidatesByHeight, "", identityset.PrivateKey(0), c,
+	)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+	rctx.encodedAddr = rctx.round.Proposer()
+	proposal, err = rctx.Proposal()
+	require.NoError(err)
+	require.NotNil(proposal)
+}
+
+// systemActionMinter prepends a system action to the actions of every block
+type systemActionMinter struct {
+	BlockMinter
+	sender       string
+	systemAction action.SealedEnvelope
+}
+
+func (m *systemActionMinter) Mint(actionMap map[string][]action.SealedEnvelope, ts time.Time) (*block.Block, error) {
+	actions := map[string][]action.SealedEnvelope{}
+	for addr, acts := range actionMap {
+		actions[addr] = acts
+	}
+	actions[m.sender] = append([]action.SealedEnvelope{m.systemAction}, actions[m.sender]...)
+	return m.BlockMinter.Mint(actions, ts)
+}
+
+func TestBlockMinter(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	// the test chain only has the candidates of the first epoch
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return b.CandidatesByHeight(1)
+	}
+	actPool := mock_actpool.NewMockActPool(ctrl)
+	actPool.EXPECT().PendingActionMap().Return(map[string][]action.SealedEnvelope{}).AnyTimes()
+	actPool.EXPECT().PendingActionIterator().DoAndReturn(func() actioniterator.ActionIterator {
+		return actioniterator.NewActionIterator(map[string][]action.SealedEnvelope{})
+	}).AnyTimes()
+	rctx, err := newRollDPoSCtx(
+		cfg, true, time.Second*20, time.Second, true, b, actPool, rp, nil, candidatesByHeight, "", identityset.PrivateKey(0), c,
+	)
+	require.NoError(err)
+	_, ok := rctx.minter.(*chainMinter)
+	require.True(ok)
+	systemAction, err := testutil.SignedTransfer(identityset.Address(2).String(), identityset.PrivateKey(1), 1, big.NewInt(1), nil, 100000, big.NewInt(0))
+	require.NoError(err)
+	rctx.minter = &systemActionMinter{
+		BlockMinter:  rctx.minter,
+		sender:       identityset.Address(1).String(),
+		systemAction: systemAction,
+	}
+	require.NoError(rctx.Prepare())
+	rctx.encodedAddr = rctx.round.Proposer()
+
+	// the proposed block carries the system action although the action pool is empty
+	proposal, err := rctx.Proposal()
+	require.NoError(err)
+	require.NotNil(proposal)
+	blk := proposal.(*EndorsedConsensusMessage).Document().(*blockProposal).block
+	require.Equal(b.TipHeight()+1, blk.Height())
+	require.True(len(blk.Actions) > 1)
+	require.Equal(systemAction.Hash(), blk.Actions[0].Hash())
+}
+
+func TestCompetingProposals(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	keys := map[string]crypto.PrivateKey{}
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		keys[identityset.Address(i).String()] = identityset.PrivateKey(i)
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	// start returns the context of a delegate other than the proposer in a new round
+	start := func() *rollDPoSCtx {
+		rctx, err := newRollDPoSCtx(
+			cfg, true, 20*time.Second, time.Second, true, b, nil, rp, nil, candidatesByHeight, "", nil, c,
+		)
+		require.NoError(err)
+		require.NoError(rctx.Prepare())
+		for _, d := range rctx.round.Delegates() {
+			if d != rctx.round.Proposer() {
+				rctx.encodedAddr = d
+				rctx.priKey = keys[d]
+				break
+			}
+		}
+		return rctx
+	}
+	rctx := start()
+	height := rctx.round.Height()
+	proposer := rctx.round.Proposer()
+	// the equivocating proposer signs two blocks of the same timestamp
+	propose := func(prevHash hash.Hash256) (*EndorsedConsensusMessage, hash.Hash256) {
+		blk, err := block.NewTestingBuilder().
+			SetHeight(height).
+			SetTimeStamp(rctx.round.StartTime()).
+			SetPrevBlockHash(prevHash).
+			SignAndBuild(keys[proposer])
+		require.NoError(err)
+		blk.WorkingSet = mock_factory.NewMockWorkingSet(ctrl)
+		bp := newBlockProposal(&blk, nil)
+		en, err := endorsement.Endorse(keys[proposer], bp, rctx.round.StartTime())
+		require.NoError(err)
+		return NewEndorsedConsensusMessage(height, bp, en), blk.HashBlock()
+	}
+	msg1, hash1 := propose(hash.Hash256{1})
+	msg2, hash2 := propose(hash.Hash256{2})
+	winner := hash1
+	if bytes.Compare(hash2[:], hash1[:]) < 0 {
+		winner = hash2
+	}
+
+	// buffering the proposals, the nodes receiving them in either order endorse the same block exactly once
+	for _, msgs := range [][]*EndorsedConsensusMessage{{msg1, msg2}, {msg2, msg1}} {
+		rctx := start()
+		for _, msg := range msgs {
+			err := rctx.ReceiveBlock(msg)
+			if err != nil {
+				require.Equal(ErrLosingProposal, errors.Cause(err))
+			}
+		}
+		res, err := rctx.NewProposalEndorsement(nil)
+		require.NoError(err)
+		vote := res.(*EndorsedConsensusMessage).Document().(*ConsensusVote)
+		require.Equal(PROPOSAL, vote.Topic())
+		require.Equal(winner[:], vote.BlockHash())
+		// neither a second endorsement nor a late proposal is made
+		_, err = rctx.NewProposalEndorsement(nil)
+		require.Equal(ErrProposalEndorsed, errors.Cause(err))
+		_, err = rctx.NewProposalEndorsement(msgs[0])
+		require.Error(err)
+		require.Equal(ErrLosingProposal, errors.Cause(rctx.ReceiveBlock(msgs[1])))
+	}
+}
+
+func TestProposalSoftCap(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	// the test chain only has the candidates of the first epoch
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return b.CandidatesByHeight(1)
+	}
+	// 3 accounts with 2 transfers each
+	pending := map[string][]action.SealedEnvelope{}
+	for i := 1; i <= 3; i++ {
+		for nonce := uint64(1); nonce <= 2; nonce++ {
+			tsf, err := testutil.SignedTransfer(
+				identityset.Address(0).String(), identityset.PrivateKey(i), nonce, big.NewInt(1), nil, 100000, big.NewInt(0),
+			)
+			require.NoError(err)
+			pending[identityset.Address(i).String()] = append(pending[identityset.Address(i).String()], tsf)
+		}
+	}
+	actPool := mock_actpool.NewMockActPool(ctrl)
+	actPool.EXPECT().PendingActionMap().DoAndReturn(func() map[string][]action.SealedEnvelope {
+		actionMap := map[string][]action.SealedEnvelope{}
+		for sender, acts := range pending {
+			actionMap[sender] = acts
+		}
+		return actionMap
+	}).AnyTimes()
+	actPool.EXPECT().PendingActionIterator().DoAndReturn(func() actioniterator.ActionIterator {
+		actionMap := map[string][]action.SealedEnvelope{}
+		for sender, acts := range pending {
+			actionMap[sender] = acts
+		}
+		return actioniterator.NewActionIterator(actionMap)
+	}).AnyTimes()
+	// propose returns the block proposed with the config
+	propose := func(cfg config.RollDPoS, blockGasLimit uint64) (*block.Block, error) {
+		rctx, err := newRollDPoSCtx(
+			cfg, true, time.Second*20, time.Second, true, b, actPool, rp, nil, candidatesByHeight, "",
+			identityset.PrivateKey(0), c,
+		)
+		require.NoError(err)
+		rctx.blockGasLimit = blockGasLimit
+		require.NoError(rctx.Prepare())
+		rctx.encodedAddr = rctx.round.Proposer()
+		proposal, err := rctx.Proposal()
+		if err != nil {
+			return nil, err
+		}
+		return proposal.(*EndorsedConsensusMessage).Document().(*blockProposal).block, nil
+	}
+	// proposeWith returns the user actions of the block proposed with the config
+	proposeWith := func(cfg config.RollDPoS, blockGasLimit uint64) []action.SealedEnvelope {
+		blk, err := propose(cfg, blockGasLimit)
+		require.NoError(err)
+		acts := []action.SealedEnvelope{}
+		for _, act := range blk.Actions {
+			if _, ok := act.Action().(*action.Transfer); ok {
+				acts = append(acts, act)
+			}
+		}
+		return acts
+	}
+
+	cfg := config.Default.Consensus.RollDPoS
+	require.Equal(6, len(proposeWith(cfg, 0)))
+	cfg.ProposalMaxActions = 3
+	capped := proposeWith(cfg, 0)
+	require.Equal(3, len(capped))
+	// the nonces of each account stay consecutive
+	nonces := map[string]uint64{}
+	for _, act := range capped {
+		sender, err := address.FromBytes(act.SrcPubkey().Hash())
+		require.NoError(err)
+		nonces[sender.String()]++
+		require.Equal(nonces[sender.String()], act.Nonce())
+	}
+	cfg.ProposalMaxActions = 0
+	cfg.ProposalMaxGas = 250000
+	require.Equal(2, len(proposeWith(cfg, 0)))
+	// the soft cap never exceeds the block gas limit
+	cfg.ProposalMaxGas = 1000000
+	require.Equal(6, len(proposeWith(cfg, 0)))
+	require.Equal(1, len(proposeWith(cfg, 150000)))
+	cfg.ProposalMaxGas = 0
+	require.Equal(1, len(proposeWith(cfg, 150000)))
+
+	// the actions are trimmed to fit the proposal max size, which the block comes near
+	size := serializedActionSize(pending[identityset.Address(1).String()][0])
+	for _, acts := range pending {
+		for _, act := range acts {
+			require.Equal(size, serializedActionSize(act))
+		}
+	}
+	cfg.ProposalMaxSize = blockSizeSlack + 3*size
+	blk, err := propose(cfg, 0)
+	require.NoError(err)
+	require.True(uint64(blk.SerializedSize()) <= cfg.ProposalMaxSize)
+	require.Equal(3, len(proposeWith(cfg, 0)))
+	cfg.ProposalMaxSize = blockSizeSlack + 3*size - 1
+	require.Equal(2, len(proposeWith(cfg, 0)))
+	// the block isn't proposed if too large all the same
+	cfg.ProposalMaxSize = 100
+	_, err = propose(cfg, 0)
+	require.Equal(ErrProposalTooLarge, errors.Cause(err))
+}
+
+func TestMinBlockInterval(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	cfg.MinBlockInterval = 50 * time.Second
+	b, rp := makeChain(t)
+	tipHeader, err := b.BlockHeaderByHeight(b.TipHeight())
+	require.NoError(err)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	// distinct delegates, such that a majority is reachable
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	actPool := mock_actpool.NewMockActPool(ctrl)
+	actPool.EXPECT().PendingActionMap().Return(map[string][]action.SealedEnvelope{}).AnyTimes()
+	actPool.EXPECT().PendingActionIterator().DoAndReturn(func() actioniterator.ActionIterator {
+		return actioniterator.NewActionIterator(map[string][]action.SealedEnvelope{})
+	}).AnyTimes()
+	actPool.EXPECT().Reset().Times(1)
+	broadcastHandler := func(proto.Message) error { return nil }
+	rctx, err := newRollDPoSCtx(
+		cfg, true, 20*time.Second, time.Second, true, b, actPool, rp, broadcastHandler, candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+	// prepare switches to the round of the proposer, and returns the block of the round
+	prepare := func() *block.Block {
+		require.NoError(rctx.Prepare())
+		for i := 0; i < len(candidates); i++ {
+			if identityset.Address(i).String() == rctx.round.Proposer() {
+				rctx.encodedAddr = rctx.round.Proposer()
+				rctx.priKey = identityset.PrivateKey(i)
+			}
+		}
+		blk, err := b.MintNewBlock(nil, rctx.round.StartTime())
+		require.NoError(err)
+		require.NoError(rctx.round.AddBlock(blk))
+		return blk
+	}
+	commit := func(blk *block.Block) (bool, error) {
+		blkHash := blk.HashBlock()
+		vote := NewConsensusVote(blkHash[:], COMMIT)
+		for i := 0; i < len(candidates); i++ {
+			en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, rctx.round.StartTime())
+			require.NoError(err)
+			committed, err := rctx.Commit(NewEndorsedConsensusMessage(blk.Height(), vote, en))
+			if committed || err != nil {
+				return committed, err
+			}
+		}
+		return false, nil
+	}
+
+	// a block within the min block interval is neither proposed nor committed
+	blk := prepare()
+	require.True(blk.Timestamp().Sub(tipHeader.Timestamp()) < cfg.MinBlockInterval)
+	proposal, err := rctx.Proposal()
+	require.NoError(err)
+	require.Nil(proposal)
+	committed, err := commit(blk)
+	require.Equal(ErrBlockTooEarly, errors.Cause(err))
+	require.False(committed)
+	require.Equal(blk.Height()-1, b.TipHeight())
+
+	// the block of a later round is
+	c.Add(time.Minute)
+	blk = prepare()
+	require.True(blk.Timestamp().Sub(tipHeader.Timestamp()) >= cfg.MinBlockInterval)
+	proposal, err = rctx.Proposal()
+	require.NoError(err)
+	require.NotNil(proposal)
+	committed, err = commit(blk)
+	require.NoError(err)
+	require.True(committed)
+	require.Equal(blk.Height(), b.TipHeight())
+}
+
+func TestEpochParticipation(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	actPool := mock_actpool.NewMockActPool(ctrl)
+	actPool.EXPECT().Reset().AnyTimes()
+	broadcastHandler := func(proto.Message) error { return nil }
+	rctx, err := newRollDPoSCtx(
+		cfg, true, 20*time.Second, time.Second, true, b, actPool, rp, broadcastHandler, candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+
+	// several rounds of the epoch, in each of which a different delegate doesn't endorse the block
+	expected := map[string]ParticipationStats{}
+	var epochNum uint64
+	for r := 0; r < 3; r++ {
+		require.NoError(rctx.Prepare())
+		if r == 0 {
+			epochNum = rctx.round.EpochNum()
+			for _, d := range rctx.round.Delegates() {
+				expected[d] = ParticipationStats{}
+			}
+		}
+		require.Equal(epochNum, rctx.round.EpochNum())
+		proposer := rctx.round.Proposer()
+		stats := expected[proposer]
+		stats.Proposals++
+		expected[proposer] = stats
+		blk, err := b.MintNewBlock(nil, rctx.round.StartTime())
+		require.NoError(err)
+		require.NoError(rctx.round.AddBlock(blk))
+		blkHash := blk.HashBlock()
+		vote := NewConsensusVote(blkHash[:], COMMIT)
+		committed := false
+		for i := 0; i < len(candidates) && !committed; i++ {
+			if i == r {
+				continue
+			}
+			en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, rctx.round.StartTime())
+			require.NoError(err)
+			committed, err = rctx.Commit(NewEndorsedConsensusMessage(blk.Height(), vote, en))
+			require.NoError(err)
+			stats := expected[identityset.Address(i).String()]
+			stats.Endorsements++
+			expected[identityset.Address(i).String()] = stats
+		}
+		require.True(committed)
+		require.Equal(blk.Height(), b.TipHeight())
+		c.Add(20 * time.Second)
+	}
+	participation, err := rctx.EpochParticipation(epochNum)
+	require.NoError(err)
+	require.Equal(expected, participation)
+	_, err = rctx.EpochParticipation(epochNum + 1)
+	require.Error(err)
+}
+
+func TestRotateKey(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	blockInterval := time.Second * 20
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	// the test chain only has the candidates of the first epoch
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return b.CandidatesByHeight(1)
+	}
+	actPool := mock_actpool.NewMockActPool(ctrl)
+	actPool.EXPECT().PendingActionMap().Return(map[string][]action.SealedEnvelope{}).AnyTimes()
+	actPool.EXPECT().PendingActionIterator().DoAndReturn(func() actioniterator.ActionIterator {
+		return actioniterator.NewActionIterator(map[string][]action.SealedEnvelope{})
+	}).AnyTimes()
+	rctx, err := newRollDPoSCtx(cfg, true, blockInterval, time.Second, true, b, actPool, rp, nil, candidatesByHeight, "", nil, c)
+	require.NoError(err)
+	require.Error(rctx.RotateKey(nil))
+
+	// proposerKey returns the key of the proposer of the round at the given time
+	proposerKey := func(now time.Time) crypto.PrivateKey {
+		round, err := rctx.roundCalc.UpdateRound(rctx.round, b.TipHeight()+1, now)
+		require.NoError(err)
+		for i := 0; i < identityset.Size(); i++ {
+			if identityset.Address(i).String() == round.Proposer() {
+				return identityset.PrivateKey(i)
+			}
+		}
+		require.FailNow("unknown proposer " + round.Proposer())
+		return nil
+	}
+	propose := func() *EndorsedConsensusMessage {
+		proposal, err := rctx.Proposal()
+		require.NoError(err)
+		require.NotNil(proposal)
+		return proposal.(*EndorsedConsensusMessage)
+	}
+
+	oldKey := proposerKey(c.Now())
+	require.NoError(rctx.RotateKey(oldKey))
+	require.NoError(rctx.Prepare())
+	require.True(rctx.IsDelegate())
+	require.Equal(oldKey.PublicKey().Bytes(), propose().Endorsement().Endorser().Bytes())
+
+	// the endorsements of the current round are still signed by the old key
+	newKey := proposerKey(c.Now().Add(blockInterval))
+	require.NotEqual(oldKey.PublicKey().Bytes(), newKey.PublicKey().Bytes())
+	require.NoError(rctx.RotateKey(newKey))
+	en, err := rctx.NewProposalEndorsement(nil)
+	require.NoError(err)
+	require.Equal(oldKey.PublicKey().Bytes(), en.(*EndorsedConsensusMessage).Endorsement().Endorser().Bytes())
+
+	// the new key signs the endorsements and the block since the next round
+	c.Add(blockInterval)
+	require.NoError(rctx.Prepare())
+	require.True(rctx.IsDelegate())
+	proposal := propose()
+	require.Equal(newKey.PublicKey().Bytes(), proposal.Endorsement().Endorser().Bytes())
+	blk := proposal.Document().(*blockProposal).block
+	require.Equal(newKey.PublicKey().Bytes(), blk.PublicKey().Bytes())
+	require.Equal(rctx.round.Proposer(), blk.ProducerAddress())
+	en, err = rctx.NewProposalEndorsement(nil)
+	require.NoError(err)
+	require.Equal(newKey.PublicKey().Bytes(), en.(*EndorsedConsensusMessage).Endorsement().Endorser().Bytes())
+
+	// the delegate status is evaluated against the new key
+	delegates, err := rctx.roundCalc.Delegates(b.TipHeight() + 1)
+	require.NoError(err)
+	nonDelegate := identityset.PrivateKey(len(delegates))
+	for _, d := range delegates {
+		require.NotEqual(identityset.Address(len(delegates)).String(), d)
+	}
+	require.NoError(rctx.RotateKey(nonDelegate))
+	c.Add(blockInterval)
+	require.NoError(rctx.Prepare())
+	require.False(rctx.IsDelegate())
+}
+
+func TestSkipLatePhase(t *testing.T) {
+	require := require.New(t)
+
+	b, rp := makeChain(t)
+	keys := map[string]crypto.PrivateKey{}
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		keys[identityset.Address(i).String()] = identityset.PrivateKey(i)
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	toleratedOvertime := time.Second
+	rctx, err := newRollDPoSCtx(
+		config.Default.Consensus.RollDPoS, true, 20*time.Second, toleratedOvertime, true, b, nil, rp, nil,
+		candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+	// the node is the proposer of the round
+	rctx.encodedAddr = rctx.round.Proposer()
+	rctx.priKey = keys[rctx.encodedAddr]
+	height := rctx.round.Height()
+	ts := rctx.round.StartTime()
+	blk, err := b.MintNewBlock(nil, ts)
+	require.NoError(err)
+	require.NoError(rctx.round.AddBlock(blk))
+	blkHash := blk.HashBlock()
+	// endorse returns the vote endorsement of the nth delegate, with the ones of the delegates before added already
+	endorse := func(topic ConsensusVoteTopic, n int) *EndorsedConsensusMessage {
+		vote := NewConsensusVote(blkHash[:], topic)
+		for i := 0; i < n; i++ {
+			en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, ts)
+			require.NoError(err)
+			require.NoError(rctx.round.AddVoteEndorsement(vote, en))
+		}
+		en, err := endorsement.Endorse(identityset.PrivateKey(n), vote, ts)
+		require.NoError(err)
+		return NewEndorsedConsensusMessage(height, vote, en)
+	}
+	// jumpPast moves the clock past the deadline along with the tolerated overtime
+	jumpPast := func(deadline time.Time) {
+		c.Add(deadline.Add(toleratedOvertime + time.Second).Sub(c.Now()))
+	}
+	skipped := func(phase string) float64 {
+		return promtestutil.ToFloat64(latePhaseMtc.WithLabelValues(phase))
+	}
+
+	// the block is not proposed after the phase accepting it
+	numSkipped := skipped("proposal")
+	jumpPast(rctx.deadlines.acceptBlock)
+	proposal, err := rctx.Proposal()
+	require.NoError(err)
+	require.Nil(proposal)
+	require.Equal(numSkipped+1, skipped("proposal"))
+
+	numSkipped = skipped("proposalEndorsement")
+	jumpPast(rctx.deadlines.acceptProposalEndorsement)
+	en, err := rctx.NewProposalEndorsement(nil)
+	require.NoError(err)
+	require.Nil(en)
+	require.Equal(numSkipped+1, skipped("proposalEndorsement"))
+
+	// the lock and the pre-commit are not endorsed after their phases, though the round is still locked
+	numSkipped = skipped("lockEndorsement")
+	jumpPast(rctx.deadlines.acceptLockEndorsement)
+	en, err = rctx.NewLockEndorsement(endorse(PROPOSAL, 16))
+	require.NoError(err)
+	require.Nil(en)
+	require.Equal(numSkipped+1, skipped("lockEndorsement"))
+	require.True(rctx.round.IsLocked())
+	require.Equal(blkHash[:], rctx.round.HashOfBlockInLock())
+
+	numSkipped = skipped("preCommitEndorsement")
+	jumpPast(rctx.deadlines.commit)
+	en, err = rctx.NewPreCommitEndorsement(endorse(LOCK, 16))
+	require.NoError(err)
+	require.Nil(en)
+	require.Equal(numSkipped+1, skipped("preCommitEndorsement"))
+}
+
+func TestFinalityHook(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	// distinct delegates, such that a majority is reachable
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	actPool := mock_actpool.NewMockActPool(ctrl)
+	actPool.EXPECT().Reset().Times(1)
+	broadcastHandler := func(proto.Message) error { return nil }
+	rctx, err := newRollDPoSCtx(
+		cfg, true, time.Second*20, time.Second, true, b, actPool, rp, broadcastHandler, candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+	finalized := make(chan *block.Block, 2)
+	for i := 0; i < 2; i++ {
+		rctx.RegisterFinalityHook(func(blk *block.Block) { finalized <- blk })
+	}
+	rctx.RegisterFinalityHook(nil)
+	require.NoError(rctx.Prepare())
+
+	// commit a block with the commit votes of a majority of the delegates
+	blk, err := b.MintNewBlock(nil, rctx.round.StartTime())
+	require.NoError(err)
+	require.NoError(rctx.round.AddBlock(blk))
+	blkHash := blk.HashBlock()
+	vote := NewConsensusVote(blkHash[:], COMMIT)
+	committed := false
+	for i := 0; i < len(candidates) && !committed; i++ {
+		en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, rctx.round.StartTime())
+		require.NoError(err)
+		committed, err = rctx.Commit(NewEndorsedConsensusMessage(blk.Height(), vote, en))
+		require.NoError(err)
+	}
+	require.True(committed)
+	require.Equal(blk.Height(), b.TipHeight())
+
+	// both hooks are called with the committed block
+	for i := 0; i < 2; i++ {
+		select {
+		case finalizedBlk := <-finalized:
+			require.Equal(blkHash, finalizedBlk.HashBlock())
+		case <-time.After(5 * time.Second):
+			require.FailNow("finality hook is not called")
+		}
+	}
+
+	// the round of the committed block is recorded
+	meta, err := b.ConsensusMetaByHeight(blk.Height())
+	require.NoError(err)
+	require.Equal(blkHash, meta.BlockHash)
+	require.True(meta.RoundKnown())
+	require.Equal(rctx.round.Number(), meta.Round)
+	require.Equal(rctx.round.Proposer(), meta.Proposer)
+	require.Equal(uint32(len(blk.Endorsements())), meta.NumCommitEndorsements)
+	require.NotZero(meta.NumCommitEndorsements)
+	require.True(rctx.round.StartTime().Equal(meta.RoundStartTime))
+}
+
+func TestRoundsToCommitMtc(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	// 2 block intervals after the last block, i.e., the rounds 0 and 1 have passed without a block
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(50 * time.Second).Sub(c.Now()))
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	actPool := mock_actpool.NewMockActPool(ctrl)
+	actPool.EXPECT().Reset().Times(1)
+	broadcastHandler := func(proto.Message) error { return nil }
+	rctx, err := newRollDPoSCtx(
+		cfg, true, time.Second*20, time.Second, true, b, actPool, rp, broadcastHandler, candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+	require.Equal(uint32(2), rctx.round.Number())
+
+	histogram := func() *dto.Histogram {
+		m := &dto.Metric{}
+		require.NoError(roundsToCommitMtc.WithLabelValues("1").(prometheus.Metric).Write(m))
+		return m.GetHistogram()
+	}
+	before := histogram()
+
+	blk, err := b.MintNewBlock(nil, rctx.round.StartTime())
+	require.NoError(err)
+	require.NoError(rctx.round.AddBlock(blk))
+	blkHash := blk.HashBlock()
+	vote := NewConsensusVote(blkHash[:], COMMIT)
+	committed := false
+	for i := 0; i < len(candidates) && !committed; i++ {
+		en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, rctx.round.StartTime())
+		require.NoError(err)
+		committed, err = rctx.Commit(NewEndorsedConsensusMessage(blk.Height(), vote, en))
+		require.NoError(err)
+	}
+	require.True(committed)
+
+	// the commit is recorded at round 2
+	after := histogram()
+	require.Equal(before.GetSampleCount()+1, after.GetSampleCount())
+	require.Equal(before.GetSampleSum()+2, after.GetSampleSum())
+	for i, bucket := range after.GetBucket() {
+		delta := bucket.GetCumulativeCount() - before.GetBucket()[i].GetCumulativeCount()
+		if bucket.GetUpperBound() < 2 {
+			require.Zero(delta)
+		} else {
+			require.Equal(uint64(1), delta)
+		}
+	}
+}
+
+func TestSubscribeBlockCommit(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	blockInterval := 20 * time.Second
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	// distinct delegates, such that a majority is reachable
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	numBlocks := 3
+	actPool := mock_actpool.NewMockActPool(ctrl)
+	actPool.EXPECT().Reset().Times(numBlocks)
+	broadcastHandler := func(proto.Message) error { return nil }
+	rctx, err := newRollDPoSCtx(
+		cfg, true, blockInterval, time.Second, true, b, actPool, rp, broadcastHandler, candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+	committedBlocks, unsubscribe := b.SubscribeBlockCommit(numBlocks)
+	defer unsubscribe()
+
+	// commit the blocks of a few heights with the commit votes of a majority of the delegates
+	hashes := []hash.Hash256{}
+	for h := 0; h < numBlocks; h++ {
+		require.NoError(rctx.Prepare())
+		blk, err := b.MintNewBlock(nil, rctx.round.StartTime())
+		require.NoError(err)
+		require.NoError(rctx.round.AddBlock(blk))
+		blkHash := blk.HashBlock()
+		hashes = append(hashes, blkHash)
+		vote := NewConsensusVote(blkHash[:], COMMIT)
+		committed := false
+		for i := 0; i < len(candidates) && !committed; i++ {
+			en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, rctx.round.StartTime())
+			require.NoError(err)
+			committed, err = rctx.Commit(NewEndorsedConsensusMessage(blk.Height(), vote, en))
+			require.NoError(err)
+		}
+		require.True(committed)
+		c.Add(blockInterval)
+	}
+
+	// the blocks are received in order, with the commit endorsements in the footer
+	for _, blkHash := range hashes {
+		select {
+		case blk := <-committedBlocks:
+			require.Equal(blkHash, blk.HashBlock())
+			require.True(len(blk.Endorsements())*3 > len(candidates)*2)
+			for _, en := range blk.Endorsements() {
+				vote := NewConsensusVote(blkHash[:], COMMIT)
+				require.True(endorsement.VerifyEndorsement(vote, en))
+			}
+			require.False(blk.CommitTime().IsZero())
+		default:
+			require.FailNow("committed block is not received")
+		}
+	}
+	unsubscribe()
+	unsubscribe()
+	_, ok := <-committedBlocks
+	require.False(ok)
+}
+
+func TestReload(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	blockInterval := 20 * time.Second
+	b, rp := makeChain(t)
+	// the test chain only has the candidates of the first epoch
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return b.CandidatesByHeight(1)
+	}
+	// 1.5s into a round of the next height
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	genesis := time.Unix(b.GenesisTimestamp(), 0)
+	lastBlockTime := genesis.Add(footer.CommitTime().Sub(genesis) / blockInterval * blockInterval)
+	c := clock.NewMock()
+	c.Add(lastBlockTime.Add(3*blockInterval + 1500*time.Millisecond).Sub(c.Now()))
+	newCtx := func() *rollDPoSCtx {
+		rctx, err := newRollDPoSCtx(
+			cfg, true, blockInterval, time.Second, true, b, nil, rp, nil, candidatesByHeight,
+			identityset.Address(0).String(), identityset.PrivateKey(0), c,
+		)
+		require.NoError(err)
+		return rctx
+	}
+	rctx := newCtx()
+	var fsmCfg *consensusfsm.Config
+	rctx.reloadFSM = func(cfg consensusfsm.Config) { fsmCfg = &cfg }
+
+	// the sum of the ttls exceeds the block interval
+	reloaded := cfg
+	reloaded.FSM.CommitTTL = blockInterval
+	require.Error(rctx.Reload(reloaded))
+	reloaded = cfg
+	reloaded.ToleratedOvertime = -time.Second
+	require.Error(rctx.Reload(reloaded))
+
+	reloaded = cfg
+	reloaded.FSM.EventChanSize = 1
+	reloaded.FSM.UnmatchedEventTTL = 5 * time.Second
+	reloaded.FSM.AcceptBlockTTL = 3 * time.Second
+	reloaded.FSM.CommitTTL = time.Second
+	reloaded.ToleratedOvertime = 2 * time.Second
+	reloaded.Delay = time.Hour
+	require.NoError(rctx.Reload(reloaded))
+	// an unmatched event in between the old and the new ttls
+	evt := rctx.NewConsensusEvent(fsm.EventType("E_UNMATCHED"), nil)
+	c.Add(4 * time.Second)
+	require.True(rctx.IsStaleUnmatchedEvent(evt))
+	c.Add(-4 * time.Second)
+
+	// nothing changes until the next round
+	require.Nil(fsmCfg)
+	require.Equal(cfg.FSM, rctx.cfg.FSM)
+	require.Equal(time.Second, rctx.RoundCalc().toleratedOvertime)
+
+	require.NoError(rctx.Prepare())
+	expected := reloaded.FSM
+	expected.EventChanSize = cfg.FSM.EventChanSize
+	require.Equal(expected, rctx.cfg.FSM)
+	require.Equal(&expected, fsmCfg)
+	require.Equal(cfg.Delay, rctx.cfg.Delay)
+	require.Equal(2*time.Second, rctx.RoundCalc().toleratedOvertime)
+	c.Add(4 * time.Second)
+	require.False(rctx.IsStaleUnmatchedEvent(evt))
+	c.Add(-4 * time.Second)
+	// the round within the new tolerated overtime of its end is still running
+	origin := newCtx()
+	require.NoError(origin.Prepare())
+	require.Equal(origin.round.Number()-1, rctx.round.Number())
+
+	// a reload is applied only once
+	fsmCfg = nil
+	c.Add(blockInterval)
+	require.NoError(rctx.Prepare())
+	require.Nil(fsmCfg)
+}
+
+func TestFutureRoundEvent(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	cfg.MaxFutureRounds = 3
+	blockInterval := 20 * time.Second
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return b.CandidatesByHeight(1)
+	}
+	rctx, err := newRollDPoSCtx(cfg, true, blockInterval, time.Second, true, b, nil, rp, nil, candidatesByHeight, "", nil, c)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+	height := rctx.round.Height()
+	roundNum, _, err := rctx.roundCalc.RoundInfo(height, c.Now())
+	require.NoError(err)
+	blkHash := hash.Hash256b([]byte("block"))
+	vote := NewConsensusVote(blkHash[:], PROPOSAL)
+	event := func(height uint64, ahead time.Duration) *consensusfsm.ConsensusEvent {
+		en, err := endorsement.Endorse(identityset.PrivateKey(0), vote, c.Now().Add(ahead))
+		require.NoError(err)
+		return rctx.NewConsensusEvent(consensusfsm.BackdoorEvent, NewEndorsedConsensusMessage(height, vote, en))
+	}
+	dropped := func() float64 {
+		return promtestutil.ToFloat64(futureRoundEventMtc.WithLabelValues())
+	}
+	base := dropped()
+
+	// a message up to the max future rounds ahead of the current time is fed to the FSM
+	evt := event(height, 3*blockInterval)
+	require.NotNil(evt)
+	require.Equal(roundNum+3, evt.Round())
+	// a message timestamped further ahead is dropped, rather than taking the FSM to a wild round
+	require.Nil(event(height, 4*blockInterval))
+	require.Nil(event(height, 1000*time.Hour))
+	require.Equal(base+2, dropped())
+	// a node of which the FSM lags behind the clock isn't cut off from the rounds ahead of the FSM
+	c.Add(10 * blockInterval)
+	evt = event(height, 0)
+	require.NotNil(evt)
+	require.True(evt.Round() > rctx.round.Number()+3)
+
+	// no limit applies if disabled
+	rctx.cfg.MaxFutureRounds = 0
+	evt = event(height, 1000*time.Hour)
+	require.NotNil(evt)
+	require.True(evt.Round() > roundNum+3)
+	require.Equal(base+2, dropped())
+}
+
+func TestBroadcastRetry(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	cfg.BroadcastMaxAttempts = 3
+	cfg.BroadcastRetryInterval = 10 * time.Millisecond
+	b, rp := makeChain(t)
+	var calls int32
+	failures := int32(2)
+	broadcastHandler := func(proto.Message) error {
+		if atomic.AddInt32(&calls, 1) <= atomic.LoadInt32(&failures) {
+			return errors.New("failed to publish")
+		}
+		return nil
+	}
+	c := clock.New()
+	rctx, err := newRollDPoSCtx(cfg, true, time.Second*20, time.Second, true, b, nil, rp, broadcastHandler, nil, "", nil, c)
+	require.NoError(err)
+	require.NotNil(rctx)
+	require.NoError(rctx.Start(context.Background()))
+	defer func() {
+		require.NoError(rctx.Stop(context.Background()))
+	}()
+	msg := &iotextypes.Block{}
+	failed := func() float64 {
+		return promtestutil.ToFloat64(broadcastFailureMtc.WithLabelValues("endorsement"))
+	}
+	numFailed := failed()
+
+	// case 1: fail twice then succeed
+	rctx.broadcast(msg, "endorsement", c.Now().Add(time.Second), nil)
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, time.Second, func() (bool, error) {
+		return atomic.LoadInt32(&calls) == 3, nil
+	}))
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(int32(3), atomic.LoadInt32(&calls))
+	require.Equal(numFailed, failed())
+
+	// case 2: attempts are used up
+	atomic.StoreInt32(&calls, 0)
+	atomic.StoreInt32(&failures, 3)
+	rctx.broadcast(msg, "endorsement", c.Now().Add(time.Second), nil)
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, time.Second, func() (bool, error) {
+		return failed() == numFailed+1, nil
+	}))
+	require.Equal(int32(3), atomic.LoadInt32(&calls))
+
+	// case 3: abort when moving to a new round
+	atomic.StoreInt32(&calls, 0)
+	rctx.broadcast(msg, "endorsement", c.Now().Add(time.Second), func() bool { return true })
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(int32(1), atomic.LoadInt32(&calls))
+	require.Equal(numFailed+1, failed())
+
+	// case 4: no retry beyond the deadline
+	atomic.StoreInt32(&calls, 0)
+	rctx.broadcast(msg, "endorsement", c.Now().Add(15*time.Millisecond), nil)
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, time.Second, func() (bool, error) {
+		return failed() == numFailed+2, nil
+	}))
+	require.Equal(int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRoundStartJitter(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	cfg.RoundStartJitter = 20 * time.Millisecond
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	rctx, err := newRollDPoSCtx(
+		cfg, true, 20*time.Second, time.Second, true, b, nil, rp, nil, candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+	startTime := rctx.round.StartTime()
+	// wait moves the clock to a time relative to the round start, and returns the overtime and the time slept
+	wait := func(sinceStart time.Duration) (time.Duration, time.Duration) {
+		c.Add(startTime.Add(sinceStart).Sub(c.Now()))
+		start := time.Now()
+		overtime := rctx.WaitUntilRoundStart()
+		return overtime, time.Since(start)
+	}
+	// the time slept is bounded by the max jitter plus a margin for the scheduling
+	const margin = 50 * time.Millisecond
+
+	// the jitter delays the delegate after the round start, and counts as overtime
+	jittered := false
+	for i := 0; i < 20; i++ {
+		overtime, slept := wait(0)
+		require.True(overtime >= 0 && overtime < cfg.RoundStartJitter)
+		require.True(slept >= overtime && slept < cfg.RoundStartJitter+margin)
+		jittered = jittered || overtime > 0
+	}
+	require.True(jittered)
+
+	// the overtime of a late delegate is added to the jitter
+	overtime, _ := wait(time.Second)
+	require.True(overtime >= time.Second && overtime < time.Second+cfg.RoundStartJitter)
+
+	// a delegate ahead of the round start sleeps until then, before the jitter
+	overtime, slept := wait(-30 * time.Millisecond)
+	require.True(overtime >= 0 && overtime < cfg.RoundStartJitter)
+	require.True(slept >= 30*time.Millisecond+overtime && slept < 30*time.Millisecond+cfg.RoundStartJitter+margin)
+
+	// the jitter never passes the end of the block proposal phase
+	window := cfg.FSM.AcceptBlockTTL
+	for i := 0; i < 20; i++ {
+		overtime, _ := wait(window - 2*time.Millisecond)
+		require.True(overtime >= window-2*time.Millisecond && overtime < window)
+	}
+	overtime, slept = wait(window + time.Millisecond)
+	require.Equal(window+time.Millisecond, overtime)
+	require.True(slept < margin)
+
+	// no jitter if disabled
+	rctx.cfg.RoundStartJitter = 0
+	overtime, _ = wait(0)
+	require.Equal(time.Duration(0), overtime)
+}
+
+// slowCommitChain blocks committing a block until released
+type slowCommitChain struct {
+	blockchain.Blockchain
+	committing chan struct{}
+	release    chan struct{}
+}
+
+func (c *slowCommitChain) CommitBlock(blk *block.Block) error {
+	close(c.committing)
+	<-c.release
+	return c.Blockchain.CommitBlock(blk)
+}
+
+func TestStopGracefully(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := config.Default.Consensus.RollDPoS
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	actPool := mock_actpool.NewMockActPool(ctrl)
+	actPool.EXPECT().Reset().AnyTimes()
+	// the committed block fails to broadcast, which leaves a retry pending
+	var broadcasts int32
+	broadcastHandler := func(proto.Message) error {
+		atomic.AddInt32(&broadcasts, 1)
+		return errors.New("failed to publish")
+	}
+	// commit starts committing a block with the commit votes of a majority of the delegates, and returns a channel
+	// closed once committed
+	commit := func() (*RollDPoS, *slowCommitChain, <-chan struct{}) {
+		b, rp := makeChain(t)
+		footer, err := b.BlockFooterByHeight(b.TipHeight())
+		require.NoError(err)
+		c := clock.NewMock()
+		c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+		chain := &slowCommitChain{Blockchain: b, committing: make(chan struct{}), release: make(chan struct{})}
+		rctx, err := newRollDPoSCtx(
+			cfg, true, time.Second*20, time.Second, true, chain, actPool, rp, broadcastHandler, candidatesByHeight, "", nil, c,
+		)
+		require.NoError(err)
+		require.NoError(rctx.Prepare())
+		blk, err := b.MintNewBlock(nil, rctx.round.StartTime())
+		require.NoError(err)
+		require.NoError(rctx.round.AddBlock(blk))
+		blkHash := blk.HashBlock()
+		vote := NewConsensusVote(blkHash[:], COMMIT)
+		msgs := []*EndorsedConsensusMessage{}
+		for i := 0; i < len(candidates); i++ {
+			en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, rctx.round.StartTime())
+			require.NoError(err)
+			msgs = append(msgs, NewEndorsedConsensusMessage(blk.Height(), vote, en))
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for _, msg := range msgs {
+				if committed, err := rctx.Commit(msg); err != nil || committed {
+					return
+				}
+			}
+		}()
+		<-chain.committing
+		return &RollDPoS{ctx: rctx}, chain, done
+	}
+
+	t.Run("wait", func(t *testing.T) {
+		atomic.StoreInt32(&broadcasts, 0)
+		r, chain, done := commit()
+		height := chain.TipHeight()
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			close(chain.release)
+		}()
+		start := time.Now()
+		require.NoError(r.StopGracefully(10 * time.Second))
+		require.True(time.Since(start) >= 100*time.Millisecond)
+		// the block is committed before proceeding, and its broadcast is no longer retried
+		require.Equal(height+1, chain.TipHeight())
+		require.False(r.ctx.Active())
+		<-done
+		require.Equal(int32(1), atomic.LoadInt32(&broadcasts))
+		require.Equal(0, r.ctx.retryQueue.Len())
+		require.Empty(r.ctx.retries)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		atomic.StoreInt32(&broadcasts, 0)
+		r, chain, done := commit()
+		height := chain.TipHeight()
+		require.Error(r.StopGracefully(50 * time.Millisecond))
+		require.Equal(height, chain.TipHeight())
+		// the broadcast of the block committed after the timeout isn't retried either
+		close(chain.release)
+		<-done
+		require.Equal(height+1, chain.TipHeight())
+		require.Equal(int32(1), atomic.LoadInt32(&broadcasts))
+		require.Equal(0, r.ctx.retryQueue.Len())
+	})
 }
 
 func getBlockforctx(t *testing.T, i int, sign bool) block.Block {
@@ -173,3 +1928,111 @@ func getBlockforctx(t *testing.T, i int, sign bool) block.Block {
 	b := block.Block{Header: header}
 	return b
 }
+
+func TestRoundStateRecovery(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	store := db.NewMemKVStore()
+	// start creates the context of the node of the last delegate persisting its round state in the store
+	start := func() *rollDPoSCtx {
+		n := len(candidates) - 1
+		rctx, err := newRollDPoSCtx(
+			cfg, true, time.Second*20, time.Second, true, b, nil, rp, nil, candidatesByHeight,
+			identityset.Address(n).String(), identityset.PrivateKey(n), c,
+		)
+		require.NoError(err)
+		rctx.stateStore = store
+		require.NoError(rctx.Start(context.Background()))
+		require.NoError(rctx.Prepare())
+		return rctx
+	}
+	rctx := start()
+	require.False(rctx.round.IsLocked())
+
+	// the node endorses the proposed block and gets locked on it
+	height := rctx.round.Height()
+	ts := rctx.round.StartTime()
+	blk, err := b.MintNewBlock(nil, ts)
+	require.NoError(err)
+	blkHash := blk.HashBlock()
+	proposal := newBlockProposal(blk, nil)
+	en, err := endorsement.Endorse(identityset.PrivateKey(0), proposal, ts)
+	require.NoError(err)
+	vote, err := rctx.NewProposalEndorsement(NewEndorsedConsensusMessage(height, proposal, en))
+	require.NoError(err)
+	require.NotNil(vote)
+	proposalVote := NewConsensusVote(blkHash[:], PROPOSAL)
+	for i := 0; i < 16; i++ {
+		en, err := endorsement.Endorse(identityset.PrivateKey(i), proposalVote, ts)
+		require.NoError(err)
+		require.NoError(rctx.round.AddVoteEndorsement(proposalVote, en))
+	}
+	en, err = endorsement.Endorse(identityset.PrivateKey(16), proposalVote, ts)
+	require.NoError(err)
+	vote, err = rctx.NewLockEndorsement(NewEndorsedConsensusMessage(height, proposalVote, en))
+	require.NoError(err)
+	require.NotNil(vote)
+	require.True(rctx.round.IsLocked())
+	proofOfLock := rctx.round.ProofOfLock()
+	require.Len(proofOfLock, 17)
+	require.NoError(rctx.Stop(context.Background()))
+
+	// the node restarting in the same round resumes with the lock
+	requireLocked := func(rctx *rollDPoSCtx) {
+		require.True(rctx.round.IsLocked())
+		require.Equal(blkHash[:], rctx.round.HashOfBlockInLock())
+		require.Len(rctx.round.ProofOfLock(), len(proofOfLock))
+		for i, en := range rctx.round.ProofOfLock() {
+			require.Equal(proofOfLock[i].Endorser().Bytes(), en.Endorser().Bytes())
+			require.Equal(proofOfLock[i].Signature(), en.Signature())
+		}
+		locked := rctx.round.Block(blkHash[:])
+		require.NotNil(locked)
+		require.NotNil(locked.WorkingSet)
+	}
+	rctx = start()
+	require.Equal(height, rctx.round.Height())
+	requireLocked(rctx)
+	// and never endorses another block in the round
+	vote, err = rctx.newVote([]byte("another block"), PROPOSAL, ts)
+	require.NoError(err)
+	require.Nil(vote)
+	vote, err = rctx.newVote(blkHash[:], COMMIT, ts)
+	require.NoError(err)
+	require.NotNil(vote)
+	require.NoError(rctx.Stop(context.Background()))
+
+	// the lock is kept in a later round of the height, where the votes are of the round
+	c.Add(20 * time.Second)
+	rctx = start()
+	require.Equal(height, rctx.round.Height())
+	require.NotEqual(uint32(0), rctx.round.Number())
+	requireLocked(rctx)
+	vote, err = rctx.newVote([]byte("another block"), PROPOSAL, rctx.round.StartTime())
+	require.NoError(err)
+	require.NotNil(vote)
+	require.NoError(rctx.Stop(context.Background()))
+
+	// a state of another height is dropped
+	require.NoError(b.CommitBlock(blk))
+	rctx = start()
+	require.Equal(height+1, rctx.round.Height())
+	require.False(rctx.round.IsLocked())
+	require.NoError(rctx.Stop(context.Background()))
+}
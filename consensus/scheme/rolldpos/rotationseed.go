@@ -0,0 +1,61 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/action/protocol/rolldpos"
+	"github.com/iotexproject/iotex-core/blockchain"
+	"github.com/iotexproject/iotex-core/crypto"
+)
+
+// RotationSeedFunc defines a function returning the seed shuffling the proposer rotation of an epoch, which has to be
+// the same on all the nodes, e.g., derived from the blocks of the previous epoch or from a randomness beacon. A nil
+// seed leaves the rotation of the epoch unshuffled.
+type RotationSeedFunc func(epochNum uint64) ([]byte, error)
+
+// NewBlockHashRotationSeedFunc returns the RotationSeedFunc seeding an epoch with the hash of the last block of the
+// previous epoch, which is unknown until the block is committed. The first epoch has no seed.
+func NewBlockHashRotationSeedFunc(chain blockchain.Blockchain, rp *rolldpos.Protocol) RotationSeedFunc {
+	return func(epochNum uint64) ([]byte, error) {
+		if epochNum <= 1 {
+			return nil, nil
+		}
+		height := rp.GetEpochHeight(epochNum) - 1
+		h, err := chain.GetHashByHeight(height)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the hash of block %d", height)
+		}
+		return h[:], nil
+	}
+}
+
+// rotationSeed returns the seed of the proposer rotation of the epoch, which is nil unless the seeding applies to the
+// epoch
+func (c *roundCalculator) rotationSeed(epochNum uint64) ([]byte, error) {
+	if c.rotationSeedFunc == nil || c.rotationSeedHeight == 0 ||
+		c.rp.GetEpochHeight(epochNum) < c.rotationSeedHeight {
+		return nil, nil
+	}
+	seed, err := c.rotationSeedFunc(epochNum)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the rotation seed of epoch %d", epochNum)
+	}
+	return seed, nil
+}
+
+// shuffleRotation returns the proposer rotation of the epoch shuffled by the seed, which is deterministic given the
+// seed but can't be told before it. The rotation is returned as it is if the seed is nil.
+func shuffleRotation(rotation []string, epochNum uint64, seed []byte) []string {
+	if seed == nil {
+		return rotation
+	}
+	shuffled := append([]string{}, rotation...)
+	crypto.SortCandidates(shuffled, epochNum, seed)
+	return shuffled
+}
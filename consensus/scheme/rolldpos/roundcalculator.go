@@ -7,17 +7,34 @@
 package rolldpos
 
 import (
+	"math/big"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/iotexproject/iotex-core/action/protocol/rolldpos"
 	"github.com/iotexproject/iotex-core/blockchain"
 	"github.com/iotexproject/iotex-core/blockchain/block"
 	"github.com/iotexproject/iotex-core/crypto"
 	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/state"
 )
 
+var sharedCandidatesFetchMtc = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "iotex_consensus_shared_candidates_fetch",
+		Help: "Number of candidates fetches suppressed as duplicates of the one in flight for the same height",
+	},
+	[]string{},
+)
+
+func init() {
+	prometheus.MustRegister(sharedCandidatesFetchMtc)
+}
+
 type roundCalculator struct {
 	chain                  blockchain.Blockchain
 	blockInterval          time.Duration
@@ -25,6 +42,24 @@ type roundCalculator struct {
 	timeBasedRotation      bool
 	rp                     *rolldpos.Protocol
 	candidatesByHeightFunc CandidatesByHeightFunc
+	// probationListFunc returns the probated delegates of an epoch, nil if there is no probation
+	probationListFunc ProbationListFunc
+	// probationHeight is the height from which the epochs are subject to the probation, 0 to disable
+	probationHeight uint64
+	// countProbated tells whether the probated delegates count toward the majority of the endorsements
+	countProbated bool
+	// thresholdHeight is the height from which the epochs use the threshold rather than 2/3 of the delegates for the
+	// majority of the endorsements, 0 to disable
+	thresholdHeight uint64
+	threshold       endorsementThreshold
+	// weightedVotingHeight is the height from which the epochs weight the endorsements by the votes of the delegates,
+	// 0 to disable
+	weightedVotingHeight uint64
+	// rotationSeedFunc returns the seed shuffling the proposer rotation of an epoch, nil if there is no seed
+	rotationSeedFunc RotationSeedFunc
+	// rotationSeedHeight is the height from which the epochs shuffle the proposer rotation by their seeds, 0 to
+	// disable
+	rotationSeedHeight uint64
 }
 
 func (c *roundCalculator) BlockInterval() time.Duration {
@@ -35,6 +70,10 @@ func (c *roundCalculator) UpdateRound(round *roundCtx, height uint64, now time.T
 	epochNum := round.EpochNum()
 	epochStartHeight := round.EpochStartHeight()
 	delegates := round.Delegates()
+	probated := round.probated
+	threshold := round.threshold
+	weights := round.weights
+	rotationSeed := round.rotationSeed
 	switch {
 	case height < round.Height():
 		return nil, errors.New("cannot update to a lower height")
@@ -50,6 +89,16 @@ func (c *roundCalculator) UpdateRound(round *roundCtx, height uint64, now time.T
 			if delegates, err = c.Delegates(epochStartHeight); err != nil {
 				return nil, err
 			}
+			if probated, err = c.probated(epochNum, delegates); err != nil {
+				return nil, err
+			}
+			threshold = c.endorsementThreshold(epochNum)
+			if weights, err = c.weights(epochNum, delegates); err != nil {
+				return nil, err
+			}
+			if rotationSeed, err = c.rotationSeed(epochNum); err != nil {
+				return nil, err
+			}
 		}
 	}
 	roundNum, roundStartTime, err := c.roundInfo(height, now, true)
@@ -68,7 +117,7 @@ func (c *roundCalculator) UpdateRound(round *roundCtx, height uint64, now time.T
 	} else {
 		eManager = newEndorsementManager()
 	}
-	proposer, err := c.calculateProposer(height, roundNum, delegates)
+	proposer, err := c.calculateProposer(height, roundNum, delegates, probated, rotationSeed)
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +126,11 @@ func (c *roundCalculator) UpdateRound(round *roundCtx, height uint64, now time.T
 		epochStartHeight:     epochStartHeight,
 		nextEpochStartHeight: c.rp.GetEpochHeight(epochNum + 1),
 		delegates:            delegates,
+		probated:             probated,
+		countProbated:        c.countProbated,
+		threshold:            threshold,
+		weights:              weights,
+		rotationSeed:         rotationSeed,
 
 		height:             height,
 		roundNum:           roundNum,
@@ -99,6 +153,45 @@ func (c *roundCalculator) Proposer(height uint64, roundStartTime time.Time) stri
 	return round.Proposer()
 }
 
+// ProposerByHeight returns the proposer expected for the committed block of a height, which is the proposer of the
+// round at the timestamp of the block, by the same rule as the block proposals are checked against
+func (c *roundCalculator) ProposerByHeight(height uint64) (string, error) {
+	if tip := c.chain.TipHeight(); height == 0 || height > tip {
+		return "", errors.Errorf("height %d is not a committed block, tip = %d", height, tip)
+	}
+	header, err := c.chain.BlockHeaderByHeight(height)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the header of block %d", height)
+	}
+	round, err := c.newRound(height, header.Timestamp(), false)
+	if err != nil {
+		return "", err
+	}
+	return round.Proposer(), nil
+}
+
+// Validate returns ErrProposerMismatch if the proposer of the height at the round start time isn't the expected one,
+// e.g., to verify the proposer of a block on chain, which is timestamped with the start time of its round
+func (c *roundCalculator) Validate(height uint64, ts time.Time, expectedProposer string) error {
+	round, err := c.newRound(height, ts, false)
+	if err != nil {
+		return err
+	}
+	if round.Proposer() != expectedProposer {
+		return errors.Wrapf(
+			ErrProposerMismatch,
+			"proposer of height %d at %s is %s rather than %s",
+			height,
+			ts,
+			round.Proposer(),
+			expectedProposer,
+		)
+	}
+	return nil
+}
+
+// IsDelegate returns whether the address is a delegate endorsing at the height, which excludes the probated ones
+// unless they count toward the majority, by the same rule as roundCtx.IsDelegate
 func (c *roundCalculator) IsDelegate(addr string, height uint64) bool {
 	delegates, err := c.Delegates(height)
 	if err != nil {
@@ -106,7 +199,11 @@ func (c *roundCalculator) IsDelegate(addr string, height uint64) bool {
 	}
 	for _, d := range delegates {
 		if addr == d {
-			return true
+			if c.countProbated {
+				return true
+			}
+			probated, err := c.probated(c.rp.GetEpochNum(height), delegates)
+			return err == nil && !probated[addr]
 		}
 	}
 
@@ -206,6 +303,10 @@ func (c *roundCalculator) newRound(
 	epochNum := uint64(0)
 	epochStartHeight := uint64(0)
 	var delegates []string
+	var probated map[string]bool
+	var threshold endorsementThreshold
+	var weights map[string]*big.Int
+	var rotationSeed []byte
 	var roundNum uint32
 	var proposer string
 	var roundStartTime time.Time
@@ -215,10 +316,20 @@ func (c *roundCalculator) newRound(
 		if delegates, err = c.Delegates(epochStartHeight); err != nil {
 			return
 		}
+		if probated, err = c.probated(epochNum, delegates); err != nil {
+			return
+		}
+		threshold = c.endorsementThreshold(epochNum)
+		if weights, err = c.weights(epochNum, delegates); err != nil {
+			return
+		}
+		if rotationSeed, err = c.rotationSeed(epochNum); err != nil {
+			return
+		}
 		if roundNum, roundStartTime, err = c.roundInfo(height, now, withToleration); err != nil {
 			return
 		}
-		if proposer, err = c.calculateProposer(height, roundNum, delegates); err != nil {
+		if proposer, err = c.calculateProposer(height, roundNum, delegates, probated, rotationSeed); err != nil {
 			return
 		}
 	}
@@ -228,6 +339,11 @@ func (c *roundCalculator) newRound(
 		epochStartHeight:     epochStartHeight,
 		nextEpochStartHeight: c.rp.GetEpochHeight(epochNum + 1),
 		delegates:            delegates,
+		probated:             probated,
+		countProbated:        c.countProbated,
+		threshold:            threshold,
+		weights:              weights,
+		rotationSeed:         rotationSeed,
 
 		height:             height,
 		roundNum:           roundNum,
@@ -239,20 +355,115 @@ func (c *roundCalculator) newRound(
 	}, nil
 }
 
+// probated returns the delegates of the epoch which are on probation, which is empty unless the probation applies to
+// the epoch
+func (c *roundCalculator) probated(epochNum uint64, delegates []string) (map[string]bool, error) {
+	if c.probationListFunc == nil || c.probationHeight == 0 || c.rp.GetEpochHeight(epochNum) < c.probationHeight {
+		return nil, nil
+	}
+	probationList, err := c.probationListFunc(epochNum)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the probation list of epoch %d", epochNum)
+	}
+	probated := map[string]bool{}
+	for _, d := range delegates {
+		if _, ok := probationList[d]; ok {
+			probated[d] = true
+		}
+	}
+	return probated, nil
+}
+
+// endorsementThreshold returns the threshold of the majority of the endorsements in the epoch, which is the default
+// unless the epoch starts at or after the threshold height
+func (c *roundCalculator) endorsementThreshold(epochNum uint64) endorsementThreshold {
+	if c.thresholdHeight == 0 || c.rp.GetEpochHeight(epochNum) < c.thresholdHeight {
+		return endorsementThreshold{}
+	}
+	return c.threshold
+}
+
+// weights returns the votes of the delegates of the epoch weighting their endorsements, which is nil unless the
+// weighted voting applies to the epoch. A delegate without votes weighs nothing.
+func (c *roundCalculator) weights(epochNum uint64, delegates []string) (map[string]*big.Int, error) {
+	epochStartHeight := c.rp.GetEpochHeight(epochNum)
+	if c.weightedVotingHeight == 0 || epochStartHeight < c.weightedVotingHeight {
+		return nil, nil
+	}
+	candidates, err := c.candidatesByHeightFunc(epochStartHeight)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get candidates on height %d", epochStartHeight)
+	}
+	votes := make(map[string]*big.Int, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.Votes != nil {
+			votes[candidate.Address] = candidate.Votes
+		}
+	}
+	weights := make(map[string]*big.Int, len(delegates))
+	for _, d := range delegates {
+		if v, ok := votes[d]; ok {
+			weights[d] = new(big.Int).Set(v)
+		} else {
+			weights[d] = big.NewInt(0)
+		}
+	}
+	return weights, nil
+}
+
+// calculateProposer rotates the proposers over the delegates not on probation, or over all the delegates if all of
+// them are on probation
 func (c *roundCalculator) calculateProposer(
 	height uint64,
 	round uint32,
 	delegates []string,
+	probated map[string]bool,
+	seed []byte,
 ) (proposer string, err error) {
 	numDelegates := c.rp.NumDelegates()
 	if numDelegates != uint64(len(delegates)) {
 		err = errors.New("invalid delegate list")
 		return
 	}
+	rotation := delegates
+	if len(probated) != 0 {
+		rotation = make([]string, 0, len(delegates))
+		for _, d := range delegates {
+			if !probated[d] {
+				rotation = append(rotation, d)
+			}
+		}
+		if len(rotation) == 0 {
+			rotation = delegates
+		}
+	}
+	rotation = shuffleRotation(rotation, c.rp.GetEpochNum(height), seed)
 	idx := height
 	if c.timeBasedRotation {
 		idx += uint64(round)
 	}
-	proposer = delegates[idx%numDelegates]
+	proposer = rotation[idx%uint64(len(rotation))]
 	return
 }
+
+// shareCandidatesFetch shares a fetch of the candidates among the concurrent callers of the same height, which is the
+// start height of an epoch, such that a burst of votes checked against the delegates runs one state query rather than
+// one per vote. Each caller waits for no longer than its own fetch would take, and gets the error of the shared fetch
+// if it fails. The candidates returned are shared by the callers, so they must not be modified.
+func shareCandidatesFetch(candidatesByHeightFunc CandidatesByHeightFunc) CandidatesByHeightFunc {
+	group := &singleflight.Group{}
+	return func(height uint64) ([]*state.Candidate, error) {
+		fetched := false
+		v, err, _ := group.Do(strconv.FormatUint(height, 10), func() (interface{}, error) {
+			fetched = true
+			return candidatesByHeightFunc(height)
+		})
+		if !fetched {
+			sharedCandidatesFetchMtc.WithLabelValues().Inc()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return v.([]*state.Candidate), nil
+	}
+}
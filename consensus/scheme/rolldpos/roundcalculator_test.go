@@ -9,11 +9,13 @@ package rolldpos
 import (
 	"context"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/iotexproject/go-pkgs/crypto"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 
 	"github.com/iotexproject/iotex-core/action/protocol"
@@ -25,6 +27,7 @@ import (
 	"github.com/iotexproject/iotex-core/blockchain/genesis"
 	"github.com/iotexproject/iotex-core/config"
 	"github.com/iotexproject/iotex-core/pkg/unit"
+	"github.com/iotexproject/iotex-core/state"
 	"github.com/iotexproject/iotex-core/test/identityset"
 	"github.com/iotexproject/iotex-core/testutil"
 )
@@ -32,7 +35,7 @@ import (
 func TestUpdateRound(t *testing.T) {
 	require := require.New(t)
 	bc, roll := makeChain(t)
-	rc := &roundCalculator{bc, time.Second, time.Second, true, roll, bc.CandidatesByHeight}
+	rc := &roundCalculator{bc, time.Second, time.Second, true, roll, bc.CandidatesByHeight, nil, 0, false, 0, endorsementThreshold{}, 0, nil, 0}
 	ra, err := rc.NewRound(1, time.Unix(1562382392, 0))
 	require.NoError(err)
 
@@ -56,19 +59,19 @@ func TestUpdateRound(t *testing.T) {
 func TestNewRound(t *testing.T) {
 	require := require.New(t)
 	bc, roll := makeChain(t)
-	rc := &roundCalculator{bc, time.Second, time.Second, true, roll, bc.CandidatesByHeight}
-	proposer, err := rc.calculateProposer(5, 1, []string{"1", "2", "3", "4", "5"})
+	rc := &roundCalculator{bc, time.Second, time.Second, true, roll, bc.CandidatesByHeight, nil, 0, false, 0, endorsementThreshold{}, 0, nil, 0}
+	proposer, err := rc.calculateProposer(5, 1, []string{"1", "2", "3", "4", "5"}, nil, nil)
 	require.Error(err)
 	var validDelegates [24]string
 	for i := 0; i < 24; i++ {
 		validDelegates[i] = identityset.Address(i).String()
 	}
-	proposer, err = rc.calculateProposer(5, 1, validDelegates[:])
+	proposer, err = rc.calculateProposer(5, 1, validDelegates[:], nil, nil)
 	require.NoError(err)
 	require.Equal(validDelegates[6], proposer)
 
 	rc.timeBasedRotation = false
-	proposer, err = rc.calculateProposer(50, 1, validDelegates[:])
+	proposer, err = rc.calculateProposer(50, 1, validDelegates[:], nil, nil)
 	require.NoError(err)
 	require.Equal(validDelegates[2], proposer)
 
@@ -86,10 +89,105 @@ func TestNewRound(t *testing.T) {
 	require.Equal(uint64(1), ra.height)
 	require.Equal(identityset.Address(5).String(), ra.proposer)
 }
+
+func TestEndorsementThresholdHeight(t *testing.T) {
+	require := require.New(t)
+	bc, roll := makeChain(t)
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(roll.NumDelegates()); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	unanimity := endorsementThreshold{numerator: 1, denominator: 1}
+	rc := &roundCalculator{bc, time.Second, time.Second, true, roll, candidatesByHeight, nil, 0, false, 0, unanimity, 0, nil, 0}
+	now := time.Unix(bc.GenesisTimestamp()+60, 0)
+
+	// the threshold applies from the first epoch starting at or after the threshold height
+	for _, height := range []uint64{0, 50} {
+		rc.thresholdHeight = height
+		round, err := rc.NewRound(51, now)
+		require.NoError(err)
+		require.Equal(endorsementThreshold{}, round.threshold)
+	}
+	rc.thresholdHeight = 49
+	round, err := rc.NewRound(51, now)
+	require.NoError(err)
+	require.Equal(unanimity, round.threshold)
+
+	// a round updated into the next epoch picks up the threshold of the epoch
+	round, err = rc.NewRound(48, now)
+	require.NoError(err)
+	require.Equal(endorsementThreshold{}, round.threshold)
+	round, err = rc.UpdateRound(round, 49, now)
+	require.NoError(err)
+	require.Equal(unanimity, round.threshold)
+}
+
+func TestWeightedVotingHeight(t *testing.T) {
+	require := require.New(t)
+	bc, roll := makeChain(t)
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(roll.NumDelegates()); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	// a delegate without votes weighs nothing
+	candidates[1].Votes = nil
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	rc := &roundCalculator{bc, time.Second, time.Second, true, roll, candidatesByHeight, nil, 0, false, 0, endorsementThreshold{}, 0, nil, 0}
+	now := time.Unix(bc.GenesisTimestamp()+60, 0)
+
+	// the weights apply from the first epoch starting at or after the weighted voting height
+	for _, height := range []uint64{0, 50} {
+		rc.weightedVotingHeight = height
+		round, err := rc.NewRound(51, now)
+		require.NoError(err)
+		require.Nil(round.weights)
+	}
+	rc.weightedVotingHeight = 49
+	round, err := rc.NewRound(51, now)
+	require.NoError(err)
+	require.Equal(len(round.delegates), len(round.weights))
+	for _, d := range round.delegates {
+		for _, c := range candidates {
+			if c.Address != d {
+				continue
+			}
+			if c.Votes == nil {
+				require.Equal(0, round.weights[d].Sign())
+			} else {
+				require.Equal(c.Votes, round.weights[d])
+			}
+		}
+	}
+
+	// a round updated into the next epoch picks up the weights of the epoch
+	round, err = rc.NewRound(48, now)
+	require.NoError(err)
+	require.Nil(round.weights)
+	round, err = rc.UpdateRound(round, 49, now)
+	require.NoError(err)
+	require.Equal(len(round.delegates), len(round.weights))
+	updated, err := rc.UpdateRound(round, 50, now)
+	require.NoError(err)
+	require.Equal(round.weights, updated.weights)
+}
+
 func TestDelegates(t *testing.T) {
 	require := require.New(t)
 	bc, roll := makeChain(t)
-	rc := &roundCalculator{bc, time.Second, time.Second, true, roll, bc.CandidatesByHeight}
+	rc := &roundCalculator{bc, time.Second, time.Second, true, roll, bc.CandidatesByHeight, nil, 0, false, 0, endorsementThreshold{}, 0, nil, 0}
 	_, err := rc.Delegates(361)
 	require.Error(err)
 
@@ -102,11 +200,11 @@ func TestDelegates(t *testing.T) {
 }
 func TestRoundInfo(t *testing.T) {
 	require := require.New(t)
-	rc := &roundCalculator{nil, time.Second, time.Second, true, nil, nil}
+	rc := &roundCalculator{nil, time.Second, time.Second, true, nil, nil, nil, 0, false, 0, endorsementThreshold{}, 0, nil, 0}
 	require.NotNil(rc)
 	require.Equal(time.Second, rc.BlockInterval())
 	bc, roll := makeChain(t)
-	rc = &roundCalculator{bc, time.Second, time.Second, true, roll, bc.CandidatesByHeight}
+	rc = &roundCalculator{bc, time.Second, time.Second, true, roll, bc.CandidatesByHeight, nil, 0, false, 0, endorsementThreshold{}, 0, nil, 0}
 
 	// error for lastBlockTime.Before(now)
 	_, _, err := rc.RoundInfo(1, time.Unix(1562382300, 0))
@@ -138,6 +236,186 @@ func TestRoundInfo(t *testing.T) {
 	require.Equal(uint32(17), roundNum)
 	require.True(roundStartTime.Equal(time.Unix(1562382392, 0)))
 }
+func TestProbation(t *testing.T) {
+	require := require.New(t)
+	bc, roll := makeChain(t)
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(roll.NumDelegates()); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	rc := &roundCalculator{bc, time.Second, time.Second, true, roll, candidatesByHeight, nil, 0, false, 0, endorsementThreshold{}, 0, nil, 0}
+	now := time.Unix(bc.GenesisTimestamp()+60, 0)
+	round, err := rc.NewRound(51, now)
+	require.NoError(err)
+	probatedAddr := round.Proposer()
+	rc.probationListFunc = func(epochNum uint64) (map[string]uint32, error) {
+		require.Equal(uint64(2), epochNum)
+		return map[string]uint32{probatedAddr: 50, identityset.Address(30).String(): 50}, nil
+	}
+
+	// the probation applies from the first epoch starting at or after the probation height
+	for _, height := range []uint64{0, 50} {
+		rc.probationHeight = height
+		round, err = rc.NewRound(51, now)
+		require.NoError(err)
+		require.Equal(probatedAddr, round.Proposer())
+		require.True(rc.IsDelegate(probatedAddr, 51))
+	}
+	rc.probationHeight = 49
+	round, err = rc.NewRound(51, now)
+	require.NoError(err)
+	require.NotEqual(probatedAddr, round.Proposer())
+	require.Equal(map[string]bool{probatedAddr: true}, round.probated)
+	require.False(round.IsDelegate(probatedAddr))
+	require.False(rc.IsDelegate(probatedAddr, 51))
+	rc.countProbated = true
+	require.True(rc.IsDelegate(probatedAddr, 51))
+
+	// the probated delegate has no proposer slot in any round of the epoch
+	proposers := map[string]bool{}
+	for height := uint64(49); height <= 96; height++ {
+		for roundNum := uint32(0); roundNum < 24; roundNum++ {
+			proposer, err := rc.calculateProposer(height, roundNum, round.Delegates(), round.probated, nil)
+			require.NoError(err)
+			proposers[proposer] = true
+		}
+	}
+	require.False(proposers[probatedAddr])
+	require.Equal(23, len(proposers))
+
+	// an epoch with all the delegates on probation falls back to the full rotation
+	all := map[string]bool{}
+	for _, d := range round.Delegates() {
+		all[d] = true
+	}
+	proposer, err := rc.calculateProposer(51, 0, round.Delegates(), all, nil)
+	require.NoError(err)
+	expected, err := rc.calculateProposer(51, 0, round.Delegates(), nil, nil)
+	require.NoError(err)
+	require.Equal(expected, proposer)
+
+	// the probation list is required once the probation applies
+	rc.probationListFunc = func(uint64) (map[string]uint32, error) {
+		return nil, errors.New("probation list is unavailable")
+	}
+	_, err = rc.NewRound(51, now)
+	require.Error(err)
+}
+
+func TestProposerByHeight(t *testing.T) {
+	require := require.New(t)
+	bc, roll := makeChain(t)
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(roll.NumDelegates()); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	rc := &roundCalculator{bc, time.Second, time.Second, true, roll, candidatesByHeight, nil, 0, false, 0, endorsementThreshold{}, 0, nil, 0}
+
+	// the block of height 1 is timestamped with the genesis, which no round starts at
+	for height := uint64(2); height <= bc.TipHeight(); height++ {
+		proposer, err := rc.ProposerByHeight(height)
+		require.NoError(err)
+		header, err := bc.BlockHeaderByHeight(height)
+		require.NoError(err)
+		require.NoError(rc.Validate(height, header.Timestamp(), proposer))
+		// the proposer of the round the block was proposed in
+		round, err := rc.NewRound(height, header.Timestamp().Add(rc.blockInterval/2))
+		require.NoError(err)
+		require.True(header.Timestamp().Equal(round.StartTime()))
+		require.Equal(round.Proposer(), proposer)
+	}
+	for _, height := range []uint64{0, bc.TipHeight() + 1} {
+		_, err := rc.ProposerByHeight(height)
+		require.Error(err)
+	}
+}
+
+func TestRotationSeed(t *testing.T) {
+	require := require.New(t)
+	bc, roll := makeChain(t)
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(roll.NumDelegates()); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	rc := &roundCalculator{bc, time.Second, time.Second, true, roll, candidatesByHeight, nil, 0, false, 0, endorsementThreshold{}, 0, nil, 0}
+	schedule := func(seed []byte) []string {
+		rc.rotationSeedHeight = 49
+		rc.rotationSeedFunc = func(epochNum uint64) ([]byte, error) {
+			require.Equal(uint64(2), epochNum)
+			return seed, nil
+		}
+		round, err := rc.NewRound(51, time.Unix(bc.GenesisTimestamp()+60, 0))
+		require.NoError(err)
+		require.Equal(seed, round.rotationSeed)
+		proposers := []string{}
+		for roundNum := uint32(0); roundNum < 24; roundNum++ {
+			proposer, err := rc.calculateProposer(51, roundNum, round.Delegates(), nil, round.rotationSeed)
+			require.NoError(err)
+			proposers = append(proposers, proposer)
+		}
+		require.Equal(proposers[round.Number()], round.Proposer())
+		return proposers
+	}
+
+	// the seeds shuffle the rotation, which is reproducible given the seed
+	unseeded := schedule(nil)
+	seeded1, seeded2 := schedule([]byte("seed1")), schedule([]byte("seed2"))
+	require.Equal(seeded1, schedule([]byte("seed1")))
+	require.Equal(seeded2, schedule([]byte("seed2")))
+	require.NotEqual(seeded1, seeded2)
+	require.NotEqual(unseeded, seeded1)
+	// each delegate still proposes in turn
+	require.ElementsMatch(unseeded, seeded1)
+	require.ElementsMatch(unseeded, seeded2)
+
+	// the seeding applies from the first epoch starting at or after the rotation seed height
+	rc.rotationSeedHeight = 50
+	round, err := rc.NewRound(51, time.Unix(bc.GenesisTimestamp()+60, 0))
+	require.NoError(err)
+	require.Nil(round.rotationSeed)
+	require.Equal(unseeded[round.Number()], round.Proposer())
+
+	// the seed is required once the seeding applies
+	rc.rotationSeedHeight = 49
+	rc.rotationSeedFunc = func(uint64) ([]byte, error) {
+		return nil, errors.New("seed is unavailable")
+	}
+	_, err = rc.NewRound(51, time.Unix(bc.GenesisTimestamp()+60, 0))
+	require.Error(err)
+
+	// the seed of an epoch is the hash of the last block of the previous epoch
+	seedFunc := NewBlockHashRotationSeedFunc(bc, roll)
+	seed, err := seedFunc(1)
+	require.NoError(err)
+	require.Nil(seed)
+	seed, err = seedFunc(2)
+	require.NoError(err)
+	h, err := bc.GetHashByHeight(48)
+	require.NoError(err)
+	require.Equal(h[:], seed)
+	_, err = seedFunc(3)
+	require.Error(err)
+}
+
 func makeChain(t *testing.T) (blockchain.Blockchain, *rolldpos.Protocol) {
 	require := require.New(t)
 	cfg := config.Default
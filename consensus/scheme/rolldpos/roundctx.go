@@ -7,17 +7,130 @@
 package rolldpos
 
 import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
 	"time"
 
+	"github.com/iotexproject/iotex-address/address"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
 	"github.com/iotexproject/iotex-core/blockchain/block"
+	"github.com/iotexproject/iotex-core/config"
 	"github.com/iotexproject/iotex-core/endorsement"
 )
 
-// ErrInsufficientEndorsements represents the error that not enough endorsements
-var ErrInsufficientEndorsements = errors.New("Insufficient endorsements")
+var (
+	// ErrInsufficientEndorsements represents the error that not enough endorsements
+	ErrInsufficientEndorsements = errors.New("Insufficient endorsements")
+	// ErrTooManyEndorsements represents the error that a proof carries more endorsements than the delegates
+	ErrTooManyEndorsements = errors.New("too many endorsements")
+	// ErrDuplicateEndorser represents the error that a proof carries more than one endorsement of an endorser
+	ErrDuplicateEndorser = errors.New("duplicate endorser")
+	// ErrLosingProposal represents the error that a block proposal is not preferred over the one already received
+	ErrLosingProposal = errors.New("block proposal loses to the received one")
+	// ErrProposalEndorsed represents the error that the block proposal of the round has been endorsed already
+	ErrProposalEndorsed = errors.New("block proposal of the round has been endorsed")
+)
+
+// InsufficientEndorsementsError is ErrInsufficientEndorsements along with how far the endorsements of a block on the
+// topics are from the majority, which is the cause of the error
+type InsufficientEndorsementsError struct {
+	// Topics are the topics of the endorsements counted
+	Topics []ConsensusVoteTopic
+	// Weighted tells whether the endorsements are weighted by the votes of the delegates rather than counted
+	Weighted bool
+	// Endorsed is the number of the delegates endorsing, or their total weight if weighted
+	Endorsed *big.Int
+	// Required is the least number, or weight, of the endorsing delegates making a majority
+	Required *big.Int
+	// Total is the number of the delegates counting toward the majority, or their total weight if weighted
+	Total *big.Int
+}
+
+func (e *InsufficientEndorsementsError) Error() string {
+	unit := "delegates"
+	if e.Weighted {
+		unit = "weight"
+	}
+	topics := make([]string, len(e.Topics))
+	for i, t := range e.Topics {
+		topics[i] = t.String()
+	}
+	return fmt.Sprintf(
+		"%s: %s of %s %s endorsing on %s, %s required",
+		ErrInsufficientEndorsements,
+		e.Endorsed,
+		e.Total,
+		unit,
+		strings.Join(topics, ","),
+		e.Required,
+	)
+}
+
+// Cause returns ErrInsufficientEndorsements
+func (e *InsufficientEndorsementsError) Cause() error { return ErrInsufficientEndorsements }
+
+// Unwrap returns ErrInsufficientEndorsements
+func (e *InsufficientEndorsementsError) Unwrap() error { return ErrInsufficientEndorsements }
+
+// endorsementThreshold is the fraction of the delegates whose endorsements make a majority, which is reached by at
+// least numerator/denominator of the delegates. The zero value stands for more than 2/3 of the delegates.
+type endorsementThreshold struct {
+	numerator   uint64
+	denominator uint64
+}
+
+// newEndorsementThreshold returns the threshold configured, and rejects the ones not guaranteeing the safety unless
+// explicitly allowed
+func newEndorsementThreshold(cfg config.RollDPoS) (endorsementThreshold, error) {
+	if cfg.EndorsementThresholdHeight == 0 {
+		return endorsementThreshold{}, nil
+	}
+	t := endorsementThreshold{
+		numerator:   cfg.EndorsementThreshold.Numerator,
+		denominator: cfg.EndorsementThreshold.Denominator,
+	}
+	if t.numerator == 0 || t.numerator > t.denominator {
+		return endorsementThreshold{}, errors.Errorf("invalid endorsement threshold %d/%d", t.numerator, t.denominator)
+	}
+	if 3*t.numerator <= 2*t.denominator && !cfg.UnsafeEndorsementThreshold {
+		return endorsementThreshold{}, errors.Errorf(
+			"endorsement threshold %d/%d is not above 2/3, which is unsafe",
+			t.numerator,
+			t.denominator,
+		)
+	}
+	return t, nil
+}
+
+// reached tells whether the endorsements of the delegates of a number, or weight, reach the threshold of the total
+func (t endorsementThreshold) reached(endorsed, total *big.Int) bool {
+	if t.denominator == 0 {
+		lhs := new(big.Int).Mul(endorsed, big.NewInt(3))
+		return lhs.Cmp(new(big.Int).Mul(total, big.NewInt(2))) > 0
+	}
+	lhs := new(big.Int).Mul(endorsed, new(big.Int).SetUint64(t.denominator))
+	return lhs.Cmp(new(big.Int).Mul(total, new(big.Int).SetUint64(t.numerator))) >= 0
+}
+
+// required returns the least number, or weight, of the endorsing delegates out of the total reaching the threshold
+func (t endorsementThreshold) required(total *big.Int) *big.Int {
+	if t.denominator == 0 {
+		// more than 2/3 of the total
+		r := new(big.Int).Mul(total, big.NewInt(2))
+		r.Div(r, big.NewInt(3))
+		return r.Add(r, big.NewInt(1))
+	}
+	// at least numerator/denominator of the total, rounded up
+	r := new(big.Int).Mul(total, new(big.Int).SetUint64(t.numerator))
+	d := new(big.Int).SetUint64(t.denominator)
+	r.Add(r, d)
+	r.Sub(r, big.NewInt(1))
+	return r.Div(r, d)
+}
 
 type status int
 
@@ -33,6 +146,16 @@ type roundCtx struct {
 	epochStartHeight     uint64
 	nextEpochStartHeight uint64
 	delegates            []string
+	// probated are the delegates on probation, which are out of the proposer rotation
+	probated map[string]bool
+	// countProbated tells whether the probated delegates endorse and count toward the majority
+	countProbated bool
+	// threshold is the fraction of the delegates whose endorsements make a majority
+	threshold endorsementThreshold
+	// weights are the votes of the delegates weighting their endorsements, nil if each delegate counts as one
+	weights map[string]*big.Int
+	// rotationSeed is the seed shuffling the proposer rotation of the epoch, nil if the rotation isn't shuffled
+	rotationSeed []byte
 
 	height             uint64
 	roundNum           uint32
@@ -44,6 +167,13 @@ type roundCtx struct {
 	proofOfLock []*endorsement.Endorsement
 	status      status
 	eManager    *endorsementManager
+
+	// candidate is the block proposal winning the tie-break among those received in the round so far, which is
+	// endorsed at the end of the accept-block phase
+	candidate          []byte
+	candidateTimestamp time.Time
+	proposalEndorsed   bool
+	proposalInEndorse  []byte
 }
 
 func (ctx *roundCtx) Log(l *zap.Logger) *zap.Logger {
@@ -95,10 +225,12 @@ func (ctx *roundCtx) Delegates() []string {
 	return ctx.delegates
 }
 
+// IsDelegate returns whether the address is a delegate endorsing in the round, which excludes the probated ones
+// unless they count toward the majority
 func (ctx *roundCtx) IsDelegate(addr string) bool {
 	for _, d := range ctx.delegates {
 		if addr == d {
-			return true
+			return ctx.countProbated || !ctx.probated[addr]
 		}
 	}
 
@@ -150,6 +282,54 @@ func (ctx *roundCtx) ProofOfLock() []*endorsement.Endorsement {
 	return ctx.proofOfLock
 }
 
+func (ctx *roundCtx) ProposalInEndorse() []byte {
+	return ctx.proposalInEndorse
+}
+
+// AcceptProposal records a valid block proposal received in this round as the candidate to endorse. Only one
+// proposal is expected in a round, but an equivocating proposer could sign multiple blocks for the same round, which
+// all pass the proposer check. The proposals are totally ordered, such that all honest nodes converge on the same block
+// regardless of the order in which the proposals arrive: the one with the earliest timestamp wins, and among those of
+// the same timestamp, the one with the lexicographically smallest block hash wins. No proposal is accepted once the
+// round's proposal endorsement is made.
+func (ctx *roundCtx) AcceptProposal(blkHash []byte, timestamp time.Time) error {
+	switch {
+	case ctx.proposalEndorsed:
+		return errors.Wrap(ErrLosingProposal, "proposal phase has ended")
+	case len(ctx.candidate) == 0:
+	case timestamp.After(ctx.candidateTimestamp):
+		return errors.Wrapf(
+			ErrLosingProposal,
+			"block timestamp %s is later than the received one %s",
+			timestamp,
+			ctx.candidateTimestamp,
+		)
+	case timestamp.Equal(ctx.candidateTimestamp) && bytes.Compare(blkHash, ctx.candidate) >= 0:
+		return errors.Wrapf(
+			ErrLosingProposal,
+			"block hash %x is not smaller than the received one %x",
+			blkHash,
+			ctx.candidate,
+		)
+	}
+	ctx.candidate = blkHash
+	ctx.candidateTimestamp = timestamp
+
+	return nil
+}
+
+// EndorseProposal returns the candidate block proposal to endorse in this round, which is empty if no valid proposal
+// has been received. The proposal endorsement is made once in a round, and the ones after are rejected.
+func (ctx *roundCtx) EndorseProposal() ([]byte, error) {
+	if ctx.proposalEndorsed {
+		return nil, errors.Wrapf(ErrProposalEndorsed, "block %x endorsed", ctx.proposalInEndorse)
+	}
+	ctx.proposalEndorsed = true
+	ctx.proposalInEndorse = ctx.candidate
+
+	return ctx.proposalInEndorse, nil
+}
+
 func (ctx *roundCtx) IsStale(height uint64, num uint32, data interface{}) bool {
 	switch {
 	case height < ctx.height:
@@ -195,12 +375,52 @@ func (ctx *roundCtx) AddVoteEndorsement(
 	en *endorsement.Endorsement,
 ) error {
 	if !endorsement.VerifyEndorsement(vote, en) {
-		return errors.New("invalid endorsement for the vote")
+		return reject(ReasonInvalidSignature, errors.New("invalid endorsement for the vote"))
+	}
+	return ctx.addVerifiedVoteEndorsement(vote, en)
+}
+
+// VerifyProofOfLock verifies that the endorsements of the block for PROPOSAL or COMMIT make up a majority of the
+// delegates of the round, which the block has been added to, e.g., to validate the commit certificate of a block. The
+// endorsements are added to the round, and an endorser listed more than once fails the verification.
+func VerifyProofOfLock(round *roundCtx, blockHash []byte, endorsements []*endorsement.Endorsement) error {
+	votes := []*ConsensusVote{
+		NewConsensusVote(blockHash, PROPOSAL),
+		NewConsensusVote(blockHash, COMMIT),
+	}
+	// an endorser counts once towards the majority, a proof listing it twice is malformed
+	endorsers := make(map[string]struct{}, len(endorsements))
+	for _, e := range endorsements {
+		endorserAddr, err := address.FromBytes(e.Endorser().Hash())
+		if err != nil {
+			return err
+		}
+		if _, exists := endorsers[endorserAddr.String()]; exists {
+			return errors.Wrapf(ErrDuplicateEndorser, "%s endorses more than once in proof of lock", endorserAddr)
+		}
+		endorsers[endorserAddr.String()] = struct{}{}
+		if err := round.AddVoteEndorsement(votes[0], e); err == nil {
+			continue
+		}
+		if err := round.AddVoteEndorsement(votes[1], e); err != nil {
+			return err
+		}
+	}
+	if !round.EndorsedByMajority(blockHash, []ConsensusVoteTopic{PROPOSAL, COMMIT}) {
+		return errors.Wrap(ErrInsufficientEndorsements, "failed to verify proof of lock")
 	}
+	return nil
+}
+
+// addVerifiedVoteEndorsement adds a vote endorsement of which the signature has been verified
+func (ctx *roundCtx) addVerifiedVoteEndorsement(
+	vote *ConsensusVote,
+	en *endorsement.Endorsement,
+) error {
 	blockHash := vote.BlockHash()
 	// TODO: (zhi) request for block
 	if len(blockHash) != 0 && ctx.block(blockHash) == nil {
-		return errors.New("the corresponding block not received")
+		return reject(ReasonBlockNotReceived, errors.New("the corresponding block not received"))
 	}
 	if err := ctx.eManager.AddVoteEndorsement(vote, en); err != nil {
 		return err
@@ -244,8 +464,85 @@ func (ctx *roundCtx) endorsedByMajority(blockHash []byte, topics []ConsensusVote
 	return ctx.isMajority(ctx.endorsements(blockHash, topics))
 }
 
+// checkMajority returns an InsufficientEndorsementsError unless the block is endorsed by a majority on the topics
+func (ctx *roundCtx) checkMajority(blockHash []byte, topics []ConsensusVoteTopic) error {
+	endorsed, total, weighted := ctx.tally(ctx.endorsements(blockHash, topics))
+	if ctx.threshold.reached(endorsed, total) {
+		return nil
+	}
+	return &InsufficientEndorsementsError{
+		Topics:   append([]ConsensusVoteTopic{}, topics...),
+		Weighted: weighted,
+		Endorsed: endorsed,
+		Required: ctx.threshold.required(total),
+		Total:    total,
+	}
+}
+
 func (ctx *roundCtx) isMajority(endorsements []*endorsement.Endorsement) bool {
-	return 3*len(endorsements) > 2*len(ctx.delegates)
+	endorsed, total, _ := ctx.tally(endorsements)
+	return ctx.threshold.reached(endorsed, total)
+}
+
+// tally returns how much the endorsements count toward the majority out of the total, which is the weight of the
+// endorsers if weighted, or the number of them otherwise
+func (ctx *roundCtx) tally(endorsements []*endorsement.Endorsement) (endorsed, total *big.Int, weighted bool) {
+	if endorsed, total, weighed := ctx.tallyWeights(endorsements); weighed {
+		return endorsed, total, true
+	}
+	if ctx.countProbated || len(ctx.probated) == 0 {
+		return big.NewInt(int64(len(endorsements))), big.NewInt(int64(len(ctx.delegates))), false
+	}
+	// the majority is out of the delegates not on probation, whose endorsements count only
+	voters := 0
+	for _, d := range ctx.delegates {
+		if !ctx.probated[d] {
+			voters++
+		}
+	}
+	counted := 0
+	for _, en := range endorsements {
+		endorserAddr, err := address.FromBytes(en.Endorser().Hash())
+		if err == nil && !ctx.probated[endorserAddr.String()] {
+			counted++
+		}
+	}
+	return big.NewInt(int64(counted)), big.NewInt(int64(voters)), false
+}
+
+// tallyWeights returns the weight of the endorsements out of the total weight of the delegates, out of the ones not
+// on probation unless they count, each endorser weighing once. It doesn't weigh if the weighted voting doesn't apply
+// to the round or the delegates weigh nothing at all, which falls back to counting the delegates.
+func (ctx *roundCtx) tallyWeights(endorsements []*endorsement.Endorsement) (endorsed, total *big.Int, weighed bool) {
+	if ctx.weights == nil {
+		return nil, nil, false
+	}
+	counts := func(d string) bool {
+		return ctx.countProbated || !ctx.probated[d]
+	}
+	total = big.NewInt(0)
+	for _, d := range ctx.delegates {
+		if counts(d) {
+			total.Add(total, ctx.weights[d])
+		}
+	}
+	if total.Sign() == 0 {
+		return nil, nil, false
+	}
+	endorsed = big.NewInt(0)
+	seen := map[string]bool{}
+	for _, en := range endorsements {
+		endorserAddr, err := address.FromBytes(en.Endorser().Hash())
+		if err != nil {
+			continue
+		}
+		d := endorserAddr.String()
+		if w, ok := ctx.weights[d]; ok && counts(d) && !seen[d] {
+			seen[d] = true
+			endorsed.Add(endorsed, w)
+		}
+	}
+	return endorsed, total, true
 }
 
 func (ctx *roundCtx) block(blkHash []byte) *block.Block {
@@ -7,13 +7,19 @@
 package rolldpos
 
 import (
+	goerrors "errors"
+	"math/big"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 
+	"github.com/iotexproject/iotex-core/blockchain/block"
+	"github.com/iotexproject/iotex-core/config"
 	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/test/identityset"
 )
 
 func TestRoundCtx(t *testing.T) {
@@ -94,3 +100,373 @@ func TestRoundCtx(t *testing.T) {
 	})
 	// TODO: add more unit tests
 }
+
+func TestEndorseProposal(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	now := time.Now()
+	small := []byte("block hash 1")
+	large := []byte("block hash 2")
+
+	// two nodes receive the same-timestamp proposals in opposite orders
+	node1 := &roundCtx{}
+	require.NoError(node1.AcceptProposal(small, now))
+	require.Equal(errors.Cause(node1.AcceptProposal(large, now)), ErrLosingProposal)
+	node2 := &roundCtx{}
+	require.NoError(node2.AcceptProposal(large, now))
+	require.NoError(node2.AcceptProposal(small, now))
+	// duplicate proposal
+	require.Equal(errors.Cause(node1.AcceptProposal(small, now)), ErrLosingProposal)
+	// proposal with a later timestamp loses regardless of the hash
+	require.Equal(errors.Cause(node1.AcceptProposal([]byte("block hash 0"), now.Add(time.Second))), ErrLosingProposal)
+
+	// both endorse the same block, and only once
+	endorsed1, err := node1.EndorseProposal()
+	require.NoError(err)
+	require.Equal(small, endorsed1)
+	endorsed2, err := node2.EndorseProposal()
+	require.NoError(err)
+	require.Equal(endorsed1, endorsed2)
+	require.Equal(small, node2.ProposalInEndorse())
+	_, err = node2.EndorseProposal()
+	require.Equal(ErrProposalEndorsed, errors.Cause(err))
+	// no proposal is accepted after the endorsement
+	require.Equal(errors.Cause(node2.AcceptProposal([]byte("block hash 0"), now)), ErrLosingProposal)
+
+	// nothing is endorsed if no proposal is received
+	node3 := &roundCtx{}
+	endorsed, err := node3.EndorseProposal()
+	require.NoError(err)
+	require.Nil(endorsed)
+	require.Equal(errors.Cause(node3.AcceptProposal(small, now)), ErrLosingProposal)
+	require.Nil(node3.ProposalInEndorse())
+}
+
+func TestAcceptProposalTotalOrder(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	type proposal struct {
+		hash      []byte
+		timestamp time.Time
+	}
+	now := time.Now()
+	proposals := []proposal{
+		{[]byte("block hash 0"), now.Add(time.Second)},
+		{[]byte("block hash 1"), now},
+		{[]byte("block hash 2"), now},
+		{[]byte("block hash 3"), now.Add(-time.Second)},
+		{[]byte("block hash 4"), now.Add(-time.Second)},
+	}
+	// the earliest timestamp wins, then the smallest hash
+	winner := proposals[3].hash
+
+	// permute calls f with every order of the proposals
+	var permute func([]proposal, int, func([]proposal))
+	permute = func(ps []proposal, i int, f func([]proposal)) {
+		if i == len(ps) {
+			f(ps)
+			return
+		}
+		for j := i; j < len(ps); j++ {
+			ps[i], ps[j] = ps[j], ps[i]
+			permute(ps, i+1, f)
+			ps[i], ps[j] = ps[j], ps[i]
+		}
+	}
+	orders := 0
+	permute(proposals, 0, func(ps []proposal) {
+		orders++
+		node := &roundCtx{}
+		for _, p := range ps {
+			if err := node.AcceptProposal(p.hash, p.timestamp); err != nil {
+				require.Equal(ErrLosingProposal, errors.Cause(err))
+			}
+		}
+		endorsed, err := node.EndorseProposal()
+		require.NoError(err)
+		require.Equal(winner, endorsed)
+	})
+	require.Equal(120, orders)
+}
+
+func TestProbationMajority(t *testing.T) {
+	require := require.New(t)
+	delegates := []string{}
+	for i := 0; i < 24; i++ {
+		delegates = append(delegates, identityset.Address(i).String())
+	}
+	// the first 6 delegates are on probation
+	probated := map[string]bool{}
+	for _, d := range delegates[:6] {
+		probated[d] = true
+	}
+	endorse := func(from, to int) []*endorsement.Endorsement {
+		ens := []*endorsement.Endorsement{}
+		for i := from; i < to; i++ {
+			ens = append(ens, endorsement.NewEndorsement(time.Now(), identityset.PrivateKey(i).PublicKey(), nil))
+		}
+		return ens
+	}
+
+	// 2/3 of the 24 delegates
+	round := &roundCtx{delegates: delegates, probated: probated, countProbated: true}
+	require.True(round.IsDelegate(delegates[0]))
+	require.False(round.isMajority(endorse(6, 22)))
+	require.True(round.isMajority(endorse(6, 23)))
+	require.True(round.isMajority(endorse(0, 17)))
+
+	// 2/3 of the 18 delegates not on probation, and the endorsements of the probated delegates don't count
+	round.countProbated = false
+	require.False(round.IsDelegate(delegates[0]))
+	require.True(round.IsDelegate(delegates[6]))
+	require.False(round.isMajority(endorse(6, 18)))
+	require.True(round.isMajority(endorse(6, 19)))
+	require.False(round.isMajority(endorse(0, 18)))
+
+	// nothing changes without probation
+	round.probated = nil
+	require.False(round.isMajority(endorse(6, 22)))
+	require.True(round.isMajority(endorse(6, 23)))
+}
+
+func TestEndorsementThreshold(t *testing.T) {
+	require := require.New(t)
+	newThreshold := func(num, den uint64, unsafe bool) (endorsementThreshold, error) {
+		cfg := config.Default.Consensus.RollDPoS
+		cfg.EndorsementThresholdHeight = 1
+		cfg.EndorsementThreshold = config.EndorsementThreshold{Numerator: num, Denominator: den}
+		cfg.UnsafeEndorsementThreshold = unsafe
+		return newEndorsementThreshold(cfg)
+	}
+	for _, invalid := range [][2]uint64{{0, 1}, {1, 0}, {6, 5}, {2, 3}, {3, 5}} {
+		_, err := newThreshold(invalid[0], invalid[1], false)
+		require.Error(err)
+	}
+	_, err := newThreshold(6, 5, true)
+	require.Error(err)
+	threshold, err := newEndorsementThreshold(config.Default.Consensus.RollDPoS)
+	require.NoError(err)
+	require.Equal(endorsementThreshold{}, threshold)
+
+	delegates := func(n int) []string {
+		addrs := []string{}
+		for i := 0; i < n; i++ {
+			addrs = append(addrs, identityset.Address(i).String())
+		}
+		return addrs
+	}
+	endorse := func(n int) []*endorsement.Endorsement {
+		ens := []*endorsement.Endorsement{}
+		for i := 0; i < n; i++ {
+			ens = append(ens, endorsement.NewEndorsement(time.Now(), identityset.PrivateKey(i).PublicKey(), nil))
+		}
+		return ens
+	}
+
+	// the unanimity of 4 delegates
+	round := &roundCtx{delegates: delegates(4)}
+	require.True(round.isMajority(endorse(3)))
+	round.threshold, err = newThreshold(1, 1, false)
+	require.NoError(err)
+	require.False(round.isMajority(endorse(3)))
+	require.True(round.isMajority(endorse(4)))
+
+	// 15 votes of 21 delegates
+	round = &roundCtx{delegates: delegates(21)}
+	round.threshold, err = newThreshold(15, 21, false)
+	require.NoError(err)
+	require.False(round.isMajority(endorse(14)))
+	require.True(round.isMajority(endorse(15)))
+
+	// 3 of 5 delegates, which is short of the default majority
+	round = &roundCtx{delegates: delegates(5)}
+	require.False(round.isMajority(endorse(3)))
+	round.threshold, err = newThreshold(3, 5, true)
+	require.NoError(err)
+	require.False(round.isMajority(endorse(2)))
+	require.True(round.isMajority(endorse(3)))
+}
+
+func TestWeightedMajority(t *testing.T) {
+	require := require.New(t)
+	delegates := []string{}
+	for i := 0; i < 4; i++ {
+		delegates = append(delegates, identityset.Address(i).String())
+	}
+	endorse := func(ids ...int) []*endorsement.Endorsement {
+		ens := []*endorsement.Endorsement{}
+		for _, i := range ids {
+			ens = append(ens, endorsement.NewEndorsement(time.Now(), identityset.PrivateKey(i).PublicKey(), nil))
+		}
+		return ens
+	}
+	// a total weight of 100, of which the first delegate carries 60
+	round := &roundCtx{
+		delegates: delegates,
+		weights: map[string]*big.Int{
+			delegates[0]: big.NewInt(60),
+			delegates[1]: big.NewInt(10),
+			delegates[2]: big.NewInt(20),
+			delegates[3]: big.NewInt(10),
+		},
+	}
+	// 3 of the 4 delegates are short of 2/3 of the weight
+	require.False(round.isMajority(endorse(1, 2, 3)))
+	// 70 of 100 is a majority, although of 2 delegates only
+	require.True(round.isMajority(endorse(0, 3)))
+	require.False(round.isMajority(endorse(0)))
+	// an endorser weighs once, and an endorser not a delegate weighs nothing
+	require.False(round.isMajority(endorse(0, 0, 4)))
+
+	// the threshold applies to the weights
+	round.threshold = endorsementThreshold{numerator: 4, denominator: 5}
+	require.False(round.isMajority(endorse(0, 1)))
+	require.True(round.isMajority(endorse(0, 2)))
+	round.threshold = endorsementThreshold{}
+
+	// the majority is out of the weight of the delegates not on probation
+	round.probated = map[string]bool{delegates[0]: true}
+	round.countProbated = true
+	require.False(round.isMajority(endorse(1, 2, 3)))
+	round.countProbated = false
+	require.False(round.isMajority(endorse(0, 2)))
+	require.True(round.isMajority(endorse(1, 2)))
+	round.probated = nil
+
+	// the delegates weighing nothing at all count one each
+	for _, d := range delegates {
+		round.weights[d] = big.NewInt(0)
+	}
+	require.False(round.isMajority(endorse(0, 1)))
+	require.True(round.isMajority(endorse(0, 1, 2)))
+}
+
+func TestInsufficientEndorsementsError(t *testing.T) {
+	require := require.New(t)
+
+	// the required is the least reaching the threshold
+	for _, threshold := range []endorsementThreshold{{}, {numerator: 1, denominator: 1}, {numerator: 15, denominator: 21}} {
+		for total := int64(1); total <= 50; total++ {
+			required := threshold.required(big.NewInt(total))
+			require.True(threshold.reached(required, big.NewInt(total)))
+			require.False(threshold.reached(new(big.Int).Sub(required, big.NewInt(1)), big.NewInt(total)))
+		}
+	}
+
+	delegates := []string{}
+	for i := 0; i < 24; i++ {
+		delegates = append(delegates, identityset.Address(i).String())
+	}
+	blk, err := block.NewTestingBuilder().
+		SetHeight(21).
+		SetTimeStamp(time.Unix(1562382392, 0)).
+		SignAndBuild(identityset.PrivateKey(0))
+	require.NoError(err)
+	blkHash := blk.HashBlock()
+	round := &roundCtx{delegates: delegates, eManager: newEndorsementManager()}
+	require.NoError(round.AddBlock(&blk))
+	endorse := func(from, to int, topic ConsensusVoteTopic) {
+		vote := NewConsensusVote(blkHash[:], topic)
+		for i := from; i < to; i++ {
+			en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, time.Unix(1562382592, 0))
+			require.NoError(err)
+			require.NoError(round.AddVoteEndorsement(vote, en))
+		}
+	}
+	topics := []ConsensusVoteTopic{PROPOSAL, COMMIT}
+	endorse(0, 10, PROPOSAL)
+	endorse(10, 16, COMMIT)
+	err = round.checkMajority(blkHash[:], topics)
+	require.Equal(ErrInsufficientEndorsements, errors.Cause(err))
+	require.True(goerrors.Is(err, ErrInsufficientEndorsements))
+	var insufficient *InsufficientEndorsementsError
+	require.True(goerrors.As(err, &insufficient))
+	require.Equal(topics, insufficient.Topics)
+	require.False(insufficient.Weighted)
+	require.Equal(big.NewInt(16), insufficient.Endorsed)
+	require.Equal(big.NewInt(17), insufficient.Required)
+	require.Equal(big.NewInt(24), insufficient.Total)
+	require.Equal(
+		"Insufficient endorsements: 16 of 24 delegates endorsing on PROPOSAL,COMMIT, 17 required",
+		err.Error(),
+	)
+	// the topics fall short on their own
+	err = round.checkMajority(blkHash[:], []ConsensusVoteTopic{COMMIT})
+	require.True(goerrors.As(err, &insufficient))
+	require.Equal([]ConsensusVoteTopic{COMMIT}, insufficient.Topics)
+	require.Equal(big.NewInt(6), insufficient.Endorsed)
+
+	// the weights of the endorsers
+	round.weights = make(map[string]*big.Int)
+	for i, d := range delegates {
+		round.weights[d] = big.NewInt(int64(i + 1))
+	}
+	err = round.checkMajority(blkHash[:], topics)
+	require.True(goerrors.As(err, &insufficient))
+	require.True(insufficient.Weighted)
+	require.Equal(big.NewInt(136), insufficient.Endorsed)
+	require.Equal(big.NewInt(201), insufficient.Required)
+	require.Equal(big.NewInt(300), insufficient.Total)
+	require.Contains(err.Error(), "136 of 300 weight")
+	round.weights = nil
+
+	endorse(16, 17, COMMIT)
+	require.NoError(round.checkMajority(blkHash[:], topics))
+	require.True(round.EndorsedByMajority(blkHash[:], topics))
+}
+
+func TestVerifyProofOfLock(t *testing.T) {
+	require := require.New(t)
+	delegates := []string{}
+	for i := 0; i < 4; i++ {
+		delegates = append(delegates, identityset.Address(i).String())
+	}
+	blk, err := block.NewTestingBuilder().
+		SetHeight(21).
+		SetTimeStamp(time.Unix(1562382392, 0)).
+		SignAndBuild(identityset.PrivateKey(0))
+	require.NoError(err)
+	blkHash := blk.HashBlock()
+	newRound := func() *roundCtx {
+		round := &roundCtx{delegates: delegates, eManager: newEndorsementManager()}
+		require.NoError(round.AddBlock(&blk))
+		return round
+	}
+	endorse := func(i int, blkHash []byte, topic ConsensusVoteTopic) *endorsement.Endorsement {
+		en, err := endorsement.Endorse(identityset.PrivateKey(i), NewConsensusVote(blkHash, topic), time.Unix(1562382592, 0))
+		require.NoError(err)
+		return en
+	}
+
+	// 3 of 4 delegates, mixing the proposal and commit endorsements
+	proof := []*endorsement.Endorsement{
+		endorse(0, blkHash[:], PROPOSAL),
+		endorse(1, blkHash[:], COMMIT),
+		endorse(2, blkHash[:], PROPOSAL),
+	}
+	require.NoError(VerifyProofOfLock(newRound(), blkHash[:], proof))
+
+	// 2 of 4 delegates
+	err = VerifyProofOfLock(newRound(), blkHash[:], proof[:2])
+	require.Equal(ErrInsufficientEndorsements, errors.Cause(err))
+	err = VerifyProofOfLock(newRound(), blkHash[:], nil)
+	require.Equal(ErrInsufficientEndorsements, errors.Cause(err))
+
+	withThird := func(en *endorsement.Endorsement) []*endorsement.Endorsement {
+		return []*endorsement.Endorsement{proof[0], proof[1], en}
+	}
+
+	// the same delegate endorsing twice doesn't make up the majority
+	err = VerifyProofOfLock(newRound(), blkHash[:], withThird(endorse(1, blkHash[:], PROPOSAL)))
+	require.Equal(ErrDuplicateEndorser, errors.Cause(err))
+
+	// an endorsement of another block, or of a lock, is invalid
+	err = VerifyProofOfLock(newRound(), blkHash[:], withThird(endorse(2, []byte("another block"), COMMIT)))
+	require.Error(err)
+	require.NotEqual(ErrInsufficientEndorsements, errors.Cause(err))
+	err = VerifyProofOfLock(newRound(), blkHash[:], withThird(endorse(2, blkHash[:], LOCK)))
+	require.Error(err)
+	require.NotEqual(ErrInsufficientEndorsements, errors.Cause(err))
+}
@@ -0,0 +1,316 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"bytes"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/blockchain/block"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/pkg/enc"
+	"github.com/iotexproject/iotex-core/pkg/log"
+)
+
+const (
+	roundStateNamespace = "rds"
+	// roundStateVersion is the version of the serialized round state
+	roundStateVersion = 1
+)
+
+var roundStateKey = []byte("round")
+
+type (
+	// ownVote is a vote endorsed by this node
+	ownVote struct {
+		height   uint64
+		roundNum uint32
+		topic    ConsensusVoteTopic
+		blkHash  []byte
+	}
+
+	// roundState is the minimal state of the round of a height, i.e., the lock, its proof and the last vote endorsed
+	// by this node, which is persisted on each transition. A delegate restarting in the middle of the height resumes
+	// with it, such that it neither loses its lock nor endorses a vote conflicting with the one endorsed before.
+	roundState struct {
+		height      uint64
+		roundNum    uint32
+		status      status
+		blockInLock []byte
+		// lockedBlock is the block in lock, which is nil unless locked
+		lockedBlock *block.Block
+		proofOfLock []*endorsement.Endorsement
+		lastVote    *ownVote
+	}
+)
+
+// conflicts returns true if a vote of the same round is for another block
+func (v *ownVote) conflicts(height uint64, roundNum uint32, blkHash []byte) bool {
+	return v != nil && v.height == height && v.roundNum == roundNum && !bytes.Equal(v.blkHash, blkHash)
+}
+
+// digest returns the serialized state without the locked block and the proof of lock, but their size, which tells
+// whether the state changes since the last time it is persisted, as the proof is only made on a transition of the lock
+func (s *roundState) digest() []byte {
+	buf := []byte{roundStateVersion}
+	buf = append(buf, make([]byte, 13)...)
+	enc.MachineEndian.PutUint64(buf[1:], s.height)
+	enc.MachineEndian.PutUint32(buf[9:], s.roundNum)
+	buf[13] = byte(s.status)
+	buf = appendWithLength(buf, s.blockInLock)
+	if v := s.lastVote; v != nil {
+		buf = append(buf, 1, byte(v.topic), 0, 0, 0, 0)
+		enc.MachineEndian.PutUint32(buf[len(buf)-4:], v.roundNum)
+		buf = appendWithLength(buf, v.blkHash)
+	} else {
+		buf = append(buf, 0)
+	}
+	return append(buf, byte(len(s.proofOfLock)))
+}
+
+// Serialize returns the serialized bytes of the round state
+func (s *roundState) Serialize() ([]byte, error) {
+	pb := &iotextypes.BlockProposal{}
+	if s.lockedBlock != nil {
+		pb.Block = s.lockedBlock.ConvertToBlockPb()
+	}
+	for _, en := range s.proofOfLock {
+		enPb, err := en.Proto()
+		if err != nil {
+			return nil, err
+		}
+		pb.Endorsements = append(pb.Endorsements, enPb)
+	}
+	ser, err := proto.Marshal(pb)
+	if err != nil {
+		return nil, err
+	}
+	return append(s.digest(), ser...), nil
+}
+
+// Deserialize loads the round state from the serialized bytes
+func (s *roundState) Deserialize(buf []byte) error {
+	if len(buf) < 14 {
+		return errors.Errorf("round state of %d bytes is too short", len(buf))
+	}
+	if buf[0] != roundStateVersion {
+		return errors.Errorf("unsupported version %d of round state", buf[0])
+	}
+	s.height = enc.MachineEndian.Uint64(buf[1:])
+	s.roundNum = enc.MachineEndian.Uint32(buf[9:])
+	s.status = status(buf[13])
+	var err error
+	if s.blockInLock, buf, err = readWithLength(buf[14:]); err != nil {
+		return errors.Wrap(err, "failed to read block in lock")
+	}
+	if len(buf) == 0 {
+		return errors.New("round state is truncated")
+	}
+	s.lastVote = nil
+	if buf[0] == 1 {
+		if len(buf) < 6 {
+			return errors.New("last vote is truncated")
+		}
+		s.lastVote = &ownVote{
+			height:   s.height,
+			roundNum: enc.MachineEndian.Uint32(buf[2:]),
+			topic:    ConsensusVoteTopic(buf[1]),
+		}
+		if s.lastVote.blkHash, buf, err = readWithLength(buf[6:]); err != nil {
+			return errors.Wrap(err, "failed to read last vote")
+		}
+	} else {
+		buf = buf[1:]
+	}
+	// the size of the proof of lock in the digest is followed by the locked block and the proof
+	if len(buf) == 0 {
+		return errors.New("round state is truncated")
+	}
+	pb := &iotextypes.BlockProposal{}
+	if err := proto.Unmarshal(buf[1:], pb); err != nil {
+		return err
+	}
+	s.lockedBlock = nil
+	if pb.Block != nil {
+		s.lockedBlock = &block.Block{}
+		if err := s.lockedBlock.ConvertFromBlockPb(pb.Block); err != nil {
+			return errors.Wrap(err, "failed to load locked block")
+		}
+	}
+	s.proofOfLock = nil
+	for _, enPb := range pb.Endorsements {
+		en := &endorsement.Endorsement{}
+		if err := en.LoadProto(enPb); err != nil {
+			return errors.Wrap(err, "failed to load proof of lock")
+		}
+		s.proofOfLock = append(s.proofOfLock, en)
+	}
+	return nil
+}
+
+func appendWithLength(buf []byte, b []byte) []byte {
+	return append(append(buf, byte(len(b))), b...)
+}
+
+func readWithLength(buf []byte) ([]byte, []byte, error) {
+	if len(buf) == 0 || len(buf) < 1+int(buf[0]) {
+		return nil, nil, errors.New("bytes are truncated")
+	}
+	n := int(buf[0])
+	if n == 0 {
+		return nil, buf[1:], nil
+	}
+	return append([]byte{}, buf[1:1+n]...), buf[1+n:], nil
+}
+
+// roundState returns the state of the current round to persist
+func (ctx *rollDPoSCtx) roundState() *roundState {
+	s := &roundState{
+		height:      ctx.round.height,
+		roundNum:    ctx.round.roundNum,
+		status:      ctx.round.status,
+		blockInLock: ctx.round.blockInLock,
+		proofOfLock: ctx.round.proofOfLock,
+	}
+	if s.status == locked {
+		s.lockedBlock = ctx.round.Block(s.blockInLock)
+	}
+	if v := ctx.lastVote; v != nil && v.height == s.height {
+		s.lastVote = v
+	}
+	return s
+}
+
+// persistRoundState persists the state of the current round if it changes since the last time. A failure is logged
+// only, as the consensus goes on regardless, at the risk of losing the state on a restart.
+func (ctx *rollDPoSCtx) persistRoundState() {
+	if ctx.stateStore == nil {
+		return
+	}
+	ctx.stateMutex.Lock()
+	defer ctx.stateMutex.Unlock()
+	s := ctx.roundState()
+	digest := s.digest()
+	if bytes.Equal(digest, ctx.persistedDigest) {
+		return
+	}
+	ser, err := s.Serialize()
+	if err == nil {
+		err = ctx.stateStore.Put(roundStateNamespace, roundStateKey, ser)
+	}
+	if err != nil {
+		ctx.logger().Warn("Failed to persist the round state.", zap.Error(err))
+		return
+	}
+	ctx.persistedDigest = digest
+}
+
+// loadRoundState loads the persisted round state, which is restored on preparing the round of its height
+func (ctx *rollDPoSCtx) loadRoundState() error {
+	if ctx.stateStore == nil {
+		return nil
+	}
+	value, err := ctx.stateStore.Get(roundStateNamespace, roundStateKey)
+	switch errors.Cause(err) {
+	case nil:
+	case db.ErrNotExist:
+		return nil
+	default:
+		return errors.Wrap(err, "failed to load the round state")
+	}
+	s := &roundState{}
+	if err := s.Deserialize(value); err != nil {
+		// the consensus starts fresh, as it does without any persisted state
+		ctx.logger().Warn("Failed to deserialize the round state.", zap.Error(err))
+		return nil
+	}
+	ctx.stateMutex.Lock()
+	defer ctx.stateMutex.Unlock()
+	ctx.recovered = s
+	return nil
+}
+
+// restoreRoundState restores the recovered state onto the current round if they are of the same height, which is
+// done once on the first round prepared after the start
+func (ctx *rollDPoSCtx) restoreRoundState() {
+	ctx.stateMutex.Lock()
+	s := ctx.recovered
+	ctx.recovered = nil
+	ctx.stateMutex.Unlock()
+	if s == nil || s.height != ctx.round.height {
+		return
+	}
+	l := ctx.logger().With(zap.Uint64("height", s.height), zap.Uint32("round", s.roundNum))
+	if s.lastVote != nil {
+		ctx.stateMutex.Lock()
+		ctx.lastVote = s.lastVote
+		if s.lastVote.roundNum == ctx.round.roundNum {
+			ctx.recoveredVote = s.lastVote
+		}
+		ctx.stateMutex.Unlock()
+	}
+	switch s.status {
+	case locked:
+		if s.lockedBlock == nil {
+			l.Warn("The locked block is missing in the recovered round state.")
+			return
+		}
+		// the working set of the block is rebuilt to commit it
+		if err := ctx.chain.ValidateBlock(s.lockedBlock); err != nil {
+			l.Warn("The locked block in the recovered round state is invalid.", zap.Error(err))
+			return
+		}
+		if err := ctx.round.AddBlock(s.lockedBlock); err != nil {
+			l.Warn("Failed to add the locked block in the recovered round state.", zap.Error(err))
+			return
+		}
+	case unlocked:
+	default:
+		return
+	}
+	ctx.round.status = s.status
+	ctx.round.blockInLock = s.blockInLock
+	ctx.round.proofOfLock = s.proofOfLock
+	l.Info("Restored the lock of the round.", log.Hex("blockInLock", s.blockInLock))
+}
+
+// recordVote records a vote endorsed by this node, which is persisted along with the round state
+func (ctx *rollDPoSCtx) recordVote(topic ConsensusVoteTopic, blkHash []byte) {
+	if ctx.stateStore == nil {
+		return
+	}
+	ctx.stateMutex.Lock()
+	ctx.lastVote = &ownVote{
+		height:   ctx.round.height,
+		roundNum: ctx.round.roundNum,
+		topic:    topic,
+		blkHash:  blkHash,
+	}
+	ctx.stateMutex.Unlock()
+	ctx.persistRoundState()
+}
+
+// conflictsRecoveredVote returns true if a vote for a block conflicts with the one endorsed in the current round
+// before the restart, which is never endorsed
+func (ctx *rollDPoSCtx) conflictsRecoveredVote(blkHash []byte) bool {
+	ctx.stateMutex.Lock()
+	defer ctx.stateMutex.Unlock()
+	return ctx.recoveredVote.conflicts(ctx.round.height, ctx.round.roundNum, blkHash)
+}
+
+// votedBeforeRestart returns true if this node endorsed a vote in the current round before the restart
+func (ctx *rollDPoSCtx) votedBeforeRestart() bool {
+	ctx.stateMutex.Lock()
+	defer ctx.stateMutex.Unlock()
+	v := ctx.recoveredVote
+	return v != nil && v.height == ctx.round.height && v.roundNum == ctx.round.roundNum
+}
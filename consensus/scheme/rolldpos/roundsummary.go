@@ -0,0 +1,110 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// roundSummary aggregates the endorsements received in a round, which are logged in a single line at the end of the
+// round rather than one line per endorsement
+type roundSummary struct {
+	mutex     sync.Mutex
+	height    uint64
+	roundNum  uint32
+	proposer  string
+	startTime time.Time
+	received  map[ConsensusVoteTopic]int
+	rejected  map[string]int
+}
+
+func newRoundSummary(round *roundCtx) *roundSummary {
+	s := &roundSummary{}
+	s.reset(round)
+	return s
+}
+
+// reset starts the summary of a round
+func (s *roundSummary) reset(round *roundCtx) {
+	s.height = round.Height()
+	s.roundNum = round.Number()
+	s.proposer = round.Proposer()
+	s.startTime = round.StartTime()
+	s.received = make(map[ConsensusVoteTopic]int)
+	s.rejected = make(map[string]int)
+}
+
+// Receive counts an endorsement accepted in the round
+func (s *roundSummary) Receive(topic ConsensusVoteTopic) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.received[topic]++
+}
+
+// Reject counts an endorsement rejected in the round, by the cause of the error
+func (s *roundSummary) Reject(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.rejected[errors.Cause(err).Error()]++
+}
+
+// Next flushes the summary of the round if the given round is a new one, i.e., of another height or round number,
+// and then starts the summary of the new round
+func (s *roundSummary) Next(logger *zap.Logger, now time.Time, round *roundCtx) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if round.Height() == s.height && round.Number() == s.roundNum {
+		return
+	}
+	s.flush(logger, now)
+	s.reset(round)
+}
+
+// Flush logs the summary of the round if any endorsement has been received or rejected since the last flush, e.g.,
+// once the block of the round is committed
+func (s *roundSummary) Flush(logger *zap.Logger, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.flush(logger, now)
+}
+
+func (s *roundSummary) flush(logger *zap.Logger, now time.Time) {
+	if len(s.received) == 0 && len(s.rejected) == 0 {
+		return
+	}
+	numRejected := 0
+	topReason := ""
+	for reason, count := range s.rejected {
+		numRejected += count
+		// a tie is broken by the reason, such that the summary is deterministic
+		if top := s.rejected[topReason]; count > top || (count == top && reason < topReason) {
+			topReason = reason
+		}
+	}
+	logger.Info(
+		"round summary",
+		zap.Uint64("height", s.height),
+		zap.Uint32("round", s.roundNum),
+		zap.String("proposer", s.proposer),
+		zap.Int("proposalEndorsements", s.received[PROPOSAL]),
+		zap.Int("lockEndorsements", s.received[LOCK]),
+		zap.Int("commitEndorsements", s.received[COMMIT]),
+		zap.Int("rejectedEndorsements", numRejected),
+		zap.String("topRejection", topReason),
+		zap.Duration("duration", now.Sub(s.startTime)),
+	)
+	s.received = make(map[ConsensusVoteTopic]int)
+	s.rejected = make(map[string]int)
+}
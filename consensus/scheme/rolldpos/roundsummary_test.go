@@ -0,0 +1,160 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/state"
+	"github.com/iotexproject/iotex-core/test/identityset"
+)
+
+func TestRoundSummary(t *testing.T) {
+	require := require.New(t)
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core)
+	start := time.Now()
+	s := newRoundSummary(&roundCtx{height: 10, roundNum: 1, proposer: "alice", roundStartTime: start})
+
+	s.Receive(PROPOSAL)
+	s.Receive(COMMIT)
+	s.Receive(COMMIT)
+	s.Reject(errors.Wrap(errors.New("invalid endorsement"), "failed to add"))
+	s.Reject(errors.New("invalid endorsement"))
+	s.Reject(errors.New("block not received"))
+	// the round isn't over yet
+	s.Next(logger, start.Add(time.Second), &roundCtx{height: 10, roundNum: 1})
+	require.Equal(0, logs.Len())
+
+	s.Flush(logger, start.Add(2*time.Second))
+	require.Equal(1, logs.Len())
+	entry := logs.All()[0]
+	require.Equal("round summary", entry.Message)
+	require.Equal(map[string]interface{}{
+		"height":               uint64(10),
+		"round":                uint32(1),
+		"proposer":             "alice",
+		"proposalEndorsements": int64(1),
+		"lockEndorsements":     int64(0),
+		"commitEndorsements":   int64(2),
+		"rejectedEndorsements": int64(3),
+		"topRejection":         "invalid endorsement",
+		"duration":             2 * time.Second,
+	}, entry.ContextMap())
+	// nothing is logged twice
+	s.Flush(logger, start.Add(3*time.Second))
+	require.Equal(1, logs.Len())
+
+	// a late endorsement is summarized once the next round starts
+	s.Receive(COMMIT)
+	next := &roundCtx{height: 11, proposer: "bob", roundStartTime: start.Add(5 * time.Second)}
+	s.Next(logger, start.Add(4*time.Second), next)
+	require.Equal(2, logs.Len())
+	require.Equal(uint64(10), logs.All()[1].ContextMap()["height"])
+	s.Next(logger, start.Add(6*time.Second), &roundCtx{height: 11, roundNum: 1})
+	require.Equal(2, logs.Len())
+	require.Equal(uint64(11), s.height)
+	require.Equal(uint32(1), s.roundNum)
+}
+
+func TestRoundSummaryOfCtx(t *testing.T) {
+	require := require.New(t)
+	core, logs := observer.New(zapcore.DebugLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	cfg := config.Default.Consensus.RollDPoS
+	blockInterval := 20 * time.Second
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	// distinct delegates, such that the endorsers are all delegates
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	rctx, err := newRollDPoSCtx(
+		cfg, true, blockInterval, time.Second, true, b, nil, rp, nil, candidatesByHeight, "", nil, c,
+	)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+	height := rctx.round.Height()
+	blk, err := b.MintNewBlock(nil, rctx.round.StartTime())
+	require.NoError(err)
+	require.NoError(rctx.round.AddBlock(blk))
+	blkHash := blk.HashBlock()
+	vote := NewConsensusVote(blkHash[:], COMMIT)
+	// the commit votes short of a majority, and a vote with a forged endorsement
+	for i := 0; i < 3; i++ {
+		en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, rctx.round.StartTime())
+		require.NoError(err)
+		committed, err := rctx.Commit(NewEndorsedConsensusMessage(height, vote, en))
+		require.NoError(err)
+		require.False(committed)
+	}
+	en, err := endorsement.Endorse(identityset.PrivateKey(3), NewConsensusVote(blkHash[:], LOCK), rctx.round.StartTime())
+	require.NoError(err)
+	_, err = rctx.Commit(NewEndorsedConsensusMessage(height, vote, en))
+	require.Error(err)
+	hasStats := func(entry observer.LoggedEntry) bool {
+		for key := range entry.ContextMap() {
+			if strings.HasPrefix(key, "numCommits:") {
+				return true
+			}
+		}
+		return false
+	}
+	// the votes are logged at debug level without the stats of the round
+	verified := logs.FilterMessage("verified consensus vote").All()
+	require.Equal(3, len(verified))
+	for _, entry := range verified {
+		require.Equal(zapcore.DebugLevel, entry.Level)
+		require.False(hasStats(entry))
+	}
+	require.Equal(0, logs.FilterMessage("round summary").Len())
+	// which are logged along with each vote in verbose mode
+	rctx.cfg.VerboseLogging = true
+	en, err = endorsement.Endorse(identityset.PrivateKey(4), vote, rctx.round.StartTime())
+	require.NoError(err)
+	_, err = rctx.Commit(NewEndorsedConsensusMessage(height, vote, en))
+	require.NoError(err)
+	verified = logs.FilterMessage("verified consensus vote").All()
+	require.Equal(4, len(verified))
+	require.True(hasStats(verified[3]))
+
+	// the round is summarized once the next round starts
+	c.Add(blockInterval)
+	require.NoError(rctx.Prepare())
+	summaries := logs.FilterMessage("round summary").All()
+	require.Equal(1, len(summaries))
+	require.Equal(zapcore.InfoLevel, summaries[0].Level)
+	fields := summaries[0].ContextMap()
+	require.Equal(height, fields["height"])
+	require.Equal(int64(4), fields["commitEndorsements"])
+	require.Equal(int64(1), fields["rejectedEndorsements"])
+	require.Equal("invalid endorsement for the vote", fields["topRejection"])
+}
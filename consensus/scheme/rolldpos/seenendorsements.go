@@ -0,0 +1,100 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/iotexproject/iotex-core/endorsement"
+)
+
+// seenEndorsementsLimit is the max number of the vote endorsements recorded in a round, which is far more than the
+// delegates could make in a round with the topics of the votes
+const seenEndorsementsLimit = 4096
+
+var (
+	// ErrDuplicateEndorsement indicates that an endorser has endorsed the same vote in the round already
+	ErrDuplicateEndorsement = errors.New("duplicate endorsement")
+
+	duplicateEndorsementMtc = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "iotex_consensus_duplicate_endorsement",
+			Help: "Number of vote endorsements dropped as duplicates of the ones added in the round",
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(duplicateEndorsementMtc)
+}
+
+// seenEndorsements is the set of the vote endorsements added in a round, keyed by the endorser, the topic and the
+// block hash, such that an endorsement broadcast repeatedly is dropped before touching the round. It is bounded, and
+// the endorsements beyond the limit are not recorded, which are then added as if never seen.
+type seenEndorsements struct {
+	mutex sync.Mutex
+	limit int
+	keys  map[string]struct{}
+}
+
+func newSeenEndorsements(limit int) *seenEndorsements {
+	return &seenEndorsements{
+		limit: limit,
+		keys:  make(map[string]struct{}),
+	}
+}
+
+// Seen returns whether the endorser has endorsed the vote in the round
+func (s *seenEndorsements) Seen(vote *ConsensusVote, en *endorsement.Endorsement) bool {
+	if s == nil {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, ok := s.keys[seenEndorsementKey(vote, en)]
+	return ok
+}
+
+// Add records the endorsement of the vote, which should have been added to the round
+func (s *seenEndorsements) Add(vote *ConsensusVote, en *endorsement.Endorsement) {
+	s.tryAdd(seenEndorsementKey(vote, en))
+}
+
+// tryAdd records the key unless it has been seen, and returns whether it is new, which is true as well for a key
+// beyond the limit
+func (s *seenEndorsements) tryAdd(key string) bool {
+	if s == nil {
+		return true
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.keys[key]; ok {
+		return false
+	}
+	if len(s.keys) < s.limit {
+		s.keys[key] = struct{}{}
+	}
+	return true
+}
+
+// seenEndorsementKey concatenates the public key of the endorser, which is of a fixed length, the topic and the block
+// hash of the vote
+func seenEndorsementKey(vote *ConsensusVote, en *endorsement.Endorsement) string {
+	endorser := en.Endorser().Bytes()
+	blkHash := vote.BlockHash()
+	key := make([]byte, 0, len(endorser)+1+len(blkHash))
+	key = append(key, endorser...)
+	key = append(key, byte(vote.Topic()))
+	key = append(key, blkHash...)
+	return string(key)
+}
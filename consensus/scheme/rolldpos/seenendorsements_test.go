@@ -0,0 +1,132 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/pkg/errors"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/state"
+	"github.com/iotexproject/iotex-core/test/identityset"
+)
+
+func TestSeenEndorsements(t *testing.T) {
+	require := require.New(t)
+	blkHash := hash.Hash256b([]byte("block"))
+	vote := NewConsensusVote(blkHash[:], PROPOSAL)
+	en := endorsement.NewEndorsement(time.Now(), identityset.PrivateKey(0).PublicKey(), nil)
+
+	var nilSet *seenEndorsements
+	nilSet.Add(vote, en)
+	require.False(nilSet.Seen(vote, en))
+
+	s := newSeenEndorsements(2)
+	require.False(s.Seen(vote, en))
+	s.Add(vote, en)
+	require.True(s.Seen(vote, en))
+	// a later endorsement of the same vote is a duplicate
+	require.True(s.Seen(vote, endorsement.NewEndorsement(time.Now().Add(time.Second), en.Endorser(), nil)))
+	// the endorsements of another endorser, topic or block are not
+	require.False(s.Seen(vote, endorsement.NewEndorsement(time.Now(), identityset.PrivateKey(1).PublicKey(), nil)))
+	require.False(s.Seen(NewConsensusVote(blkHash[:], LOCK), en))
+	require.False(s.Seen(NewConsensusVote(nil, PROPOSAL), en))
+
+	// the endorsements beyond the limit are not recorded
+	s.Add(NewConsensusVote(blkHash[:], LOCK), en)
+	s.Add(NewConsensusVote(blkHash[:], COMMIT), en)
+	require.True(s.Seen(NewConsensusVote(blkHash[:], LOCK), en))
+	require.False(s.Seen(NewConsensusVote(blkHash[:], COMMIT), en))
+
+	// a key is new once, unless it is beyond the limit
+	require.False(s.tryAdd(seenEndorsementKey(vote, en)))
+	require.True(s.tryAdd("beyond"))
+	require.True(s.tryAdd("beyond"))
+	require.True(nilSet.tryAdd("nil"))
+}
+
+func TestDuplicateEndorsement(t *testing.T) {
+	require := require.New(t)
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	rctx, err := newRollDPoSCtx(
+		config.Default.Consensus.RollDPoS,
+		true,
+		20*time.Second,
+		time.Second,
+		true,
+		b,
+		nil,
+		rp,
+		nil,
+		candidatesByHeight,
+		"",
+		nil,
+		c,
+	)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+	height := rctx.round.Height()
+	blk, err := b.MintNewBlock(nil, rctx.round.StartTime())
+	require.NoError(err)
+	blkHash := blk.HashBlock()
+	vote := NewConsensusVote(blkHash[:], COMMIT)
+	en, err := endorsement.Endorse(identityset.PrivateKey(0), vote, rctx.round.StartTime())
+	require.NoError(err)
+	msg := NewEndorsedConsensusMessage(height, vote, en)
+	topics := []ConsensusVoteTopic{COMMIT}
+
+	// an endorsement failing to be added is not recorded
+	_, err = rctx.verifyVote(msg, topics)
+	require.Equal(ReasonBlockNotReceived, RejectionReasonOf(err))
+	_, err = rctx.verifyVote(msg, topics)
+	require.Equal(ReasonBlockNotReceived, RejectionReasonOf(err))
+
+	require.NoError(rctx.round.AddBlock(blk))
+	_, err = rctx.verifyVote(msg, topics)
+	require.Equal(ErrInsufficientEndorsements, errors.Cause(err))
+	require.Equal(1, len(rctx.round.endorsements(blkHash[:], topics)))
+
+	// the same endorsement fed again is dropped
+	dropped := promtestutil.ToFloat64(duplicateEndorsementMtc.WithLabelValues())
+	_, err = rctx.verifyVote(msg, topics)
+	require.Equal(ErrDuplicateEndorsement, errors.Cause(err))
+	require.Equal(ReasonDuplicateEndorsement, RejectionReasonOf(err))
+	require.Equal(dropped+1, promtestutil.ToFloat64(duplicateEndorsementMtc.WithLabelValues()))
+	committed, err := rctx.Commit(NewEndorsedConsensusMessage(height, vote, en))
+	require.NoError(err)
+	require.False(committed)
+	require.Equal(dropped+2, promtestutil.ToFloat64(duplicateEndorsementMtc.WithLabelValues()))
+	require.Equal(1, len(rctx.round.endorsements(blkHash[:], topics)))
+
+	// the set is reset along with the round
+	c.Add(20 * time.Second)
+	require.NoError(rctx.Prepare())
+	require.False(rctx.seen.Seen(vote, en))
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/pkg/log"
+)
+
+var voteVerifierMtc = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "iotex_consensus_vote_verifier",
+		Help: "Consensus votes verified by the vote verifier pool, by the result",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prometheus.MustRegister(voteVerifierMtc)
+}
+
+// voteVerifier verifies the signatures of the incoming votes in a pool of workers, off the goroutine driving the
+// consensus FSM. A vote with a valid signature is marked as verified and handed over, such that the FSM doesn't
+// verify it again, while a vote with an invalid signature is dropped.
+type voteVerifier struct {
+	workers int
+	queue   chan submittedVote
+	handle  func(*EndorsedConsensusMessage)
+	// report reports the outcome of the verification to the peer relaying the vote, and is nil if not needed
+	report func(peerID string, err error)
+	close  chan struct{}
+	wg     sync.WaitGroup
+}
+
+// submittedVote is a vote to verify, along with the peer relaying it
+type submittedVote struct {
+	msg    *EndorsedConsensusMessage
+	peerID string
+}
+
+func newVoteVerifier(workers int, queueSize int, handle func(*EndorsedConsensusMessage)) *voteVerifier {
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	return &voteVerifier{
+		workers: workers,
+		queue:   make(chan submittedVote, queueSize),
+		handle:  handle,
+		close:   make(chan struct{}),
+	}
+}
+
+// Start starts the workers
+func (v *voteVerifier) Start() {
+	for i := 0; i < v.workers; i++ {
+		v.wg.Add(1)
+		go v.run()
+	}
+}
+
+// Stop stops the workers, and the votes still in the queue are discarded
+func (v *voteVerifier) Stop() {
+	close(v.close)
+	v.wg.Wait()
+}
+
+// Submit queues a vote relayed by a peer to verify, which blocks while the queue is full. The peer ID is empty if the
+// vote is not from the network. It returns false if the verifier is stopped.
+func (v *voteVerifier) Submit(msg *EndorsedConsensusMessage, peerID string) bool {
+	select {
+	case <-v.close:
+		return false
+	default:
+	}
+	select {
+	case <-v.close:
+		return false
+	case v.queue <- submittedVote{msg: msg, peerID: peerID}:
+		return true
+	}
+}
+
+func (v *voteVerifier) run() {
+	defer v.wg.Done()
+	for {
+		select {
+		case <-v.close:
+			return
+		case vote := <-v.queue:
+			msg := vote.msg
+			if !endorsement.VerifyEndorsedDocument(msg) {
+				voteVerifierMtc.WithLabelValues("invalid").Inc()
+				log.Logger("consensus").Debug(
+					"dropped a vote with an invalid signature",
+					zap.Uint64("height", msg.Height()),
+					zap.String("endorser", msg.Endorsement().Endorser().HexString()),
+				)
+				if v.report != nil {
+					v.report(vote.peerID, reject(ReasonInvalidSignature, errors.New("failed to verify signature in endorsement")))
+				}
+				continue
+			}
+			voteVerifierMtc.WithLabelValues("valid").Inc()
+			if v.report != nil {
+				v.report(vote.peerID, nil)
+			}
+			msg.verified = true
+			v.handle(msg)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/iotexproject/go-pkgs/hash"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/blockchain/block"
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/endorsement"
+	"github.com/iotexproject/iotex-core/state"
+	"github.com/iotexproject/iotex-core/test/identityset"
+	"github.com/iotexproject/iotex-core/testutil"
+)
+
+func TestVoteVerifier(t *testing.T) {
+	require := require.New(t)
+	handled := make(chan *EndorsedConsensusMessage, 10)
+	v := newVoteVerifier(2, 10, func(msg *EndorsedConsensusMessage) {
+		handled <- msg
+	})
+	v.Start()
+
+	ts := time.Now()
+	blkHash := hash.Hash256b([]byte("block"))
+	vote := NewConsensusVote(blkHash[:], COMMIT)
+	en, err := endorsement.Endorse(identityset.PrivateKey(0), vote, ts)
+	require.NoError(err)
+	valid := NewEndorsedConsensusMessage(1, vote, en)
+	// an endorsement of another vote
+	en, err = endorsement.Endorse(identityset.PrivateKey(1), NewConsensusVote(blkHash[:], LOCK), ts)
+	require.NoError(err)
+	invalid := NewEndorsedConsensusMessage(1, vote, en)
+
+	numInvalid := promtestutil.ToFloat64(voteVerifierMtc.WithLabelValues("invalid"))
+	require.True(v.Submit(invalid, ""))
+	require.True(v.Submit(valid, ""))
+	select {
+	case msg := <-handled:
+		require.Equal(valid, msg)
+		require.True(msg.verified)
+	case <-time.After(5 * time.Second):
+		require.FailNow("the valid vote isn't handled")
+	}
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return promtestutil.ToFloat64(voteVerifierMtc.WithLabelValues("invalid")) == numInvalid+1, nil
+	}))
+	// the invalid vote is dropped
+	require.Equal(0, len(handled))
+	require.False(invalid.verified)
+
+	v.Stop()
+	require.False(v.Submit(valid, ""))
+}
+
+func TestVerifiedVote(t *testing.T) {
+	require := require.New(t)
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Add(30 * time.Second).Sub(c.Now()))
+	candidates := []*state.Candidate{}
+	for i := 0; i < int(config.Default.Genesis.NumDelegates); i++ {
+		candidates = append(candidates, &state.Candidate{
+			Address:       identityset.Address(i).String(),
+			Votes:         big.NewInt(int64(100 - i)),
+			RewardAddress: identityset.Address(i).String(),
+		})
+	}
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return candidates, nil
+	}
+	rctx, err := newRollDPoSCtx(
+		config.Default.Consensus.RollDPoS,
+		true,
+		20*time.Second,
+		time.Second,
+		true,
+		b,
+		nil,
+		rp,
+		nil,
+		candidatesByHeight,
+		"",
+		nil,
+		c,
+	)
+	require.NoError(err)
+	require.NoError(rctx.Prepare())
+	height := rctx.round.Height()
+	blk, err := b.MintNewBlock(nil, rctx.round.StartTime())
+	require.NoError(err)
+	require.NoError(rctx.round.AddBlock(blk))
+	blkHash := blk.HashBlock()
+	vote := NewConsensusVote(blkHash[:], COMMIT)
+	en, err := endorsement.Endorse(identityset.PrivateKey(0), NewConsensusVote(blkHash[:], LOCK), rctx.round.StartTime())
+	require.NoError(err)
+
+	// the signature is verified by the FSM unless the vote is verified by the pool
+	_, err = rctx.Commit(NewEndorsedConsensusMessage(height, vote, en))
+	require.Error(err)
+	require.Equal(0, len(rctx.round.endorsements(blkHash[:], []ConsensusVoteTopic{COMMIT})))
+	msg := NewEndorsedConsensusMessage(height, vote, en)
+	msg.verified = true
+	_, err = rctx.Commit(msg)
+	require.NoError(err)
+	require.Equal(1, len(rctx.round.endorsements(blkHash[:], []ConsensusVoteTopic{COMMIT})))
+}
+
+func BenchmarkVoteIngest(b *testing.B) {
+	require := require.New(b)
+	// the votes of the delegates on a block
+	delegates := []string{}
+	for i := 0; i < 24; i++ {
+		delegates = append(delegates, identityset.Address(i).String())
+	}
+	blk, err := block.NewTestingBuilder().
+		SetHeight(1).
+		SignAndBuild(identityset.PrivateKey(0))
+	require.NoError(err)
+	blkHash := blk.HashBlock()
+	vote := NewConsensusVote(blkHash[:], COMMIT)
+	ens := []*endorsement.Endorsement{}
+	for i := range delegates {
+		en, err := endorsement.Endorse(identityset.PrivateKey(i), vote, time.Now())
+		require.NoError(err)
+		ens = append(ens, en)
+	}
+
+	for _, workers := range []int{0, runtime.NumCPU()} {
+		b.Run(fmt.Sprintf("workers-%d", workers), func(b *testing.B) {
+			round := &roundCtx{delegates: delegates, eManager: newEndorsementManager()}
+			require.NoError(round.AddBlock(&blk))
+			// the goroutine driving the FSM, which adds the votes into the round
+			fsm := make(chan *EndorsedConsensusMessage, 1000)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < b.N; i++ {
+					msg := <-fsm
+					addVoteEndorsement := round.AddVoteEndorsement
+					if msg.verified {
+						addVoteEndorsement = round.addVerifiedVoteEndorsement
+					}
+					if err := addVoteEndorsement(vote, msg.Endorsement()); err != nil {
+						b.Error(err)
+					}
+				}
+			}()
+			submit := func(msg *EndorsedConsensusMessage) {
+				fsm <- msg
+			}
+			if workers > 0 {
+				v := newVoteVerifier(workers, 1000, submit)
+				v.Start()
+				defer v.Stop()
+				submit = func(msg *EndorsedConsensusMessage) {
+					v.Submit(msg, "")
+				}
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				submit(NewEndorsedConsensusMessage(1, vote, ens[i%len(ens)]))
+			}
+			<-done
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"sync"
+	"time"
+
+	fsm "github.com/iotexproject/go-fsm"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/consensus/consensusfsm"
+	"github.com/iotexproject/iotex-core/pkg/log"
+)
+
+var watchdogUnstickMtc = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "iotex_consensus_watchdog_unsticks",
+		Help: "Number of times the consensus FSM stuck in a state is moved back to prepare by the watchdog",
+	},
+	[]string{"state"},
+)
+
+func init() {
+	prometheus.MustRegister(watchdogUnstickMtc)
+}
+
+type (
+	// SyncTargetFunc returns the height the block sync is catching up to
+	SyncTargetFunc func() uint64
+
+	// stuckFSM is the part of the consensus FSM watched by the watchdog
+	stuckFSM interface {
+		CurrentStateSince() (fsm.State, time.Time)
+		Unstick()
+	}
+
+	// watchdog moves the consensus FSM back to the prepare state, if it stays in another state without a block
+	// committed for a number of block intervals. It checks the FSM once every block interval.
+	watchdog struct {
+		cfsm           stuckFSM
+		ctx            *rollDPoSCtx
+		stallIntervals uint64
+
+		mutex      sync.RWMutex
+		syncTarget SyncTargetFunc
+
+		// unstuckSince is the time the FSM entered the state it was last moved out of, so that the watchdog fires
+		// once per stuck state
+		unstuckSince time.Time
+		close        chan interface{}
+		wg           sync.WaitGroup
+	}
+)
+
+func newWatchdog(cfsm stuckFSM, ctx *rollDPoSCtx, stallIntervals uint64) *watchdog {
+	return &watchdog{
+		cfsm:           cfsm,
+		ctx:            ctx,
+		stallIntervals: stallIntervals,
+		close:          make(chan interface{}),
+	}
+}
+
+// SetSyncTarget sets the func returning the target height of the block sync, which holds off the watchdog while the
+// chain tip is below it
+func (w *watchdog) SetSyncTarget(syncTarget SyncTargetFunc) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.syncTarget = syncTarget
+}
+
+// Start starts checking the FSM, unless the watchdog is disabled
+func (w *watchdog) Start() {
+	if w.stallIntervals == 0 {
+		return
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := w.ctx.clock.Ticker(w.ctx.roundCalc.blockInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.close:
+				return
+			case <-ticker.C:
+				w.check()
+			}
+		}
+	}()
+}
+
+// Stop stops checking the FSM
+func (w *watchdog) Stop() {
+	close(w.close)
+	w.wg.Wait()
+}
+
+// check moves the FSM back to the prepare state if it is stuck, and returns whether it did
+func (w *watchdog) check() bool {
+	if !w.ctx.Active() || w.syncing() {
+		return false
+	}
+	state, since := w.cfsm.CurrentStateSince()
+	if state == consensusfsm.InitState || since.Equal(w.unstuckSince) {
+		return false
+	}
+	lastCommitTime, err := w.ctx.lastCommitTime()
+	if err != nil {
+		log.Logger("consensus").Warn("Failed to get the tip block footer.", zap.Error(err))
+		return false
+	}
+	maxStall := time.Duration(w.stallIntervals) * w.ctx.roundCalc.blockInterval
+	now := w.ctx.clock.Now()
+	if now.Sub(since) <= maxStall || now.Sub(lastCommitTime) <= maxStall {
+		return false
+	}
+	log.Logger("consensus").Warn(
+		"Consensus FSM is stuck, moving it back to prepare.",
+		zap.String("state", string(state)),
+		zap.Duration("stuckFor", now.Sub(since)),
+		zap.Time("lastCommitTime", lastCommitTime),
+	)
+	watchdogUnstickMtc.WithLabelValues(string(state)).Inc()
+	w.unstuckSince = since
+	w.cfsm.Unstick()
+	return true
+}
+
+// syncing tells whether the chain tip is below the target height of the block sync
+func (w *watchdog) syncing() bool {
+	w.mutex.RLock()
+	syncTarget := w.syncTarget
+	w.mutex.RUnlock()
+
+	return syncTarget != nil && syncTarget() > w.ctx.chain.TipHeight()
+}
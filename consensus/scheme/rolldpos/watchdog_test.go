@@ -0,0 +1,99 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package rolldpos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	fsm "github.com/iotexproject/go-fsm"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/consensus/consensusfsm"
+	"github.com/iotexproject/iotex-core/state"
+)
+
+// fakeStuckFSM stays in a state until it is unstuck, which moves it back to prepare
+type fakeStuckFSM struct {
+	clock   clock.Clock
+	state   fsm.State
+	since   time.Time
+	unstuck int
+}
+
+func (f *fakeStuckFSM) CurrentStateSince() (fsm.State, time.Time) { return f.state, f.since }
+
+func (f *fakeStuckFSM) Unstick() {
+	f.unstuck++
+	f.state = consensusfsm.InitState
+	f.since = f.clock.Now()
+}
+
+func (f *fakeStuckFSM) enter(state fsm.State) {
+	f.state = state
+	f.since = f.clock.Now()
+}
+
+func TestWatchdog(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default.Consensus.RollDPoS
+	blockInterval := 10 * time.Second
+	b, rp := makeChain(t)
+	footer, err := b.BlockFooterByHeight(b.TipHeight())
+	require.NoError(err)
+	c := clock.NewMock()
+	c.Add(footer.CommitTime().Sub(c.Now()))
+	candidatesByHeight := func(uint64) ([]*state.Candidate, error) {
+		return b.CandidatesByHeight(1)
+	}
+	rctx, err := newRollDPoSCtx(cfg, true, blockInterval, time.Second, true, b, nil, rp, nil, candidatesByHeight, "", nil, c)
+	require.NoError(err)
+	cfsm := &fakeStuckFSM{clock: c, state: consensusfsm.InitState, since: c.Now()}
+	w := newWatchdog(cfsm, rctx, 3)
+	maxStall := 3 * blockInterval
+	unsticks := func(state fsm.State) float64 {
+		return testutil.ToFloat64(watchdogUnstickMtc.WithLabelValues(string(state)))
+	}
+	stuckState := fsm.State("S_ACCEPT_LOCK_ENDORSEMENT")
+	base := unsticks(stuckState)
+
+	// the FSM sitting in prepare isn't stuck
+	c.Add(2 * maxStall)
+	require.False(w.check())
+
+	// the FSM entering another state isn't stuck until the stall intervals pass
+	cfsm.enter(stuckState)
+	c.Add(maxStall)
+	require.False(w.check())
+	c.Add(time.Second)
+
+	// not while the node is inactive or syncing
+	rctx.Activate(false)
+	require.False(w.check())
+	rctx.Activate(true)
+	syncTarget := b.TipHeight() + 1
+	w.SetSyncTarget(func() uint64 { return syncTarget })
+	require.False(w.check())
+	syncTarget = b.TipHeight()
+	require.Zero(cfsm.unstuck)
+
+	// the stuck FSM is moved back to prepare
+	require.True(w.check())
+	require.Equal(1, cfsm.unstuck)
+	require.Equal(consensusfsm.InitState, cfsm.state)
+	require.Equal(base+1, unsticks(stuckState))
+
+	// the watchdog fires once per stuck state
+	cfsm.state, cfsm.since = stuckState, c.Now().Add(-2*maxStall)
+	require.True(w.check())
+	require.False(w.check())
+	require.Equal(2, cfsm.unstuck)
+	require.Equal(base+2, unsticks(stuckState))
+}
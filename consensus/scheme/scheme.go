@@ -7,6 +7,8 @@
 package scheme
 
 import (
+	"context"
+
 	"github.com/golang/protobuf/proto"
 
 	"github.com/iotexproject/iotex-core/blockchain/block"
@@ -29,11 +31,20 @@ type BroadcastCB func(*block.Block) error
 // Broadcast sends a broadcast message to the whole network
 type Broadcast func(msg proto.Message) error
 
+// PeerScoreReporter receives the outcomes of validating the consensus messages relayed by the peers, such that the
+// peers relaying too many invalid messages can be deprioritized
+type PeerScoreReporter interface {
+	// ReportValid reports a valid message relayed by a peer
+	ReportValid(peerID string)
+	// ReportInvalid reports an invalid message relayed by a peer, along with the reason of the rejection
+	ReportInvalid(peerID string, reason string)
+}
+
 // Scheme is the interface that consensus schemes should implement
 type Scheme interface {
 	lifecycle.StartStopper
 
-	HandleConsensusMsg(msg *iotextypes.ConsensusMessage) error
+	HandleConsensusMsg(ctx context.Context, msg *iotextypes.ConsensusMessage) error
 	Calibrate(uint64)
 	ValidateBlockFooter(*block.Block) error
 	Metrics() (ConsensusMetrics, error)
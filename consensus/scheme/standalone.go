@@ -73,7 +73,7 @@ func (s *Standalone) Stop(ctx context.Context) error {
 }
 
 // HandleConsensusMsg handles incoming consensus message
-func (s *Standalone) HandleConsensusMsg(msg *iotextypes.ConsensusMessage) error {
+func (s *Standalone) HandleConsensusMsg(ctx context.Context, msg *iotextypes.ConsensusMessage) error {
 	log.L().Warn("Noop scheme does not handle incoming block propose requests.")
 	return nil
 }
@@ -0,0 +1,933 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ZeroIndex is the key of the record which stores the count and the offset of a counting index
+	ZeroIndex = make([]byte, 8)
+	// ErrIndexClosed indicates the counting index has been closed
+	ErrIndexClosed = errors.New("counting index is closed")
+
+	// bucketLocks holds the lock serializing the writes to each bucket, shared by all the counting indexes of it
+	bucketLocks sync.Map
+	// countingIndexes registers the namespaces of the counting indexes created on each KV store, which are the ones
+	// AllCountingIndexStats reports
+	countingIndexes sync.Map
+)
+
+type (
+	// CountingIndex is a bucket of values at consecutive positions starting from 0. The front of the index can be
+	// pruned, while the remaining values keep their positions. It is safe for concurrent use, and the reads are
+	// exact: they reflect all the writes which have returned, and none which has not been committed yet. All the
+	// methods but Namespace return ErrIndexClosed after the index is closed.
+	CountingIndex interface {
+		// Namespace returns the bucket of the index
+		Namespace() string
+		// BucketName returns the name of the bucket of the index
+		BucketName() []byte
+		// Size returns the number of values ever added, including the pruned ones
+		Size() (uint64, error)
+		// Offset returns the position of the first value which has not been pruned
+		Offset() (uint64, error)
+		// Add appends a value to the index
+		Add([]byte) error
+		// Get returns the value at a position
+		Get(uint64) ([]byte, error)
+		// First returns the oldest value which has not been pruned
+		First() ([]byte, error)
+		// Last returns the newest value
+		Last() ([]byte, error)
+		// Range returns count values starting from a position
+		Range(uint64, uint64) ([][]byte, error)
+		// SnapshotRange returns up to count values ending a number of values before the newest one, along with the
+		// size they are read at, all read in one transaction. It requires the KV store of the index to support
+		// snapshot reads.
+		SnapshotRange(uint64, uint64) ([][]byte, uint64, error)
+		// RangeWithIndex returns count values starting from a position along with their positions
+		RangeWithIndex(uint64, uint64) ([]uint64, [][]byte, error)
+		// RangeFiltered examines count values starting from a position, and returns up to max of them which begin
+		// with a prefix
+		RangeFiltered(uint64, uint64, []byte, int) ([][]byte, error)
+		// Stream writes count values starting from a position to a writer, each followed by a separator
+		Stream(uint64, uint64, io.Writer, []byte) error
+		// PruneFront deletes the values before a position, at most batchSize values per commit. It returns the
+		// number of values deleted.
+		PruneFront(uint64, int) (uint64, error)
+		// Clone copies the values and the count of the index to an empty bucket of another KV store, or the same one.
+		// The copy is a consistent snapshot, which requires the KV store of the index to support snapshot reads.
+		Clone(KVStore, []byte) error
+		// Verify checks that there is a value at each position from the offset to the size, and no stray record
+		// beyond them. It requires the KV store of the index to support snapshot reads.
+		Verify() error
+		// Rewrite rewrites the values from the offset densely in their order in one commit, dropping the gaps and the
+		// stray records, such that Verify passes afterward. The values after a gap move to lower positions.
+		Rewrite() error
+		// Stats returns the number of the values and the disk usage of the index
+		Stats(...StatsOption) (CountingIndexStats, error)
+		// Close closes the index, which waits for the ongoing write. It could be called more than once.
+		Close() error
+	}
+
+	// ReverseCountingIndex is a counting index which also looks up the position of a value, by a second bucket mapping
+	// the hash of each value to its position. Only the values added through a ReverseCountingIndex are looked up, so
+	// all the counting indexes of the bucket have to be created by NewCountingIndexWithReverse. Clone copies the values
+	// without the reverse lookup.
+	ReverseCountingIndex interface {
+		CountingIndex
+		// IndexOf returns the position of a value, and false if the value is not in the index. The position of the
+		// latest add is returned for a value added more than once.
+		IndexOf([]byte) (uint64, bool, error)
+	}
+
+	// SnapshotKVStore is a KV store which can read all the records of a namespace in a consistent snapshot
+	SnapshotKVStore interface {
+		KVStore
+		// ForEach calls a func with each record of a namespace, all read in one transaction
+		ForEach(string, func([]byte, []byte) error) error
+		// View calls a func with a getter of the records of a namespace, all read in one transaction. The getter
+		// returns nil for a missing key, and the values it returns are only valid within the func.
+		View(string, func(func([]byte) []byte) error) error
+	}
+
+	// StatsKVStore is a KV store which can compute the stats of its counting indexes in a read transaction, along with
+	// the page stats of their buckets
+	StatsKVStore interface {
+		KVStore
+		// CountingIndexStats returns the stats of the counting index of a namespace
+		CountingIndexStats(string, ...StatsOption) (CountingIndexStats, error)
+		// AllCountingIndexStats returns the stats of all the counting indexes created on the KV store, keyed by the
+		// namespaces
+		AllCountingIndexStats(...StatsOption) (map[string]CountingIndexStats, error)
+	}
+
+	// CountingIndexStats is the number of the values and the disk usage of a counting index
+	CountingIndexStats struct {
+		// Entries is the number of the values which have not been pruned
+		Entries uint64
+		// Inspected is the number of the values read to compute the stats, fewer than Entries in the sampled mode
+		Inspected uint64
+		// ValueBytes is the total size of the values, which is extrapolated from the inspected ones in the sampled mode
+		ValueBytes uint64
+		// AvgValueSize is the average size of the inspected values
+		AvgValueSize float64
+		// BranchPages, LeafPages and OverflowPages are the numbers of the pages of the bucket, which are zero unless
+		// requested by StatsPagesOption from a KV store keeping the pages
+		BranchPages   int
+		LeafPages     int
+		OverflowPages int
+		// AllocBytes is the size of the pages allocated for the bucket, and InuseBytes is the part in use
+		AllocBytes int
+		InuseBytes int
+	}
+
+	// StatsOption sets an option of computing the stats of the counting indexes
+	StatsOption func(*statsConfig)
+
+	statsConfig struct {
+		sampleEvery uint64
+		pages       bool
+	}
+
+	// countingIndex stores the value at position i with key i+1 in big endian, and the count and offset at ZeroIndex
+	countingIndex struct {
+		mutex   *sync.Mutex
+		closed  int32
+		kvStore KVStore
+		ns      string
+		// reverseNs is the bucket mapping the hash of each value to its position, empty without the reverse lookup
+		reverseNs string
+	}
+
+	// reverseEntry is a value and its position, stored in full under the hash of the value to tell apart the values
+	// of the same hash
+	reverseEntry struct {
+		pos   uint64
+		value []byte
+	}
+
+	bucketKey struct {
+		kvStore KVStore
+		ns      string
+	}
+)
+
+// StatsSampleOption sets the sampled mode, which inspects every nth value only, such that computing the stats of a
+// huge index is bounded. All the values are inspected if n is 0 or 1.
+func StatsSampleOption(n uint64) StatsOption {
+	return func(cfg *statsConfig) {
+		cfg.sampleEvery = n
+	}
+}
+
+// StatsPagesOption adds the page stats of the bucket, which walks all the pages of it and is much more costly than
+// the sampled values
+func StatsPagesOption() StatsOption {
+	return func(cfg *statsConfig) {
+		cfg.pages = true
+	}
+}
+
+// NewCountingIndex returns a counting index stored in the bucket of the KV store. The writes of all the counting
+// indexes of a bucket are serialized, such that each write reads and updates the committed count.
+func NewCountingIndex(kvStore KVStore, namespace string) (CountingIndex, error) {
+	if kvStore == nil {
+		return nil, errors.New("kvStore is nil")
+	}
+	if namespace == "" {
+		return nil, errors.New("namespace is empty")
+	}
+	key := bucketKey{kvStore: kvStore, ns: namespace}
+	mutex, _ := bucketLocks.LoadOrStore(key, &sync.Mutex{})
+	countingIndexes.Store(key, struct{}{})
+	return &countingIndex{
+		mutex:   mutex.(*sync.Mutex),
+		kvStore: kvStore,
+		ns:      namespace,
+	}, nil
+}
+
+// NewCountingIndexWithReverse returns a counting index stored in the bucket of the KV store like NewCountingIndex,
+// which also maintains the reverse lookup from the values to their positions in a second bucket
+func NewCountingIndexWithReverse(kvStore KVStore, namespace string) (ReverseCountingIndex, error) {
+	index, err := NewCountingIndex(kvStore, namespace)
+	if err != nil {
+		return nil, err
+	}
+	c := index.(*countingIndex)
+	c.reverseNs = reverseNamespace(namespace)
+	return c, nil
+}
+
+// Namespace returns the bucket of the index
+func (c *countingIndex) Namespace() string {
+	return c.ns
+}
+
+// BucketName returns the name of the bucket of the index
+func (c *countingIndex) BucketName() []byte {
+	return []byte(c.ns)
+}
+
+// Size returns the number of values ever added, including the pruned ones
+func (c *countingIndex) Size() (uint64, error) {
+	size, _, err := c.header()
+	return size, err
+}
+
+// Offset returns the position of the first value which has not been pruned
+func (c *countingIndex) Offset() (uint64, error) {
+	_, offset, err := c.header()
+	return offset, err
+}
+
+// Add appends a value to the index
+func (c *countingIndex) Add(value []byte) error {
+	if err := checkWritable(c.kvStore); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// the count is read from the store rather than cached, since other indexes of the bucket may have added values
+	size, offset, err := c.header()
+	if err != nil {
+		return err
+	}
+	batch := NewBatch()
+	batch.Put(c.ns, positionKey(size), value, "failed to add value at %d", size)
+	batch.Put(c.ns, ZeroIndex, encodeHeader(size+1, offset), "failed to update the count of %s", c.ns)
+	if c.reverseNs != "" {
+		key := hash.Hash256b(value)
+		entries, err := c.reverseEntries(key[:])
+		if err != nil {
+			return err
+		}
+		entries = setReverseEntry(entries, value, size)
+		batch.Put(c.reverseNs, key[:], encodeReverseEntries(entries), "failed to add reverse lookup of %d", size)
+	}
+	return c.kvStore.Commit(batch)
+}
+
+// IndexOf returns the position of a value, and false if the value is not in the index or has been pruned
+func (c *countingIndex) IndexOf(value []byte) (uint64, bool, error) {
+	if c.reverseNs == "" {
+		return 0, false, errors.Errorf("counting index %s has no reverse lookup", c.ns)
+	}
+	_, offset, err := c.header()
+	if err != nil {
+		return 0, false, err
+	}
+	key := hash.Hash256b(value)
+	entries, err := c.reverseEntries(key[:])
+	if err != nil {
+		return 0, false, err
+	}
+	for _, e := range entries {
+		if bytes.Equal(e.value, value) {
+			return e.pos, e.pos >= offset, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// Get returns the value at a position
+func (c *countingIndex) Get(pos uint64) ([]byte, error) {
+	size, offset, err := c.header()
+	if err != nil {
+		return nil, err
+	}
+	if pos >= size {
+		return nil, errors.Wrapf(ErrNotExist, "position %d is out of bound %d", pos, size)
+	}
+	if pos < offset {
+		return nil, errors.Wrapf(ErrNotExist, "position %d has been pruned, offset = %d", pos, offset)
+	}
+	return c.kvStore.Get(c.ns, positionKey(pos))
+}
+
+// First returns the oldest value which has not been pruned, i.e., the value at position 0 unless the front of the
+// index is pruned. It returns ErrNotExist if the index holds no value.
+func (c *countingIndex) First() ([]byte, error) {
+	size, offset, err := c.header()
+	if err != nil {
+		return nil, err
+	}
+	if offset >= size {
+		return nil, errors.Wrapf(ErrNotExist, "counting index %s is empty", c.ns)
+	}
+	return c.kvStore.Get(c.ns, positionKey(offset))
+}
+
+// Last returns the newest value, i.e., the value at position Size()-1. It returns ErrNotExist if the index holds no
+// value.
+func (c *countingIndex) Last() ([]byte, error) {
+	size, offset, err := c.header()
+	if err != nil {
+		return nil, err
+	}
+	if offset >= size {
+		return nil, errors.Wrapf(ErrNotExist, "counting index %s is empty", c.ns)
+	}
+	return c.kvStore.Get(c.ns, positionKey(size-1))
+}
+
+// Range returns count values starting from a position. The bounds are checked against the count read from the store
+// rather than cached, yet in another read than the values, such that a window computed from Size() may shift by the
+// values added in between. SnapshotRange reads the count and the values in one transaction instead.
+func (c *countingIndex) Range(start, count uint64) ([][]byte, error) {
+	if err := c.checkRange(start, count); err != nil {
+		return nil, err
+	}
+	values := make([][]byte, 0, count)
+	for pos := start; pos < start+count; pos++ {
+		value, err := c.kvStore.Get(c.ns, positionKey(pos))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// SnapshotRange returns the values in [size-fromEnd-count, size-fromEnd) along with the size, where size is the count
+// read in the same transaction as the values, such that values[i] is at position size-fromEnd-len(values)+i. The
+// window is clipped to the values which have not been pruned, hence fewer values, or none, may be returned.
+func (c *countingIndex) SnapshotRange(fromEnd, count uint64) ([][]byte, uint64, error) {
+	if count == 0 {
+		return nil, 0, errors.New("count must be positive")
+	}
+	if atomic.LoadInt32(&c.closed) != 0 {
+		return nil, 0, errors.Wrapf(ErrIndexClosed, "failed to access counting index %s", c.ns)
+	}
+	kvStore, ok := c.kvStore.(SnapshotKVStore)
+	if !ok {
+		return nil, 0, errors.New("kvStore doesn't support snapshot reads")
+	}
+	var (
+		size   uint64
+		values [][]byte
+	)
+	err := kvStore.View(c.ns, func(get func([]byte) []byte) error {
+		header := get(ZeroIndex)
+		if header == nil {
+			return nil
+		}
+		var (
+			offset uint64
+			err    error
+		)
+		if size, offset, err = decodeHeader(c.ns, header); err != nil {
+			return err
+		}
+		if fromEnd >= size-offset {
+			return nil
+		}
+		end := size - fromEnd
+		start := offset
+		if end-offset > count {
+			start = end - count
+		}
+		values = make([][]byte, 0, end-start)
+		for pos := start; pos < end; pos++ {
+			value := get(positionKey(pos))
+			if value == nil {
+				return errors.Wrapf(ErrNotExist, "no value at %d of counting index %s", pos, c.ns)
+			}
+			values = append(values, append([]byte(nil), value...))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return values, size, nil
+}
+
+// RangeWithIndex returns count values starting from a position along with their positions, which serve as the
+// cursors to continue from. It fails in the same way as Range does.
+func (c *countingIndex) RangeWithIndex(start, count uint64) ([]uint64, [][]byte, error) {
+	values, err := c.Range(start, count)
+	if err != nil {
+		return nil, nil, err
+	}
+	indexes := make([]uint64, len(values))
+	for i := range indexes {
+		indexes[i] = start + uint64(i)
+	}
+	return indexes, values, nil
+}
+
+// RangeFiltered examines count values starting from a position, and returns copies of up to max of them which begin
+// with the prefix, in the order of their positions. The scan stops after count values or max matches, whichever comes
+// first, so its cost is bounded by count regardless of how many values match. An empty prefix matches all the values
+// like Range does. It returns ErrInvalid if max isn't positive, and fails in the same way as Range does otherwise.
+func (c *countingIndex) RangeFiltered(start, count uint64, prefix []byte, max int) ([][]byte, error) {
+	if max <= 0 {
+		return nil, errors.Wrapf(ErrInvalid, "max %d must be positive", max)
+	}
+	if err := c.checkRange(start, count); err != nil {
+		return nil, err
+	}
+	var values [][]byte
+	for pos := start; pos < start+count && len(values) < max; pos++ {
+		value, err := c.kvStore.Get(c.ns, positionKey(pos))
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.HasPrefix(value, prefix) {
+			continue
+		}
+		match := make([]byte, len(value))
+		copy(match, value)
+		values = append(values, match)
+	}
+	return values, nil
+}
+
+// Stream writes count values starting from a position to a writer, each followed by the separator, without holding
+// all of them in memory. It fails in the same way as Range does before writing anything, and stops at the first
+// failure of the writer.
+func (c *countingIndex) Stream(start, count uint64, w io.Writer, sep []byte) error {
+	if w == nil {
+		return errors.New("writer is nil")
+	}
+	if err := c.checkRange(start, count); err != nil {
+		return err
+	}
+	for pos := start; pos < start+count; pos++ {
+		value, err := c.kvStore.Get(c.ns, positionKey(pos))
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(value); err != nil {
+			return errors.Wrapf(err, "failed to write value at %d", pos)
+		}
+		if len(sep) == 0 {
+			continue
+		}
+		if _, err := w.Write(sep); err != nil {
+			return errors.Wrapf(err, "failed to write separator after %d", pos)
+		}
+	}
+	return nil
+}
+
+// checkRange returns an error unless the count values starting from a position are all in the index
+func (c *countingIndex) checkRange(start, count uint64) error {
+	if count == 0 {
+		return errors.New("count must be positive")
+	}
+	size, offset, err := c.header()
+	if err != nil {
+		return err
+	}
+	if start < offset {
+		return errors.Wrapf(ErrNotExist, "start %d has been pruned, offset = %d", start, offset)
+	}
+	if start+count > size {
+		return errors.Wrapf(ErrNotExist, "range [%d, %d) is out of bound %d", start, start+count, size)
+	}
+	return nil
+}
+
+// PruneFront deletes the values before a position, at most batchSize values per commit. The offset is updated along
+// with each commit, so that an interrupted pruning leaves the index consistent.
+func (c *countingIndex) PruneFront(pos uint64, batchSize int) (uint64, error) {
+	if batchSize <= 0 {
+		return 0, errors.Errorf("invalid batch size %d", batchSize)
+	}
+	if err := checkWritable(c.kvStore); err != nil {
+		return 0, err
+	}
+	var pruned uint64
+	for {
+		done, n, err := c.pruneBatch(pos, uint64(batchSize))
+		pruned += n
+		if err != nil || done {
+			return pruned, err
+		}
+	}
+}
+
+func (c *countingIndex) pruneBatch(pos, batchSize uint64) (bool, uint64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	size, offset, err := c.header()
+	if err != nil {
+		return false, 0, err
+	}
+	if pos > size {
+		pos = size
+	}
+	if pos <= offset {
+		return true, 0, nil
+	}
+	end := offset + batchSize
+	if end > pos {
+		end = pos
+	}
+	batch := NewBatch()
+	for i := offset; i < end; i++ {
+		batch.Delete(c.ns, positionKey(i), "failed to delete value at %d", i)
+	}
+	batch.Put(c.ns, ZeroIndex, encodeHeader(size, end), "failed to update the offset of %s", c.ns)
+	if c.reverseNs != "" {
+		if err := c.pruneReverse(batch, offset, end); err != nil {
+			return false, 0, err
+		}
+	}
+	if err := c.kvStore.Commit(batch); err != nil {
+		return false, 0, err
+	}
+	return end == pos, end - offset, nil
+}
+
+// pruneReverse adds to the batch the deletes of the reverse lookups of the values in [start, end), unless a value has
+// been added again since
+func (c *countingIndex) pruneReverse(batch KVStoreBatch, start, end uint64) error {
+	pending := make(map[hash.Hash256][]reverseEntry)
+	for i := start; i < end; i++ {
+		value, err := c.kvStore.Get(c.ns, positionKey(i))
+		if err != nil {
+			return errors.Wrapf(err, "failed to get value at %d", i)
+		}
+		key := hash.Hash256b(value)
+		entries, ok := pending[key]
+		if !ok {
+			if entries, err = c.reverseEntries(key[:]); err != nil {
+				return err
+			}
+		}
+		for j, e := range entries {
+			if e.pos == i {
+				entries = append(entries[:j], entries[j+1:]...)
+				break
+			}
+		}
+		pending[key] = entries
+	}
+	for key, entries := range pending {
+		k := make([]byte, len(key))
+		copy(k, key[:])
+		if len(entries) == 0 {
+			batch.Delete(c.reverseNs, k, "failed to delete reverse lookup %x", k)
+			continue
+		}
+		batch.Put(c.reverseNs, k, encodeReverseEntries(entries), "failed to update reverse lookup %x", k)
+	}
+	return nil
+}
+
+// reverseEntries returns the values and their positions stored under the hash of the values
+func (c *countingIndex) reverseEntries(key []byte) ([]reverseEntry, error) {
+	record, err := c.kvStore.Get(c.reverseNs, key)
+	switch {
+	case errors.Cause(err) == ErrNotExist:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return decodeReverseEntries(c.reverseNs, record)
+}
+
+// Clone copies the index to the bucket of the destination store in one commit. The index is read in one transaction
+// rather than under the lock of the writes, since each write commits a value along with the count.
+func (c *countingIndex) Clone(dst KVStore, dstBucket []byte) error {
+	if atomic.LoadInt32(&c.closed) != 0 {
+		return errors.Wrapf(ErrIndexClosed, "failed to clone counting index %s", c.ns)
+	}
+	src, ok := c.kvStore.(SnapshotKVStore)
+	if !ok {
+		return errors.New("kvStore doesn't support snapshot reads")
+	}
+	if dst == nil {
+		return errors.New("destination kvStore is nil")
+	}
+	if len(dstBucket) == 0 {
+		return errors.New("destination bucket is empty")
+	}
+	dstNs := string(dstBucket)
+	if src == dst && dstNs == c.ns {
+		return errors.Errorf("cannot clone counting index %s into itself", c.ns)
+	}
+	if _, err := dst.Get(dstNs, ZeroIndex); errors.Cause(err) != ErrNotExist {
+		if err != nil {
+			return err
+		}
+		return errors.Errorf("bucket %s already holds a counting index", dstNs)
+	}
+	// the snapshot is committed after the read transaction, which must not overlap a write of the same store
+	batch := NewBatch()
+	err := src.ForEach(c.ns, func(k, v []byte) error {
+		key := make([]byte, len(k))
+		copy(key, k)
+		value := make([]byte, len(v))
+		copy(value, v)
+		batch.Put(dstNs, key, value, "failed to clone key %x", key)
+		return nil
+	})
+	switch {
+	case errors.Cause(err) == ErrNotExist:
+		// an index without any value
+		return nil
+	case err != nil:
+		return errors.Wrapf(err, "failed to read counting index %s", c.ns)
+	case batch.Size() == 0:
+		return nil
+	}
+	return dst.Commit(batch)
+}
+
+// Verify checks the records of the index read in one transaction
+func (c *countingIndex) Verify() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	size, offset, records, strays, err := c.records()
+	if err != nil {
+		return err
+	}
+	for pos := offset; pos < size; pos++ {
+		if _, ok := records[pos]; !ok {
+			return errors.Errorf("counting index %s has no value at %d", c.ns, pos)
+		}
+	}
+	if n := len(strays) + len(records) - int(size-offset); n != 0 {
+		return errors.Errorf("counting index %s has %d stray records", c.ns, n)
+	}
+	return nil
+}
+
+// Rewrite deletes all the records of the index, along with the reverse lookup, and puts the values back at the
+// consecutive positions from the offset, all in one batch
+func (c *countingIndex) Rewrite() error {
+	if err := checkWritable(c.kvStore); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	size, offset, records, strays, err := c.records()
+	if err != nil {
+		return err
+	}
+	batch := NewBatch()
+	for _, key := range strays {
+		batch.Delete(c.ns, key, "failed to delete stray record %x", key)
+	}
+	positions := make([]uint64, 0, len(records))
+	for pos := range records {
+		if pos >= offset && pos < size {
+			positions = append(positions, pos)
+		}
+		batch.Delete(c.ns, positionKey(pos), "failed to delete record at %d", pos)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i] < positions[j] })
+	reverse := make(map[hash.Hash256][]reverseEntry)
+	if c.reverseNs != "" {
+		err := c.kvStore.(SnapshotKVStore).ForEach(c.reverseNs, func(k, _ []byte) error {
+			key := make([]byte, len(k))
+			copy(key, k)
+			batch.Delete(c.reverseNs, key, "failed to delete reverse lookup %x", key)
+			return nil
+		})
+		if err != nil && errors.Cause(err) != ErrNotExist {
+			return errors.Wrapf(err, "failed to read the reverse lookup of counting index %s", c.ns)
+		}
+	}
+	for i, pos := range positions {
+		value := records[pos]
+		batch.Put(c.ns, positionKey(offset+uint64(i)), value, "failed to rewrite value at %d", offset+uint64(i))
+		if c.reverseNs != "" {
+			key := hash.Hash256b(value)
+			reverse[key] = setReverseEntry(reverse[key], value, offset+uint64(i))
+		}
+	}
+	for key, entries := range reverse {
+		k := make([]byte, len(key))
+		copy(k, key[:])
+		batch.Put(c.reverseNs, k, encodeReverseEntries(entries), "failed to rewrite reverse lookup %x", k)
+	}
+	newSize := offset + uint64(len(positions))
+	batch.Put(c.ns, ZeroIndex, encodeHeader(newSize, offset), "failed to update the count of %s", c.ns)
+	return c.kvStore.Commit(batch)
+}
+
+// records reads the header and the values of the index keyed by their positions in one transaction, along with the
+// keys of the records which are not positions
+func (c *countingIndex) records() (uint64, uint64, map[uint64][]byte, [][]byte, error) {
+	if atomic.LoadInt32(&c.closed) != 0 {
+		return 0, 0, nil, nil, errors.Wrapf(ErrIndexClosed, "failed to access counting index %s", c.ns)
+	}
+	kvStore, ok := c.kvStore.(SnapshotKVStore)
+	if !ok {
+		return 0, 0, nil, nil, errors.New("kvStore doesn't support snapshot reads")
+	}
+	var (
+		size, offset uint64
+		records      = make(map[uint64][]byte)
+		strays       [][]byte
+	)
+	err := kvStore.ForEach(c.ns, func(k, v []byte) error {
+		if bytes.Equal(k, ZeroIndex) {
+			var err error
+			size, offset, err = decodeHeader(c.ns, v)
+			return err
+		}
+		if len(k) != len(ZeroIndex) {
+			strays = append(strays, append([]byte(nil), k...))
+			return nil
+		}
+		value := make([]byte, len(v))
+		copy(value, v)
+		records[binary.BigEndian.Uint64(k)-1] = value
+		return nil
+	})
+	if err != nil && errors.Cause(err) != ErrNotExist {
+		return 0, 0, nil, nil, errors.Wrapf(err, "failed to read counting index %s", c.ns)
+	}
+	return size, offset, records, strays, nil
+}
+
+// Stats returns the number of the values and the disk usage of the index. They are read in one transaction if the KV
+// store supports it, which also provides the page stats of the bucket.
+func (c *countingIndex) Stats(opts ...StatsOption) (CountingIndexStats, error) {
+	if atomic.LoadInt32(&c.closed) != 0 {
+		return CountingIndexStats{}, errors.Wrapf(ErrIndexClosed, "failed to get the stats of counting index %s", c.ns)
+	}
+	if kvStore, ok := c.kvStore.(StatsKVStore); ok {
+		return kvStore.CountingIndexStats(c.ns, opts...)
+	}
+	return countingIndexStats(c.ns, func(key []byte) ([]byte, error) {
+		value, err := c.kvStore.Get(c.ns, key)
+		if errors.Cause(err) == ErrNotExist {
+			return nil, nil
+		}
+		return value, err
+	}, opts...)
+}
+
+// Close closes the index, which waits for the ongoing write
+func (c *countingIndex) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+// header returns the count and the offset stored at ZeroIndex
+func (c *countingIndex) header() (uint64, uint64, error) {
+	if atomic.LoadInt32(&c.closed) != 0 {
+		return 0, 0, errors.Wrapf(ErrIndexClosed, "failed to access counting index %s", c.ns)
+	}
+	value, err := c.kvStore.Get(c.ns, ZeroIndex)
+	switch {
+	case errors.Cause(err) == ErrNotExist:
+		return 0, 0, nil
+	case err != nil:
+		return 0, 0, err
+	}
+	return decodeHeader(c.ns, value)
+}
+
+// decodeHeader decodes the count and the offset of a counting index
+func decodeHeader(ns string, value []byte) (uint64, uint64, error) {
+	switch len(value) {
+	case 8:
+		// an index which has never been pruned may store the count only
+		return binary.BigEndian.Uint64(value), 0, nil
+	case 16:
+		return binary.BigEndian.Uint64(value[:8]), binary.BigEndian.Uint64(value[8:]), nil
+	default:
+		return 0, 0, errors.Errorf("invalid header length %d of counting index %s", len(value), ns)
+	}
+}
+
+// countingIndexStats computes the stats of a counting index from its records, which are read by get returning nil for
+// a missing key. The values at the sampled positions are read only.
+func countingIndexStats(
+	ns string,
+	get func([]byte) ([]byte, error),
+	opts ...StatsOption,
+) (CountingIndexStats, error) {
+	cfg := newStatsConfig(opts...)
+	stats := CountingIndexStats{}
+	value, err := get(ZeroIndex)
+	if err != nil || value == nil {
+		return stats, err
+	}
+	size, offset, err := decodeHeader(ns, value)
+	if err != nil {
+		return stats, err
+	}
+	if size <= offset {
+		return stats, nil
+	}
+	stats.Entries = size - offset
+	var inspectedBytes uint64
+	for pos := offset; pos < size; pos += cfg.sampleEvery {
+		value, err := get(positionKey(pos))
+		if err != nil {
+			return stats, err
+		}
+		if value == nil {
+			return stats, errors.Errorf("value at %d of counting index %s is missing", pos, ns)
+		}
+		stats.Inspected++
+		inspectedBytes += uint64(len(value))
+	}
+	stats.AvgValueSize = float64(inspectedBytes) / float64(stats.Inspected)
+	stats.ValueBytes = inspectedBytes
+	if stats.Inspected < stats.Entries {
+		stats.ValueBytes = uint64(stats.AvgValueSize * float64(stats.Entries))
+	}
+	return stats, nil
+}
+
+func newStatsConfig(opts ...StatsOption) statsConfig {
+	cfg := statsConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.sampleEvery == 0 {
+		cfg.sampleEvery = 1
+	}
+	return cfg
+}
+
+// countingIndexNamespaces returns the namespaces of the counting indexes created on a KV store in order
+func countingIndexNamespaces(kvStore KVStore) []string {
+	var namespaces []string
+	countingIndexes.Range(func(key, _ interface{}) bool {
+		if k := key.(bucketKey); k.kvStore == kvStore {
+			namespaces = append(namespaces, k.ns)
+		}
+		return true
+	})
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// reverseNamespace returns the bucket of the reverse lookup of a counting index
+func reverseNamespace(ns string) string {
+	return ns + "_reverse"
+}
+
+// setReverseEntry sets the position of a value, which replaces the earlier position of the same value
+func setReverseEntry(entries []reverseEntry, value []byte, pos uint64) []reverseEntry {
+	for i, e := range entries {
+		if bytes.Equal(e.value, value) {
+			entries[i].pos = pos
+			return entries
+		}
+	}
+	return append(entries, reverseEntry{pos: pos, value: value})
+}
+
+// encodeReverseEntries encodes each entry as the position in 8 bytes, the length of the value in 4 bytes, and the value
+func encodeReverseEntries(entries []reverseEntry) []byte {
+	size := 0
+	for _, e := range entries {
+		size += 12 + len(e.value)
+	}
+	record := make([]byte, 0, size)
+	for _, e := range entries {
+		var header [12]byte
+		binary.BigEndian.PutUint64(header[:8], e.pos)
+		binary.BigEndian.PutUint32(header[8:], uint32(len(e.value)))
+		record = append(record, header[:]...)
+		record = append(record, e.value...)
+	}
+	return record
+}
+
+func decodeReverseEntries(ns string, record []byte) ([]reverseEntry, error) {
+	var entries []reverseEntry
+	for len(record) > 0 {
+		if len(record) < 12 {
+			return nil, errors.Errorf("invalid reverse lookup of length %d in %s", len(record), ns)
+		}
+		pos := binary.BigEndian.Uint64(record[:8])
+		length := uint64(binary.BigEndian.Uint32(record[8:12]))
+		if uint64(len(record)-12) < length {
+			return nil, errors.Errorf("invalid value length %d of reverse lookup in %s", length, ns)
+		}
+		value := make([]byte, length)
+		copy(value, record[12:12+length])
+		entries = append(entries, reverseEntry{pos: pos, value: value})
+		record = record[12+length:]
+	}
+	return entries, nil
+}
+
+func encodeHeader(size, offset uint64) []byte {
+	value := make([]byte, 16)
+	binary.BigEndian.PutUint64(value[:8], size)
+	binary.BigEndian.PutUint64(value[8:], offset)
+	return value
+}
+
+// positionKey returns the key of the value at a position, which skips ZeroIndex
+func positionKey(pos uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, pos+1)
+	return key
+}
@@ -0,0 +1,855 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package db
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/testutil"
+)
+
+func TestCountingIndex(t *testing.T) {
+	for _, backend := range []string{config.MemDBBackend, config.BoltDBBackend} {
+		t.Run(backend, func(t *testing.T) {
+			require := require.New(t)
+			path, err := ioutil.TempFile("", "counting_index")
+			require.NoError(err)
+			defer testutil.CleanupPath(t, path.Name())
+			kv := NewKVStore(config.DB{Backend: backend, DbPath: path.Name(), NumRetries: 3})
+			require.NoError(kv.Start(context.Background()))
+			defer func() {
+				require.NoError(kv.Stop(context.Background()))
+			}()
+			testCountingIndex(t, kv)
+		})
+	}
+}
+
+func testCountingIndex(t *testing.T, kv KVStore) {
+	require := require.New(t)
+	_, err := NewCountingIndex(nil, "ns")
+	require.Error(err)
+	_, err = NewCountingIndex(kv, "")
+	require.Error(err)
+	index, err := NewCountingIndex(kv, "ns")
+	require.NoError(err)
+	require.Equal("ns", index.Namespace())
+	size, err := index.Size()
+	require.NoError(err)
+	require.Equal(uint64(0), size)
+	_, err = index.Get(0)
+	require.Equal(ErrNotExist, errors.Cause(err))
+
+	for i := 0; i < 10; i++ {
+		require.NoError(index.Add([]byte(fmt.Sprintf("value_%d", i))))
+	}
+	size, err = index.Size()
+	require.NoError(err)
+	require.Equal(uint64(10), size)
+	value, err := index.Get(3)
+	require.NoError(err)
+	require.Equal([]byte("value_3"), value)
+	_, err = index.Range(8, 3)
+	require.Equal(ErrNotExist, errors.Cause(err))
+	_, err = index.Range(0, 0)
+	require.Error(err)
+
+	// prune the front in batches of 3
+	_, err = index.PruneFront(4, 0)
+	require.Error(err)
+	pruned, err := index.PruneFront(7, 3)
+	require.NoError(err)
+	require.Equal(uint64(7), pruned)
+	pruned, err = index.PruneFront(5, 3)
+	require.NoError(err)
+	require.Equal(uint64(0), pruned)
+	offset, err := index.Offset()
+	require.NoError(err)
+	require.Equal(uint64(7), offset)
+	for i := uint64(0); i < 7; i++ {
+		_, err = index.Get(i)
+		require.Equal(ErrNotExist, errors.Cause(err))
+		_, err = kv.Get("ns", positionKey(i))
+		require.Equal(ErrNotExist, errors.Cause(err))
+	}
+
+	// the remaining values keep their positions
+	size, err = index.Size()
+	require.NoError(err)
+	require.Equal(uint64(10), size)
+	values, err := index.Range(7, 3)
+	require.NoError(err)
+	require.Equal([][]byte{[]byte("value_7"), []byte("value_8"), []byte("value_9")}, values)
+	_, err = index.Range(6, 2)
+	require.Equal(ErrNotExist, errors.Cause(err))
+	indexes, values, err := index.RangeWithIndex(8, 2)
+	require.NoError(err)
+	require.Equal([]uint64{8, 9}, indexes)
+	require.Equal([][]byte{[]byte("value_8"), []byte("value_9")}, values)
+	for _, r := range [][2]uint64{{6, 2}, {8, 3}, {7, 0}} {
+		_, rangeErr := index.Range(r[0], r[1])
+		_, _, err = index.RangeWithIndex(r[0], r[1])
+		require.Error(err)
+		require.Equal(rangeErr.Error(), err.Error())
+	}
+	require.NoError(index.Add([]byte("value_10")))
+	value, err = index.Get(10)
+	require.NoError(err)
+	require.Equal([]byte("value_10"), value)
+
+	// pruning beyond the size prunes everything
+	pruned, err = index.PruneFront(100, 3)
+	require.NoError(err)
+	require.Equal(uint64(4), pruned)
+	offset, err = index.Offset()
+	require.NoError(err)
+	require.Equal(uint64(11), offset)
+
+	// an index which stores the count only
+	require.NoError(kv.Put("legacy", ZeroIndex, []byte{0, 0, 0, 0, 0, 0, 0, 1}))
+	require.NoError(kv.Put("legacy", positionKey(0), []byte("value_0")))
+	legacy, err := NewCountingIndex(kv, "legacy")
+	require.NoError(err)
+	value, err = legacy.Get(0)
+	require.NoError(err)
+	require.Equal([]byte("value_0"), value)
+
+	// a closed index fails, while the others of the bucket are not affected
+	require.NoError(legacy.Close())
+	require.NoError(legacy.Close())
+	_, err = legacy.Size()
+	require.Equal(ErrIndexClosed, errors.Cause(err))
+	require.Equal(ErrIndexClosed, errors.Cause(legacy.Add([]byte("value_1"))))
+	_, err = legacy.Range(0, 1)
+	require.Equal(ErrIndexClosed, errors.Cause(err))
+	_, err = legacy.PruneFront(1, 1)
+	require.Equal(ErrIndexClosed, errors.Cause(err))
+	legacy, err = NewCountingIndex(kv, "legacy")
+	require.NoError(err)
+	size, err = legacy.Size()
+	require.NoError(err)
+	require.Equal(uint64(1), size)
+}
+
+func TestCountingIndexFirstLast(t *testing.T) {
+	require := require.New(t)
+	kv := NewMemKVStore()
+	require.NoError(kv.Start(context.Background()))
+	defer kv.Stop(context.Background())
+
+	index, err := NewCountingIndex(kv, "ns")
+	require.NoError(err)
+	_, err = index.First()
+	require.Equal(ErrNotExist, errors.Cause(err))
+	_, err = index.Last()
+	require.Equal(ErrNotExist, errors.Cause(err))
+
+	require.NoError(index.Add([]byte("value_0")))
+	value, err := index.First()
+	require.NoError(err)
+	require.Equal([]byte("value_0"), value)
+	value, err = index.Last()
+	require.NoError(err)
+	require.Equal([]byte("value_0"), value)
+
+	for i := 1; i < 5; i++ {
+		require.NoError(index.Add([]byte(fmt.Sprintf("value_%d", i))))
+	}
+	value, err = index.First()
+	require.NoError(err)
+	require.Equal([]byte("value_0"), value)
+	value, err = index.Last()
+	require.NoError(err)
+	require.Equal([]byte("value_4"), value)
+
+	// the first value is the oldest one not pruned
+	_, err = index.PruneFront(3, 2)
+	require.NoError(err)
+	value, err = index.First()
+	require.NoError(err)
+	require.Equal([]byte("value_3"), value)
+	value, err = index.Last()
+	require.NoError(err)
+	require.Equal([]byte("value_4"), value)
+
+	// an index with all the values pruned is empty
+	_, err = index.PruneFront(5, 2)
+	require.NoError(err)
+	_, err = index.First()
+	require.Equal(ErrNotExist, errors.Cause(err))
+	_, err = index.Last()
+	require.Equal(ErrNotExist, errors.Cause(err))
+
+	require.NoError(index.Close())
+	_, err = index.First()
+	require.Equal(ErrIndexClosed, errors.Cause(err))
+	_, err = index.Last()
+	require.Equal(ErrIndexClosed, errors.Cause(err))
+}
+
+func TestCountingIndexStream(t *testing.T) {
+	require := require.New(t)
+	kv := NewMemKVStore()
+	require.NoError(kv.Start(context.Background()))
+	defer kv.Stop(context.Background())
+
+	index, err := NewCountingIndex(kv, "ns")
+	require.NoError(err)
+	for i := 0; i < 10; i++ {
+		require.NoError(index.Add([]byte(fmt.Sprintf("value_%d", i))))
+	}
+	_, err = index.PruneFront(2, 1)
+	require.NoError(err)
+
+	sep := []byte("\n")
+	for _, r := range [][2]uint64{{2, 8}, {5, 1}, {3, 4}} {
+		values, err := index.Range(r[0], r[1])
+		require.NoError(err)
+		var buf bytes.Buffer
+		require.NoError(index.Stream(r[0], r[1], &buf, sep))
+		require.Equal(append(bytes.Join(values, sep), sep...), buf.Bytes())
+		// the values are concatenated without a separator
+		buf.Reset()
+		require.NoError(index.Stream(r[0], r[1], &buf, nil))
+		require.Equal(bytes.Join(values, nil), buf.Bytes())
+	}
+
+	// the same bounds checks as Range, which fail before writing anything
+	for _, r := range [][2]uint64{{1, 2}, {8, 3}, {7, 0}} {
+		_, rangeErr := index.Range(r[0], r[1])
+		var buf bytes.Buffer
+		err = index.Stream(r[0], r[1], &buf, sep)
+		require.Error(err)
+		require.Equal(rangeErr.Error(), err.Error())
+		require.Zero(buf.Len())
+	}
+	require.Error(index.Stream(2, 1, nil, sep))
+
+	// a failure of the writer stops the stream
+	w := &failingWriter{limit: 3}
+	err = index.Stream(2, 8, w, sep)
+	require.Error(err)
+	require.Equal(errWriteLimit, errors.Cause(err))
+	require.Equal(3, w.writes)
+
+	require.NoError(index.Close())
+	require.Equal(ErrIndexClosed, errors.Cause(index.Stream(2, 1, &bytes.Buffer{}, sep)))
+}
+
+var errWriteLimit = errors.New("write limit reached")
+
+// failingWriter fails the writes after the limit
+type failingWriter struct {
+	limit  int
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.writes >= w.limit {
+		return 0, errWriteLimit
+	}
+	w.writes++
+	return len(p), nil
+}
+
+func TestCountingIndexRangeFiltered(t *testing.T) {
+	require := require.New(t)
+	kv := NewMemKVStore()
+	require.NoError(kv.Start(context.Background()))
+	defer kv.Stop(context.Background())
+
+	index, err := NewCountingIndex(kv, "ns")
+	require.NoError(err)
+	// the values of three types, each of which is a type byte followed by a hash
+	types := [][]byte{{1}, {2}, {3}}
+	valueAt := func(i int) []byte {
+		h := hash.Hash256b([]byte(fmt.Sprintf("value_%d", i)))
+		return append([]byte{types[i%len(types)][0]}, h[:]...)
+	}
+	const size = 10000
+	for i := 0; i < size; i++ {
+		require.NoError(index.Add(valueAt(i)))
+	}
+
+	// the first 50 values of the second type
+	values, err := index.RangeFiltered(0, size, types[1], 50)
+	require.NoError(err)
+	require.Equal(50, len(values))
+	for i, v := range values {
+		require.Equal(valueAt(3*i+1), v)
+	}
+	// the last 50 values of the third type
+	values, err = index.RangeFiltered(size-151, 151, types[2], 50)
+	require.NoError(err)
+	require.Equal(50, len(values))
+	for i, v := range values {
+		require.Equal(valueAt(size-151+3*i+2), v)
+	}
+	// the scan stops after count values, however few match
+	values, err = index.RangeFiltered(10, 9, types[0], 50)
+	require.NoError(err)
+	require.Equal([][]byte{valueAt(12), valueAt(15), valueAt(18)}, values)
+	values, err = index.RangeFiltered(0, size, []byte{4}, 50)
+	require.NoError(err)
+	require.Empty(values)
+	// the prefix could be longer than the type byte
+	values, err = index.RangeFiltered(0, size, valueAt(4321), 50)
+	require.NoError(err)
+	require.Equal([][]byte{valueAt(4321)}, values)
+
+	// an empty prefix behaves like Range
+	for _, prefix := range [][]byte{nil, {}} {
+		values, err = index.RangeFiltered(100, 20, prefix, 20)
+		require.NoError(err)
+		expected, err := index.Range(100, 20)
+		require.NoError(err)
+		require.Equal(expected, values)
+	}
+
+	// the values returned are copies
+	values, err = index.RangeFiltered(0, 3, nil, 3)
+	require.NoError(err)
+	values[0][0] = 0xff
+	value, err := index.Get(0)
+	require.NoError(err)
+	require.Equal(valueAt(0), value)
+
+	// max must be positive, and the same bounds checks as Range
+	for _, max := range []int{0, -1} {
+		_, err = index.RangeFiltered(0, 10, types[0], max)
+		require.Equal(ErrInvalid, errors.Cause(err))
+	}
+	_, err = index.PruneFront(100, 50)
+	require.NoError(err)
+	for _, r := range [][2]uint64{{99, 2}, {size - 1, 2}, {200, 0}} {
+		_, rangeErr := index.Range(r[0], r[1])
+		_, err = index.RangeFiltered(r[0], r[1], types[0], 10)
+		require.Error(err)
+		require.Equal(rangeErr.Error(), err.Error())
+	}
+
+	require.NoError(index.Close())
+	_, err = index.RangeFiltered(100, 1, nil, 1)
+	require.Equal(ErrIndexClosed, errors.Cause(err))
+}
+
+func TestCountingIndexStats(t *testing.T) {
+	require := require.New(t)
+	path, err := ioutil.TempFile("", "countingindex")
+	require.NoError(err)
+	defer testutil.CleanupPath(t, path.Name())
+	cfg := config.Default.DB
+	cfg.DbPath = path.Name()
+	bolt := NewBoltDB(cfg)
+	require.NoError(bolt.Start(context.Background()))
+	defer bolt.Stop(context.Background())
+	mem := NewMemKVStore()
+	require.NoError(mem.Start(context.Background()))
+	defer mem.Stop(context.Background())
+
+	for _, kv := range []KVStore{mem, bolt} {
+		index, err := NewCountingIndex(kv, "ns")
+		require.NoError(err)
+		stats, err := index.Stats()
+		require.NoError(err)
+		require.Equal(CountingIndexStats{}, stats)
+
+		// values of 1 to 100 bytes
+		for i := 1; i <= 100; i++ {
+			require.NoError(index.Add(make([]byte, i)))
+		}
+		stats, err = index.Stats()
+		require.NoError(err)
+		require.Equal(uint64(100), stats.Entries)
+		require.Equal(uint64(100), stats.Inspected)
+		require.Equal(uint64(5050), stats.ValueBytes)
+		require.Equal(50.5, stats.AvgValueSize)
+		require.Zero(stats.LeafPages)
+		// the page stats are computed on request only
+		stats, err = index.Stats(StatsPagesOption())
+		require.NoError(err)
+		require.Equal(uint64(5050), stats.ValueBytes)
+		_, isBolt := kv.(*boltDB)
+		require.Equal(isBolt, stats.LeafPages > 0)
+		require.Equal(isBolt, stats.InuseBytes > 5050)
+		require.True(stats.AllocBytes >= stats.InuseBytes)
+
+		// the sampled mode inspects the values at 0, 10, ..., 90 of 1, 11, ..., 91 bytes
+		stats, err = index.Stats(StatsSampleOption(10))
+		require.NoError(err)
+		require.Equal(uint64(100), stats.Entries)
+		require.Equal(uint64(10), stats.Inspected)
+		require.Equal(46.0, stats.AvgValueSize)
+		require.Equal(uint64(4600), stats.ValueBytes)
+
+		// the pruned values are not accounted
+		_, err = index.PruneFront(50, 10)
+		require.NoError(err)
+		stats, err = index.Stats(StatsSampleOption(1))
+		require.NoError(err)
+		require.Equal(uint64(50), stats.Entries)
+		require.Equal(uint64(3775), stats.ValueBytes)
+
+		require.NoError(index.Close())
+		_, err = index.Stats()
+		require.Equal(ErrIndexClosed, errors.Cause(err))
+	}
+
+	// all the counting indexes created on the DB, except for the other buckets even if they look alike
+	for _, store := range []KVStore{mem, bolt} {
+		kv := store.(StatsKVStore)
+		index, err := NewCountingIndex(kv, "another")
+		require.NoError(err)
+		require.NoError(index.Add([]byte("value")))
+		_, err = NewCountingIndex(kv, "empty")
+		require.NoError(err)
+		require.NoError(kv.Put("bucket", []byte("key"), []byte("value")))
+		require.NoError(kv.Put("invalid", ZeroIndex, []byte("value")))
+		require.NoError(kv.Put("lookalike", ZeroIndex, encodeHeader(1, 0)))
+		require.NoError(kv.Put("lookalike", positionKey(0), []byte("value")))
+		all, err := kv.AllCountingIndexStats(StatsSampleOption(2))
+		require.NoError(err)
+		require.Equal(2, len(all))
+		require.Equal(uint64(50), all["ns"].Entries)
+		require.Equal(uint64(25), all["ns"].Inspected)
+		require.Equal(uint64(1), all["another"].Entries)
+		require.Equal(uint64(5), all["another"].ValueBytes)
+		stats, err := kv.CountingIndexStats("another")
+		require.NoError(err)
+		require.Equal(all["another"], stats)
+		stats, err = kv.CountingIndexStats("missing")
+		require.NoError(err)
+		require.Equal(CountingIndexStats{}, stats)
+	}
+}
+
+func TestCountingIndexConcurrency(t *testing.T) {
+	require := require.New(t)
+	path, err := ioutil.TempFile("", "countingindex")
+	require.NoError(err)
+	defer testutil.CleanupPath(t, path.Name())
+	cfg := config.Default.DB
+	cfg.DbPath = path.Name()
+	kv := NewBoltDB(cfg)
+	require.NoError(kv.Start(context.Background()))
+	defer kv.Stop(context.Background())
+
+	const (
+		numWriters = 8
+		numReaders = 8
+		numAdds    = 20
+	)
+	errChan := make(chan error, numWriters+numReaders)
+	var writers, readers sync.WaitGroup
+	done := make(chan struct{})
+	// each writer adds via its own index of the bucket
+	for i := 0; i < numWriters; i++ {
+		index, err := NewCountingIndex(kv, "ns")
+		require.NoError(err)
+		writers.Add(1)
+		go func(i int) {
+			defer writers.Done()
+			for j := 0; j < numAdds; j++ {
+				if err := index.Add([]byte(fmt.Sprintf("value_%d_%d", i, j))); err != nil {
+					errChan <- err
+					return
+				}
+			}
+		}(i)
+	}
+	index, err := NewCountingIndex(kv, "ns")
+	require.NoError(err)
+	for i := 0; i < numReaders; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			var last uint64
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				size, err := index.Size()
+				if err != nil {
+					errChan <- err
+					return
+				}
+				if size < last {
+					errChan <- errors.Errorf("size decreases from %d to %d", last, size)
+					return
+				}
+				last = size
+				// the size never runs ahead of the committed values
+				if size > 0 {
+					if _, err := index.Get(size - 1); err != nil {
+						errChan <- err
+						return
+					}
+				}
+			}
+		}()
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+	close(errChan)
+	for err := range errChan {
+		require.NoError(err)
+	}
+
+	size, err := index.Size()
+	require.NoError(err)
+	require.Equal(uint64(numWriters*numAdds), size)
+	values, err := index.Range(0, size)
+	require.NoError(err)
+	added := make(map[string]bool)
+	for _, value := range values {
+		added[string(value)] = true
+	}
+	require.Equal(numWriters*numAdds, len(added))
+}
+
+func TestCountingIndexClone(t *testing.T) {
+	require := require.New(t)
+	newBoltDB := func(name string) KVStore {
+		path, err := ioutil.TempFile("", name)
+		require.NoError(err)
+		kv := NewBoltDB(config.DB{DbPath: path.Name(), NumRetries: 3})
+		require.NoError(kv.Start(context.Background()))
+		return kv
+	}
+	src := newBoltDB("clone_src")
+	defer func() {
+		require.NoError(src.Stop(context.Background()))
+		testutil.CleanupPath(t, src.(*boltDB).path)
+	}()
+	dst := newBoltDB("clone_dst")
+	defer func() {
+		require.NoError(dst.Stop(context.Background()))
+		testutil.CleanupPath(t, dst.(*boltDB).path)
+	}()
+
+	index, err := NewCountingIndex(src, "ns")
+	require.NoError(err)
+	require.Equal([]byte("ns"), index.BucketName())
+	// an empty index is cloned as an empty one
+	require.NoError(index.Clone(dst, []byte("empty")))
+	for i := 0; i < 10; i++ {
+		require.NoError(index.Add([]byte(fmt.Sprintf("value_%d", i))))
+	}
+	_, err = index.PruneFront(2, 10)
+	require.NoError(err)
+
+	require.Error(index.Clone(dst, nil))
+	require.Error(index.Clone(src, index.BucketName()))
+	require.NoError(index.Clone(dst, []byte("clone")))
+	clone, err := NewCountingIndex(dst, "clone")
+	require.NoError(err)
+	size, err := clone.Size()
+	require.NoError(err)
+	require.Equal(uint64(10), size)
+	offset, err := clone.Offset()
+	require.NoError(err)
+	require.Equal(uint64(2), offset)
+	values, err := index.Range(2, 8)
+	require.NoError(err)
+	cloned, err := clone.Range(2, 8)
+	require.NoError(err)
+	require.Equal(values, cloned)
+	// the clone is independent of the index
+	require.NoError(index.Add([]byte("value_10")))
+	size, err = clone.Size()
+	require.NoError(err)
+	require.Equal(uint64(10), size)
+	// the bucket of an existing index is not overwritten
+	require.Error(index.Clone(dst, []byte("clone")))
+	// cloning into another bucket of the same store
+	require.NoError(index.Clone(src, []byte("clone")))
+	clone, err = NewCountingIndex(src, "clone")
+	require.NoError(err)
+	size, err = clone.Size()
+	require.NoError(err)
+	require.Equal(uint64(11), size)
+
+	// an index in memory is cloned as well
+	mem, err := NewCountingIndex(NewMemKVStore(), "ns")
+	require.NoError(err)
+	require.NoError(mem.Add([]byte("value_0")))
+	require.NoError(mem.Clone(dst, []byte("mem")))
+	clone, err = NewCountingIndex(dst, "mem")
+	require.NoError(err)
+	size, err = clone.Size()
+	require.NoError(err)
+	require.Equal(uint64(1), size)
+	// the store of the index has to support snapshot reads
+	plain, err := NewCountingIndex(struct{ KVStore }{NewMemKVStore()}, "ns")
+	require.NoError(err)
+	require.Error(plain.Clone(dst, []byte("plain")))
+	require.NoError(index.Close())
+	require.Equal(ErrIndexClosed, errors.Cause(index.Clone(dst, []byte("closed"))))
+}
+
+func TestCountingIndexSnapshotRange(t *testing.T) {
+	for _, backend := range []string{config.MemDBBackend, config.BoltDBBackend} {
+		t.Run(backend, func(t *testing.T) {
+			require := require.New(t)
+			path, err := ioutil.TempFile("", "counting_index_snapshot_range")
+			require.NoError(err)
+			defer testutil.CleanupPath(t, path.Name())
+			kv := NewKVStore(config.DB{Backend: backend, DbPath: path.Name(), NumRetries: 3})
+			require.NoError(kv.Start(context.Background()))
+			defer func() {
+				require.NoError(kv.Stop(context.Background()))
+			}()
+
+			index, err := NewCountingIndex(kv, "ns")
+			require.NoError(err)
+			_, _, err = index.SnapshotRange(0, 0)
+			require.Error(err)
+			values, size, err := index.SnapshotRange(0, 10)
+			require.NoError(err)
+			require.Empty(values)
+			require.Zero(size)
+			for i := 0; i < 10; i++ {
+				require.NoError(index.Add([]byte(strconv.Itoa(i))))
+			}
+			_, err = index.PruneFront(2, 10)
+			require.NoError(err)
+			values, size, err = index.SnapshotRange(3, 4)
+			require.NoError(err)
+			require.Equal(uint64(10), size)
+			require.Equal([][]byte{[]byte("3"), []byte("4"), []byte("5"), []byte("6")}, values)
+			// the window is clipped to the values which have not been pruned
+			values, _, err = index.SnapshotRange(5, 10)
+			require.NoError(err)
+			require.Equal([][]byte{[]byte("2"), []byte("3"), []byte("4")}, values)
+			values, _, err = index.SnapshotRange(8, 10)
+			require.NoError(err)
+			require.Empty(values)
+
+			// the values read along with the ongoing adds always match their positions
+			done := make(chan struct{})
+			added := make(chan error, 1)
+			go func() {
+				for i := 10; ; i++ {
+					select {
+					case <-done:
+						added <- nil
+						return
+					default:
+					}
+					if err := index.Add([]byte(strconv.Itoa(i))); err != nil {
+						added <- err
+						return
+					}
+				}
+			}()
+			for i := 0; i < 200; i++ {
+				fromEnd := uint64(i % 3)
+				values, size, err := index.SnapshotRange(fromEnd, 5)
+				require.NoError(err)
+				require.Equal(5, len(values))
+				for j, value := range values {
+					pos := size - fromEnd - uint64(len(values)) + uint64(j)
+					require.Equal(strconv.FormatUint(pos, 10), string(value))
+				}
+			}
+			close(done)
+			require.NoError(<-added)
+
+			plain, err := NewCountingIndex(struct{ KVStore }{NewMemKVStore()}, "ns")
+			require.NoError(err)
+			_, _, err = plain.SnapshotRange(0, 1)
+			require.Error(err)
+			require.NoError(index.Close())
+			_, _, err = index.SnapshotRange(0, 1)
+			require.Equal(ErrIndexClosed, errors.Cause(err))
+		})
+	}
+}
+
+func TestCountingIndexRewrite(t *testing.T) {
+	for _, backend := range []string{config.MemDBBackend, config.BoltDBBackend} {
+		t.Run(backend, func(t *testing.T) {
+			require := require.New(t)
+			path, err := ioutil.TempFile("", "counting_index_rewrite")
+			require.NoError(err)
+			defer testutil.CleanupPath(t, path.Name())
+			kv := NewKVStore(config.DB{Backend: backend, DbPath: path.Name(), NumRetries: 3})
+			require.NoError(kv.Start(context.Background()))
+			defer func() {
+				require.NoError(kv.Stop(context.Background()))
+			}()
+
+			index, err := NewCountingIndexWithReverse(kv, "ns")
+			require.NoError(err)
+			// an empty index is consistent
+			require.NoError(index.Verify())
+			require.NoError(index.Rewrite())
+			require.NoError(index.Verify())
+			for i := 0; i < 10; i++ {
+				require.NoError(index.Add([]byte(fmt.Sprintf("value_%d", i))))
+			}
+			_, err = index.PruneFront(2, 10)
+			require.NoError(err)
+			require.NoError(index.Verify())
+
+			// a gap in the middle and stray records beyond the size
+			require.NoError(kv.Delete("ns", positionKey(5)))
+			require.Error(index.Verify())
+			require.NoError(kv.Put("ns", positionKey(20), []byte("stray")))
+			require.NoError(kv.Put("ns", []byte("stray"), []byte("stray")))
+			require.NoError(index.Rewrite())
+			require.NoError(index.Verify())
+
+			size, err := index.Size()
+			require.NoError(err)
+			require.Equal(uint64(9), size)
+			offset, err := index.Offset()
+			require.NoError(err)
+			require.Equal(uint64(2), offset)
+			values, err := index.Range(2, 7)
+			require.NoError(err)
+			for i, value := range values {
+				expected := i + 2
+				if expected >= 5 {
+					expected++
+				}
+				require.Equal(fmt.Sprintf("value_%d", expected), string(value))
+			}
+			_, err = kv.Get("ns", positionKey(20))
+			require.Equal(ErrNotExist, errors.Cause(err))
+			// the reverse lookup follows the values moved
+			pos, found, err := index.IndexOf([]byte("value_9"))
+			require.NoError(err)
+			require.True(found)
+			require.Equal(uint64(8), pos)
+			_, found, err = index.IndexOf([]byte("value_5"))
+			require.NoError(err)
+			require.False(found)
+			// the values are added after the rewritten ones
+			require.NoError(index.Add([]byte("value_10")))
+			value, err := index.Last()
+			require.NoError(err)
+			require.Equal([]byte("value_10"), value)
+			require.NoError(index.Verify())
+
+			require.NoError(index.Close())
+			require.Equal(ErrIndexClosed, errors.Cause(index.Rewrite()))
+		})
+	}
+}
+
+func TestCountingIndexWithReverse(t *testing.T) {
+	for _, backend := range []string{config.MemDBBackend, config.BoltDBBackend} {
+		t.Run(backend, func(t *testing.T) {
+			require := require.New(t)
+			path, err := ioutil.TempFile("", "counting_index_reverse")
+			require.NoError(err)
+			defer testutil.CleanupPath(t, path.Name())
+			kv := NewKVStore(config.DB{Backend: backend, DbPath: path.Name(), NumRetries: 3})
+			require.NoError(kv.Start(context.Background()))
+			defer func() {
+				require.NoError(kv.Stop(context.Background()))
+			}()
+
+			_, err = NewCountingIndexWithReverse(kv, "")
+			require.Error(err)
+			plain, err := NewCountingIndex(kv, "plain")
+			require.NoError(err)
+			_, _, err = plain.(ReverseCountingIndex).IndexOf([]byte("value"))
+			require.Error(err)
+
+			index, err := NewCountingIndexWithReverse(kv, "ns")
+			require.NoError(err)
+			_, found, err := index.IndexOf([]byte("value_0"))
+			require.NoError(err)
+			require.False(found)
+			for i := 0; i < 10; i++ {
+				require.NoError(index.Add([]byte(fmt.Sprintf("value_%d", i))))
+			}
+			for i := 0; i < 10; i++ {
+				pos, found, err := index.IndexOf([]byte(fmt.Sprintf("value_%d", i)))
+				require.NoError(err)
+				require.True(found)
+				require.Equal(uint64(i), pos)
+			}
+			_, found, err = index.IndexOf([]byte("value_10"))
+			require.NoError(err)
+			require.False(found)
+			_, found, err = index.IndexOf(nil)
+			require.NoError(err)
+			require.False(found)
+
+			// a duplicate value is found at the position of the latest add
+			require.NoError(index.Add([]byte("value_3")))
+			pos, found, err := index.IndexOf([]byte("value_3"))
+			require.NoError(err)
+			require.True(found)
+			require.Equal(uint64(10), pos)
+
+			// the pruned values are not found, unless added again after the pruned positions
+			_, err = index.PruneFront(5, 2)
+			require.NoError(err)
+			for i := 0; i < 5; i++ {
+				pos, found, err := index.IndexOf([]byte(fmt.Sprintf("value_%d", i)))
+				require.NoError(err)
+				require.Equal(i == 3, found)
+				if found {
+					require.Equal(uint64(10), pos)
+				}
+			}
+			pos, found, err = index.IndexOf([]byte("value_5"))
+			require.NoError(err)
+			require.True(found)
+			require.Equal(uint64(5), pos)
+
+			// an index reopened keeps the reverse lookup
+			reopened, err := NewCountingIndexWithReverse(kv, "ns")
+			require.NoError(err)
+			pos, found, err = reopened.IndexOf([]byte("value_9"))
+			require.NoError(err)
+			require.True(found)
+			require.Equal(uint64(9), pos)
+		})
+	}
+}
+
+func TestReverseEntries(t *testing.T) {
+	require := require.New(t)
+
+	// the values of the same hash are told apart by the full values
+	entries := setReverseEntry(nil, []byte("a"), 1)
+	entries = setReverseEntry(entries, []byte("b"), 2)
+	entries = setReverseEntry(entries, []byte(""), 3)
+	entries = setReverseEntry(entries, []byte("a"), 4)
+	decoded, err := decodeReverseEntries("ns", encodeReverseEntries(entries))
+	require.NoError(err)
+	require.Equal([]reverseEntry{
+		{pos: 4, value: []byte("a")},
+		{pos: 2, value: []byte("b")},
+		{pos: 3, value: []byte{}},
+	}, decoded)
+
+	record := encodeReverseEntries(entries)
+	_, err = decodeReverseEntries("ns", record[:len(record)-1])
+	require.Error(err)
+	_, err = decodeReverseEntries("ns", record[:5])
+	require.Error(err)
+}
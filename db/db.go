@@ -7,11 +7,13 @@
 package db
 
 import (
-	"context"
-	"sync"
+	"encoding/binary"
+	"io"
+	"math"
 
 	"github.com/pkg/errors"
 
+	"github.com/iotexproject/iotex-core/config"
 	"github.com/iotexproject/iotex-core/pkg/lifecycle"
 )
 
@@ -24,6 +26,10 @@ var (
 	ErrAlreadyExist = errors.New("already exist in DB")
 	// ErrIO indicates the generic error of DB I/O operation
 	ErrIO = errors.New("DB I/O operation error")
+	// ErrReadOnly indicates a write to a DB opened in the read-only mode
+	ErrReadOnly = errors.New("DB is read-only")
+	// ErrInvalid indicates an invalid argument of a DB operation
+	ErrInvalid = errors.New("invalid argument")
 )
 
 // KVStore is the interface of KV store.
@@ -36,89 +42,71 @@ type KVStore interface {
 	Get(string, []byte) ([]byte, error)
 	// Delete deletes a record by (namespace, key)
 	Delete(string, []byte) error
+	// DeletePrefix deletes all the records of a namespace whose keys have the prefix, and returns the number of them
+	DeletePrefix(string, []byte) (uint64, error)
 	// Commit commits a batch
 	Commit(KVStoreBatch) error
+	// Incr atomically adds delta to the counter identified by (namespace, key), and returns the new value
+	Incr(string, string, uint64) (uint64, error)
+	// CounterValue returns the value of the counter identified by (namespace, key), 0 if it doesn't exist
+	CounterValue(string, string) (uint64, error)
 }
 
-const (
-	keyDelimiter = "."
-)
-
-// memKVStore is the in-memory implementation of KVStore for testing purpose
-type memKVStore struct {
-	data   *sync.Map
-	bucket *sync.Map
+// writeChecker is a KV store which may reject the writes, e.g., if opened in the read-only mode, such that a write
+// composed of several reads fails fast
+type writeChecker interface {
+	checkWritable() error
 }
 
-// NewMemKVStore instantiates an in-memory KV store
-func NewMemKVStore() KVStore {
-	return &memKVStore{
-		bucket: &sync.Map{},
-		data:   &sync.Map{},
+// checkWritable returns the error of writing to the KV store if it rejects the writes
+func checkWritable(kvStore KVStore) error {
+	if c, ok := kvStore.(writeChecker); ok {
+		return c.checkWritable()
 	}
+	return nil
 }
 
-func (m *memKVStore) Start(_ context.Context) error { return nil }
-
-func (m *memKVStore) Stop(_ context.Context) error { return nil }
-
-// Put inserts a <key, value> record
-func (m *memKVStore) Put(namespace string, key, value []byte) error {
-	_, _ = m.bucket.LoadOrStore(namespace, struct{}{})
-	m.data.Store(namespace+keyDelimiter+string(key), value)
-	return nil
+// BackupKVStore is a KV store which can be backed up while running, and restored offline
+type BackupKVStore interface {
+	KVStore
+	// Snapshot writes a consistent copy of the store, which is safe to take along with the ongoing writes
+	Snapshot(io.Writer) error
+	// RestoreFromSnapshot replaces the content of the store by the snapshot file at a path, while it is stopped
+	RestoreFromSnapshot(string) error
 }
 
-// Get retrieves a record
-func (m *memKVStore) Get(namespace string, key []byte) ([]byte, error) {
-	if _, ok := m.bucket.Load(namespace); !ok {
-		return nil, errors.Wrapf(ErrNotExist, "namespace = %s doesn't exist", namespace)
+// NewKVStore instantiates the KV store of the backend in the config, which is the bolt DB by default
+func NewKVStore(cfg config.DB) KVStore {
+	if cfg.Backend == config.MemDBBackend {
+		return NewMemKVStore()
 	}
-	value, _ := m.data.Load(namespace + keyDelimiter + string(key))
+	return NewBoltDB(cfg)
+}
+
+// incrCounter adds delta to the encoded counter, which is 0 if nil
+func incrCounter(value []byte, delta uint64) (uint64, error) {
+	var counter uint64
 	if value != nil {
-		return value.([]byte), nil
+		var err error
+		if counter, err = decodeCounter(value); err != nil {
+			return 0, err
+		}
+	}
+	if counter > math.MaxUint64-delta {
+		return 0, errors.Errorf("counter %d overflows by adding %d", counter, delta)
 	}
-	return nil, errors.Wrapf(ErrNotExist, "key = %x doesn't exist", key)
+	return counter + delta, nil
 }
 
-// Delete deletes a record
-func (m *memKVStore) Delete(namespace string, key []byte) error {
-	m.data.Delete(namespace + keyDelimiter + string(key))
-	return nil
+func encodeCounter(counter uint64) []byte {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, counter)
+	return value
 }
 
-// Commit commits a batch
-func (m *memKVStore) Commit(b KVStoreBatch) (e error) {
-	succeed := false
-	b.Lock()
-	defer func() {
-		if succeed {
-			// clear the batch if commit succeeds
-			b.ClearAndUnlock()
-		} else {
-			b.Unlock()
-		}
-	}()
-	for i := 0; i < b.Size(); i++ {
-		write, err := b.Entry(i)
-		if err != nil {
-			return err
-		}
-		if write.writeType == Put {
-			if err := m.Put(write.namespace, write.key, write.value); err != nil {
-				e = err
-				break
-			}
-		} else if write.writeType == Delete {
-			if err := m.Delete(write.namespace, write.key); err != nil {
-				e = err
-				break
-			}
-		}
+func decodeCounter(value []byte) (uint64, error) {
+	if len(value) != 8 {
+		return 0, errors.Errorf("invalid counter length %d", len(value))
 	}
-	if e == nil {
-		succeed = true
-	}
-
-	return e
+	return binary.BigEndian.Uint64(value), nil
 }
@@ -7,21 +7,38 @@
 package db
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/pkg/errors"
 	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
 
 	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/pkg/log"
 )
 
-const fileMode = 0600
+const (
+	fileMode = 0600
+	// lockTimeout is how long to wait for the file lock of a DB file to restore, which is held while it is open
+	lockTimeout = 100 * time.Millisecond
+)
 
 // boltDB is KVStore implementation based bolt DB
 type boltDB struct {
 	db     *bolt.DB
 	path   string
 	config config.DB
+	// opened tells whether the DB file is open, which is fsynced only once upon stop
+	opened bool
+	// dirty wakes up the syncer of the batched durability mode after a commit
+	dirty chan struct{}
+	quit  chan struct{}
+	done  chan struct{}
 }
 
 // NewBoltDB instantiates an BoltDB with implements KVStore
@@ -29,28 +46,61 @@ func NewBoltDB(cfg config.DB) KVStore {
 	return &boltDB{db: nil, path: cfg.DbPath, config: cfg}
 }
 
-// Start opens the BoltDB (creates new file if not existing yet)
+// Start opens the BoltDB (creates new file if not existing yet). In the read-only mode, the file has to exist, and it
+// fails rather than waits if a writer holds the file lock.
 func (b *boltDB) Start(_ context.Context) error {
-	db, err := bolt.Open(b.path, fileMode, nil)
+	opts := &bolt.Options{NoSync: !b.syncOnCommit()}
+	if b.config.ReadOnly {
+		// bolt creates the file even in the read-only mode
+		if _, err := os.Stat(b.path); err != nil {
+			return errors.Wrap(ErrIO, err.Error())
+		}
+		opts = &bolt.Options{ReadOnly: true, Timeout: lockTimeout}
+	}
+	db, err := bolt.Open(b.path, fileMode, opts)
 	if err != nil {
 		return errors.Wrap(ErrIO, err.Error())
 	}
 	b.db = db
+	b.opened = true
+	if b.config.ReadOnly {
+		return nil
+	}
+	if b.config.Durability == config.BatchedDurability {
+		b.dirty = make(chan struct{}, 1)
+		b.quit = make(chan struct{})
+		b.done = make(chan struct{})
+		go b.syncBatched(db, b.dirty, b.quit, b.done)
+	}
 	return nil
 }
 
-// Stop closes the BoltDB
+// Stop closes the BoltDB, which fsyncs the DB file first unless it is fsynced on every commit
 func (b *boltDB) Stop(_ context.Context) error {
+	if b.quit != nil {
+		close(b.quit)
+		<-b.done
+		b.quit = nil
+	}
 	if b.db != nil {
+		if b.opened && !b.syncOnCommit() && !b.config.ReadOnly {
+			if err := b.db.Sync(); err != nil {
+				return errors.Wrap(ErrIO, err.Error())
+			}
+		}
 		if err := b.db.Close(); err != nil {
 			return errors.Wrap(ErrIO, err.Error())
 		}
+		b.opened = false
 	}
 	return nil
 }
 
 // Put inserts a <key, value> record
 func (b *boltDB) Put(namespace string, key, value []byte) (err error) {
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
 	numRetries := b.config.NumRetries
 	for c := uint8(0); c < numRetries; c++ {
 		if err = b.db.Update(func(tx *bolt.Tx) error {
@@ -60,6 +110,7 @@ func (b *boltDB) Put(namespace string, key, value []byte) (err error) {
 			}
 			return bucket.Put(key, value)
 		}); err == nil {
+			b.committed()
 			break
 		}
 	}
@@ -95,8 +146,197 @@ func (b *boltDB) Get(namespace string, key []byte) ([]byte, error) {
 	return nil, errors.Wrap(ErrIO, err.Error())
 }
 
+// RangeFrom retrieves up to limit records after startKey, in ascending order of keys, or descending order if reverse
+// is true. An empty startKey means starting from the first key, or the last key if reverse is true. Passing the last
+// key of a page as startKey fetches the next page.
+func (b *boltDB) RangeFrom(namespace string, startKey []byte, limit int, reverse bool) ([][]byte, [][]byte, error) {
+	if limit <= 0 {
+		return nil, nil, errors.Errorf("invalid limit %d", limit)
+	}
+	var keys, values [][]byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(namespace))
+		if bucket == nil {
+			return errors.Wrapf(ErrNotExist, "bucket = %s doesn't exist", namespace)
+		}
+		c := bucket.Cursor()
+		var k, v []byte
+		switch {
+		case len(startKey) == 0 && !reverse:
+			k, v = c.First()
+		case len(startKey) == 0:
+			k, v = c.Last()
+		case !reverse:
+			if k, v = c.Seek(startKey); bytes.Equal(k, startKey) {
+				k, v = c.Next()
+			}
+		default:
+			// Seek moves to the first key >= startKey, so the last key < startKey is the previous one
+			if k, _ = c.Seek(startKey); k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		}
+		for ; k != nil && len(keys) < limit; k, v = moveCursor(c, reverse) {
+			key := make([]byte, len(k))
+			copy(key, k)
+			value := make([]byte, len(v))
+			copy(value, v)
+			keys = append(keys, key)
+			values = append(values, value)
+		}
+		return nil
+	})
+	if err == nil {
+		return keys, values, nil
+	}
+	if errors.Cause(err) == ErrNotExist {
+		return nil, nil, err
+	}
+	return nil, nil, errors.Wrap(ErrIO, err.Error())
+}
+
+// ForEach calls fn with each record of a namespace in ascending order of keys, all read in one transaction. The key
+// and value are only valid within fn.
+func (b *boltDB) ForEach(namespace string, fn func([]byte, []byte) error) error {
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(namespace))
+		if bucket == nil {
+			return errors.Wrapf(ErrNotExist, "bucket = %s doesn't exist", namespace)
+		}
+		return bucket.ForEach(fn)
+	})
+	if err == nil || errors.Cause(err) == ErrNotExist {
+		return err
+	}
+	return errors.Wrap(ErrIO, err.Error())
+}
+
+// View calls fn with a getter of the records of a namespace, all read in one transaction, which returns nil for any key
+// if the namespace doesn't exist. The error of fn is returned as it is.
+func (b *boltDB) View(namespace string, fn func(func([]byte) []byte) error) error {
+	var fnErr error
+	err := b.db.View(func(tx *bolt.Tx) error {
+		get := func([]byte) []byte { return nil }
+		if bucket := tx.Bucket([]byte(namespace)); bucket != nil {
+			get = bucket.Get
+		}
+		fnErr = fn(get)
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return errors.Wrap(ErrIO, err.Error())
+	}
+	return nil
+}
+
+// CountingIndexStats returns the stats of the counting index of a namespace along with the page stats of its bucket if
+// requested, all read in one transaction. An index without any value has zero stats.
+func (b *boltDB) CountingIndexStats(namespace string, opts ...StatsOption) (CountingIndexStats, error) {
+	var stats CountingIndexStats
+	if err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(namespace))
+		if bucket == nil {
+			return nil
+		}
+		var err error
+		stats, err = bucketCountingIndexStats(namespace, bucket, opts...)
+		return err
+	}); err != nil {
+		return CountingIndexStats{}, errors.Wrap(ErrIO, err.Error())
+	}
+	return stats, nil
+}
+
+// AllCountingIndexStats returns the stats of all the counting indexes created on the DB keyed by the namespaces, all
+// read in one transaction
+func (b *boltDB) AllCountingIndexStats(opts ...StatsOption) (map[string]CountingIndexStats, error) {
+	all := make(map[string]CountingIndexStats)
+	namespaces := countingIndexNamespaces(b)
+	if len(namespaces) == 0 {
+		return all, nil
+	}
+	if err := b.db.View(func(tx *bolt.Tx) error {
+		for _, ns := range namespaces {
+			bucket := tx.Bucket([]byte(ns))
+			if bucket == nil {
+				continue
+			}
+			stats, err := bucketCountingIndexStats(ns, bucket, opts...)
+			if err != nil {
+				return err
+			}
+			all[ns] = stats
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(ErrIO, err.Error())
+	}
+	return all, nil
+}
+
+// Snapshot writes a consistent copy of the DB file in a read transaction, which doesn't block the writes. The copy is
+// a DB file to restore from.
+func (b *boltDB) Snapshot(w io.Writer) error {
+	if err := b.db.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	}); err != nil {
+		return errors.Wrap(ErrIO, err.Error())
+	}
+	return nil
+}
+
+// RestoreFromSnapshot replaces the DB file by a copy of the snapshot file at path. The DB has to be stopped, and the
+// DB file is replaced in one rename, such that it is intact if the restore fails.
+func (b *boltDB) RestoreFromSnapshot(path string) error {
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
+	snapshot, err := bolt.Open(path, fileMode, &bolt.Options{ReadOnly: true, Timeout: lockTimeout})
+	if err != nil {
+		return errors.Wrapf(ErrIO, "failed to open snapshot %s: %v", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0700); err != nil {
+		snapshot.Close()
+		return errors.Wrap(ErrIO, err.Error())
+	}
+	restored := b.path + ".restore"
+	err = snapshot.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(restored, fileMode)
+	})
+	if closeErr := snapshot.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(restored)
+		return errors.Wrapf(ErrIO, "failed to copy snapshot %s: %v", path, err)
+	}
+	if _, err := os.Stat(b.path); err == nil {
+		// the file lock of the DB is taken till the replacement, which fails if the DB is running
+		db, err := bolt.Open(b.path, fileMode, &bolt.Options{Timeout: lockTimeout})
+		if err != nil {
+			os.Remove(restored)
+			return errors.Wrapf(ErrIO, "DB %s is in use: %v", b.path, err)
+		}
+		defer db.Close()
+	}
+	if err := os.Rename(restored, b.path); err != nil {
+		os.Remove(restored)
+		return errors.Wrap(ErrIO, err.Error())
+	}
+	return nil
+}
+
 // Delete deletes a record,if key is nil,this will delete the whole bucket
 func (b *boltDB) Delete(namespace string, key []byte) (err error) {
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
 	numRetries := b.config.NumRetries
 	for c := uint8(0); c < numRetries; c++ {
 		if key == nil {
@@ -116,6 +356,7 @@ func (b *boltDB) Delete(namespace string, key []byte) (err error) {
 			})
 		}
 		if err == nil {
+			b.committed()
 			break
 		}
 	}
@@ -125,8 +366,50 @@ func (b *boltDB) Delete(namespace string, key []byte) (err error) {
 	return err
 }
 
+// DeletePrefix deletes all the records of a namespace whose keys have the prefix in one transaction, and returns the
+// number of them. A namespace which doesn't exist has none.
+func (b *boltDB) DeletePrefix(namespace string, prefix []byte) (deleted uint64, err error) {
+	if err := b.checkWritable(); err != nil {
+		return 0, err
+	}
+	numRetries := b.config.NumRetries
+	for c := uint8(0); c < numRetries; c++ {
+		deleted = 0
+		err = b.db.Update(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte(namespace))
+			if bucket == nil {
+				return nil
+			}
+			// the keys are collected before being deleted, as deleting at the cursor would skip the next key
+			var keys [][]byte
+			cursor := bucket.Cursor()
+			for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+				keys = append(keys, copyBytes(k))
+			}
+			for _, k := range keys {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+			deleted = uint64(len(keys))
+			return nil
+		})
+		if err == nil {
+			b.committed()
+			break
+		}
+	}
+	if err != nil {
+		return 0, errors.Wrap(ErrIO, err.Error())
+	}
+	return deleted, nil
+}
+
 // Commit commits a batch
 func (b *boltDB) Commit(batch KVStoreBatch) (err error) {
+	if err := b.checkWritable(); err != nil {
+		return err
+	}
 	succeed := true
 	batch.Lock()
 	defer func() {
@@ -167,6 +450,7 @@ func (b *boltDB) Commit(batch KVStoreBatch) (err error) {
 			}
 			return nil
 		}); err == nil {
+			b.committed()
 			break
 		}
 	}
@@ -178,10 +462,109 @@ func (b *boltDB) Commit(batch KVStoreBatch) (err error) {
 	return err
 }
 
+// Incr atomically adds delta to a counter within a single transaction
+func (b *boltDB) Incr(namespace, key string, delta uint64) (counter uint64, err error) {
+	if err := b.checkWritable(); err != nil {
+		return 0, err
+	}
+	numRetries := b.config.NumRetries
+	for c := uint8(0); c < numRetries; c++ {
+		if err = b.db.Update(func(tx *bolt.Tx) error {
+			bucket, err := tx.CreateBucketIfNotExists([]byte(namespace))
+			if err != nil {
+				return err
+			}
+			if counter, err = incrCounter(bucket.Get([]byte(key)), delta); err != nil {
+				return errors.Wrapf(err, "failed to increase counter %s in %s", key, namespace)
+			}
+			return bucket.Put([]byte(key), encodeCounter(counter))
+		}); err == nil {
+			b.committed()
+			break
+		}
+	}
+	if err != nil {
+		return 0, errors.Wrap(ErrIO, err.Error())
+	}
+	return counter, nil
+}
+
+// CounterValue returns the value of a counter
+func (b *boltDB) CounterValue(namespace, key string) (counter uint64, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(namespace))
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get([]byte(key))
+		if value == nil {
+			return nil
+		}
+		counter, err = decodeCounter(value)
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrap(ErrIO, err.Error())
+	}
+	return counter, nil
+}
+
 //======================================
 // private functions
 //======================================
 
+// syncOnCommit tells whether bolt fsyncs the DB file on every commit
+func (b *boltDB) syncOnCommit() bool {
+	switch b.config.Durability {
+	case config.NoSyncDurability, config.BatchedDurability:
+		return false
+	default:
+		return true
+	}
+}
+
+// checkWritable returns ErrReadOnly if the DB is opened in the read-only mode
+func (b *boltDB) checkWritable() error {
+	if b.config.ReadOnly {
+		return errors.Wrapf(ErrReadOnly, "failed to write to DB %s", b.path)
+	}
+	return nil
+}
+
+// committed wakes up the syncer of the batched durability mode, if any
+func (b *boltDB) committed() {
+	if b.dirty == nil {
+		return
+	}
+	select {
+	case b.dirty <- struct{}{}:
+	default:
+	}
+}
+
+// syncBatched fsyncs the DB file once per sync interval, for all the commits made within the interval
+func (b *boltDB) syncBatched(db *bolt.DB, dirty <-chan struct{}, quit <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	for {
+		select {
+		case <-quit:
+			return
+		case <-dirty:
+		}
+		timer := time.NewTimer(b.config.SyncInterval)
+		select {
+		case <-quit:
+			// Stop fsyncs the DB file
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := db.Sync(); err != nil {
+			log.L().Error("Failed to sync the DB file.", zap.String("path", b.path), zap.Error(err))
+		}
+	}
+}
+
 // intentionally fail to test DB can successfully rollback
 func (b *boltDB) batchPutForceFail(namespace string, key [][]byte, value [][]byte) error {
 	return b.db.Update(func(tx *bolt.Tx) error {
@@ -204,3 +587,31 @@ func (b *boltDB) batchPutForceFail(namespace string, key [][]byte, value [][]byt
 		return nil
 	})
 }
+
+func moveCursor(c *bolt.Cursor, reverse bool) ([]byte, []byte) {
+	if reverse {
+		return c.Prev()
+	}
+	return c.Next()
+}
+
+// bucketCountingIndexStats computes the stats of the counting index in a bucket, along with the page stats of it if
+// requested
+func bucketCountingIndexStats(ns string, bucket *bolt.Bucket, opts ...StatsOption) (CountingIndexStats, error) {
+	stats, err := countingIndexStats(ns, func(key []byte) ([]byte, error) {
+		return bucket.Get(key), nil
+	}, opts...)
+	if err != nil {
+		return CountingIndexStats{}, err
+	}
+	if !newStatsConfig(opts...).pages {
+		return stats, nil
+	}
+	pages := bucket.Stats()
+	stats.BranchPages = pages.BranchPageN
+	stats.LeafPages = pages.LeafPageN
+	stats.OverflowPages = pages.BranchOverflowN + pages.LeafOverflowN
+	stats.AllocBytes = pages.BranchAlloc + pages.LeafAlloc
+	stats.InuseBytes = pages.BranchInuse + pages.LeafInuse
+	return stats, nil
+}
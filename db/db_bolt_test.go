@@ -7,16 +7,90 @@
 package db
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 
 	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/testutil"
 )
 
+func TestBoltDB_RangeFrom(t *testing.T) {
+	require := require.New(t)
+	path, err := ioutil.TempFile("", "boltdb")
+	require.NoError(err)
+	defer testutil.CleanupPath(t, path.Name())
+	db := boltDB{
+		path:   path.Name(),
+		config: config.Default.DB,
+	}
+	require.NoError(db.Start(context.Background()))
+	defer db.Stop(context.Background())
+
+	_, _, err = db.RangeFrom("ns", nil, 3, false)
+	require.Equal(ErrNotExist, errors.Cause(err))
+	for i := 0; i < 10; i++ {
+		require.NoError(db.Put("ns", []byte(fmt.Sprintf("key_%d", i)), []byte(fmt.Sprintf("value_%d", i))))
+	}
+	_, _, err = db.RangeFrom("ns", nil, 0, false)
+	require.Error(err)
+
+	paginate := func(reverse bool) [][]string {
+		pages := [][]string{}
+		var cursor []byte
+		for {
+			keys, values, err := db.RangeFrom("ns", cursor, 3, reverse)
+			require.NoError(err)
+			require.Equal(len(keys), len(values))
+			if len(keys) == 0 {
+				return pages
+			}
+			page := []string{}
+			for i, k := range keys {
+				require.Equal(strings.Replace(string(k), "key", "value", 1), string(values[i]))
+				page = append(page, string(k))
+			}
+			pages = append(pages, page)
+			cursor = keys[len(keys)-1]
+		}
+	}
+	require.Equal([][]string{
+		{"key_0", "key_1", "key_2"},
+		{"key_3", "key_4", "key_5"},
+		{"key_6", "key_7", "key_8"},
+		{"key_9"},
+	}, paginate(false))
+	require.Equal([][]string{
+		{"key_9", "key_8", "key_7"},
+		{"key_6", "key_5", "key_4"},
+		{"key_3", "key_2", "key_1"},
+		{"key_0"},
+	}, paginate(true))
+
+	// start key not in the bucket
+	keys, _, err := db.RangeFrom("ns", []byte("key_45"), 2, false)
+	require.NoError(err)
+	require.Equal([][]byte{[]byte("key_5"), []byte("key_6")}, keys)
+	keys, _, err = db.RangeFrom("ns", []byte("key_45"), 2, true)
+	require.NoError(err)
+	require.Equal([][]byte{[]byte("key_4"), []byte("key_3")}, keys)
+	keys, _, err = db.RangeFrom("ns", []byte("z"), 2, true)
+	require.NoError(err)
+	require.Equal([][]byte{[]byte("key_9"), []byte("key_8")}, keys)
+	keys, _, err = db.RangeFrom("ns", []byte("a"), 2, true)
+	require.NoError(err)
+	require.Empty(keys)
+}
+
 func BenchmarkBoltDB_Get(b *testing.B) {
 	runBenchmark := func(b *testing.B, size int) {
 		path, err := ioutil.TempFile("", "boltdb")
@@ -54,3 +128,246 @@ func BenchmarkBoltDB_Get(b *testing.B) {
 		runBenchmark(b, 100)
 	})
 }
+
+func TestBoltDB_Durability(t *testing.T) {
+	for _, durability := range []string{
+		"", config.SyncDurability, config.NoSyncDurability, config.BatchedDurability,
+	} {
+		t.Run(durability, func(t *testing.T) {
+			require := require.New(t)
+			ctx := context.Background()
+			path, err := ioutil.TempFile("", "boltdb")
+			require.NoError(err)
+			defer testutil.CleanupPath(t, path.Name())
+			cfg := config.Default.DB
+			cfg.DbPath = path.Name()
+			cfg.Durability = durability
+			cfg.SyncInterval = time.Millisecond
+
+			db := NewBoltDB(cfg)
+			require.NoError(db.Start(ctx))
+			require.Equal(
+				durability == config.NoSyncDurability || durability == config.BatchedDurability,
+				db.(*boltDB).db.NoSync,
+			)
+			require.NoError(db.Put("ns", []byte("put"), []byte("1")))
+			batch := NewBatch()
+			batch.Put("ns", []byte("batch"), []byte("2"), "failed to put")
+			require.NoError(db.Commit(batch))
+			index, err := NewCountingIndex(db, "index")
+			require.NoError(err)
+			require.NoError(index.Add([]byte("3")))
+			// let the syncer fsync the commits in the batched mode
+			time.Sleep(10 * time.Millisecond)
+			require.NoError(index.Close())
+			require.NoError(db.Stop(ctx))
+			require.NoError(db.Stop(ctx))
+
+			// all the commits are kept after a clean stop
+			db = NewBoltDB(cfg)
+			require.NoError(db.Start(ctx))
+			defer func() {
+				require.NoError(db.Stop(ctx))
+			}()
+			v, err := db.Get("ns", []byte("put"))
+			require.NoError(err)
+			require.Equal([]byte("1"), v)
+			v, err = db.Get("ns", []byte("batch"))
+			require.NoError(err)
+			require.Equal([]byte("2"), v)
+			index, err = NewCountingIndex(db, "index")
+			require.NoError(err)
+			v, err = index.Last()
+			require.NoError(err)
+			require.Equal([]byte("3"), v)
+		})
+	}
+}
+
+func BenchmarkBoltDB_Durability(b *testing.B) {
+	runBenchmark := func(b *testing.B, durability string) {
+		path, err := ioutil.TempFile("", "boltdb")
+		require.NoError(b, err)
+		defer os.Remove(path.Name())
+		cfg := config.Default.DB
+		cfg.DbPath = path.Name()
+		cfg.Durability = durability
+		db := NewBoltDB(cfg)
+		require.NoError(b, db.Start(context.Background()))
+		defer db.Stop(context.Background())
+		index, err := NewCountingIndex(db, "index")
+		require.NoError(b, err)
+
+		value := make([]byte, 100)
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			require.NoError(b, index.Add(value))
+		}
+	}
+
+	for _, durability := range []string{
+		config.SyncDurability, config.NoSyncDurability, config.BatchedDurability,
+	} {
+		b.Run(durability, func(b *testing.B) {
+			runBenchmark(b, durability)
+		})
+	}
+}
+
+func TestBoltDB_Snapshot(t *testing.T) {
+	require := require.New(t)
+	newPath := func(name string) string {
+		f, err := ioutil.TempFile("", name)
+		require.NoError(err)
+		require.NoError(f.Close())
+		return f.Name()
+	}
+	path := newPath("boltdb")
+	defer testutil.CleanupPath(t, path)
+	kv := NewBoltDB(config.DB{DbPath: path, NumRetries: 3})
+	require.NoError(kv.Start(context.Background()))
+	defer kv.Stop(context.Background())
+	for i := 0; i < 100; i++ {
+		require.NoError(kv.Put("ns", []byte(fmt.Sprintf("key_%d", i)), []byte(fmt.Sprintf("value_%d", i))))
+	}
+	_, err := kv.Incr("counters", "c", 5)
+	require.NoError(err)
+
+	// snapshot along with the ongoing writes
+	backup, ok := kv.(BackupKVStore)
+	require.True(ok)
+	done := make(chan error)
+	go func() {
+		for i := 100; i < 200; i++ {
+			if err := kv.Put("ns", []byte(fmt.Sprintf("key_%d", i)), []byte(fmt.Sprintf("value_%d", i))); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	var buf bytes.Buffer
+	require.NoError(backup.Snapshot(&buf))
+	require.NoError(<-done)
+	snapshotPath := newPath("snapshot")
+	defer testutil.CleanupPath(t, snapshotPath)
+	require.NoError(ioutil.WriteFile(snapshotPath, buf.Bytes(), 0600))
+
+	// the store is restored offline only
+	require.Error(backup.RestoreFromSnapshot(snapshotPath))
+	restoredPath := newPath("restored")
+	defer testutil.CleanupPath(t, restoredPath)
+	restored := NewBoltDB(config.DB{DbPath: restoredPath, NumRetries: 3})
+	require.Error(restored.(BackupKVStore).RestoreFromSnapshot(path + ".missing"))
+	require.NoError(restored.(BackupKVStore).RestoreFromSnapshot(snapshotPath))
+	require.NoError(restored.Start(context.Background()))
+	defer restored.Stop(context.Background())
+
+	// the restored store holds a consistent prefix of the writes
+	keys, values, err := restored.(*boltDB).RangeFrom("ns", nil, 1000, false)
+	require.NoError(err)
+	require.True(len(keys) >= 100)
+	for i, key := range keys {
+		value, err := kv.Get("ns", key)
+		require.NoError(err)
+		require.Equal(value, values[i])
+	}
+	for i := 0; i < len(keys); i++ {
+		_, err := restored.Get("ns", []byte(fmt.Sprintf("key_%d", i)))
+		require.NoError(err)
+	}
+	counter, err := restored.CounterValue("counters", "c")
+	require.NoError(err)
+	require.Equal(uint64(5), counter)
+}
+
+func TestBoltDB_ReadOnly(t *testing.T) {
+	require := require.New(t)
+	f, err := ioutil.TempFile("", "boltdb")
+	require.NoError(err)
+	require.NoError(f.Close())
+	path := f.Name()
+	defer testutil.CleanupPath(t, path)
+	ctx := context.Background()
+
+	// a read-only DB has to exist
+	missing := NewBoltDB(config.DB{DbPath: path + ".missing", NumRetries: 3, ReadOnly: true})
+	require.Error(missing.Start(ctx))
+	_, err = os.Stat(path + ".missing")
+	require.True(os.IsNotExist(err))
+
+	kv := NewBoltDB(config.DB{DbPath: path, NumRetries: 3})
+	require.NoError(kv.Start(ctx))
+	for i := 0; i < 10; i++ {
+		require.NoError(kv.Put("ns", []byte(fmt.Sprintf("key_%d", i)), []byte(fmt.Sprintf("value_%d", i))))
+	}
+	_, err = kv.Incr("counters", "c", 5)
+	require.NoError(err)
+	index, err := NewCountingIndexWithReverse(kv, "index")
+	require.NoError(err)
+	for i := 0; i < 5; i++ {
+		require.NoError(index.Add([]byte(fmt.Sprintf("value_%d", i))))
+	}
+	// a read-only DB isn't opened while the writer holds the file lock
+	cfg := config.DB{DbPath: path, NumRetries: 3, ReadOnly: true, Durability: config.BatchedDurability}
+	readOnly := NewBoltDB(cfg)
+	require.Error(readOnly.Start(ctx))
+	require.NoError(kv.Stop(ctx))
+
+	// the readers share the file lock
+	require.NoError(readOnly.Start(ctx))
+	defer func() {
+		require.NoError(readOnly.Stop(ctx))
+	}()
+	another := NewBoltDB(cfg)
+	require.NoError(another.Start(ctx))
+	require.NoError(another.Stop(ctx))
+
+	value, err := readOnly.Get("ns", []byte("key_3"))
+	require.NoError(err)
+	require.Equal([]byte("value_3"), value)
+	keys, _, err := readOnly.(*boltDB).RangeFrom("ns", nil, 100, false)
+	require.NoError(err)
+	require.Equal(10, len(keys))
+	counter, err := readOnly.CounterValue("counters", "c")
+	require.NoError(err)
+	require.Equal(uint64(5), counter)
+	index, err = NewCountingIndexWithReverse(readOnly, "index")
+	require.NoError(err)
+	size, err := index.Size()
+	require.NoError(err)
+	require.Equal(uint64(5), size)
+	value, err = index.Get(2)
+	require.NoError(err)
+	require.Equal([]byte("value_2"), value)
+	pos, ok, err := index.IndexOf([]byte("value_4"))
+	require.NoError(err)
+	require.True(ok)
+	require.Equal(uint64(4), pos)
+
+	// the writes fail
+	require.Equal(ErrReadOnly, errors.Cause(readOnly.Put("ns", []byte("key_3"), []byte("new"))))
+	require.Equal(ErrReadOnly, errors.Cause(readOnly.Delete("ns", []byte("key_3"))))
+	require.Equal(ErrReadOnly, errors.Cause(readOnly.Delete("ns", nil)))
+	_, err = readOnly.DeletePrefix("ns", []byte("key_"))
+	require.Equal(ErrReadOnly, errors.Cause(err))
+	_, err = readOnly.Incr("counters", "c", 1)
+	require.Equal(ErrReadOnly, errors.Cause(err))
+	batch := NewBatch()
+	batch.Put("ns", []byte("key_10"), []byte("value_10"), "failed to put")
+	require.Equal(ErrReadOnly, errors.Cause(readOnly.Commit(batch)))
+	require.Equal(1, batch.Size())
+	require.Equal(ErrReadOnly, errors.Cause(readOnly.(BackupKVStore).RestoreFromSnapshot(path)))
+	require.Equal(ErrReadOnly, errors.Cause(index.Add([]byte("value_5"))))
+	_, err = index.PruneFront(2, 10)
+	require.Equal(ErrReadOnly, errors.Cause(err))
+	size, err = index.Size()
+	require.NoError(err)
+	require.Equal(uint64(5), size)
+
+	value, err = readOnly.Get("ns", []byte("key_3"))
+	require.NoError(err)
+	require.Equal([]byte("value_3"), value)
+	_, err = readOnly.Get("ns", []byte("key_10"))
+	require.Equal(ErrNotExist, errors.Cause(err))
+}
@@ -0,0 +1,285 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// memKVStore is the in-memory implementation of KVStore, which behaves like the bolt DB without touching the disk:
+// a batch is committed atomically, the records of a namespace are ranged in the order of the keys, and a read of
+// multiple records sees a consistent snapshot. The records are kept after the store is stopped.
+type memKVStore struct {
+	mutex   sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemKVStore instantiates an in-memory KV store
+func NewMemKVStore() KVStore {
+	return &memKVStore{
+		buckets: make(map[string]map[string][]byte),
+	}
+}
+
+func (m *memKVStore) Start(_ context.Context) error { return nil }
+
+func (m *memKVStore) Stop(_ context.Context) error { return nil }
+
+// Put inserts a <key, value> record
+func (m *memKVStore) Put(namespace string, key, value []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.put(namespace, key, value)
+	return nil
+}
+
+// Get retrieves a record
+func (m *memKVStore) Get(namespace string, key []byte) ([]byte, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	bucket, ok := m.buckets[namespace]
+	if !ok {
+		return nil, errors.Wrapf(ErrNotExist, "namespace = %s doesn't exist", namespace)
+	}
+	value, ok := bucket[string(key)]
+	if !ok {
+		return nil, errors.Wrapf(ErrNotExist, "key = %x doesn't exist", key)
+	}
+	return copyBytes(value), nil
+}
+
+// RangeFrom retrieves up to limit records after startKey, in the same way as the bolt DB does
+func (m *memKVStore) RangeFrom(namespace string, startKey []byte, limit int, reverse bool) ([][]byte, [][]byte, error) {
+	if limit <= 0 {
+		return nil, nil, errors.Errorf("invalid limit %d", limit)
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	bucket, ok := m.buckets[namespace]
+	if !ok {
+		return nil, nil, errors.Wrapf(ErrNotExist, "bucket = %s doesn't exist", namespace)
+	}
+	sorted := sortedKeys(bucket)
+	start := string(startKey)
+	var i, step int
+	switch {
+	case len(startKey) == 0 && !reverse:
+		i, step = 0, 1
+	case len(startKey) == 0:
+		i, step = len(sorted)-1, -1
+	case !reverse:
+		// the first key > startKey
+		i, step = sort.Search(len(sorted), func(j int) bool { return sorted[j] > start }), 1
+	default:
+		// the last key < startKey
+		i, step = sort.SearchStrings(sorted, start)-1, -1
+	}
+	var keys, values [][]byte
+	for ; i >= 0 && i < len(sorted) && len(keys) < limit; i += step {
+		keys = append(keys, []byte(sorted[i]))
+		values = append(values, copyBytes(bucket[sorted[i]]))
+	}
+	return keys, values, nil
+}
+
+// ForEach calls fn with each record of a namespace in ascending order of keys, all read in one snapshot. The store
+// must not be written within fn.
+func (m *memKVStore) ForEach(namespace string, fn func([]byte, []byte) error) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	bucket, ok := m.buckets[namespace]
+	if !ok {
+		return errors.Wrapf(ErrNotExist, "bucket = %s doesn't exist", namespace)
+	}
+	for _, key := range sortedKeys(bucket) {
+		if err := fn([]byte(key), bucket[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// View calls fn with a getter of the records of a namespace, all read in one snapshot, which returns nil for any key if
+// the namespace doesn't exist. The store must not be written within fn.
+func (m *memKVStore) View(namespace string, fn func(func([]byte) []byte) error) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	bucket := m.buckets[namespace]
+	return fn(func(key []byte) []byte {
+		return bucket[string(key)]
+	})
+}
+
+// CountingIndexStats returns the stats of the counting index of a namespace, all read in one snapshot. There are no
+// pages in memory, whose stats are zero.
+func (m *memKVStore) CountingIndexStats(namespace string, opts ...StatsOption) (CountingIndexStats, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	bucket, ok := m.buckets[namespace]
+	if !ok {
+		return CountingIndexStats{}, nil
+	}
+	return bucketMemCountingIndexStats(namespace, bucket, opts...)
+}
+
+// AllCountingIndexStats returns the stats of all the counting indexes created on the KV store keyed by the namespaces,
+// all read in one snapshot
+func (m *memKVStore) AllCountingIndexStats(opts ...StatsOption) (map[string]CountingIndexStats, error) {
+	namespaces := countingIndexNamespaces(m)
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	all := make(map[string]CountingIndexStats)
+	for _, ns := range namespaces {
+		bucket, ok := m.buckets[ns]
+		if !ok {
+			continue
+		}
+		stats, err := bucketMemCountingIndexStats(ns, bucket, opts...)
+		if err != nil {
+			return nil, err
+		}
+		all[ns] = stats
+	}
+	return all, nil
+}
+
+// Delete deletes a record, if key is nil, this will delete the whole bucket
+func (m *memKVStore) Delete(namespace string, key []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if key == nil {
+		delete(m.buckets, namespace)
+		return nil
+	}
+	m.delete(namespace, key)
+	return nil
+}
+
+// DeletePrefix deletes all the records of a namespace whose keys have the prefix, and returns the number of them
+func (m *memKVStore) DeletePrefix(namespace string, prefix []byte) (uint64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var deleted uint64
+	for key := range m.buckets[namespace] {
+		if strings.HasPrefix(key, string(prefix)) {
+			delete(m.buckets[namespace], key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Commit commits a batch, which is applied all or nothing
+func (m *memKVStore) Commit(b KVStoreBatch) (e error) {
+	succeed := false
+	b.Lock()
+	defer func() {
+		if succeed {
+			// clear the batch if commit succeeds
+			b.ClearAndUnlock()
+		} else {
+			b.Unlock()
+		}
+	}()
+	writes := make([]*writeInfo, 0, b.Size())
+	for i := 0; i < b.Size(); i++ {
+		write, err := b.Entry(i)
+		if err != nil {
+			return err
+		}
+		writes = append(writes, write)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, write := range writes {
+		switch write.writeType {
+		case Put:
+			m.put(write.namespace, write.key, write.value)
+		case Delete:
+			m.delete(write.namespace, write.key)
+		}
+	}
+	succeed = true
+	return nil
+}
+
+// Incr atomically adds delta to a counter
+func (m *memKVStore) Incr(namespace, key string, delta uint64) (uint64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	counter, err := incrCounter(m.buckets[namespace][key], delta)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to increase counter %s in %s", key, namespace)
+	}
+	m.put(namespace, []byte(key), encodeCounter(counter))
+	return counter, nil
+}
+
+// CounterValue returns the value of a counter
+func (m *memKVStore) CounterValue(namespace, key string) (uint64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	value, ok := m.buckets[namespace][key]
+	if !ok {
+		return 0, nil
+	}
+	return decodeCounter(value)
+}
+
+func (m *memKVStore) put(namespace string, key, value []byte) {
+	bucket, ok := m.buckets[namespace]
+	if !ok {
+		bucket = make(map[string][]byte)
+		m.buckets[namespace] = bucket
+	}
+	bucket[string(key)] = copyBytes(value)
+}
+
+func (m *memKVStore) delete(namespace string, key []byte) {
+	if bucket, ok := m.buckets[namespace]; ok {
+		delete(bucket, string(key))
+	}
+}
+
+// bucketMemCountingIndexStats computes the stats of the counting index in a bucket of the in-memory KV store
+func bucketMemCountingIndexStats(ns string, bucket map[string][]byte, opts ...StatsOption) (CountingIndexStats, error) {
+	return countingIndexStats(ns, func(key []byte) ([]byte, error) {
+		return bucket[string(key)], nil
+	}, opts...)
+}
+
+func sortedKeys(bucket map[string][]byte) []string {
+	keys := make([]string, 0, len(bucket))
+	for key := range bucket {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func copyBytes(b []byte) []byte {
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemKVStore_RangeFrom(t *testing.T) {
+	require := require.New(t)
+	db := NewMemKVStore().(*memKVStore)
+	require.NoError(db.Start(context.Background()))
+	defer db.Stop(context.Background())
+
+	_, _, err := db.RangeFrom("ns", nil, 3, false)
+	require.Equal(ErrNotExist, errors.Cause(err))
+	for i := 0; i < 10; i++ {
+		require.NoError(db.Put("ns", []byte(fmt.Sprintf("key_%d", i)), []byte(fmt.Sprintf("value_%d", i))))
+	}
+	_, _, err = db.RangeFrom("ns", nil, 0, false)
+	require.Error(err)
+
+	keys, values, err := db.RangeFrom("ns", nil, 3, false)
+	require.NoError(err)
+	require.Equal([][]byte{[]byte("key_0"), []byte("key_1"), []byte("key_2")}, keys)
+	require.Equal([][]byte{[]byte("value_0"), []byte("value_1"), []byte("value_2")}, values)
+	keys, _, err = db.RangeFrom("ns", []byte("key_8"), 3, false)
+	require.NoError(err)
+	require.Equal([][]byte{[]byte("key_9")}, keys)
+	keys, _, err = db.RangeFrom("ns", nil, 2, true)
+	require.NoError(err)
+	require.Equal([][]byte{[]byte("key_9"), []byte("key_8")}, keys)
+	keys, _, err = db.RangeFrom("ns", []byte("key_45"), 2, false)
+	require.NoError(err)
+	require.Equal([][]byte{[]byte("key_5"), []byte("key_6")}, keys)
+	keys, _, err = db.RangeFrom("ns", []byte("key_45"), 2, true)
+	require.NoError(err)
+	require.Equal([][]byte{[]byte("key_4"), []byte("key_3")}, keys)
+	keys, _, err = db.RangeFrom("ns", []byte("a"), 2, true)
+	require.NoError(err)
+	require.Empty(keys)
+
+	// the records are iterated in the order of the keys
+	var iterated []string
+	require.NoError(db.ForEach("ns", func(k, v []byte) error {
+		iterated = append(iterated, string(k))
+		return nil
+	}))
+	require.Equal(10, len(iterated))
+	require.Equal("key_0", iterated[0])
+	require.Equal("key_9", iterated[9])
+	require.Equal(ErrNotExist, errors.Cause(db.ForEach("ns_1", func(k, v []byte) error { return nil })))
+}
+
+func TestMemKVStore_Commit(t *testing.T) {
+	require := require.New(t)
+	db := NewMemKVStore()
+	require.NoError(db.Start(context.Background()))
+	defer db.Stop(context.Background())
+
+	value := []byte("value_1")
+	require.NoError(db.Put(bucket1, testK1[0], value))
+	// the store keeps its own copy of the value
+	value[0] = 'V'
+	v, err := db.Get(bucket1, testK1[0])
+	require.NoError(err)
+	require.Equal(testV1[0], v)
+
+	batch := NewBatch()
+	batch.Put(bucket1, testK1[1], testV1[1], "")
+	batch.Delete(bucket1, testK1[0], "")
+	batch.Put(bucket2, testK2[0], testV2[0], "")
+	require.NoError(db.Commit(batch))
+	require.Equal(0, batch.Size())
+	_, err = db.Get(bucket1, testK1[0])
+	require.Equal(ErrNotExist, errors.Cause(err))
+	v, err = db.Get(bucket1, testK1[1])
+	require.NoError(err)
+	require.Equal(testV1[1], v)
+	v, err = db.Get(bucket2, testK2[0])
+	require.NoError(err)
+	require.Equal(testV2[0], v)
+}
@@ -9,11 +9,15 @@ package db
 import (
 	"context"
 	"io/ioutil"
+	"math"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/iotexproject/iotex-core/testutil"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -318,6 +322,10 @@ func TestDeleteBucket(t *testing.T) {
 		require.Equal(testV1[0], v)
 	}
 
+	t.Run("In-memory KV Store", func(t *testing.T) {
+		testFunc(NewMemKVStore(), t)
+	})
+
 	path := "test-cache-kv.bolt"
 	testFile, _ := ioutil.TempFile(os.TempDir(), path)
 	testPath := testFile.Name()
@@ -328,3 +336,137 @@ func TestDeleteBucket(t *testing.T) {
 		testFunc(NewBoltDB(cfg), t)
 	})
 }
+
+func TestCounter(t *testing.T) {
+	testFunc := func(kv KVStore, t *testing.T) {
+		require := require.New(t)
+
+		require.NoError(kv.Start(context.Background()))
+		defer func() {
+			require.NoError(kv.Stop(context.Background()))
+		}()
+
+		// a counter which has never been increased is 0
+		v, err := kv.CounterValue(bucket1, "actions")
+		require.NoError(err)
+		require.Equal(uint64(0), v)
+
+		const (
+			numWorkers = 20
+			numIncrs   = 50
+		)
+		var wg sync.WaitGroup
+		for i := 0; i < numWorkers; i++ {
+			wg.Add(1)
+			go func(delta uint64) {
+				defer wg.Done()
+				for j := 0; j < numIncrs; j++ {
+					_, err := kv.Incr(bucket1, "actions", delta)
+					require.NoError(err)
+				}
+			}(uint64(i + 1))
+		}
+		wg.Wait()
+		v, err = kv.CounterValue(bucket1, "actions")
+		require.NoError(err)
+		require.Equal(uint64(numIncrs*numWorkers*(numWorkers+1)/2), v)
+
+		// counters are independent of each other
+		v, err = kv.Incr(bucket2, "actions", 3)
+		require.NoError(err)
+		require.Equal(uint64(3), v)
+		v, err = kv.Incr(bucket1, "blocks", 0)
+		require.NoError(err)
+		require.Equal(uint64(0), v)
+
+		// overflow
+		_, err = kv.Incr(bucket2, "actions", math.MaxUint64)
+		require.Error(err)
+		v, err = kv.CounterValue(bucket2, "actions")
+		require.NoError(err)
+		require.Equal(uint64(3), v)
+
+		// a value which is not a counter
+		require.NoError(kv.Put(bucket2, []byte("value"), []byte("value")))
+		_, err = kv.Incr(bucket2, "value", 1)
+		require.Error(err)
+		_, err = kv.CounterValue(bucket2, "value")
+		require.Error(err)
+	}
+
+	t.Run("In-memory KV Store", func(t *testing.T) {
+		testFunc(NewMemKVStore(), t)
+	})
+
+	path := "test-counter.bolt"
+	testFile, _ := ioutil.TempFile(os.TempDir(), path)
+	testPath := testFile.Name()
+	cfg.DbPath = testPath
+	t.Run("Bolt DB", func(t *testing.T) {
+		testutil.CleanupPath(t, testPath)
+		defer testutil.CleanupPath(t, testPath)
+		testFunc(NewBoltDB(cfg), t)
+	})
+}
+
+func TestDeletePrefix(t *testing.T) {
+	testFunc := func(kv KVStore, t *testing.T) {
+		require := require.New(t)
+
+		require.NoError(kv.Start(context.Background()))
+		defer func() {
+			require.NoError(kv.Stop(context.Background()))
+		}()
+
+		// a namespace which doesn't exist has nothing to delete
+		deleted, err := kv.DeletePrefix(bucket1, []byte("sub"))
+		require.NoError(err)
+		require.Zero(deleted)
+
+		keys := []string{"su", "sub", "sub1/a", "sub1/b", "sub2/a", "subchain", "tub1", "a"}
+		for _, key := range keys {
+			require.NoError(kv.Put(bucket1, []byte(key), []byte(key)))
+			require.NoError(kv.Put(bucket2, []byte(key), []byte(key)))
+		}
+		deleted, err = kv.DeletePrefix(bucket1, []byte("sub1/"))
+		require.NoError(err)
+		require.Equal(uint64(2), deleted)
+		deleted, err = kv.DeletePrefix(bucket1, []byte("sub1/"))
+		require.NoError(err)
+		require.Zero(deleted)
+		deleted, err = kv.DeletePrefix(bucket1, []byte("sub"))
+		require.NoError(err)
+		require.Equal(uint64(3), deleted)
+		for _, key := range keys {
+			_, err := kv.Get(bucket1, []byte(key))
+			if strings.HasPrefix(key, "sub") {
+				require.Equal(ErrNotExist, errors.Cause(err))
+			} else {
+				require.NoError(err)
+			}
+			// the other namespace is left alone
+			v, err := kv.Get(bucket2, []byte(key))
+			require.NoError(err)
+			require.Equal([]byte(key), v)
+		}
+
+		// an empty prefix deletes the whole namespace
+		deleted, err = kv.DeletePrefix(bucket1, nil)
+		require.NoError(err)
+		require.Equal(uint64(3), deleted)
+	}
+
+	t.Run("In-memory KV Store", func(t *testing.T) {
+		testFunc(NewMemKVStore(), t)
+	})
+
+	path := "test-delete-prefix.bolt"
+	testFile, _ := ioutil.TempFile(os.TempDir(), path)
+	testPath := testFile.Name()
+	cfg.DbPath = testPath
+	t.Run("Bolt DB", func(t *testing.T) {
+		testutil.CleanupPath(t, testPath)
+		defer testutil.CleanupPath(t, testPath)
+		testFunc(NewBoltDB(cfg), t)
+	})
+}
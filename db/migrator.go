@@ -0,0 +1,200 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/pkg/log"
+)
+
+var (
+	// migrationVersionKey is the key of the schema version in the metadata bucket of a migrator
+	migrationVersionKey = []byte("version")
+	// migrationCursorKey is the key of the progress of the migration being run, which is prefixed by its version
+	migrationCursorKey = []byte("cursor")
+)
+
+type (
+	// MigrationFunc runs a part of a migration from the cursor, which is nil for the first part, by adding the writes
+	// to the batch. It returns the cursor of the next part, or done once the migration completes. The batch is
+	// committed along with the cursor, so the reads of a part don't see the writes of the same part. A part could be
+	// run again if the commit fails, hence it has to be idempotent.
+	MigrationFunc func(kvStore KVStore, batch KVStoreBatch, cursor []byte) (next []byte, done bool, err error)
+
+	// Migration is an upgrade step of the layout of the buckets to a schema version
+	Migration struct {
+		// Version is the schema version after the migration
+		Version uint64
+		// Name describes the migration
+		Name    string
+		Migrate MigrationFunc
+	}
+
+	// Migrator upgrades the layout of the buckets of a KV store by running the migrations in the order of their
+	// versions on Start, up to the target version. The schema version is stored in a metadata bucket. Each part of a
+	// migration is committed along with its progress in one transaction, and the version along with the last part,
+	// such that an interrupted migration resumes from the last part committed.
+	Migrator struct {
+		kvStore    KVStore
+		ns         string
+		migrations []Migration
+		target     uint64
+	}
+
+	// MigratorOption sets an option of the migrator
+	MigratorOption func(*Migrator) error
+)
+
+// MigrationTargetOption sets the version to migrate up to, which is the version of the last migration by default
+func MigrationTargetOption(version uint64) MigratorOption {
+	return func(m *Migrator) error {
+		m.target = version
+		return nil
+	}
+}
+
+// NewMigrator returns a migrator storing the schema version in a bucket of the KV store. The versions of the
+// migrations have to be positive and ascending.
+func NewMigrator(kvStore KVStore, namespace string, migrations []Migration, opts ...MigratorOption) (*Migrator, error) {
+	if kvStore == nil {
+		return nil, errors.New("kvStore is nil")
+	}
+	if namespace == "" {
+		return nil, errors.New("namespace is empty")
+	}
+	var last uint64
+	for _, migration := range migrations {
+		if migration.Version <= last {
+			return nil, errors.Errorf("version %d of migration %s isn't above %d", migration.Version, migration.Name, last)
+		}
+		if migration.Migrate == nil {
+			return nil, errors.Errorf("migration %s is nil", migration.Name)
+		}
+		last = migration.Version
+	}
+	m := &Migrator{
+		kvStore:    kvStore,
+		ns:         namespace,
+		migrations: migrations,
+		target:     last,
+	}
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Start runs the migrations above the schema version up to the target version. The KV store has to be started
+// already. It fails if the schema version is above the target, i.e., the KV store is of a newer layout. The context
+// being done interrupts the migrations between the parts.
+func (m *Migrator) Start(ctx context.Context) error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version > m.target {
+		return errors.Errorf("schema version %d is newer than the target version %d", version, m.target)
+	}
+	for _, migration := range m.migrations {
+		if migration.Version <= version || migration.Version > m.target {
+			continue
+		}
+		if err := m.migrate(ctx, migration); err != nil {
+			return errors.Wrapf(err, "failed to migrate to version %d by %s", migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// Stop does nothing, as the migrations complete on Start
+func (m *Migrator) Stop(_ context.Context) error { return nil }
+
+// Version returns the schema version, which is 0 before any migration
+func (m *Migrator) Version() (uint64, error) {
+	value, err := m.kvStore.Get(m.ns, migrationVersionKey)
+	switch {
+	case errors.Cause(err) == ErrNotExist:
+		return 0, nil
+	case err != nil:
+		return 0, errors.Wrap(err, "failed to get the schema version")
+	}
+	return decodeCounter(value)
+}
+
+func (m *Migrator) migrate(ctx context.Context, migration Migration) error {
+	cursor, err := m.cursor(migration.Version)
+	if err != nil {
+		return err
+	}
+	l := log.L().With(zap.String("namespace", m.ns), zap.Uint64("version", migration.Version))
+	if cursor == nil {
+		l.Info("Start the migration.", zap.String("migration", migration.Name))
+	} else {
+		l.Info("Resume the migration.", zap.String("migration", migration.Name))
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch := NewBatch()
+		next, done, err := migration.Migrate(m.kvStore, batch, cursor)
+		if err != nil {
+			return err
+		}
+		if done {
+			batch.Put(m.ns, migrationVersionKey, encodeCounter(migration.Version), "failed to update the schema version")
+			batch.Delete(m.ns, migrationCursorKey, "failed to delete the migration progress")
+		} else {
+			if len(next) == 0 {
+				return errors.New("cursor of the next part is empty")
+			}
+			batch.Put(
+				m.ns,
+				migrationCursorKey,
+				append(encodeCounter(migration.Version), next...),
+				"failed to record the migration progress",
+			)
+		}
+		if err := m.kvStore.Commit(batch); err != nil {
+			return err
+		}
+		if done {
+			l.Info("Finished the migration.", zap.String("migration", migration.Name))
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// cursor returns the progress of the migration to a version, nil if it hasn't started
+func (m *Migrator) cursor(version uint64) ([]byte, error) {
+	value, err := m.kvStore.Get(m.ns, migrationCursorKey)
+	switch {
+	case errors.Cause(err) == ErrNotExist:
+		return nil, nil
+	case err != nil:
+		return nil, errors.Wrap(err, "failed to get the migration progress")
+	}
+	if len(value) <= 8 {
+		return nil, errors.Errorf("invalid migration progress of length %d", len(value))
+	}
+	cursorVersion, err := decodeCounter(value[:8])
+	if err != nil {
+		return nil, err
+	}
+	if cursorVersion != version {
+		// the progress of another migration, which is unexpected since the version is updated along with deleting it
+		return nil, errors.Errorf("migration progress is of version %d rather than %d", cursorVersion, version)
+	}
+	return value[8:], nil
+}
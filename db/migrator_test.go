@@ -0,0 +1,168 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/testutil"
+)
+
+func TestMigrator(t *testing.T) {
+	for _, backend := range []string{config.MemDBBackend, config.BoltDBBackend} {
+		t.Run(backend, func(t *testing.T) {
+			require := require.New(t)
+			path, err := ioutil.TempFile("", "migrator")
+			require.NoError(err)
+			defer testutil.CleanupPath(t, path.Name())
+			kv := NewKVStore(config.DB{Backend: backend, DbPath: path.Name(), NumRetries: 3})
+			require.NoError(kv.Start(context.Background()))
+			defer func() {
+				require.NoError(kv.Stop(context.Background()))
+			}()
+			testMigrator(t, kv)
+		})
+	}
+}
+
+func testMigrator(t *testing.T, kv KVStore) {
+	require := require.New(t)
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		require.NoError(kv.Put("old", []byte{byte(i)}, []byte(fmt.Sprintf("value_%d", i))))
+	}
+
+	// the first migration copies the values to a new bucket 3 at a time, and the second one deletes the old bucket
+	var (
+		runs      []string
+		failAfter = -1
+	)
+	copyValues := func(kv KVStore, batch KVStoreBatch, cursor []byte) ([]byte, bool, error) {
+		var start uint64
+		if cursor != nil {
+			start = binary.BigEndian.Uint64(cursor)
+		}
+		if failAfter >= 0 && start >= uint64(failAfter) {
+			return nil, false, errors.New("interrupted")
+		}
+		runs = append(runs, fmt.Sprintf("copy_%d", start))
+		for i := start; i < start+3 && i < 10; i++ {
+			value, err := kv.Get("old", []byte{byte(i)})
+			if err != nil {
+				return nil, false, err
+			}
+			batch.Put("new", []byte{byte(i)}, value, "failed to copy %d", i)
+		}
+		if start+3 >= 10 {
+			return nil, true, nil
+		}
+		next := make([]byte, 8)
+		binary.BigEndian.PutUint64(next, start+3)
+		return next, false, nil
+	}
+	deleteOld := func(kv KVStore, batch KVStoreBatch, _ []byte) ([]byte, bool, error) {
+		runs = append(runs, "delete")
+		for i := 0; i < 10; i++ {
+			batch.Delete("old", []byte{byte(i)}, "failed to delete %d", i)
+		}
+		return nil, true, nil
+	}
+	migrations := []Migration{
+		{Version: 1, Name: "copy", Migrate: copyValues},
+		{Version: 2, Name: "delete", Migrate: deleteOld},
+	}
+
+	// invalid migrations
+	_, err := NewMigrator(nil, "meta", migrations)
+	require.Error(err)
+	_, err = NewMigrator(kv, "", migrations)
+	require.Error(err)
+	_, err = NewMigrator(kv, "meta", []Migration{migrations[1], migrations[0]})
+	require.Error(err)
+	_, err = NewMigrator(kv, "meta", []Migration{{Version: 0, Name: "zero", Migrate: deleteOld}})
+	require.Error(err)
+	_, err = NewMigrator(kv, "meta", []Migration{{Version: 1, Name: "nil"}})
+	require.Error(err)
+
+	// a fresh DB is of version 0, and the migration interrupted after 2 parts stays at it
+	m, err := NewMigrator(kv, "meta", migrations)
+	require.NoError(err)
+	version, err := m.Version()
+	require.NoError(err)
+	require.Zero(version)
+	failAfter = 6
+	require.Error(m.Start(ctx))
+	require.Equal([]string{"copy_0", "copy_3"}, runs)
+	version, err = m.Version()
+	require.NoError(err)
+	require.Zero(version)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.Equal(context.Canceled, errors.Cause(m.Start(cancelled)))
+
+	// the migration resumes from the last part committed, followed by the next migration
+	failAfter = -1
+	runs = nil
+	m, err = NewMigrator(kv, "meta", migrations)
+	require.NoError(err)
+	require.NoError(m.Start(ctx))
+	require.NoError(m.Stop(ctx))
+	require.Equal([]string{"copy_6", "copy_9", "delete"}, runs)
+	version, err = m.Version()
+	require.NoError(err)
+	require.Equal(uint64(2), version)
+	for i := 0; i < 10; i++ {
+		value, err := kv.Get("new", []byte{byte(i)})
+		require.NoError(err)
+		require.Equal([]byte(fmt.Sprintf("value_%d", i)), value)
+		_, err = kv.Get("old", []byte{byte(i)})
+		require.Equal(ErrNotExist, errors.Cause(err))
+	}
+	_, err = kv.Get("meta", migrationCursorKey)
+	require.Equal(ErrNotExist, errors.Cause(err))
+
+	// the version is persisted, so the migrations don't run again
+	runs = nil
+	m, err = NewMigrator(kv, "meta", migrations)
+	require.NoError(err)
+	require.NoError(m.Start(ctx))
+	require.Empty(runs)
+
+	// a new migration runs alone, up to the target
+	migrations = append(migrations, Migration{
+		Version: 3,
+		Name:    "third",
+		Migrate: func(KVStore, KVStoreBatch, []byte) ([]byte, bool, error) {
+			runs = append(runs, "third")
+			return nil, true, nil
+		},
+	})
+	m, err = NewMigrator(kv, "meta", migrations, MigrationTargetOption(2))
+	require.NoError(err)
+	require.NoError(m.Start(ctx))
+	require.Empty(runs)
+	m, err = NewMigrator(kv, "meta", migrations)
+	require.NoError(err)
+	require.NoError(m.Start(ctx))
+	require.Equal([]string{"third"}, runs)
+	version, err = m.Version()
+	require.NoError(err)
+	require.Equal(uint64(3), version)
+
+	// the DB of a newer version fails an older migrator
+	m, err = NewMigrator(kv, "meta", migrations[:2])
+	require.NoError(err)
+	require.Error(m.Start(ctx))
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package db
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultPruneBatchSize is the default max number of deletes in a transaction
+const DefaultPruneBatchSize = 10000
+
+var (
+	prunedEntriesMtc = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "iotex_db_pruned_entries",
+			Help: "Number of entries pruned from the db.",
+		},
+		[]string{"namespace"},
+	)
+	pruningDurationMtc = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "iotex_db_pruning_duration",
+			Help: "Duration of the last pruning in milliseconds.",
+		},
+		[]string{},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(prunedEntriesMtc)
+	prometheus.MustRegister(pruningDurationMtc)
+}
+
+type (
+	// RangeKVStore is a KV store which supports paginated range queries
+	RangeKVStore interface {
+		KVStore
+		// RangeFrom returns at most limit records of a namespace after the start key, in ascending or descending order
+		RangeFrom(string, []byte, int, bool) ([][]byte, [][]byte, error)
+	}
+
+	// RetentionPolicy defines which entries of a bucket to keep. Entries beyond the retention are pruned.
+	RetentionPolicy struct {
+		// Namespace is the bucket to prune
+		Namespace string
+		// KeepEntries is the number of latest entries to keep, 0 for no limit
+		KeepEntries uint64
+		// KeepHeights is the number of latest heights to keep, 0 for no limit. The height of an entry is the first 8
+		// bytes of its key in big endian, and entries with shorter keys are never pruned.
+		KeepHeights uint64
+		// Index is the counting index stored in the bucket, if any. Only KeepEntries applies to a counting index, and
+		// the remaining entries keep their positions.
+		Index CountingIndex
+	}
+
+	// Pruner deletes the entries beyond the retention of the buckets, in bounded-size transactions so that it never
+	// blocks the writers for long
+	Pruner struct {
+		kvStore   RangeKVStore
+		policies  []RetentionPolicy
+		batchSize int
+	}
+
+	// PrunerOption sets an option of the pruner
+	PrunerOption func(*Pruner) error
+)
+
+// PruneBatchSizeOption sets the max number of deletes in a transaction
+func PruneBatchSizeOption(size int) PrunerOption {
+	return func(p *Pruner) error {
+		if size <= 0 {
+			return errors.Errorf("invalid batch size %d", size)
+		}
+		p.batchSize = size
+		return nil
+	}
+}
+
+// NewPruner creates a pruner of the buckets in the KV store, which has to support range queries
+func NewPruner(kvStore KVStore, policies []RetentionPolicy, opts ...PrunerOption) (*Pruner, error) {
+	rangeStore, ok := kvStore.(RangeKVStore)
+	if !ok {
+		return nil, errors.New("kvStore doesn't support range queries")
+	}
+	for _, policy := range policies {
+		if policy.Index != nil && policy.KeepHeights != 0 {
+			return nil, errors.Errorf("counting index %s can only be pruned by entries", policy.Index.Namespace())
+		}
+		if policy.Index == nil && policy.Namespace == "" {
+			return nil, errors.New("namespace is empty")
+		}
+	}
+	p := &Pruner{
+		kvStore:   rangeStore,
+		policies:  policies,
+		batchSize: DefaultPruneBatchSize,
+	}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Prune deletes the entries beyond the retention of each bucket as of the tip height
+func (p *Pruner) Prune(tipHeight uint64) error {
+	start := time.Now()
+	defer func() {
+		pruningDurationMtc.WithLabelValues().Set(float64(time.Since(start) / time.Millisecond))
+	}()
+	for _, policy := range p.policies {
+		var (
+			pruned uint64
+			err    error
+		)
+		ns := policy.Namespace
+		if policy.Index != nil {
+			ns = policy.Index.Namespace()
+			pruned, err = p.pruneCountingIndex(policy)
+		} else {
+			pruned, err = p.pruneBucket(policy, tipHeight)
+		}
+		prunedEntriesMtc.WithLabelValues(ns).Add(float64(pruned))
+		if err != nil {
+			return errors.Wrapf(err, "failed to prune %s", ns)
+		}
+	}
+	return nil
+}
+
+func (p *Pruner) pruneCountingIndex(policy RetentionPolicy) (uint64, error) {
+	if policy.KeepEntries == 0 {
+		return 0, nil
+	}
+	size, err := policy.Index.Size()
+	if err != nil || size <= policy.KeepEntries {
+		return 0, err
+	}
+	return policy.Index.PruneFront(size-policy.KeepEntries, p.batchSize)
+}
+
+func (p *Pruner) pruneBucket(policy RetentionPolicy, tipHeight uint64) (uint64, error) {
+	var pruned uint64
+	if policy.KeepEntries != 0 {
+		count, err := p.countEntries(policy.Namespace)
+		if err != nil {
+			return 0, err
+		}
+		if count > policy.KeepEntries {
+			excess := count - policy.KeepEntries
+			n, err := p.deleteFront(policy.Namespace, func([]byte) (bool, bool) {
+				if excess == 0 {
+					return false, true
+				}
+				excess--
+				return true, false
+			})
+			pruned += n
+			if err != nil {
+				return pruned, err
+			}
+		}
+	}
+	if policy.KeepHeights != 0 && tipHeight > policy.KeepHeights {
+		// entries with heights in (tipHeight - KeepHeights, tipHeight] are kept
+		threshold := tipHeight - policy.KeepHeights
+		n, err := p.deleteFront(policy.Namespace, func(key []byte) (bool, bool) {
+			if len(key) < 8 {
+				return false, false
+			}
+			if binary.BigEndian.Uint64(key[:8]) > threshold {
+				return false, true
+			}
+			return true, false
+		})
+		pruned += n
+		if err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, nil
+}
+
+func (p *Pruner) countEntries(ns string) (uint64, error) {
+	var (
+		count  uint64
+		cursor []byte
+	)
+	for {
+		keys, _, err := p.kvStore.RangeFrom(ns, cursor, p.batchSize, false)
+		switch {
+		case errors.Cause(err) == ErrNotExist:
+			return 0, nil
+		case err != nil:
+			return 0, err
+		case len(keys) == 0:
+			return count, nil
+		}
+		count += uint64(len(keys))
+		cursor = keys[len(keys)-1]
+	}
+}
+
+// deleteFront scans the entries in ascending order of keys, and deletes those selected by filter until it tells to
+// stop, committing at most batchSize deletes in a transaction
+func (p *Pruner) deleteFront(ns string, filter func([]byte) (bool, bool)) (uint64, error) {
+	var (
+		pruned uint64
+		cursor []byte
+	)
+	for {
+		keys, _, err := p.kvStore.RangeFrom(ns, cursor, p.batchSize, false)
+		switch {
+		case errors.Cause(err) == ErrNotExist:
+			return pruned, nil
+		case err != nil:
+			return pruned, err
+		case len(keys) == 0:
+			return pruned, nil
+		}
+		batch := NewBatch()
+		done := false
+		for _, key := range keys {
+			del, stop := filter(key)
+			if stop {
+				done = true
+				break
+			}
+			if del {
+				batch.Delete(ns, key, "failed to delete key %x", key)
+			}
+		}
+		// the batch is cleared upon commit
+		if size := batch.Size(); size > 0 {
+			if err := p.kvStore.Commit(batch); err != nil {
+				return pruned, err
+			}
+			pruned += uint64(size)
+		}
+		if done {
+			return pruned, nil
+		}
+		cursor = keys[len(keys)-1]
+	}
+}
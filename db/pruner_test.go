@@ -0,0 +1,99 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/testutil"
+)
+
+func TestPruner(t *testing.T) {
+	require := require.New(t)
+	path, err := ioutil.TempFile("", "pruner")
+	require.NoError(err)
+	defer testutil.CleanupPath(t, path.Name())
+	kv := NewBoltDB(config.DB{DbPath: path.Name(), NumRetries: 3})
+	require.NoError(kv.Start(context.Background()))
+	defer kv.Stop(context.Background())
+
+	heightKey := func(height uint64, i int) []byte {
+		key := make([]byte, 9)
+		binary.BigEndian.PutUint64(key, height)
+		key[8] = byte(i)
+		return key
+	}
+	for h := uint64(1); h <= 20; h++ {
+		for i := 0; i < 2; i++ {
+			require.NoError(kv.Put("byHeight", heightKey(h, i), []byte{}))
+		}
+		require.NoError(kv.Put("byEntries", heightKey(h, 0), []byte{}))
+	}
+	require.NoError(kv.Put("byHeight", []byte("meta"), []byte{}))
+	index, err := NewCountingIndex(kv, "counting")
+	require.NoError(err)
+	for i := 0; i < 25; i++ {
+		require.NoError(index.Add([]byte(fmt.Sprintf("value_%d", i))))
+	}
+
+	_, err = NewPruner(struct{ KVStore }{NewMemKVStore()}, nil)
+	require.Error(err)
+	_, err = NewPruner(kv, []RetentionPolicy{{Index: index, KeepHeights: 1}})
+	require.Error(err)
+	_, err = NewPruner(kv, []RetentionPolicy{{KeepEntries: 1}})
+	require.Error(err)
+	_, err = NewPruner(kv, nil, PruneBatchSizeOption(0))
+	require.Error(err)
+	p, err := NewPruner(kv, []RetentionPolicy{
+		{Namespace: "byHeight", KeepHeights: 5},
+		{Namespace: "byEntries", KeepEntries: 8},
+		{Index: index, KeepEntries: 10},
+		{Namespace: "nonexistent", KeepEntries: 1},
+	}, PruneBatchSizeOption(3))
+	require.NoError(err)
+
+	// nothing to prune by heights yet
+	require.NoError(p.Prune(5))
+	keys, _, err := kv.(RangeKVStore).RangeFrom("byHeight", nil, 100, false)
+	require.NoError(err)
+	require.Equal(41, len(keys))
+
+	require.NoError(p.Prune(20))
+	keys, _, err = kv.(RangeKVStore).RangeFrom("byHeight", nil, 100, false)
+	require.NoError(err)
+	require.Equal(11, len(keys))
+	require.Equal(heightKey(16, 0), keys[0])
+	require.Equal([]byte("meta"), keys[len(keys)-1])
+
+	keys, _, err = kv.(RangeKVStore).RangeFrom("byEntries", nil, 100, false)
+	require.NoError(err)
+	require.Equal(8, len(keys))
+	require.Equal(heightKey(13, 0), keys[0])
+
+	offset, err := index.Offset()
+	require.NoError(err)
+	require.Equal(uint64(15), offset)
+	_, err = index.Get(14)
+	require.Equal(ErrNotExist, errors.Cause(err))
+	value, err := index.Get(15)
+	require.NoError(err)
+	require.Equal([]byte("value_15"), value)
+
+	// pruning again is a no-op
+	require.NoError(p.Prune(20))
+	keys, _, err = kv.(RangeKVStore).RangeFrom("byEntries", nil, 100, false)
+	require.NoError(err)
+	require.Equal(8, len(keys))
+}
@@ -0,0 +1,307 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/pkg/log"
+)
+
+// taskIDKey is the counter of the task IDs, kept in a bucket along with the task bucket
+const taskIDKey = "taskID"
+
+type (
+	// TaskHandler handles a task once it is due
+	TaskHandler func(id uint64, payload []byte)
+
+	// TaskQueue runs the tasks scheduled at a time once they are due. The tasks are persisted in a bucket of the KV
+	// store, keyed by the time they are due followed by their IDs, such that the pending tasks are loaded again upon
+	// restart. A task is deleted only after its handler returns, hence the delivery is at-least-once: a task handled
+	// right before a crash is handled again after the restart, and the handler should be idempotent. Likewise, a task
+	// cancelled while it is being run may still be handled.
+	TaskQueue struct {
+		mutex   sync.Mutex
+		kvStore RangeKVStore
+		ns      string
+		clk     clock.Clock
+		handler TaskHandler
+		// pending maps the IDs of the pending tasks to their keys
+		pending map[uint64][]byte
+		wake    chan struct{}
+		quit    chan struct{}
+		done    chan struct{}
+	}
+
+	// TaskQueueOption sets an option of the task queue
+	TaskQueueOption func(*TaskQueue) error
+)
+
+// TaskClockOption sets the clock telling the time the tasks are due at
+func TaskClockOption(clk clock.Clock) TaskQueueOption {
+	return func(q *TaskQueue) error {
+		if clk == nil {
+			return errors.New("clock is nil")
+		}
+		q.clk = clk
+		return nil
+	}
+}
+
+// NewTaskQueue returns a task queue persisted in a bucket of the KV store, which has to support range queries
+func NewTaskQueue(kvStore KVStore, namespace string, handler TaskHandler, opts ...TaskQueueOption) (*TaskQueue, error) {
+	rangeStore, ok := kvStore.(RangeKVStore)
+	if !ok {
+		return nil, errors.New("kvStore doesn't support range queries")
+	}
+	if namespace == "" {
+		return nil, errors.New("namespace is empty")
+	}
+	if handler == nil {
+		return nil, errors.New("handler is nil")
+	}
+	q := &TaskQueue{
+		kvStore: rangeStore,
+		ns:      namespace,
+		clk:     clock.New(),
+		handler: handler,
+		pending: make(map[uint64][]byte),
+	}
+	for _, opt := range opts {
+		if err := opt(q); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// Start loads the pending tasks and starts running them once they are due. The KV store has to be started already.
+func (q *TaskQueue) Start(_ context.Context) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.quit != nil {
+		return errors.New("task queue has started")
+	}
+	var cursor []byte
+	for {
+		keys, _, err := q.kvStore.RangeFrom(q.ns, cursor, DefaultPruneBatchSize, false)
+		if errors.Cause(err) == ErrNotExist || err == nil && len(keys) == 0 {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to load the pending tasks")
+		}
+		for _, key := range keys {
+			_, id, err := decodeTaskKey(key)
+			if err != nil {
+				return err
+			}
+			q.pending[id] = key
+		}
+		cursor = keys[len(keys)-1]
+	}
+	q.wake = make(chan struct{}, 1)
+	q.quit = make(chan struct{})
+	q.done = make(chan struct{})
+	go q.run(q.wake, q.quit, q.done)
+	return nil
+}
+
+// Stop stops running the tasks, after the handler of the task being run, if any, returns. The pending tasks are kept.
+func (q *TaskQueue) Stop(_ context.Context) error {
+	q.mutex.Lock()
+	quit, done := q.quit, q.done
+	q.quit = nil
+	q.mutex.Unlock()
+
+	if quit == nil {
+		return nil
+	}
+	close(quit)
+	<-done
+	return nil
+}
+
+// Schedule schedules a task at a time, and returns its ID
+func (q *TaskQueue) Schedule(at time.Time, payload []byte) (uint64, error) {
+	if at.UnixNano() < 0 {
+		return 0, errors.Errorf("invalid time %s", at)
+	}
+	id, err := q.kvStore.Incr(taskIDNamespace(q.ns), taskIDKey, 1)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to allocate the task ID")
+	}
+	key := encodeTaskKey(at, id)
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if err := q.kvStore.Put(q.ns, key, payload); err != nil {
+		return 0, errors.Wrapf(err, "failed to put task %d", id)
+	}
+	q.pending[id] = key
+	q.notify()
+	return id, nil
+}
+
+// Cancel cancels a pending task, or returns ErrNotExist if the task isn't pending
+func (q *TaskQueue) Cancel(id uint64) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	key, ok := q.pending[id]
+	if !ok {
+		return errors.Wrapf(ErrNotExist, "task %d is not pending", id)
+	}
+	if err := q.kvStore.Delete(q.ns, key); err != nil {
+		return errors.Wrapf(err, "failed to delete task %d", id)
+	}
+	delete(q.pending, id)
+	q.notify()
+	return nil
+}
+
+// Len returns the number of the pending tasks
+func (q *TaskQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.pending)
+}
+
+// notify wakes up the runner to check the next task again
+func (q *TaskQueue) notify() {
+	if q.wake == nil {
+		return
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *TaskQueue) run(wake chan struct{}, quit <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	// the timer wakes up the runner rather than sending the time, which never blocks the clock if the runner has moved
+	// on, e.g., to a task scheduled earlier
+	alarm := func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	for {
+		next, ok := q.runDue(quit)
+		var timer *clock.Timer
+		if ok {
+			timer = q.clk.AfterFunc(next.Sub(q.clk.Now()), alarm)
+			// the clock may pass the time while the timer is being set, e.g., a mock clock moved by another goroutine
+			if !next.After(q.clk.Now()) {
+				timer.Stop()
+				continue
+			}
+		}
+		select {
+		case <-quit:
+		case <-wake:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-quit:
+			return
+		default:
+		}
+	}
+}
+
+// runDue runs the tasks due by now in the order of the time they are due, and returns the time the next task is due
+func (q *TaskQueue) runDue(quit <-chan struct{}) (time.Time, bool) {
+	for {
+		select {
+		case <-quit:
+			return time.Time{}, false
+		default:
+		}
+		keys, values, err := q.kvStore.RangeFrom(q.ns, nil, 1, false)
+		if errors.Cause(err) == ErrNotExist || err == nil && len(keys) == 0 {
+			return time.Time{}, false
+		}
+		if err != nil {
+			log.L().Error("Failed to get the next task.", zap.String("namespace", q.ns), zap.Error(err))
+			// retry once a task is scheduled or cancelled
+			return time.Time{}, false
+		}
+		at, id, err := decodeTaskKey(keys[0])
+		if err != nil {
+			log.L().Error("Invalid task.", zap.String("namespace", q.ns), zap.Error(err))
+			return time.Time{}, false
+		}
+		if at.After(q.clk.Now()) {
+			return at, true
+		}
+		if !q.isPending(id) {
+			continue
+		}
+		q.handler(id, values[0])
+		if err := q.remove(id, keys[0]); err != nil {
+			log.L().Error("Failed to delete the task.", zap.Uint64("id", id), zap.Error(err))
+			return time.Time{}, false
+		}
+	}
+}
+
+func (q *TaskQueue) isPending(id uint64) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	_, ok := q.pending[id]
+	return ok
+}
+
+// remove deletes a task which has been run, unless it has been cancelled already
+func (q *TaskQueue) remove(id uint64, key []byte) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if _, ok := q.pending[id]; !ok {
+		return nil
+	}
+	if err := q.kvStore.Delete(q.ns, key); err != nil {
+		return err
+	}
+	delete(q.pending, id)
+	return nil
+}
+
+func taskIDNamespace(ns string) string {
+	return ns + "_id"
+}
+
+// encodeTaskKey returns the key of a task, the time it is due in unix nanoseconds followed by its ID
+func encodeTaskKey(at time.Time, id uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(at.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], id)
+	return key
+}
+
+func decodeTaskKey(key []byte) (time.Time, uint64, error) {
+	if len(key) != 16 {
+		return time.Time{}, 0, errors.Errorf("task key of %d bytes, 16 expected", len(key))
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key))), binary.BigEndian.Uint64(key[8:]), nil
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/testutil"
+)
+
+// taskRecorder records the payloads of the tasks handled, in the order they are handled
+type taskRecorder struct {
+	mutex    sync.Mutex
+	payloads []string
+}
+
+func (r *taskRecorder) handle(_ uint64, payload []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.payloads = append(r.payloads, string(payload))
+}
+
+func (r *taskRecorder) handled() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]string{}, r.payloads...)
+}
+
+// waitHandled waits until n tasks in total have been handled
+func (r *taskRecorder) waitHandled(t *testing.T, n int) []string {
+	require.NoError(t, testutil.WaitUntil(time.Millisecond, 5*time.Second, func() (bool, error) {
+		return len(r.handled()) >= n, nil
+	}))
+	return r.handled()
+}
+
+// waitLen waits until the task is removed after its handler returns
+func waitLen(t *testing.T, q *TaskQueue, n int) {
+	require.NoError(t, testutil.WaitUntil(time.Millisecond, 5*time.Second, func() (bool, error) {
+		return q.Len() == n, nil
+	}))
+}
+
+func TestTaskQueue(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	kv := NewMemKVStore()
+	require.NoError(kv.Start(ctx))
+	recorder := &taskRecorder{}
+
+	_, err := NewTaskQueue(kv, "", recorder.handle)
+	require.Error(err)
+	_, err = NewTaskQueue(kv, "tasks", nil)
+	require.Error(err)
+	_, err = NewTaskQueue(kv, "tasks", recorder.handle, TaskClockOption(nil))
+	require.Error(err)
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	q, err := NewTaskQueue(kv, "tasks", recorder.handle, TaskClockOption(c))
+	require.NoError(err)
+	require.NoError(q.Start(ctx))
+	defer func() {
+		require.NoError(q.Stop(ctx))
+	}()
+	require.Error(q.Start(ctx))
+
+	t.Run("reordering", func(t *testing.T) {
+		// the tasks run in the order of the time they are due, regardless of the order they are scheduled
+		now := c.Now()
+		for _, task := range []struct {
+			after   time.Duration
+			payload string
+		}{
+			{3 * time.Second, "c"},
+			{time.Second, "a"},
+			{2 * time.Second, "b"},
+			{time.Second, "a2"},
+		} {
+			_, err := q.Schedule(now.Add(task.after), []byte(task.payload))
+			require.NoError(err)
+		}
+		require.Equal(4, q.Len())
+		c.Add(500 * time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		require.Empty(recorder.handled())
+
+		c.Add(500 * time.Millisecond)
+		require.Equal([]string{"a", "a2"}, recorder.waitHandled(t, 2))
+		c.Add(5 * time.Second)
+		require.Equal([]string{"a", "a2", "b", "c"}, recorder.waitHandled(t, 4))
+		waitLen(t, q, 0)
+
+		// a task due already runs right away
+		_, err := q.Schedule(c.Now().Add(-time.Minute), []byte("d"))
+		require.NoError(err)
+		require.Equal([]string{"a", "a2", "b", "c", "d"}, recorder.waitHandled(t, 5))
+		waitLen(t, q, 0)
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		now := c.Now()
+		id1, err := q.Schedule(now.Add(time.Second), []byte("e"))
+		require.NoError(err)
+		id2, err := q.Schedule(now.Add(2*time.Second), []byte("f"))
+		require.NoError(err)
+		require.NotEqual(id1, id2)
+		require.NoError(q.Cancel(id1))
+		require.Equal(ErrNotExist, errors.Cause(q.Cancel(id1)))
+		require.Equal(ErrNotExist, errors.Cause(q.Cancel(id2+100)))
+		require.Equal(1, q.Len())
+
+		c.Add(2 * time.Second)
+		require.Equal([]string{"a", "a2", "b", "c", "d", "f"}, recorder.waitHandled(t, 6))
+		waitLen(t, q, 0)
+		// a task handled already can't be cancelled
+		require.Equal(ErrNotExist, errors.Cause(q.Cancel(id2)))
+	})
+}
+
+func TestTaskQueueRestart(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	path, err := ioutil.TempFile("", "taskqueue")
+	require.NoError(err)
+	defer testutil.CleanupPath(t, path.Name())
+	cfg := config.DB{DbPath: path.Name(), NumRetries: 3}
+	c := clock.NewMock()
+	c.Add(time.Hour)
+	now := c.Now()
+
+	kv := NewBoltDB(cfg)
+	require.NoError(kv.Start(ctx))
+	recorder := &taskRecorder{}
+	q, err := NewTaskQueue(kv, "tasks", recorder.handle, TaskClockOption(c))
+	require.NoError(err)
+	require.NoError(q.Start(ctx))
+	// schedule the tasks before the restart
+	ids := map[uint64]bool{}
+	for i, payload := range []string{"a", "b", "c"} {
+		id, err := q.Schedule(now.Add(time.Duration(i+1)*time.Second), []byte(payload))
+		require.NoError(err)
+		ids[id] = true
+	}
+	c.Add(time.Second)
+	require.Equal([]string{"a"}, recorder.waitHandled(t, 1))
+	waitLen(t, q, 2)
+	require.NoError(q.Stop(ctx))
+	require.NoError(kv.Stop(ctx))
+
+	// the task due while the node is down runs right after the restart, followed by the one due later
+	c.Add(time.Second)
+	kv = NewBoltDB(cfg)
+	require.NoError(kv.Start(ctx))
+	defer func() {
+		require.NoError(kv.Stop(ctx))
+	}()
+	recorder = &taskRecorder{}
+	q, err = NewTaskQueue(kv, "tasks", recorder.handle, TaskClockOption(c))
+	require.NoError(err)
+	require.Equal(0, q.Len())
+	require.NoError(q.Start(ctx))
+	defer func() {
+		require.NoError(q.Stop(ctx))
+	}()
+	require.Equal([]string{"b"}, recorder.waitHandled(t, 1))
+	waitLen(t, q, 1)
+	// the task IDs keep increasing across the restart
+	id, err := q.Schedule(now.Add(4*time.Second), []byte("d"))
+	require.NoError(err)
+	require.False(ids[id])
+	c.Add(2 * time.Second)
+	require.Equal([]string{"b", "c", "d"}, recorder.waitHandled(t, 3))
+	waitLen(t, q, 0)
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package db
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/pkg/errors"
+)
+
+type (
+	// TTLBucket is a bucket of the KV store whose entries expire after a TTL since they are put. An expired entry is
+	// deleted on access, and Sweep deletes all the expired entries of the bucket, e.g., periodically. Hence the
+	// entries of a TTL bucket should only be accessed via the bucket.
+	TTLBucket struct {
+		mutex     sync.Mutex
+		kvStore   KVStore
+		ns        string
+		ttl       time.Duration
+		clk       clock.Clock
+		batchSize int
+	}
+
+	// TTLBucketOption sets an option of the TTL bucket
+	TTLBucketOption func(*TTLBucket) error
+)
+
+// TTLClockOption sets the clock telling the time the entries are put and expire at
+func TTLClockOption(clk clock.Clock) TTLBucketOption {
+	return func(b *TTLBucket) error {
+		if clk == nil {
+			return errors.New("clock is nil")
+		}
+		b.clk = clk
+		return nil
+	}
+}
+
+// TTLSweepBatchSizeOption sets the max number of deletes in a transaction of Sweep
+func TTLSweepBatchSizeOption(size int) TTLBucketOption {
+	return func(b *TTLBucket) error {
+		if size <= 0 {
+			return errors.Errorf("invalid batch size %d", size)
+		}
+		b.batchSize = size
+		return nil
+	}
+}
+
+// NewTTLBucket returns a bucket of the KV store whose entries expire after the TTL
+func NewTTLBucket(kvStore KVStore, namespace string, ttl time.Duration, opts ...TTLBucketOption) (*TTLBucket, error) {
+	if kvStore == nil {
+		return nil, errors.New("kvStore is nil")
+	}
+	if namespace == "" {
+		return nil, errors.New("namespace is empty")
+	}
+	if ttl <= 0 {
+		return nil, errors.Errorf("invalid ttl %s", ttl)
+	}
+	b := &TTLBucket{
+		kvStore:   kvStore,
+		ns:        namespace,
+		ttl:       ttl,
+		clk:       clock.New(),
+		batchSize: DefaultPruneBatchSize,
+	}
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Namespace returns the bucket of the KV store
+func (b *TTLBucket) Namespace() string { return b.ns }
+
+// Put stores the value of a key, which expires after the TTL since now
+func (b *TTLBucket) Put(key, value []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// the value is stored after the time it is put, in unix nanoseconds
+	data := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(data, uint64(b.clk.Now().UnixNano()))
+	copy(data[8:], value)
+	return b.kvStore.Put(b.ns, key, data)
+}
+
+// Get returns the value of a key, or ErrNotExist if it doesn't exist or has expired. An expired entry is deleted.
+func (b *TTLBucket) Get(key []byte) ([]byte, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	data, err := b.kvStore.Get(b.ns, key)
+	if err != nil {
+		return nil, err
+	}
+	expired, err := b.expired(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid entry of key %x", key)
+	}
+	if expired {
+		if err := b.kvStore.Delete(b.ns, key); err != nil {
+			return nil, errors.Wrapf(err, "failed to delete expired key %x", key)
+		}
+		return nil, errors.Wrapf(ErrNotExist, "key = %x has expired", key)
+	}
+	return data[8:], nil
+}
+
+// Delete deletes the entry of a key
+func (b *TTLBucket) Delete(key []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.kvStore.Delete(b.ns, key)
+}
+
+// Sweep deletes all the expired entries, at most batchSize deletes per transaction, and returns the number of entries
+// deleted. The KV store has to support range queries.
+func (b *TTLBucket) Sweep() (uint64, error) {
+	rangeStore, ok := b.kvStore.(RangeKVStore)
+	if !ok {
+		return 0, errors.New("kvStore doesn't support range queries")
+	}
+	var (
+		swept  uint64
+		cursor []byte
+	)
+	for {
+		keys, values, err := rangeStore.RangeFrom(b.ns, cursor, b.batchSize, false)
+		switch {
+		case errors.Cause(err) == ErrNotExist:
+			return swept, nil
+		case err != nil:
+			return swept, err
+		case len(keys) == 0:
+			return swept, nil
+		}
+		n, err := b.deleteExpired(keys, values)
+		swept += n
+		if err != nil {
+			return swept, err
+		}
+		cursor = keys[len(keys)-1]
+	}
+}
+
+// deleteExpired deletes the keys of the expired values in a transaction. The keys are checked again under the lock,
+// such that an entry put since the range query isn't deleted.
+func (b *TTLBucket) deleteExpired(keys, values [][]byte) (uint64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	batch := NewBatch()
+	for i, key := range keys {
+		if expired, err := b.expired(values[i]); err != nil || !expired {
+			continue
+		}
+		data, err := b.kvStore.Get(b.ns, key)
+		if err != nil {
+			continue
+		}
+		if expired, err := b.expired(data); err != nil || !expired {
+			continue
+		}
+		batch.Delete(b.ns, key, "failed to delete expired key %x", key)
+	}
+	// the batch is cleared upon commit
+	size := batch.Size()
+	if size == 0 {
+		return 0, nil
+	}
+	if err := b.kvStore.Commit(batch); err != nil {
+		return 0, err
+	}
+	return uint64(size), nil
+}
+
+func (b *TTLBucket) expired(data []byte) (bool, error) {
+	if len(data) < 8 {
+		return false, errors.Errorf("entry of %d bytes misses the timestamp", len(data))
+	}
+	putAt := time.Unix(0, int64(binary.BigEndian.Uint64(data[:8])))
+	return !b.clk.Now().Before(putAt.Add(b.ttl)), nil
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/facebookgo/clock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/testutil"
+)
+
+func TestTTLBucket(t *testing.T) {
+	require := require.New(t)
+	path, err := ioutil.TempFile("", "ttlbucket")
+	require.NoError(err)
+	defer testutil.CleanupPath(t, path.Name())
+	kv := NewBoltDB(config.DB{DbPath: path.Name(), NumRetries: 3})
+	require.NoError(kv.Start(context.Background()))
+	defer kv.Stop(context.Background())
+
+	_, err = NewTTLBucket(kv, "", time.Minute)
+	require.Error(err)
+	_, err = NewTTLBucket(kv, "peers", 0)
+	require.Error(err)
+	_, err = NewTTLBucket(kv, "peers", time.Minute, TTLSweepBatchSizeOption(0))
+	require.Error(err)
+	c := clock.NewMock()
+	b, err := NewTTLBucket(kv, "peers", time.Minute, TTLClockOption(c), TTLSweepBatchSizeOption(2))
+	require.NoError(err)
+	require.Equal("peers", b.Namespace())
+
+	require.NoError(b.Put([]byte("a"), []byte("1")))
+	c.Add(30 * time.Second)
+	require.NoError(b.Put([]byte("b"), []byte("2")))
+	v, err := b.Get([]byte("a"))
+	require.NoError(err)
+	require.Equal([]byte("1"), v)
+
+	// an expired entry is gone on read
+	c.Add(30 * time.Second)
+	_, err = b.Get([]byte("a"))
+	require.Equal(ErrNotExist, errors.Cause(err))
+	_, err = kv.Get("peers", []byte("a"))
+	require.Equal(ErrNotExist, errors.Cause(err))
+	v, err = b.Get([]byte("b"))
+	require.NoError(err)
+	require.Equal([]byte("2"), v)
+	// putting again renews the entry
+	require.NoError(b.Put([]byte("a"), []byte("3")))
+	c.Add(59 * time.Second)
+	v, err = b.Get([]byte("a"))
+	require.NoError(err)
+	require.Equal([]byte("3"), v)
+
+	// sweep deletes all the expired entries
+	for _, k := range []string{"c", "d", "e"} {
+		require.NoError(b.Put([]byte(k), []byte(k)))
+	}
+	swept, err := b.Sweep()
+	require.NoError(err)
+	require.Equal(uint64(1), swept)
+	_, err = kv.Get("peers", []byte("b"))
+	require.Equal(ErrNotExist, errors.Cause(err))
+	c.Add(time.Minute)
+	swept, err = b.Sweep()
+	require.NoError(err)
+	require.Equal(uint64(4), swept)
+	swept, err = b.Sweep()
+	require.NoError(err)
+	require.Equal(uint64(0), swept)
+
+	// the store has to support range queries to sweep
+	b, err = NewTTLBucket(struct{ KVStore }{NewMemKVStore()}, "peers", time.Minute, TTLClockOption(c))
+	require.NoError(err)
+	require.NoError(b.Put([]byte("a"), []byte("1")))
+	_, err = b.Sweep()
+	require.Error(err)
+	require.NoError(b.Delete([]byte("a")))
+	_, err = b.Get([]byte("a"))
+	require.Equal(ErrNotExist, errors.Cause(err))
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package dispatcher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/iotexproject/iotex-proto/golang/iotexrpc"
+)
+
+// auditErrorsLimit is the number of the recent errors kept by the event audit
+const auditErrorsLimit = 100
+
+type (
+	// AuditError is an error of handling an event, recorded by the event audit
+	AuditError struct {
+		// Seq is the sequence number of the audit update recording the error
+		Seq     uint64
+		Time    time.Time
+		MsgType iotexrpc.MessageType
+		Err     string
+	}
+
+	// AuditSnapshot is a copy of the event audit, which is safe to read along with the ongoing dispatching
+	AuditSnapshot struct {
+		// Seq is the sequence number of the last audit update, which increases monotonically over the life of the
+		// dispatcher, including across the resets
+		Seq uint64
+		// ResetSeq is the sequence number at the last reset
+		ResetSeq uint64
+		// Events is the number of the events handled of each message type since the last reset
+		Events map[iotexrpc.MessageType]int
+		// Errors are the recent errors since the last reset, from the oldest to the newest
+		Errors []AuditError
+	}
+
+	// eventAudit counts the events by the message type, which is bounded by the enum of the message types, and keeps
+	// a ring buffer of the recent errors, such that its memory is bounded over the life of the process
+	eventAudit struct {
+		mutex    sync.RWMutex
+		seq      uint64
+		resetSeq uint64
+		events   map[iotexrpc.MessageType]int
+		errors   []AuditError
+		// next is the position of the ring buffer the next error is recorded at
+		next int
+	}
+)
+
+func newEventAudit() *eventAudit {
+	return &eventAudit{
+		events: make(map[iotexrpc.MessageType]int, len(iotexrpc.MessageType_name)),
+		errors: make([]AuditError, 0, auditErrorsLimit),
+	}
+}
+
+// Since returns the audit updates after the previous snapshot, where the counters are subtracted unless reset since
+func (s AuditSnapshot) Since(prev AuditSnapshot) AuditSnapshot {
+	delta := AuditSnapshot{
+		Seq:      s.Seq,
+		ResetSeq: s.ResetSeq,
+		Events:   make(map[iotexrpc.MessageType]int, len(s.Events)),
+	}
+	reset := s.ResetSeq > prev.Seq || s.Seq < prev.Seq
+	for t, count := range s.Events {
+		if !reset {
+			count -= prev.Events[t]
+		}
+		if count > 0 {
+			delta.Events[t] = count
+		}
+	}
+	for _, e := range s.Errors {
+		if e.Seq > prev.Seq {
+			delta.Errors = append(delta.Errors, e)
+		}
+	}
+	return delta
+}
+
+// AddEvent counts an event of the message type, which is counted as unknown if not in the enum
+func (a *eventAudit) AddEvent(t iotexrpc.MessageType) {
+	if _, ok := iotexrpc.MessageType_name[int32(t)]; !ok {
+		t = iotexrpc.MessageType_UNKNOWN
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.seq++
+	a.events[t]++
+}
+
+// AddError records an error of handling an event, overwriting the oldest one once the ring buffer is full
+func (a *eventAudit) AddError(t iotexrpc.MessageType, err error, now time.Time) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.seq++
+	e := AuditError{Seq: a.seq, Time: now, MsgType: t, Err: err.Error()}
+	if len(a.errors) < auditErrorsLimit {
+		a.errors = append(a.errors, e)
+	} else {
+		a.errors[a.next] = e
+	}
+	a.next = (a.next + 1) % auditErrorsLimit
+}
+
+// Reset clears the counters and the errors, where the sequence number goes on
+func (a *eventAudit) Reset() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.seq++
+	a.resetSeq = a.seq
+	a.events = make(map[iotexrpc.MessageType]int, len(iotexrpc.MessageType_name))
+	a.errors = a.errors[:0]
+	a.next = 0
+}
+
+// Snapshot returns a copy of the audit
+func (a *eventAudit) Snapshot() AuditSnapshot {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	s := AuditSnapshot{
+		Seq:      a.seq,
+		ResetSeq: a.resetSeq,
+		Events:   make(map[iotexrpc.MessageType]int, len(a.events)),
+		Errors:   make([]AuditError, 0, len(a.errors)),
+	}
+	for t, count := range a.events {
+		s.Events[t] = count
+	}
+	if len(a.errors) < auditErrorsLimit {
+		s.Errors = append(s.Errors, a.errors...)
+	} else {
+		s.Errors = append(s.Errors, a.errors[a.next:]...)
+		s.Errors = append(s.Errors, a.errors[:a.next]...)
+	}
+	return s
+}
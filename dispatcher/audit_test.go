@@ -0,0 +1,145 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/testutil"
+	"github.com/iotexproject/iotex-proto/golang/iotexrpc"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+)
+
+func TestEventAudit(t *testing.T) {
+	require := require.New(t)
+
+	a := newEventAudit()
+	now := time.Now()
+	a.AddEvent(iotexrpc.MessageType_ACTION)
+	a.AddEvent(iotexrpc.MessageType_BLOCK)
+	// a type out of the enum is counted as unknown
+	a.AddEvent(iotexrpc.MessageType(12345))
+	a.AddError(iotexrpc.MessageType_ACTION, errors.New("error 0"), now)
+	prev := a.Snapshot()
+	require.Equal(uint64(4), prev.Seq)
+	require.Equal(map[iotexrpc.MessageType]int{
+		iotexrpc.MessageType_ACTION:  1,
+		iotexrpc.MessageType_BLOCK:   1,
+		iotexrpc.MessageType_UNKNOWN: 1,
+	}, prev.Events)
+	require.Equal([]AuditError{{Seq: 4, Time: now, MsgType: iotexrpc.MessageType_ACTION, Err: "error 0"}}, prev.Errors)
+
+	// the snapshot is a copy
+	prev.Events[iotexrpc.MessageType_ACTION] = 100
+	require.Equal(1, a.Snapshot().Events[iotexrpc.MessageType_ACTION])
+
+	// the errors beyond the limit overwrite the oldest ones
+	for i := 1; i <= auditErrorsLimit+10; i++ {
+		a.AddError(iotexrpc.MessageType_BLOCK, errors.Errorf("error %d", i), now)
+	}
+	a.AddEvent(iotexrpc.MessageType_ACTION)
+	s := a.Snapshot()
+	require.Equal(auditErrorsLimit, len(s.Errors))
+	for i, e := range s.Errors {
+		require.Equal(fmt.Sprintf("error %d", i+11), e.Err)
+	}
+
+	// the delta since the previous snapshot
+	prev = a.Snapshot()
+	a.AddEvent(iotexrpc.MessageType_ACTION)
+	a.AddEvent(iotexrpc.MessageType_ACTION)
+	a.AddError(iotexrpc.MessageType_ACTION, errors.New("new error"), now)
+	delta := a.Snapshot().Since(prev)
+	require.Equal(map[iotexrpc.MessageType]int{iotexrpc.MessageType_ACTION: 2}, delta.Events)
+	require.Equal(1, len(delta.Errors))
+	require.Equal("new error", delta.Errors[0].Err)
+
+	// the counters restart on reset, while the sequence number goes on
+	prev = a.Snapshot()
+	a.Reset()
+	a.AddEvent(iotexrpc.MessageType_BLOCK)
+	s = a.Snapshot()
+	require.True(s.Seq > prev.Seq)
+	require.Equal(map[iotexrpc.MessageType]int{iotexrpc.MessageType_BLOCK: 1}, s.Events)
+	require.Empty(s.Errors)
+	delta = s.Since(prev)
+	require.Equal(map[iotexrpc.MessageType]int{iotexrpc.MessageType_BLOCK: 1}, delta.Events)
+	require.Empty(delta.Errors)
+	// everything is new since an empty snapshot
+	require.Equal(s.Events, s.Since(AuditSnapshot{}).Events)
+}
+
+func TestAuditSnapshotConcurrent(t *testing.T) {
+	require := require.New(t)
+
+	cfg := config.Config{
+		Consensus:  config.Consensus{Scheme: config.NOOPScheme},
+		Dispatcher: config.Dispatcher{EventChanSize: 1024},
+	}
+	dp, err := NewDispatcher(cfg)
+	require.NoError(err)
+	d := dp.(*IotxDispatcher)
+	d.AddSubscriber(config.Default.Chain.ID, &errActionSubscriber{})
+	ctx := context.Background()
+	require.NoError(d.Start(ctx))
+	defer func() { require.NoError(d.Stop(ctx)) }()
+
+	const senders, actions = 4, 100
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < actions; j++ {
+				d.HandleBroadcast(ctx, config.Default.Chain.ID, &iotextypes.Action{})
+				d.HandleBroadcast(ctx, config.Default.Chain.ID, &iotextypes.ConsensusMessage{})
+			}
+		}()
+	}
+	// the snapshots are taken along with the dispatching, each of which is consistent on its own
+	done := make(chan struct{})
+	snapshotted := make(chan struct{})
+	go func() {
+		defer close(snapshotted)
+		var prev AuditSnapshot
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			s := d.AuditSnapshot()
+			if s.Seq < prev.Seq || len(s.Errors) > auditErrorsLimit {
+				t.Errorf("inconsistent snapshot of seq %d after %d", s.Seq, prev.Seq)
+				return
+			}
+			s.Since(prev)
+			prev = s
+		}
+	}()
+	wg.Wait()
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, 2*time.Second, func() (bool, error) {
+		return d.AuditSnapshot().Events[iotexrpc.MessageType_ACTION] == senders*actions, nil
+	}))
+	close(done)
+	<-snapshotted
+
+	s := d.AuditSnapshot()
+	require.Equal(auditErrorsLimit, len(s.Errors))
+	require.Equal(senders*actions, d.EventAudit()[iotexrpc.MessageType_ACTION])
+	d.ResetAudit()
+	require.Empty(d.EventAudit())
+	require.Empty(d.AuditSnapshot().Errors)
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
@@ -32,7 +33,7 @@ type Subscriber interface {
 	HandleBlock(context.Context, *iotextypes.Block) error
 	HandleBlockSync(context.Context, *iotextypes.Block) error
 	HandleSyncRequest(context.Context, peerstore.PeerInfo, *iotexrpc.BlockSync) error
-	HandleConsensusMsg(*iotextypes.ConsensusMessage) error
+	HandleConsensusMsg(context.Context, *iotextypes.ConsensusMessage) error
 }
 
 // Dispatcher is used by peers, handles incoming block and header notifications and relays announcements of new blocks.
@@ -47,8 +48,31 @@ type Dispatcher interface {
 	// HandleTell handles the incoming tell message. The transportation layer semantics is exact once. The sender is
 	// given for the sake of replying the message
 	HandleTell(context.Context, uint32, peerstore.PeerInfo, proto.Message)
+	// HandleTelemetry handles the incoming telemetry report, which is sent by the given peer on a topic of its own. An
+	// error is returned if the report is not accepted.
+	HandleTelemetry(context.Context, string, []byte) error
 }
 
+const (
+	// eventHandled is the outcome of an event successfully handled by the subscriber
+	eventHandled = "handled"
+	// eventDropped is the outcome of an event dropped before reaching any subscriber
+	eventDropped = "dropped"
+	// eventError is the outcome of an event failed to be handled by the subscriber
+	eventError = "error"
+
+	// drainPollInterval is the interval of checking whether the pending events are handled when draining
+	drainPollInterval = 10 * time.Millisecond
+)
+
+const (
+	// HighPriorityLane is the lane of the time-critical events, i.e., the blocks, which are always handled before the
+	// events in the normal lane
+	HighPriorityLane = "high"
+	// NormalPriorityLane is the lane of the other events, e.g., the action gossip and the block sync requests
+	NormalPriorityLane = "normal"
+)
+
 var requestMtc = prometheus.NewCounterVec(
 	prometheus.CounterOpts{
 		Name: "iotex_dispatch_request",
@@ -57,8 +81,17 @@ var requestMtc = prometheus.NewCounterVec(
 	[]string{"method", "succeed"},
 )
 
+var eventMtc = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "iotex_dispatch_event",
+		Help: "Dispatcher event counter by message type and outcome.",
+	},
+	[]string{"msg_type", "outcome"},
+)
+
 func init() {
 	prometheus.MustRegister(requestMtc)
+	prometheus.MustRegister(eventMtc)
 }
 
 // blockMsg packages a proto block message.
@@ -97,25 +130,34 @@ func (m actionMsg) ChainID() uint32 {
 
 // IotxDispatcher is the request and event dispatcher for iotx node.
 type IotxDispatcher struct {
-	started        int32
-	shutdown       int32
-	eventChan      chan interface{}
-	eventAudit     map[iotexrpc.MessageType]int
-	eventAuditLock sync.RWMutex
-	wg             sync.WaitGroup
-	quit           chan struct{}
+	started  int32
+	shutdown int32
+	draining int32
+	// pendingEvents is the number of events queued but not handled yet
+	pendingEvents int32
+	// highEventChan and eventChan are the queues of the events in the high priority lane and the normal one. The
+	// consensus messages are not queued but handled right away.
+	highEventChan chan interface{}
+	eventChan     chan interface{}
+	audit         *eventAudit
+	wg            sync.WaitGroup
+	quit          chan struct{}
 
 	subscribers   map[uint32]Subscriber
 	subscribersMU sync.RWMutex
+
+	telemetry *telemetryStore
 }
 
 // NewDispatcher creates a new Dispatcher
 func NewDispatcher(cfg config.Config) (Dispatcher, error) {
 	d := &IotxDispatcher{
-		eventChan:   make(chan interface{}, cfg.Dispatcher.EventChanSize),
-		eventAudit:  make(map[iotexrpc.MessageType]int),
-		quit:        make(chan struct{}),
-		subscribers: make(map[uint32]Subscriber),
+		highEventChan: make(chan interface{}, cfg.Dispatcher.EventChanSize),
+		eventChan:     make(chan interface{}, cfg.Dispatcher.EventChanSize),
+		audit:         newEventAudit(),
+		quit:          make(chan struct{}),
+		subscribers:   make(map[uint32]Subscriber),
+		telemetry:     newTelemetryStore(cfg.Dispatcher.TelemetryRateLimit),
 	}
 	return d, nil
 }
@@ -153,40 +195,69 @@ func (d *IotxDispatcher) Stop(ctx context.Context) error {
 	return nil
 }
 
-// EventChan returns the event chan
+// Drain stops queueing new events from the network, and waits until the pending events are handled or the context
+// is done. The consensus messages, which are handled right away, are still accepted, such that the current consensus
+// round could finish.
+func (d *IotxDispatcher) Drain(ctx context.Context) error {
+	atomic.StoreInt32(&d.draining, 1)
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for atomic.LoadInt32(&d.pendingEvents) > 0 {
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "%d events left", atomic.LoadInt32(&d.pendingEvents))
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// EventChan returns the event chan of the normal priority lane
 func (d *IotxDispatcher) EventChan() *chan interface{} {
 	return &d.eventChan
 }
 
-// EventAudit returns the event audit map
-func (d *IotxDispatcher) EventAudit() map[iotexrpc.MessageType]int {
-	d.eventAuditLock.RLock()
-	defer d.eventAuditLock.RUnlock()
-	snapshot := make(map[iotexrpc.MessageType]int)
-	for k, v := range d.eventAudit {
-		snapshot[k] = v
+// EventLaneDepths returns the number of the events queued in each lane
+func (d *IotxDispatcher) EventLaneDepths() map[string]int {
+	return map[string]int{
+		HighPriorityLane:   len(d.highEventChan),
+		NormalPriorityLane: len(d.eventChan),
 	}
-	return snapshot
 }
 
-// newsHandler is the main handler for handling all news from peers.
+// EventAudit returns the number of the events handled of each message type since the last reset of the audit
+func (d *IotxDispatcher) EventAudit() map[iotexrpc.MessageType]int {
+	return d.audit.Snapshot().Events
+}
+
+// AuditSnapshot returns a copy of the event audit, which is safe to read along with the ongoing dispatching
+func (d *IotxDispatcher) AuditSnapshot() AuditSnapshot {
+	return d.audit.Snapshot()
+}
+
+// ResetAudit clears the event counters and the recent errors of the audit
+func (d *IotxDispatcher) ResetAudit() {
+	d.audit.Reset()
+}
+
+// newsHandler is the main handler for handling all news from peers. The events in the high priority lane are drained
+// before an event in the normal lane is handled.
 func (d *IotxDispatcher) newsHandler() {
 loop:
 	for {
 		select {
+		case m := <-d.highEventChan:
+			d.handleEvent(m)
+			continue
+		case <-d.quit:
+			break loop
+		default:
+		}
+		select {
+		case m := <-d.highEventChan:
+			d.handleEvent(m)
 		case m := <-d.eventChan:
-			switch msg := m.(type) {
-			case *actionMsg:
-				d.handleActionMsg(msg)
-			case *blockMsg:
-				d.handleBlockMsg(msg)
-			case *blockSyncMsg:
-				d.handleBlockSyncMsg(msg)
-
-			default:
-				log.L().Warn("Invalid message type in block handler.", zap.Any("msg", msg))
-			}
-
+			d.handleEvent(m)
 		case <-d.quit:
 			break loop
 		}
@@ -196,15 +267,35 @@ loop:
 	log.L().Info("News handler done.")
 }
 
+func (d *IotxDispatcher) handleEvent(m interface{}) {
+	switch msg := m.(type) {
+	case *actionMsg:
+		d.handleActionMsg(msg)
+	case *blockMsg:
+		d.handleBlockMsg(msg)
+	case *blockSyncMsg:
+		d.handleBlockSyncMsg(msg)
+
+	default:
+		log.L().Warn("Invalid message type in block handler.", zap.Any("msg", msg))
+	}
+	atomic.AddInt32(&d.pendingEvents, -1)
+}
+
 // handleActionMsg handles actionMsg from all peers.
 func (d *IotxDispatcher) handleActionMsg(m *actionMsg) {
-	d.updateEventAudit(iotexrpc.MessageType_ACTION)
+	d.audit.AddEvent(iotexrpc.MessageType_ACTION)
 	if subscriber, ok := d.subscribers[m.ChainID()]; ok {
 		if err := subscriber.HandleAction(m.ctx, m.action); err != nil {
 			requestMtc.WithLabelValues("AddAction", "false").Inc()
+			countEvent(iotexrpc.MessageType_ACTION, eventError)
+			d.audit.AddError(iotexrpc.MessageType_ACTION, err, time.Now())
 			log.L().Debug("Handle action request error.", zap.Error(err))
+		} else {
+			countEvent(iotexrpc.MessageType_ACTION, eventHandled)
 		}
 	} else {
+		countEvent(iotexrpc.MessageType_ACTION, eventDropped)
 		log.L().Info("No subscriber specified in the dispatcher.", zap.Uint32("chainID", m.ChainID()))
 	}
 }
@@ -214,11 +305,16 @@ func (d *IotxDispatcher) handleBlockMsg(m *blockMsg) {
 	d.subscribersMU.RLock()
 	defer d.subscribersMU.RUnlock()
 	if subscriber, ok := d.subscribers[m.ChainID()]; ok {
-		d.updateEventAudit(iotexrpc.MessageType_BLOCK)
+		d.audit.AddEvent(iotexrpc.MessageType_BLOCK)
 		if err := subscriber.HandleBlock(m.ctx, m.block); err != nil {
+			countEvent(iotexrpc.MessageType_BLOCK, eventError)
+			d.audit.AddError(iotexrpc.MessageType_BLOCK, err, time.Now())
 			log.L().Error("Fail to handle the block.", zap.Error(err))
+		} else {
+			countEvent(iotexrpc.MessageType_BLOCK, eventHandled)
 		}
 	} else {
+		countEvent(iotexrpc.MessageType_BLOCK, eventDropped)
 		log.L().Info("No subscriber specified in the dispatcher.", zap.Uint32("chainID", m.ChainID()))
 	}
 }
@@ -230,23 +326,28 @@ func (d *IotxDispatcher) handleBlockSyncMsg(m *blockSyncMsg) {
 		zap.Uint64("start", m.sync.Start),
 		zap.Uint64("end", m.sync.End))
 
-	d.updateEventAudit(iotexrpc.MessageType_BLOCK_REQUEST)
+	d.audit.AddEvent(iotexrpc.MessageType_BLOCK_REQUEST)
 	if subscriber, ok := d.subscribers[m.ChainID()]; ok {
 		// dispatch to block sync
 		if err := subscriber.HandleSyncRequest(m.ctx, m.peer, m.sync); err != nil {
+			countEvent(iotexrpc.MessageType_BLOCK_REQUEST, eventError)
+			d.audit.AddError(iotexrpc.MessageType_BLOCK_REQUEST, err, time.Now())
 			log.L().Error("Failed to handle sync request.", zap.Error(err))
+		} else {
+			countEvent(iotexrpc.MessageType_BLOCK_REQUEST, eventHandled)
 		}
 	} else {
+		countEvent(iotexrpc.MessageType_BLOCK_REQUEST, eventDropped)
 		log.L().Info("No subscriber specified in the dispatcher.", zap.Uint32("chainID", m.ChainID()))
 	}
 }
 
 // dispatchAction adds the passed action message to the news handling queue.
 func (d *IotxDispatcher) dispatchAction(ctx context.Context, chainID uint32, msg proto.Message) {
-	if atomic.LoadInt32(&d.shutdown) != 0 {
+	if !d.accepting() {
 		return
 	}
-	d.enqueueEvent(&actionMsg{
+	d.enqueueEvent(d.eventChan, iotexrpc.MessageType_ACTION, &actionMsg{
 		ctx:     ctx,
 		chainID: chainID,
 		action:  (msg).(*iotextypes.Action),
@@ -255,10 +356,10 @@ func (d *IotxDispatcher) dispatchAction(ctx context.Context, chainID uint32, msg
 
 // dispatchBlockCommit adds the passed block message to the news handling queue.
 func (d *IotxDispatcher) dispatchBlockCommit(ctx context.Context, chainID uint32, msg proto.Message) {
-	if atomic.LoadInt32(&d.shutdown) != 0 {
+	if !d.accepting() {
 		return
 	}
-	d.enqueueEvent(&blockMsg{
+	d.enqueueEvent(d.highEventChan, iotexrpc.MessageType_BLOCK, &blockMsg{
 		ctx:     ctx,
 		chainID: chainID,
 		block:   (msg).(*iotextypes.Block),
@@ -267,10 +368,10 @@ func (d *IotxDispatcher) dispatchBlockCommit(ctx context.Context, chainID uint32
 
 // dispatchBlockSyncReq adds the passed block sync request to the news handling queue.
 func (d *IotxDispatcher) dispatchBlockSyncReq(ctx context.Context, chainID uint32, peer peerstore.PeerInfo, msg proto.Message) {
-	if atomic.LoadInt32(&d.shutdown) != 0 {
+	if !d.accepting() {
 		return
 	}
-	d.enqueueEvent(&blockSyncMsg{
+	d.enqueueEvent(d.eventChan, iotexrpc.MessageType_BLOCK_REQUEST, &blockSyncMsg{
 		ctx:     ctx,
 		chainID: chainID,
 		peer:    peer,
@@ -289,14 +390,19 @@ func (d *IotxDispatcher) HandleBroadcast(ctx context.Context, chainID uint32, me
 	if !ok {
 		log.L().Warn("chainID has not been registered in dispatcher.", zap.Uint32("chainID", chainID))
 		d.subscribersMU.RUnlock()
+		countEvent(msgType, eventDropped)
 		return
 	}
 	d.subscribersMU.RUnlock()
 
 	switch msgType {
 	case iotexrpc.MessageType_CONSENSUS:
-		if err := subscriber.HandleConsensusMsg(message.(*iotextypes.ConsensusMessage)); err != nil {
+		if err := subscriber.HandleConsensusMsg(ctx, message.(*iotextypes.ConsensusMessage)); err != nil {
+			countEvent(msgType, eventError)
+			d.audit.AddError(msgType, err, time.Now())
 			log.L().Debug("Failed to handle consensus message.", zap.Error(err))
+		} else {
+			countEvent(msgType, eventHandled)
 		}
 	case iotexrpc.MessageType_ACTION:
 		d.dispatchAction(ctx, chainID, message)
@@ -323,18 +429,25 @@ func (d *IotxDispatcher) HandleTell(ctx context.Context, chainID uint32, peer pe
 	}
 }
 
-func (d *IotxDispatcher) enqueueEvent(event interface{}) {
+// enqueueEvent queues the event into the lane
+func (d *IotxDispatcher) enqueueEvent(lane chan interface{}, t iotexrpc.MessageType, event interface{}) {
+	atomic.AddInt32(&d.pendingEvents, 1)
 	go func() {
-		if len(d.eventChan) == cap(d.eventChan) {
+		if len(lane) == cap(lane) {
+			atomic.AddInt32(&d.pendingEvents, -1)
+			countEvent(t, eventDropped)
 			log.L().Debug("dispatcher event chan is full, drop an event.")
 			return
 		}
-		d.eventChan <- event
+		lane <- event
 	}()
 }
 
-func (d *IotxDispatcher) updateEventAudit(t iotexrpc.MessageType) {
-	d.eventAuditLock.Lock()
-	defer d.eventAuditLock.Unlock()
-	d.eventAudit[t]++
+// accepting returns true if new events could be queued
+func (d *IotxDispatcher) accepting() bool {
+	return atomic.LoadInt32(&d.shutdown) == 0 && atomic.LoadInt32(&d.draining) == 0
+}
+
+func countEvent(t iotexrpc.MessageType, outcome string) {
+	eventMtc.WithLabelValues(t.String(), outcome).Inc()
 }
@@ -9,13 +9,18 @@ package dispatcher
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/golang/protobuf/proto"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
+	"github.com/pkg/errors"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/testutil"
 	"github.com/iotexproject/iotex-proto/golang/iotexrpc"
 	"github.com/iotexproject/iotex-proto/golang/iotextypes"
 	"github.com/iotexproject/iotex-proto/golang/testingpb"
@@ -86,6 +91,136 @@ func TestHandleTell(t *testing.T) {
 	}
 }
 
+func TestEventMetrics(t *testing.T) {
+	require := require.New(t)
+
+	cfg := config.Config{
+		Consensus:  config.Consensus{Scheme: config.NOOPScheme},
+		Dispatcher: config.Dispatcher{EventChanSize: 1024},
+	}
+	d, err := NewDispatcher(cfg)
+	require.NoError(err)
+	d.AddSubscriber(config.Default.Chain.ID, &errActionSubscriber{})
+	ctx := context.Background()
+	require.NoError(d.Start(ctx))
+	defer func() { require.NoError(d.Stop(ctx)) }()
+
+	counter := func(t iotexrpc.MessageType, outcome string) float64 {
+		return promtestutil.ToFloat64(eventMtc.WithLabelValues(t.String(), outcome))
+	}
+	actionErr := counter(iotexrpc.MessageType_ACTION, eventError)
+	blockHandled := counter(iotexrpc.MessageType_BLOCK, eventHandled)
+	syncHandled := counter(iotexrpc.MessageType_BLOCK_REQUEST, eventHandled)
+	consensusHandled := counter(iotexrpc.MessageType_CONSENSUS, eventHandled)
+	consensusDropped := counter(iotexrpc.MessageType_CONSENSUS, eventDropped)
+
+	for i := 0; i < 3; i++ {
+		d.HandleBroadcast(ctx, config.Default.Chain.ID, &iotextypes.Action{})
+	}
+	for i := 0; i < 2; i++ {
+		d.HandleBroadcast(ctx, config.Default.Chain.ID, &iotextypes.Block{})
+	}
+	for i := 0; i < 4; i++ {
+		d.HandleBroadcast(ctx, config.Default.Chain.ID, &iotextypes.ConsensusMessage{})
+	}
+	d.HandleTell(ctx, config.Default.Chain.ID, peerstore.PeerInfo{}, &iotexrpc.BlockSync{})
+	// message of unregistered chain is dropped
+	d.HandleBroadcast(ctx, config.Default.Chain.ID+1, &iotextypes.ConsensusMessage{})
+
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, 2*time.Second, func() (bool, error) {
+		return counter(iotexrpc.MessageType_ACTION, eventError)-actionErr == 3 &&
+			counter(iotexrpc.MessageType_BLOCK, eventHandled)-blockHandled == 2 &&
+			counter(iotexrpc.MessageType_BLOCK_REQUEST, eventHandled)-syncHandled == 1, nil
+	}))
+	require.Equal(float64(4), counter(iotexrpc.MessageType_CONSENSUS, eventHandled)-consensusHandled)
+	require.Equal(float64(1), counter(iotexrpc.MessageType_CONSENSUS, eventDropped)-consensusDropped)
+
+	// event audit map is kept for backward compatibility
+	audit := d.(*IotxDispatcher).EventAudit()
+	require.Equal(3, audit[iotexrpc.MessageType_ACTION])
+	require.Equal(2, audit[iotexrpc.MessageType_BLOCK])
+	require.Equal(1, audit[iotexrpc.MessageType_BLOCK_REQUEST])
+}
+
+func TestDrain(t *testing.T) {
+	require := require.New(t)
+
+	d := createDispatcher(t, config.Default.Chain.ID).(*IotxDispatcher)
+	ctx := context.Background()
+	counter := func(t iotexrpc.MessageType, outcome string) float64 {
+		return promtestutil.ToFloat64(eventMtc.WithLabelValues(t.String(), outcome))
+	}
+	blockHandled := counter(iotexrpc.MessageType_BLOCK, eventHandled)
+	consensusHandled := counter(iotexrpc.MessageType_CONSENSUS, eventHandled)
+
+	// the pending events are not handled before the dispatcher starts
+	for i := 0; i < 2; i++ {
+		d.HandleBroadcast(ctx, config.Default.Chain.ID, &iotextypes.Block{})
+	}
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, 2*time.Second, func() (bool, error) {
+		return len(d.highEventChan) == 2, nil
+	}))
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	require.Equal(context.DeadlineExceeded, errors.Cause(d.Drain(timeoutCtx)))
+
+	// new events are not queued while the pending ones are handled
+	d.HandleBroadcast(ctx, config.Default.Chain.ID, &iotextypes.Block{})
+	require.NoError(d.Start(ctx))
+	defer func() { require.NoError(d.Stop(ctx)) }()
+	require.NoError(d.Drain(ctx))
+	require.Equal(float64(2), counter(iotexrpc.MessageType_BLOCK, eventHandled)-blockHandled)
+
+	// consensus messages are still handled
+	d.HandleBroadcast(ctx, config.Default.Chain.ID, &iotextypes.ConsensusMessage{})
+	require.Equal(float64(1), counter(iotexrpc.MessageType_CONSENSUS, eventHandled)-consensusHandled)
+}
+
+func TestPriorityLanes(t *testing.T) {
+	require := require.New(t)
+
+	d, err := NewDispatcher(config.Config{
+		Consensus:  config.Consensus{Scheme: config.NOOPScheme},
+		Dispatcher: config.Dispatcher{EventChanSize: 1024},
+	})
+	require.NoError(err)
+	dp := d.(*IotxDispatcher)
+	subscriber := &slowActionSubscriber{blockHandled: make(chan struct{}, 1)}
+	dp.AddSubscriber(config.Default.Chain.ID, subscriber)
+	ctx := context.Background()
+
+	// flood the normal lane with the action gossip, which takes two seconds to handle
+	for i := 0; i < 1000; i++ {
+		dp.HandleBroadcast(ctx, config.Default.Chain.ID, &iotextypes.Action{})
+	}
+	dp.HandleBroadcast(ctx, config.Default.Chain.ID, &iotextypes.Block{})
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, 2*time.Second, func() (bool, error) {
+		depths := dp.EventLaneDepths()
+		return depths[HighPriorityLane] == 1 && depths[NormalPriorityLane] == 1000, nil
+	}))
+
+	// the block is handled ahead of the backlog
+	start := time.Now()
+	require.NoError(dp.Start(ctx))
+	defer func() { require.NoError(dp.Stop(ctx)) }()
+	select {
+	case <-subscriber.blockHandled:
+		require.True(time.Since(start) < 200*time.Millisecond)
+	case <-time.After(time.Second):
+		require.FailNow("the block isn't handled ahead of the actions")
+	}
+	require.True(dp.EventLaneDepths()[NormalPriorityLane] > 900)
+
+	// and so is a block arriving behind the backlog
+	dp.HandleBroadcast(ctx, config.Default.Chain.ID, &iotextypes.Block{})
+	select {
+	case <-subscriber.blockHandled:
+	case <-time.After(200 * time.Millisecond):
+		require.FailNow("the block isn't handled ahead of the actions")
+	}
+	require.True(dp.EventLaneDepths()[NormalPriorityLane] > 800)
+}
+
 type DummySubscriber struct{}
 
 func (s *DummySubscriber) HandleBlock(context.Context, *iotextypes.Block) error { return nil }
@@ -98,4 +233,29 @@ func (s *DummySubscriber) HandleSyncRequest(context.Context, peerstore.PeerInfo,
 
 func (s *DummySubscriber) HandleAction(context.Context, *iotextypes.Action) error { return nil }
 
-func (s *DummySubscriber) HandleConsensusMsg(*iotextypes.ConsensusMessage) error { return nil }
+func (s *DummySubscriber) HandleConsensusMsg(context.Context, *iotextypes.ConsensusMessage) error {
+	return nil
+}
+
+type errActionSubscriber struct {
+	DummySubscriber
+}
+
+func (s *errActionSubscriber) HandleAction(context.Context, *iotextypes.Action) error {
+	return errors.New("invalid action")
+}
+
+type slowActionSubscriber struct {
+	DummySubscriber
+	blockHandled chan struct{}
+}
+
+func (s *slowActionSubscriber) HandleAction(context.Context, *iotextypes.Action) error {
+	time.Sleep(2 * time.Millisecond)
+	return nil
+}
+
+func (s *slowActionSubscriber) HandleBlock(context.Context, *iotextypes.Block) error {
+	s.blockHandled <- struct{}{}
+	return nil
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/go-pkgs/crypto"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/dispatcher/telemetrypb"
+	"github.com/iotexproject/iotex-core/pkg/log"
+)
+
+// telemetryMsgType is the message type of the telemetry reports in the event counter
+const telemetryMsgType = "TELEMETRY"
+
+// errTelemetryRateLimited indicates a telemetry report sent by a delegate within the rate limit, e.g., a copy relayed
+// by another peer
+var errTelemetryRateLimited = errors.New("telemetry report exceeds the rate limit")
+
+type (
+	// TelemetryReport is the latest verified status report published by a delegate
+	TelemetryReport struct {
+		Delegate  string
+		Version   string
+		Height    uint64
+		NumPeers  uint32
+		Timestamp time.Time
+		// ReceivedAt is the local time the report is accepted
+		ReceivedAt time.Time
+	}
+
+	// delegatesGetter is implemented by the subscribers which know the delegates of the current consensus round
+	delegatesGetter interface {
+		Delegates() ([]string, error)
+	}
+
+	// telemetryStore keeps the latest report of each delegate per chain, which also rate-limits the reports per
+	// delegate. As only the reports of the current delegates are kept, the store is capped to the delegate list.
+	telemetryStore struct {
+		mutex     sync.Mutex
+		rateLimit time.Duration
+		reports   map[uint32]map[string]TelemetryReport
+	}
+)
+
+func newTelemetryStore(rateLimit time.Duration) *telemetryStore {
+	return &telemetryStore{
+		rateLimit: rateLimit,
+		reports:   make(map[uint32]map[string]TelemetryReport),
+	}
+}
+
+// limited returns true if a report of the delegate has been accepted within the rate limit
+func (s *telemetryStore) limited(chainID uint32, delegate string, now time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.limitedLocked(chainID, delegate, now)
+}
+
+func (s *telemetryStore) limitedLocked(chainID uint32, delegate string, now time.Time) bool {
+	prev, ok := s.reports[chainID][delegate]
+	return ok && now.Sub(prev.ReceivedAt) < s.rateLimit
+}
+
+// put stores the report of a delegate, and drops the reports of those no longer delegates
+func (s *telemetryStore) put(chainID uint32, report TelemetryReport, delegates []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	reports := s.retain(chainID, delegates)
+	isDelegate := false
+	for _, d := range delegates {
+		if d == report.Delegate {
+			isDelegate = true
+			break
+		}
+	}
+	if !isDelegate {
+		return errors.Errorf("%s is not a delegate of chain %d", report.Delegate, chainID)
+	}
+	if s.limitedLocked(chainID, report.Delegate, report.ReceivedAt) {
+		return errTelemetryRateLimited
+	}
+	if prev, ok := reports[report.Delegate]; ok && !report.Timestamp.After(prev.Timestamp) {
+		return errors.Errorf("report of %s at %s is not newer than the stored one", report.Delegate, report.Timestamp)
+	}
+	reports[report.Delegate] = report
+	return nil
+}
+
+// get returns a copy of the reports of the given delegates
+func (s *telemetryStore) get(chainID uint32, delegates []string) map[string]TelemetryReport {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	reports := make(map[string]TelemetryReport)
+	for d, report := range s.retain(chainID, delegates) {
+		reports[d] = report
+	}
+	return reports
+}
+
+// retain drops the reports of the chain not from the given delegates, and returns the remaining ones
+func (s *telemetryStore) retain(chainID uint32, delegates []string) map[string]TelemetryReport {
+	reports, ok := s.reports[chainID]
+	if !ok {
+		reports = make(map[string]TelemetryReport)
+		s.reports[chainID] = reports
+	}
+	current := make(map[string]bool, len(delegates))
+	for _, d := range delegates {
+		current[d] = true
+	}
+	for d := range reports {
+		if !current[d] {
+			delete(reports, d)
+		}
+	}
+	return reports
+}
+
+// HandleTelemetry handles the incoming telemetry report sent or relayed by the given peer. The report is stored if it
+// is signed by a current delegate of the chain it reports on, and no other report of the delegate has been accepted
+// within the rate limit, otherwise an error is returned.
+func (d *IotxDispatcher) HandleTelemetry(ctx context.Context, peerID string, data []byte) error {
+	err := d.handleTelemetry(data, time.Now())
+	switch errors.Cause(err) {
+	case nil:
+		eventMtc.WithLabelValues(telemetryMsgType, eventHandled).Inc()
+	case errTelemetryRateLimited:
+		eventMtc.WithLabelValues(telemetryMsgType, eventDropped).Inc()
+	default:
+		eventMtc.WithLabelValues(telemetryMsgType, eventError).Inc()
+		log.L().Debug("Failed to handle telemetry report.", zap.String("peer", peerID), zap.Error(err))
+	}
+	return err
+}
+
+// TelemetryReports returns the latest telemetry report of each current delegate of the chain
+func (d *IotxDispatcher) TelemetryReports(chainID uint32) (map[string]TelemetryReport, error) {
+	delegates, err := d.delegates(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return d.telemetry.get(chainID, delegates), nil
+}
+
+func (d *IotxDispatcher) handleTelemetry(data []byte, now time.Time) error {
+	var signed telemetrypb.SignedTelemetryReport
+	if err := proto.Unmarshal(data, &signed); err != nil {
+		return errors.Wrap(err, "failed to unmarshal signed telemetry report")
+	}
+	var report telemetrypb.TelemetryReport
+	if err := proto.Unmarshal(signed.Report, &report); err != nil {
+		return errors.Wrap(err, "failed to unmarshal telemetry report")
+	}
+	pubKey, err := crypto.BytesToPublicKey(signed.SenderPubKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to load the public key of the sender")
+	}
+	sender, err := address.FromBytes(pubKey.Hash())
+	if err != nil {
+		return errors.Wrap(err, "failed to get the address of the sender")
+	}
+	// check the rate limit ahead of the signature, such that the copies of a report are dropped cheaply
+	if d.telemetry.limited(report.ChainID, sender.String(), now) {
+		return errTelemetryRateLimited
+	}
+	h := hash.Hash256b(signed.Report)
+	if !pubKey.Verify(h[:], signed.Signature) {
+		return errors.New("invalid signature of telemetry report")
+	}
+	delegates, err := d.delegates(report.ChainID)
+	if err != nil {
+		return err
+	}
+	return d.telemetry.put(report.ChainID, TelemetryReport{
+		Delegate:   sender.String(),
+		Version:    report.Version,
+		Height:     report.Height,
+		NumPeers:   report.NumPeers,
+		Timestamp:  time.Unix(report.Timestamp, 0),
+		ReceivedAt: now,
+	}, delegates)
+}
+
+// delegates returns the current delegates of the chain, known by its subscriber
+func (d *IotxDispatcher) delegates(chainID uint32) ([]string, error) {
+	d.subscribersMU.RLock()
+	subscriber, ok := d.subscribers[chainID]
+	d.subscribersMU.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("chain %d has not been registered in dispatcher", chainID)
+	}
+	getter, ok := subscriber.(delegatesGetter)
+	if !ok {
+		return nil, errors.Errorf("delegates of chain %d are unknown", chainID)
+	}
+	return getter.Delegates()
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package dispatcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/go-pkgs/crypto"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/dispatcher/telemetrypb"
+	"github.com/iotexproject/iotex-core/test/identityset"
+)
+
+type delegatesSubscriber struct {
+	DummySubscriber
+	delegates []string
+}
+
+func (s *delegatesSubscriber) Delegates() ([]string, error) { return s.delegates, nil }
+
+func signTelemetry(t *testing.T, sk crypto.PrivateKey, report *telemetrypb.TelemetryReport) []byte {
+	data, err := proto.Marshal(report)
+	require.NoError(t, err)
+	h := hash.Hash256b(data)
+	sig, err := sk.Sign(h[:])
+	require.NoError(t, err)
+	signed, err := proto.Marshal(&telemetrypb.SignedTelemetryReport{
+		Report:       data,
+		SenderPubKey: sk.PublicKey().Bytes(),
+		Signature:    sig,
+	})
+	require.NoError(t, err)
+	return signed
+}
+
+func TestHandleTelemetry(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	chainID := config.Default.Chain.ID
+	cfg := config.Config{
+		Dispatcher: config.Dispatcher{EventChanSize: 1024, TelemetryRateLimit: time.Hour},
+	}
+	dp, err := NewDispatcher(cfg)
+	require.NoError(err)
+	d := dp.(*IotxDispatcher)
+	now := time.Now().Unix()
+	report := func(sk crypto.PrivateKey, height uint64, timestamp int64) []byte {
+		return signTelemetry(t, sk, &telemetrypb.TelemetryReport{
+			ChainID:   chainID,
+			Version:   "v0.5.0",
+			Height:    height,
+			NumPeers:  10,
+			Timestamp: timestamp,
+		})
+	}
+
+	// the delegates of a chain without a delegate list are unknown
+	d.AddSubscriber(chainID, &DummySubscriber{})
+	require.Error(d.HandleTelemetry(ctx, "peer", report(identityset.PrivateKey(0), 10, now)))
+	_, err = d.TelemetryReports(chainID)
+	require.Error(err)
+
+	subscriber := &delegatesSubscriber{
+		delegates: []string{identityset.Address(0).String(), identityset.Address(1).String()},
+	}
+	d.AddSubscriber(chainID, subscriber)
+	require.NoError(d.HandleTelemetry(ctx, "peer", report(identityset.PrivateKey(0), 10, now)))
+	reports, err := d.TelemetryReports(chainID)
+	require.NoError(err)
+	require.Equal(1, len(reports))
+	r := reports[identityset.Address(0).String()]
+	require.Equal(identityset.Address(0).String(), r.Delegate)
+	require.Equal("v0.5.0", r.Version)
+	require.Equal(uint64(10), r.Height)
+	require.Equal(uint32(10), r.NumPeers)
+	require.Equal(time.Unix(now, 0), r.Timestamp)
+	require.False(r.ReceivedAt.IsZero())
+
+	// the reports of a delegate within the rate limit, including the copies relayed by other peers, are dropped
+	require.Equal(
+		errTelemetryRateLimited,
+		errors.Cause(d.HandleTelemetry(ctx, "another", report(identityset.PrivateKey(0), 11, now+1))),
+	)
+	// a report signed by a non-delegate is dropped
+	require.Error(d.HandleTelemetry(ctx, "peer", report(identityset.PrivateKey(2), 11, now)))
+	// a report with an invalid signature is dropped
+	var signed telemetrypb.SignedTelemetryReport
+	require.NoError(proto.Unmarshal(report(identityset.PrivateKey(2), 11, now), &signed))
+	signed.SenderPubKey = identityset.PrivateKey(1).PublicKey().Bytes()
+	forged, err := proto.Marshal(&signed)
+	require.NoError(err)
+	require.Error(d.HandleTelemetry(ctx, "peer", forged))
+	// which doesn't take the place of the delegate within the rate limit
+	require.NoError(d.HandleTelemetry(ctx, "peer", report(identityset.PrivateKey(1), 11, now)))
+	reports, err = d.TelemetryReports(chainID)
+	require.NoError(err)
+	require.Equal(2, len(reports))
+	require.Equal(uint64(10), reports[identityset.Address(0).String()].Height)
+
+	// the reports are capped to the current delegates
+	subscriber.delegates = []string{identityset.Address(1).String(), identityset.Address(2).String()}
+	reports, err = d.TelemetryReports(chainID)
+	require.NoError(err)
+	require.Equal(1, len(reports))
+	require.Contains(reports, identityset.Address(1).String())
+}
+
+func TestTelemetryStore(t *testing.T) {
+	require := require.New(t)
+	s := newTelemetryStore(time.Minute)
+	delegates := []string{identityset.Address(0).String()}
+	now := time.Now()
+	report := TelemetryReport{
+		Delegate:   delegates[0],
+		Height:     10,
+		Timestamp:  now,
+		ReceivedAt: now,
+	}
+	require.False(s.limited(1, delegates[0], now))
+	require.NoError(s.put(1, report, delegates))
+	require.True(s.limited(1, delegates[0], now.Add(time.Second)))
+	// the rate limit applies per chain
+	require.False(s.limited(2, delegates[0], now.Add(time.Second)))
+
+	// a replayed report is dropped after the rate limit
+	report.ReceivedAt = now.Add(time.Minute)
+	require.False(s.limited(1, delegates[0], report.ReceivedAt))
+	require.Error(s.put(1, report, delegates))
+	report.Height = 11
+	report.Timestamp = now.Add(time.Minute)
+	require.NoError(s.put(1, report, delegates))
+	require.Equal(map[string]TelemetryReport{delegates[0]: report}, s.get(1, delegates))
+	require.Equal(0, len(s.get(1, nil)))
+	require.Equal(0, len(s.reports[1]))
+}
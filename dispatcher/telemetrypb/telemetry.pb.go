@@ -0,0 +1,173 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: telemetry.proto
+
+package telemetrypb
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type TelemetryReport struct {
+	ChainID              uint32   `protobuf:"varint,1,opt,name=chainID,proto3" json:"chainID,omitempty"`
+	Version              string   `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Height               uint64   `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	NumPeers             uint32   `protobuf:"varint,4,opt,name=numPeers,proto3" json:"numPeers,omitempty"`
+	Timestamp            int64    `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TelemetryReport) Reset()         { *m = TelemetryReport{} }
+func (m *TelemetryReport) String() string { return proto.CompactTextString(m) }
+func (*TelemetryReport) ProtoMessage()    {}
+func (*TelemetryReport) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edbfcf76559f568d, []int{0}
+}
+
+func (m *TelemetryReport) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TelemetryReport.Unmarshal(m, b)
+}
+func (m *TelemetryReport) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TelemetryReport.Marshal(b, m, deterministic)
+}
+func (m *TelemetryReport) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TelemetryReport.Merge(m, src)
+}
+func (m *TelemetryReport) XXX_Size() int {
+	return xxx_messageInfo_TelemetryReport.Size(m)
+}
+func (m *TelemetryReport) XXX_DiscardUnknown() {
+	xxx_messageInfo_TelemetryReport.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TelemetryReport proto.InternalMessageInfo
+
+func (m *TelemetryReport) GetChainID() uint32 {
+	if m != nil {
+		return m.ChainID
+	}
+	return 0
+}
+
+func (m *TelemetryReport) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *TelemetryReport) GetHeight() uint64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *TelemetryReport) GetNumPeers() uint32 {
+	if m != nil {
+		return m.NumPeers
+	}
+	return 0
+}
+
+func (m *TelemetryReport) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+type SignedTelemetryReport struct {
+	// the serialized TelemetryReport being signed
+	Report               []byte   `protobuf:"bytes,1,opt,name=report,proto3" json:"report,omitempty"`
+	SenderPubKey         []byte   `protobuf:"bytes,2,opt,name=senderPubKey,proto3" json:"senderPubKey,omitempty"`
+	Signature            []byte   `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SignedTelemetryReport) Reset()         { *m = SignedTelemetryReport{} }
+func (m *SignedTelemetryReport) String() string { return proto.CompactTextString(m) }
+func (*SignedTelemetryReport) ProtoMessage()    {}
+func (*SignedTelemetryReport) Descriptor() ([]byte, []int) {
+	return fileDescriptor_edbfcf76559f568d, []int{1}
+}
+
+func (m *SignedTelemetryReport) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SignedTelemetryReport.Unmarshal(m, b)
+}
+func (m *SignedTelemetryReport) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SignedTelemetryReport.Marshal(b, m, deterministic)
+}
+func (m *SignedTelemetryReport) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignedTelemetryReport.Merge(m, src)
+}
+func (m *SignedTelemetryReport) XXX_Size() int {
+	return xxx_messageInfo_SignedTelemetryReport.Size(m)
+}
+func (m *SignedTelemetryReport) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignedTelemetryReport.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SignedTelemetryReport proto.InternalMessageInfo
+
+func (m *SignedTelemetryReport) GetReport() []byte {
+	if m != nil {
+		return m.Report
+	}
+	return nil
+}
+
+func (m *SignedTelemetryReport) GetSenderPubKey() []byte {
+	if m != nil {
+		return m.SenderPubKey
+	}
+	return nil
+}
+
+func (m *SignedTelemetryReport) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*TelemetryReport)(nil), "telemetrypb.TelemetryReport")
+	proto.RegisterType((*SignedTelemetryReport)(nil), "telemetrypb.SignedTelemetryReport")
+}
+
+func init() { proto.RegisterFile("telemetry.proto", fileDescriptor_edbfcf76559f568d) }
+
+var fileDescriptor_edbfcf76559f568d = []byte{
+	// 216 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0x90, 0xcd, 0x4a, 0xc3, 0x40,
+	0x10, 0x80, 0x59, 0x5b, 0xa3, 0x1d, 0x23, 0x85, 0x05, 0xcb, 0x22, 0x1e, 0x42, 0x4e, 0x7b, 0xf2,
+	0xe2, 0x2b, 0x78, 0x11, 0x2f, 0x65, 0xf4, 0x05, 0x12, 0x3b, 0x24, 0x0b, 0xee, 0x8f, 0xb3, 0x13,
+	0xa1, 0x4f, 0xe2, 0xeb, 0x4a, 0x97, 0xa6, 0x45, 0x6f, 0xfb, 0x7d, 0x0b, 0x33, 0x1f, 0x03, 0x6b,
+	0xa1, 0x4f, 0xf2, 0x24, 0xbc, 0x7f, 0x4c, 0x1c, 0x25, 0xea, 0x9b, 0x93, 0x48, 0x7d, 0xfb, 0xa3,
+	0x60, 0xfd, 0x3e, 0x33, 0x52, 0x8a, 0x2c, 0xda, 0xc0, 0xd5, 0xc7, 0xd8, 0xb9, 0xf0, 0xf2, 0x6c,
+	0x54, 0xa3, 0xec, 0x2d, 0xce, 0x78, 0xf8, 0xf9, 0x26, 0xce, 0x2e, 0x06, 0x73, 0xd1, 0x28, 0xbb,
+	0xc2, 0x19, 0xf5, 0x06, 0xaa, 0x91, 0xdc, 0x30, 0x8a, 0x59, 0x34, 0xca, 0x2e, 0xf1, 0x48, 0xfa,
+	0x1e, 0xae, 0xc3, 0xe4, 0xb7, 0x44, 0x9c, 0xcd, 0xb2, 0x0c, 0x3b, 0xb1, 0x7e, 0x80, 0x95, 0x38,
+	0x4f, 0x59, 0x3a, 0x9f, 0xcc, 0x65, 0xa3, 0xec, 0x02, 0xcf, 0xa2, 0xfd, 0x82, 0xbb, 0x37, 0x37,
+	0x04, 0xda, 0xfd, 0xcf, 0xdb, 0x40, 0xc5, 0xe5, 0x55, 0xea, 0x6a, 0x3c, 0x92, 0x6e, 0xa1, 0xce,
+	0x14, 0x76, 0xc4, 0xdb, 0xa9, 0x7f, 0xa5, 0x7d, 0x29, 0xac, 0xf1, 0x8f, 0x3b, 0xac, 0xcc, 0x6e,
+	0x08, 0x9d, 0x4c, 0x4c, 0xa5, 0xb4, 0xc6, 0xb3, 0xe8, 0xab, 0x72, 0xa0, 0xa7, 0xdf, 0x01, 0x00,
+	0x0e, 0x51, 0xc9, 0x04, 0x33, 0x01, 0x00, 0x00,
+}
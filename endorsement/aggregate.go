@@ -0,0 +1,206 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package endorsement
+
+import (
+	"encoding/binary"
+	"time"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/iotexproject/go-pkgs/crypto"
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/pkg/errors"
+)
+
+const (
+	// signatureLen is the length of a recoverable secp256k1 signature in [R || S || V] format
+	signatureLen = 65
+	// aggregateEntryLen is the length of a serialized endorsement in an aggregate, i.e., unix seconds, nanoseconds
+	// and signature
+	aggregateEntryLen = 8 + 4 + signatureLen
+)
+
+// Aggregate is a compact form of a set of endorsements made by members of a committee. Instead of carrying the public
+// key of every endorser, it marks the endorsers in a bitmap over the committee, and concatenates their timestamps and
+// signatures in the order of the committee. The endorsers' public keys are recovered from the signatures upon
+// verification.
+type Aggregate struct {
+	bitmap     []byte
+	timestamps []time.Time
+	signatures []byte
+}
+
+// NewAggregate aggregates the endorsements made by members of the committee
+func NewAggregate(committee []string, endorsements []*Endorsement) (*Aggregate, error) {
+	indices := make(map[string]int, len(committee))
+	for i, addr := range committee {
+		if _, ok := indices[addr]; !ok {
+			indices[addr] = i
+		}
+	}
+	byIndex := make(map[int]*Endorsement, len(endorsements))
+	for _, en := range endorsements {
+		if len(en.signature) != signatureLen {
+			return nil, errors.Errorf("invalid signature length %d", len(en.signature))
+		}
+		addr, err := address.FromBytes(en.Endorser().Hash())
+		if err != nil {
+			return nil, err
+		}
+		i, ok := indices[addr.String()]
+		if !ok {
+			return nil, errors.Errorf("endorser %s is not in the committee", addr)
+		}
+		if _, ok := byIndex[i]; ok {
+			return nil, errors.Errorf("duplicate endorsements from %s", addr)
+		}
+		byIndex[i] = en
+	}
+	agg := &Aggregate{
+		bitmap:     make([]byte, (len(committee)+7)/8),
+		timestamps: make([]time.Time, 0, len(byIndex)),
+		signatures: make([]byte, 0, len(byIndex)*signatureLen),
+	}
+	for i := range committee {
+		en, ok := byIndex[i]
+		if !ok {
+			continue
+		}
+		agg.bitmap[i/8] |= 1 << uint(i%8)
+		agg.timestamps = append(agg.timestamps, en.ts)
+		agg.signatures = append(agg.signatures, en.signature...)
+	}
+
+	return agg, nil
+}
+
+// AggregateFromBytes deserializes an aggregate from the bytes produced by Bytes()
+func AggregateFromBytes(b []byte) (*Aggregate, error) {
+	if len(b) < 2 {
+		return nil, errors.Errorf("wrong length %d, expecting at least 2", len(b))
+	}
+	bitmapLen := int(binary.BigEndian.Uint16(b[:2]))
+	b = b[2:]
+	if len(b) < bitmapLen {
+		return nil, errors.New("bitmap is truncated")
+	}
+	agg := &Aggregate{bitmap: make([]byte, bitmapLen)}
+	copy(agg.bitmap, b[:bitmapLen])
+	b = b[bitmapLen:]
+	num := agg.Count()
+	if len(b) != num*aggregateEntryLen {
+		return nil, errors.Errorf("wrong length %d of %d endorsements", len(b), num)
+	}
+	agg.timestamps = make([]time.Time, 0, num)
+	agg.signatures = make([]byte, 0, num*signatureLen)
+	for i := 0; i < num; i++ {
+		entry := b[i*aggregateEntryLen : (i+1)*aggregateEntryLen]
+		agg.timestamps = append(agg.timestamps, time.Unix(
+			int64(binary.BigEndian.Uint64(entry[0:8])),
+			int64(binary.BigEndian.Uint32(entry[8:12])),
+		))
+		agg.signatures = append(agg.signatures, entry[12:]...)
+	}
+
+	return agg, nil
+}
+
+// Bytes serializes the aggregate as the bitmap length, the bitmap, followed by the timestamp and signature of each
+// endorsement
+func (agg *Aggregate) Bytes() []byte {
+	b := make([]byte, 2, 2+len(agg.bitmap)+len(agg.timestamps)*aggregateEntryLen)
+	binary.BigEndian.PutUint16(b, uint16(len(agg.bitmap)))
+	b = append(b, agg.bitmap...)
+	entry := make([]byte, 12)
+	for i, ts := range agg.timestamps {
+		binary.BigEndian.PutUint64(entry[0:8], uint64(ts.Unix()))
+		binary.BigEndian.PutUint32(entry[8:12], uint32(ts.Nanosecond()))
+		b = append(b, entry...)
+		b = append(b, agg.signatures[i*signatureLen:(i+1)*signatureLen]...)
+	}
+
+	return b
+}
+
+// Count returns the number of endorsements in the aggregate
+func (agg *Aggregate) Count() int {
+	count := 0
+	for _, v := range agg.bitmap {
+		for ; v != 0; v &= v - 1 {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Endorsements verifies the aggregate against the committee and restores the endorsements. Each endorsement should be
+// signed by the corresponding committee member on one of the documents.
+func (agg *Aggregate) Endorsements(committee []string, docs ...Document) ([]*Endorsement, error) {
+	if len(agg.bitmap) != (len(committee)+7)/8 {
+		return nil, errors.Errorf("bitmap of %d bytes mismatches committee of size %d", len(agg.bitmap), len(committee))
+	}
+	endorsements := make([]*Endorsement, 0, len(agg.timestamps))
+	for i, addr := range committee {
+		if agg.bitmap[i/8]&(1<<uint(i%8)) == 0 {
+			continue
+		}
+		j := len(endorsements)
+		if j >= len(agg.timestamps) {
+			return nil, errors.New("bitmap mismatches the number of endorsements")
+		}
+		ts := agg.timestamps[j]
+		sig := agg.signatures[j*signatureLen : (j+1)*signatureLen]
+		pk, err := recoverEndorser(addr, ts, sig, docs)
+		if err != nil {
+			return nil, err
+		}
+		endorsements = append(endorsements, NewEndorsement(ts, pk, sig))
+	}
+	for i := len(committee); i < len(agg.bitmap)*8; i++ {
+		if agg.bitmap[i/8]&(1<<uint(i%8)) != 0 {
+			return nil, errors.Errorf("endorser %d is out of the committee", i)
+		}
+	}
+	if len(endorsements) != len(agg.timestamps) {
+		return nil, errors.New("bitmap mismatches the number of endorsements")
+	}
+
+	return endorsements, nil
+}
+
+// recoverEndorser recovers the public key from a signature on one of the documents, which should match the address
+func recoverEndorser(addr string, ts time.Time, sig []byte, docs []Document) (crypto.PublicKey, error) {
+	rs := make([]byte, signatureLen)
+	copy(rs, sig)
+	if rs[signatureLen-1] >= 27 {
+		rs[signatureLen-1] -= 27
+	}
+	for _, doc := range docs {
+		h, err := hashDocWithTime(doc, ts)
+		if err != nil {
+			return nil, err
+		}
+		ecdsaPK, err := ethcrypto.SigToPub(h, rs)
+		if err != nil {
+			continue
+		}
+		pk, err := crypto.BytesToPublicKey(ethcrypto.FromECDSAPub(ecdsaPK))
+		if err != nil {
+			return nil, err
+		}
+		recovered, err := address.FromBytes(pk.Hash())
+		if err != nil {
+			return nil, err
+		}
+		if recovered.String() == addr {
+			return pk, nil
+		}
+	}
+
+	return nil, errors.Errorf("invalid endorsement of %s", addr)
+}
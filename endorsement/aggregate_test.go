@@ -0,0 +1,153 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package endorsement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/test/identityset"
+)
+
+type testDoc []byte
+
+func (d testDoc) Hash() ([]byte, error) { return d, nil }
+
+// makeProofOfLock returns a committee of size n, and endorsements on doc by the first m members
+func makeProofOfLock(t require.TestingT, n, m int, doc Document) ([]string, []*Endorsement) {
+	require := require.New(t)
+	committee := make([]string, n)
+	endorsements := make([]*Endorsement, 0, m)
+	for i := 0; i < n; i++ {
+		committee[i] = identityset.Address(i).String()
+		if i >= m {
+			continue
+		}
+		en, err := Endorse(identityset.PrivateKey(i), doc, time.Unix(1562382392, int64(i)))
+		require.NoError(err)
+		endorsements = append(endorsements, en)
+	}
+	return committee, endorsements
+}
+
+func TestAggregate(t *testing.T) {
+	require := require.New(t)
+	doc := testDoc("block hash")
+	committee, endorsements := makeProofOfLock(t, 24, 17, doc)
+	// aggregate in reverse order
+	reversed := make([]*Endorsement, len(endorsements))
+	for i, en := range endorsements {
+		reversed[len(endorsements)-1-i] = en
+	}
+	agg, err := NewAggregate(committee, reversed)
+	require.NoError(err)
+	require.Equal(17, agg.Count())
+
+	agg, err = AggregateFromBytes(agg.Bytes())
+	require.NoError(err)
+	require.Equal(17, agg.Count())
+	restored, err := agg.Endorsements(committee, testDoc("another doc"), doc)
+	require.NoError(err)
+	require.Equal(len(endorsements), len(restored))
+	for i, en := range restored {
+		require.True(VerifyEndorsement(doc, en))
+		require.Equal(endorsements[i].Endorser().Bytes(), en.Endorser().Bytes())
+		require.True(endorsements[i].Timestamp().Equal(en.Timestamp()))
+	}
+
+	// wrong document
+	_, err = agg.Endorsements(committee, testDoc("another doc"))
+	require.Error(err)
+	// wrong committee
+	_, err = agg.Endorsements(committee[1:])
+	require.Error(err)
+	shuffled := append([]string{committee[1], committee[0]}, committee[2:]...)
+	_, err = agg.Endorsements(shuffled, doc)
+	require.Error(err)
+
+	// endorser out of committee
+	_, err = NewAggregate(committee[1:], endorsements)
+	require.Error(err)
+	// duplicate endorsements
+	_, err = NewAggregate(committee, append(endorsements, endorsements[0]))
+	require.Error(err)
+
+	// corrupted bytes
+	b := agg.Bytes()
+	_, err = AggregateFromBytes(b[:1])
+	require.Error(err)
+	_, err = AggregateFromBytes(b[:len(b)-1])
+	require.Error(err)
+}
+
+func TestAggregateSize(t *testing.T) {
+	require := require.New(t)
+	committee, endorsements := makeProofOfLock(t, 24, 17, testDoc("block hash"))
+	agg, err := NewAggregate(committee, endorsements)
+	require.NoError(err)
+	naive := naiveProofOfLockBytes(t, endorsements)
+	require.True(len(agg.Bytes()) < len(naive)*2/3)
+}
+
+func naiveProofOfLockBytes(t require.TestingT, endorsements []*Endorsement) []byte {
+	require := require.New(t)
+	msg := &iotextypes.BlockProposal{}
+	for _, en := range endorsements {
+		ePb, err := en.Proto()
+		require.NoError(err)
+		msg.Endorsements = append(msg.Endorsements, ePb)
+	}
+	b, err := proto.Marshal(msg)
+	require.NoError(err)
+	return b
+}
+
+func BenchmarkProofOfLockSize(b *testing.B) {
+	committee, endorsements := makeProofOfLock(b, 24, 17, testDoc("block hash"))
+	b.Run("naive", func(b *testing.B) {
+		b.SetBytes(int64(len(naiveProofOfLockBytes(b, endorsements))))
+		for i := 0; i < b.N; i++ {
+			naiveProofOfLockBytes(b, endorsements)
+		}
+	})
+	b.Run("aggregate", func(b *testing.B) {
+		agg, err := NewAggregate(committee, endorsements)
+		require.NoError(b, err)
+		b.SetBytes(int64(len(agg.Bytes())))
+		for i := 0; i < b.N; i++ {
+			agg, _ := NewAggregate(committee, endorsements)
+			agg.Bytes()
+		}
+	})
+}
+
+func BenchmarkProofOfLockVerification(b *testing.B) {
+	doc := testDoc("block hash")
+	committee, endorsements := makeProofOfLock(b, 24, 17, doc)
+	b.Run("naive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, en := range endorsements {
+				if !VerifyEndorsement(doc, en) {
+					b.Fatal("invalid endorsement")
+				}
+			}
+		}
+	})
+	b.Run("aggregate", func(b *testing.B) {
+		agg, err := NewAggregate(committee, endorsements)
+		require.NoError(b, err)
+		for i := 0; i < b.N; i++ {
+			if _, err := agg.Endorsements(committee, doc); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -13,6 +13,7 @@ require (
 	github.com/golang/groupcache v0.0.0-20190129154638-5b532d6fd5ef
 	github.com/golang/mock v1.3.1
 	github.com/golang/protobuf v1.3.1
+	github.com/golang/snappy v0.0.1
 	github.com/grpc-ecosystem/go-grpc-prometheus v1.2.0
 	github.com/iotexproject/go-fsm v1.0.0
 	github.com/iotexproject/go-p2p v0.2.10
@@ -26,14 +27,17 @@ require (
 	github.com/libp2p/go-libp2p-connmgr v0.0.3 // indirect
 	github.com/libp2p/go-libp2p-host v0.0.2 // indirect
 	github.com/libp2p/go-libp2p-kad-dht v0.0.10 // indirect
+	github.com/libp2p/go-libp2p-peer v0.1.0
 	github.com/libp2p/go-libp2p-peerstore v0.0.5
-	github.com/libp2p/go-libp2p-pubsub v0.0.1 // indirect
+	github.com/libp2p/go-libp2p-pubsub v0.0.1
 	github.com/mattn/go-sqlite3 v1.10.0
 	github.com/minio/blake2b-simd v0.0.0-20160723061019-3f5f724cb5b1
 	github.com/multiformats/go-multiaddr v0.0.2
 	github.com/multiformats/go-multihash v0.0.5 // indirect
+	github.com/multiformats/go-multistream v0.0.2
 	github.com/pkg/errors v0.8.1
 	github.com/prometheus/client_golang v1.0.0
+	github.com/prometheus/client_model v0.0.0-20190129233127-fd36f4220a90
 	github.com/rs/zerolog v1.14.3
 	github.com/spf13/cobra v0.0.4
 	github.com/stretchr/testify v1.3.0
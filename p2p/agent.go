@@ -14,19 +14,24 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	p2p "github.com/iotexproject/go-p2p"
+	"github.com/iotexproject/go-pkgs/hash"
+	peer "github.com/libp2p/go-libp2p-peer"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
 	multiaddr "github.com/multiformats/go-multiaddr"
+	multistream "github.com/multiformats/go-multistream"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	"github.com/iotexproject/iotex-core/config"
 	"github.com/iotexproject/iotex-core/pkg/log"
+	"github.com/iotexproject/iotex-core/pkg/version"
 	goproto "github.com/iotexproject/iotex-proto/golang"
 	"github.com/iotexproject/iotex-proto/golang/iotexrpc"
 )
@@ -52,19 +57,34 @@ var (
 		},
 		[]string{"protocol", "message", "status"},
 	)
+	p2pPeerReportCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "iotex_p2p_peer_report_counter",
+			Help: "Validation outcomes of the messages relayed by the peers",
+		},
+		[]string{"result", "reason"},
+	)
 )
 
+// errPeerBanned is the error of rejecting a message from a banned peer
+var errPeerBanned = errors.New("peer is banned")
+
 func init() {
 	prometheus.MustRegister(p2pMsgCounter)
 	prometheus.MustRegister(p2pMsgLatency)
+	prometheus.MustRegister(p2pPeerReportCounter)
 }
 
 const (
 	// TODO: the topic could be fine tuned
 	broadcastTopic    = "broadcast"
 	unicastTopic      = "unicast"
+	telemetryTopic    = "telemetry"
+	versionTopic      = "version"
 	numDialRetries    = 8
 	dialRetryInterval = 2 * time.Second
+	// uncompressedPeerTTL is how long a peer found not supporting the compression is sent the messages uncompressed
+	uncompressedPeerTTL = 10 * time.Minute
 )
 
 type (
@@ -73,27 +93,73 @@ type (
 
 	// HandleUnicastInboundAsync handles unicast message when agent listens it from the network
 	HandleUnicastInboundAsync func(context.Context, uint32, peerstore.PeerInfo, proto.Message)
+
+	// HandleTelemetryInbound handles the raw telemetry report sent by the given peer on the telemetry topic. The report
+	// is relayed to the other neighbors if it is accepted without error.
+	HandleTelemetryInbound func(context.Context, string, []byte) error
+
+	// Option sets an option of the agent
+	Option func(*Agent)
 )
 
+// WithTelemetryHandler sets the handler of the telemetry reports received from the network
+func WithTelemetryHandler(handler HandleTelemetryInbound) Option {
+	return func(p *Agent) {
+		p.telemetryInboundHandler = handler
+	}
+}
+
 // Agent is the agent to help the blockchain node connect into the P2P networks and send/receive messages
 type Agent struct {
 	cfg                        config.Network
 	topicSuffix                string
 	broadcastInboundHandler    HandleBroadcastInbound
 	unicastInboundAsyncHandler HandleUnicastInboundAsync
+	telemetryInboundHandler    HandleTelemetryInbound
 	host                       *p2p.Host
+	scorer                     *peerScorer
+	// compressor compresses the unicast messages sent to the peers supporting it, and is nil if disabled
+	compressor *compressor
+	// uncompressedPeers are the peers found not supporting the compression, along with when they were found, which are
+	// tried again after uncompressedPeerTTL in case they are upgraded
+	uncompressedPeers map[peer.ID]time.Time
+	// dedup suppresses the messages broadcast again within a window, and is nil if disabled
+	dedup *broadcastDedup
+	// version is the software version advertised to the neighbors, and peerVersions are those advertised by them
+	version      string
+	peerVersions *peerVersions
+	mutex        sync.RWMutex
 }
 
 // NewAgent instantiates a local P2P agent instance
-func NewAgent(cfg config.Config, broadcastHandler HandleBroadcastInbound, unicastHandler HandleUnicastInboundAsync) *Agent {
+func NewAgent(
+	cfg config.Config,
+	broadcastHandler HandleBroadcastInbound,
+	unicastHandler HandleUnicastInboundAsync,
+	opts ...Option,
+) *Agent {
 	gh := cfg.Genesis.Hash()
-	return &Agent{
+	p := &Agent{
 		cfg: cfg.Network,
 		// Make sure the honest node only care the messages related the chain from the same genesis
 		topicSuffix:                hex.EncodeToString(gh[22:]), // last 10 bytes of genesis hash
 		broadcastInboundHandler:    broadcastHandler,
 		unicastInboundAsyncHandler: unicastHandler,
+		scorer: newPeerScorer(cfg.Network.PeerBanThreshold, cfg.Network.PeerBanDuration).withBanLimit(
+			cfg.Network.PeerScoreWindow,
+			cfg.Network.MinBanInterval,
+		),
+		compressor:        newCompressor(cfg.Network.Compression),
+		uncompressedPeers: make(map[peer.ID]time.Time),
+		dedup:             newBroadcastDedup(cfg.Network.BroadcastDedupSize, cfg.Network.BroadcastDedupWindow),
+		version:           version.PackageVersion,
+		peerVersions:      newPeerVersions(),
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	setGossipFanout(cfg.Network.BroadcastFanout)
+	return p
 }
 
 // Start connects into P2P network
@@ -139,6 +205,10 @@ func (p *Agent) Start(ctx context.Context) error {
 			status := successStr
 			if err != nil {
 				status = failureStr
+				// a banned peer isn't penalized again, otherwise it would be banned over again as long as it sends
+				if peerID != "" && errors.Cause(err) != errPeerBanned {
+					p.scorer.penalize(peerID)
+				}
 			}
 			p2pMsgCounter.WithLabelValues("broadcast", strconv.Itoa(int(broadcast.MsgType)), "in", peerID, status).Inc()
 			p2pMsgLatency.WithLabelValues("broadcast", strconv.Itoa(int(broadcast.MsgType)), status).Observe(float64(latency))
@@ -158,6 +228,10 @@ func (p *Agent) Start(ctx context.Context) error {
 			skip = true
 			return
 		}
+		if p.scorer.isBanned(peerID) {
+			err = errors.Wrap(errPeerBanned, peerID)
+			return
+		}
 
 		t, _ := ptypes.Timestamp(broadcast.GetTimestamp())
 		latency = time.Since(t).Nanoseconds() / time.Millisecond.Nanoseconds()
@@ -167,13 +241,13 @@ func (p *Agent) Start(ctx context.Context) error {
 			err = errors.Wrap(err, "error when typifying broadcast message")
 			return
 		}
-		p.broadcastInboundHandler(ctx, broadcast.ChainId, msg)
+		p.broadcastInboundHandler(WithPeerID(ctx, peerID), broadcast.ChainId, msg)
 		return
 	}); err != nil {
 		return errors.Wrap(err, "error when adding broadcast pubsub")
 	}
 
-	if err := host.AddUnicastPubSub(unicastTopic+p.topicSuffix, func(ctx context.Context, _ io.Writer, data []byte) (err error) {
+	handleUnicast := func(ctx context.Context, _ io.Writer, data []byte) (err error) {
 		// Blocking handling the unicast message until the agent is started
 		<-ready
 		var (
@@ -185,10 +259,24 @@ func (p *Agent) Start(ctx context.Context) error {
 			status := successStr
 			if err != nil {
 				status = failureStr
+				// a banned peer isn't penalized again, otherwise it would be banned over again as long as it sends
+				if peerID != "" && errors.Cause(err) != errPeerBanned {
+					p.scorer.penalize(peerID)
+				}
 			}
 			p2pMsgCounter.WithLabelValues("unicast", strconv.Itoa(int(unicast.MsgType)), "in", peerID, status).Inc()
 			p2pMsgLatency.WithLabelValues("unicast", strconv.Itoa(int(unicast.MsgType)), status).Observe(float64(latency))
 		}()
+		stream, ok := p2p.GetUnicastStream(ctx)
+		if !ok {
+			err = errors.New("error when asserting unicast stream context")
+			return
+		}
+		peerID = stream.Conn().RemotePeer().Pretty()
+		if p.scorer.isBanned(peerID) {
+			err = errors.Wrap(errPeerBanned, peerID)
+			return
+		}
 		if err = proto.Unmarshal(data, &unicast); err != nil {
 			err = errors.Wrap(err, "error when marshaling unicast message")
 			return
@@ -202,20 +290,115 @@ func (p *Agent) Start(ctx context.Context) error {
 		t, _ := ptypes.Timestamp(unicast.GetTimestamp())
 		latency = time.Since(t).Nanoseconds() / time.Millisecond.Nanoseconds()
 
+		peerInfo := peerstore.PeerInfo{
+			ID:    stream.Conn().RemotePeer(),
+			Addrs: []multiaddr.Multiaddr{stream.Conn().RemoteMultiaddr()},
+		}
+		p.unicastInboundAsyncHandler(WithPeerID(ctx, peerID), unicast.ChainId, peerInfo, msg)
+		return
+	}
+	if err := host.AddUnicastPubSub(unicastTopic+p.topicSuffix, handleUnicast); err != nil {
+		return errors.Wrap(err, "error when adding unicast pubsub")
+	}
+	// The peers supporting the compression listen to the unicast topic of the algorithm as well, which is negotiated as
+	// the protocol of the stream
+	if p.compressor != nil {
+		if err := host.AddUnicastPubSub(p.compressedUnicastTopic(), func(ctx context.Context, w io.Writer, data []byte) error {
+			data, err := p.compressor.decode(data)
+			if err != nil {
+				return errors.Wrap(err, "error when decompressing unicast message")
+			}
+			return handleUnicast(ctx, w, data)
+		}); err != nil {
+			return errors.Wrap(err, "error when adding compressed unicast pubsub")
+		}
+	}
+
+	// The telemetry reports are sent to the neighbors on a topic of their own, and relayed by the receivers which
+	// accept them, such that they don't compete with the chain messages
+	if err := host.AddUnicastPubSub(telemetryTopic+p.topicSuffix, func(ctx context.Context, _ io.Writer, data []byte) (err error) {
+		// Blocking handling the telemetry report until the agent is started
+		<-ready
+		var peerID string
+		rejected := false
+		defer func() {
+			status := successStr
+			if err != nil || rejected {
+				status = failureStr
+			}
+			p2pMsgCounter.WithLabelValues("telemetry", "", "in", peerID, status).Inc()
+		}()
 		stream, ok := p2p.GetUnicastStream(ctx)
 		if !ok {
-			err = errors.Wrap(err, "error when typifying unicast message")
+			err = errors.New("error when asserting unicast stream context")
 			return
 		}
 		peerID = stream.Conn().RemotePeer().Pretty()
-		peerInfo := peerstore.PeerInfo{
+		if p.scorer.isBanned(peerID) {
+			err = errors.Wrap(errPeerBanned, peerID)
+			return
+		}
+		if p.telemetryInboundHandler == nil {
+			return
+		}
+		// A rejected report, e.g., a copy relayed by another neighbor, is not relayed further. The rejection is not
+		// returned as an error, which the host would log.
+		if p.telemetryInboundHandler(ctx, peerID, data) != nil {
+			rejected = true
+			return
+		}
+		go func() {
+			if err := p.sendTelemetry(context.Background(), data, peerID); err != nil {
+				log.L().Debug("Failed to relay telemetry report.", zap.Error(err))
+			}
+		}()
+		return
+	}); err != nil {
+		return errors.Wrap(err, "error when adding telemetry pubsub")
+	}
+
+	// The software versions are exchanged on a topic of their own, where an advertisement is replied with the version
+	// of the receiver
+	if err := host.AddUnicastPubSub(versionTopic+p.topicSuffix, func(ctx context.Context, _ io.Writer, data []byte) (err error) {
+		// Blocking handling the version until the agent is started
+		<-ready
+		var peerID string
+		defer func() {
+			status := successStr
+			if err != nil {
+				status = failureStr
+			}
+			p2pMsgCounter.WithLabelValues("version", "", "in", peerID, status).Inc()
+		}()
+		stream, ok := p2p.GetUnicastStream(ctx)
+		if !ok {
+			err = errors.New("error when asserting unicast stream context")
+			return
+		}
+		peerID = stream.Conn().RemotePeer().Pretty()
+		if p.scorer.isBanned(peerID) {
+			err = errors.Wrap(errPeerBanned, peerID)
+			return
+		}
+		reply, err := p.handleVersion(peerID, data)
+		if err != nil || !reply {
+			return
+		}
+		target := peerstore.PeerInfo{
 			ID:    stream.Conn().RemotePeer(),
 			Addrs: []multiaddr.Multiaddr{stream.Conn().RemoteMultiaddr()},
 		}
-		p.unicastInboundAsyncHandler(ctx, unicast.ChainId, peerInfo, msg)
+		go func() {
+			status := successStr
+			if err := p.host.Unicast(context.Background(), target, versionTopic+p.topicSuffix, p.versionMsg(versionReply)); err != nil {
+				log.L().Debug("Failed to reply version.", zap.Error(err))
+				status = failureStr
+			}
+			p2pMsgCounter.WithLabelValues("version", "", "out", peerID, status).Inc()
+		}()
 		return
 	}); err != nil {
-		return errors.Wrap(err, "error when adding unicast pubsub")
+		return errors.Wrap(err, "error when adding version pubsub")
 	}
 
 	if len(p.cfg.BootstrapNodes) > 0 {
@@ -285,7 +468,9 @@ func (p *Agent) Stop(ctx context.Context) error {
 	return nil
 }
 
-// BroadcastOutbound sends a broadcast message to the whole network
+// BroadcastOutbound sends a broadcast message to the whole network. Unlike the unicast messages, it isn't compressed,
+// as there is no per-peer negotiation over the gossip, and a compressed broadcast topic would split the mesh between
+// the peers supporting the compression and the others.
 func (p *Agent) BroadcastOutbound(ctx context.Context, msg proto.Message) (err error) {
 	var msgType iotexrpc.MessageType
 	var msgBody []byte
@@ -311,6 +496,20 @@ func (p *Agent) BroadcastOutbound(ctx context.Context, msg proto.Message) (err e
 		err = errors.New("P2P context doesn't exist")
 		return
 	}
+	// The same message broadcast again within the dedup window, e.g., relayed by more than one path, is suppressed
+	var key hash.Hash256
+	if p.dedup != nil {
+		key = broadcastKey(p2pCtx.ChainID, int32(msgType), msgBody)
+		if !p.dedup.mark(key) {
+			p2pSuppressedBroadcastCounter.WithLabelValues(strconv.Itoa(int(msgType))).Inc()
+			return
+		}
+		defer func() {
+			if err != nil {
+				p.dedup.unmark(key)
+			}
+		}()
+	}
 	broadcast := iotexrpc.BroadcastMsg{
 		ChainId:   p2pCtx.ChainID,
 		PeerId:    p.host.HostIdentity(),
@@ -330,6 +529,45 @@ func (p *Agent) BroadcastOutbound(ctx context.Context, msg proto.Message) (err e
 	return err
 }
 
+// BroadcastTelemetry sends a telemetry report to the neighbors on the telemetry topic, which is then relayed to the
+// whole network by the receivers
+func (p *Agent) BroadcastTelemetry(ctx context.Context, msg proto.Message) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "error when marshaling telemetry report")
+	}
+	return p.sendTelemetry(ctx, data, "")
+}
+
+// sendTelemetry sends the raw telemetry report to the neighbors except the given one, and fails only if none of them
+// receives it
+func (p *Agent) sendTelemetry(ctx context.Context, data []byte, except string) error {
+	neighbors, err := p.Neighbors(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error when getting neighbors")
+	}
+	var lastErr error
+	sent := 0
+	for _, neighbor := range neighbors {
+		peerID := neighbor.ID.Pretty()
+		if peerID == except {
+			continue
+		}
+		status := successStr
+		if err := p.host.Unicast(ctx, neighbor, telemetryTopic+p.topicSuffix, data); err != nil {
+			status = failureStr
+			lastErr = err
+		} else {
+			sent++
+		}
+		p2pMsgCounter.WithLabelValues("telemetry", "", "out", peerID, status).Inc()
+	}
+	if sent == 0 && lastErr != nil {
+		return errors.Wrap(lastErr, "error when sending telemetry report")
+	}
+	return nil
+}
+
 // UnicastOutbound sends a unicast message to the given address
 func (p *Agent) UnicastOutbound(ctx context.Context, peer peerstore.PeerInfo, msg proto.Message) (err error) {
 	var msgType iotexrpc.MessageType
@@ -362,22 +600,116 @@ func (p *Agent) UnicastOutbound(ctx context.Context, peer peerstore.PeerInfo, ms
 		err = errors.Wrap(err, "error when marshaling unicast message")
 		return err
 	}
-	if err = p.host.Unicast(ctx, peer, unicastTopic+p.topicSuffix, data); err != nil {
+	if err = p.unicast(ctx, peer, data); err != nil {
 		err = errors.Wrap(err, "error when sending unicast message")
 		return err
 	}
 	return err
 }
 
+// unicast sends the message compressed to a peer supporting the compression, which is negotiated as the protocol of
+// the stream. A peer not supporting it is sent the message uncompressed, and remembered.
+func (p *Agent) unicast(ctx context.Context, target peerstore.PeerInfo, data []byte) error {
+	if p.compressor == nil || !p.supportsCompression(target.ID) {
+		return p.host.Unicast(ctx, target, unicastTopic+p.topicSuffix, data)
+	}
+	compressed, err := p.compressor.encode(data)
+	if err != nil {
+		return errors.Wrap(err, "error when compressing unicast message")
+	}
+	err = p.host.Unicast(ctx, target, p.compressedUnicastTopic(), compressed)
+	if err == nil {
+		if saved := len(data) - len(compressed); saved > 0 {
+			p2pCompressionSavedBytes.WithLabelValues(p.compressor.algorithm, "out").Add(float64(saved))
+		}
+		return nil
+	}
+	if errors.Cause(err) != multistream.ErrNotSupported {
+		return err
+	}
+	p.markUncompressed(target.ID, time.Now())
+	return p.host.Unicast(ctx, target, unicastTopic+p.topicSuffix, data)
+}
+
+// markUncompressed remembers the peer not supporting the compression, and forgets the peers remembered for over
+// uncompressedPeerTTL, such that the map is bounded by the peers found within the TTL
+func (p *Agent) markUncompressed(id peer.ID, now time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for other, since := range p.uncompressedPeers {
+		if now.Sub(since) >= uncompressedPeerTTL {
+			delete(p.uncompressedPeers, other)
+		}
+	}
+	p.uncompressedPeers[id] = now
+}
+
+func (p *Agent) supportsCompression(id peer.ID) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	since, ok := p.uncompressedPeers[id]
+	return !ok || time.Since(since) >= uncompressedPeerTTL
+}
+
+func (p *Agent) compressedUnicastTopic() string {
+	return unicastTopic + "-" + p.compressor.algorithm + p.topicSuffix
+}
+
 // Info returns agents' peer info.
 func (p *Agent) Info() peerstore.PeerInfo { return p.host.Info() }
 
 // Self returns the self network address
 func (p *Agent) Self() []multiaddr.Multiaddr { return p.host.Addresses() }
 
-// Neighbors returns the neighbors' peer info
+// Neighbors returns the neighbors' peer info, excluding the banned peers
 func (p *Agent) Neighbors(ctx context.Context) ([]peerstore.PeerInfo, error) {
-	return p.host.Neighbors(ctx)
+	neighbors, err := p.host.Neighbors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]peerstore.PeerInfo, 0, len(neighbors))
+	for _, neighbor := range neighbors {
+		if !p.scorer.isBanned(neighbor.ID.Pretty()) {
+			filtered = append(filtered, neighbor)
+		}
+	}
+	return filtered, nil
+}
+
+// BanPeer bans a peer for the given duration. Messages from a banned peer are dropped, and it is excluded from
+// Neighbors.
+func (p *Agent) BanPeer(id string, duration time.Duration) error {
+	if id == "" {
+		return errors.New("peer ID is empty")
+	}
+	if duration <= 0 {
+		return errors.Errorf("invalid ban duration %s", duration)
+	}
+	p.scorer.ban(id, duration)
+	return nil
+}
+
+// PeerScores returns the scores of the peers which have sent invalid messages
+func (p *Agent) PeerScores() map[string]int { return p.scorer.snapshot() }
+
+// ReportValid counts a valid message relayed by a peer, e.g., a consensus message passing validation
+func (p *Agent) ReportValid(id string) {
+	if id == "" {
+		return
+	}
+	p2pPeerReportCounter.WithLabelValues("valid", "").Inc()
+}
+
+// ReportInvalid counts an invalid message relayed by a peer by the reason, e.g., a consensus message failing
+// validation, and decrements the score of the peer as the other invalid messages received from it do
+func (p *Agent) ReportInvalid(id string, reason string) {
+	if id == "" {
+		return
+	}
+	p2pPeerReportCounter.WithLabelValues("invalid", reason).Inc()
+	if p.scorer.penalize(id) {
+		p2pPeerReportCounter.WithLabelValues("banned", reason).Inc()
+	}
 }
 
 func convertAppMsg(msg proto.Message) (iotexrpc.MessageType, []byte, error) {
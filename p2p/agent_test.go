@@ -7,17 +7,22 @@
 package p2p
 
 import (
+	"bytes"
 	"context"
 	"net"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
+	"github.com/pkg/errors"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 
 	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/consensus/scheme"
 	"github.com/iotexproject/iotex-core/testutil"
 	"github.com/iotexproject/iotex-proto/golang/testingpb"
 )
@@ -99,6 +104,123 @@ func TestBroadcast(t *testing.T) {
 	}
 }
 
+func TestBroadcastDuplicateSuppressed(t *testing.T) {
+	require := require.New(t)
+	ctx := WitContext(context.Background(), Context{ChainID: 1})
+	var mutex sync.RWMutex
+	counts := make(map[uint8]int)
+	b := func(_ context.Context, _ uint32, msg proto.Message) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		counts[msg.(*testingpb.TestPayload).MsgBody[0]]++
+	}
+	u := func(_ context.Context, _ uint32, _ peerstore.PeerInfo, _ proto.Message) {}
+	bootnodePort := testutil.RandomPort()
+	bootnode := NewAgent(config.Config{
+		Network: config.Network{Host: "127.0.0.1", Port: bootnodePort},
+	}, b, u)
+	require.NoError(bootnode.Start(ctx))
+	defer func() { require.NoError(bootnode.Stop(ctx)) }()
+	agent := NewAgent(config.Config{
+		Network: config.Network{
+			Host:                 "127.0.0.1",
+			Port:                 bootnodePort + 1,
+			BootstrapNodes:       []string{bootnode.Self()[0].String()},
+			BroadcastDedupSize:   10,
+			BroadcastDedupWindow: time.Minute,
+		},
+	}, b, u)
+	require.NoError(agent.Start(ctx))
+	defer func() { require.NoError(agent.Stop(ctx)) }()
+
+	// warm up until the agents subscribe to the topics of each other, with a distinct payload on each attempt
+	attempt := 0
+	require.NoError(testutil.WaitUntil(100*time.Millisecond, 20*time.Second, func() (bool, error) {
+		attempt++
+		if err := agent.BroadcastOutbound(ctx, &testingpb.TestPayload{MsgBody: []byte{0, byte(attempt)}}); err != nil {
+			return false, err
+		}
+		mutex.RLock()
+		defer mutex.RUnlock()
+		return counts[0] > 0, nil
+	}))
+
+	duplicate := &testingpb.TestPayload{MsgBody: []byte{1}}
+	msgType, _, err := convertAppMsg(duplicate)
+	require.NoError(err)
+	suppressed := func() float64 {
+		return promtestutil.ToFloat64(p2pSuppressedBroadcastCounter.WithLabelValues(strconv.Itoa(int(msgType))))
+	}
+	base := suppressed()
+	require.NoError(agent.BroadcastOutbound(ctx, duplicate))
+	require.NoError(testutil.WaitUntil(100*time.Millisecond, 20*time.Second, func() (bool, error) {
+		mutex.RLock()
+		defer mutex.RUnlock()
+		return counts[1] == 1, nil
+	}))
+
+	// the second broadcast of the same payload is suppressed, while another payload goes through
+	require.NoError(agent.BroadcastOutbound(ctx, &testingpb.TestPayload{MsgBody: []byte{1}}))
+	require.Equal(base+1, suppressed())
+	require.NoError(agent.BroadcastOutbound(ctx, &testingpb.TestPayload{MsgBody: []byte{2}}))
+	require.Equal(base+1, suppressed())
+	require.NoError(testutil.WaitUntil(100*time.Millisecond, 20*time.Second, func() (bool, error) {
+		mutex.RLock()
+		defer mutex.RUnlock()
+		return counts[2] == 1, nil
+	}))
+	mutex.RLock()
+	defer mutex.RUnlock()
+	require.Equal(1, counts[1])
+}
+
+func TestBroadcastTelemetry(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	b := func(_ context.Context, _ uint32, _ proto.Message) {}
+	u := func(_ context.Context, _ uint32, _ peerstore.PeerInfo, _ proto.Message) {}
+	var mutex sync.RWMutex
+	received := make(map[int]int)
+	// the handler of each agent accepts a report once, such that the relay stops
+	handler := func(i int) HandleTelemetryInbound {
+		return func(_ context.Context, _ string, _ []byte) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			received[i]++
+			if received[i] > 1 {
+				return errors.New("duplicate report")
+			}
+			return nil
+		}
+	}
+	bootnodePort := testutil.RandomPort()
+	bootnode := NewAgent(config.Config{
+		Network: config.Network{Host: "127.0.0.1", Port: bootnodePort},
+	}, b, u, WithTelemetryHandler(handler(0)))
+	require.NoError(bootnode.Start(ctx))
+	defer func() { require.NoError(bootnode.Stop(ctx)) }()
+	agents := []*Agent{bootnode}
+	for i := 1; i <= 2; i++ {
+		agent := NewAgent(config.Config{
+			Network: config.Network{
+				Host:           "127.0.0.1",
+				Port:           bootnodePort + i,
+				BootstrapNodes: []string{bootnode.Self()[0].String()},
+			},
+		}, b, u, WithTelemetryHandler(handler(i)))
+		require.NoError(agent.Start(ctx))
+		defer func() { require.NoError(agent.Stop(ctx)) }()
+		agents = append(agents, agent)
+	}
+
+	require.NoError(agents[2].BroadcastTelemetry(ctx, &testingpb.TestPayload{MsgBody: []byte{1}}))
+	require.NoError(testutil.WaitUntil(100*time.Millisecond, 10*time.Second, func() (bool, error) {
+		mutex.RLock()
+		defer mutex.RUnlock()
+		return received[0] > 0 && received[1] > 0, nil
+	}))
+}
+
 func TestUnicast(t *testing.T) {
 	ctx := context.Background()
 	n := 10
@@ -162,3 +284,274 @@ func TestUnicast(t *testing.T) {
 		}))
 	}
 }
+
+func TestUnicastCompression(t *testing.T) {
+	require := require.New(t)
+
+	ctx := WitContext(context.Background(), Context{ChainID: 1})
+	received := make(chan []byte, 10)
+	b := func(_ context.Context, _ uint32, _ proto.Message) {}
+	u := func(_ context.Context, _ uint32, _ peerstore.PeerInfo, msg proto.Message) {
+		testMsg, ok := msg.(*testingpb.TestPayload)
+		require.True(ok)
+		received <- testMsg.MsgBody
+	}
+	newAgent := func(compression string) *Agent {
+		agent := NewAgent(config.Config{
+			Network: config.Network{
+				Host:        "127.0.0.1",
+				Port:        testutil.RandomPort(),
+				Compression: compression,
+			},
+		}, b, u)
+		require.NoError(agent.Start(ctx))
+		return agent
+	}
+	compressed := newAgent(config.SnappyCompression)
+	defer func() { require.NoError(compressed.Stop(ctx)) }()
+	uncompressed := newAgent("")
+	defer func() { require.NoError(uncompressed.Stop(ctx)) }()
+	another := newAgent(config.SnappyCompression)
+	defer func() { require.NoError(another.Stop(ctx)) }()
+
+	large := bytes.Repeat([]byte("block"), 100000)
+	send := func(from, to *Agent) {
+		require.NoError(from.UnicastOutbound(ctx, to.Info(), &testingpb.TestPayload{MsgBody: large}))
+		select {
+		case body := <-received:
+			require.Equal(large, body)
+		case <-time.After(10 * time.Second):
+			require.FailNow("the message isn't received")
+		}
+	}
+	saved := func() float64 {
+		return promtestutil.ToFloat64(p2pCompressionSavedBytes.WithLabelValues(config.SnappyCompression, "out"))
+	}
+
+	// the message is compressed between the peers supporting the compression
+	before := saved()
+	send(compressed, another)
+	require.True(saved() > before)
+	require.True(compressed.supportsCompression(another.Info().ID))
+
+	// and falls back to uncompressed otherwise
+	before = saved()
+	send(compressed, uncompressed)
+	require.Equal(before, saved())
+	require.False(compressed.supportsCompression(uncompressed.Info().ID))
+	send(compressed, uncompressed)
+	send(uncompressed, compressed)
+	require.Equal(before, saved())
+
+	// the peer is tried again once remembered for over the TTL, and forgotten
+	compressed.mutex.Lock()
+	compressed.uncompressedPeers[uncompressed.Info().ID] = time.Now().Add(-uncompressedPeerTTL)
+	compressed.mutex.Unlock()
+	require.True(compressed.supportsCompression(uncompressed.Info().ID))
+	compressed.markUncompressed(another.Info().ID, time.Now())
+	require.True(compressed.supportsCompression(uncompressed.Info().ID))
+	require.False(compressed.supportsCompression(another.Info().ID))
+	compressed.mutex.RLock()
+	require.Equal(1, len(compressed.uncompressedPeers))
+	compressed.mutex.RUnlock()
+}
+
+func TestBanPeer(t *testing.T) {
+	require := require.New(t)
+
+	ctx := context.Background()
+	b := func(_ context.Context, _ uint32, _ proto.Message) {}
+	u := func(_ context.Context, _ uint32, _ peerstore.PeerInfo, _ proto.Message) {}
+
+	bootnode := NewAgent(config.Config{
+		Network: config.Network{
+			Host:             "127.0.0.1",
+			Port:             testutil.RandomPort(),
+			PeerBanThreshold: -3,
+			PeerBanDuration:  time.Hour,
+		},
+	}, b, u)
+	require.NoError(bootnode.Start(ctx))
+	defer func() { require.NoError(bootnode.Stop(ctx)) }()
+	agent := NewAgent(config.Config{
+		Network: config.Network{
+			Host:           "127.0.0.1",
+			Port:           testutil.RandomPort(),
+			BootstrapNodes: []string{bootnode.Self()[0].String()},
+		},
+	}, b, u)
+	require.NoError(agent.Start(ctx))
+	defer func() { require.NoError(agent.Stop(ctx)) }()
+
+	peerID := agent.Info().ID.Pretty()
+	isNeighbor := func() bool {
+		neighbors, err := bootnode.Neighbors(ctx)
+		require.NoError(err)
+		for _, neighbor := range neighbors {
+			if neighbor.ID.Pretty() == peerID {
+				return true
+			}
+		}
+		return false
+	}
+	require.NoError(testutil.WaitUntil(100*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return isNeighbor(), nil
+	}))
+
+	// score decrements on each consensus validation failure, until the peer gets banned
+	var reporter scheme.PeerScoreReporter = bootnode
+	banned := p2pPeerReportCounter.WithLabelValues("banned", "invalidSignature")
+	bannedBefore := promtestutil.ToFloat64(banned)
+	reporter.ReportInvalid(peerID, "invalidSignature")
+	reporter.ReportInvalid(peerID, "notDelegate")
+	require.Equal(-2, bootnode.PeerScores()[peerID])
+	require.True(isNeighbor())
+	reporter.ReportInvalid(peerID, "invalidSignature")
+	require.False(isNeighbor())
+	require.True(bootnode.scorer.isBanned(peerID))
+	require.Equal(bannedBefore+1, promtestutil.ToFloat64(banned))
+	_, ok := bootnode.PeerScores()[peerID]
+	require.False(ok)
+
+	// the messages rejected from the banned peer don't penalize it over again
+	// the message of a banned peer is rejected before parsing its type
+	rejected := p2pMsgCounter.WithLabelValues("unicast", "0", "in", peerID, failureStr)
+	before := promtestutil.ToFloat64(rejected)
+	for i := 0; i < 5; i++ {
+		require.NoError(agent.UnicastOutbound(WitContext(ctx, Context{ChainID: 1}), bootnode.Info(), &testingpb.TestPayload{
+			MsgBody: []byte{uint8(i)},
+		}))
+	}
+	require.NoError(testutil.WaitUntil(100*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return promtestutil.ToFloat64(rejected) == before+5, nil
+	}))
+	_, ok = bootnode.PeerScores()[peerID]
+	require.False(ok)
+
+	// manual ban
+	require.Error(agent.BanPeer("", time.Hour))
+	require.Error(agent.BanPeer(bootnode.Info().ID.Pretty(), 0))
+	neighbors, err := agent.Neighbors(ctx)
+	require.NoError(err)
+	require.NotEmpty(neighbors)
+	require.NoError(agent.BanPeer(bootnode.Info().ID.Pretty(), time.Hour))
+	neighbors, err = agent.Neighbors(ctx)
+	require.NoError(err)
+	require.Empty(neighbors)
+}
+
+func TestPeerScorer_BanExpiry(t *testing.T) {
+	require := require.New(t)
+
+	s := newPeerScorer(-1, 50*time.Millisecond)
+	s.penalize("peer")
+	require.True(s.isBanned("peer"))
+	time.Sleep(60 * time.Millisecond)
+	require.False(s.isBanned("peer"))
+
+	// auto ban is disabled with a non-negative threshold
+	s = newPeerScorer(0, time.Hour)
+	s.penalize("peer")
+	s.penalize("peer")
+	require.False(s.isBanned("peer"))
+	require.Equal(-2, s.snapshot()["peer"])
+}
+
+func TestPeerScorer_BanLimit(t *testing.T) {
+	require := require.New(t)
+
+	s := newPeerScorer(-3, time.Hour).withBanLimit(50*time.Millisecond, time.Hour)
+	require.False(s.penalize("peer1"))
+	require.False(s.penalize("peer1"))
+	require.Equal(-2, s.snapshot()["peer1"])
+	// the penalties out of the window don't count
+	time.Sleep(60 * time.Millisecond)
+	require.False(s.penalize("peer1"))
+	require.False(s.penalize("peer1"))
+	require.Equal(-2, s.snapshot()["peer1"])
+	require.False(s.isBanned("peer1"))
+	require.True(s.penalize("peer1"))
+	require.True(s.isBanned("peer1"))
+
+	// another ban is rate limited, while the score stays at the threshold
+	for i := 0; i < 5; i++ {
+		require.False(s.penalize("peer2"))
+	}
+	require.False(s.isBanned("peer2"))
+	require.Equal(-3, s.snapshot()["peer2"])
+	s.minBanInterval = 0
+	require.True(s.penalize("peer2"))
+	require.True(s.isBanned("peer2"))
+}
+
+func TestPeerVersions(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	b := func(_ context.Context, _ uint32, _ proto.Message) {}
+	u := func(_ context.Context, _ uint32, _ peerstore.PeerInfo, _ proto.Message) {}
+	bootnodePort := testutil.RandomPort()
+	bootnode := NewAgent(config.Config{
+		Network: config.Network{Host: "127.0.0.1", Port: bootnodePort},
+	}, b, u, WithVersion("v1.1.0"))
+	require.NoError(bootnode.Start(ctx))
+	defer func() { require.NoError(bootnode.Stop(ctx)) }()
+	agents := []*Agent{bootnode}
+	// the second agent doesn't report a version
+	for i, version := range []string{"v1.0.0", ""} {
+		agent := NewAgent(config.Config{
+			Network: config.Network{
+				Host:           "127.0.0.1",
+				Port:           bootnodePort + i + 1,
+				BootstrapNodes: []string{bootnode.Self()[0].String()},
+			},
+		}, b, u, WithVersion(version))
+		require.NoError(agent.Start(ctx))
+		defer func() { require.NoError(agent.Stop(ctx)) }()
+		agents = append(agents, agent)
+	}
+	versionsOf := func(agent *Agent) map[string]int {
+		versions, err := agent.PeerVersions(ctx)
+		require.NoError(err)
+		return versions
+	}
+	require.NoError(testutil.WaitUntil(100*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return versionsOf(bootnode)[UnknownPeerVersion] == 2, nil
+	}))
+
+	// an advertisement is replied with the version of the receiver
+	require.NoError(agents[1].AdvertiseVersion(ctx))
+	require.NoError(agents[2].AdvertiseVersion(ctx))
+	require.NoError(testutil.WaitUntil(100*time.Millisecond, 10*time.Second, func() (bool, error) {
+		return versionsOf(bootnode)["v1.0.0"] == 1 && versionsOf(agents[1])["v1.1.0"] == 1, nil
+	}))
+	require.Equal(map[string]int{"v1.0.0": 1, UnknownPeerVersion: 1}, versionsOf(bootnode))
+	require.Equal(1, versionsOf(agents[2])["v1.1.0"])
+
+	// the invalid version messages are rejected
+	for _, data := range [][]byte{nil, {2}, append([]byte{versionReply}, make([]byte, maxPeerVersionLength+1)...)} {
+		_, err := bootnode.handleVersion("peer", data)
+		require.Error(err)
+	}
+	reply, err := bootnode.handleVersion("peer", append([]byte{versionReply}, " v1.2.0 "...))
+	require.NoError(err)
+	require.False(reply)
+	version, ok := bootnode.peerVersions.get("peer")
+	require.True(ok)
+	require.Equal("v1.2.0", version)
+}
+
+func TestPeerScorer_Prune(t *testing.T) {
+	require := require.New(t)
+
+	s := newPeerScorer(-2, 50*time.Millisecond).withBanLimit(time.Hour, 0)
+	s.scoreTTL = 100 * time.Millisecond
+	s.penalize("idle")
+	s.penalize("banned")
+	s.penalize("banned")
+	require.True(s.isBanned("banned"))
+	time.Sleep(110 * time.Millisecond)
+	// the idle peer is pruned along with the expired ban, while the peer penalized just now is kept
+	s.penalize("active")
+	require.Equal(map[string]int{"active": -1}, s.snapshot())
+	require.Empty(s.bans)
+}
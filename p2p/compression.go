@@ -0,0 +1,145 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/pkg/compress"
+)
+
+const (
+	// The first byte of a message on a compressed topic tells whether the rest of it is compressed
+	uncompressedFlag byte = 0
+	compressedFlag   byte = 1
+
+	// compressionThreshold is the size below which a message is sent uncompressed, as the compression hardly saves
+	// anything on it
+	compressionThreshold = 256
+
+	// maxDecompressedSize is the max size of a message decompressed, well above the largest message sent, e.g., a block
+	// in the block sync, such that a small frame can't make the node allocate an arbitrary amount of memory
+	maxDecompressedSize = 32 << 20
+)
+
+var p2pCompressionSavedBytes = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "iotex_p2p_compression_saved_bytes",
+		Help: "Bytes saved by compressing the P2P messages",
+	},
+	[]string{"algorithm", "direction"},
+)
+
+func init() {
+	prometheus.MustRegister(p2pCompressionSavedBytes)
+}
+
+// compressor compresses the messages with an algorithm, and frames them with a flag byte, such that a message not
+// worth compressing is sent as it is. A message decompressed to more than maxSize bytes is rejected.
+type compressor struct {
+	algorithm  string
+	maxSize    int
+	compress   func([]byte) ([]byte, error)
+	decompress func(data []byte, maxSize int) ([]byte, error)
+}
+
+// newCompressor returns the compressor of the algorithm, or nil if the algorithm is empty or unknown
+func newCompressor(algorithm string) *compressor {
+	switch algorithm {
+	case config.SnappyCompression:
+		return &compressor{
+			algorithm: algorithm,
+			maxSize:   maxDecompressedSize,
+			compress: func(data []byte) ([]byte, error) {
+				return snappy.Encode(nil, data), nil
+			},
+			decompress: snappyDecompress,
+		}
+	case config.GzipCompression:
+		return &compressor{
+			algorithm:  algorithm,
+			maxSize:    maxDecompressedSize,
+			compress:   compress.Compress,
+			decompress: gzipDecompress,
+		}
+	default:
+		return nil
+	}
+}
+
+// snappyDecompress decompresses the data, whose decompressed size is read from its header before allocating for it
+func snappyDecompress(data []byte, maxSize int) ([]byte, error) {
+	size, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxSize {
+		return nil, errors.Errorf("decompressed size %d exceeds the max %d", size, maxSize)
+	}
+	return snappy.Decode(nil, data)
+}
+
+// gzipDecompress decompresses the data, which stops reading once the decompressed size exceeds the max
+func gzipDecompress(data []byte, maxSize int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	decompressed, err := ioutil.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > maxSize {
+		return nil, errors.Errorf("decompressed size exceeds the max %d", maxSize)
+	}
+	return decompressed, nil
+}
+
+// encode returns the framed message, which is compressed unless it is small or the compression doesn't shrink it
+func (c *compressor) encode(data []byte) ([]byte, error) {
+	if len(data) >= compressionThreshold {
+		compressed, err := c.compress(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(compressed)+1 < len(data) {
+			return append([]byte{compressedFlag}, compressed...), nil
+		}
+	}
+	return append([]byte{uncompressedFlag}, data...), nil
+}
+
+// decode returns the message of the frame
+func (c *compressor) decode(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty compressed message")
+	}
+	switch data[0] {
+	case uncompressedFlag:
+		return data[1:], nil
+	case compressedFlag:
+		decompressed, err := c.decompress(data[1:], c.maxSize)
+		if err != nil {
+			return nil, err
+		}
+		if saved := len(decompressed) - len(data); saved > 0 {
+			p2pCompressionSavedBytes.WithLabelValues(c.algorithm, "in").Add(float64(saved))
+		}
+		return decompressed, nil
+	default:
+		return nil, errors.Errorf("unknown compression flag %d", data[0])
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+)
+
+func TestCompressor(t *testing.T) {
+	require := require.New(t)
+	require.Nil(newCompressor(""))
+	require.Nil(newCompressor("lz4"))
+
+	large := bytes.Repeat([]byte("block"), 10000)
+	small := []byte("block")
+	for _, algorithm := range []string{config.SnappyCompression, config.GzipCompression} {
+		c := newCompressor(algorithm)
+		require.NotNil(c)
+		require.Equal(algorithm, c.algorithm)
+
+		encoded, err := c.encode(large)
+		require.NoError(err)
+		require.Equal(compressedFlag, encoded[0])
+		require.True(len(encoded) < len(large))
+		decoded, err := c.decode(encoded)
+		require.NoError(err)
+		require.Equal(large, decoded)
+
+		// a small message is sent as it is
+		encoded, err = c.encode(small)
+		require.NoError(err)
+		require.Equal(append([]byte{uncompressedFlag}, small...), encoded)
+		decoded, err = c.decode(encoded)
+		require.NoError(err)
+		require.Equal(small, decoded)
+
+		_, err = c.decode(nil)
+		require.Error(err)
+		_, err = c.decode([]byte{2, 1})
+		require.Error(err)
+		_, err = c.decode([]byte{compressedFlag, 1, 2, 3})
+		require.Error(err)
+
+		// a message decompressed beyond the max size is rejected
+		encoded, err = c.encode(large)
+		require.NoError(err)
+		c.maxSize = len(large) - 1
+		_, err = c.decode(encoded)
+		require.Error(err)
+		c.maxSize = len(large)
+		decoded, err = c.decode(encoded)
+		require.NoError(err)
+		require.Equal(large, decoded)
+	}
+}
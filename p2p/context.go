@@ -10,6 +10,8 @@ import "context"
 
 type p2pCtxKey struct{}
 
+type peerIDCtxKey struct{}
+
 // Context provides the auxiliary information Agent network operations
 type Context struct {
 	ChainID uint32
@@ -25,3 +27,14 @@ func GetContext(ctx context.Context) (Context, bool) {
 	p2pCtx, ok := ctx.Value(p2pCtxKey{}).(Context)
 	return p2pCtx, ok
 }
+
+// WithPeerID adds the ID of the peer relaying an inbound message into context
+func WithPeerID(ctx context.Context, peerID string) context.Context {
+	return context.WithValue(ctx, peerIDCtxKey{}, peerID)
+}
+
+// GetPeerID gets the ID of the peer relaying the inbound message
+func GetPeerID(ctx context.Context) (string, bool) {
+	peerID, ok := ctx.Value(peerIDCtxKey{}).(string)
+	return peerID, ok
+}
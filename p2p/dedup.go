@@ -0,0 +1,106 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/golang/groupcache/lru"
+	"github.com/iotexproject/go-pkgs/hash"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var p2pSuppressedBroadcastCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "iotex_p2p_suppressed_broadcasts",
+		Help: "Duplicate broadcast messages suppressed as they were broadcast within the dedup window",
+	},
+	[]string{"message"},
+)
+
+func init() {
+	prometheus.MustRegister(p2pSuppressedBroadcastCounter)
+}
+
+// broadcastDedup remembers the messages recently broadcast by their hashes, in an LRU cache of a bounded size, such
+// that a message broadcast again within the window is suppressed
+type broadcastDedup struct {
+	mutex  sync.Mutex
+	window time.Duration
+	seen   *lru.Cache
+	now    func() time.Time
+}
+
+// newBroadcastDedup returns the dedup of the messages broadcast within the window, or nil if the size or the window
+// isn't positive
+func newBroadcastDedup(size int, window time.Duration) *broadcastDedup {
+	if size <= 0 || window <= 0 {
+		return nil
+	}
+	return &broadcastDedup{
+		window: window,
+		seen:   lru.New(size),
+		now:    time.Now,
+	}
+}
+
+// mark records the message about to be broadcast, unless it has been broadcast within the window, and tells whether it
+// is recorded. The check and the record are done at once, such that only one of the concurrent broadcasts of the same
+// message goes out.
+func (d *broadcastDedup) mark(key hash.Hash256) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := d.now()
+	if value, ok := d.seen.Get(key); ok && now.Sub(value.(time.Time)) <= d.window {
+		return false
+	}
+	d.seen.Add(key, now)
+	return true
+}
+
+// unmark forgets the message whose broadcast failed, such that a retry isn't suppressed
+func (d *broadcastDedup) unmark(key hash.Hash256) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.seen.Remove(key)
+}
+
+// broadcastKey returns the key of a message in the dedup, which is the hash of the chain ID, the message type and the
+// message body, excluding the timestamp of the broadcast
+func broadcastKey(chainID uint32, msgType int32, msgBody []byte) hash.Hash256 {
+	buf := make([]byte, 8, 8+len(msgBody))
+	binary.BigEndian.PutUint32(buf, chainID)
+	binary.BigEndian.PutUint32(buf[4:], uint32(msgType))
+	return hash.Hash256b(append(buf, msgBody...))
+}
+
+// gossipFanoutOnce makes sure the gossip fan-out is only set once in the process
+var gossipFanoutOnce sync.Once
+
+// setGossipFanout sets the number of the peers a broadcast message is gossiped to, along with the bounds of the mesh
+// around it. The gossip router reads them from package variables, hence they apply to all the agents in the process.
+// They are only set by the first agent created, before any host runs a router reading them.
+func setGossipFanout(degree int) {
+	gossipFanoutOnce.Do(func() {
+		setGossipSubDegree(degree)
+	})
+}
+
+// setGossipSubDegree sets the degree of the gossip mesh and its bounds, unless the degree isn't positive
+func setGossipSubDegree(degree int) {
+	if degree <= 0 {
+		return
+	}
+	pubsub.GossipSubD = degree
+	pubsub.GossipSubDlo = (degree*2 + 2) / 3
+	pubsub.GossipSubDhi = degree * 2
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcastDedup(t *testing.T) {
+	require := require.New(t)
+	require.Nil(newBroadcastDedup(0, time.Second))
+	require.Nil(newBroadcastDedup(10, 0))
+
+	now := time.Unix(1500000000, 0)
+	d := newBroadcastDedup(2, time.Second)
+	d.now = func() time.Time { return now }
+	k1 := broadcastKey(1, 1, []byte("block"))
+	require.True(d.mark(k1))
+	require.False(d.mark(k1))
+
+	// the key tells the chains and the message types apart
+	require.NotEqual(k1, broadcastKey(2, 1, []byte("block")))
+	require.NotEqual(k1, broadcastKey(1, 2, []byte("block")))
+
+	// a message is broadcast again once the window passes
+	now = now.Add(time.Second)
+	require.False(d.mark(k1))
+	now = now.Add(time.Millisecond)
+	require.True(d.mark(k1))
+
+	// or once its broadcast fails
+	d.unmark(k1)
+	require.True(d.mark(k1))
+
+	// the oldest message is dropped beyond the size
+	k2 := broadcastKey(1, 1, []byte("k2"))
+	k3 := broadcastKey(1, 1, []byte("k3"))
+	require.True(d.mark(k2))
+	require.True(d.mark(k3))
+	require.True(d.mark(k1))
+	require.False(d.mark(k3))
+
+	// only one of the concurrent broadcasts of the same message goes out
+	d = newBroadcastDedup(10, time.Minute)
+	k4 := broadcastKey(1, 1, []byte("k4"))
+	var (
+		wg     sync.WaitGroup
+		marked int32
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if d.mark(k4) {
+				atomic.AddInt32(&marked, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(int32(1), marked)
+}
+
+func TestSetGossipFanout(t *testing.T) {
+	require := require.New(t)
+	d, dlo, dhi := pubsub.GossipSubD, pubsub.GossipSubDlo, pubsub.GossipSubDhi
+	defer func() {
+		pubsub.GossipSubD, pubsub.GossipSubDlo, pubsub.GossipSubDhi = d, dlo, dhi
+	}()
+
+	setGossipSubDegree(0)
+	require.Equal(d, pubsub.GossipSubD)
+	setGossipSubDegree(3)
+	require.Equal(3, pubsub.GossipSubD)
+	require.Equal(2, pubsub.GossipSubDlo)
+	require.Equal(6, pubsub.GossipSubDhi)
+	setGossipSubDegree(1)
+	require.Equal(1, pubsub.GossipSubDlo)
+
+	// the fan-out is only set once in the process
+	gossipFanoutOnce.Do(func() {})
+	setGossipFanout(5)
+	require.Equal(1, pubsub.GossipSubD)
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/pkg/log"
+)
+
+// peerScoreTTL is how long the score of a peer is kept since it is last penalized, such that the peers which have
+// disconnected are eventually forgotten
+const peerScoreTTL = time.Hour
+
+type (
+	// peerScore is the score of a peer and the last time it is penalized
+	peerScore struct {
+		score int
+		// penalties are the times of the penalties within the window, which make up the score if the window is set
+		penalties []time.Time
+		updated   time.Time
+	}
+
+	// peerScorer keeps track of the score of each peer, and the peers which are banned
+	peerScorer struct {
+		mutex        sync.RWMutex
+		banThreshold int
+		banDuration  time.Duration
+		scores       map[string]peerScore
+		bans         map[string]time.Time
+		// window is how long a penalty counts toward the score of a peer, and 0 counts it until the peer is banned or
+		// pruned
+		window         time.Duration
+		minBanInterval time.Duration
+		lastBan        time.Time
+		// scoreTTL is how long an idle peer is kept, where the stale entries are pruned at most once per scoreTTL
+		scoreTTL  time.Duration
+		lastPrune time.Time
+	}
+)
+
+func newPeerScorer(banThreshold int, banDuration time.Duration) *peerScorer {
+	return &peerScorer{
+		banThreshold: banThreshold,
+		banDuration:  banDuration,
+		scores:       make(map[string]peerScore),
+		bans:         make(map[string]time.Time),
+		scoreTTL:     peerScoreTTL,
+		lastPrune:    time.Now(),
+	}
+}
+
+// withBanLimit makes a penalty count toward the score of a peer only within the window, while at most one peer is
+// banned automatically in minBanInterval
+func (s *peerScorer) withBanLimit(window, minBanInterval time.Duration) *peerScorer {
+	s.window = window
+	s.minBanInterval = minBanInterval
+	return s
+}
+
+// ban bans a peer until the given duration elapses
+func (s *peerScorer) ban(id string, duration time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.bans[id] = time.Now().Add(duration)
+}
+
+// isBanned checks whether a peer is currently banned
+func (s *peerScorer) isBanned(id string) bool {
+	s.mutex.RLock()
+	until, ok := s.bans[id]
+	s.mutex.RUnlock()
+	if !ok {
+		return false
+	}
+	if time.Now().Before(until) {
+		return true
+	}
+	s.mutex.Lock()
+	// check again, the ban may be extended in between
+	if until, ok = s.bans[id]; ok && !time.Now().Before(until) {
+		delete(s.bans, id)
+	}
+	s.mutex.Unlock()
+	return false
+}
+
+// penalize decrements the score of a peer, and bans it if the score reaches the ban threshold. A ban within
+// minBanInterval of the last one is deferred to the next penalty after the interval. It returns whether the peer is
+// banned.
+func (s *peerScorer) penalize(id string) bool {
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prune(now)
+	score := s.scores[id]
+	score.updated = now
+	if s.window > 0 {
+		i := 0
+		for i < len(score.penalties) && now.Sub(score.penalties[i]) >= s.window {
+			i++
+		}
+		score.penalties = append(score.penalties[i:], now)
+		// the penalties beyond the threshold only wait for the rate limit
+		if s.banThreshold < 0 && len(score.penalties) > -s.banThreshold {
+			score.penalties = score.penalties[len(score.penalties)+s.banThreshold:]
+		}
+		score.score = -len(score.penalties)
+	} else {
+		score.score--
+	}
+	s.scores[id] = score
+	if s.banThreshold >= 0 || s.banDuration <= 0 || score.score > s.banThreshold ||
+		now.Sub(s.lastBan) < s.minBanInterval {
+		return false
+	}
+	log.L().Warn("Ban peer due to too many invalid messages.",
+		zap.String("peer", id),
+		zap.Int("score", score.score),
+		zap.Duration("window", s.window),
+		zap.Duration("duration", s.banDuration))
+	s.bans[id] = now.Add(s.banDuration)
+	s.lastBan = now
+	// start over once the ban expires
+	delete(s.scores, id)
+	return true
+}
+
+// snapshot returns a copy of the scores of all peers
+func (s *peerScorer) snapshot() map[string]int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	scores := make(map[string]int, len(s.scores))
+	for id, score := range s.scores {
+		scores[id] = score.score
+	}
+	return scores
+}
+
+// prune drops the scores of the peers idle for scoreTTL, e.g., those which have disconnected, along with the expired
+// bans. It sweeps at most once per scoreTTL, and has to be called with the mutex locked.
+func (s *peerScorer) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < s.scoreTTL {
+		return
+	}
+	s.lastPrune = now
+	for id, score := range s.scores {
+		if now.Sub(score.updated) >= s.scoreTTL {
+			delete(s.scores, id)
+		}
+	}
+	for id, until := range s.bans {
+		if !now.Before(until) {
+			delete(s.bans, id)
+		}
+	}
+}
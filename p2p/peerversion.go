@@ -0,0 +1,149 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// UnknownPeerVersion is the version of a neighbor which hasn't advertised its software version
+	UnknownPeerVersion = "unknown"
+	// maxPeerVersionLength is the max length of a software version advertised by a peer
+	maxPeerVersionLength = 64
+)
+
+const (
+	// versionAdvertisement is sent to a neighbor whose version is unknown, which replies with its own version
+	versionAdvertisement byte = iota
+	// versionReply is sent in reply to an advertisement, which isn't replied again
+	versionReply
+)
+
+// peerVersions holds the software versions advertised by the peers, keyed by the peer IDs
+type peerVersions struct {
+	mutex    sync.RWMutex
+	versions map[string]string
+}
+
+func newPeerVersions() *peerVersions {
+	return &peerVersions{versions: make(map[string]string)}
+}
+
+func (v *peerVersions) set(peerID, version string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.versions[peerID] = version
+}
+
+func (v *peerVersions) get(peerID string) (string, bool) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	version, ok := v.versions[peerID]
+	return version, ok
+}
+
+// retain drops the versions of the peers which are not neighbors any more
+func (v *peerVersions) retain(neighbors map[string]struct{}) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for peerID := range v.versions {
+		if _, ok := neighbors[peerID]; !ok {
+			delete(v.versions, peerID)
+		}
+	}
+}
+
+// WithVersion sets the software version the agent advertises to its neighbors, which is the package version by default
+func WithVersion(version string) Option {
+	return func(p *Agent) {
+		p.version = version
+	}
+}
+
+// AdvertiseVersion sends the software version of the node to the neighbors whose versions are unknown, each of which
+// replies with its own version. It fails only if none of them receives it.
+func (p *Agent) AdvertiseVersion(ctx context.Context) error {
+	neighbors, err := p.Neighbors(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error when getting neighbors")
+	}
+	current := make(map[string]struct{}, len(neighbors))
+	var lastErr error
+	sent := 0
+	for _, neighbor := range neighbors {
+		peerID := neighbor.ID.Pretty()
+		current[peerID] = struct{}{}
+		if _, ok := p.peerVersions.get(peerID); ok {
+			continue
+		}
+		status := successStr
+		if err := p.host.Unicast(ctx, neighbor, versionTopic+p.topicSuffix, p.versionMsg(versionAdvertisement)); err != nil {
+			status = failureStr
+			lastErr = err
+		} else {
+			sent++
+		}
+		p2pMsgCounter.WithLabelValues("version", "", "out", peerID, status).Inc()
+	}
+	p.peerVersions.retain(current)
+	if sent == 0 && lastErr != nil {
+		return errors.Wrap(lastErr, "error when advertising version")
+	}
+	return nil
+}
+
+// PeerVersions returns the number of the neighbors running each software version, where the neighbors which haven't
+// advertised their versions are counted as UnknownPeerVersion
+func (p *Agent) PeerVersions(ctx context.Context) (map[string]int, error) {
+	neighbors, err := p.Neighbors(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error when getting neighbors")
+	}
+	counts := make(map[string]int)
+	for _, neighbor := range neighbors {
+		version, ok := p.peerVersions.get(neighbor.ID.Pretty())
+		if !ok {
+			version = UnknownPeerVersion
+		}
+		counts[version]++
+	}
+	return counts, nil
+}
+
+// versionMsg returns the message carrying the software version of the node, which is truncated to the max length
+func (p *Agent) versionMsg(kind byte) []byte {
+	version := p.version
+	if len(version) > maxPeerVersionLength {
+		version = version[:maxPeerVersionLength]
+	}
+	return append([]byte{kind}, version...)
+}
+
+// handleVersion records the version advertised by a peer, and returns whether it has to be replied
+func (p *Agent) handleVersion(peerID string, data []byte) (bool, error) {
+	if len(data) == 0 || len(data) > 1+maxPeerVersionLength {
+		return false, errors.Errorf("invalid version message of length %d", len(data))
+	}
+	kind := data[0]
+	if kind != versionAdvertisement && kind != versionReply {
+		return false, errors.Errorf("invalid version message kind %d", kind)
+	}
+	version := strings.TrimSpace(string(data[1:]))
+	if version == "" {
+		version = UnknownPeerVersion
+	}
+	p.peerVersions.set(peerID, version)
+	return kind == versionAdvertisement, nil
+}
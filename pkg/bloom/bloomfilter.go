@@ -0,0 +1,285 @@
+// Copyright (c) 2019 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package bloom
+
+import (
+	"math/bits"
+	"sync"
+
+	"github.com/iotexproject/go-pkgs/bloom"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/pkg/bloom/bloompb"
+)
+
+const (
+	// bloomFilterVersion is the version of the serialized form of BloomFilter
+	bloomFilterVersion = 1
+	// compatibleNumBits is the size of the bloom filter used as the logs bloom of block headers
+	compatibleNumBits = 2048
+)
+
+var _ bloom.BloomFilter = (*BloomFilter)(nil)
+
+type (
+	// BloomFilter is a bloom filter along with its parameters, i.e., the number of bits and hash functions, such that
+	// it could be transported without knowing the parameters in advance. A filter of 2048 bits hashes the keys as the
+	// logs bloom of block headers does, while filters of other sizes use double hashing over the bits. It is not safe
+	// for concurrent use, which ConcurrentBloomFilter provides.
+	BloomFilter struct {
+		bloom.BloomFilter
+		numBits uint
+		numHash uint
+	}
+
+	// ConcurrentBloomFilter is a BloomFilter safe for concurrent use, on which TestAndAdd is atomic, such that
+	// exactly one of the concurrent callers adding a key absent from the filter is told so
+	ConcurrentBloomFilter struct {
+		mutex sync.RWMutex
+		f     *BloomFilter
+	}
+
+	// bitmapFilter is a bloom filter of arbitrary size, which owns its bitmap
+	bitmapFilter struct {
+		numBits uint64
+		numHash uint32
+		bits    []byte
+		// legacy hashes the keys as the 2048-bit logs bloom of go-pkgs does, which takes each 2-byte pair of the hash
+		// of a key as the byte and bit positions
+		legacy bool
+	}
+
+	// keyHash is the hash of a key, from which each hash function derives a bit position
+	keyHash struct {
+		h      hash.Hash256
+		h1, h2 uint64
+	}
+)
+
+// NewBloomFilter returns a bloom filter of m bits and h hash functions. The number of bits has to be a multiple of 8.
+func NewBloomFilter(m, h uint) (*BloomFilter, error) {
+	return bloomFilterFromBytes(make([]byte, m/8), m, h)
+}
+
+// BloomFilterFromProto constructs a bloom filter from the protobuf message produced by ToProto()
+func BloomFilterFromProto(pb *bloompb.BloomFilter) (*BloomFilter, error) {
+	if pb == nil {
+		return nil, errors.New("bloom filter message is nil")
+	}
+	if pb.Version != bloomFilterVersion {
+		return nil, errors.Errorf("bloom filter version %d not supported", pb.Version)
+	}
+	if uint64(len(pb.Bitmap))*8 != pb.NumBits {
+		return nil, errors.Errorf("wrong bitmap length %d, expecting %d bits", len(pb.Bitmap), pb.NumBits)
+	}
+	return bloomFilterFromBytes(pb.Bitmap, uint(pb.NumBits), uint(pb.NumHash))
+}
+
+// NumBits returns the number of bits of the bloom filter
+func (f *BloomFilter) NumBits() uint { return f.numBits }
+
+// NumHash returns the number of hash functions of the bloom filter
+func (f *BloomFilter) NumHash() uint { return f.numHash }
+
+// ToProto converts the bloom filter along with its parameters into a protobuf message
+func (f *BloomFilter) ToProto() *bloompb.BloomFilter {
+	return &bloompb.BloomFilter{
+		Version: bloomFilterVersion,
+		NumBits: uint64(f.numBits),
+		NumHash: uint32(f.numHash),
+		Bitmap:  f.Bytes(),
+	}
+}
+
+// ExistConstantTime checks if a key is in the bloom filter like Exist, but always examines all the hash positions
+// instead of returning on the first missing bit, such that the time taken does not depend on the content of the
+// filter. It is meant for filters gating the access to sensitive data.
+func (f *BloomFilter) ExistConstantTime(key hash.Hash256) bool {
+	return f.bitmap().existConstantTime(key[:])
+}
+
+// TestAndAdd adds a key into the bloom filter, and returns whether the key is (probably) in the filter before, i.e.,
+// what Exist returns before Add, while hashing the key once
+func (f *BloomFilter) TestAndAdd(key hash.Hash256) bool {
+	return f.bitmap().testAndAdd(key[:])
+}
+
+// PopCount returns the number of bits set in the bloom filter. The false-positive rate of the filter is about
+// (PopCount / NumBits) ^ NumHash, which tells when the filter is too saturated and should be rotated.
+func (f *BloomFilter) PopCount() int {
+	n := 0
+	for _, v := range f.bitmap().bits {
+		n += bits.OnesCount8(v)
+	}
+	return n
+}
+
+// Reset clears all the bits of the filter, which keeps its parameters
+func (f *BloomFilter) Reset() {
+	bm := f.bitmap()
+	for i := range bm.bits {
+		bm.bits[i] = 0
+	}
+}
+
+// bitmap returns the bitmap filter underneath, which all the constructors create
+func (f *BloomFilter) bitmap() *bitmapFilter {
+	return f.BloomFilter.(*bitmapFilter)
+}
+
+// NewConcurrentBloomFilter returns a bloom filter of m bits and h hash functions safe for concurrent use
+func NewConcurrentBloomFilter(m, h uint) (*ConcurrentBloomFilter, error) {
+	f, err := NewBloomFilter(m, h)
+	if err != nil {
+		return nil, err
+	}
+	return &ConcurrentBloomFilter{f: f}, nil
+}
+
+// Add adds a key into the bloom filter
+func (c *ConcurrentBloomFilter) Add(key []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.f.Add(key)
+}
+
+// Exist checks if a key is in the bloom filter
+func (c *ConcurrentBloomFilter) Exist(key []byte) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.f.Exist(key)
+}
+
+// TestAndAdd adds a key into the bloom filter, and returns whether the key is (probably) in the filter before
+func (c *ConcurrentBloomFilter) TestAndAdd(key hash.Hash256) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.f.TestAndAdd(key)
+}
+
+// PopCount returns the number of bits set in the bloom filter
+func (c *ConcurrentBloomFilter) PopCount() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.f.PopCount()
+}
+
+// Bytes returns a copy of the bitmap of the bloom filter
+func (c *ConcurrentBloomFilter) Bytes() []byte {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	b := c.f.Bytes()
+	return append(make([]byte, 0, len(b)), b...)
+}
+
+// NumBits returns the number of bits of the bloom filter
+func (c *ConcurrentBloomFilter) NumBits() uint { return c.f.NumBits() }
+
+// NumHash returns the number of hash functions of the bloom filter
+func (c *ConcurrentBloomFilter) NumHash() uint { return c.f.NumHash() }
+
+func bloomFilterFromBytes(b []byte, m, h uint) (*BloomFilter, error) {
+	if m == 0 || m%8 != 0 {
+		return nil, errors.Errorf("expecting the number of bits %d to be a positive multiple of 8", m)
+	}
+	if h == 0 || h > 64 {
+		return nil, errors.Errorf("expecting 0 < number of hash functions %d <= 64", h)
+	}
+	if uint(len(b))*8 != m {
+		return nil, errors.Errorf("wrong length %d, expecting %d", len(b), m/8)
+	}
+	legacy := m == compatibleNumBits
+	if legacy && h > 16 {
+		// a 32-byte hash has 16 pairs only
+		return nil, errors.Errorf("expecting 0 < number of hash functions %d <= 16 for %d bits", h, m)
+	}
+	bits := make([]byte, len(b))
+	copy(bits, b)
+	return &BloomFilter{
+		BloomFilter: &bitmapFilter{
+			numBits: uint64(m),
+			numHash: uint32(h),
+			bits:    bits,
+			legacy:  legacy,
+		},
+		numBits: m,
+		numHash: h,
+	}, nil
+}
+
+// hash hashes a key once for all the hash functions
+func (f *bitmapFilter) hash(key []byte) keyHash {
+	kh := keyHash{h: hash.Hash256b(key)}
+	if !f.legacy {
+		kh.h1, kh.h2 = doubleHash(kh.h)
+	}
+	return kh
+}
+
+// pos returns the bit position of a key by the i-th hash function
+func (f *bitmapFilter) pos(kh keyHash, i uint32) uint64 {
+	if f.legacy {
+		return uint64(kh.h[2*i])<<3 | uint64(kh.h[2*i+1]&7)
+	}
+	return (kh.h1 + uint64(i)*kh.h2) % f.numBits
+}
+
+func (f *bitmapFilter) Add(key []byte) {
+	if key == nil {
+		return
+	}
+	kh := f.hash(key)
+	for i := uint32(0); i < f.numHash; i++ {
+		pos := f.pos(kh, i)
+		f.bits[pos>>3] |= 1 << (pos & 7)
+	}
+}
+
+func (f *bitmapFilter) Exist(key []byte) bool {
+	if key == nil {
+		return false
+	}
+	kh := f.hash(key)
+	for i := uint32(0); i < f.numHash; i++ {
+		pos := f.pos(kh, i)
+		if f.bits[pos>>3]&(1<<(pos&7)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *bitmapFilter) testAndAdd(key []byte) bool {
+	kh := f.hash(key)
+	present := true
+	for i := uint32(0); i < f.numHash; i++ {
+		pos := f.pos(kh, i)
+		mask := byte(1) << (pos & 7)
+		if f.bits[pos>>3]&mask == 0 {
+			present = false
+			f.bits[pos>>3] |= mask
+		}
+	}
+	return present
+}
+
+func (f *bitmapFilter) existConstantTime(key []byte) bool {
+	kh := f.hash(key)
+	found := byte(1)
+	for i := uint32(0); i < f.numHash; i++ {
+		pos := f.pos(kh, i)
+		found &= f.bits[pos>>3] >> (pos & 7)
+	}
+	return found&1 == 1
+}
+
+func (f *bitmapFilter) Bytes() []byte {
+	b := make([]byte, len(f.bits))
+	copy(b, f.bits)
+	return b
+}
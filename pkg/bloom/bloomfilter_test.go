@@ -0,0 +1,278 @@
+// Copyright (c) 2019 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package bloom
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/go-pkgs/bloom"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/pkg/bloom/bloompb"
+)
+
+func TestNewBloomFilter(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewBloomFilter(0, 3)
+	require.Error(err)
+	_, err = NewBloomFilter(2047, 3)
+	require.Error(err)
+	_, err = NewBloomFilter(2048, 0)
+	require.Error(err)
+	_, err = NewBloomFilter(2048, 17)
+	require.Error(err)
+	_, err = NewBloomFilter(4096, 65)
+	require.Error(err)
+
+	// the filter of 2048 bits hashes the keys as the logs bloom does, of any number of hash functions
+	for h := uint(1); h <= 16; h++ {
+		f, err := NewBloomFilter(2048, h)
+		require.NoError(err)
+		logsBloom, err := bloom.NewBloomFilter(2048, h)
+		require.NoError(err)
+		for i := 0; i < 100; i++ {
+			key := hash.Hash256b([]byte(strconv.Itoa(i)))
+			if i%2 == 0 {
+				f.Add(key[:])
+			} else {
+				f.TestAndAdd(key)
+			}
+			logsBloom.Add(key[:])
+		}
+		require.Equal(logsBloom.Bytes(), f.Bytes())
+		for i := 0; i < 200; i++ {
+			key := hash.Hash256b([]byte(strconv.Itoa(i)))
+			require.Equal(logsBloom.Exist(key[:]), f.Exist(key[:]))
+			require.Equal(logsBloom.Exist(key[:]), f.ExistConstantTime(key))
+		}
+	}
+}
+
+func TestBloomFilter_ProtoRoundTrip(t *testing.T) {
+	require := require.New(t)
+
+	for _, params := range []struct {
+		m, h uint
+	}{
+		{2048, 3},
+		{1 << 20, 7},
+	} {
+		f, err := NewBloomFilter(params.m, params.h)
+		require.NoError(err)
+		for i := 0; i < 1000; i++ {
+			f.Add([]byte(strconv.Itoa(i)))
+		}
+		b, err := proto.Marshal(f.ToProto())
+		require.NoError(err)
+		pb := &bloompb.BloomFilter{}
+		require.NoError(proto.Unmarshal(b, pb))
+		f2, err := BloomFilterFromProto(pb)
+		require.NoError(err)
+		require.Equal(params.m, f2.NumBits())
+		require.Equal(params.h, f2.NumHash())
+		require.Equal(f.Bytes(), f2.Bytes())
+		for i := 0; i < 1000; i++ {
+			require.True(f2.Exist([]byte(strconv.Itoa(i))))
+		}
+	}
+}
+
+func TestBloomFilterFromProto(t *testing.T) {
+	require := require.New(t)
+
+	f, err := NewBloomFilter(2048, 3)
+	require.NoError(err)
+	_, err = BloomFilterFromProto(nil)
+	require.Error(err)
+
+	pb := f.ToProto()
+	pb.Version = 2
+	_, err = BloomFilterFromProto(pb)
+	require.Error(err)
+
+	pb = f.ToProto()
+	pb.NumBits = 4096
+	_, err = BloomFilterFromProto(pb)
+	require.Error(err)
+
+	pb = f.ToProto()
+	pb.Bitmap = pb.Bitmap[1:]
+	_, err = BloomFilterFromProto(pb)
+	require.Error(err)
+
+	pb = f.ToProto()
+	pb.NumHash = 0
+	_, err = BloomFilterFromProto(pb)
+	require.Error(err)
+}
+
+func TestBloomFilter_ExistConstantTime(t *testing.T) {
+	require := require.New(t)
+
+	for _, params := range []struct {
+		m, h uint
+	}{
+		{2048, 3},
+		{2048, 16},
+		{4096, 5},
+		{1 << 16, 7},
+	} {
+		f, err := NewBloomFilter(params.m, params.h)
+		require.NoError(err)
+		for i := 0; i < 200; i++ {
+			k := hash.Hash256b([]byte(strconv.Itoa(i)))
+			f.Add(k[:])
+		}
+		var found, missing int
+		for i := 0; i < 2000; i++ {
+			k := hash.Hash256b([]byte(strconv.Itoa(i)))
+			exist := f.Exist(k[:])
+			require.Equal(exist, f.ExistConstantTime(k), "key %d of filter %d/%d", i, params.m, params.h)
+			if exist {
+				found++
+			} else {
+				missing++
+			}
+		}
+		require.True(found >= 200)
+		require.NotZero(missing)
+	}
+}
+
+func TestBloomFilter_TestAndAdd(t *testing.T) {
+	require := require.New(t)
+
+	for _, params := range []struct {
+		m, h uint
+	}{
+		{2048, 3},
+		{2048, 16},
+		{1 << 16, 7},
+	} {
+		f, err := NewBloomFilter(params.m, params.h)
+		require.NoError(err)
+		added, err := NewBloomFilter(params.m, params.h)
+		require.NoError(err)
+		for i := 0; i < 500; i++ {
+			k := hash.Hash256b([]byte(strconv.Itoa(i)))
+			// the same as Exist then Add
+			exist := added.Exist(k[:])
+			added.Add(k[:])
+			require.Equal(exist, f.TestAndAdd(k), "key %d of filter %d/%d", i, params.m, params.h)
+			require.True(f.Exist(k[:]))
+			require.True(f.TestAndAdd(k))
+		}
+		require.Equal(added.Bytes(), f.Bytes())
+	}
+}
+
+func TestBloomFilter_PopCount(t *testing.T) {
+	require := require.New(t)
+
+	for _, params := range []struct {
+		m, h uint
+	}{
+		{2048, 3},
+		{1 << 16, 7},
+	} {
+		f, err := NewBloomFilter(params.m, params.h)
+		require.NoError(err)
+		require.Zero(f.PopCount())
+		k := hash.Hash256b([]byte("key"))
+		f.TestAndAdd(k)
+		require.True(f.PopCount() > 0 && f.PopCount() <= int(params.h))
+
+		// the filter is half saturated after about m * ln2 / h keys, when the false-positive rate is 2^-h
+		var n int
+		for f.PopCount() < int(params.m/2) {
+			k := hash.Hash256b([]byte(strconv.Itoa(n)))
+			f.TestAndAdd(k)
+			n++
+		}
+		expected := float64(params.m) * math.Ln2 / float64(params.h)
+		require.InDelta(expected, float64(n), expected*0.1, "filter %d/%d", params.m, params.h)
+		fpRate := math.Pow(float64(f.PopCount())/float64(params.m), float64(params.h))
+		var falsePositives int
+		for i := 0; i < 10000; i++ {
+			k := hash.Hash256b([]byte("missing" + strconv.Itoa(i)))
+			if f.Exist(k[:]) {
+				falsePositives++
+			}
+		}
+		require.InDelta(fpRate, float64(falsePositives)/10000, 0.02, "filter %d/%d", params.m, params.h)
+
+		// a fully saturated filter holds every key
+		if params.m > 2048 {
+			continue
+		}
+		for i := 0; f.PopCount() < int(params.m); i++ {
+			k := hash.Hash256b([]byte("saturate" + strconv.Itoa(i)))
+			f.TestAndAdd(k)
+		}
+		require.True(f.TestAndAdd(hash.Hash256b([]byte("any"))))
+	}
+}
+
+func TestConcurrentBloomFilter(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewConcurrentBloomFilter(2047, 3)
+	require.Error(err)
+	for _, params := range []struct {
+		m, h uint
+	}{
+		{2048, 3},
+		{1 << 16, 7},
+	} {
+		f, err := NewConcurrentBloomFilter(params.m, params.h)
+		require.NoError(err)
+		require.Equal(params.m, f.NumBits())
+		require.Equal(params.h, f.NumHash())
+
+		// each key is told absent to at most one of the callers adding it concurrently
+		const keys = 200
+		var (
+			wg     sync.WaitGroup
+			mutex  sync.Mutex
+			absent = make(map[int]int)
+		)
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < keys; i++ {
+					if !f.TestAndAdd(hash.Hash256b([]byte(strconv.Itoa(i)))) {
+						mutex.Lock()
+						absent[i]++
+						mutex.Unlock()
+					}
+					f.PopCount()
+				}
+			}()
+		}
+		wg.Wait()
+		for i, n := range absent {
+			require.Equal(1, n, "key %d", i)
+		}
+		require.NotEmpty(absent)
+		for i := 0; i < keys; i++ {
+			k := hash.Hash256b([]byte(strconv.Itoa(i)))
+			require.True(f.Exist(k[:]))
+		}
+		f.Add([]byte("key"))
+		require.True(f.Exist([]byte("key")))
+		b := f.Bytes()
+		b[0] ^= 0xff
+		require.NotEqual(b, f.Bytes())
+	}
+}
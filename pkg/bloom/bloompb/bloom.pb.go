@@ -0,0 +1,102 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: bloom.proto
+
+package bloompb
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type BloomFilter struct {
+	Version              uint32   `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	NumBits              uint64   `protobuf:"varint,2,opt,name=numBits,proto3" json:"numBits,omitempty"`
+	NumHash              uint32   `protobuf:"varint,3,opt,name=numHash,proto3" json:"numHash,omitempty"`
+	Bitmap               []byte   `protobuf:"bytes,4,opt,name=bitmap,proto3" json:"bitmap,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BloomFilter) Reset()         { *m = BloomFilter{} }
+func (m *BloomFilter) String() string { return proto.CompactTextString(m) }
+func (*BloomFilter) ProtoMessage()    {}
+func (*BloomFilter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_244a8f28f63f0104, []int{0}
+}
+
+func (m *BloomFilter) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BloomFilter.Unmarshal(m, b)
+}
+func (m *BloomFilter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BloomFilter.Marshal(b, m, deterministic)
+}
+func (m *BloomFilter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BloomFilter.Merge(m, src)
+}
+func (m *BloomFilter) XXX_Size() int {
+	return xxx_messageInfo_BloomFilter.Size(m)
+}
+func (m *BloomFilter) XXX_DiscardUnknown() {
+	xxx_messageInfo_BloomFilter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BloomFilter proto.InternalMessageInfo
+
+func (m *BloomFilter) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *BloomFilter) GetNumBits() uint64 {
+	if m != nil {
+		return m.NumBits
+	}
+	return 0
+}
+
+func (m *BloomFilter) GetNumHash() uint32 {
+	if m != nil {
+		return m.NumHash
+	}
+	return 0
+}
+
+func (m *BloomFilter) GetBitmap() []byte {
+	if m != nil {
+		return m.Bitmap
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*BloomFilter)(nil), "bloompb.BloomFilter")
+}
+
+func init() { proto.RegisterFile("bloom.proto", fileDescriptor_244a8f28f63f0104) }
+
+var fileDescriptor_244a8f28f63f0104 = []byte{
+	// 125 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x4e, 0xca, 0xc9, 0xcf,
+	0xcf, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x07, 0x73, 0x0a, 0x92, 0x94, 0x8a, 0xb9,
+	0xb8, 0x9d, 0x40, 0x4c, 0xb7, 0xcc, 0x9c, 0x92, 0xd4, 0x22, 0x21, 0x09, 0x2e, 0xf6, 0xb2, 0xd4,
+	0xa2, 0xe2, 0xcc, 0xfc, 0x3c, 0x09, 0x46, 0x05, 0x46, 0x0d, 0xde, 0x20, 0x18, 0x17, 0x24, 0x93,
+	0x57, 0x9a, 0xeb, 0x94, 0x59, 0x52, 0x2c, 0xc1, 0xa4, 0xc0, 0xa8, 0xc1, 0x12, 0x04, 0xe3, 0x42,
+	0x65, 0x3c, 0x12, 0x8b, 0x33, 0x24, 0x98, 0x21, 0x7a, 0xa0, 0x5c, 0x21, 0x31, 0x2e, 0xb6, 0xa4,
+	0xcc, 0x92, 0xdc, 0xc4, 0x02, 0x09, 0x16, 0x05, 0x46, 0x0d, 0x9e, 0x20, 0x28, 0x2f, 0x89, 0x0d,
+	0xec, 0x08, 0x63, 0xc0, 0x00, 0x50, 0xdd, 0x44, 0x61, 0x93, 0x00, 0x00, 0x00,
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2019 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package bloom
+
+import (
+	"sync"
+)
+
+// logsBloomNumHash is the number of hash functions of the logs bloom of block headers
+const logsBloomNumHash = 3
+
+// logsBloomPool recycles the filters of the parameters of the logs bloom of block headers
+var logsBloomPool, _ = NewBloomFilterPool(compatibleNumBits, logsBloomNumHash)
+
+// BloomFilterPool recycles the bloom filters of the same parameters, such that the hot paths creating many
+// short-lived filters, e.g., minting and validating blocks, could save the allocations
+type BloomFilterPool struct {
+	numBits uint
+	numHash uint
+	pool    sync.Pool
+}
+
+// NewBloomFilterPool returns a pool of bloom filters of m bits and h hash functions
+func NewBloomFilterPool(m, h uint) (*BloomFilterPool, error) {
+	// create a filter to validate the parameters
+	f, err := NewBloomFilter(m, h)
+	if err != nil {
+		return nil, err
+	}
+	p := &BloomFilterPool{
+		numBits: m,
+		numHash: h,
+	}
+	p.pool.New = func() interface{} {
+		f, _ := NewBloomFilter(m, h)
+		return f
+	}
+	p.pool.Put(f)
+	return p, nil
+}
+
+// Get returns a cleared bloom filter from the pool
+func (p *BloomFilterPool) Get() *BloomFilter {
+	return p.pool.Get().(*BloomFilter)
+}
+
+// Put clears the bloom filter and returns it to the pool, such that no key leaks into the next user. A filter of
+// other parameters is dropped. The caller must not use the filter, nor any block holding it, afterwards.
+func (p *BloomFilterPool) Put(f *BloomFilter) {
+	if f == nil || f.numBits != p.numBits || f.numHash != p.numHash {
+		return
+	}
+	f.Reset()
+	p.pool.Put(f)
+}
+
+// GetBloomFilter returns a cleared bloom filter of the parameters of the logs bloom of block headers from a shared
+// pool, which is to be returned by PutBloomFilter once done with it
+func GetBloomFilter() *BloomFilter {
+	return logsBloomPool.Get()
+}
+
+// PutBloomFilter clears the bloom filter and returns it to the shared pool of GetBloomFilter. The caller must not use
+// the filter, nor any block holding it, afterwards.
+func PutBloomFilter(f *BloomFilter) {
+	logsBloomPool.Put(f)
+}
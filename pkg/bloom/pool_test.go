@@ -0,0 +1,125 @@
+// Copyright (c) 2019 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package bloom
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/iotexproject/go-pkgs/bloom"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBloomFilterPool(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewBloomFilterPool(2047, 3)
+	require.Error(err)
+
+	for _, params := range []struct {
+		m, h uint
+	}{
+		{2048, 3},
+		{4096, 5},
+	} {
+		pool, err := NewBloomFilterPool(params.m, params.h)
+		require.NoError(err)
+		f := pool.Get()
+		require.Equal(params.m, f.NumBits())
+		require.Equal(params.h, f.NumHash())
+		require.Equal(make([]byte, params.m/8), f.Bytes())
+		for i := 0; i < 100; i++ {
+			f.Add([]byte(strconv.Itoa(i)))
+		}
+		pool.Put(f)
+		// no key leaks into the next user of the filter
+		require.Equal(make([]byte, params.m/8), f.Bytes())
+		f = pool.Get()
+		require.Equal(make([]byte, params.m/8), f.Bytes())
+		require.False(f.Exist([]byte("0")))
+		pool.Put(f)
+
+		// a filter of other parameters is not pooled
+		other, err := NewBloomFilter(params.m*2, params.h)
+		require.NoError(err)
+		other.Add([]byte("0"))
+		pool.Put(other)
+		require.True(other.Exist([]byte("0")))
+		pool.Put(nil)
+	}
+}
+
+func TestGetBloomFilter(t *testing.T) {
+	require := require.New(t)
+
+	f := GetBloomFilter()
+	require.Equal(uint(2048), f.NumBits())
+	require.Equal(uint(3), f.NumHash())
+	require.Zero(f.PopCount())
+	f.Add([]byte("key"))
+	// the filter is identical to the logs bloom of block headers
+	logsBloom, err := bloom.NewBloomFilter(2048, 3)
+	require.NoError(err)
+	logsBloom.Add([]byte("key"))
+	require.Equal(logsBloom.Bytes(), f.Bytes())
+	PutBloomFilter(f)
+	require.Zero(f.PopCount())
+	require.Zero(GetBloomFilter().PopCount())
+
+	// a filter is reset in place keeping its parameters
+	other, err := NewBloomFilter(4096, 5)
+	require.NoError(err)
+	other.Add([]byte("key"))
+	require.True(other.Exist([]byte("key")))
+	other.Reset()
+	require.False(other.Exist([]byte("key")))
+	require.Equal(uint(4096), other.NumBits())
+	require.Equal(uint(5), other.NumHash())
+}
+
+func BenchmarkBloomFilterPool(b *testing.B) {
+	// a few contract events per block, such that the allocations of the filters themselves stand out
+	topics := make([][]byte, 8)
+	for i := range topics {
+		topic := hash.Hash256b([]byte(strconv.Itoa(i)))
+		topics[i] = topic[:]
+	}
+	// mintAndValidate computes the logs bloom of a block when minting it, and again when validating it
+	mintAndValidate := func(b *testing.B, newFilter func() *BloomFilter, release func(*BloomFilter)) {
+		minted := newFilter()
+		validated := newFilter()
+		for _, topic := range topics {
+			minted.Add(topic)
+			validated.Add(topic)
+		}
+		if !bytes.Equal(minted.Bytes(), validated.Bytes()) {
+			b.Fatal("logs bloom mismatch")
+		}
+		release(minted)
+		release(validated)
+	}
+	b.Run("without-pool", func(b *testing.B) {
+		b.ReportAllocs()
+		newFilter := func() *BloomFilter {
+			f, _ := NewBloomFilter(2048, 3)
+			return f
+		}
+		for i := 0; i < b.N; i++ {
+			mintAndValidate(b, newFilter, func(*BloomFilter) {})
+		}
+	})
+	b.Run("with-pool", func(b *testing.B) {
+		b.ReportAllocs()
+		pool, err := NewBloomFilterPool(2048, 3)
+		require.NoError(b, err)
+		for i := 0; i < b.N; i++ {
+			mintAndValidate(b, pool.Get, pool.Put)
+		}
+	})
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2019 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package bloom
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/iotexproject/go-pkgs/bloom"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/pkg/errors"
+)
+
+const (
+	// growthFactor is the ratio between the capacity of two consecutive sub-filters
+	growthFactor = 2
+	// tighteningRatio is the ratio between the false-positive rate of two consecutive sub-filters
+	tighteningRatio = 0.9
+	// scalableHeaderLen is the length of the header of a serialized scalable bloom filter
+	scalableHeaderLen = 20
+	// subFilterHeaderLen is the length of the header of each serialized sub-filter
+	subFilterHeaderLen = 24
+)
+
+var _ bloom.BloomFilter = (*ScalableBloomFilter)(nil)
+
+type (
+	// ScalableBloomFilter is a bloom filter which grows as more keys are added into it. It is a chain of sub-filters,
+	// each with a geometrically growing capacity and a geometrically tightening false-positive rate, such that the
+	// aggregate false-positive rate stays bounded by the target rate no matter how many keys are added.
+	ScalableBloomFilter struct {
+		initialCapacity uint
+		fpRate          float64
+		filters         []*subFilter
+	}
+
+	// subFilter is a fixed-capacity bloom filter of arbitrary size
+	subFilter struct {
+		capacity uint64
+		count    uint64
+		numHash  uint32
+		numBits  uint32
+		bits     []byte
+	}
+)
+
+// NewScalableBloomFilter returns a scalable bloom filter, whose first sub-filter holds initialCapacity keys and whose
+// aggregate false-positive rate is bounded by fpRate
+func NewScalableBloomFilter(initialCapacity uint, fpRate float64) (*ScalableBloomFilter, error) {
+	if initialCapacity == 0 {
+		return nil, errors.New("expecting initial capacity > 0")
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, errors.New("expecting 0 < false-positive rate < 1")
+	}
+	f := &ScalableBloomFilter{
+		initialCapacity: initialCapacity,
+		fpRate:          fpRate,
+	}
+	f.grow()
+	return f, nil
+}
+
+// ScalableBloomFilterFromBytes constructs a scalable bloom filter from the bytes produced by Bytes()
+func ScalableBloomFilterFromBytes(b []byte) (*ScalableBloomFilter, error) {
+	if len(b) < scalableHeaderLen {
+		return nil, errors.Errorf("wrong length %d, expecting at least %d", len(b), scalableHeaderLen)
+	}
+	f := &ScalableBloomFilter{
+		initialCapacity: uint(binary.BigEndian.Uint64(b[0:8])),
+		fpRate:          math.Float64frombits(binary.BigEndian.Uint64(b[8:16])),
+	}
+	if f.initialCapacity == 0 || f.fpRate <= 0 || f.fpRate >= 1 {
+		return nil, errors.New("invalid scalable bloom filter header")
+	}
+	num := binary.BigEndian.Uint32(b[16:20])
+	b = b[scalableHeaderLen:]
+	for i := uint32(0); i < num; i++ {
+		if len(b) < subFilterHeaderLen {
+			return nil, errors.Errorf("sub-filter %d header is truncated", i)
+		}
+		sub := &subFilter{
+			capacity: binary.BigEndian.Uint64(b[0:8]),
+			count:    binary.BigEndian.Uint64(b[8:16]),
+			numHash:  binary.BigEndian.Uint32(b[16:20]),
+			numBits:  binary.BigEndian.Uint32(b[20:24]),
+		}
+		if sub.numHash == 0 || sub.numBits == 0 {
+			return nil, errors.Errorf("sub-filter %d has invalid header", i)
+		}
+		size := int((sub.numBits + 7) / 8)
+		b = b[subFilterHeaderLen:]
+		if len(b) < size {
+			return nil, errors.Errorf("sub-filter %d is truncated", i)
+		}
+		sub.bits = make([]byte, size)
+		copy(sub.bits, b[:size])
+		b = b[size:]
+		f.filters = append(f.filters, sub)
+	}
+	if len(f.filters) == 0 {
+		return nil, errors.New("expecting at least 1 sub-filter")
+	}
+	if len(b) != 0 {
+		return nil, errors.Errorf("%d trailing bytes after the last sub-filter", len(b))
+	}
+	return f, nil
+}
+
+// Add adds a key into the bloom filter, appending a new sub-filter if the current one is full
+func (f *ScalableBloomFilter) Add(key []byte) {
+	if key == nil {
+		return
+	}
+	last := f.filters[len(f.filters)-1]
+	if last.count >= last.capacity {
+		f.grow()
+		last = f.filters[len(f.filters)-1]
+	}
+	last.add(key)
+}
+
+// Exist checks if a key is in any of the sub-filters
+func (f *ScalableBloomFilter) Exist(key []byte) bool {
+	if key == nil {
+		return false
+	}
+	for _, sub := range f.filters {
+		if sub.exist(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bytes serializes the chain of sub-filters. The output starts with a header of the initial capacity, the target
+// false-positive rate and the number of sub-filters, followed by each sub-filter's header (capacity, number of keys
+// added, number of hash functions, number of bits) and its bit array.
+func (f *ScalableBloomFilter) Bytes() []byte {
+	size := scalableHeaderLen
+	for _, sub := range f.filters {
+		size += subFilterHeaderLen + len(sub.bits)
+	}
+	b := make([]byte, size)
+	binary.BigEndian.PutUint64(b[0:8], uint64(f.initialCapacity))
+	binary.BigEndian.PutUint64(b[8:16], math.Float64bits(f.fpRate))
+	binary.BigEndian.PutUint32(b[16:20], uint32(len(f.filters)))
+	pos := scalableHeaderLen
+	for _, sub := range f.filters {
+		binary.BigEndian.PutUint64(b[pos:pos+8], sub.capacity)
+		binary.BigEndian.PutUint64(b[pos+8:pos+16], sub.count)
+		binary.BigEndian.PutUint32(b[pos+16:pos+20], sub.numHash)
+		binary.BigEndian.PutUint32(b[pos+20:pos+24], sub.numBits)
+		pos += subFilterHeaderLen
+		pos += copy(b[pos:], sub.bits)
+	}
+	return b
+}
+
+// NumFilters returns the number of sub-filters in the chain
+func (f *ScalableBloomFilter) NumFilters() int { return len(f.filters) }
+
+// grow appends a new sub-filter to the chain. The i-th sub-filter has capacity c0 * s^i and false-positive rate
+// p0 * r^i, where p0 = P * (1 - r), so that the aggregate rate sum(p0 * r^i) converges to P.
+func (f *ScalableBloomFilter) grow() {
+	i := float64(len(f.filters))
+	capacity := float64(f.initialCapacity) * math.Pow(growthFactor, i)
+	p := f.fpRate * (1 - tighteningRatio) * math.Pow(tighteningRatio, i)
+	f.filters = append(f.filters, newSubFilter(uint64(capacity), p))
+}
+
+// newSubFilter returns a sub-filter with the optimal number of bits and hash functions for the given capacity and
+// false-positive rate
+func newSubFilter(capacity uint64, p float64) *subFilter {
+	m := math.Ceil(-float64(capacity) * math.Log(p) / (math.Ln2 * math.Ln2))
+	k := math.Ceil(-math.Log2(p))
+	numBits := uint32(m)
+	if numBits < 8 {
+		numBits = 8
+	}
+	return &subFilter{
+		capacity: capacity,
+		numHash:  uint32(k),
+		numBits:  numBits,
+		bits:     make([]byte, (numBits+7)/8),
+	}
+}
+
+func (s *subFilter) add(key []byte) {
+	h1, h2 := hashKey(key)
+	for i := uint32(0); i < s.numHash; i++ {
+		pos := (h1 + uint64(i)*h2) % uint64(s.numBits)
+		s.bits[pos>>3] |= 1 << (pos & 7)
+	}
+	s.count++
+}
+
+func (s *subFilter) exist(key []byte) bool {
+	h1, h2 := hashKey(key)
+	for i := uint32(0); i < s.numHash; i++ {
+		pos := (h1 + uint64(i)*h2) % uint64(s.numBits)
+		if s.bits[pos>>3]&(1<<(pos&7)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashKey derives the two base hashes used for double hashing, i.e., the i-th hash is h1 + i*h2
+func hashKey(key []byte) (uint64, uint64) {
+	return doubleHash(hash.Hash256b(key))
+}
+
+// doubleHash derives the two base hashes of double hashing from the hash of a key
+func doubleHash(h hash.Hash256) (uint64, uint64) {
+	h2 := binary.BigEndian.Uint64(h[8:16])
+	// make sure h2 is odd so that it does not degenerate to a single position
+	return binary.BigEndian.Uint64(h[0:8]), h2 | 1
+}
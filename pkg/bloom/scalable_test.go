@@ -0,0 +1,90 @@
+// Copyright (c) 2019 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package bloom
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewScalableBloomFilter(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewScalableBloomFilter(0, 0.01)
+	require.Error(err)
+	_, err = NewScalableBloomFilter(100, 0)
+	require.Error(err)
+	_, err = NewScalableBloomFilter(100, 1)
+	require.Error(err)
+
+	f, err := NewScalableBloomFilter(100, 0.01)
+	require.NoError(err)
+	require.Equal(1, f.NumFilters())
+	require.False(f.Exist(nil))
+}
+
+func TestScalableBloomFilter_FalsePositiveRate(t *testing.T) {
+	require := require.New(t)
+
+	const (
+		initialCapacity = 1000
+		fpRate          = 0.01
+		numKeys         = 20 * initialCapacity
+		numProbes       = 50000
+	)
+	f, err := NewScalableBloomFilter(initialCapacity, fpRate)
+	require.NoError(err)
+	for i := 0; i < numKeys; i++ {
+		f.Add([]byte("key" + strconv.Itoa(i)))
+	}
+	require.True(f.NumFilters() > 1)
+
+	// no false negative
+	for i := 0; i < numKeys; i++ {
+		require.True(f.Exist([]byte("key" + strconv.Itoa(i))))
+	}
+
+	// false-positive rate stays near target
+	fp := 0
+	for i := 0; i < numProbes; i++ {
+		if f.Exist([]byte("probe" + strconv.Itoa(i))) {
+			fp++
+		}
+	}
+	require.True(float64(fp)/numProbes <= 1.5*fpRate, "false-positive rate %f", float64(fp)/numProbes)
+}
+
+func TestScalableBloomFilter_Bytes(t *testing.T) {
+	require := require.New(t)
+
+	f, err := NewScalableBloomFilter(10, 0.01)
+	require.NoError(err)
+	for i := 0; i < 100; i++ {
+		f.Add([]byte(strconv.Itoa(i)))
+	}
+	b := f.Bytes()
+	f2, err := ScalableBloomFilterFromBytes(b)
+	require.NoError(err)
+	require.Equal(f.NumFilters(), f2.NumFilters())
+	require.Equal(b, f2.Bytes())
+	for i := 0; i < 100; i++ {
+		require.True(f2.Exist([]byte(strconv.Itoa(i))))
+	}
+
+	// keep growing after deserialization
+	f2.Add([]byte("new key"))
+	require.True(f2.Exist([]byte("new key")))
+
+	_, err = ScalableBloomFilterFromBytes(b[:len(b)-1])
+	require.Error(err)
+	_, err = ScalableBloomFilterFromBytes(append(b, 0))
+	require.Error(err)
+	_, err = ScalableBloomFilterFromBytes(b[:10])
+	require.Error(err)
+}
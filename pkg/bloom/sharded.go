@@ -0,0 +1,122 @@
+// Copyright (c) 2019 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package bloom
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// shardedBloomFilterVersion is the version of the serialized form of ShardedBloomFilter
+	shardedBloomFilterVersion = 1
+	// shardedHeaderLen is the length of the header of a serialized sharded bloom filter
+	shardedHeaderLen = 20
+)
+
+// ShardedBloomFilter is a set of bloom filters of the same parameters, one per shard, such as the filters of the
+// subchains of a node. The keys are added to and checked against the shard the caller-supplied shard key (e.g., the
+// chain ID) is routed to, such that the shards don't interfere with each other. It is not safe for concurrent use.
+type ShardedBloomFilter struct {
+	numBits uint
+	numHash uint
+	shards  []*BloomFilter
+}
+
+// NewShardedBloomFilter returns a sharded bloom filter of n shards, each of which is a bloom filter of m bits and h
+// hash functions
+func NewShardedBloomFilter(n uint32, m, h uint) (*ShardedBloomFilter, error) {
+	if n == 0 {
+		return nil, errors.New("expecting number of shards > 0")
+	}
+	f := &ShardedBloomFilter{
+		numBits: m,
+		numHash: h,
+		shards:  make([]*BloomFilter, n),
+	}
+	for i := range f.shards {
+		shard, err := NewBloomFilter(m, h)
+		if err != nil {
+			return nil, err
+		}
+		f.shards[i] = shard
+	}
+	return f, nil
+}
+
+// ShardedBloomFilterFromBytes constructs a sharded bloom filter from the bytes produced by Bytes()
+func ShardedBloomFilterFromBytes(b []byte) (*ShardedBloomFilter, error) {
+	if len(b) < shardedHeaderLen {
+		return nil, errors.Errorf("wrong length %d, expecting at least %d", len(b), shardedHeaderLen)
+	}
+	if version := binary.BigEndian.Uint32(b[0:4]); version != shardedBloomFilterVersion {
+		return nil, errors.Errorf("sharded bloom filter version %d not supported", version)
+	}
+	n := binary.BigEndian.Uint32(b[4:8])
+	if n == 0 {
+		return nil, errors.New("expecting number of shards > 0")
+	}
+	f := &ShardedBloomFilter{
+		numBits: uint(binary.BigEndian.Uint64(b[8:16])),
+		numHash: uint(binary.BigEndian.Uint32(b[16:20])),
+		shards:  make([]*BloomFilter, n),
+	}
+	size := uint64(f.numBits / 8)
+	b = b[shardedHeaderLen:]
+	if uint64(len(b)) != uint64(n)*size {
+		return nil, errors.Errorf("wrong length %d of %d shards, expecting %d", len(b), n, uint64(n)*size)
+	}
+	for i := range f.shards {
+		shard, err := bloomFilterFromBytes(b[:size], f.numBits, f.numHash)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to construct shard %d", i)
+		}
+		f.shards[i] = shard
+		b = b[size:]
+	}
+	return f, nil
+}
+
+// Add adds a key into the shard the shard key is routed to
+func (f *ShardedBloomFilter) Add(shardKey uint32, key []byte) {
+	f.Shard(shardKey).Add(key)
+}
+
+// Exist checks if a key is in the shard the shard key is routed to
+func (f *ShardedBloomFilter) Exist(shardKey uint32, key []byte) bool {
+	return f.Shard(shardKey).Exist(key)
+}
+
+// Shard returns the bloom filter of the shard the shard key is routed to
+func (f *ShardedBloomFilter) Shard(shardKey uint32) *BloomFilter {
+	return f.shards[shardKey%uint32(len(f.shards))]
+}
+
+// NumShards returns the number of shards
+func (f *ShardedBloomFilter) NumShards() uint32 { return uint32(len(f.shards)) }
+
+// NumBits returns the number of bits of each shard
+func (f *ShardedBloomFilter) NumBits() uint { return f.numBits }
+
+// NumHash returns the number of hash functions of each shard
+func (f *ShardedBloomFilter) NumHash() uint { return f.numHash }
+
+// Bytes serializes all the shards. The output starts with a header of the version, the number of shards, the number
+// of bits and the number of hash functions of each shard, followed by the bit arrays of the shards in order.
+func (f *ShardedBloomFilter) Bytes() []byte {
+	size := int(f.numBits / 8)
+	b := make([]byte, shardedHeaderLen, shardedHeaderLen+len(f.shards)*size)
+	binary.BigEndian.PutUint32(b[0:4], shardedBloomFilterVersion)
+	binary.BigEndian.PutUint32(b[4:8], uint32(len(f.shards)))
+	binary.BigEndian.PutUint64(b[8:16], uint64(f.numBits))
+	binary.BigEndian.PutUint32(b[16:20], uint32(f.numHash))
+	for _, shard := range f.shards {
+		b = append(b, shard.Bytes()...)
+	}
+	return b
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2019 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package bloom
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedBloomFilter(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewShardedBloomFilter(0, 2048, 3)
+	require.Error(err)
+	_, err = NewShardedBloomFilter(2, 2047, 3)
+	require.Error(err)
+
+	for _, m := range []uint{compatibleNumBits, 4096} {
+		f, err := NewShardedBloomFilter(3, m, 3)
+		require.NoError(err)
+		require.Equal(uint32(3), f.NumShards())
+		require.Equal(m, f.NumBits())
+		require.Equal(uint(3), f.NumHash())
+
+		// the keys added to a shard are only found in that shard
+		for shard := uint32(0); shard < 3; shard++ {
+			for i := 0; i < 10; i++ {
+				f.Add(shard, []byte("chain"+strconv.Itoa(int(shard))+"-key"+strconv.Itoa(i)))
+			}
+		}
+		for shard := uint32(0); shard < 3; shard++ {
+			for i := 0; i < 10; i++ {
+				key := []byte("chain" + strconv.Itoa(int(shard)) + "-key" + strconv.Itoa(i))
+				for other := uint32(0); other < 3; other++ {
+					require.Equal(shard == other, f.Exist(other, key))
+				}
+			}
+		}
+		// the shard keys beyond the number of shards wrap around
+		require.True(f.Exist(4, []byte("chain1-key0")))
+		require.Equal(f.Shard(1), f.Shard(4))
+
+		b := f.Bytes()
+		require.Equal(shardedHeaderLen+3*int(m/8), len(b))
+		f2, err := ShardedBloomFilterFromBytes(b)
+		require.NoError(err)
+		require.Equal(b, f2.Bytes())
+		for shard := uint32(0); shard < 3; shard++ {
+			require.Equal(f.Shard(shard).Bytes(), f2.Shard(shard).Bytes())
+		}
+
+		_, err = ShardedBloomFilterFromBytes(b[:len(b)-1])
+		require.Error(err)
+		_, err = ShardedBloomFilterFromBytes(b[:shardedHeaderLen-1])
+		require.Error(err)
+		b[3] = 2
+		_, err = ShardedBloomFilterFromBytes(b)
+		require.Error(err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
 	"sync/atomic"
 
 	"go.uber.org/zap"
@@ -29,6 +30,7 @@ const (
 type Server struct {
 	ready            int32 // 0 is not ready, 1 is ready
 	server           http.Server
+	mutex            sync.RWMutex
 	readinessHandler http.Handler
 }
 
@@ -50,15 +52,20 @@ func New(port int, opts ...Option) *Server {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/liveness", successHandleFunc)
+	mux.HandleFunc("/healthz", successHandleFunc)
 	readiness := func(w http.ResponseWriter, r *http.Request) {
 		if atomic.LoadInt32(&s.ready) == _notReady {
 			failureHandleFunc(w, r)
 			return
 		}
-		s.readinessHandler.ServeHTTP(w, r)
+		s.mutex.RLock()
+		h := s.readinessHandler
+		s.mutex.RUnlock()
+		h.ServeHTTP(w, r)
 	}
 
 	mux.HandleFunc("/readiness", readiness)
+	mux.HandleFunc("/readyz", readiness)
 	mux.HandleFunc("/health", readiness)
 	mux.Handle("/metrics", promhttp.Handler())
 
@@ -81,6 +88,17 @@ func (s *Server) Start(_ context.Context) error {
 	return nil
 }
 
+// SetReadinessHandler sets the handler telling the readiness once the probe server is ready, which is for the
+// services created after the probe server is started
+func (s *Server) SetReadinessHandler(h http.Handler) {
+	if h == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.readinessHandler = h
+}
+
 // Ready makes the probe server starts returning status on readiness and
 // health endpoint.
 func (s *Server) Ready() { atomic.SwapInt32(&s.ready, _ready) }
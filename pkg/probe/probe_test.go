@@ -52,6 +52,14 @@ func TestBasicProbe(t *testing.T) {
 			endpoint: "/health",
 			code:     http.StatusServiceUnavailable,
 		},
+		{
+			endpoint: "/healthz",
+			code:     http.StatusOK,
+		},
+		{
+			endpoint: "/readyz",
+			code:     http.StatusServiceUnavailable,
+		},
 	}
 	testFunc(t, test1)
 
@@ -68,6 +76,14 @@ func TestBasicProbe(t *testing.T) {
 			endpoint: "/health",
 			code:     http.StatusOK,
 		},
+		{
+			endpoint: "/healthz",
+			code:     http.StatusOK,
+		},
+		{
+			endpoint: "/readyz",
+			code:     http.StatusOK,
+		},
 	}
 	s.Ready()
 	testFunc(t, test2)
@@ -107,4 +123,19 @@ func TestReadniessHandler(t *testing.T) {
 	}
 	s.Ready()
 	testFunc(t, test)
+
+	// the handler is replaced by the one of a service created later
+	s.SetReadinessHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	testFunc(t, []testCase{
+		{
+			endpoint: "/readyz",
+			code:     http.StatusServiceUnavailable,
+		},
+		{
+			endpoint: "/healthz",
+			code:     http.StatusOK,
+		},
+	})
 }
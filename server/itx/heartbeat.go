@@ -8,21 +8,27 @@ package itx
 
 import (
 	"context"
-	"encoding/json"
 	"strconv"
+	"time"
 
-	"github.com/iotexproject/go-fsm"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	"github.com/iotexproject/iotex-core/consensus"
-	"github.com/iotexproject/iotex-core/consensus/scheme"
 	"github.com/iotexproject/iotex-core/consensus/scheme/rolldpos"
+	"github.com/iotexproject/iotex-core/db"
 	"github.com/iotexproject/iotex-core/dispatcher"
 	"github.com/iotexproject/iotex-core/pkg/log"
 	"github.com/iotexproject/iotex-core/pkg/version"
 )
 
+// countingIndexStatsSample is the sampling of the values of the counting indexes to compute their sizes, which bounds
+// the cost of the heartbeat on huge indexes
+const countingIndexStatsSample = 1000
+
+// forkEventsWindow is the period over which the fork events of the consensus are counted
+const forkEventsWindow = time.Hour
+
 // TODO: HeartbeatHandler opens encapsulation of a few structs to inspect the internal status, we need to find a better
 // approach to do so in the future
 
@@ -47,33 +53,246 @@ func init() {
 	prometheus.MustRegister(versionMtc)
 }
 
-// HeartbeatHandler is the handler to periodically log the system key metrics
-type HeartbeatHandler struct {
-	s *Server
+type (
+	// HeartbeatHandler is the handler to periodically log the system key metrics
+	HeartbeatHandler struct {
+		s     *Server
+		sink  func(Status)
+		noLog bool
+		// backpressureRatio is the actpool utilization above which the actpool is flagged as under backpressure
+		backpressureRatio float64
+		// minPeerVersion is the min software version of the neighbors, which is checked if set, and outdatedPeerRatio
+		// is the fraction of the neighbors below it to warn above
+		minPeerVersion    string
+		minPeerVersionNum [3]uint64
+		outdatedPeerRatio float64
+		// lastAudit is the dispatcher audit taken by the previous heartbeat, since which the delta is reported
+		lastAudit dispatcher.AuditSnapshot
+	}
+
+	// HeartbeatOption sets an option of the heartbeat handler
+	HeartbeatOption func(*HeartbeatHandler)
+
+	// Status is the node status collected by the heartbeat handler
+	Status struct {
+		NumPeers                int
+		PendingDispatcherEvents int
+		// PendingDispatcherLanes is the number of the events queued in each priority lane of the dispatcher
+		PendingDispatcherLanes map[string]int
+		// DispatcherEvents is the number of the events of each message type handled by the dispatcher since the
+		// previous heartbeat, and DispatcherErrors are the errors of handling them
+		DispatcherEvents map[string]int
+		DispatcherErrors []dispatcher.AuditError
+		// PeerVersions is the number of the neighbors running each software version
+		PeerVersions map[string]int
+		Chains       []ChainStatus
+		// ShutdownPhase is the phase of stopping the server, which is empty unless stopping, in which case nothing
+		// else is collected as the components are going away
+		ShutdownPhase string
+	}
+
+	// ChainStatus is the status of a chain service
+	ChainStatus struct {
+		ChainID          uint32
+		RolldposEvents   int
+		FSMState         string
+		BlockchainHeight uint64
+		ActPoolSize      uint64
+		ActPoolCapacity  uint64
+		// ActPoolUtilization is the size of the actpool over its capacity, which is 0 if the capacity is 0
+		ActPoolUtilization float64
+		// ActPoolBackpressure is true if the utilization of the actpool is above the backpressure ratio
+		ActPoolBackpressure bool
+		TargetHeight        uint64
+		ConsensusEpoch      uint64
+		ConsensusHeight     uint64
+		ConsensusHealth     string
+		// ConsensusHealthy is false if the node is supposed to participate in the consensus but fails to
+		ConsensusHealthy bool
+		// ParticipationRates is the endorsement participation rate of each delegate
+		ParticipationRates map[string]float64
+		// ForkEvents is the number of the blocks endorsed by the consensus in the last hour but losing to a
+		// competing block of the same height committed by the block sync
+		ForkEvents int
+		// CountingIndexEntries and CountingIndexBytes are the sums of the numbers of the values and their sizes over
+		// the counting indexes of the chain DB, where the sizes are estimated from sampled values
+		CountingIndexEntries uint64
+		CountingIndexBytes   uint64
+	}
+)
+
+// WithStatusSink sets a sink which receives the node status each time the heartbeat handler runs. The sink is called
+// without holding any server lock, and a panic inside it is recovered.
+func WithStatusSink(sink func(Status)) HeartbeatOption {
+	return func(h *HeartbeatHandler) {
+		h.sink = sink
+	}
+}
+
+// WithoutStatusLog disables logging the node status, which is useful when a status sink is set
+func WithoutStatusLog() HeartbeatOption {
+	return func(h *HeartbeatHandler) {
+		h.noLog = true
+	}
 }
 
 // NewHeartbeatHandler instantiates a HeartbeatHandler instance
-func NewHeartbeatHandler(s *Server) *HeartbeatHandler {
-	return &HeartbeatHandler{s: s}
+func NewHeartbeatHandler(s *Server, opts ...HeartbeatOption) *HeartbeatHandler {
+	h := &HeartbeatHandler{
+		s:                 s,
+		backpressureRatio: s.cfg.System.ActPoolBackpressureRatio,
+		outdatedPeerRatio: s.cfg.System.OutdatedPeerRatio,
+	}
+	if min := s.cfg.System.MinPeerVersion; min != "" {
+		if parsed, ok := parseVersion(min); ok {
+			h.minPeerVersion, h.minPeerVersionNum = min, parsed
+		} else {
+			log.L().Warn("Invalid min peer version, which isn't checked.", zap.String("minPeerVersion", min))
+		}
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // Log executes the logging logic
 func (h *HeartbeatHandler) Log() {
-	// Network metrics
-	p2pAgent := h.s.P2PAgent()
+	status := h.collect()
+	if status.ShutdownPhase != "" {
+		if !h.noLog {
+			log.L().Info("Node is stopping.", zap.String("shutdownPhase", status.ShutdownPhase))
+		}
+		if h.sink != nil {
+			h.emit(status)
+		}
+		return
+	}
+	if !h.noLog {
+		log.L().Info("Node status.",
+			zap.Int("numPeers", status.NumPeers),
+			zap.Int("pendingDispatcherEvents", status.PendingDispatcherEvents),
+			zap.Any("pendingDispatcherLanes", status.PendingDispatcherLanes),
+			zap.Any("dispatcherEvents", status.DispatcherEvents),
+			zap.Any("dispatcherErrors", status.DispatcherErrors),
+			zap.Any("peerVersions", status.PeerVersions))
+		for _, c := range status.Chains {
+			log.L().Info("chain service status",
+				zap.Int("rolldposEvents", c.RolldposEvents),
+				zap.String("fsmState", c.FSMState),
+				zap.Uint64("blockchainHeight", c.BlockchainHeight),
+				zap.Uint64("actpoolSize", c.ActPoolSize),
+				zap.Uint64("actpoolCapacity", c.ActPoolCapacity),
+				zap.Float64("actpoolUtilization", c.ActPoolUtilization),
+				zap.Bool("actpoolBackpressure", c.ActPoolBackpressure),
+				zap.Uint32("chainID", c.ChainID),
+				zap.Uint64("targetHeight", c.TargetHeight),
+				zap.Uint64("concensusEpoch", c.ConsensusEpoch),
+				zap.Uint64("consensusHeight", c.ConsensusHeight),
+				zap.String("consensusHealth", c.ConsensusHealth),
+				zap.Any("participationRates", c.ParticipationRates),
+				zap.Int("forkEvents", c.ForkEvents),
+				zap.Uint64("countingIndexEntries", c.CountingIndexEntries),
+				zap.Uint64("countingIndexBytes", c.CountingIndexBytes),
+			)
+		}
+	}
 
-	// Dispatcher metrics
-	dp, ok := h.s.Dispatcher().(*dispatcher.IotxDispatcher)
-	if !ok {
-		log.L().Error("dispatcher is not the instance of IotxDispatcher")
+	heartbeatMtc.WithLabelValues("numPeers", "node").Set(float64(status.NumPeers))
+	heartbeatMtc.WithLabelValues("pendingDispatcherEvents", "node").Set(float64(status.PendingDispatcherEvents))
+	for lane, depth := range status.PendingDispatcherLanes {
+		heartbeatMtc.WithLabelValues(lane+"PriorityDispatcherEvents", "node").Set(float64(depth))
+	}
+	// the versions no neighbor runs any more are dropped
+	peerVersionMtc.Reset()
+	for v, count := range status.PeerVersions {
+		peerVersionMtc.WithLabelValues(v).Set(float64(count))
+	}
+	h.checkPeerVersions(status.PeerVersions)
+	for _, c := range status.Chains {
+		chainIDStr := strconv.FormatUint(uint64(c.ChainID), 10)
+		heartbeatMtc.WithLabelValues("consensusEpoch", chainIDStr).Set(float64(c.ConsensusHeight))
+		heartbeatMtc.WithLabelValues("consensusRound", chainIDStr).Set(float64(c.ConsensusEpoch))
+		heartbeatMtc.WithLabelValues("pendingRolldposEvents", chainIDStr).Set(float64(c.RolldposEvents))
+		heartbeatMtc.WithLabelValues("blockchainHeight", chainIDStr).Set(float64(c.BlockchainHeight))
+		heartbeatMtc.WithLabelValues("actpoolSize", chainIDStr).Set(float64(c.ActPoolSize))
+		heartbeatMtc.WithLabelValues("actpoolCapacity", chainIDStr).Set(float64(c.ActPoolCapacity))
+		heartbeatMtc.WithLabelValues("actpoolUtilization", chainIDStr).Set(c.ActPoolUtilization)
+		backpressure := 0.0
+		if c.ActPoolBackpressure {
+			backpressure = 1
+		}
+		heartbeatMtc.WithLabelValues("actpoolBackpressure", chainIDStr).Set(backpressure)
+		heartbeatMtc.WithLabelValues("targetHeight", chainIDStr).Set(float64(c.TargetHeight))
+		heartbeatMtc.WithLabelValues("forkEvents", chainIDStr).Set(float64(c.ForkEvents))
+		heartbeatMtc.WithLabelValues("countingIndexEntries", chainIDStr).Set(float64(c.CountingIndexEntries))
+		heartbeatMtc.WithLabelValues("countingIndexBytes", chainIDStr).Set(float64(c.CountingIndexBytes))
+		if c.ConsensusHealth != "" {
+			healthy := 0.0
+			if c.ConsensusHealthy {
+				healthy = 1
+			}
+			heartbeatMtc.WithLabelValues("consensusHealthy", chainIDStr).Set(healthy)
+		}
+		heartbeatMtc.WithLabelValues("packageVersion", version.PackageVersion).Set(1)
+		heartbeatMtc.WithLabelValues("packageCommitID", version.PackageCommitID).Set(1)
+		heartbeatMtc.WithLabelValues("goVersion", version.GoVersion).Set(1)
+	}
+
+	if h.sink != nil {
+		h.emit(status)
+	}
+}
+
+// checkPeerVersions warns if more than the outdated peer ratio of the neighbors run a version below the min version
+func (h *HeartbeatHandler) checkPeerVersions(versions map[string]int) {
+	if h.minPeerVersion == "" {
 		return
 	}
-	numDPEvts := len(*dp.EventChan())
-	dpEvtsAudit, err := json.Marshal(dp.EventAudit())
-	if err != nil {
-		log.L().Error("error when serializing the dispatcher event audit map.", zap.Error(err))
+	outdated, total, warn := tooManyOutdatedPeers(versions, h.minPeerVersionNum, h.outdatedPeerRatio)
+	if !warn {
 		return
 	}
+	log.L().Warn("Too many neighbors run a version below the min version.",
+		zap.String("minPeerVersion", h.minPeerVersion),
+		zap.Int("outdatedPeers", outdated),
+		zap.Int("numPeers", total),
+		zap.Any("peerVersions", versions))
+}
+
+// emit sends the status to the sink, isolating the heartbeat loop from a panic inside it
+func (h *HeartbeatHandler) emit(status Status) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.L().Error("Heartbeat status sink panicked.", zap.Any("panic", r))
+		}
+	}()
+	h.sink(status)
+}
+
+// collect collects the node status
+func (h *HeartbeatHandler) collect() Status {
+	if phase := h.s.ShutdownPhase(); phase != "" {
+		return Status{ShutdownPhase: phase}
+	}
+	// Network metrics
+	p2pAgent := h.s.P2PAgent()
+
+	// Dispatcher metrics, the per message type event counters are exported by the dispatcher itself
+	numDPEvts := 0
+	var dpLanes map[string]int
+	var dpAudit dispatcher.AuditSnapshot
+	if dp, ok := h.s.Dispatcher().(*dispatcher.IotxDispatcher); ok {
+		dpLanes = dp.EventLaneDepths()
+		for _, depth := range dpLanes {
+			numDPEvts += depth
+		}
+		audit := dp.AuditSnapshot()
+		dpAudit = audit.Since(h.lastAudit)
+		h.lastAudit = audit
+	} else {
+		log.L().Error("dispatcher is not the instance of IotxDispatcher")
+	}
 
 	ctx := context.Background()
 	peers, err := p2pAgent.Neighbors(ctx)
@@ -81,75 +300,97 @@ func (h *HeartbeatHandler) Log() {
 		log.L().Debug("error when get neighbors.", zap.Error(err))
 		peers = nil
 	}
-	numPeers := len(peers)
-	log.L().Info("Node status.",
-		zap.Int("numPeers", numPeers),
-		zap.Int("pendingDispatcherEvents", numDPEvts),
-		zap.String("pendingDispatcherEventsAudit", string(dpEvtsAudit)))
+	// the neighbors whose versions are unknown are asked for them, which are collected by the next heartbeat
+	if err := p2pAgent.AdvertiseVersion(ctx); err != nil {
+		log.L().Debug("error when advertising version.", zap.Error(err))
+	}
+	peerVersions, err := p2pAgent.PeerVersions(ctx)
+	if err != nil {
+		log.L().Debug("error when get peer versions.", zap.Error(err))
+	}
+	status := Status{
+		NumPeers:                len(peers),
+		PendingDispatcherEvents: numDPEvts,
+		PendingDispatcherLanes:  dpLanes,
+		DispatcherEvents:        auditEvents(dpAudit),
+		DispatcherErrors:        dpAudit.Errors,
+		PeerVersions:            peerVersions,
+	}
 
-	heartbeatMtc.WithLabelValues("numPeers", "node").Set(float64(numPeers))
-	heartbeatMtc.WithLabelValues("pendingDispatcherEvents", "node").Set(float64(numDPEvts))
 	// chain service
-	for _, c := range h.s.chainservices {
+	for _, c := range h.s.chainServices() {
 		// Consensus metrics
 		cs, ok := c.Consensus().(*consensus.IotxConsensus)
 		if !ok {
 			log.L().Info("consensus is not the instance of IotxConsensus.")
-			return
+			break
 		}
 		rolldpos, ok := cs.Scheme().(*rolldpos.RollDPoS)
-		numPendingEvts := 0
-		consensusEpoch := uint64(0)
-		consensusHeight := uint64(0)
-
-		var consensusMetrics scheme.ConsensusMetrics
-		var state fsm.State
+		chainStatus := ChainStatus{ChainID: c.ChainID()}
 		if ok {
-			numPendingEvts = rolldpos.NumPendingEvts()
-			state = rolldpos.CurrentState()
+			chainStatus.RolldposEvents = rolldpos.NumPendingEvts()
+			chainStatus.FSMState = string(rolldpos.CurrentState())
+			health := rolldpos.Health()
+			chainStatus.ConsensusHealth = health.String()
+			chainStatus.ConsensusHealthy = health.Healthy()
+			chainStatus.ParticipationRates = rolldpos.ParticipationRates()
+			chainStatus.ForkEvents = rolldpos.NumForkEventsSince(time.Now().Add(-forkEventsWindow))
 
 			// RollDpos Concensus Metrics
-			consensusMetrics, err = rolldpos.Metrics()
+			consensusMetrics, err := rolldpos.Metrics()
 			if err != nil {
 				log.L().Error("failed to read consensus metrics", zap.Error(err))
-				return
+				break
 			}
-			consensusEpoch = consensusMetrics.LatestEpoch
-			consensusHeight = consensusMetrics.LatestHeight
+			chainStatus.ConsensusEpoch = consensusMetrics.LatestEpoch
+			chainStatus.ConsensusHeight = consensusMetrics.LatestHeight
 		} else {
 			log.L().Debug("scheme is not the instance of RollDPoS")
 		}
 
 		// Block metrics
-		height := c.Blockchain().TipHeight()
-
-		actPoolSize := c.ActionPool().GetSize()
-		actPoolCapacity := c.ActionPool().GetCapacity()
-		targetHeight := c.BlockSync().TargetHeight()
-
-		log.L().Info("chain service status",
-			zap.Int("rolldposEvents", numPendingEvts),
-			zap.String("fsmState", string(state)),
-			zap.Uint64("blockchainHeight", height),
-			zap.Uint64("actpoolSize", actPoolSize),
-			zap.Uint64("actpoolCapacity", actPoolCapacity),
-			zap.Uint32("chainID", c.ChainID()),
-			zap.Uint64("targetHeight", targetHeight),
-			zap.Uint64("concensusEpoch", consensusEpoch),
-			zap.Uint64("consensusHeight", consensusHeight),
+		chainStatus.BlockchainHeight = c.Blockchain().TipHeight()
+		chainStatus.ActPoolSize = c.ActionPool().GetSize()
+		chainStatus.ActPoolCapacity = c.ActionPool().GetCapacity()
+		chainStatus.ActPoolUtilization, chainStatus.ActPoolBackpressure = actPoolUtilization(
+			chainStatus.ActPoolSize,
+			chainStatus.ActPoolCapacity,
+			h.backpressureRatio,
 		)
+		chainStatus.TargetHeight = c.BlockSync().TargetHeight()
 
-		chainIDStr := strconv.FormatUint(uint64(c.ChainID()), 10)
-		heartbeatMtc.WithLabelValues("consensusEpoch", chainIDStr).Set(float64(consensusHeight))
-		heartbeatMtc.WithLabelValues("consensusRound", chainIDStr).Set(float64(consensusEpoch))
-		heartbeatMtc.WithLabelValues("pendingRolldposEvents", chainIDStr).Set(float64(numPendingEvts))
-		heartbeatMtc.WithLabelValues("blockchainHeight", chainIDStr).Set(float64(height))
-		heartbeatMtc.WithLabelValues("actpoolSize", chainIDStr).Set(float64(actPoolSize))
-		heartbeatMtc.WithLabelValues("actpoolCapacity", chainIDStr).Set(float64(actPoolCapacity))
-		heartbeatMtc.WithLabelValues("targetHeight", chainIDStr).Set(float64(targetHeight))
-		heartbeatMtc.WithLabelValues("packageVersion", version.PackageVersion).Set(1)
-		heartbeatMtc.WithLabelValues("packageCommitID", version.PackageCommitID).Set(1)
-		heartbeatMtc.WithLabelValues("goVersion", version.GoVersion).Set(1)
+		// Counting index metrics, which leave out the page stats of the buckets to keep the heartbeat cheap
+		if kvStore, ok := c.Blockchain().KVStore().(db.StatsKVStore); ok {
+			all, err := kvStore.AllCountingIndexStats(db.StatsSampleOption(countingIndexStatsSample))
+			if err != nil {
+				log.L().Error("failed to read counting index stats", zap.Error(err))
+			}
+			for _, stats := range all {
+				chainStatus.CountingIndexEntries += stats.Entries
+				chainStatus.CountingIndexBytes += stats.ValueBytes
+			}
+		}
+		status.Chains = append(status.Chains, chainStatus)
 	}
 
+	return status
+}
+
+// actPoolUtilization returns the size of the actpool over its capacity, and whether it is above the backpressure ratio.
+// An actpool of no capacity has a utilization of 0 rather than NaN, and a ratio of 0 disables the backpressure flag.
+func actPoolUtilization(size, capacity uint64, backpressureRatio float64) (float64, bool) {
+	if capacity == 0 {
+		return 0, false
+	}
+	utilization := float64(size) / float64(capacity)
+	return utilization, backpressureRatio > 0 && utilization > backpressureRatio
+}
+
+// auditEvents returns the event counters of the dispatcher audit keyed by the names of the message types
+func auditEvents(audit dispatcher.AuditSnapshot) map[string]int {
+	events := make(map[string]int, len(audit.Events))
+	for t, count := range audit.Events {
+		events[t.String()] = count
+	}
+	return events
 }
@@ -14,6 +14,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/dispatcher"
+	"github.com/iotexproject/iotex-core/p2p"
 	"github.com/iotexproject/iotex-core/pkg/probe"
 )
 
@@ -37,8 +40,103 @@ func TestNewHeartbeatHandler(t *testing.T) {
 	require.NoError(s.Start(ctx))
 	time.Sleep(time.Second * 2)
 	handler.Log()
+
+	// the counting indexes of the chain DB are accounted
+	index, err := db.NewCountingIndex(s.rootChainService.Blockchain().KVStore(), "heartbeat")
+	require.NoError(err)
+	require.NoError(index.Add([]byte("value")))
+
+	// the status is sent to the sink
+	var status Status
+	handler = NewHeartbeatHandler(s, WithStatusSink(func(st Status) {
+		status = st
+	}), WithoutStatusLog())
+	handler.Log()
+	require.Equal(1, len(status.Chains))
+	require.Equal(cfg.Chain.ID, status.Chains[0].ChainID)
+	require.Equal(s.rootChainService.Blockchain().TipHeight(), status.Chains[0].BlockchainHeight)
+	require.True(status.Chains[0].CountingIndexEntries >= 1)
+	require.True(status.Chains[0].CountingIndexBytes >= uint64(len("value")))
+	require.Equal(cfg.ActPool.MaxNumActsPerPool, status.Chains[0].ActPoolCapacity)
+	require.False(status.Chains[0].ActPoolBackpressure)
+	require.Equal(s.PeerVersions(), status.PeerVersions)
+	require.Equal(2, len(status.PendingDispatcherLanes))
+	require.Equal(
+		status.PendingDispatcherLanes[dispatcher.HighPriorityLane]+status.PendingDispatcherLanes[dispatcher.NormalPriorityLane],
+		status.PendingDispatcherEvents,
+	)
+
+	// a panic inside the sink is isolated
+	handler = NewHeartbeatHandler(s, WithStatusSink(func(Status) {
+		panic("sink failure")
+	}))
+	require.NotPanics(func() { handler.Log() })
 	cancel()
 	err = probeSvr.Stop(livenessCtx)
 	require.NoError(err)
 	livenessCancel()
 }
+
+func TestActPoolUtilization(t *testing.T) {
+	require := require.New(t)
+	ratio := config.Default.System.ActPoolBackpressureRatio
+
+	for _, c := range []struct {
+		size, capacity uint64
+		utilization    float64
+		backpressure   bool
+	}{
+		{0, 100, 0, false},
+		{50, 100, 0.5, false},
+		{99, 100, 0.99, true},
+		// no NaN for an actpool of no capacity
+		{0, 0, 0, false},
+	} {
+		utilization, backpressure := actPoolUtilization(c.size, c.capacity, ratio)
+		require.Equal(c.utilization, utilization)
+		require.Equal(c.backpressure, backpressure)
+	}
+
+	// the flag is disabled by a ratio of 0
+	_, backpressure := actPoolUtilization(99, 100, 0)
+	require.False(backpressure)
+}
+
+func TestPeerVersions(t *testing.T) {
+	require := require.New(t)
+
+	for v, expected := range map[string][3]uint64{
+		"v1.2.3":              {1, 2, 3},
+		"1.2.3":               {1, 2, 3},
+		"v0.10":               {0, 10, 0},
+		"v1.2.3-rc1-4-gabcde": {1, 2, 3},
+		"v2.0.0+build":        {2, 0, 0},
+	} {
+		parsed, ok := parseVersion(v)
+		require.True(ok, v)
+		require.Equal(expected, parsed, v)
+	}
+	for _, v := range []string{"", "v", p2p.UnknownPeerVersion, "NoBuildInfo", "v1.2.3.4", "v1.x.3", "v1..3"} {
+		_, ok := parseVersion(v)
+		require.False(ok, v)
+	}
+
+	min, ok := parseVersion("v1.1.0")
+	require.True(ok)
+	versions := map[string]int{
+		"v1.0.9":               2,
+		"v0.12.0":              1,
+		"v1.1.0-rc1":           1,
+		"v1.2.0":               3,
+		p2p.UnknownPeerVersion: 2,
+	}
+	// the neighbors not reporting their versions are not counted as outdated
+	outdated, total, warn := tooManyOutdatedPeers(versions, min, 0.3)
+	require.Equal(3, outdated)
+	require.Equal(9, total)
+	require.True(warn)
+	_, _, warn = tooManyOutdatedPeers(versions, min, 0.5)
+	require.False(warn)
+	_, _, warn = tooManyOutdatedPeers(nil, min, 0)
+	require.False(warn)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package itx
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/pkg/log"
+)
+
+var peerVersionMtc = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "iotex_peer_versions",
+		Help: "Number of the neighbors running each software version.",
+	},
+	[]string{"version"},
+)
+
+func init() {
+	prometheus.MustRegister(peerVersionMtc)
+}
+
+// PeerVersions returns the number of the neighbors running each software version, where those which haven't advertised
+// their versions are counted as p2p.UnknownPeerVersion
+func (s *Server) PeerVersions() map[string]int {
+	versions, err := s.p2pAgent.PeerVersions(context.Background())
+	if err != nil {
+		log.L().Debug("Failed to get the versions of the neighbors.", zap.Error(err))
+		return nil
+	}
+	return versions
+}
+
+// outdatedPeers returns the number of the neighbors running a version below the min version. The versions which are
+// not parsed, e.g., those of the neighbors not advertising their versions, are not counted.
+func outdatedPeers(versions map[string]int, min [3]uint64) int {
+	outdated := 0
+	for v, count := range versions {
+		parsed, ok := parseVersion(v)
+		if ok && compareVersions(parsed, min) < 0 {
+			outdated += count
+		}
+	}
+	return outdated
+}
+
+// tooManyOutdatedPeers returns the number of the neighbors running a version below the min version, the number of all
+// the neighbors, and whether the former is more than the ratio of the latter
+func tooManyOutdatedPeers(versions map[string]int, min [3]uint64, ratio float64) (int, int, bool) {
+	total := 0
+	for _, count := range versions {
+		total += count
+	}
+	outdated := outdatedPeers(versions, min)
+	return outdated, total, total > 0 && float64(outdated) > ratio*float64(total)
+}
+
+// parseVersion parses the major, minor and patch numbers of a release tag, e.g., v1.2.3, where the suffix of a
+// prerelease or a build after the patch number is ignored
+func parseVersion(v string) ([3]uint64, bool) {
+	var parsed [3]uint64
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) == 0 || len(parts) > len(parsed) {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
+
+func compareVersions(a, b [3]uint64) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
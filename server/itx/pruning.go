@@ -0,0 +1,52 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package itx
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/blockchain"
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/pkg/log"
+	"github.com/iotexproject/iotex-core/pkg/routine"
+)
+
+// newPruningTask returns a recurring task which prunes the buckets of the chain DB as of the tip height
+func newPruningTask(bc blockchain.Blockchain, cfg config.Pruning) (*routine.RecurringTask, error) {
+	if cfg.Interval <= 0 {
+		return nil, errors.Errorf("invalid pruning interval %s", cfg.Interval)
+	}
+	kvStore := bc.KVStore()
+	policies := make([]db.RetentionPolicy, 0, len(cfg.Buckets))
+	for _, bucket := range cfg.Buckets {
+		policy := db.RetentionPolicy{
+			Namespace:   bucket.Name,
+			KeepEntries: bucket.KeepEntries,
+			KeepHeights: bucket.KeepHeights,
+		}
+		if bucket.Counting {
+			index, err := db.NewCountingIndex(kvStore, bucket.Name)
+			if err != nil {
+				return nil, err
+			}
+			policy.Index = index
+		}
+		policies = append(policies, policy)
+	}
+	pruner, err := db.NewPruner(kvStore, policies, db.PruneBatchSizeOption(cfg.BatchSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create pruner")
+	}
+	return routine.NewRecurringTask(func() {
+		tipHeight := bc.TipHeight()
+		if err := pruner.Prune(tipHeight); err != nil {
+			log.L().Error("Failed to prune the chain DB.", zap.Uint64("height", tipHeight), zap.Error(err))
+		}
+	}, cfg.Interval), nil
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package itx
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/test/mock/mock_blockchain"
+	"github.com/iotexproject/iotex-core/testutil"
+)
+
+func TestPruningTask(t *testing.T) {
+	require := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	path, err := ioutil.TempFile("", "pruning")
+	require.NoError(err)
+	defer testutil.CleanupPath(t, path.Name())
+	kvStore := db.NewBoltDB(config.DB{DbPath: path.Name(), NumRetries: 3})
+	require.NoError(kvStore.Start(context.Background()))
+	defer kvStore.Stop(context.Background())
+	index, err := db.NewCountingIndex(kvStore, "counting")
+	require.NoError(err)
+	for i := 0; i < 10; i++ {
+		require.NoError(index.Add([]byte{byte(i)}))
+	}
+
+	bc := mock_blockchain.NewMockBlockchain(ctrl)
+	bc.EXPECT().TipHeight().Return(uint64(100)).AnyTimes()
+	cfg := config.Default.Chain.Pruning
+	_, err = newPruningTask(bc, cfg)
+	require.Error(err)
+
+	cfg.Interval = 10 * time.Millisecond
+	cfg.Buckets = []config.PruningBucket{{Name: "counting", KeepEntries: 4, Counting: true}}
+	bc.EXPECT().KVStore().Return(struct{ db.KVStore }{db.NewMemKVStore()}).Times(1)
+	_, err = newPruningTask(bc, cfg)
+	require.Error(err)
+
+	bc.EXPECT().KVStore().Return(kvStore).Times(1)
+	task, err := newPruningTask(bc, cfg)
+	require.NoError(err)
+	require.NoError(task.Start(context.Background()))
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, time.Second, func() (bool, error) {
+		offset, err := index.Offset()
+		return offset == 6, err
+	}))
+	require.NoError(task.Stop(context.Background()))
+}
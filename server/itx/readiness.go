@@ -0,0 +1,68 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package itx
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/consensus"
+	rolldposscheme "github.com/iotexproject/iotex-core/consensus/scheme/rolldpos"
+	"github.com/iotexproject/iotex-core/pkg/log"
+)
+
+// Healthz serves the liveness of the server, which is ok as long as the process is up, such that the node is restarted
+// by the orchestration only if it is gone, but not while it is syncing
+func (s *Server) Healthz(w http.ResponseWriter, _ *http.Request) {
+	writeProbeResponse(w, http.StatusOK, "OK")
+}
+
+// Readyz serves the readiness of the server, which is ok only if the server is ready as told by Readiness, or 503
+// with the reason otherwise
+func (s *Server) Readyz(w http.ResponseWriter, _ *http.Request) {
+	if err := s.Readiness(); err != nil {
+		writeProbeResponse(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeProbeResponse(w, http.StatusOK, "OK")
+}
+
+// Readiness returns nil if the server is ready, i.e., the block sync of each chain has caught up within
+// ReadinessMaxSyncLag blocks of its target height, and the consensus, if roll-DPoS, is progressing or standing by on
+// purpose. Otherwise it returns the reason why the server isn't ready.
+func (s *Server) Readiness() error {
+	if phase := s.ShutdownPhase(); phase != "" {
+		return errors.Errorf("server is stopping in phase %s", phase)
+	}
+	for _, cs := range s.chainServices() {
+		tip := cs.Blockchain().TipHeight()
+		if target := cs.BlockSync().TargetHeight(); target > tip+s.cfg.System.ReadinessMaxSyncLag {
+			return errors.Errorf("chain %d is syncing from height %d to %d", cs.ChainID(), tip, target)
+		}
+		c, ok := cs.Consensus().(*consensus.IotxConsensus)
+		if !ok {
+			continue
+		}
+		r, ok := c.Scheme().(*rolldposscheme.RollDPoS)
+		if !ok {
+			continue
+		}
+		if health := r.Health(); !health.Healthy() {
+			return errors.Errorf("consensus of chain %d is %s", cs.ChainID(), health)
+		}
+	}
+	return nil
+}
+
+func writeProbeResponse(w http.ResponseWriter, code int, msg string) {
+	w.WriteHeader(code)
+	if _, err := w.Write([]byte(msg)); err != nil {
+		log.L().Warn("Failed to send http response.", zap.Error(err))
+	}
+}
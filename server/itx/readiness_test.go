@@ -0,0 +1,66 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package itx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/blockchain/block"
+	"github.com/iotexproject/iotex-core/test/identityset"
+	"github.com/iotexproject/iotex-core/testutil"
+)
+
+func TestReadiness(t *testing.T) {
+	require := require.New(t)
+	cfg, cleanup := singleDelegateConfig(t)
+	defer cleanup()
+
+	s, err := NewServer(cfg)
+	require.NoError(err)
+	ctx := context.Background()
+	require.NoError(s.Start(ctx))
+	serve := func(handler http.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return w
+	}
+
+	// the single delegate is synced and participating once it produces blocks
+	chain := s.rootChainService.Blockchain()
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, 20*time.Second, func() (bool, error) {
+		return chain.TipHeight() >= 2, nil
+	}))
+	w := serve(s.Readyz)
+	require.Equal(http.StatusOK, w.Code, w.Body.String())
+	require.Equal(http.StatusOK, serve(s.Healthz).Code)
+
+	// a block far above the tip sets the target of the block sync, which the node is still syncing to
+	target := chain.TipHeight() + cfg.System.ReadinessMaxSyncLag + 100
+	blk, err := block.NewTestingBuilder().SetHeight(target).SignAndBuild(identityset.PrivateKey(1))
+	require.NoError(err)
+	require.NoError(s.rootChainService.BlockSync().ProcessBlock(ctx, &blk))
+	w = serve(s.Readyz)
+	require.Equal(http.StatusServiceUnavailable, w.Code)
+	require.Contains(w.Body.String(), "syncing")
+	require.Error(s.Readiness())
+	// but the node is alive
+	require.Equal(http.StatusOK, serve(s.Healthz).Code)
+
+	stopCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	require.NoError(s.Stop(stopCtx))
+	w = serve(s.Readyz)
+	require.Equal(http.StatusServiceUnavailable, w.Code)
+	require.Contains(w.Body.String(), "stopping")
+	require.Equal(http.StatusOK, serve(s.Healthz).Code)
+}
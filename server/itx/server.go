@@ -27,6 +27,8 @@ import (
 	"github.com/iotexproject/iotex-core/action/protocol/rolldpos"
 	"github.com/iotexproject/iotex-core/chainservice"
 	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/consensus"
+	rolldposscheme "github.com/iotexproject/iotex-core/consensus/scheme/rolldpos"
 	"github.com/iotexproject/iotex-core/dispatcher"
 	"github.com/iotexproject/iotex-core/p2p"
 	"github.com/iotexproject/iotex-core/pkg/ha"
@@ -36,6 +38,20 @@ import (
 	"github.com/iotexproject/iotex-core/pkg/util/httputil"
 )
 
+// drainPollInterval is the interval of checking whether the consensus finishes the current round when draining
+const drainPollInterval = 50 * time.Millisecond
+
+// The phases of stopping the server on top of those of stopping the chain services, see chainservice.StopInPhases
+const (
+	// ShutdownConsensus prepares the consensus of the chains for the shutdown, which waits for the blocks being
+	// committed
+	ShutdownConsensus = "consensus"
+	// ShutdownDispatcher stops the P2P agent and the dispatcher
+	ShutdownDispatcher = "dispatcher"
+	// ShutdownDone means the server is stopped
+	ShutdownDone = "done"
+)
+
 // Server is the iotex server instance containing all components.
 type Server struct {
 	cfg                  config.Config
@@ -47,6 +63,8 @@ type Server struct {
 	initializedSubChains map[uint32]bool
 	mutex                sync.RWMutex
 	subModuleCancel      context.CancelFunc
+	// shutdownPhase is the phase of stopping the server, which is empty unless stopping
+	shutdownPhase string
 }
 
 // NewServer creates a new server
@@ -66,14 +84,20 @@ func newServer(cfg config.Config, testing bool) (*Server, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "fail to create dispatcher")
 	}
-	p2pAgent := p2p.NewAgent(cfg, dispatcher.HandleBroadcast, dispatcher.HandleTell)
+	p2pAgent := p2p.NewAgent(
+		cfg,
+		dispatcher.HandleBroadcast,
+		dispatcher.HandleTell,
+		p2p.WithTelemetryHandler(dispatcher.HandleTelemetry),
+	)
 	chains := make(map[uint32]*chainservice.ChainService)
 	var cs *chainservice.ChainService
-	var opts []chainservice.Option
+	// the peers relaying invalid consensus messages are scored by the P2P agent
+	opts := []chainservice.Option{
+		chainservice.WithPeerScoreReporter(p2pAgent),
+	}
 	if testing {
-		opts = []chainservice.Option{
-			chainservice.WithTesting(),
-		}
+		opts = append(opts, chainservice.WithTesting())
 	}
 	cs, err = chainservice.New(cfg, p2pAgent, dispatcher, opts...)
 	if err != nil {
@@ -136,9 +160,26 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop stops the server
+// Stop stops the server in the order of the dependencies of the components, i.e., the consensus, the network and the
+// dispatcher, and then the chain services. The consensus is prepared for the shutdown first, which waits for the block
+// being committed, if any, up to the shutdown commit timeout, or the deadline of the context if sooner. The phase of
+// the shutdown is logged and reported by the heartbeat.
 func (s *Server) Stop(ctx context.Context) error {
 	defer s.subModuleCancel()
+	chainservices := s.chainServices()
+	s.enterShutdownPhase(ShutdownConsensus)
+	timeout := s.cfg.System.ShutdownCommitTimeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	for _, cs := range chainservices {
+		if err := cs.StopConsensusGracefully(timeout); err != nil {
+			log.L().Warn("Failed to stop consensus gracefully, stopping it anyway.",
+				zap.Uint32("chainID", cs.ChainID()),
+				zap.Error(err))
+		}
+	}
+	s.enterShutdownPhase(ShutdownDispatcher)
 	if err := s.p2pAgent.Stop(ctx); err != nil {
 		return errors.Wrap(err, "error when stopping P2P agent")
 	}
@@ -148,11 +189,93 @@ func (s *Server) Stop(ctx context.Context) error {
 	if err := s.rootChainService.Blockchain().RemoveSubscriber(s); err != nil {
 		return errors.Wrap(err, "error when unsubscribing root chain block creation")
 	}
-	for _, cs := range s.chainservices {
-		if err := cs.Stop(ctx); err != nil {
+	for _, cs := range chainservices {
+		if err := cs.StopInPhases(ctx, s.enterShutdownPhase); err != nil {
 			return errors.Wrap(err, "error when stopping blockchain")
 		}
 	}
+	s.enterShutdownPhase(ShutdownDone)
+	return nil
+}
+
+// ShutdownPhase returns the phase of stopping the server, which is empty unless stopping
+func (s *Server) ShutdownPhase() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.shutdownPhase
+}
+
+func (s *Server) enterShutdownPhase(phase string) {
+	s.mutex.Lock()
+	s.shutdownPhase = phase
+	s.mutex.Unlock()
+	log.L().Info("Stopping server.", zap.String("phase", phase))
+}
+
+// chainServices returns the chain services run in the server
+func (s *Server) chainServices() []*chainservice.ChainService {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	chainservices := make([]*chainservice.ChainService, 0, len(s.chainservices))
+	for _, cs := range s.chainservices {
+		chainservices = append(chainservices, cs)
+	}
+	return chainservices
+}
+
+// Drain shuts down the server gracefully. It stops queueing new events from the network, lets the pending events be
+// handled and the current consensus round finish, flushes the indexer, and then stops the server. The server is
+// stopped even if the context is done in the middle, in which case the remaining work is dropped and the error of
+// the unfinished step is returned.
+func (s *Server) Drain(ctx context.Context) error {
+	drainErr := s.drain(ctx)
+	if drainErr != nil {
+		log.L().Warn("Failed to drain the server, stopping it anyway.", zap.Error(drainErr))
+	}
+	if err := s.Stop(ctx); err != nil {
+		return err
+	}
+	return drainErr
+}
+
+func (s *Server) drain(ctx context.Context) error {
+	if dp, ok := s.dispatcher.(*dispatcher.IotxDispatcher); ok {
+		if err := dp.Drain(ctx); err != nil {
+			return errors.Wrap(err, "error when draining dispatcher")
+		}
+	}
+	for _, cs := range s.chainServices() {
+		if err := drainConsensus(ctx, cs); err != nil {
+			return errors.Wrapf(err, "error when draining consensus of chain %d", cs.ChainID())
+		}
+		if err := cs.Flush(ctx); err != nil {
+			return errors.Wrapf(err, "error when flushing chain %d", cs.ChainID())
+		}
+	}
+	return nil
+}
+
+// drainConsensus deactivates the roll-DPoS consensus, which finishes the current round if the node is doing the work,
+// and waits until it returns to the initial state. Other schemes have no round to finish.
+func drainConsensus(ctx context.Context, cs *chainservice.ChainService) error {
+	c, ok := cs.Consensus().(*consensus.IotxConsensus)
+	if !ok {
+		return nil
+	}
+	r, ok := c.Scheme().(*rolldposscheme.RollDPoS)
+	if !ok {
+		return nil
+	}
+	r.Activate(false)
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for r.Active() {
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "consensus stuck in state %s", r.CurrentState())
+		case <-ticker.C:
+		}
+	}
 	return nil
 }
 
@@ -165,6 +288,7 @@ func (s *Server) NewSubChainService(cfg config.Config, opts ...chainservice.Opti
 
 func (s *Server) newSubChainService(cfg config.Config, opts ...chainservice.Option) error {
 	// TODO: explorer dependency deleted here at #1085, need to revive by migrating to api
+	opts = append([]chainservice.Option{chainservice.WithPeerScoreReporter(s.p2pAgent)}, opts...)
 	cs, err := chainservice.New(cfg, s.p2pAgent, s.dispatcher, opts...)
 	if err != nil {
 		return err
@@ -218,10 +342,15 @@ func StartServer(ctx context.Context, svr *Server, probeSvr *probe.Server, cfg c
 		log.L().Fatal("Failed to start server.", zap.Error(err))
 		return
 	}
+	probeSvr.SetReadinessHandler(http.HandlerFunc(svr.Readyz))
 	probeSvr.Ready()
 
 	if cfg.System.HeartbeatInterval > 0 {
-		task := routine.NewRecurringTask(NewHeartbeatHandler(svr).Log, cfg.System.HeartbeatInterval)
+		var opts []HeartbeatOption
+		if cfg.System.TelemetryInterval > 0 {
+			opts = append(opts, WithStatusSink(NewTelemetryReporter(svr, cfg.System.TelemetryInterval).Report))
+		}
+		task := routine.NewRecurringTask(NewHeartbeatHandler(svr, opts...).Log, cfg.System.HeartbeatInterval)
 		if err := task.Start(ctx); err != nil {
 			log.L().Panic("Failed to start heartbeat routine.", zap.Error(err))
 		}
@@ -232,6 +361,21 @@ func StartServer(ctx context.Context, svr *Server, probeSvr *probe.Server, cfg c
 		}()
 	}
 
+	if cfg.Chain.Pruning.Interval > 0 {
+		task, err := newPruningTask(svr.rootChainService.Blockchain(), cfg.Chain.Pruning)
+		if err != nil {
+			log.L().Panic("Failed to create pruning routine.", zap.Error(err))
+		}
+		if err := task.Start(ctx); err != nil {
+			log.L().Panic("Failed to start pruning routine.", zap.Error(err))
+		}
+		defer func() {
+			if err := task.Stop(ctx); err != nil {
+				log.L().Panic("Failed to stop pruning routine.", zap.Error(err))
+			}
+		}()
+	}
+
 	var adminserv http.Server
 	if cfg.System.HTTPAdminPort > 0 {
 		mux := http.NewServeMux()
@@ -0,0 +1,179 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package itx
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+	"github.com/iotexproject/iotex-core/consensus"
+	"github.com/iotexproject/iotex-core/consensus/consensusfsm"
+	"github.com/iotexproject/iotex-core/consensus/scheme/rolldpos"
+	"github.com/iotexproject/iotex-core/pkg/unit"
+	"github.com/iotexproject/iotex-core/test/identityset"
+	"github.com/iotexproject/iotex-core/testutil"
+)
+
+func TestNewServerWithInvalidConsensusConfig(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default
+	cfg.Consensus.Scheme = config.RollDPoSScheme
+	cfg.Consensus.RollDPoS.FSM.CommitTTL = cfg.Genesis.BlockInterval
+	s, err := NewServer(cfg)
+	require.Equal(rolldpos.ErrNewRollDPoS, errors.Cause(err))
+	require.Contains(err.Error(), "invalid ttl config")
+	require.Nil(s)
+}
+
+// singleDelegateConfig returns the config of a single delegate network, which commits a block every round, and the
+// func to clean up its DBs
+func singleDelegateConfig(t *testing.T) (config.Config, func()) {
+	require := require.New(t)
+	cfg := config.Default
+	var paths []string
+	for _, path := range []*string{&cfg.Chain.ChainDBPath, &cfg.Chain.TrieDBPath} {
+		f, err := ioutil.TempFile("", "itx")
+		require.NoError(err)
+		*path = f.Name()
+		paths = append(paths, f.Name())
+	}
+	cfg.Chain.ProducerPrivKey = identityset.PrivateKey(0).HexString()
+	cfg.Network.Port = testutil.RandomPort()
+	cfg.API.Port = testutil.RandomPort()
+	cfg.Consensus.Scheme = config.RollDPoSScheme
+	cfg.Consensus.RollDPoS.Delay = 100 * time.Millisecond
+	cfg.Consensus.RollDPoS.ToleratedOvertime = 200 * time.Millisecond
+	cfg.Consensus.RollDPoS.FSM.AcceptBlockTTL = 400 * time.Millisecond
+	cfg.Consensus.RollDPoS.FSM.AcceptProposalEndorsementTTL = 200 * time.Millisecond
+	cfg.Consensus.RollDPoS.FSM.AcceptLockEndorsementTTL = 200 * time.Millisecond
+	cfg.Consensus.RollDPoS.FSM.CommitTTL = 200 * time.Millisecond
+	cfg.Genesis.BlockInterval = time.Second
+	cfg.Genesis.NumDelegates = 1
+	cfg.Genesis.NumSubEpochs = 1
+	cfg.Genesis.Delegates = cfg.Genesis.Delegates[:1]
+	cfg.Genesis.EnableGravityChainVoting = true
+	return cfg, func() {
+		for _, path := range paths {
+			testutil.CleanupPath(t, path)
+		}
+	}
+}
+
+func TestServerDrain(t *testing.T) {
+	require := require.New(t)
+	cfg, cleanup := singleDelegateConfig(t)
+	defer cleanup()
+
+	s, err := NewServer(cfg)
+	require.NoError(err)
+	ctx := context.Background()
+	require.NoError(s.Start(ctx))
+	chain := s.rootChainService.Blockchain()
+	r, ok := s.rootChainService.Consensus().(*consensus.IotxConsensus).Scheme().(*rolldpos.RollDPoS)
+	require.True(ok)
+
+	// drain in the middle of a round
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, 20*time.Second, func() (bool, error) {
+		return chain.TipHeight() >= 2 && r.CurrentState() != consensusfsm.InitState, nil
+	}))
+	drainCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	require.NotPanics(func() {
+		require.NoError(s.Drain(drainCtx))
+	})
+	require.False(r.Active())
+	require.Equal(consensusfsm.InitState, r.CurrentState())
+
+	// no block is committed after draining
+	height := chain.TipHeight()
+	time.Sleep(2 * cfg.Genesis.BlockInterval)
+	require.Equal(height, chain.TipHeight())
+}
+
+func TestServerStop(t *testing.T) {
+	require := require.New(t)
+	cfg, cleanup := singleDelegateConfig(t)
+	defer cleanup()
+
+	s, err := NewServer(cfg)
+	require.NoError(err)
+	ctx := context.Background()
+	require.NoError(s.Start(ctx))
+	chain := s.rootChainService.Blockchain()
+	r, ok := s.rootChainService.Consensus().(*consensus.IotxConsensus).Scheme().(*rolldpos.RollDPoS)
+	require.True(ok)
+	var status Status
+	handler := NewHeartbeatHandler(s, WithStatusSink(func(st Status) {
+		status = st
+	}), WithoutStatusLog())
+	handler.Log()
+	require.Empty(status.ShutdownPhase)
+
+	// stop in the middle of a round, where the block being committed, if any, is committed before the chain stops
+	require.NoError(testutil.WaitUntil(10*time.Millisecond, 20*time.Second, func() (bool, error) {
+		return chain.TipHeight() >= 2 && r.CurrentState() != consensusfsm.InitState, nil
+	}))
+	stopCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	require.NoError(s.Stop(stopCtx))
+	require.False(r.Active())
+
+	// the heartbeat reports the phase only, as the components are gone
+	handler.Log()
+	require.Equal(ShutdownDone, status.ShutdownPhase)
+	require.Empty(status.Chains)
+}
+
+func TestServerActPoolDump(t *testing.T) {
+	require := require.New(t)
+	cfg, cleanup := singleDelegateConfig(t)
+	defer cleanup()
+	// no block is minted to take the actions out of the pool
+	cfg.Consensus.Scheme = config.NOOPScheme
+	dir, err := ioutil.TempDir("", "itx")
+	require.NoError(err)
+	defer testutil.CleanupPath(t, dir)
+	cfg.ActPool.DumpPath = filepath.Join(dir, "actpool.dump")
+
+	ctx := context.Background()
+	s, err := NewServer(cfg)
+	require.NoError(err)
+	require.NoError(s.Start(ctx))
+	tsf, err := testutil.SignedTransfer(
+		identityset.Address(1).String(),
+		identityset.PrivateKey(0),
+		1,
+		big.NewInt(1),
+		nil,
+		testutil.TestGasLimit,
+		big.NewInt(unit.Qev),
+	)
+	require.NoError(err)
+	require.NoError(s.rootChainService.ActionPool().Add(tsf))
+	require.NoError(s.Stop(ctx))
+
+	// the action is back in the pool after the restart
+	cfg.Network.Port = testutil.RandomPort()
+	cfg.API.Port = testutil.RandomPort()
+	s, err = NewServer(cfg)
+	require.NoError(err)
+	require.NoError(s.Start(ctx))
+	defer func() {
+		require.NoError(s.Stop(ctx))
+	}()
+	act, err := s.rootChainService.ActionPool().GetActionByHash(tsf.Hash())
+	require.NoError(err)
+	require.Equal(tsf.Hash(), act.Hash())
+}
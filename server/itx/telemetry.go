@@ -0,0 +1,104 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package itx
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/dispatcher/telemetrypb"
+	"github.com/iotexproject/iotex-core/pkg/log"
+	"github.com/iotexproject/iotex-core/pkg/version"
+)
+
+// TelemetryReporter publishes a status report signed by the delegate key of the node every a few heartbeats, such that
+// the network could display the health of the delegates without trusting a central scraper
+type TelemetryReporter struct {
+	s        *Server
+	interval uint
+	count    uint
+}
+
+// NewTelemetryReporter instantiates a reporter publishing a report every interval heartbeats
+func NewTelemetryReporter(s *Server, interval uint) *TelemetryReporter {
+	return &TelemetryReporter{
+		s:        s,
+		interval: interval,
+	}
+}
+
+// Report is the status sink of the heartbeat handler. Every interval calls, it publishes a report for each chain in
+// the status of which the node is a current delegate.
+func (r *TelemetryReporter) Report(status Status) {
+	r.count++
+	if r.count < r.interval {
+		return
+	}
+	r.count = 0
+	for _, c := range status.Chains {
+		if err := r.publish(c, status.NumPeers); err != nil {
+			log.L().Debug("Failed to publish telemetry report.", zap.Uint32("chainID", c.ChainID), zap.Error(err))
+		}
+	}
+}
+
+func (r *TelemetryReporter) publish(c ChainStatus, numPeers int) error {
+	cs := r.s.ChainService(c.ChainID)
+	if cs == nil {
+		return errors.Errorf("chain %d doesn't exist", c.ChainID)
+	}
+	signer, err := cs.Signer()
+	if err != nil {
+		return err
+	}
+	delegates, err := cs.Delegates()
+	if err != nil {
+		return err
+	}
+	pubKey := signer.PublicKey()
+	addr, err := address.FromBytes(pubKey.Hash())
+	if err != nil {
+		return errors.Wrap(err, "failed to get the address of the delegate key")
+	}
+	isDelegate := false
+	for _, d := range delegates {
+		if d == addr.String() {
+			isDelegate = true
+			break
+		}
+	}
+	if !isDelegate {
+		// the reports of the others are dropped by the receivers anyway
+		return nil
+	}
+	data, err := proto.Marshal(&telemetrypb.TelemetryReport{
+		ChainID:   c.ChainID,
+		Version:   version.PackageVersion,
+		Height:    c.BlockchainHeight,
+		NumPeers:  uint32(numPeers),
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal telemetry report")
+	}
+	h := hash.Hash256b(data)
+	sig, err := signer.Sign(h[:])
+	if err != nil {
+		return errors.Wrap(err, "failed to sign telemetry report")
+	}
+	return r.s.P2PAgent().BroadcastTelemetry(context.Background(), &telemetrypb.SignedTelemetryReport{
+		Report:       data,
+		SenderPubKey: pubKey.Bytes(),
+		Signature:    sig,
+	})
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package itx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-core/config"
+)
+
+func TestTelemetryReporter(t *testing.T) {
+	require := require.New(t)
+	cfg := config.Default
+	cfg.Chain.ChainDBPath = ""
+	cfg.Chain.TrieDBPath = ""
+	s, err := NewInMemTestServer(cfg)
+	require.NoError(err)
+	r := NewTelemetryReporter(s, 3)
+
+	// a report is published every 3 heartbeats
+	status := Status{Chains: []ChainStatus{{ChainID: cfg.Chain.ID}}}
+	r.Report(status)
+	r.Report(status)
+	require.Equal(uint(2), r.count)
+	// the standalone consensus has no delegate key to sign the report with
+	cs := s.ChainService(cfg.Chain.ID)
+	_, err = cs.Signer()
+	require.Error(err)
+	require.Error(r.publish(status.Chains[0], 0))
+	r.Report(status)
+	require.Equal(uint(0), r.count)
+}
@@ -9,6 +9,8 @@ import (
 	hash "github.com/iotexproject/go-pkgs/hash"
 	action "github.com/iotexproject/iotex-core/action"
 	protocol "github.com/iotexproject/iotex-core/action/protocol"
+	actioniterator "github.com/iotexproject/iotex-core/actpool/actioniterator"
+	io "io"
 	reflect "reflect"
 )
 
@@ -57,6 +59,18 @@ func (mr *MockActPoolMockRecorder) PendingActionMap() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingActionMap", reflect.TypeOf((*MockActPool)(nil).PendingActionMap))
 }
 
+// PendingActionIterator mocks base method
+func (m *MockActPool) PendingActionIterator() actioniterator.ActionIterator {
+	ret := m.ctrl.Call(m, "PendingActionIterator")
+	ret0, _ := ret[0].(actioniterator.ActionIterator)
+	return ret0
+}
+
+// PendingActionIterator indicates an expected call of PendingActionIterator
+func (mr *MockActPoolMockRecorder) PendingActionIterator() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingActionIterator", reflect.TypeOf((*MockActPool)(nil).PendingActionIterator))
+}
+
 // Add mocks base method
 func (m *MockActPool) Add(act action.SealedEnvelope) error {
 	ret := m.ctrl.Call(m, "Add", act)
@@ -182,3 +196,27 @@ func (m *MockActPool) AddActionEnvelopeValidators(arg0 ...protocol.ActionEnvelop
 func (mr *MockActPoolMockRecorder) AddActionEnvelopeValidators(arg0 ...interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddActionEnvelopeValidators", reflect.TypeOf((*MockActPool)(nil).AddActionEnvelopeValidators), arg0...)
 }
+
+// Dump mocks base method
+func (m *MockActPool) Dump(w io.Writer) error {
+	ret := m.ctrl.Call(m, "Dump", w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Dump indicates an expected call of Dump
+func (mr *MockActPoolMockRecorder) Dump(w interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dump", reflect.TypeOf((*MockActPool)(nil).Dump), w)
+}
+
+// Load mocks base method
+func (m *MockActPool) Load(r io.Reader) error {
+	ret := m.ctrl.Call(m, "Load", r)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Load indicates an expected call of Load
+func (mr *MockActPoolMockRecorder) Load(r interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockActPool)(nil).Load), r)
+}
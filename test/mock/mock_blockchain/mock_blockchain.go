@@ -7,11 +7,14 @@ package mock_blockchain
 import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
+	crypto "github.com/iotexproject/go-pkgs/crypto"
 	hash "github.com/iotexproject/go-pkgs/hash"
 	address "github.com/iotexproject/iotex-address/address"
 	action "github.com/iotexproject/iotex-core/action"
+	actioniterator "github.com/iotexproject/iotex-core/actpool/actioniterator"
 	blockchain "github.com/iotexproject/iotex-core/blockchain"
 	block "github.com/iotexproject/iotex-core/blockchain/block"
+	db "github.com/iotexproject/iotex-core/db"
 	state "github.com/iotexproject/iotex-core/state"
 	factory "github.com/iotexproject/iotex-core/state/factory"
 	big "math/big"
@@ -430,6 +433,51 @@ func (mr *MockBlockchainMockRecorder) GetReceiptsByHeight(height interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceiptsByHeight", reflect.TypeOf((*MockBlockchain)(nil).GetReceiptsByHeight), height)
 }
 
+// GetReceiptsByBlockHash mocks base method
+func (m *MockBlockchain) GetReceiptsByBlockHash(h hash.Hash256) ([]*action.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReceiptsByBlockHash", h)
+	ret0, _ := ret[0].([]*action.Receipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReceiptsByBlockHash indicates an expected call of GetReceiptsByBlockHash
+func (mr *MockBlockchainMockRecorder) GetReceiptsByBlockHash(h interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceiptsByBlockHash", reflect.TypeOf((*MockBlockchain)(nil).GetReceiptsByBlockHash), h)
+}
+
+// BlocksMatchingBloom mocks base method
+func (m *MockBlockchain) BlocksMatchingBloom(start, end uint64, keys []hash.Hash256) ([]uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlocksMatchingBloom", start, end, keys)
+	ret0, _ := ret[0].([]uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BlocksMatchingBloom indicates an expected call of BlocksMatchingBloom
+func (mr *MockBlockchainMockRecorder) BlocksMatchingBloom(start, end, keys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlocksMatchingBloom", reflect.TypeOf((*MockBlockchain)(nil).BlocksMatchingBloom), start, end, keys)
+}
+
+// ConsensusMetaByHeight mocks base method
+func (m *MockBlockchain) ConsensusMetaByHeight(height uint64) (*blockchain.ConsensusMeta, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsensusMetaByHeight", height)
+	ret0, _ := ret[0].(*blockchain.ConsensusMeta)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsensusMetaByHeight indicates an expected call of ConsensusMetaByHeight
+func (mr *MockBlockchainMockRecorder) ConsensusMetaByHeight(height interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsensusMetaByHeight", reflect.TypeOf((*MockBlockchain)(nil).ConsensusMetaByHeight), height)
+}
+
 // GetFactory mocks base method
 func (m *MockBlockchain) GetFactory() factory.Factory {
 	m.ctrl.T.Helper()
@@ -444,6 +492,20 @@ func (mr *MockBlockchainMockRecorder) GetFactory() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFactory", reflect.TypeOf((*MockBlockchain)(nil).GetFactory))
 }
 
+// KVStore mocks base method
+func (m *MockBlockchain) KVStore() db.KVStore {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KVStore")
+	ret0, _ := ret[0].(db.KVStore)
+	return ret0
+}
+
+// KVStore indicates an expected call of KVStore
+func (mr *MockBlockchainMockRecorder) KVStore() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KVStore", reflect.TypeOf((*MockBlockchain)(nil).KVStore))
+}
+
 // ChainID mocks base method
 func (m *MockBlockchain) ChainID() uint32 {
 	m.ctrl.T.Helper()
@@ -558,6 +620,33 @@ func (mr *MockBlockchainMockRecorder) MintNewBlock(actionMap, timestamp interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MintNewBlock", reflect.TypeOf((*MockBlockchain)(nil).MintNewBlock), actionMap, timestamp)
 }
 
+// MintNewBlockWithActionIterator mocks base method
+func (m *MockBlockchain) MintNewBlockWithActionIterator(actionIterator actioniterator.ActionIterator, timestamp time.Time) (*block.Block, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MintNewBlockWithActionIterator", actionIterator, timestamp)
+	ret0, _ := ret[0].(*block.Block)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MintNewBlockWithActionIterator indicates an expected call of MintNewBlockWithActionIterator
+func (mr *MockBlockchainMockRecorder) MintNewBlockWithActionIterator(actionIterator, timestamp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MintNewBlockWithActionIterator", reflect.TypeOf((*MockBlockchain)(nil).MintNewBlockWithActionIterator), actionIterator, timestamp)
+}
+
+// SetProducerPrivateKey mocks base method
+func (m *MockBlockchain) SetProducerPrivateKey(sk crypto.PrivateKey) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetProducerPrivateKey", sk)
+}
+
+// SetProducerPrivateKey indicates an expected call of SetProducerPrivateKey
+func (mr *MockBlockchainMockRecorder) SetProducerPrivateKey(sk interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetProducerPrivateKey", reflect.TypeOf((*MockBlockchain)(nil).SetProducerPrivateKey), sk)
+}
+
 // CommitBlock mocks base method
 func (m *MockBlockchain) CommitBlock(blk *block.Block) error {
 	m.ctrl.T.Helper()
@@ -572,6 +661,20 @@ func (mr *MockBlockchainMockRecorder) CommitBlock(blk interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitBlock", reflect.TypeOf((*MockBlockchain)(nil).CommitBlock), blk)
 }
 
+// PutConsensusMeta mocks base method
+func (m *MockBlockchain) PutConsensusMeta(meta *blockchain.ConsensusMeta) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutConsensusMeta", meta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutConsensusMeta indicates an expected call of PutConsensusMeta
+func (mr *MockBlockchainMockRecorder) PutConsensusMeta(meta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutConsensusMeta", reflect.TypeOf((*MockBlockchain)(nil).PutConsensusMeta), meta)
+}
+
 // ValidateBlock mocks base method
 func (m *MockBlockchain) ValidateBlock(blk *block.Block) error {
 	m.ctrl.T.Helper()
@@ -628,6 +731,22 @@ func (mr *MockBlockchainMockRecorder) ExecuteContractRead(caller, ex interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteContractRead", reflect.TypeOf((*MockBlockchain)(nil).ExecuteContractRead), caller, ex)
 }
 
+// SimulateExecution mocks base method
+func (m *MockBlockchain) SimulateExecution(caller address.Address, act action.Action) ([]byte, *action.Receipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SimulateExecution", caller, act)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(*action.Receipt)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SimulateExecution indicates an expected call of SimulateExecution
+func (mr *MockBlockchainMockRecorder) SimulateExecution(caller, act interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SimulateExecution", reflect.TypeOf((*MockBlockchain)(nil).SimulateExecution), caller, act)
+}
+
 // AddSubscriber mocks base method
 func (m *MockBlockchain) AddSubscriber(arg0 blockchain.BlockCreationSubscriber) error {
 	m.ctrl.T.Helper()
@@ -656,6 +775,21 @@ func (mr *MockBlockchainMockRecorder) RemoveSubscriber(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveSubscriber", reflect.TypeOf((*MockBlockchain)(nil).RemoveSubscriber), arg0)
 }
 
+// SubscribeBlockCommit mocks base method
+func (m *MockBlockchain) SubscribeBlockCommit(buffer int) (<-chan *block.Block, func()) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeBlockCommit", buffer)
+	ret0, _ := ret[0].(<-chan *block.Block)
+	ret1, _ := ret[1].(func())
+	return ret0, ret1
+}
+
+// SubscribeBlockCommit indicates an expected call of SubscribeBlockCommit
+func (mr *MockBlockchainMockRecorder) SubscribeBlockCommit(buffer interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeBlockCommit", reflect.TypeOf((*MockBlockchain)(nil).SubscribeBlockCommit), buffer)
+}
+
 // GetActionHashFromIndex mocks base method
 func (m *MockBlockchain) GetActionHashFromIndex(index uint64) (hash.Hash256, error) {
 	m.ctrl.T.Helper()
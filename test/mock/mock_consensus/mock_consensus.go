@@ -61,15 +61,15 @@ func (mr *MockConsensusMockRecorder) Stop(arg0 interface{}) *gomock.Call {
 }
 
 // HandleConsensusMsg mocks base method
-func (m *MockConsensus) HandleConsensusMsg(arg0 *iotextypes.ConsensusMessage) error {
-	ret := m.ctrl.Call(m, "HandleConsensusMsg", arg0)
+func (m *MockConsensus) HandleConsensusMsg(arg0 context.Context, arg1 *iotextypes.ConsensusMessage) error {
+	ret := m.ctrl.Call(m, "HandleConsensusMsg", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // HandleConsensusMsg indicates an expected call of HandleConsensusMsg
-func (mr *MockConsensusMockRecorder) HandleConsensusMsg(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleConsensusMsg", reflect.TypeOf((*MockConsensus)(nil).HandleConsensusMsg), arg0)
+func (mr *MockConsensusMockRecorder) HandleConsensusMsg(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleConsensusMsg", reflect.TypeOf((*MockConsensus)(nil).HandleConsensusMsg), arg0, arg1)
 }
 
 // Calibrate mocks base method
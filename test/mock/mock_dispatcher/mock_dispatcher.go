@@ -87,15 +87,15 @@ func (mr *MockSubscriberMockRecorder) HandleSyncRequest(arg0, arg1, arg2 interfa
 }
 
 // HandleConsensusMsg mocks base method
-func (m *MockSubscriber) HandleConsensusMsg(arg0 *iotextypes.ConsensusMessage) error {
-	ret := m.ctrl.Call(m, "HandleConsensusMsg", arg0)
+func (m *MockSubscriber) HandleConsensusMsg(arg0 context.Context, arg1 *iotextypes.ConsensusMessage) error {
+	ret := m.ctrl.Call(m, "HandleConsensusMsg", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // HandleConsensusMsg indicates an expected call of HandleConsensusMsg
-func (mr *MockSubscriberMockRecorder) HandleConsensusMsg(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleConsensusMsg", reflect.TypeOf((*MockSubscriber)(nil).HandleConsensusMsg), arg0)
+func (mr *MockSubscriberMockRecorder) HandleConsensusMsg(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleConsensusMsg", reflect.TypeOf((*MockSubscriber)(nil).HandleConsensusMsg), arg0, arg1)
 }
 
 // MockDispatcher is a mock of Dispatcher interface
@@ -174,3 +174,15 @@ func (m *MockDispatcher) HandleTell(arg0 context.Context, arg1 uint32, arg2 go_l
 func (mr *MockDispatcherMockRecorder) HandleTell(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleTell", reflect.TypeOf((*MockDispatcher)(nil).HandleTell), arg0, arg1, arg2, arg3)
 }
+
+// HandleTelemetry mocks base method
+func (m *MockDispatcher) HandleTelemetry(arg0 context.Context, arg1 string, arg2 []byte) error {
+	ret := m.ctrl.Call(m, "HandleTelemetry", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleTelemetry indicates an expected call of HandleTelemetry
+func (mr *MockDispatcherMockRecorder) HandleTelemetry(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleTelemetry", reflect.TypeOf((*MockDispatcher)(nil).HandleTelemetry), arg0, arg1, arg2)
+}
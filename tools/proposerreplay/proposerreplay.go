@@ -0,0 +1,118 @@
+// Copyright (c) 2019 IoTeX Foundation
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// This is an admin tool that replays the proposer calculation against the blocks of a chain database, to make sure a
+// new version agrees with the proposers on chain before it is released. To use, stop the node and run
+// "go run ./tools/proposerreplay -config-path=[string] -from=[int] -to=[int]"
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	glog "log"
+	"os"
+
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-core/action/protocol/rolldpos"
+	"github.com/iotexproject/iotex-core/blockchain/genesis"
+	"github.com/iotexproject/iotex-core/config"
+	rolldposscheme "github.com/iotexproject/iotex-core/consensus/scheme/rolldpos"
+	"github.com/iotexproject/iotex-core/pkg/log"
+	"github.com/iotexproject/iotex-core/server/itx"
+)
+
+var (
+	// fromHeight is the first height to replay
+	fromHeight uint64
+	// toHeight is the last height to replay, 0 for the tip
+	toHeight uint64
+)
+
+func init() {
+	flag.Uint64Var(&fromHeight, "from", 1, "First height to replay")
+	flag.Uint64Var(&toHeight, "to", 0, "Last height to replay, 0 for the tip")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stderr,
+			"usage: proposerreplay -config-path=[string]\n -from=[int]\n -to=[int]\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	flag.Parse()
+}
+
+func main() {
+	genesisCfg, err := genesis.New()
+	if err != nil {
+		glog.Fatalln("Failed to new genesis config.", zap.Error(err))
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		glog.Fatalln("Failed to new config.", zap.Error(err))
+	}
+
+	cfg.Genesis = genesisCfg
+
+	svr, err := itx.NewServer(cfg)
+	if err != nil {
+		log.L().Fatal("Failed to create server.", zap.Error(err))
+	}
+	cs := svr.ChainService(cfg.Chain.ID)
+	bc := cs.Blockchain()
+	if err := bc.Start(context.Background()); err != nil {
+		log.L().Fatal("Failed to start blockchain.", zap.Error(err))
+	}
+	defer func() {
+		if err := bc.Stop(context.Background()); err != nil {
+			log.L().Fatal("Failed to stop blockchain.", zap.Error(err))
+		}
+	}()
+	p, ok := cs.Registry().Find(rolldpos.ProtocolID)
+	if !ok {
+		log.L().Fatal("Protocol rolldpos has not been registered.")
+	}
+	rp, ok := p.(*rolldpos.Protocol)
+	if !ok {
+		log.L().Fatal("Failed to cast to rolldpos protocol.")
+	}
+	if toHeight == 0 {
+		toHeight = bc.TipHeight()
+	}
+
+	records, err := rolldposscheme.ReplayProposers(
+		bc,
+		rp,
+		fromHeight,
+		toHeight,
+		rolldposscheme.ReplayBlockIntervalOption(cfg.Genesis.BlockInterval),
+		rolldposscheme.ReplayTimeBasedRotationOption(cfg.Genesis.TimeBasedRotation),
+	)
+	if err != nil {
+		log.L().Fatal("Failed to replay proposers.", zap.Error(err))
+	}
+	mismatches := 0
+	for _, r := range records {
+		if r.Skipped {
+			fmt.Printf("SKIPPED epoch from height %d: %v\n", r.Height, r.Err)
+			continue
+		}
+		mismatches++
+		fmt.Printf(
+			"MISMATCH height %d at %s: actual %s, expected %s: %v\n",
+			r.Height,
+			r.Timestamp,
+			r.Actual,
+			r.Expected,
+			r.Err,
+		)
+	}
+	fmt.Printf("Replayed heights %d to %d, %d mismatches\n", fromHeight, toHeight, mismatches)
+	if mismatches != 0 {
+		os.Exit(1)
+	}
+}